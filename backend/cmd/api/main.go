@@ -1,29 +1,42 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"time"
+	// Event time zones must load on hosts without a zoneinfo database
+	_ "time/tzdata"
 
+	"github.com/Athooh/social-network/internal/account"
 	"github.com/Athooh/social-network/internal/auth"
+	backupHandler "github.com/Athooh/social-network/internal/backup"
 	"github.com/Athooh/social-network/internal/config"
+	"github.com/Athooh/social-network/internal/export"
 	"github.com/Athooh/social-network/internal/follow"
 	"github.com/Athooh/social-network/internal/group"
+	"github.com/Athooh/social-network/internal/health"
 	notifications "github.com/Athooh/social-network/internal/notifcations"
 	"github.com/Athooh/social-network/internal/post"
 	"github.com/Athooh/social-network/internal/profile"
 	"github.com/Athooh/social-network/internal/server"
+	"github.com/Athooh/social-network/internal/stats"
+	"github.com/Athooh/social-network/internal/storage"
 	wsHandler "github.com/Athooh/social-network/internal/websocket"
-	"github.com/Athooh/social-network/pkg/db/sqlite"
+	"github.com/Athooh/social-network/pkg/backup"
+	"github.com/Athooh/social-network/pkg/db/uow"
 	"github.com/Athooh/social-network/pkg/filestore"
 	"github.com/Athooh/social-network/pkg/logger"
+	"github.com/Athooh/social-network/pkg/metrics"
+	"github.com/Athooh/social-network/pkg/scheduler"
 	"github.com/Athooh/social-network/pkg/websocket"
 
 	"github.com/Athooh/social-network/internal/chat"
 	"github.com/Athooh/social-network/internal/event"
 	userHandler "github.com/Athooh/social-network/internal/user"
 	"github.com/Athooh/social-network/pkg/session"
-	"github.com/Athooh/social-network/pkg/user"
 )
 
 func main() {
@@ -52,45 +65,58 @@ func main() {
 
 	log := logger.New(logger.Config{
 		Level:       level,
+		Format:      logger.ParseFormat(cfg.Log.Format),
 		Output:      os.Stdout,
 		TimeFormat:  cfg.Log.TimeFormat,
 		ShowCaller:  cfg.Log.ShowCaller,
 		FilePath:    cfg.Log.FilePath,
 		EnableColor: cfg.Log.EnableColor,
 		OutputType:  logger.BothOutput,
+		Rotation: logger.RotationConfig{
+			MaxSize:    int64(cfg.Log.MaxSizeMB) << 20,
+			Interval:   cfg.Log.RotateInterval,
+			MaxBackups: cfg.Log.MaxFiles,
+			Compress:   cfg.Log.Compress,
+		},
 	})
+	defer logger.Close()
+	logger.ReopenOnSIGHUP()
+	// Libraries that log with log/slog write to the same outputs in the same format
+	slog.SetDefault(log.Slog())
 
 	log.Info("Starting social network API server")
 
-	// Set up database
-	dbConfig := sqlite.Config{
-		DBPath:         cfg.Database.Path,
-		MigrationsPath: cfg.Database.MigrationsPath,
-	}
-
-	db, err := sqlite.New(dbConfig)
+	// Set up database, only committed migrations are applied here, new ones are generated
+	// with cmd/migrate
+	db, repos, err := storage.Open(cfg.Database)
 	if err != nil {
-		log.Fatal("Failed to connect to database: %v", err)
+		log.Fatal("Failed to set up %s database: %v", cfg.Database.Driver, err)
 	}
 	defer db.Close()
 
-	log.Info("Connected to database")
-
-	if err := sqlite.CreateMigrations(db); err != nil {
-		log.Fatal("Failed to create migrations: %v", err)
+	log.Info("Connected to %s database", cfg.Database.Driver)
+	if err := metrics.ObserveDB(db, cfg.Database.Driver); err != nil {
+		log.Warn("Failed to register database metrics: %v", err)
 	}
 
 	// Set up repositories
-	userRepo := user.NewSQLiteRepository(db.DB)
-	sessionRepo := session.NewSQLiteRepository(db.DB)
-	postRepo := post.NewSQLiteRepository(db.DB)
-	followRepo := follow.NewSQLiteRepository(db.DB)
-	statusRepo := userHandler.NewSQLiteStatusRepository(db.DB)
-	groupRepo := group.NewSQLiteRepository(db.DB)
-	eventRepo := event.NewSQLiteRepository(db.DB)
-	chatRepo := chat.NewSQLiteRepository(db.DB)
-	profileRepo := profile.NewSQLiteRepository(db.DB)
-	notificationsRepo := notifications.NewSQLiteRepository(db.DB)
+	userRepo := repos.Users
+	sessionRepo := repos.Sessions
+	postRepo := repos.Posts
+	followRepo := repos.Follows
+	statusRepo := repos.Statuses
+	groupRepo := repos.Groups
+	eventRepo := repos.Events
+	chatRepo := repos.Chat
+	profileRepo := repos.Profiles
+	notificationsRepo := repos.Notifications
+	statsRepo := repos.Stats
+	jobsRepo := repos.Jobs
+	exportsRepo := repos.Exports
+	accountsRepo := repos.Accounts
+
+	// Services use units of work to change several tables in one transaction
+	work := uow.New(db)
 
 	// Set up session manager
 	sessionManager := session.NewSessionManager(
@@ -118,24 +144,82 @@ func main() {
 	wsHub := websocket.NewHub(log)
 	go wsHub.Run()
 
+	// Delayed jobs such as event reminders are kept in the database and run once due, the
+	// scheduler starts once every service has registered its handlers
+	jobs := scheduler.New(jobsRepo, log, scheduler.Config{PollInterval: cfg.Scheduler.PollInterval})
+
+	// Group permissions are checked the same way by the group, event and post services
+	groupAuth := group.NewAuthorizer(groupRepo)
+
 	// Set up services
 	notificationsService := notifications.NewService(notificationsRepo, userRepo, log, wsHub)
 	authService := auth.NewService(userRepo, sessionManager, jwtConfig, statusRepo)
 	postNotificationSvc := post.NewNotificationService(wsHub, userRepo, notificationsService, log)
-	postService := post.NewService(postRepo, fileStore, log, postNotificationSvc)
+	postService := post.NewService(postRepo, work, fileStore, log, postNotificationSvc, groupAuth)
 	statusService := userHandler.NewStatusService(statusRepo, sessionRepo, wsHub, log)
-	eventService := event.NewService(eventRepo, fileStore, log, notificationsService, wsHub)
-	groupService := group.NewService(groupRepo, fileStore, log, wsHub, notificationsService)
+	eventService := event.NewService(eventRepo, fileStore, log, notificationsService, wsHub, groupAuth, jobs, cfg.Events.ReminderOffsets)
+	groupService := group.NewService(groupRepo, work, fileStore, log, wsHub, notificationsService)
 	chatService := chat.NewService(chatRepo, log, wsHub)
-	followService := follow.NewService(followRepo, userRepo, statusRepo, notificationsService, log, wsHub)
+	followService := follow.NewService(followRepo, work, userRepo, statusRepo, notificationsService, log, wsHub)
 	profileService := profile.NewService(profileRepo, "./data/uploads")
+	exportService, err := export.NewService(exportsRepo, fileStore, notificationsService, wsHub, jobs, log, export.Config{
+		Dir:      cfg.Exports.Dir,
+		Cooldown: cfg.Exports.Cooldown,
+		TTL:      cfg.Exports.TTL,
+	})
+	if err != nil {
+		log.Fatal("Failed to set up data exports: %v", err)
+	}
+	reconciler := stats.NewReconciler(statsRepo, wsHub)
+	accountService := account.NewService(accountsRepo, userRepo, statusRepo, groupRepo, work, sessionManager, wsHub, jobs, reconciler, fileStore, notificationsService, log, account.Config{
+		DeletionGrace: cfg.Accounts.DeletionGrace,
+	})
 
 	// Connect the Hub to the StatusService
 	wsHub.SetStatusUpdater(statusService)
 
+	// Run jobs that came due while the server was down, then poll for new ones
+	go jobs.Run()
+	defer jobs.Stop()
+
 	// Run status cleanup to ensure consistency between sessions and online status
 	go statusService.CleanupUserStatuses()
 
+	// Recompute trending hashtags in the background
+	trendingJob := post.NewTrendingJob(postRepo, log, 10*time.Minute)
+	go trendingJob.Run()
+	defer trendingJob.Stop()
+
+	// Repair drifted user and post counters on a schedule unless STATS_RECONCILE_INTERVAL is 0
+	if cfg.Stats.ReconcileInterval > 0 {
+		reconcileJob := stats.NewJob(reconciler, log, cfg.Stats.ReconcileInterval)
+		go reconcileJob.Run()
+		defer reconcileJob.Stop()
+	}
+
+	// Set up backups, snapshots are taken on a schedule unless BACKUP_INTERVAL is 0.
+	// Snapshots copy the SQLite file, PostgreSQL databases are backed up with pg_dump.
+	var backupsHandler *backupHandler.Handler
+	if cfg.Database.Driver == config.DriverSQLite {
+		backupManager, err := backup.NewManager(db, backup.Config{
+			DBPath:     cfg.Database.Path,
+			UploadDir:  cfg.FileStore.UploadDir,
+			BackupDir:  cfg.Backup.Dir,
+			Compress:   cfg.Backup.Compress,
+			KeepDaily:  cfg.Backup.KeepDaily,
+			KeepWeekly: cfg.Backup.KeepWeekly,
+		})
+		if err != nil {
+			log.Fatal("Failed to set up backups: %v", err)
+		}
+		if cfg.Backup.Interval > 0 {
+			backupJob := backupHandler.NewJob(backupManager, log, cfg.Backup.Interval)
+			go backupJob.Run()
+			defer backupJob.Stop()
+		}
+		backupsHandler = backupHandler.NewHandler(backupManager, log, cfg.Auth.AdminUserIDs)
+	}
+
 	// Set up handlers
 	authHandler := auth.NewHandler(authService, fileStore)
 	postHandler := post.NewHandler(postService, log)
@@ -146,6 +230,15 @@ func main() {
 	chatHandler := chat.NewHandler(chatService, log)
 	notificationHanler := notifications.NewHandler(notificationsService, log)
 	profileHandler := profile.NewHandler(profileService, log)
+	statsHandler := stats.NewHandler(reconciler, log, cfg.Auth.AdminUserIDs)
+	exportHandler := export.NewHandler(exportService, log)
+	accountHandler := account.NewHandler(accountService, log)
+
+	// Probes for /livez and /readyz
+	healthHandler := health.NewHandler(log)
+	healthHandler.AddLivenessCheck("websocket_hub", wsHub.Alive)
+	healthHandler.AddReadinessCheck("database", health.Database(db))
+	healthHandler.AddReadinessCheck("uploads", health.WritableDir(cfg.FileStore.UploadDir))
 
 	// Set up router with both session and JWT middleware
 	router := server.Router(server.RouterConfig{
@@ -157,6 +250,11 @@ func main() {
 		EventHandler:        eventHandler,
 		ChatHandler:         chatHandler,
 		NotificationHanlder: notificationHanler,
+		BackupHandler:       backupsHandler,
+		StatsHandler:        statsHandler,
+		ExportHandler:       exportHandler,
+		AccountHandler:      accountHandler,
+		HealthHandler:       healthHandler,
 		AuthMiddleware:      authService.RequireAuth,
 		JWTMiddleware:       authService.RequireJWTAuth,
 		Logger:              log,
@@ -171,10 +269,36 @@ func main() {
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
+
+		ShutdownDelay:   cfg.Server.ShutdownDelay,
+		ShutdownTimeout: cfg.Server.ShutdownTimeout,
 	}
 
 	srv := server.New(serverConfig, router, log)
 
+	// On shutdown fail readiness first, then close websockets once no new ones can connect
+	srv.BeforeShutdown(func() { healthHandler.SetReady(false) })
+	srv.AfterShutdown(func(ctx context.Context) error {
+		return wsHub.Shutdown(ctx, cfg.Server.ReconnectDelay)
+	})
+
+	// Metrics name routes and count users, so they are served on an internal listener
+	// that Prometheus scrapes instead of the public one
+	if cfg.Server.MetricsAddr != "" {
+		metricsServer := &http.Server{
+			Addr:        cfg.Server.MetricsAddr,
+			Handler:     metrics.Handler(),
+			ReadTimeout: cfg.Server.ReadTimeout,
+		}
+		go func() {
+			log.Info("Serving metrics on http://%s/metrics", cfg.Server.MetricsAddr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("Metrics server error: %v", err)
+			}
+		}()
+		srv.AfterShutdown(metricsServer.Shutdown)
+	}
+
 	// Start server
 	if err := srv.Start(); err != nil {
 		log.Fatal("Server error: %v", err)
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Athooh/social-network/internal/config"
+	"github.com/Athooh/social-network/pkg/backup"
+	"github.com/Athooh/social-network/pkg/db/sqlite"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+const usage = `Usage: go run ./cmd/backup <command> [arguments]
+
+Commands:
+  snapshot      take a snapshot of the database and its upload files, safe while the server runs
+  list          list the snapshots in the backup directory, newest first
+  verify FILE   check a snapshot's checksums and database integrity without restoring it
+  restore FILE  verify a snapshot and restore it, the server must be stopped
+  prune         delete the snapshots the retention policy doesn't keep
+
+DB_PATH, FILE_STORE_UPLOAD_DIR and BACKUP_DIR select the database, the uploads and the
+snapshots. BACKUP_COMPRESS, BACKUP_KEEP_DAILY and BACKUP_KEEP_WEEKLY configure snapshots
+and retention.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	cfg := config.Load()
+	if cfg.Database.Driver != config.DriverSQLite {
+		fmt.Fprintln(os.Stderr, "backup: snapshots copy the SQLite database, back up PostgreSQL with pg_dump")
+		os.Exit(1)
+	}
+
+	logger.Init(logger.Config{
+		Level:         logger.WARN,
+		OutputType:    logger.ConsoleOutput,
+		ConsoleOutput: os.Stderr,
+		EnableColor:   cfg.Log.EnableColor,
+	})
+
+	backupConfig := backup.Config{
+		DBPath:     cfg.Database.Path,
+		UploadDir:  cfg.FileStore.UploadDir,
+		BackupDir:  cfg.Backup.Dir,
+		Compress:   cfg.Backup.Compress,
+		KeepDaily:  cfg.Backup.KeepDaily,
+		KeepWeekly: cfg.Backup.KeepWeekly,
+	}
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "snapshot":
+		err = withManager(backupConfig, snapshot)
+	case "list":
+		err = list(backupConfig.BackupDir)
+	case "verify":
+		if len(args) != 1 {
+			err = fmt.Errorf("usage: verify FILE")
+			break
+		}
+		err = verify(args[0])
+	case "restore":
+		if len(args) != 1 {
+			err = fmt.Errorf("usage: restore FILE")
+			break
+		}
+		err = restore(args[0], backupConfig)
+	case "prune":
+		err = withManager(backupConfig, prune)
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// withManager opens the configured database and runs fn with a backup manager for it
+func withManager(cfg backup.Config, fn func(m *backup.Manager) error) error {
+	if _, err := os.Stat(cfg.DBPath); err != nil {
+		return fmt.Errorf("database %s: %w", cfg.DBPath, err)
+	}
+
+	db, err := sqlite.New(sqlite.Config{DBPath: cfg.DBPath})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := backup.NewManager(db.DB, cfg)
+	if err != nil {
+		return err
+	}
+	return fn(m)
+}
+
+// snapshot takes a snapshot and prints what went into it
+func snapshot(m *backup.Manager) error {
+	snapshot, manifest, err := m.Create()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s (%d bytes, schema version %d)\n", snapshot.Path, snapshot.Size, manifest.SchemaVersion)
+	fmt.Printf("%d upload files included\n", len(manifest.Uploads))
+	for _, missing := range manifest.MissingUploads {
+		fmt.Printf("  missing upload: %s\n", missing)
+	}
+	return nil
+}
+
+// list prints the snapshots in dir
+func list(dir string) error {
+	snapshots, err := backup.List(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Printf("No snapshots in %s\n", dir)
+		return nil
+	}
+	for _, snapshot := range snapshots {
+		fmt.Printf("%s  %s  %d bytes\n", snapshot.CreatedAt.Format("2006-01-02 15:04:05 MST"), snapshot.Name, snapshot.Size)
+	}
+	return nil
+}
+
+// verify checks a snapshot and prints its manifest summary
+func verify(path string) error {
+	manifest, err := backup.Verify(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is valid: taken %s, schema version %d, %d upload files\n",
+		path, manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"), manifest.SchemaVersion, len(manifest.Uploads))
+	return nil
+}
+
+// restore restores a snapshot over the configured database and uploads
+func restore(path string, cfg backup.Config) error {
+	manifest, err := backup.Restore(path, cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %s into %s, schema version %d, %d upload files\n", path, cfg.DBPath, manifest.SchemaVersion, len(manifest.Uploads))
+	fmt.Println("The previous database was kept next to it with a .pre-restore suffix")
+	return nil
+}
+
+// prune applies the retention policy and prints the deleted snapshots
+func prune(m *backup.Manager) error {
+	deleted, err := m.Prune()
+	for _, snapshot := range deleted {
+		fmt.Printf("Deleted %s\n", snapshot.Name)
+	}
+	if err == nil && len(deleted) == 0 {
+		fmt.Println("Nothing to prune")
+	}
+	return err
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Athooh/social-network/internal/config"
+	"github.com/Athooh/social-network/pkg/db/migration"
+	"github.com/Athooh/social-network/pkg/db/postgres"
+	"github.com/Athooh/social-network/pkg/db/sqlite"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+const usage = `Usage: go run ./cmd/migrate <command> [arguments]
+
+Commands:
+  generate      write SQLite and PostgreSQL migration files for model changes that have none yet
+  diff          print the SQL generate would write, without writing anything
+  up            apply all pending migrations
+  down N        roll back the last N migrations
+  status        show the database version and which migrations are applied
+  force V       set the database version to V and clear the dirty flag (-1 for none)
+
+DB_DRIVER (sqlite or postgres) selects the database up, down, status and force work on,
+found at DB_PATH or DATABASE_URL. MIGRATIONS_PATH overrides its migrations directory.
+generate and diff plan changes against SQLITE_MIGRATIONS_PATH and render them for
+POSTGRES_MIGRATIONS_PATH as well.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	cfg := config.Load()
+
+	logger.Init(logger.Config{
+		Level:         logger.WARN,
+		OutputType:    logger.ConsoleOutput,
+		ConsoleOutput: os.Stderr,
+		EnableColor:   cfg.Log.EnableColor,
+	})
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "generate":
+		err = generate(cfg.Database, false)
+	case "diff":
+		err = generate(cfg.Database, true)
+	case "up":
+		err = withMigrator(cfg, func(m *migration.Migrator) error {
+			if err := m.Verify(); err != nil {
+				return err
+			}
+			if err := m.Up(); err != nil {
+				return err
+			}
+			return printStatus(m)
+		})
+	case "down":
+		n, parseErr := intArg(args, "down N")
+		if parseErr != nil {
+			err = parseErr
+			break
+		}
+		err = withMigrator(cfg, func(m *migration.Migrator) error {
+			if err := m.Down(n); err != nil {
+				return err
+			}
+			return printStatus(m)
+		})
+	case "status":
+		err = withMigrator(cfg, printStatus)
+	case "force":
+		version, parseErr := intArg(args, "force V")
+		if parseErr != nil {
+			err = parseErr
+			break
+		}
+		err = withMigrator(cfg, func(m *migration.Migrator) error {
+			if err := m.Force(version); err != nil {
+				return err
+			}
+			return printStatus(m)
+		})
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate plans migrations for model changes on top of the committed SQLite migrations,
+// renders them for PostgreSQL and either prints them or writes them to both directories.
+// The first PostgreSQL migration is a baseline of the whole schema.
+func generate(cfg config.DatabaseConfig, dryRun bool) error {
+	migrations, err := sqlite.DiffMigrations(cfg.SQLiteMigrationsPath)
+	if err != nil {
+		return err
+	}
+
+	hasPostgres, err := hasMigrations(cfg.PostgresMigrationsPath)
+	if err != nil {
+		return err
+	}
+
+	var pgMigrations []sqlite.Migration
+	if hasPostgres {
+		pgMigrations = postgres.RenderMigrations(migrations)
+	} else {
+		tables, err := sqlite.ModelTables()
+		if err != nil {
+			return err
+		}
+		pgMigrations = []sqlite.Migration{postgres.InitialMigration(tables)}
+	}
+
+	if len(migrations) == 0 && len(pgMigrations) == 0 {
+		fmt.Println("Models match the committed migrations, nothing to generate")
+		return nil
+	}
+
+	if dryRun {
+		printMigrations("sqlite", migrations)
+		printMigrations("postgres", pgMigrations)
+		return nil
+	}
+
+	targets := []struct {
+		dir        string
+		migrations []sqlite.Migration
+	}{
+		{cfg.SQLiteMigrationsPath, migrations},
+		{cfg.PostgresMigrationsPath, pgMigrations},
+	}
+	for _, target := range targets {
+		files, err := sqlite.WriteMigrations(target.dir, target.migrations)
+		for _, file := range files {
+			fmt.Printf("Created %s\n", filepath.Join(target.dir, file))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printMigrations prints the SQL of migrations for one dialect
+func printMigrations(dialect string, migrations []sqlite.Migration) {
+	for _, migration := range migrations {
+		fmt.Printf("-- ==== %s: %s (up) ====\n%s\n", dialect, migration.Name, migration.Up)
+		fmt.Printf("-- ==== %s: %s (down) ====\n%s\n\n", dialect, migration.Name, migration.Down)
+	}
+}
+
+// hasMigrations checks if dir holds any up migration
+func hasMigrations(dir string) (bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	return len(files) > 0, err
+}
+
+// withMigrator opens the configured database and runs fn with a migrator for it
+func withMigrator(cfg config.Config, fn func(m *migration.Migrator) error) error {
+	var m *migration.Migrator
+	switch cfg.Database.Driver {
+	case config.DriverSQLite:
+		db, err := sqlite.New(sqlite.Config{
+			DBPath:         cfg.Database.Path,
+			MigrationsPath: cfg.Database.MigrationsPath,
+		})
+		if err != nil {
+			return err
+		}
+
+		m, err = sqlite.NewMigrator(db.DB, cfg.Database.MigrationsPath)
+		if err != nil {
+			db.Close()
+			return err
+		}
+	case config.DriverPostgres:
+		db, err := postgres.New(postgres.Config{
+			URL:            cfg.Database.URL,
+			MigrationsPath: cfg.Database.MigrationsPath,
+		})
+		if err != nil {
+			return err
+		}
+
+		m, err = postgres.NewMigrator(db.DB, cfg.Database.MigrationsPath)
+		if err != nil {
+			db.Close()
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown DB_DRIVER %q, use %s or %s", cfg.Database.Driver, config.DriverSQLite, config.DriverPostgres)
+	}
+	defer m.Close()
+
+	return fn(m)
+}
+
+// printStatus prints the database version followed by every migration
+func printStatus(m *migration.Migrator) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	state := "clean"
+	if status.Dirty {
+		state = "dirty"
+	}
+	if !status.Known {
+		state += ", unknown to this migrations directory"
+	}
+	fmt.Printf("Version: %d (%s)\n", status.Version, state)
+
+	for _, migration := range status.Migrations {
+		mark := " "
+		if migration.Applied {
+			mark = "x"
+		}
+		fmt.Printf("  [%s] %s\n", mark, migration.Name)
+	}
+	fmt.Printf("%d pending\n", len(status.Pending()))
+
+	return nil
+}
+
+// intArg parses the single integer argument of a command
+func intArg(args []string, form string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("usage: %s", form)
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("usage: %s: %w", form, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Athooh/social-network/internal/config"
+	"github.com/Athooh/social-network/internal/stats"
+	"github.com/Athooh/social-network/internal/storage"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+const usage = `Usage: go run ./cmd/stats <command>
+
+Commands:
+  check   list the user stats and post counters that differ from the rows they count
+  repair  recompute the counters that have drifted, safe while the server runs
+
+DB_DRIVER, DB_PATH and DATABASE_URL select the database. Users see stats repaired here
+the next time they load them, POST /api/admin/stats/reconcile on a running server also
+pushes the corrected values to connected clients.
+`
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	cfg := config.Load()
+
+	logger.Init(logger.Config{
+		Level:         logger.WARN,
+		OutputType:    logger.ConsoleOutput,
+		ConsoleOutput: os.Stderr,
+		EnableColor:   cfg.Log.EnableColor,
+	})
+
+	var err error
+	switch command := os.Args[1]; command {
+	case "check":
+		err = withReconciler(cfg.Database, check)
+	case "repair":
+		err = withReconciler(cfg.Database, repair)
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "stats: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// withReconciler opens the configured database and runs fn with a reconciler for it
+func withReconciler(cfg config.DatabaseConfig, fn func(r *stats.Reconciler) error) error {
+	db, repos, err := storage.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return fn(stats.NewReconciler(repos.Stats, nil))
+}
+
+// check prints the counters that have drifted
+func check(r *stats.Reconciler) error {
+	report, err := r.Check()
+	if err != nil {
+		return err
+	}
+
+	printReport(report)
+	if report.Drifted() {
+		fmt.Println("Run repair to fix them")
+	}
+	return nil
+}
+
+// repair fixes the counters that have drifted and prints what it changed
+func repair(r *stats.Reconciler) error {
+	report, err := r.Repair()
+	if report != nil {
+		printReport(report)
+	}
+	if err == nil && report.Drifted() {
+		fmt.Printf("Repaired %d users and %d posts\n", len(report.Users), len(report.Posts))
+	}
+	return err
+}
+
+// printReport lists every drifted counter as stored -> actual
+func printReport(report *stats.Report) {
+	if !report.Drifted() {
+		fmt.Println("All counters match")
+		return
+	}
+
+	for _, d := range report.Users {
+		fmt.Printf("user %s:", d.UserID)
+		printChange("posts", d.Stored.PostsCount, d.Actual.PostsCount)
+		printChange("groups", d.Stored.GroupsJoined, d.Actual.GroupsJoined)
+		printChange("followers", d.Stored.FollowersCount, d.Actual.FollowersCount)
+		printChange("following", d.Stored.FollowingCount, d.Actual.FollowingCount)
+		fmt.Println()
+	}
+
+	for _, d := range report.Posts {
+		fmt.Printf("%s %d:", d.Table, d.PostID)
+		printChange("likes", d.Stored.LikesCount, d.Actual.LikesCount)
+		printChange("comments", d.Stored.CommentsCount, d.Actual.CommentsCount)
+		fmt.Println()
+	}
+}
+
+// printChange prints a counter if it differs
+func printChange(name string, stored, actual int) {
+	if stored != actual {
+		fmt.Printf(" %s %d -> %d", name, stored, actual)
+	}
+}
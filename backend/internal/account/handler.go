@@ -0,0 +1,121 @@
+package account
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Athooh/social-network/internal/auth"
+	"github.com/Athooh/social-network/pkg/httputil"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+// Handler handles HTTP requests for deactivating and deleting the current account
+type Handler struct {
+	service *Service
+	log     *logger.Logger
+}
+
+// NewHandler creates a new account handler
+func NewHandler(service *Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     log,
+	}
+}
+
+// DeletionResponse is a scheduled account deletion as returned by the API
+type DeletionResponse struct {
+	Content     string    `json:"content"`
+	RequestedAt time.Time `json:"requestedAt"`
+	DeleteAt    time.Time `json:"deleteAt"`
+}
+
+// HandleDeactivate deactivates the current account after checking the password. The user
+// is logged out everywhere, logging in again reactivates the account.
+func (h *Handler) HandleDeactivate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var request struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.Deactivate(r.Context(), userID, request.Password); err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			h.sendError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.sendError(w, http.StatusInternalServerError, "Failed to deactivate account")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Account deactivated, log in again to reactivate it"})
+}
+
+// HandleDelete schedules the deletion of the current account after checking the password.
+// The account is deactivated right away and deleted once the grace period ends, unless
+// the user logs in again before.
+func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var request struct {
+		Password string `json:"password"`
+		Content  string `json:"content"` // "anonymize" or "remove"
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	deletion, err := h.service.RequestDeletion(r.Context(), userID, request.Password, request.Content)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidContent):
+			h.sendError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ErrWrongPassword):
+			h.sendError(w, http.StatusForbidden, err.Error())
+		default:
+			h.sendError(w, http.StatusInternalServerError, "Failed to delete account")
+		}
+		return
+	}
+
+	h.sendJSON(w, http.StatusAccepted, DeletionResponse{
+		Content:     deletion.Content,
+		RequestedAt: deletion.RequestedAt,
+		DeleteAt:    deletion.DeleteAt,
+	})
+}
+
+// Helper method to send JSON responses
+func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	httputil.SendJSON(w, status, data)
+}
+
+// Helper method to send error responses
+func (h *Handler) sendError(w http.ResponseWriter, status int, message string) {
+	httputil.SendError(w, status, message, status < 500)
+}
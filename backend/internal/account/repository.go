@@ -0,0 +1,410 @@
+package account
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Athooh/social-network/internal/group"
+	"github.com/Athooh/social-network/pkg/db/uow"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/user"
+)
+
+// Repository stores deactivations and scheduled deletions, and erases the data of deleted
+// accounts. The Delete methods return the uploaded files the deleted rows referred to so
+// that they can be removed once the deletion is committed.
+type Repository interface {
+	// Deactivate hides an account, it stays deactivated since the first time
+	Deactivate(userID string, at time.Time) error
+	// ScheduleDeletion schedules the deletion of an account, replacing an earlier request
+	ScheduleDeletion(deletion *models.AccountDeletion) error
+	// GetDeletion gets the scheduled deletion of an account, nil if there is none
+	GetDeletion(userID string) (*models.AccountDeletion, error)
+
+	// GetOwnedGroups gets the groups the user created
+	GetOwnedGroups(userID string) ([]*models.Group, error)
+	// GetGroupSuccessor picks the member a group goes to when its creator leaves: admins
+	// before moderators before anyone else, longest members first. Empty if there is none.
+	GetGroupSuccessor(groupID, userID string) (string, error)
+
+	DeletePosts(userID string) ([]string, error)
+	DeleteReactions(userID string) error
+	// DeleteComments deletes the user's comments together with the replies to them
+	DeleteComments(userID string) ([]string, error)
+	// DeleteMessages deletes the private and group chat messages the user sent
+	DeleteMessages(userID string) error
+	// DeleteConnections deletes everything tying the user to others, such as follows,
+	// blocks, group memberships, notifications and sessions
+	DeleteConnections(userID string) error
+	// Anonymize erases the personal data of the user row and profile and marks the account
+	// deleted. The row stays so that what they leave behind keeps an author.
+	Anonymize(userID string, at time.Time) ([]string, error)
+
+	// WithTx binds the repository to a unit of work
+	WithTx(tx *uow.Tx) Repository
+}
+
+// SQLiteRepository implements Repository for SQLite
+type SQLiteRepository struct {
+	db *uow.DB
+}
+
+// NewSQLiteRepository creates a new SQLite repository
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: uow.NewDB(db)}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *SQLiteRepository) WithTx(tx *uow.Tx) Repository {
+	return r.withTx(tx)
+}
+
+func (r *SQLiteRepository) withTx(tx *uow.Tx) *SQLiteRepository {
+	return &SQLiteRepository{db: r.db.WithTx(tx)}
+}
+
+// Deactivate hides an account until its user logs in again
+func (r *SQLiteRepository) Deactivate(userID string, at time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE users SET deactivated_at = COALESCE(deactivated_at, ?), updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, at.UTC(), at.UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate account: %w", err)
+	}
+	return nil
+}
+
+// ScheduleDeletion schedules the deletion of an account
+func (r *SQLiteRepository) ScheduleDeletion(deletion *models.AccountDeletion) error {
+	_, err := r.db.Exec(`
+		INSERT INTO account_deletions (user_id, content, requested_at, delete_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			content = excluded.content,
+			requested_at = excluded.requested_at,
+			delete_at = excluded.delete_at
+	`, deletion.UserID, deletion.Content, deletion.RequestedAt.UTC(), deletion.DeleteAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+	return nil
+}
+
+// GetDeletion gets the scheduled deletion of an account
+func (r *SQLiteRepository) GetDeletion(userID string) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	err := r.db.QueryRow(`
+		SELECT user_id, content, requested_at, delete_at FROM account_deletions WHERE user_id = ?
+	`, userID).Scan(&deletion.UserID, &deletion.Content, &deletion.RequestedAt, &deletion.DeleteAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account deletion: %w", err)
+	}
+	return &deletion, nil
+}
+
+// GetOwnedGroups gets the groups the user created
+func (r *SQLiteRepository) GetOwnedGroups(userID string) ([]*models.Group, error) {
+	rows, err := r.db.Query(`
+		SELECT id, name, banner_path, profile_pic_path FROM groups WHERE creator_id = ?
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get owned groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*models.Group
+	for rows.Next() {
+		g := &models.Group{CreatorID: userID}
+		if err := rows.Scan(&g.ID, &g.Name, &g.BannerPath, &g.ProfilePicPath); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// GetGroupSuccessor picks the member a group goes to when its creator leaves. Deactivated
+// members are passed over, they might be on their way out too.
+func (r *SQLiteRepository) GetGroupSuccessor(groupID, userID string) (string, error) {
+	var successor string
+	err := r.db.QueryRow(`
+		SELECT user_id FROM group_members
+		WHERE group_id = ? AND user_id != ? AND status = 'accepted'
+			AND user_id NOT IN (`+user.DeactivatedUsersSQL+`)
+		ORDER BY CASE role WHEN ? THEN 0 WHEN ? THEN 1 ELSE 2 END, created_at, id
+		LIMIT 1
+	`, groupID, userID, group.RoleAdmin, group.RoleModerator).Scan(&successor)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get group successor: %w", err)
+	}
+	return successor, nil
+}
+
+// ownPostsSQL selects the IDs of the user's posts and group posts, it takes the user ID twice
+const ownPostsSQL = `SELECT id FROM posts WHERE user_id = ? UNION SELECT id FROM group_posts WHERE user_id = ?`
+
+// DeletePosts deletes the user's posts and group posts with their tags, viewers and
+// revisions, and the comments and reactions others left on them. Reshares of their posts
+// stay and are shown as unavailable, like after deleting a single post.
+func (r *SQLiteRepository) DeletePosts(userID string) ([]string, error) {
+	media, err := r.queryMedia(`
+		SELECT image_path, video_path FROM posts WHERE user_id = ?
+		UNION ALL
+		SELECT image_path, video_path FROM group_posts WHERE user_id = ?
+		UNION ALL
+		SELECT image_path, NULL FROM comments WHERE post_id IN (`+ownPostsSQL+`)
+	`, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The posts they reshared lose those shares
+	_, err = r.db.Exec(`
+		UPDATE posts
+		SET shares_count = (
+			SELECT COUNT(*) FROM posts s WHERE s.shared_post_id = posts.id AND s.user_id != ?
+		)
+		WHERE id IN (SELECT shared_post_id FROM posts WHERE user_id = ? AND shared_post_id IS NOT NULL)
+	`, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shares count: %w", err)
+	}
+
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		// Reshares by others are tombstoned before the originals go
+		{"UPDATE posts SET shared_post_id = ? WHERE shared_post_id IN (SELECT id FROM posts WHERE user_id = ?) AND user_id != ?", []interface{}{models.DeletedSharedPostID, userID, userID}},
+		{"DELETE FROM comment_likes WHERE comment_id IN (SELECT id FROM comments WHERE post_id IN (" + ownPostsSQL + "))", []interface{}{userID, userID}},
+		{`
+			DELETE FROM post_revisions
+			WHERE target_type = ? AND target_id IN (SELECT id FROM comments WHERE post_id IN (` + ownPostsSQL + `))
+		`, []interface{}{models.RevisionTargetComment, userID, userID}},
+		{"DELETE FROM comments WHERE post_id IN (" + ownPostsSQL + ")", []interface{}{userID, userID}},
+		{"DELETE FROM post_likes WHERE post_id IN (" + ownPostsSQL + ")", []interface{}{userID, userID}},
+		{"DELETE FROM post_tags WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?)", []interface{}{userID}},
+		{"DELETE FROM post_viewers WHERE post_id IN (SELECT id FROM posts WHERE user_id = ?)", []interface{}{userID}},
+		{`
+			DELETE FROM post_revisions
+			WHERE (target_type = ? AND target_id IN (SELECT id FROM posts WHERE user_id = ?))
+				OR (target_type = ? AND target_id IN (SELECT id FROM group_posts WHERE user_id = ?))
+		`, []interface{}{models.RevisionTargetPost, userID, models.RevisionTargetGroupPost, userID}},
+		{"DELETE FROM posts WHERE user_id = ?", []interface{}{userID}},
+		{"DELETE FROM group_posts WHERE user_id = ?", []interface{}{userID}},
+	}
+	for _, q := range queries {
+		if _, err := r.db.Exec(q.query, q.args...); err != nil {
+			return nil, fmt.Errorf("failed to delete posts: %w", err)
+		}
+	}
+
+	return media, nil
+}
+
+// DeleteReactions deletes the user's reactions to posts and likes of comments. The likes
+// count of posts is left to the stats reconciler, the one of comments is updated here.
+func (r *SQLiteRepository) DeleteReactions(userID string) error {
+	_, err := r.db.Exec(`
+		UPDATE comments
+		SET likes_count = (
+			SELECT COUNT(*) FROM comment_likes l WHERE l.comment_id = comments.id AND l.user_id != ?
+		)
+		WHERE id IN (SELECT comment_id FROM comment_likes WHERE user_id = ?)
+	`, userID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update comment likes count: %w", err)
+	}
+
+	if _, err := r.db.Exec("DELETE FROM comment_likes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete comment likes: %w", err)
+	}
+	if _, err := r.db.Exec("DELETE FROM post_likes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete reactions: %w", err)
+	}
+	return nil
+}
+
+// threadsSQL selects the IDs of the user's comments and every reply below them, like
+// deleting a single comment removes its whole thread
+const threadsSQL = `
+	WITH RECURSIVE thread(id) AS (
+		SELECT id FROM comments WHERE user_id = ?
+		UNION
+		SELECT c.id FROM comments c JOIN thread t ON c.parent_id = t.id
+	)
+`
+
+// DeleteComments deletes the user's comments together with the replies to them. The
+// comments count of posts is left to the stats reconciler.
+func (r *SQLiteRepository) DeleteComments(userID string) ([]string, error) {
+	media, err := r.queryMedia(threadsSQL+`
+		SELECT image_path, NULL FROM comments WHERE id IN (SELECT id FROM thread)
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{threadsSQL + "DELETE FROM comment_likes WHERE comment_id IN (SELECT id FROM thread)", []interface{}{userID}},
+		{threadsSQL + `
+			DELETE FROM post_revisions WHERE target_type = ? AND target_id IN (SELECT id FROM thread)
+		`, []interface{}{userID, models.RevisionTargetComment}},
+		// Comments others wrote lose the replies that go away
+		{threadsSQL + `
+			UPDATE comments
+			SET replies_count = (
+				SELECT COUNT(*) FROM comments r
+				WHERE r.parent_id = comments.id AND r.id NOT IN (SELECT id FROM thread)
+			)
+			WHERE id IN (SELECT parent_id FROM comments WHERE id IN (SELECT id FROM thread))
+				AND id NOT IN (SELECT id FROM thread)
+		`, []interface{}{userID}},
+		{threadsSQL + "DELETE FROM comments WHERE id IN (SELECT id FROM thread)", []interface{}{userID}},
+	}
+	for _, q := range queries {
+		if _, err := r.db.Exec(q.query, q.args...); err != nil {
+			return nil, fmt.Errorf("failed to delete comments: %w", err)
+		}
+	}
+
+	return media, nil
+}
+
+// DeleteMessages deletes the private and group chat messages the user sent. The messages
+// their conversation partners sent them stay in the partners' history.
+func (r *SQLiteRepository) DeleteMessages(userID string) error {
+	if _, err := r.db.Exec("DELETE FROM private_messages WHERE sender_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete messages: %w", err)
+	}
+	if _, err := r.db.Exec("DELETE FROM group_chat_messages WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete group messages: %w", err)
+	}
+	return nil
+}
+
+// DeleteConnections deletes everything tying the user to others. Follower and group
+// counts are left to the stats reconciler.
+func (r *SQLiteRepository) DeleteConnections(userID string) error {
+	queries := []struct {
+		table string
+		where string
+	}{
+		{"followers", "follower_id = ? OR following_id = ?"},
+		{"follow_requests", "follower_id = ? OR following_id = ?"},
+		{"user_blocks", "blocker_id = ? OR blocked_id = ?"},
+		{"user_mutes", "muter_id = ? OR muted_id = ?"},
+		{"audience_list_members", "user_id = ? OR list_id IN (SELECT id FROM audience_lists WHERE owner_id = ?)"},
+		{"audience_lists", "owner_id = ?"},
+		{"post_viewers", "user_id = ?"},
+		{"group_members", "user_id = ?"},
+		{"group_bans", "user_id = ?"},
+		{"group_mutes", "user_id = ?"},
+		{"group_join_answers", "user_id = ?"},
+		{"event_responses", "user_id = ?"},
+		{"notifications", "user_id = ? OR sender_id = ?"},
+		{"chat_contacts", "user_id = ?"},
+		{"calendar_feed_tokens", "user_id = ?"},
+		{"sessions", "user_id = ?"},
+		{"user_status", "user_id = ?"},
+		{"user_stats", "user_id = ?"},
+	}
+	for _, q := range queries {
+		args := make([]interface{}, strings.Count(q.where, "?"))
+		for i := range args {
+			args[i] = userID
+		}
+		if _, err := r.db.Exec("DELETE FROM "+q.table+" WHERE "+q.where, args...); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", q.table, err)
+		}
+	}
+
+	// Exports can no longer be downloaded, their expiry jobs still delete the archives
+	_, err := r.db.Exec(`
+		UPDATE data_exports SET status = CASE WHEN status = ? THEN ? ELSE ? END
+		WHERE user_id = ? AND status IN (?, ?)
+	`, models.ExportPending, models.ExportFailed, models.ExportExpired, userID, models.ExportPending, models.ExportReady)
+	if err != nil {
+		return fmt.Errorf("failed to expire data exports: %w", err)
+	}
+
+	return nil
+}
+
+// Anonymize erases the personal data of the user row and profile and marks the account
+// deleted
+func (r *SQLiteRepository) Anonymize(userID string, at time.Time) ([]string, error) {
+	media, err := r.queryMedia(`
+		SELECT avatar, NULL FROM users WHERE id = ?
+		UNION ALL
+		SELECT banner_image, profile_image FROM user_profiles WHERE user_id = ?
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The email stays unique and can't be used to log in, and no password matches ''
+	_, err = r.db.Exec(`
+		UPDATE users SET
+			email = ?, password = '', first_name = 'Deleted', last_name = 'user', date_of_birth = '',
+			avatar = '', nickname = '', about_me = '', is_public = FALSE,
+			deactivated_at = COALESCE(deactivated_at, ?), deleted_at = ?, updated_at = ?
+		WHERE id = ?
+	`, "deleted-"+userID+"@deleted.invalid", at.UTC(), at.UTC(), at.UTC(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE user_profiles SET
+			banner_image = NULL, profile_image = NULL, username = NULL, full_name = 'Deleted user',
+			bio = NULL, work = NULL, education = NULL, email = NULL, phone = NULL, website = NULL,
+			location = NULL, tech_skills = NULL, soft_skills = NULL, interests = NULL,
+			is_private = TRUE, updated_at = ?
+		WHERE user_id = ?
+	`, at.UTC(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anonymize profile: %w", err)
+	}
+
+	if _, err := r.db.Exec("DELETE FROM account_deletions WHERE user_id = ?", userID); err != nil {
+		return nil, fmt.Errorf("failed to complete account deletion: %w", err)
+	}
+
+	return media, nil
+}
+
+// queryMedia runs a query selecting two columns of file paths and returns the ones set
+func (r *SQLiteRepository) queryMedia(query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get media: %w", err)
+	}
+	defer rows.Close()
+
+	var media []string
+	for rows.Next() {
+		var first, second sql.NullString
+		if err := rows.Scan(&first, &second); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		for _, path := range []sql.NullString{first, second} {
+			if path.Valid && path.String != "" {
+				media = append(media, path.String)
+			}
+		}
+	}
+	return media, rows.Err()
+}
@@ -0,0 +1,304 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Athooh/social-network/internal/group"
+	notifications "github.com/Athooh/social-network/internal/notifcations"
+	"github.com/Athooh/social-network/internal/stats"
+	"github.com/Athooh/social-network/pkg/db/uow"
+	"github.com/Athooh/social-network/pkg/filestore"
+	"github.com/Athooh/social-network/pkg/logger"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/scheduler"
+	"github.com/Athooh/social-network/pkg/session"
+	"github.com/Athooh/social-network/pkg/user"
+	"github.com/Athooh/social-network/pkg/websocket"
+)
+
+// JobAccountDeletion is the kind of the scheduled job deleting an account once its grace
+// period ended
+const JobAccountDeletion = "account_deletion"
+
+var (
+	// ErrWrongPassword is returned when the password confirming a deactivation or deletion
+	// doesn't match
+	ErrWrongPassword = errors.New("incorrect password")
+	// ErrInvalidContent is returned for content choices other than anonymize and remove
+	ErrInvalidContent = errors.New("content must be anonymize or remove")
+)
+
+// deletionJob is the payload of a JobAccountDeletion job
+type deletionJob struct {
+	UserID string `json:"userId"`
+}
+
+// Config holds the settings of the account service
+type Config struct {
+	DeletionGrace time.Duration // Time between asking for a deletion and carrying it out
+}
+
+// Service deactivates accounts and deletes them after a grace period. Logging in again
+// reactivates an account and cancels its deletion, see user.Repository.Reactivate.
+type Service struct {
+	repo           Repository
+	userRepo       user.Repository
+	statusRepo     user.StatusRepository
+	groupRepo      group.Repository
+	groupNotifier  *group.Notifications
+	work           *uow.UnitOfWork
+	sessionManager *session.SessionManager
+	hub            *websocket.Hub
+	jobs           *scheduler.Scheduler
+	reconciler     *stats.Reconciler
+	fileStore      *filestore.FileStore
+	log            *logger.Logger
+	cfg            Config
+}
+
+// NewService creates a new account service and registers its deletion job
+func NewService(repo Repository, userRepo user.Repository, statusRepo user.StatusRepository, groupRepo group.Repository, work *uow.UnitOfWork, sessionManager *session.SessionManager, hub *websocket.Hub, jobs *scheduler.Scheduler, reconciler *stats.Reconciler, fileStore *filestore.FileStore, notificationsService notifications.Service, log *logger.Logger, cfg Config) *Service {
+	s := &Service{
+		repo:           repo,
+		userRepo:       userRepo,
+		statusRepo:     statusRepo,
+		groupRepo:      groupRepo,
+		groupNotifier:  group.NewNotifications(groupRepo, hub, log, notificationsService),
+		work:           work,
+		sessionManager: sessionManager,
+		hub:            hub,
+		jobs:           jobs,
+		reconciler:     reconciler,
+		fileStore:      fileStore,
+		log:            log,
+		cfg:            cfg,
+	}
+	jobs.Handle(JobAccountDeletion, s.delete)
+	return s
+}
+
+// Deactivate hides the user's profile and content and logs them out everywhere until
+// they log in again
+func (s *Service) Deactivate(ctx context.Context, userID, password string) error {
+	if err := s.checkPassword(userID, password); err != nil {
+		return err
+	}
+
+	if err := s.repo.Deactivate(userID, time.Now()); err != nil {
+		s.log.WithContext(ctx).Error("Failed to deactivate account %s: %v", userID, err)
+		return err
+	}
+
+	s.disconnect(ctx, userID)
+	return nil
+}
+
+// RequestDeletion deactivates the account and schedules its deletion once the grace
+// period ends. content chooses whether the user's comments and messages are kept
+// anonymized or removed, their posts are deleted either way.
+func (s *Service) RequestDeletion(ctx context.Context, userID, password, content string) (*models.AccountDeletion, error) {
+	if content != models.DeletionAnonymize && content != models.DeletionRemove {
+		return nil, ErrInvalidContent
+	}
+	if err := s.checkPassword(userID, password); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	deletion := &models.AccountDeletion{
+		UserID:      userID,
+		Content:     content,
+		RequestedAt: now,
+		DeleteAt:    now.Add(s.cfg.DeletionGrace),
+	}
+
+	err := s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.Deactivate(userID, now); err != nil {
+			return err
+		}
+		return repo.ScheduleDeletion(deletion)
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to schedule deletion of account %s: %v", userID, err)
+		return nil, err
+	}
+
+	// Asking again replaces the job, the deletion happens on the latest date
+	if err := s.jobs.Schedule(JobAccountDeletion, JobAccountDeletion+":"+userID, deletion.DeleteAt, deletionJob{UserID: userID}); err != nil {
+		s.log.WithContext(ctx).Error("Failed to schedule deletion job of account %s: %v", userID, err)
+		return nil, err
+	}
+
+	s.disconnect(ctx, userID)
+	return deletion, nil
+}
+
+func (s *Service) checkPassword(userID, password string) error {
+	u, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !session.CheckPassword(u.Password, password) {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// disconnect ends every session of the user and closes their websockets
+func (s *Service) disconnect(ctx context.Context, userID string) {
+	if err := s.sessionManager.ClearAllUserSessions(userID); err != nil {
+		s.log.WithContext(ctx).Error("Failed to clear sessions of %s: %v", userID, err)
+	}
+	if err := s.statusRepo.SetUserOffline(userID); err != nil {
+		s.log.WithContext(ctx).Error("Failed to mark %s offline: %v", userID, err)
+	}
+	if s.hub != nil {
+		s.hub.CloseUserConnections(userID)
+	}
+}
+
+// delete runs a JobAccountDeletion job. Nothing happens when the user logged in again in
+// the meantime, which removed the scheduled deletion.
+func (s *Service) delete(ctx context.Context, job *scheduler.Job) error {
+	var payload deletionJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	deletion, err := s.repo.GetDeletion(payload.UserID)
+	if err != nil {
+		return err
+	}
+	if deletion == nil || time.Now().Before(deletion.DeleteAt) {
+		return nil
+	}
+
+	var media []string
+	err = s.work.Do(func(tx *uow.Tx) error {
+		var err error
+		media, err = s.deleteAccount(tx, deletion)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files can't be rolled back, so they are only removed once the rows are gone
+	for _, name := range media {
+		if err := s.fileStore.DeleteFile(name); err != nil {
+			s.log.WithContext(ctx).Warn("Failed to delete file %s of deleted account: %v", name, err)
+		}
+	}
+
+	// Counters of posts, groups and the people they followed are recomputed
+	if _, err := s.reconciler.Repair(); err != nil {
+		s.log.WithContext(ctx).Error("Failed to reconcile stats after deleting account %s: %v", deletion.UserID, err)
+	}
+
+	s.log.WithContext(ctx).Info("Deleted account %s, content %s", deletion.UserID, deletion.Content)
+	return nil
+}
+
+// deleteAccount erases the user's data in tx and returns the files it referred to
+func (s *Service) deleteAccount(tx *uow.Tx, deletion *models.AccountDeletion) ([]string, error) {
+	repo := s.repo.WithTx(tx)
+	groups := s.groupRepo.WithTx(tx)
+	userID := deletion.UserID
+
+	// Groups go to the member best placed to run them, or are deleted when nobody is left
+	owned, err := repo.GetOwnedGroups(userID)
+	if err != nil {
+		return nil, err
+	}
+	var media []string
+	for _, g := range owned {
+		successor, err := repo.GetGroupSuccessor(g.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if successor != "" {
+			if err := s.transferGroup(tx, g.ID, userID, successor); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := groups.DeleteGroup(g.ID); err != nil {
+			return nil, err
+		}
+		for _, path := range []string{g.BannerPath.String, g.ProfilePicPath.String} {
+			if path != "" {
+				media = append(media, path)
+			}
+		}
+	}
+
+	posts, err := repo.DeletePosts(userID)
+	if err != nil {
+		return nil, err
+	}
+	media = append(media, posts...)
+
+	if err := repo.DeleteReactions(userID); err != nil {
+		return nil, err
+	}
+
+	// Anonymized comments and messages stay under the user row, which is anonymized below
+	if deletion.Content == models.DeletionRemove {
+		comments, err := repo.DeleteComments(userID)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, comments...)
+
+		if err := repo.DeleteMessages(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repo.DeleteConnections(userID); err != nil {
+		return nil, err
+	}
+
+	profile, err := repo.Anonymize(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return append(media, profile...), nil
+}
+
+// transferGroup hands a group over to its successor the way its owner would, with an
+// audit log entry and members told once tx commits
+func (s *Service) transferGroup(tx *uow.Tx, groupID, ownerID, successorID string) error {
+	groups := s.groupRepo.WithTx(tx)
+
+	g, err := groups.GetGroupByID(groupID)
+	if err != nil {
+		return err
+	}
+	if err := groups.UpdateGroupOwner(groupID, successorID); err != nil {
+		return err
+	}
+	if err := groups.UpdateMemberRole(groupID, successorID, group.RoleAdmin); err != nil {
+		return err
+	}
+	err = groups.AddAuditLogEntry(&models.GroupAuditLog{
+		GroupID:      groupID,
+		ActorID:      ownerID,
+		Action:       group.AuditOwnershipTransferred,
+		TargetUserID: sql.NullString{String: successorID, Valid: true},
+		Details:      "owner deleted their account",
+	})
+	if err != nil {
+		return err
+	}
+
+	g.CreatorID = successorID
+	tx.AfterCommit(func() {
+		s.groupNotifier.NotifyGroupOwnershipTransferred(g, ownerID, successorID)
+	})
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/Athooh/social-network/pkg/httputil"
+	"github.com/Athooh/social-network/pkg/logger"
 )
 
 // contextKey is a custom type for context keys
@@ -30,12 +31,18 @@ func (s *Service) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		// Store user ID in request context
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, withUserID(r, userID))
 	})
 }
 
+// withUserID stores the user ID in the request context and adds it to the request's log
+// fields, so every message logged for the request says who made it
+func withUserID(r *http.Request, userID string) *http.Request {
+	logger.AddFields(r.Context(), "user_id", userID)
+	ctx := context.WithValue(r.Context(), UserIDKey, userID)
+	return r.WithContext(ctx)
+}
+
 // GetUserIDFromContext retrieves the user ID from the request context
 func GetUserIDFromContext(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(UserIDKey).(string)
@@ -65,8 +72,6 @@ func (s *Service) RequireJWTAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		// Store user ID in request context
-		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, withUserID(r, claims.UserID))
 	})
 }
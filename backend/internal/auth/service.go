@@ -163,6 +163,15 @@ func (s *Service) LoginWithJWT(req models.LoginRequest) (*models.TokenResponse,
 		return nil, errors.New("invalid email or password")
 	}
 
+	// Logging in again brings back a deactivated account and cancels its deletion
+	reactivated, err := s.userRepo.Reactivate(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if reactivated {
+		logger.Info("Account %s reactivated on login", user.ID)
+	}
+
 	// Generate JWT token
 	token, err := GenerateToken(user.ID, s.jwtConfig)
 	if err != nil {
@@ -0,0 +1,87 @@
+package backup
+
+import (
+	"net/http"
+
+	"github.com/Athooh/social-network/internal/auth"
+	"github.com/Athooh/social-network/pkg/backup"
+	"github.com/Athooh/social-network/pkg/httputil"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+// Handler handles HTTP requests for database backups
+type Handler struct {
+	manager *backup.Manager
+	log     *logger.Logger
+	admins  map[string]bool
+}
+
+// NewHandler creates a new backup handler that only lets adminIDs in
+func NewHandler(manager *backup.Manager, log *logger.Logger, adminIDs []string) *Handler {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+
+	return &Handler{
+		manager: manager,
+		log:     log,
+		admins:  admins,
+	}
+}
+
+// SnapshotResponse represents the response for a newly created snapshot
+type SnapshotResponse struct {
+	Snapshot backup.Snapshot  `json:"snapshot"`
+	Manifest *backup.Manifest `json:"manifest"`
+}
+
+// HandleBackups lists snapshots on GET and takes a new one on POST
+func (h *Handler) HandleBackups(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if !h.admins[userID] {
+		h.sendError(w, http.StatusForbidden, "Only administrators can manage backups")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		snapshots, err := h.manager.List()
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to list snapshots: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "Failed to list snapshots")
+			return
+		}
+		if snapshots == nil {
+			snapshots = []backup.Snapshot{}
+		}
+		h.sendJSON(w, http.StatusOK, snapshots)
+
+	case http.MethodPost:
+		snapshot, manifest, err := h.manager.Create()
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to create snapshot: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "Failed to create snapshot")
+			return
+		}
+		h.log.WithContext(r.Context()).Info("Snapshot %s created by %s", snapshot.Name, userID)
+		h.sendJSON(w, http.StatusCreated, SnapshotResponse{Snapshot: *snapshot, Manifest: manifest})
+
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Helper method to send JSON responses
+func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	httputil.SendJSON(w, status, data)
+}
+
+// Helper method to send error responses
+func (h *Handler) sendError(w http.ResponseWriter, status int, message string) {
+	httputil.SendError(w, status, message, status < 500)
+}
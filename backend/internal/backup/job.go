@@ -0,0 +1,83 @@
+package backup
+
+import (
+	"time"
+
+	"github.com/Athooh/social-network/pkg/backup"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+// Job periodically snapshots the database and applies the retention policy
+type Job struct {
+	manager  *backup.Manager
+	log      *logger.Logger
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// NewJob creates a new scheduled backup job
+func NewJob(manager *backup.Manager, log *logger.Logger, interval time.Duration) *Job {
+	return &Job{
+		manager:  manager,
+		log:      log,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Run takes a snapshot whenever the newest one is older than the interval, checking
+// every few minutes so that restarts don't skip or repeat snapshots, until Stop is called
+func (j *Job) Run() {
+	ticker := time.NewTicker(checkInterval(j.interval))
+	defer ticker.Stop()
+
+	j.RunDue(time.Now())
+	for {
+		select {
+		case <-ticker.C:
+			j.RunDue(time.Now())
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the job
+func (j *Job) Stop() {
+	close(j.stop)
+}
+
+// RunDue takes a snapshot and prunes old ones if the newest snapshot is older than the interval
+func (j *Job) RunDue(now time.Time) {
+	snapshots, err := j.manager.List()
+	if err != nil {
+		j.log.Error("Failed to list snapshots: %v", err)
+		return
+	}
+	if len(snapshots) > 0 && now.Sub(snapshots[0].CreatedAt) < j.interval {
+		return
+	}
+
+	snapshot, manifest, err := j.manager.Create()
+	if err != nil {
+		j.log.Error("Failed to create scheduled snapshot: %v", err)
+		return
+	}
+	j.log.Info("Snapshot %s created, %d upload files, %d missing", snapshot.Name, len(manifest.Uploads), len(manifest.MissingUploads))
+
+	deleted, err := j.manager.Prune()
+	if err != nil {
+		j.log.Error("Failed to prune snapshots: %v", err)
+	}
+	for _, old := range deleted {
+		j.log.Info("Snapshot %s deleted by the retention policy", old.Name)
+	}
+}
+
+// checkInterval is how often the job looks for a due snapshot
+func checkInterval(interval time.Duration) time.Duration {
+	if interval < 10*time.Minute {
+		return interval
+	}
+	return 10 * time.Minute
+}
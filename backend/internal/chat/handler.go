@@ -57,9 +57,9 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send message
-	message, err := h.service.SendMessage(userID, request.ReceiverID, request.Content)
+	message, err := h.service.SendMessage(r.Context(), userID, request.ReceiverID, request.Content)
 	if err != nil {
-		h.log.Error("Failed to send message: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to send message: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -111,9 +111,9 @@ func (h *Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get messages
-	messages, err := h.service.GetMessages(userID, otherUserID, limit, offset)
+	messages, err := h.service.GetMessages(r.Context(), userID, otherUserID, limit, offset)
 	if err != nil {
-		h.log.Error("Failed to get messages: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get messages: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -153,8 +153,8 @@ func (h *Handler) MarkAsRead(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Mark messages as read
-	if err := h.service.MarkAsRead(request.SenderID, userID); err != nil {
-		h.log.Error("Failed to mark messages as read: %v", err)
+	if err := h.service.MarkAsRead(r.Context(), request.SenderID, userID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to mark messages as read: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -179,9 +179,9 @@ func (h *Handler) GetContacts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get contacts
-	contacts, err := h.service.GetContacts(userID)
+	contacts, err := h.service.GetContacts(r.Context(), userID)
 	if err != nil {
-		h.log.Error("Failed to get contacts: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get contacts: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -221,8 +221,8 @@ func (h *Handler) SendTypingIndicator(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send typing indicator via WebSocket
-	if err := h.service.SendTypingIndicator(userID, request.ReceiverID); err != nil {
-		h.log.Error("Failed to send typing indicator: %v", err)
+	if err := h.service.SendTypingIndicator(r.Context(), userID, request.ReceiverID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to send typing indicator: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -0,0 +1,17 @@
+package chat
+
+import (
+	"database/sql"
+)
+
+// PostgresRepository implements the Repository interface for PostgreSQL. It runs the same
+// queries as SQLiteRepository, which stick to SQL both databases understand.
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository for chat, db must come from
+// postgres.New so that the ? placeholders are rewritten
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &PostgresRepository{&SQLiteRepository{db: db}}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/user"
 )
 
 // Repository defines the chat repository interface
@@ -110,7 +111,7 @@ func (r *SQLiteRepository) GetUnreadMessagesCount(userID string) (map[string]int
 	query := `
 		SELECT sender_id, COUNT(*) as count
 		FROM private_messages
-		WHERE receiver_id = ? AND is_read = 0
+		WHERE receiver_id = ? AND is_read = FALSE
 		GROUP BY sender_id
 	`
 
@@ -139,8 +140,8 @@ func (r *SQLiteRepository) GetUnreadMessagesCount(userID string) (map[string]int
 func (r *SQLiteRepository) MarkMessagesAsRead(senderID, receiverID string) error {
 	query := `
 		UPDATE private_messages
-		SET is_read = 1, read_at = CURRENT_TIMESTAMP
-		WHERE sender_id = ? AND receiver_id = ? AND is_read = 0
+		SET is_read = TRUE, read_at = CURRENT_TIMESTAMP
+		WHERE sender_id = ? AND receiver_id = ? AND is_read = FALSE
 	`
 
 	_, err := r.db.Exec(query, senderID, receiverID)
@@ -194,13 +195,14 @@ func (r *SQLiteRepository) GetChatContacts(userID string) ([]*models.ChatContact
 					(f.follower_id = cc.contact_id AND f.following_id = ?) OR
 					(f.follower_id = ? AND f.following_id = cc.contact_id)
 			)
+			AND cc.contact_id NOT IN (` + user.BlockedUsersSQL + `)
 		)
 		SELECT 
 			u.id,
 			u.first_name,
 			u.last_name,
 			u.avatar,
-			COALESCE(us.is_online, 0) as is_online,
+			COALESCE(us.is_online, FALSE) as is_online,
 			(
 				SELECT content
 				FROM private_messages
@@ -225,7 +227,7 @@ func (r *SQLiteRepository) GetChatContacts(userID string) ([]*models.ChatContact
 			(
 				SELECT COUNT(*)
 				FROM private_messages
-				WHERE sender_id = u.id AND receiver_id = ? AND is_read = 0
+				WHERE sender_id = u.id AND receiver_id = ? AND is_read = FALSE
 			) as unread_count
 		FROM chat_users cu
 		JOIN users u ON cu.contact_id = u.id
@@ -238,6 +240,7 @@ func (r *SQLiteRepository) GetChatContacts(userID string) ([]*models.ChatContact
 		userID, userID, userID, // for private_messages
 		userID, userID, userID, // for followers union
 		userID, userID,         // for follow-check condition
+		userID, userID,         // for blocked users
 		userID, userID,         // for last_message
 		userID, userID,         // for last_message_sender_id
 		userID, userID,         // for last_sent
@@ -288,6 +291,18 @@ func (r *SQLiteRepository) GetChatContacts(userID string) ([]*models.ChatContact
 
 // CanSendMessage checks if a user can send a message to another user
 func (r *SQLiteRepository) CanSendMessage(senderID, receiverID string) (bool, error) {
+	// Blocked users cannot message each other, whatever else holds
+	blocked, err := user.IsBlocked(r.db, senderID, receiverID)
+	if err != nil || blocked {
+		return false, err
+	}
+
+	// Nor can anyone message a deactivated account
+	deactivated, err := user.IsDeactivated(r.db, receiverID)
+	if err != nil || deactivated {
+		return false, err
+	}
+
 	// Check if the sender follows the receiver or vice versa
 	query := `
 		SELECT EXISTS (
@@ -295,12 +310,12 @@ func (r *SQLiteRepository) CanSendMessage(senderID, receiverID string) (bool, er
 			WHERE (follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?)
 		) OR EXISTS (
 			SELECT 1 FROM users
-			WHERE id = ? AND is_public = 1
+			WHERE id = ? AND is_public = TRUE
 		)
 	`
 
 	var canSend bool
-	err := r.db.QueryRow(query, senderID, receiverID, receiverID, senderID, receiverID).Scan(&canSend)
+	err = r.db.QueryRow(query, senderID, receiverID, receiverID, senderID, receiverID).Scan(&canSend)
 	return canSend, err
 }
 
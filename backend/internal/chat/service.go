@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -12,15 +13,15 @@ import (
 // Service defines the chat service interface
 type Service interface {
 	// Message operations
-	SendMessage(senderID, receiverID, content string) (*models.PrivateMessage, error)
-	GetMessages(userID1, userID2 string, limit, offset int) ([]*models.PrivateMessage, error)
-	MarkAsRead(senderID, receiverID string) error
+	SendMessage(ctx context.Context, senderID, receiverID, content string) (*models.PrivateMessage, error)
+	GetMessages(ctx context.Context, userID1, userID2 string, limit, offset int) ([]*models.PrivateMessage, error)
+	MarkAsRead(ctx context.Context, senderID, receiverID string) error
 
 	// Contact operations
-	GetContacts(userID string) ([]*models.ChatContact, error)
+	GetContacts(ctx context.Context, userID string) ([]*models.ChatContact, error)
 
 	// Typing indicator
-	SendTypingIndicator(senderID, receiverID string) error
+	SendTypingIndicator(ctx context.Context, senderID, receiverID string) error
 }
 
 // ChatService implements the Service interface
@@ -42,7 +43,7 @@ func NewService(repo Repository, log *logger.Logger, wsHub *websocket.Hub) Servi
 }
 
 // SendMessage sends a private message from one user to another
-func (s *ChatService) SendMessage(senderID, receiverID, content string) (*models.PrivateMessage, error) {
+func (s *ChatService) SendMessage(ctx context.Context, senderID, receiverID, content string) (*models.PrivateMessage, error) {
 	// Check if users can message each other
 	canSend, err := s.repo.CanSendMessage(senderID, receiverID)
 	if err != nil {
@@ -85,7 +86,7 @@ func (s *ChatService) SendMessage(senderID, receiverID, content string) (*models
 }
 
 // GetMessages gets messages between two users with pagination
-func (s *ChatService) GetMessages(userID1, userID2 string, limit, offset int) ([]*models.PrivateMessage, error) {
+func (s *ChatService) GetMessages(ctx context.Context, userID1, userID2 string, limit, offset int) ([]*models.PrivateMessage, error) {
 	// Check if users can view messages
 	canSend, err := s.repo.CanSendMessage(userID1, userID2)
 	if err != nil {
@@ -106,7 +107,7 @@ func (s *ChatService) GetMessages(userID1, userID2 string, limit, offset int) ([
 }
 
 // MarkAsRead marks messages from a sender to a receiver as read
-func (s *ChatService) MarkAsRead(senderID, receiverID string) error {
+func (s *ChatService) MarkAsRead(ctx context.Context, senderID, receiverID string) error {
 	// Check if users can view messages
 	canSend, err := s.repo.CanSendMessage(receiverID, senderID)
 	if err != nil {
@@ -129,12 +130,12 @@ func (s *ChatService) MarkAsRead(senderID, receiverID string) error {
 }
 
 // GetContacts gets all users that the current user can chat with
-func (s *ChatService) GetContacts(userID string) ([]*models.ChatContact, error) {
+func (s *ChatService) GetContacts(ctx context.Context, userID string) ([]*models.ChatContact, error) {
 	return s.repo.GetChatContacts(userID)
 }
 
 // SendTypingIndicator sends a typing indicator to a receiver
-func (s *ChatService) SendTypingIndicator(senderID, receiverID string) error {
+func (s *ChatService) SendTypingIndicator(ctx context.Context, senderID, receiverID string) error {
 	// Check if users can message each other
 	canSend, err := s.repo.CanSendMessage(senderID, receiverID)
 	if err != nil {
@@ -0,0 +1,89 @@
+package event
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/Athooh/social-network/pkg/ical"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// calendarProdID identifies the application in exported calendars
+const calendarProdID = "-//Social Network//Group Events//EN"
+
+// ErrFeedNotFound is returned for calendar feed tokens that belong to no one
+var ErrFeedNotFound = errors.New("calendar feed not found")
+
+// newFeedToken generates the secret part of a calendar feed URL
+func newFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func newCalendar(name string, events []*models.GroupEvent) *ical.Calendar {
+	cal := &ical.Calendar{ProdID: calendarProdID, Name: name}
+	for _, event := range events {
+		cal.Events = append(cal.Events, icalEvent(event))
+	}
+	return cal
+}
+
+// GetEventCalendar gets a calendar holding a single event, the whole series if it recurs
+func (s *EventService) GetEventCalendar(ctx context.Context, eventID, userID string) (*ical.Calendar, error) {
+	event, err := s.GetEvent(ctx, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCalendar(event.Title, []*models.GroupEvent{event}), nil
+}
+
+// GetFeedToken gets the token of a user's calendar feed, creating it on first use. Rotating
+// replaces the token so the old feed URL stops working.
+func (s *EventService) GetFeedToken(ctx context.Context, userID string, rotate bool) (string, error) {
+	if !rotate {
+		token, err := s.repo.GetFeedToken(userID)
+		if err != nil || token != "" {
+			return token, err
+		}
+	}
+
+	token, err := newFeedToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repo.SaveFeedToken(userID, token); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// GetFeedCalendar gets the calendar of the events the owner of a feed token is going to
+func (s *EventService) GetFeedCalendar(ctx context.Context, token string) (*ical.Calendar, error) {
+	if token == "" {
+		return nil, ErrFeedNotFound
+	}
+
+	userID, err := s.repo.GetUserIDByFeedToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, ErrFeedNotFound
+	}
+
+	events, err := s.repo.GetUserGoingEvents(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCalendar("Group events", events), nil
+}
@@ -1,13 +1,18 @@
 package event
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Athooh/social-network/internal/auth"
 	"github.com/Athooh/social-network/pkg/httputil"
+	"github.com/Athooh/social-network/pkg/ical"
 	"github.com/Athooh/social-network/pkg/logger"
 )
 
@@ -48,20 +53,16 @@ func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 
 	// Get form values
 	groupID := r.FormValue("groupId")
-	title := r.FormValue("title")
-	description := r.FormValue("description")
-	eventDateStr := r.FormValue("eventDate")
 	response := r.FormValue("attendance")
 
-	if groupID == "" || title == "" || eventDateStr == "" {
+	if groupID == "" || r.FormValue("title") == "" || r.FormValue("eventDate") == "" {
 		http.Error(w, "Group ID, title, and event date are required", http.StatusBadRequest)
 		return
 	}
 
-	// Parse event date
-	eventDate, err := time.Parse(time.RFC3339, eventDateStr)
+	details, err := parseEventDetails(r)
 	if err != nil {
-		http.Error(w, "Invalid event date format", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -72,9 +73,9 @@ func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create event
-	event, err := h.service.CreateEvent(groupID, userID, title, description, eventDate, banner, response)
+	event, err := h.service.CreateEvent(r.Context(), groupID, userID, details, banner, response)
 	if err != nil {
-		h.log.Error("Failed to create event: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to create event: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -106,9 +107,9 @@ func (h *Handler) GetEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get event
-	event, err := h.service.GetEvent(eventID, userID)
+	event, err := h.service.GetEvent(r.Context(), eventID, userID)
 	if err != nil {
-		h.log.Error("Failed to get event: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get event: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -139,10 +140,30 @@ func (h *Handler) GetGroupEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get the date range, every past event and the coming year by default
+	from, to := time.Time{}, time.Now().AddDate(1, 0, 0)
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid from date format", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid to date format", http.StatusBadRequest)
+			return
+		}
+	}
+	if !to.After(from) {
+		http.Error(w, "The to date must be after the from date", http.StatusBadRequest)
+		return
+	}
+
 	// Get events
-	events, err := h.service.GetGroupEvents(groupID, userID)
+	events, err := h.service.GetGroupEvents(r.Context(), groupID, userID, from, to)
 	if err != nil {
-		h.log.Error("Failed to get group events: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get group events: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -174,19 +195,15 @@ func (h *Handler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 
 	// Get form values
 	eventID := r.FormValue("eventId")
-	title := r.FormValue("title")
-	description := r.FormValue("description")
-	eventDateStr := r.FormValue("eventDate")
 
-	if eventID == "" || title == "" || eventDateStr == "" {
+	if eventID == "" || r.FormValue("title") == "" || r.FormValue("eventDate") == "" {
 		http.Error(w, "Event ID, title, and event date are required", http.StatusBadRequest)
 		return
 	}
 
-	// Parse event date
-	eventDate, err := time.Parse(time.RFC3339, eventDateStr)
+	details, err := parseEventDetails(r)
 	if err != nil {
-		http.Error(w, "Invalid event date format", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -197,9 +214,9 @@ func (h *Handler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update event
-	event, err := h.service.UpdateEvent(eventID, userID, title, description, eventDate, banner)
+	event, err := h.service.UpdateEvent(r.Context(), eventID, userID, details, banner)
 	if err != nil {
-		h.log.Error("Failed to update event: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to update event: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -231,8 +248,8 @@ func (h *Handler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete event
-	if err := h.service.DeleteEvent(eventID, userID); err != nil {
-		h.log.Error("Failed to delete event: %v", err)
+	if err := h.service.DeleteEvent(r.Context(), eventID, userID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to delete event: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -241,6 +258,144 @@ func (h *Handler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Event deleted successfully"})
 }
 
+// CancelOccurrence handles cancelling one occurrence of a recurring event
+func (h *Handler) CancelOccurrence(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse request body
+	var request struct {
+		EventID      string `json:"eventId"`
+		RecurrenceID string `json:"recurrenceId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.EventID == "" || request.RecurrenceID == "" {
+		http.Error(w, "Event ID and recurrence ID are required", http.StatusBadRequest)
+		return
+	}
+
+	occurrence, err := time.Parse(time.RFC3339, request.RecurrenceID)
+	if err != nil {
+		http.Error(w, "Invalid recurrence ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Cancel occurrence
+	event, err := h.service.CancelOccurrence(r.Context(), request.EventID, userID, occurrence)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to cancel event occurrence: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Return response
+	h.sendJSON(w, http.StatusOK, event)
+}
+
+// ExportEvent handles downloading an event as an iCalendar file
+func (h *Handler) ExportEvent(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get event ID from query
+	eventID := r.URL.Query().Get("eventId")
+	if eventID == "" {
+		http.Error(w, "Event ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Get calendar
+	cal, err := h.service.GetEventCalendar(r.Context(), eventID, userID)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to export event: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="event-`+eventID+`.ics"`)
+	h.sendCalendar(w, r, cal)
+}
+
+// HandleFeedURL handles getting the private calendar feed URL of the current user, or
+// replacing it with a new one on POST
+func (h *Handler) HandleFeedURL(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET and POST methods
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get token
+	token, err := h.service.GetFeedToken(r.Context(), userID, r.Method == http.MethodPost)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to get calendar feed token: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Return response
+	h.sendJSON(w, http.StatusOK, map[string]string{
+		"token": token,
+		"url":   feedPath + "?token=" + url.QueryEscape(token),
+	})
+}
+
+// CalendarFeed handles serving a user's calendar feed to calendar apps, which
+// authenticate with the token in the URL instead of a session
+func (h *Handler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get calendar
+	cal, err := h.service.GetFeedCalendar(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		if errors.Is(err, ErrFeedNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.log.WithContext(r.Context()).Error("Failed to get calendar feed: %v", err)
+		http.Error(w, "Failed to get calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendCalendar(w, r, cal)
+}
+
 // RespondToEvent handles responding to an event
 func (h *Handler) RespondToEvent(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST method
@@ -260,6 +415,7 @@ func (h *Handler) RespondToEvent(w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		EventID  string `json:"eventId"`
 		Response string `json:"response"`
+		Guests   int    `json:"guests"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -273,14 +429,56 @@ func (h *Handler) RespondToEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Respond to event
-	if err := h.service.RespondToEvent(request.EventID, userID, request.Response); err != nil {
-		h.log.Error("Failed to respond to event: %v", err)
+	response, err := h.service.RespondToEvent(r.Context(), request.EventID, userID, request.Response, request.Guests)
+	if err != nil {
+		if errors.Is(err, ErrNotEnoughSeats) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.log.WithContext(r.Context()).Error("Failed to respond to event: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success response
+	message := "Response recorded successfully"
+	if response.WaitlistedAt.Valid {
+		message = "The event is full, you are on the waitlist"
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{"message": message, "response": response})
+}
+
+// RemoveEventResponse handles withdrawing a response to an event
+func (h *Handler) RemoveEventResponse(w http.ResponseWriter, r *http.Request) {
+	// Only allow DELETE method
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get event ID from query
+	eventID := r.URL.Query().Get("eventId")
+	if eventID == "" {
+		http.Error(w, "Event ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Remove response
+	if err := h.service.RemoveEventResponse(r.Context(), eventID, userID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to remove event response: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Return success response
-	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Response recorded successfully"})
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Response removed successfully"})
 }
 
 // GetEventResponses handles getting responses to an event
@@ -308,9 +506,9 @@ func (h *Handler) GetEventResponses(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get responses
-	responses, err := h.service.GetEventResponses(eventID, userID, responseType)
+	responses, err := h.service.GetEventResponses(r.Context(), eventID, userID, responseType)
 	if err != nil {
-		h.log.Error("Failed to get event responses: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get event responses: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -319,6 +517,77 @@ func (h *Handler) GetEventResponses(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, responses)
 }
 
+// feedPath is where CalendarFeed is routed
+const feedPath = "/api/calendar/feed.ics"
+
+// parseEventDetails reads the event fields of a create or update form. Dates are RFC 3339,
+// or local times in the event's time zone when they have no offset.
+func parseEventDetails(r *http.Request) (EventDetails, error) {
+	details := EventDetails{
+		Title:       r.FormValue("title"),
+		Description: r.FormValue("description"),
+		TimeZone:    r.FormValue("timeZone"),
+		Location:    r.FormValue("location"),
+		Recurrence:  r.FormValue("recurrence"),
+	}
+	if details.TimeZone == "" {
+		details.TimeZone = "UTC"
+	}
+
+	loc, err := time.LoadLocation(details.TimeZone)
+	if err != nil {
+		return details, errors.New("Invalid time zone")
+	}
+
+	if details.Start, err = parseEventTime(r.FormValue("eventDate"), loc); err != nil {
+		return details, errors.New("Invalid event date format")
+	}
+
+	if v := r.FormValue("endDate"); v != "" {
+		if details.End, err = parseEventTime(v, loc); err != nil {
+			return details, errors.New("Invalid end date format")
+		}
+	}
+
+	if v := r.FormValue("capacity"); v != "" {
+		if details.Capacity, err = strconv.Atoi(v); err != nil {
+			return details, errors.New("Invalid capacity")
+		}
+	}
+
+	if v := r.FormValue("guestLimit"); v != "" {
+		if details.GuestLimit, err = strconv.Atoi(v); err != nil {
+			return details, errors.New("Invalid guest limit")
+		}
+	}
+
+	return details, nil
+}
+
+func parseEventTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", value, loc); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04", value, loc)
+}
+
+// sendCalendar writes an iCalendar response
+func (h *Handler) sendCalendar(w http.ResponseWriter, r *http.Request, cal *ical.Calendar) {
+	var buf bytes.Buffer
+	if err := cal.Encode(&buf); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to encode calendar: %v", err)
+		http.Error(w, "Failed to encode calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
 // Helper method to send JSON responses
 func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	httputil.SendJSON(w, status, data)
@@ -1,7 +1,9 @@
 package event
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -31,22 +33,24 @@ func NewNotificationService(hub *websocket.Hub, repo Repository, notificationRep
 }
 
 // SendEventCreatedNotification sends a notification when a new event is created
-func (s *NotificationService) SendEventCreatedNotification(inviterID, inviteeID string, newNote *notifications.NewNotification, inviterInfo *models.UserBasic) {
+func (s *NotificationService) SendEventCreatedNotification(ctx context.Context, inviterID, inviteeID string, newNote *notifications.NewNotification, inviterInfo *models.UserBasic) {
 	if s.hub == nil {
-		s.log.Warn("WebSocket hub is nil, cannot send follow request notification")
+		s.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send follow request notification")
 		return
 	}
 
 	// Create notification in database
-	if err := s.notificationRepo.CreateNotification(newNote); err != nil {
-		s.log.Error("Failed to create follow request notification: %v", err)
+	if err := s.notificationRepo.CreateNotification(ctx, newNote); err != nil {
+		if !errors.Is(err, notifications.ErrSenderBlocked) {
+			s.log.WithContext(ctx).Error("Failed to create follow request notification: %v", err)
+		}
 		return
 	}
 
 	// Retrieve the newly created notification to get its ID and CreatedAt
-	notifications, err := s.notificationRepo.GetNotifications(inviteeID, 1, 0)
+	notifications, err := s.notificationRepo.GetNotifications(ctx, inviteeID, 1, 0)
 	if err != nil || len(notifications) == 0 {
-		s.log.Error("Failed to retrieve newly created notification: %v", err)
+		s.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
 		return
 	}
 	dbNotification := notifications[0]
@@ -71,92 +75,199 @@ func (s *NotificationService) SendEventCreatedNotification(inviterID, inviteeID
 	s.hub.BroadcastToUser(inviteeID, event)
 }
 
-// SendEventUpdatedNotification sends a notification when an event is updated
-func (s *NotificationService) SendEventUpdatedNotification(event *models.GroupEvent) {
+// SendEventChangedNotification tells the users who responded to an event that it was
+// updated, rescheduled or cancelled, wsType saying which. Cancelled events are gone so
+// their notifications do not link to them.
+func (s *NotificationService) SendEventChangedNotification(ctx context.Context, event *models.GroupEvent, actorID string, userIDs []string, wsType events.EventType, message string) {
 	if s.hub == nil {
-		s.log.Warn("WebSocket hub is nil, cannot send event updated notification")
+		s.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send event changed notification")
 		return
 	}
 
-	// Get creator info
-	creator, err := s.repo.GetUserBasicByID(event.CreatorID)
+	// Get actor info
+	actor, err := s.repo.GetUserBasicByID(actorID)
 	if err != nil {
-		s.log.Warn("Failed to get creator info for notification: %v", err)
+		s.log.WithContext(ctx).Warn("Failed to get actor info for notification: %v", err)
 		return
 	}
 
-	creatorName := fmt.Sprintf("%s %s", creator.FirstName, creator.LastName)
-
-	// Get group members to notify
-	members, err := s.repo.GetGroupMembers(event.GroupID, "accepted")
-	if err != nil {
-		s.log.Warn("Failed to get group members for notification: %v", err)
-		return
+	actorName := fmt.Sprintf("%s %s", actor.FirstName, actor.LastName)
+	notificationType := "groupEventUpdated"
+	targetEventID := sql.NullString{String: event.ID, Valid: true}
+	if wsType == events.GroupEventDeleted {
+		notificationType = "groupEventCancelled"
+		targetEventID = sql.NullString{}
 	}
 
-	// Send to all group members
-	for _, member := range members {
+	for _, userID := range userIDs {
+		if userID == actorID {
+			continue
+		}
+
 		// Create notification in database
 		notification := &notifications.NewNotification{
-			UserId:          member.UserID,
-			NotficationType: "groupEventUpdated",
-			SenderId:        sql.NullString{String: event.CreatorID, Valid: true},
-			Message:         fmt.Sprintf("updated the event %s", event.Title),
+			UserId:          userID,
+			NotficationType: notificationType,
+			SenderId:        sql.NullString{String: actorID, Valid: true},
+			Message:         message,
+			TargetGroupID:   sql.NullString{String: event.GroupID, Valid: true},
+			TargetEventID:   targetEventID,
 		}
 
-		if err := s.notificationRepo.CreateNotification(notification); err != nil {
-			s.log.Error("Failed to create event updated notification: %v", err)
+		if err := s.notificationRepo.CreateNotification(ctx, notification); err != nil {
+			if !errors.Is(err, notifications.ErrSenderBlocked) {
+				s.log.WithContext(ctx).Error("Failed to create event changed notification: %v", err)
+			}
 			continue
 		}
 
 		// Retrieve the newly created notification
-		notifications, err := s.notificationRepo.GetNotifications(member.UserID, 1, 0)
+		notifications, err := s.notificationRepo.GetNotifications(ctx, userID, 1, 0)
 		if err != nil || len(notifications) == 0 {
-			s.log.Error("Failed to retrieve newly created notification: %v", err)
+			s.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
 			continue
 		}
 		dbNotification := notifications[0]
 
 		// Create WebSocket event
 		notificationEvent := events.Event{
-			Type: "group_event_updated",
+			Type: wsType,
 			Payload: map[string]interface{}{
 				"id":           dbNotification.ID,
 				"eventId":      event.ID,
 				"groupId":      event.GroupID,
-				"type":         "groupEventUpdated",
-				"senderId":     event.CreatorID,
-				"senderAvatar": creator.Avatar,
-				"senderName":   creatorName,
-				"message":      fmt.Sprintf("updated the event %s", event.Title),
+				"type":         notificationType,
+				"senderId":     actorID,
+				"senderAvatar": actor.Avatar,
+				"senderName":   actorName,
+				"message":      message,
 				"eventDate":    event.EventDate.Format(time.RFC3339),
 				"isRead":       false,
 				"createdAt":    dbNotification.CreatedAt.Format(time.RFC3339),
 			},
 		}
 
-		s.hub.BroadcastToUser(member.UserID, notificationEvent)
+		s.hub.BroadcastToUser(userID, notificationEvent)
+	}
+}
+
+// SendEventReminderNotification reminds the users going to an occurrence of an event
+// that it starts soon
+func (s *NotificationService) SendEventReminderNotification(ctx context.Context, event *models.GroupEvent, start time.Time, userIDs []string, message string) {
+	if s.hub == nil {
+		s.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send event reminder notification")
+		return
+	}
+
+	for _, userID := range userIDs {
+		// Notifications need a sender, reminders come from the event's creator
+		notification := &notifications.NewNotification{
+			UserId:          userID,
+			NotficationType: "groupEventReminder",
+			SenderId:        sql.NullString{String: event.CreatorID, Valid: true},
+			Message:         message,
+			TargetGroupID:   sql.NullString{String: event.GroupID, Valid: true},
+			TargetEventID:   sql.NullString{String: event.ID, Valid: true},
+		}
+
+		if err := s.notificationRepo.CreateNotification(ctx, notification); err != nil {
+			if !errors.Is(err, notifications.ErrSenderBlocked) {
+				s.log.WithContext(ctx).Error("Failed to create event reminder notification: %v", err)
+			}
+			continue
+		}
+
+		notifications, err := s.notificationRepo.GetNotifications(ctx, userID, 1, 0)
+		if err != nil || len(notifications) == 0 {
+			s.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
+			continue
+		}
+		dbNotification := notifications[0]
+
+		s.hub.BroadcastToUser(userID, events.Event{
+			Type: events.GroupEventReminder,
+			Payload: map[string]interface{}{
+				"id":        dbNotification.ID,
+				"eventId":   event.ID,
+				"groupId":   event.GroupID,
+				"type":      "groupEventReminder",
+				"senderId":  event.CreatorID,
+				"message":   message,
+				"eventDate": start.Format(time.RFC3339),
+				"isRead":    false,
+				"createdAt": dbNotification.CreatedAt.Format(time.RFC3339),
+			},
+		})
 	}
 }
 
+// SendWaitlistPromotedNotification tells a responder they got a seat off the waitlist of
+// an event
+func (s *NotificationService) SendWaitlistPromotedNotification(ctx context.Context, event *models.GroupEvent, response *models.EventResponse) {
+	message := fmt.Sprintf("A seat opened up, you are going to the event %s", event.Title)
+
+	// Nobody in particular gave the seat away, so the notification has no sender and
+	// reaches the user even if they blocked the event's creator
+	notification := &notifications.NewNotification{
+		UserId:          response.UserID,
+		NotficationType: "eventWaitlistPromoted",
+		Message:         message,
+		TargetGroupID:   sql.NullString{String: event.GroupID, Valid: true},
+		TargetEventID:   sql.NullString{String: event.ID, Valid: true},
+	}
+
+	if err := s.notificationRepo.CreateNotification(ctx, notification); err != nil {
+		s.log.WithContext(ctx).Error("Failed to create waitlist promotion notification: %v", err)
+		return
+	}
+
+	if s.hub == nil {
+		s.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot push waitlist promotion notification")
+		return
+	}
+
+	notifications, err := s.notificationRepo.GetNotifications(ctx, response.UserID, 1, 0)
+	if err != nil || len(notifications) == 0 {
+		s.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
+		return
+	}
+	dbNotification := notifications[0]
+
+	s.hub.BroadcastToUser(response.UserID, events.Event{
+		Type: events.EventResponseUpdated,
+		Payload: map[string]interface{}{
+			"id":         dbNotification.ID,
+			"eventId":    event.ID,
+			"groupId":    event.GroupID,
+			"type":       "eventWaitlistPromoted",
+			"message":    message,
+			"response":   response.Response,
+			"guests":     response.Guests,
+			"waitlisted": false,
+			"isRead":     false,
+			"createdAt":  dbNotification.CreatedAt.Format(time.RFC3339),
+		},
+	})
+}
+
 // SendEventResponseNotification sends a notification when a user responds to an event
-func (s *NotificationService) SendEventResponseNotification(eventID, userID, response string) {
+func (s *NotificationService) SendEventResponseNotification(ctx context.Context, eventID, userID, response string) {
 	if s.hub == nil {
-		s.log.Warn("WebSocket hub is nil, cannot send event response notification")
+		s.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send event response notification")
 		return
 	}
 
 	// Get event info
 	event, err := s.repo.GetEventByID(eventID)
 	if err != nil {
-		s.log.Warn("Failed to get event info for notification: %v", err)
+		s.log.WithContext(ctx).Warn("Failed to get event info for notification: %v", err)
 		return
 	}
 
 	// Get user info
 	user, err := s.repo.GetUserBasicByID(userID)
 	if err != nil {
-		s.log.Warn("Failed to get user info for notification: %v", err)
+		s.log.WithContext(ctx).Warn("Failed to get user info for notification: %v", err)
 		return
 	}
 
@@ -170,15 +281,17 @@ func (s *NotificationService) SendEventResponseNotification(eventID, userID, res
 		Message:         fmt.Sprintf("%s responded %s to the event %s", userName, response, event.Title),
 	}
 
-	if err := s.notificationRepo.CreateNotification(notification); err != nil {
-		s.log.Error("Failed to create event response notification: %v", err)
+	if err := s.notificationRepo.CreateNotification(ctx, notification); err != nil {
+		if !errors.Is(err, notifications.ErrSenderBlocked) {
+			s.log.WithContext(ctx).Error("Failed to create event response notification: %v", err)
+		}
 		return
 	}
 
 	// Retrieve the newly created notification
-	notifications, err := s.notificationRepo.GetNotifications(event.CreatorID, 1, 0)
+	notifications, err := s.notificationRepo.GetNotifications(ctx, event.CreatorID, 1, 0)
 	if err != nil || len(notifications) == 0 {
-		s.log.Error("Failed to retrieve newly created notification: %v", err)
+		s.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
 		return
 	}
 	dbNotification := notifications[0]
@@ -0,0 +1,16 @@
+package event
+
+import (
+	"database/sql"
+)
+
+// PostgresRepository implements Repository for PostgreSQL using the queries of SQLiteRepository
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL event repository. db must be opened with
+// postgres.New, which accepts the ? placeholders the queries use.
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{&SQLiteRepository{db: db}}
+}
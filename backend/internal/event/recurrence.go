@@ -0,0 +1,136 @@
+package event
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/ical"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// maxOccurrences bounds how many occurrences of one series a listing expands
+const maxOccurrences = 500
+
+// parseExceptions reads GroupEvent.Exceptions, skipping malformed entries
+func parseExceptions(s string) []time.Time {
+	var times []time.Time
+	for _, item := range strings.Split(s, ",") {
+		if t, err := ical.ParseUTC(strings.TrimSpace(item)); err == nil {
+			times = append(times, t)
+		}
+	}
+	return times
+}
+
+// formatExceptions stores times in GroupEvent.Exceptions, sorted and without duplicates
+func formatExceptions(times []time.Time) string {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	var items []string
+	for _, t := range times {
+		item := ical.FormatUTC(t)
+		if len(items) == 0 || items[len(items)-1] != item {
+			items = append(items, item)
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// eventLocation loads the time zone of an event
+func eventLocation(event *models.GroupEvent) (*time.Location, error) {
+	loc, err := time.LoadLocation(event.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", event.TimeZone, err)
+	}
+	return loc, nil
+}
+
+// expandOccurrences returns the occurrences of event that overlap [from, to). A one-off
+// event is returned as is, a recurring one as a copy per occurrence with its start, end
+// and RecurrenceID set.
+func expandOccurrences(event *models.GroupEvent, from, to time.Time) ([]*models.GroupEvent, error) {
+	var duration time.Duration
+	if event.EndDate.Valid {
+		duration = event.EndDate.Time.Sub(event.EventDate)
+	}
+
+	if event.Recurrence == "" {
+		if event.EventDate.Before(to) && !event.EventDate.Add(duration).Before(from) {
+			return []*models.GroupEvent{event}, nil
+		}
+		return nil, nil
+	}
+
+	rule, err := ical.ParseRule(event.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("event %s has an invalid recurrence rule: %w", event.ID, err)
+	}
+
+	loc, err := eventLocation(event)
+	if err != nil {
+		return nil, err
+	}
+
+	// Occurrences that started before from but are still going on overlap the range too
+	starts := rule.Between(event.EventDate.In(loc), from.Add(-duration), to, parseExceptions(event.Exceptions), maxOccurrences)
+
+	occurrences := make([]*models.GroupEvent, 0, len(starts))
+	for _, start := range starts {
+		occurrence := *event
+		occurrence.EventDate = start
+		if event.EndDate.Valid {
+			occurrence.EndDate.Time = start.Add(duration)
+		}
+		occurrence.RecurrenceID = start.UTC().Format(time.RFC3339)
+		occurrences = append(occurrences, &occurrence)
+	}
+
+	return occurrences, nil
+}
+
+// icalEvent converts an event, the whole series for a recurring one, to a VEVENT
+func icalEvent(event *models.GroupEvent) ical.Event {
+	e := ical.Event{
+		UID:         event.ID + "@social-network",
+		Summary:     event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		Start:       event.EventDate,
+		TimeZone:    event.TimeZone,
+		Rule:        event.Recurrence,
+		Except:      parseExceptions(event.Exceptions),
+		Created:     event.CreatedAt,
+		Modified:    event.UpdatedAt,
+	}
+	if event.EndDate.Valid {
+		e.End = event.EndDate.Time
+	}
+	return e
+}
+
+// nextOccurrence returns the start of the first occurrence of event at or after t, false
+// when there is none left
+func nextOccurrence(event *models.GroupEvent, t time.Time) (time.Time, bool, error) {
+	if event.Recurrence == "" {
+		return event.EventDate, !event.EventDate.Before(t), nil
+	}
+
+	rule, err := ical.ParseRule(event.Recurrence)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("event %s has an invalid recurrence rule: %w", event.ID, err)
+	}
+
+	loc, err := eventLocation(event)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	// Occurrences more than a year apart are not looked for
+	starts := rule.Between(event.EventDate.In(loc), t, t.AddDate(1, 0, 1), parseExceptions(event.Exceptions), 1)
+	if len(starts) == 0 {
+		return time.Time{}, false, nil
+	}
+	return starts[0], true, nil
+}
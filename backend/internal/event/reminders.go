@@ -0,0 +1,184 @@
+package event
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/scheduler"
+	"github.com/Athooh/social-network/pkg/websocket/events"
+)
+
+// JobEventReminder is the kind of the scheduled jobs reminding users of events they are
+// going to. Each reminder offset of an event has one job, for its next occurrence.
+const JobEventReminder = "event_reminder"
+
+// reminderJob is the payload of a JobEventReminder job
+type reminderJob struct {
+	EventID string        `json:"eventId"`
+	Start   time.Time     `json:"start"` // Start of the occurrence reminded of
+	Offset  time.Duration `json:"offset"`
+}
+
+func reminderKey(eventID string, offset time.Duration) string {
+	return reminderKeyPrefix(eventID) + offset.String()
+}
+
+func reminderKeyPrefix(eventID string) string {
+	return JobEventReminder + ":" + eventID + ":"
+}
+
+// scheduleReminders schedules the reminders of the next occurrence of an event that is
+// far enough away, replacing those scheduled before
+func (s *EventService) scheduleReminders(event *models.GroupEvent) error {
+	now := time.Now()
+	for _, offset := range s.reminderOffsets {
+		if err := s.scheduleReminder(event, offset, now.Add(offset)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleReminder schedules the reminder at offset before the first occurrence starting
+// at or after t, or cancels it when there is none
+func (s *EventService) scheduleReminder(event *models.GroupEvent, offset time.Duration, t time.Time) error {
+	key := reminderKey(event.ID, offset)
+
+	start, ok, err := nextOccurrence(event, t)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return s.jobs.Cancel(key)
+	}
+
+	return s.jobs.Schedule(JobEventReminder, key, start.Add(-offset), reminderJob{
+		EventID: event.ID,
+		Start:   start,
+		Offset:  offset,
+	})
+}
+
+// cancelReminders cancels every reminder of an event
+func (s *EventService) cancelReminders(eventID string) error {
+	return s.jobs.CancelPrefix(reminderKeyPrefix(eventID))
+}
+
+// rescheduleReminders schedules the reminders of an event after it changed. Failing to do
+// so is logged rather than failing the change.
+func (s *EventService) rescheduleReminders(ctx context.Context, event *models.GroupEvent) {
+	if err := s.scheduleReminders(event); err != nil {
+		s.log.WithContext(ctx).Error("Failed to schedule reminders of event %s: %v", event.ID, err)
+	}
+}
+
+// sendReminder runs a JobEventReminder job, reminding the users going to the event and
+// scheduling the reminder of its next occurrence
+func (s *EventService) sendReminder(ctx context.Context, job *scheduler.Job) error {
+	var reminder reminderJob
+	if err := job.Decode(&reminder); err != nil {
+		return err
+	}
+
+	event, err := s.repo.GetEventByID(reminder.EventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Deleted since, its reminders should have been cancelled with it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Reminders of an offset no longer configured, or of an occurrence that was moved or
+	// cancelled without rescheduling them, are dropped
+	if !s.hasReminderOffset(reminder.Offset) {
+		return nil
+	}
+	start, ok, err := nextOccurrence(event, reminder.Start)
+	if err != nil {
+		return err
+	}
+	if !ok || !start.Equal(reminder.Start) {
+		return nil
+	}
+
+	responses, err := s.repo.GetEventResponses(event.ID, "going")
+	if err != nil {
+		return err
+	}
+	userIDs := make([]string, 0, len(responses))
+	for _, response := range responses {
+		// The waitlist has no seat to be reminded of
+		if !response.WaitlistedAt.Valid {
+			userIDs = append(userIDs, response.UserID)
+		}
+	}
+
+	message := fmt.Sprintf("%s starts in %s", event.Title, formatOffset(reminder.Offset))
+	s.notificationService.SendEventReminderNotification(ctx, event, start, userIDs, message)
+
+	// Recurring events get the reminder of the following occurrence
+	if event.Recurrence == "" {
+		return nil
+	}
+	next := reminder.Start.Add(time.Nanosecond)
+	if soonest := time.Now().Add(reminder.Offset); soonest.After(next) {
+		next = soonest
+	}
+	return s.scheduleReminder(event, reminder.Offset, next)
+}
+
+func (s *EventService) hasReminderOffset(offset time.Duration) bool {
+	for _, o := range s.reminderOffsets {
+		if o == offset {
+			return true
+		}
+	}
+	return false
+}
+
+// formatOffset writes a reminder offset for people, such as 1 day or 90 minutes
+func formatOffset(d time.Duration) string {
+	unit := func(n int64, name string) string {
+		if n == 1 {
+			return "1 " + name
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
+
+	switch {
+	case d%(24*time.Hour) == 0:
+		return unit(int64(d/(24*time.Hour)), "day")
+	case d%time.Hour == 0:
+		return unit(int64(d/time.Hour), "hour")
+	default:
+		return unit(int64(d.Round(time.Minute)/time.Minute), "minute")
+	}
+}
+
+// notifyResponders tells the users who responded to an event, except actorID, that it changed
+func (s *EventService) notifyResponders(ctx context.Context, event *models.GroupEvent, actorID string, wsType events.EventType, message string) {
+	responses, err := s.repo.GetEventResponses(event.ID, "")
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get responders of event %s: %v", event.ID, err)
+		return
+	}
+
+	userIDs := make([]string, 0, len(responses))
+	for _, response := range responses {
+		userIDs = append(userIDs, response.UserID)
+	}
+	s.notificationService.SendEventChangedNotification(ctx, event, actorID, userIDs, wsType, message)
+}
+
+// formatEventTime writes the time of an occurrence in the event's time zone
+func formatEventTime(event *models.GroupEvent, t time.Time) string {
+	if loc, err := eventLocation(event); err == nil {
+		t = t.In(loc)
+	}
+	return t.Format("Mon Jan 2 2006 15:04 MST")
+}
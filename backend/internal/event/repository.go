@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
@@ -15,23 +16,28 @@ type Repository interface {
 	// Event operations
 	CreateEvent(event *models.GroupEvent) error
 	GetEventByID(id string) (*models.GroupEvent, error)
-	GetGroupEvents(groupID string) ([]*models.GroupEvent, error)
+	GetGroupEvents(groupID string, from, to time.Time) ([]*models.GroupEvent, error)
+	GetUserGoingEvents(userID string) ([]*models.GroupEvent, error)
 	UpdateEvent(event *models.GroupEvent) error
 	DeleteEvent(id string) error
 
+	// Calendar feed operations
+	GetFeedToken(userID string) (string, error)
+	SaveFeedToken(userID, token string) error
+	GetUserIDByFeedToken(token string) (string, error)
+
 	// Event responses operations
-	AddEventResponse(response *models.EventResponse) error
+	AddEventResponse(response *models.EventResponse) (promoted []*models.EventResponse, err error)
 	GetEventResponses(eventID string, responseType string) ([]*models.EventResponse, error)
 	GetUserEventResponse(eventID, userID string) (*models.EventResponse, error)
-	UpdateEventResponse(eventID, userID, response string) error
-	DeleteEventResponse(eventID, userID string) error
-	GetEventResponseCounts(eventID string) (going int, notGoing int, err error)
+	DeleteEventResponse(eventID, userID string) (promoted []*models.EventResponse, err error)
+	PromoteWaitlist(eventID string) (promoted []*models.EventResponse, err error)
+	GetEventResponseCounts(eventID string) (*models.EventResponseCounts, error)
 
 	// User and group operations
 	GetUserBasicByID(userID string) (*models.UserBasic, error)
 	GetGroupByID(id string) (*models.Group, error)
 	IsGroupMember(groupID, userID string) (bool, error)
-	GetMemberRole(groupID, userID string) (string, error)
 	GetGroupMembers(groupID string, status string) ([]*models.GroupMember, error)
 }
 
@@ -57,8 +63,9 @@ func (r *SQLiteRepository) CreateEvent(event *models.GroupEvent) error {
 
 	query := `
 		INSERT INTO group_events (
-			id, group_id, creator_id, title, description, event_date, banner_path, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			id, group_id, creator_id, title, description, event_date, end_date, time_zone,
+			location, capacity, guest_limit, recurrence_rule, recurrence_exdates, banner_path, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := r.db.Exec(
@@ -68,7 +75,14 @@ func (r *SQLiteRepository) CreateEvent(event *models.GroupEvent) error {
 		event.CreatorID,
 		event.Title,
 		event.Description,
-		event.EventDate,
+		event.EventDate.UTC(),
+		utcNullTime(event.EndDate),
+		event.TimeZone,
+		event.Location,
+		event.Capacity,
+		event.GuestLimit,
+		event.Recurrence,
+		event.Exceptions,
 		event.BannerPath,
 		event.CreatedAt,
 		event.UpdatedAt,
@@ -80,116 +94,167 @@ func (r *SQLiteRepository) CreateEvent(event *models.GroupEvent) error {
 	return nil
 }
 
-// GetEventByID gets an event by ID
-func (r *SQLiteRepository) GetEventByID(id string) (*models.GroupEvent, error) {
-	query := `
-		SELECT id, group_id, creator_id, title, description, event_date, banner_path, created_at, updated_at
-		FROM group_events
-		WHERE id = ?
-	`
+// utcNullTime converts a time to UTC, SQLite keeps the offset and compares times as text
+func utcNullTime(t sql.NullTime) sql.NullTime {
+	if t.Valid {
+		t.Time = t.Time.UTC()
+	}
+	return t
+}
 
-	var event models.GroupEvent
-	var bannerPath sql.NullString
+// eventColumns are the columns scanEvent reads, in order
+const eventColumns = `
+	ge.id, ge.group_id, ge.creator_id, ge.title, ge.description, ge.event_date, ge.end_date,
+	ge.time_zone, ge.location, ge.capacity, ge.guest_limit, ge.recurrence_rule, ge.recurrence_exdates,
+	ge.banner_path, ge.created_at, ge.updated_at`
 
-	err := r.db.QueryRow(query, id).Scan(
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEvent reads a row of eventColumns
+func scanEvent(row rowScanner) (*models.GroupEvent, error) {
+	var event models.GroupEvent
+	err := row.Scan(
 		&event.ID,
 		&event.GroupID,
 		&event.CreatorID,
 		&event.Title,
 		&event.Description,
 		&event.EventDate,
-		&bannerPath,
+		&event.EndDate,
+		&event.TimeZone,
+		&event.Location,
+		&event.Capacity,
+		&event.GuestLimit,
+		&event.Recurrence,
+		&event.Exceptions,
+		&event.BannerPath,
 		&event.CreatedAt,
 		&event.UpdatedAt,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("event not found: %w", err)
-		}
-		return nil, fmt.Errorf("failed to get event: %w", err)
+		return nil, err
 	}
+	return &event, nil
+}
 
-	event.BannerPath = bannerPath
-
+// fillEvent sets the creator and response counts of an event
+func (r *SQLiteRepository) fillEvent(event *models.GroupEvent) error {
 	// Get creator info
 	creator, err := r.GetUserBasicByID(event.CreatorID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get creator info: %w", err)
+		return fmt.Errorf("failed to get creator info: %w", err)
 	}
 	event.Creator = creator
 
 	// Get response counts
-	going, notGoing, err := r.GetEventResponseCounts(id)
+	counts, err := r.GetEventResponseCounts(event.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get response counts: %w", err)
+		return fmt.Errorf("failed to get response counts: %w", err)
 	}
 
-	event.GoingCount = going
-	event.NotGoingCount = notGoing
+	event.ResponseCounts = *counts
+	event.GoingCount = counts.Going
+	event.NotGoingCount = counts.NotGoing
 
-	return &event, nil
+	return nil
 }
 
-// GetGroupEvents gets all events in a group
-func (r *SQLiteRepository) GetGroupEvents(groupID string) ([]*models.GroupEvent, error) {
-	query := `
-		SELECT id, group_id, creator_id, title, description, event_date, banner_path, created_at, updated_at
-		FROM group_events
-		WHERE group_id = ?
-		ORDER BY event_date ASC
+// GetEventByID gets an event by ID
+func (r *SQLiteRepository) GetEventByID(id string) (*models.GroupEvent, error) {
+	query := `SELECT ` + eventColumns + `
+		FROM group_events ge
+		WHERE ge.id = ?
 	`
 
-	rows, err := r.db.Query(query, groupID)
+	event, err := scanEvent(r.db.QueryRow(query, id))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get group events: %w", err)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("event not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get event: %w", err)
 	}
-	defer rows.Close()
 
-	var events []*models.GroupEvent
+	if err := r.fillEvent(event); err != nil {
+		return nil, err
+	}
 
-	for rows.Next() {
-		var event models.GroupEvent
-		var bannerPath sql.NullString
+	return event, nil
+}
 
-		err := rows.Scan(
-			&event.ID,
-			&event.GroupID,
-			&event.CreatorID,
-			&event.Title,
-			&event.Description,
-			&event.EventDate,
-			&bannerPath,
-			&event.CreatedAt,
-			&event.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan event row: %w", err)
-		}
+// GetGroupEvents gets the events in a group that overlap [from, to), with a recurring
+// event listed once per occurrence, ordered by start
+func (r *SQLiteRepository) GetGroupEvents(groupID string, from, to time.Time) ([]*models.GroupEvent, error) {
+	// Recurring events are filtered once expanded, their first start only bounds them
+	query := `SELECT ` + eventColumns + `
+		FROM group_events ge
+		WHERE ge.group_id = ? AND ge.event_date < ?
+			AND (ge.recurrence_rule <> '' OR COALESCE(ge.end_date, ge.event_date) >= ?)
+		ORDER BY ge.event_date ASC
+	`
 
-		event.BannerPath = bannerPath
+	series, err := r.queryEvents(query, groupID, to.UTC(), from.UTC())
+	if err != nil {
+		return nil, err
+	}
 
-		// Get creator info
-		creator, err := r.GetUserBasicByID(event.CreatorID)
+	var events []*models.GroupEvent
+	for _, event := range series {
+		occurrences, err := expandOccurrences(event, from, to)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get creator info: %w", err)
+			return nil, err
 		}
-		event.Creator = creator
+		events = append(events, occurrences...)
+	}
 
-		// Get response counts
-		going, notGoing, err := r.GetEventResponseCounts(event.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get response counts: %w", err)
-		}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].EventDate.Before(events[j].EventDate) })
 
-		event.GoingCount = going
-		event.NotGoingCount = notGoing
+	return events, nil
+}
+
+// GetUserGoingEvents gets the events, whole series for recurring ones, that a user said
+// they are going to in the groups they are still a member of
+func (r *SQLiteRepository) GetUserGoingEvents(userID string) ([]*models.GroupEvent, error) {
+	query := `SELECT ` + eventColumns + `
+		FROM group_events ge
+		JOIN event_responses er ON er.event_id = ge.id AND er.user_id = ? AND er.response = 'going'
+		JOIN group_members gm ON gm.group_id = ge.group_id AND gm.user_id = ? AND gm.status = 'accepted'
+		ORDER BY ge.event_date ASC
+	`
+
+	return r.queryEvents(query, userID, userID)
+}
 
-		events = append(events, &event)
+func (r *SQLiteRepository) queryEvents(query string, args ...interface{}) ([]*models.GroupEvent, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.GroupEvent
+
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event row: %w", err)
+		}
+		events = append(events, event)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating event rows: %w", err)
 	}
+	rows.Close()
+
+	// Filled once the rows are closed, a pool of one connection cannot serve both
+	for _, event := range events {
+		if err := r.fillEvent(event); err != nil {
+			return nil, err
+		}
+	}
 
 	return events, nil
 }
@@ -200,7 +265,8 @@ func (r *SQLiteRepository) UpdateEvent(event *models.GroupEvent) error {
 
 	query := `
 		UPDATE group_events
-		SET title = ?, description = ?, event_date = ?, banner_path = ?, updated_at = ?
+		SET title = ?, description = ?, event_date = ?, end_date = ?, time_zone = ?, location = ?,
+			capacity = ?, guest_limit = ?, recurrence_rule = ?, recurrence_exdates = ?, banner_path = ?, updated_at = ?
 		WHERE id = ?
 	`
 
@@ -208,7 +274,14 @@ func (r *SQLiteRepository) UpdateEvent(event *models.GroupEvent) error {
 		query,
 		event.Title,
 		event.Description,
-		event.EventDate,
+		event.EventDate.UTC(),
+		utcNullTime(event.EndDate),
+		event.TimeZone,
+		event.Location,
+		event.Capacity,
+		event.GuestLimit,
+		event.Recurrence,
+		event.Exceptions,
 		event.BannerPath,
 		event.UpdatedAt,
 		event.ID,
@@ -230,48 +303,223 @@ func (r *SQLiteRepository) DeleteEvent(id string) error {
 	return nil
 }
 
-// AddEventResponse adds a user's response to an event
-func (r *SQLiteRepository) AddEventResponse(response *models.EventResponse) error {
-	if response.ID == "" {
-		response.ID = uuid.New().String()
+// ErrNotEnoughSeats is returned when a confirmed responder asks for more guests than there
+// are seats left
+var ErrNotEnoughSeats = errors.New("not enough seats left for your guests")
+
+// responseColumns are the columns scanResponse reads, in order
+const responseColumns = "id, event_id, user_id, response, guests, waitlisted_at, created_at, updated_at"
+
+// scanResponse reads a row of responseColumns
+func scanResponse(row rowScanner) (*models.EventResponse, error) {
+	var response models.EventResponse
+	err := row.Scan(
+		&response.ID,
+		&response.EventID,
+		&response.UserID,
+		&response.Response,
+		&response.Guests,
+		&response.WaitlistedAt,
+		&response.CreatedAt,
+		&response.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
+	return &response, nil
+}
 
+// lockEvent takes the write lock of an event for the rest of tx, so that seats are counted
+// by one responder at a time, and returns its capacity
+func lockEvent(tx *sql.Tx, eventID string) (int, error) {
+	var capacity int
+	if _, err := tx.Exec("UPDATE group_events SET capacity = capacity WHERE id = ?", eventID); err != nil {
+		return 0, fmt.Errorf("failed to lock event: %w", err)
+	}
+	err := tx.QueryRow("SELECT capacity FROM group_events WHERE id = ?", eventID).Scan(&capacity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("event not found: %w", err)
+		}
+		return 0, fmt.Errorf("failed to get event capacity: %w", err)
+	}
+	return capacity, nil
+}
+
+// seatsTaken counts the confirmed going responders of an event and their guests, leaving
+// out exceptUserID
+func seatsTaken(tx *sql.Tx, eventID, exceptUserID string) (int, error) {
+	var taken int
+	err := tx.QueryRow(`
+		SELECT COALESCE(SUM(1 + guests), 0)
+		FROM event_responses
+		WHERE event_id = ? AND response = 'going' AND waitlisted_at IS NULL AND user_id <> ?
+	`, eventID, exceptUserID).Scan(&taken)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count taken seats: %w", err)
+	}
+	return taken, nil
+}
+
+// promoteWaitlist gives free seats to the waitlist in the order it joined, stopping at the
+// first responder whose party does not fit, and returns the promoted responses. Parties
+// larger than the whole event, left over from before its capacity was lowered, are passed
+// over so they do not hold up everyone behind them.
+func promoteWaitlist(tx *sql.Tx, eventID string, capacity int) ([]*models.EventResponse, error) {
+	rows, err := tx.Query(`
+		SELECT `+responseColumns+`
+		FROM event_responses
+		WHERE event_id = ? AND response = 'going' AND waitlisted_at IS NOT NULL
+		ORDER BY waitlisted_at ASC, created_at ASC
+	`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist: %w", err)
+	}
+	var waitlist []*models.EventResponse
+	for rows.Next() {
+		response, err := scanResponse(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan waitlist row: %w", err)
+		}
+		waitlist = append(waitlist, response)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating waitlist rows: %w", err)
+	}
+	if len(waitlist) == 0 {
+		return nil, nil
+	}
+
+	taken, err := seatsTaken(tx, eventID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []*models.EventResponse
 	now := time.Now()
-	response.CreatedAt = now
-	response.UpdatedAt = now
+	for _, response := range waitlist {
+		party := 1 + response.Guests
+		if capacity > 0 && party > capacity {
+			continue
+		}
+		if capacity > 0 && taken+party > capacity {
+			break
+		}
+
+		_, err := tx.Exec("UPDATE event_responses SET waitlisted_at = NULL, updated_at = ? WHERE id = ?", now, response.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to promote waitlisted response: %w", err)
+		}
+		taken += party
+		response.WaitlistedAt = sql.NullTime{}
+		response.UpdatedAt = now
+		promoted = append(promoted, response)
+	}
+
+	return promoted, nil
+}
+
+// AddEventResponse adds a user's response to an event or changes it. Going responders
+// take a seat for themselves and each guest, joining the end of the waitlist when the
+// event is full, and a waitlisted responder keeps their place while they stay going. The
+// response's WaitlistedAt is set to where it ended up. Seats freed by the change go to
+// the waitlist, the other responders promoted are returned.
+func (r *SQLiteRepository) AddEventResponse(response *models.EventResponse) ([]*models.EventResponse, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	capacity, err := lockEvent(tx, response.EventID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check if response already exists
-	existingResponse, err := r.GetUserEventResponse(response.EventID, response.UserID)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return fmt.Errorf("failed to check existing response: %w", err)
+	existing, err := scanResponse(tx.QueryRow(
+		"SELECT "+responseColumns+" FROM event_responses WHERE event_id = ? AND user_id = ?",
+		response.EventID, response.UserID,
+	))
+	if err == sql.ErrNoRows {
+		existing = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to check existing response: %w", err)
 	}
 
-	// If response exists, update it
-	if existingResponse != nil {
-		return r.UpdateEventResponse(response.EventID, response.UserID, response.Response)
+	now := time.Now()
+	response.UpdatedAt = now
+	response.WaitlistedAt = sql.NullTime{}
+
+	if response.Response == "going" && capacity > 0 {
+		confirmed := existing != nil && existing.Response == "going" && !existing.WaitlistedAt.Valid
+		switch {
+		case confirmed:
+			// Already has a seat, only more guests than fit are refused
+			taken, err := seatsTaken(tx, response.EventID, response.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if taken+1+response.Guests > capacity {
+				return nil, ErrNotEnoughSeats
+			}
+		case existing != nil && existing.Response == "going":
+			response.WaitlistedAt = existing.WaitlistedAt
+		default:
+			// Joins the end of the waitlist, promoted below if there is room
+			response.WaitlistedAt = sql.NullTime{Time: now.UTC(), Valid: true}
+		}
 	}
 
-	// Otherwise, insert new response
-	query := `
-		INSERT INTO event_responses (
-			id, event_id, user_id, response, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?)
-	`
+	if existing != nil {
+		response.ID = existing.ID
+		response.CreatedAt = existing.CreatedAt
+		_, err = tx.Exec(`
+			UPDATE event_responses
+			SET response = ?, guests = ?, waitlisted_at = ?, updated_at = ?
+			WHERE id = ?
+		`, response.Response, response.Guests, response.WaitlistedAt, response.UpdatedAt, response.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update event response: %w", err)
+		}
+	} else {
+		if response.ID == "" {
+			response.ID = uuid.New().String()
+		}
+		response.CreatedAt = now
+		_, err = tx.Exec(`
+			INSERT INTO event_responses (
+				id, event_id, user_id, response, guests, waitlisted_at, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, response.ID, response.EventID, response.UserID, response.Response, response.Guests,
+			response.WaitlistedAt, response.CreatedAt, response.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add event response: %w", err)
+		}
+	}
 
-	_, err = r.db.Exec(
-		query,
-		response.ID,
-		response.EventID,
-		response.UserID,
-		response.Response,
-		response.CreatedAt,
-		response.UpdatedAt,
-	)
+	promoted, err := promoteWaitlist(tx, response.EventID, capacity)
 	if err != nil {
-		return fmt.Errorf("failed to add event response: %w", err)
+		return nil, err
 	}
 
-	return nil
+	// The responder may have got a seat straight away, that is not a promotion
+	others := promoted[:0]
+	for _, p := range promoted {
+		if p.UserID == response.UserID {
+			response.WaitlistedAt = sql.NullTime{}
+			continue
+		}
+		others = append(others, p)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit event response: %w", err)
+	}
+
+	return others, nil
 }
 
 // GetEventResponses gets all responses to an event with optional response type filter
@@ -281,7 +529,7 @@ func (r *SQLiteRepository) GetEventResponses(eventID string, responseType string
 
 	if responseType != "" {
 		query = `
-			SELECT id, event_id, user_id, response, created_at, updated_at
+			SELECT ` + responseColumns + `
 			FROM event_responses
 			WHERE event_id = ? AND response = ?
 			ORDER BY created_at DESC
@@ -289,7 +537,7 @@ func (r *SQLiteRepository) GetEventResponses(eventID string, responseType string
 		args = []interface{}{eventID, responseType}
 	} else {
 		query = `
-			SELECT id, event_id, user_id, response, created_at, updated_at
+			SELECT ` + responseColumns + `
 			FROM event_responses
 			WHERE event_id = ?
 			ORDER BY created_at DESC
@@ -306,16 +554,7 @@ func (r *SQLiteRepository) GetEventResponses(eventID string, responseType string
 	var responses []*models.EventResponse
 
 	for rows.Next() {
-		var response models.EventResponse
-
-		err := rows.Scan(
-			&response.ID,
-			&response.EventID,
-			&response.UserID,
-			&response.Response,
-			&response.CreatedAt,
-			&response.UpdatedAt,
-		)
+		response, err := scanResponse(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan response row: %w", err)
 		}
@@ -327,7 +566,7 @@ func (r *SQLiteRepository) GetEventResponses(eventID string, responseType string
 		}
 		response.User = user
 
-		responses = append(responses, &response)
+		responses = append(responses, response)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -340,21 +579,12 @@ func (r *SQLiteRepository) GetEventResponses(eventID string, responseType string
 // GetUserEventResponse gets a user's response to an event
 func (r *SQLiteRepository) GetUserEventResponse(eventID, userID string) (*models.EventResponse, error) {
 	query := `
-		SELECT id, event_id, user_id, response, created_at, updated_at
+		SELECT ` + responseColumns + `
 		FROM event_responses
 		WHERE event_id = ? AND user_id = ?
 	`
 
-	var response models.EventResponse
-
-	err := r.db.QueryRow(query, eventID, userID).Scan(
-		&response.ID,
-		&response.EventID,
-		&response.UserID,
-		&response.Response,
-		&response.CreatedAt,
-		&response.UpdatedAt,
-	)
+	response, err := scanResponse(r.db.QueryRow(query, eventID, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Response not found
@@ -369,66 +599,144 @@ func (r *SQLiteRepository) GetUserEventResponse(eventID, userID string) (*models
 	}
 	response.User = user
 
-	return &response, nil
+	return response, nil
 }
 
-// UpdateEventResponse updates a user's response to an event
-func (r *SQLiteRepository) UpdateEventResponse(eventID, userID, response string) error {
-	query := `
-		UPDATE event_responses
-		SET response = ?, updated_at = ?
-		WHERE event_id = ? AND user_id = ?
-	`
+// DeleteEventResponse deletes a user's response to an event, giving any seats it held to
+// the waitlist, and returns the responses promoted
+func (r *SQLiteRepository) DeleteEventResponse(eventID, userID string) ([]*models.EventResponse, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	_, err := r.db.Exec(
-		query,
-		response,
-		time.Now(),
+	capacity, err := lockEvent(tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		"DELETE FROM event_responses WHERE event_id = ? AND user_id = ?",
 		eventID,
 		userID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update event response: %w", err)
+		return nil, fmt.Errorf("failed to delete event response: %w", err)
 	}
 
-	return nil
+	promoted, err := promoteWaitlist(tx, eventID, capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit event response: %w", err)
+	}
+
+	return promoted, nil
 }
 
-// DeleteEventResponse deletes a user's response to an event
-func (r *SQLiteRepository) DeleteEventResponse(eventID, userID string) error {
-	_, err := r.db.Exec(
-		"DELETE FROM event_responses WHERE event_id = ? AND user_id = ?",
-		eventID,
-		userID,
-	)
+// PromoteWaitlist gives the seats an event has free to its waitlist, after its capacity
+// was raised, and returns the responses promoted
+func (r *SQLiteRepository) PromoteWaitlist(eventID string) ([]*models.EventResponse, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete event response: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	return nil
+	capacity, err := lockEvent(tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	promoted, err := promoteWaitlist(tx, eventID, capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit waitlist promotions: %w", err)
+	}
+
+	return promoted, nil
 }
 
-// GetEventResponseCounts gets the counts of going and not going responses
-func (r *SQLiteRepository) GetEventResponseCounts(eventID string) (going int, notGoing int, err error) {
-	// Get going count
-	err = r.db.QueryRow(
-		"SELECT COUNT(*) FROM event_responses WHERE event_id = ? AND response = 'going'",
-		eventID,
-	).Scan(&going)
+// GetEventResponseCounts gets the number of responses to an event of each type
+func (r *SQLiteRepository) GetEventResponseCounts(eventID string) (*models.EventResponseCounts, error) {
+	rows, err := r.db.Query(`
+		SELECT response, CASE WHEN waitlisted_at IS NULL THEN 0 ELSE 1 END AS waitlisted,
+			COUNT(*), COALESCE(SUM(guests), 0)
+		FROM event_responses
+		WHERE event_id = ?
+		GROUP BY response, CASE WHEN waitlisted_at IS NULL THEN 0 ELSE 1 END
+	`, eventID)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get going count: %w", err)
+		return nil, fmt.Errorf("failed to get response counts: %w", err)
 	}
+	defer rows.Close()
 
-	// Get not going count
-	err = r.db.QueryRow(
-		"SELECT COUNT(*) FROM event_responses WHERE event_id = ? AND response = 'not_going'",
-		eventID,
-	).Scan(&notGoing)
+	counts := &models.EventResponseCounts{}
+	for rows.Next() {
+		var response string
+		var waitlisted, count, guests int
+		if err := rows.Scan(&response, &waitlisted, &count, &guests); err != nil {
+			return nil, fmt.Errorf("failed to scan response counts: %w", err)
+		}
+
+		switch {
+		case response == "going" && waitlisted == 1:
+			counts.Waitlisted += count
+		case response == "going":
+			counts.Going += count
+			counts.Guests += guests
+		case response == "not_going":
+			counts.NotGoing += count
+		case response == "interested":
+			counts.Interested += count
+		case response == "maybe":
+			counts.Maybe += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating response counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetFeedToken gets the token of a user's calendar feed, empty if they have none
+func (r *SQLiteRepository) GetFeedToken(userID string) (string, error) {
+	var token string
+	err := r.db.QueryRow("SELECT token FROM calendar_feed_tokens WHERE user_id = ?", userID).Scan(&token)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get feed token: %w", err)
+	}
+	return token, nil
+}
+
+// SaveFeedToken sets the token of a user's calendar feed, replacing any earlier one
+func (r *SQLiteRepository) SaveFeedToken(userID, token string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO calendar_feed_tokens (user_id, token, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET token = excluded.token, created_at = excluded.created_at
+	`, userID, token, time.Now())
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get not going count: %w", err)
+		return fmt.Errorf("failed to save feed token: %w", err)
 	}
+	return nil
+}
 
-	return going, notGoing, nil
+// GetUserIDByFeedToken gets the user a calendar feed token belongs to, empty if none
+func (r *SQLiteRepository) GetUserIDByFeedToken(token string) (string, error) {
+	var userID string
+	err := r.db.QueryRow("SELECT user_id FROM calendar_feed_tokens WHERE token = ?", token).Scan(&userID)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get feed token: %w", err)
+	}
+	return userID, nil
 }
 
 // GetUserBasicByID gets basic user information by ID
@@ -509,23 +817,6 @@ func (r *SQLiteRepository) IsGroupMember(groupID, userID string) (bool, error) {
 	return count > 0, nil
 }
 
-// GetMemberRole gets a member's role in a group
-func (r *SQLiteRepository) GetMemberRole(groupID, userID string) (string, error) {
-	var role string
-	err := r.db.QueryRow(
-		"SELECT role FROM group_members WHERE group_id = ? AND user_id = ? AND status = 'accepted'",
-		groupID, userID,
-	).Scan(&role)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", fmt.Errorf("user is not a member of this group")
-		}
-		return "", fmt.Errorf("failed to get member role: %w", err)
-	}
-
-	return role, nil
-}
-
 // GetGroupMembers gets all members of a group with optional status filter
 func (r *SQLiteRepository) GetGroupMembers(groupID string, status string) ([]*models.GroupMember, error) {
 	var query string
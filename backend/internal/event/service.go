@@ -1,32 +1,125 @@
 package event
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"mime/multipart"
 	"time"
 
+	"github.com/Athooh/social-network/internal/group"
 	notifications "github.com/Athooh/social-network/internal/notifcations"
 	"github.com/Athooh/social-network/pkg/filestore"
+	"github.com/Athooh/social-network/pkg/ical"
 	"github.com/Athooh/social-network/pkg/logger"
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/scheduler"
 	"github.com/Athooh/social-network/pkg/websocket"
+	"github.com/Athooh/social-network/pkg/websocket/events"
 	"github.com/google/uuid"
 )
 
 // Service defines the interface for event business logic
 type Service interface {
 	// Event operations
-	CreateEvent(groupID, userID, title, description string, eventDate time.Time, banner *multipart.FileHeader, response string) (*models.GroupEvent, error)
-	GetEvent(eventID, userID string) (*models.GroupEvent, error)
-	GetGroupEvents(groupID, userID string) ([]*models.GroupEvent, error)
-	UpdateEvent(eventID, userID, title, description string, eventDate time.Time, banner *multipart.FileHeader) (*models.GroupEvent, error)
-	DeleteEvent(eventID, userID string) error
+	CreateEvent(ctx context.Context, groupID, userID string, details EventDetails, banner *multipart.FileHeader, response string) (*models.GroupEvent, error)
+	GetEvent(ctx context.Context, eventID, userID string) (*models.GroupEvent, error)
+	GetGroupEvents(ctx context.Context, groupID, userID string, from, to time.Time) ([]*models.GroupEvent, error)
+	UpdateEvent(ctx context.Context, eventID, userID string, details EventDetails, banner *multipart.FileHeader) (*models.GroupEvent, error)
+	DeleteEvent(ctx context.Context, eventID, userID string) error
+	CancelOccurrence(ctx context.Context, eventID, userID string, occurrence time.Time) (*models.GroupEvent, error)
+
+	// Calendar operations
+	GetEventCalendar(ctx context.Context, eventID, userID string) (*ical.Calendar, error)
+	GetFeedToken(ctx context.Context, userID string, rotate bool) (string, error)
+	GetFeedCalendar(ctx context.Context, token string) (*ical.Calendar, error)
 
 	// Event responses operations
-	RespondToEvent(eventID, userID, response string) error
-	GetEventResponses(eventID, userID string, responseType string) ([]*models.EventResponse, error)
+	RespondToEvent(ctx context.Context, eventID, userID, response string, guests int) (*models.EventResponse, error)
+	RemoveEventResponse(ctx context.Context, eventID, userID string) error
+	GetEventResponses(ctx context.Context, eventID, userID string, responseType string) ([]*models.EventResponse, error)
+}
+
+// EventDetails are the fields of an event set by its creator
+type EventDetails struct {
+	Title       string
+	Description string
+	Start       time.Time
+	End         time.Time // Zero for events without an end
+	TimeZone    string    // IANA name, UTC when empty
+	Location    string
+	Capacity    int    // Seats, guests included, zero for no limit
+	GuestLimit  int    // Guests each going responder may bring
+	Recurrence  string // RRULE value, empty for one-off events
+}
+
+// normalize validates the details and puts the recurrence rule in canonical form
+func (d *EventDetails) normalize() error {
+	if d.TimeZone == "" {
+		d.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(d.TimeZone); err != nil {
+		return fmt.Errorf("unknown time zone %q", d.TimeZone)
+	}
+
+	if !d.End.IsZero() && !d.End.After(d.Start) {
+		return errors.New("event must end after it starts")
+	}
+
+	if d.Capacity < 0 {
+		return errors.New("capacity cannot be negative")
+	}
+
+	if d.GuestLimit < 0 {
+		return errors.New("guest limit cannot be negative")
+	}
+
+	// A responder and their guests must fit in the event together
+	if d.Capacity > 0 && d.GuestLimit >= d.Capacity {
+		return errors.New("guest limit must be less than the capacity")
+	}
+
+	if d.Recurrence != "" {
+		rule, err := ical.ParseRule(d.Recurrence)
+		if err != nil {
+			return fmt.Errorf("invalid recurrence rule: %w", err)
+		}
+		d.Recurrence = rule.String()
+	}
+
+	return nil
+}
+
+// apply copies the details onto an event
+func (d *EventDetails) apply(event *models.GroupEvent) {
+	event.Title = d.Title
+	event.Description = d.Description
+	event.EventDate = d.Start
+	event.EndDate = sql.NullTime{Time: d.End, Valid: !d.End.IsZero()}
+	event.TimeZone = d.TimeZone
+	event.Location = d.Location
+	event.Capacity = d.Capacity
+	event.GuestLimit = d.GuestLimit
+	event.Recurrence = d.Recurrence
+}
+
+// Responses to an event
+const (
+	ResponseGoing      = "going"
+	ResponseNotGoing   = "not_going"
+	ResponseInterested = "interested"
+	ResponseMaybe      = "maybe"
+)
+
+// setUserResponse sets the current user's response on an event, response may be nil
+func setUserResponse(event *models.GroupEvent, response *models.EventResponse) {
+	if response == nil {
+		return
+	}
+	event.UserResponse = response.Response
+	event.UserGuests = response.Guests
+	event.UserWaitlisted = response.WaitlistedAt.Valid
 }
 
 // EventService implements the Service interface
@@ -36,42 +129,48 @@ type EventService struct {
 	log                 *logger.Logger
 	wsHub               *websocket.Hub
 	notificationService *NotificationService
+	groupAuth           *group.Authorizer
+	jobs                *scheduler.Scheduler
+	reminderOffsets     []time.Duration
 }
 
-// NewService creates a new event service
-func NewService(repo Repository, fileStore *filestore.FileStore, log *logger.Logger, NotificationRepo notifications.Service, wsHub *websocket.Hub) *EventService {
+// NewService creates a new event service. Users going to an event are reminded of it at
+// each of reminderOffsets before it starts, through jobs.
+func NewService(repo Repository, fileStore *filestore.FileStore, log *logger.Logger, NotificationRepo notifications.Service, wsHub *websocket.Hub, groupAuth *group.Authorizer, jobs *scheduler.Scheduler, reminderOffsets []time.Duration) *EventService {
 	notificationSvc := NewNotificationService(wsHub, repo, NotificationRepo, log)
 
-	return &EventService{
+	s := &EventService{
 		repo:                repo,
 		fileStore:           fileStore,
 		log:                 log,
 		wsHub:               wsHub,
 		notificationService: notificationSvc,
+		groupAuth:           groupAuth,
+		jobs:                jobs,
+		reminderOffsets:     reminderOffsets,
 	}
+	jobs.Handle(JobEventReminder, s.sendReminder)
+
+	return s
 }
 
 // CreateEvent creates a new event in a group
-func (s *EventService) CreateEvent(groupID, userID, title, description string, eventDate time.Time, banner *multipart.FileHeader, response string) (*models.GroupEvent, error) {
-	// Check if user is a member of the group
-	isMember, err := s.repo.IsGroupMember(groupID, userID)
-	if err != nil {
+func (s *EventService) CreateEvent(ctx context.Context, groupID, userID string, details EventDetails, banner *multipart.FileHeader, response string) (*models.GroupEvent, error) {
+	if err := s.groupAuth.Require(groupID, userID, group.PermCreateEvents, "creating events"); err != nil {
 		return nil, err
 	}
 
-	if !isMember {
-		return nil, errors.New("you must be a member of the group to create an event")
+	if err := details.normalize(); err != nil {
+		return nil, err
 	}
 
 	// Create event
 	event := &models.GroupEvent{
-		ID:          uuid.New().String(),
-		GroupID:     groupID,
-		CreatorID:   userID,
-		Title:       title,
-		Description: description,
-		EventDate:   eventDate,
+		ID:        uuid.New().String(),
+		GroupID:   groupID,
+		CreatorID: userID,
 	}
+	details.apply(event)
 
 	// Handle banner upload if provided
 	if banner != nil {
@@ -112,25 +211,27 @@ func (s *EventService) CreateEvent(groupID, userID, title, description string, e
 				UserId:          member.UserID,
 				SenderId:        sql.NullString{String: userID, Valid: true},
 				NotficationType: "groupEvent",
-				Message:         fmt.Sprintf(event.Title),
+				Message:         event.Title,
 				TargetGroupID:   sql.NullString{String: groupID, Valid: true},
 				TargetEventID:   sql.NullString{String: event.ID, Valid: true},
 			}
 			// Notify group members about new event
-			s.notificationService.SendEventCreatedNotification(userID, member.UserID, newNotification, eventcreator)
+			s.notificationService.SendEventCreatedNotification(ctx, userID, member.UserID, newNotification, eventcreator)
 		}
 	}
 	
-	err = s.RespondToEvent(event.ID, userID, response)
+	_, err = s.RespondToEvent(ctx, event.ID, userID, response, 0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to respond to event: %w", err)
 	}
 
+	s.rescheduleReminders(ctx, fullEvent)
+
 	return fullEvent, nil
 }
 
 // GetEvent gets an event by ID
-func (s *EventService) GetEvent(eventID, userID string) (*models.GroupEvent, error) {
+func (s *EventService) GetEvent(ctx context.Context, eventID, userID string) (*models.GroupEvent, error) {
 	// Get event
 	event, err := s.repo.GetEventByID(eventID)
 	if err != nil {
@@ -153,15 +254,13 @@ func (s *EventService) GetEvent(eventID, userID string) (*models.GroupEvent, err
 		return nil, err
 	}
 
-	if userResponse != nil {
-		event.UserResponse = userResponse.Response
-	}
+	setUserResponse(event, userResponse)
 
 	return event, nil
 }
 
-// GetGroupEvents gets all events in a group
-func (s *EventService) GetGroupEvents(groupID, userID string) ([]*models.GroupEvent, error) {
+// GetGroupEvents gets the events in a group between from and to, one per occurrence
+func (s *EventService) GetGroupEvents(ctx context.Context, groupID, userID string, from, to time.Time) ([]*models.GroupEvent, error) {
 	// Check if user is a member of the group
 	isMember, err := s.repo.IsGroupMember(groupID, userID)
 	if err != nil {
@@ -173,51 +272,56 @@ func (s *EventService) GetGroupEvents(groupID, userID string) ([]*models.GroupEv
 	}
 
 	// Get events
-	events, err := s.repo.GetGroupEvents(groupID)
+	events, err := s.repo.GetGroupEvents(groupID, from, to)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get user's response to each event
+	// Get user's response to each event, shared by the occurrences of a series
+	responses := make(map[string]*models.EventResponse)
 	for _, event := range events {
-		userResponse, err := s.repo.GetUserEventResponse(event.ID, userID)
-		if err != nil {
-			return nil, err
+		response, ok := responses[event.ID]
+		if !ok {
+			response, err = s.repo.GetUserEventResponse(event.ID, userID)
+			if err != nil {
+				return nil, err
+			}
+			responses[event.ID] = response
 		}
 
-		if userResponse != nil {
-			event.UserResponse = userResponse.Response
-		}
+		setUserResponse(event, response)
 	}
 
 	return events, nil
 }
 
 // UpdateEvent updates an event
-func (s *EventService) UpdateEvent(eventID, userID, title, description string, eventDate time.Time, banner *multipart.FileHeader) (*models.GroupEvent, error) {
+func (s *EventService) UpdateEvent(ctx context.Context, eventID, userID string, details EventDetails, banner *multipart.FileHeader) (*models.GroupEvent, error) {
 	// Get event
 	event, err := s.repo.GetEventByID(eventID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if user is the creator or an admin
+	// Check if user is the creator or may edit the group
 	if event.CreatorID != userID {
-		// Check if user is an admin
-		role, err := s.repo.GetMemberRole(event.GroupID, userID)
-		if err != nil {
+		if err := s.groupAuth.Require(event.GroupID, userID, group.PermEditSettings, "updating others' events"); err != nil {
 			return nil, err
 		}
+	}
 
-		if role != "admin" {
-			return nil, errors.New("only the event creator or group admins can update this event")
-		}
+	if err := details.normalize(); err != nil {
+		return nil, err
+	}
+
+	// Cancelled occurrences only make sense for the series they were cancelled in
+	rescheduled := details.Recurrence != event.Recurrence || !details.Start.Equal(event.EventDate) || details.TimeZone != event.TimeZone
+	if rescheduled {
+		event.Exceptions = ""
 	}
 
 	// Update fields
-	event.Title = title
-	event.Description = description
-	event.EventDate = eventDate
+	details.apply(event)
 
 	// Handle banner upload if provided
 	if banner != nil {
@@ -240,28 +344,97 @@ func (s *EventService) UpdateEvent(eventID, userID, title, description string, e
 		return nil, err
 	}
 
+	// A larger capacity frees seats for the waitlist
+	promoted, err := s.repo.PromoteWaitlist(eventID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to promote the waitlist of event %s: %v", eventID, err)
+	}
+	s.notifyPromoted(ctx, updatedEvent, promoted)
+
+	// Move the reminders and tell those who responded
+	s.rescheduleReminders(ctx, updatedEvent)
+
+	message := fmt.Sprintf("updated the event %s", updatedEvent.Title)
+	if rescheduled {
+		message = fmt.Sprintf("rescheduled the event %s to %s", updatedEvent.Title, formatEventTime(updatedEvent, updatedEvent.EventDate))
+	}
+	s.notifyResponders(ctx, updatedEvent, userID, events.GroupEventUpdated, message)
+
+	return updatedEvent, nil
+}
+
+// CancelOccurrence cancels one occurrence of a recurring event, leaving the rest of the series
+func (s *EventService) CancelOccurrence(ctx context.Context, eventID, userID string, occurrence time.Time) (*models.GroupEvent, error) {
+	// Get event
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same rights as editing the event
+	if event.CreatorID != userID {
+		if err := s.groupAuth.Require(event.GroupID, userID, group.PermEditSettings, "updating others' events"); err != nil {
+			return nil, err
+		}
+	}
+
+	if event.Recurrence == "" {
+		return nil, errors.New("only occurrences of recurring events can be cancelled")
+	}
+
+	// The time must be the start of an occurrence that is still scheduled
+	starts, err := expandOccurrences(event, occurrence, occurrence.Add(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, start := range starts {
+		if start.EventDate.Equal(occurrence) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.New("event has no occurrence at that time")
+	}
+
+	event.Exceptions = formatExceptions(append(parseExceptions(event.Exceptions), occurrence))
+
+	if err := s.repo.UpdateEvent(event); err != nil {
+		return nil, err
+	}
+
+	updatedEvent, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.rescheduleReminders(ctx, updatedEvent)
+	s.notifyResponders(ctx, updatedEvent, userID, events.GroupEventUpdated,
+		fmt.Sprintf("cancelled the %s occurrence of the event %s", formatEventTime(updatedEvent, occurrence), updatedEvent.Title))
+
 	return updatedEvent, nil
 }
 
 // DeleteEvent deletes an event
-func (s *EventService) DeleteEvent(eventID, userID string) error {
+func (s *EventService) DeleteEvent(ctx context.Context, eventID, userID string) error {
 	// Get event
 	event, err := s.repo.GetEventByID(eventID)
 	if err != nil {
 		return err
 	}
 
-	// Check if user is the creator or an admin
+	// Check if user is the creator or may delete others' content
 	if event.CreatorID != userID {
-		// Check if user is an admin
-		role, err := s.repo.GetMemberRole(event.GroupID, userID)
-		if err != nil {
+		if err := s.groupAuth.Require(event.GroupID, userID, group.PermDeleteContent, "deleting others' events"); err != nil {
 			return err
 		}
+	}
 
-		if role != "admin" {
-			return errors.New("only the event creator or group admins can delete this event")
-		}
+	// Responses go with the event, get who to tell first
+	responses, err := s.repo.GetEventResponses(eventID, "")
+	if err != nil {
+		return err
 	}
 
 	// Delete event
@@ -269,30 +442,54 @@ func (s *EventService) DeleteEvent(eventID, userID string) error {
 		return err
 	}
 
+	if err := s.cancelReminders(eventID); err != nil {
+		s.log.WithContext(ctx).Error("Failed to cancel reminders of event %s: %v", eventID, err)
+	}
+
+	userIDs := make([]string, 0, len(responses))
+	for _, response := range responses {
+		userIDs = append(userIDs, response.UserID)
+	}
+	s.notificationService.SendEventChangedNotification(ctx, event, userID, userIDs, events.GroupEventDeleted,
+		fmt.Sprintf("cancelled the event %s", event.Title))
+
 	return nil
 }
 
-// RespondToEvent handles a user's response to an event
-func (s *EventService) RespondToEvent(eventID, userID, responseType string) error {
+// RespondToEvent handles a user's response to an event. Going responders may bring up to
+// the event's guest limit, and wait for a seat when the event is full.
+func (s *EventService) RespondToEvent(ctx context.Context, eventID, userID, responseType string, guests int) (*models.EventResponse, error) {
 	// Check if response type is valid
-	if responseType != "going" && responseType != "not_going" {
-		return errors.New("invalid response type")
+	switch responseType {
+	case ResponseGoing, ResponseNotGoing, ResponseInterested, ResponseMaybe:
+	default:
+		return nil, errors.New("invalid response type")
 	}
 
 	// Get event
 	event, err := s.repo.GetEventByID(eventID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check if user is a member of the group
 	isMember, err := s.repo.IsGroupMember(event.GroupID, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !isMember {
-		return errors.New("you must be a member of the group to respond to this event")
+		return nil, errors.New("you must be a member of the group to respond to this event")
+	}
+
+	if guests < 0 || guests > event.GuestLimit {
+		return nil, fmt.Errorf("you can bring at most %d guests to this event", event.GuestLimit)
+	}
+	if guests > 0 && responseType != ResponseGoing {
+		return nil, errors.New("only going responders can bring guests")
+	}
+	if event.Capacity > 0 && 1+guests > event.Capacity {
+		return nil, fmt.Errorf("this event has %d seats, not enough for you and %d guests", event.Capacity, guests)
 	}
 
 	// Create response
@@ -300,18 +497,47 @@ func (s *EventService) RespondToEvent(eventID, userID, responseType string) erro
 		EventID:  eventID,
 		UserID:   userID,
 		Response: responseType,
+		Guests:   guests,
 	}
 
 	// Save response
-	if err := s.repo.AddEventResponse(response); err != nil {
+	promoted, err := s.repo.AddEventResponse(response)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyPromoted(ctx, event, promoted)
+
+	return response, nil
+}
+
+// RemoveEventResponse withdraws a user's response to an event, giving their seats to the
+// waitlist
+func (s *EventService) RemoveEventResponse(ctx context.Context, eventID, userID string) error {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return err
+	}
+
+	promoted, err := s.repo.DeleteEventResponse(eventID, userID)
+	if err != nil {
 		return err
 	}
 
+	s.notifyPromoted(ctx, event, promoted)
+
 	return nil
 }
 
+// notifyPromoted tells the responders who got a seat off the waitlist
+func (s *EventService) notifyPromoted(ctx context.Context, event *models.GroupEvent, promoted []*models.EventResponse) {
+	for _, response := range promoted {
+		s.notificationService.SendWaitlistPromotedNotification(ctx, event, response)
+	}
+}
+
 // GetEventResponses gets all responses to an event
-func (s *EventService) GetEventResponses(eventID, userID, responseType string) ([]*models.EventResponse, error) {
+func (s *EventService) GetEventResponses(ctx context.Context, eventID, userID, responseType string) ([]*models.EventResponse, error) {
 	// Get event
 	event, err := s.repo.GetEventByID(eventID)
 	if err != nil {
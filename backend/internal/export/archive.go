@@ -0,0 +1,164 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// mediaDir is the folder of the archive holding the user's uploaded files, under the
+// same relative paths the records use
+const mediaDir = "media/"
+
+// section is a JSON file of an archive
+type section struct {
+	name string
+	get  func(userID string) (interface{}, error)
+}
+
+// writeArchive writes the ZIP archive of a user's data to dst and returns its size. The
+// archive is written next to dst first so that dst only ever holds complete archives.
+func (s *Service) writeArchive(ctx context.Context, userID, dst string) (int64, error) {
+	tmp := dst + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export archive: %w", err)
+	}
+
+	zw := zip.NewWriter(file)
+	err = s.writeContents(ctx, zw, userID)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// writeContents writes a JSON file for each kind of data of the user, then the media
+// their profile, posts and comments refer to
+func (s *Service) writeContents(ctx context.Context, zw *zip.Writer, userID string) error {
+	profile, err := s.repo.GetProfile(userID)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return errors.New("user not found")
+	}
+	if err := writeJSON(zw, "profile.json", profile); err != nil {
+		return err
+	}
+
+	posts, err := s.repo.GetPosts(userID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "posts.json", posts); err != nil {
+		return err
+	}
+
+	comments, err := s.repo.GetComments(userID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "comments.json", comments); err != nil {
+		return err
+	}
+
+	sections := []section{
+		{"reactions.json", func(id string) (interface{}, error) { return s.repo.GetReactions(id) }},
+		{"followers.json", func(id string) (interface{}, error) { return s.repo.GetFollowers(id) }},
+		{"following.json", func(id string) (interface{}, error) { return s.repo.GetFollowing(id) }},
+		{"groups.json", func(id string) (interface{}, error) { return s.repo.GetMemberships(id) }},
+		{"event_responses.json", func(id string) (interface{}, error) { return s.repo.GetEventResponses(id) }},
+		{"messages.json", func(id string) (interface{}, error) { return s.repo.GetMessages(id) }},
+		{"group_messages.json", func(id string) (interface{}, error) { return s.repo.GetGroupMessages(id) }},
+		{"notifications.json", func(id string) (interface{}, error) { return s.repo.GetNotifications(id) }},
+	}
+	for _, sec := range sections {
+		records, err := sec.get(userID)
+		if err != nil {
+			return err
+		}
+		if err := writeJSON(zw, sec.name, records); err != nil {
+			return err
+		}
+	}
+
+	media := []string{profile.Avatar, profile.BannerImage, profile.ProfileImage}
+	for _, p := range posts {
+		media = append(media, p.Image, p.Video)
+	}
+	for _, c := range comments {
+		media = append(media, c.Image)
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range media {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if err := s.writeMedia(ctx, zw, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeMedia copies an uploaded file into the archive. Files that are gone or don't
+// belong to the upload directory are left out.
+func (s *Service) writeMedia(ctx context.Context, zw *zip.Writer, name string) error {
+	src, err := s.fileStore.Open(name)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrInvalid) {
+		s.log.WithContext(ctx).Debug("Leaving missing media %s out of export", name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(mediaDir + path.Clean(filepath.ToSlash(name)))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("failed to copy media %s: %w", name, err)
+	}
+	return nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
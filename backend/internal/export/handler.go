@@ -0,0 +1,135 @@
+package export
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Athooh/social-network/internal/auth"
+	"github.com/Athooh/social-network/pkg/httputil"
+	"github.com/Athooh/social-network/pkg/logger"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// downloadPath is where HandleDownload is routed
+const downloadPath = "/api/exports/download"
+
+// Handler handles HTTP requests for personal data exports
+type Handler struct {
+	service *Service
+	log     *logger.Logger
+}
+
+// NewHandler creates a new export handler
+func NewHandler(service *Service, log *logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		log:     log,
+	}
+}
+
+// ExportResponse is an export as returned by the API
+type ExportResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	DownloadURL string     `json:"downloadUrl,omitempty"` // Only while the archive can be downloaded
+}
+
+func newExportResponse(export *models.DataExport) *ExportResponse {
+	response := &ExportResponse{
+		ID:        export.ID,
+		Status:    export.Status,
+		Size:      export.Size,
+		CreatedAt: export.CreatedAt,
+	}
+	if export.CompletedAt.Valid {
+		response.CompletedAt = &export.CompletedAt.Time
+	}
+	if export.ExpiresAt.Valid {
+		response.ExpiresAt = &export.ExpiresAt.Time
+	}
+	if export.Status == models.ExportReady && time.Now().Before(export.ExpiresAt.Time) {
+		response.DownloadURL = DownloadURL(export)
+	}
+	return response
+}
+
+// HandleExports requests a new export of the current user's data on POST, and reports on
+// the latest one on GET
+func (h *Handler) HandleExports(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		export, err := h.service.GetLatestExport(r.Context(), userID)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "Failed to get export")
+			return
+		}
+		if export == nil {
+			h.sendError(w, http.StatusNotFound, "No export requested yet")
+			return
+		}
+		h.sendJSON(w, http.StatusOK, newExportResponse(export))
+
+	case http.MethodPost:
+		export, err := h.service.RequestExport(r.Context(), userID)
+		if errors.Is(err, ErrTooSoon) {
+			h.sendError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "Failed to request export")
+			return
+		}
+		h.sendJSON(w, http.StatusAccepted, newExportResponse(export))
+
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleDownload serves the archive of an export. The token in the URL grants access
+// instead of a session, so that the link works from notifications and download managers.
+func (h *Handler) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	export, file, err := h.service.OpenDownload(r.Context(), r.URL.Query().Get("token"))
+	if errors.Is(err, ErrExportNotFound) {
+		h.sendError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to open export: %v", err)
+		h.sendError(w, http.StatusInternalServerError, "Failed to download export")
+		return
+	}
+	defer file.Close()
+
+	name := "social-network-data-" + export.CreatedAt.Format("2006-01-02") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.ServeContent(w, r, name, export.CompletedAt.Time, file)
+}
+
+// Helper method to send JSON responses
+func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	httputil.SendJSON(w, status, data)
+}
+
+// Helper method to send error responses
+func (h *Handler) sendError(w http.ResponseWriter, status int, message string) {
+	httputil.SendError(w, status, message, status < 500)
+}
@@ -0,0 +1,124 @@
+package export
+
+import "time"
+
+// The records below are what a data export holds. Each kind is written to its own JSON
+// file, media paths point into the archive's media folder.
+
+// Profile is the account and profile of the exported user, without their password
+type Profile struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	FirstName    string    `json:"firstName"`
+	LastName     string    `json:"lastName"`
+	DateOfBirth  string    `json:"dateOfBirth"`
+	Nickname     string    `json:"nickname"`
+	AboutMe      string    `json:"aboutMe"`
+	Avatar       string    `json:"avatar,omitempty"`
+	IsPublic     bool      `json:"isPublic"`
+	Username     string    `json:"username"`
+	FullName     string    `json:"fullName"`
+	Bio          string    `json:"bio"`
+	Work         string    `json:"work"`
+	Education    string    `json:"education"`
+	ContactEmail string    `json:"contactEmail"`
+	Phone        string    `json:"phone"`
+	Website      string    `json:"website"`
+	Location     string    `json:"location"`
+	TechSkills   string    `json:"techSkills"`
+	SoftSkills   string    `json:"softSkills"`
+	Interests    string    `json:"interests"`
+	BannerImage  string    `json:"bannerImage,omitempty"`
+	ProfileImage string    `json:"profileImage,omitempty"`
+	IsPrivate    bool      `json:"isPrivate"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Post is a post the user wrote, on their profile or in a group
+type Post struct {
+	ID           int64     `json:"id"`
+	GroupID      string    `json:"groupId,omitempty"`
+	Content      string    `json:"content"`
+	Image        string    `json:"image,omitempty"`
+	Video        string    `json:"video,omitempty"`
+	Privacy      string    `json:"privacy,omitempty"`
+	Status       string    `json:"status,omitempty"` // Moderation status of group posts
+	SharedPostID int64     `json:"sharedPostId,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// Comment is a comment or reply the user wrote
+type Comment struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"postId"`
+	ParentID  int64     `json:"parentId,omitempty"`
+	Content   string    `json:"content"`
+	Image     string    `json:"image,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Reaction is a reaction the user left on a post, or a like on a comment
+type Reaction struct {
+	TargetType   string    `json:"targetType"` // post or comment
+	TargetID     int64     `json:"targetId"`
+	ReactionType string    `json:"reactionType"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Connection is a follower of the user or someone they follow
+type Connection struct {
+	UserID string    `json:"userId"`
+	Name   string    `json:"name"`
+	Since  time.Time `json:"since"`
+}
+
+// Membership is a group the user belongs to, asked to join or was invited to
+type Membership struct {
+	GroupID   string    `json:"groupId"`
+	GroupName string    `json:"groupName"`
+	Role      string    `json:"role"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// EventResponse is the user's response to a group event
+type EventResponse struct {
+	EventID    string    `json:"eventId"`
+	EventTitle string    `json:"eventTitle"`
+	GroupID    string    `json:"groupId"`
+	Response   string    `json:"response"`
+	Guests     int       `json:"guests"`
+	Waitlisted bool      `json:"waitlisted"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Message is a private message the user sent or received
+type Message struct {
+	ID         int64     `json:"id"`
+	SenderID   string    `json:"senderId"`
+	ReceiverID string    `json:"receiverId"`
+	Content    string    `json:"content"`
+	IsRead     bool      `json:"isRead"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// GroupMessage is a message the user sent in a group chat
+type GroupMessage struct {
+	ID        int64     `json:"id"`
+	GroupID   string    `json:"groupId"`
+	GroupName string    `json:"groupName"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Notification is a notification the user received
+type Notification struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	SenderID  string    `json:"senderId,omitempty"`
+	IsRead    bool      `json:"isRead"`
+	CreatedAt time.Time `json:"createdAt"`
+}
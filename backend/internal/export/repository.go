@@ -0,0 +1,375 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// Repository stores data exports and reads the data that goes into them
+type Repository interface {
+	CreateExport(export *models.DataExport) error
+	// GetExport gets an export by ID, nil if there is none
+	GetExport(id string) (*models.DataExport, error)
+	// GetExportByToken gets the export a download token belongs to, nil if there is none
+	GetExportByToken(token string) (*models.DataExport, error)
+	// GetLatestExport gets the export the user requested last, nil if there is none
+	GetLatestExport(userID string) (*models.DataExport, error)
+	// CompleteExport marks an export ready to download until expiresAt
+	CompleteExport(id, fileName string, size int64, completedAt, expiresAt time.Time) error
+	// SetExportStatus changes the status of an export, such as to failed or expired
+	SetExportStatus(id, status string) error
+
+	GetProfile(userID string) (*Profile, error)
+	GetPosts(userID string) ([]*Post, error)
+	GetComments(userID string) ([]*Comment, error)
+	GetReactions(userID string) ([]*Reaction, error)
+	GetFollowers(userID string) ([]*Connection, error)
+	GetFollowing(userID string) ([]*Connection, error)
+	GetMemberships(userID string) ([]*Membership, error)
+	GetEventResponses(userID string) ([]*EventResponse, error)
+	GetMessages(userID string) ([]*Message, error)
+	GetGroupMessages(userID string) ([]*GroupMessage, error)
+	GetNotifications(userID string) ([]*Notification, error)
+}
+
+// SQLiteRepository implements Repository for SQLite. Times are stored in UTC, SQLite
+// compares them as text.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite repository
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// CreateExport adds a newly requested export
+func (r *SQLiteRepository) CreateExport(export *models.DataExport) error {
+	_, err := r.db.Exec(`
+		INSERT INTO data_exports (id, user_id, status, token, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, export.ID, export.UserID, export.Status, export.Token, export.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+	return nil
+}
+
+const exportColumns = "id, user_id, status, token, file_name, size, created_at, completed_at, expires_at"
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (r *SQLiteRepository) getExport(where string, args ...interface{}) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.QueryRow("SELECT "+exportColumns+" FROM data_exports WHERE "+where, args...).Scan(
+		&export.ID, &export.UserID, &export.Status, &export.Token, &export.FileName, &export.Size,
+		&export.CreatedAt, &export.CompletedAt, &export.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	return &export, nil
+}
+
+// GetExport gets an export by ID, nil if there is none
+func (r *SQLiteRepository) GetExport(id string) (*models.DataExport, error) {
+	return r.getExport("id = ?", id)
+}
+
+// GetExportByToken gets the export a download token belongs to, nil if there is none
+func (r *SQLiteRepository) GetExportByToken(token string) (*models.DataExport, error) {
+	return r.getExport("token = ?", token)
+}
+
+// GetLatestExport gets the export the user requested last, nil if there is none
+func (r *SQLiteRepository) GetLatestExport(userID string) (*models.DataExport, error) {
+	return r.getExport("user_id = ? ORDER BY created_at DESC LIMIT 1", userID)
+}
+
+// CompleteExport marks an export ready to download until expiresAt
+func (r *SQLiteRepository) CompleteExport(id, fileName string, size int64, completedAt, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE data_exports
+		SET status = ?, file_name = ?, size = ?, completed_at = ?, expires_at = ?
+		WHERE id = ?
+	`, models.ExportReady, fileName, size, completedAt.UTC(), expiresAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to complete export: %w", err)
+	}
+	return nil
+}
+
+// SetExportStatus changes the status of an export, such as to failed or expired
+func (r *SQLiteRepository) SetExportStatus(id, status string) error {
+	if _, err := r.db.Exec("UPDATE data_exports SET status = ? WHERE id = ?", status, id); err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+	return nil
+}
+
+// GetProfile gets the account and profile of a user, nil if there is no such user
+func (r *SQLiteRepository) GetProfile(userID string) (*Profile, error) {
+	var p Profile
+	err := r.db.QueryRow(`
+		SELECT u.id, u.email, u.first_name, u.last_name, u.date_of_birth,
+			COALESCE(u.nickname, ''), COALESCE(u.about_me, ''), COALESCE(u.avatar, ''), u.is_public,
+			COALESCE(up.username, ''), COALESCE(up.full_name, ''), COALESCE(up.bio, ''),
+			COALESCE(up.work, ''), COALESCE(up.education, ''), COALESCE(up.email, ''),
+			COALESCE(up.phone, ''), COALESCE(up.website, ''), COALESCE(up.location, ''),
+			COALESCE(up.tech_skills, ''), COALESCE(up.soft_skills, ''), COALESCE(up.interests, ''),
+			COALESCE(up.banner_image, ''), COALESCE(up.profile_image, ''), COALESCE(up.is_private, FALSE),
+			u.created_at
+		FROM users u
+		LEFT JOIN user_profiles up ON up.user_id = u.id
+		WHERE u.id = ?
+	`, userID).Scan(
+		&p.ID, &p.Email, &p.FirstName, &p.LastName, &p.DateOfBirth,
+		&p.Nickname, &p.AboutMe, &p.Avatar, &p.IsPublic,
+		&p.Username, &p.FullName, &p.Bio,
+		&p.Work, &p.Education, &p.ContactEmail,
+		&p.Phone, &p.Website, &p.Location,
+		&p.TechSkills, &p.SoftSkills, &p.Interests,
+		&p.BannerImage, &p.ProfileImage, &p.IsPrivate,
+		&p.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	return &p, nil
+}
+
+// queryAll runs a query and scans each of its rows with scan
+func (r *SQLiteRepository) queryAll(what string, scan func(rowScanner) error, query string, args ...interface{}) error {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", what, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", what, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get %s: %w", what, err)
+	}
+	return nil
+}
+
+// GetPosts gets the posts and group posts a user wrote
+func (r *SQLiteRepository) GetPosts(userID string) ([]*Post, error) {
+	posts := []*Post{}
+	err := r.queryAll("posts", func(row rowScanner) error {
+		var p Post
+		var sharedPostID sql.NullInt64
+		if err := row.Scan(&p.ID, &p.GroupID, &p.Content, &p.Image, &p.Video, &p.Privacy, &p.Status, &sharedPostID, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return err
+		}
+		p.SharedPostID = sharedPostID.Int64
+		posts = append(posts, &p)
+		return nil
+	}, `
+		SELECT id, '' AS group_id, content, COALESCE(image_path, ''), COALESCE(video_path, ''),
+			privacy, '' AS status, shared_post_id, created_at, updated_at
+		FROM posts
+		WHERE user_id = ?
+		UNION ALL
+		SELECT id, group_id, COALESCE(content, ''), COALESCE(image_path, ''), COALESCE(video_path, ''),
+			'' AS privacy, status, NULL AS shared_post_id, created_at, updated_at
+		FROM group_posts
+		WHERE user_id = ?
+		ORDER BY created_at
+	`, userID, userID)
+	return posts, err
+}
+
+// GetComments gets the comments and replies a user wrote
+func (r *SQLiteRepository) GetComments(userID string) ([]*Comment, error) {
+	comments := []*Comment{}
+	err := r.queryAll("comments", func(row rowScanner) error {
+		var c Comment
+		var parentID sql.NullInt64
+		if err := row.Scan(&c.ID, &c.PostID, &parentID, &c.Content, &c.Image, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return err
+		}
+		c.ParentID = parentID.Int64
+		comments = append(comments, &c)
+		return nil
+	}, `
+		SELECT id, post_id, parent_id, content, COALESCE(image_path, ''), created_at, updated_at
+		FROM comments
+		WHERE user_id = ?
+		ORDER BY created_at
+	`, userID)
+	return comments, err
+}
+
+// GetReactions gets the reactions a user left on posts and the comments they liked
+func (r *SQLiteRepository) GetReactions(userID string) ([]*Reaction, error) {
+	reactions := []*Reaction{}
+	err := r.queryAll("reactions", func(row rowScanner) error {
+		var reaction Reaction
+		if err := row.Scan(&reaction.TargetType, &reaction.TargetID, &reaction.ReactionType, &reaction.CreatedAt); err != nil {
+			return err
+		}
+		reactions = append(reactions, &reaction)
+		return nil
+	}, `
+		SELECT 'post', post_id, reaction_type, created_at FROM post_likes WHERE user_id = ?
+		UNION ALL
+		SELECT 'comment', comment_id, 'like', created_at FROM comment_likes WHERE user_id = ?
+		ORDER BY created_at
+	`, userID, userID)
+	return reactions, err
+}
+
+// getConnections gets the users on the other side of a user's follows, following is true
+// for the users they follow
+func (r *SQLiteRepository) getConnections(userID string, following bool) ([]*Connection, error) {
+	self, other := "following_id", "follower_id"
+	if following {
+		self, other = other, self
+	}
+
+	connections := []*Connection{}
+	err := r.queryAll("follows", func(row rowScanner) error {
+		var c Connection
+		if err := row.Scan(&c.UserID, &c.Name, &c.Since); err != nil {
+			return err
+		}
+		connections = append(connections, &c)
+		return nil
+	}, `
+		SELECT u.id, u.first_name || ' ' || u.last_name, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.`+other+`
+		WHERE f.`+self+` = ?
+		ORDER BY f.created_at
+	`, userID)
+	return connections, err
+}
+
+// GetFollowers gets the followers of a user
+func (r *SQLiteRepository) GetFollowers(userID string) ([]*Connection, error) {
+	return r.getConnections(userID, false)
+}
+
+// GetFollowing gets the users a user follows
+func (r *SQLiteRepository) GetFollowing(userID string) ([]*Connection, error) {
+	return r.getConnections(userID, true)
+}
+
+// GetMemberships gets the groups a user belongs to, asked to join or was invited to
+func (r *SQLiteRepository) GetMemberships(userID string) ([]*Membership, error) {
+	memberships := []*Membership{}
+	err := r.queryAll("group memberships", func(row rowScanner) error {
+		var m Membership
+		if err := row.Scan(&m.GroupID, &m.GroupName, &m.Role, &m.Status, &m.CreatedAt); err != nil {
+			return err
+		}
+		memberships = append(memberships, &m)
+		return nil
+	}, `
+		SELECT gm.group_id, g.name, gm.role, gm.status, gm.created_at
+		FROM group_members gm
+		JOIN groups g ON g.id = gm.group_id
+		WHERE gm.user_id = ?
+		ORDER BY gm.created_at
+	`, userID)
+	return memberships, err
+}
+
+// GetEventResponses gets a user's responses to group events
+func (r *SQLiteRepository) GetEventResponses(userID string) ([]*EventResponse, error) {
+	responses := []*EventResponse{}
+	err := r.queryAll("event responses", func(row rowScanner) error {
+		var e EventResponse
+		var waitlistedAt sql.NullTime
+		if err := row.Scan(&e.EventID, &e.EventTitle, &e.GroupID, &e.Response, &e.Guests, &waitlistedAt, &e.UpdatedAt); err != nil {
+			return err
+		}
+		e.Waitlisted = waitlistedAt.Valid
+		responses = append(responses, &e)
+		return nil
+	}, `
+		SELECT er.event_id, ge.title, ge.group_id, er.response, er.guests, er.waitlisted_at, er.updated_at
+		FROM event_responses er
+		JOIN group_events ge ON ge.id = er.event_id
+		WHERE er.user_id = ?
+		ORDER BY er.updated_at
+	`, userID)
+	return responses, err
+}
+
+// GetMessages gets the private messages a user sent or received
+func (r *SQLiteRepository) GetMessages(userID string) ([]*Message, error) {
+	messages := []*Message{}
+	err := r.queryAll("messages", func(row rowScanner) error {
+		var m Message
+		if err := row.Scan(&m.ID, &m.SenderID, &m.ReceiverID, &m.Content, &m.IsRead, &m.CreatedAt); err != nil {
+			return err
+		}
+		messages = append(messages, &m)
+		return nil
+	}, `
+		SELECT id, sender_id, receiver_id, content, is_read, created_at
+		FROM private_messages
+		WHERE sender_id = ? OR receiver_id = ?
+		ORDER BY created_at
+	`, userID, userID)
+	return messages, err
+}
+
+// GetGroupMessages gets the messages a user sent in group chats
+func (r *SQLiteRepository) GetGroupMessages(userID string) ([]*GroupMessage, error) {
+	messages := []*GroupMessage{}
+	err := r.queryAll("group messages", func(row rowScanner) error {
+		var m GroupMessage
+		if err := row.Scan(&m.ID, &m.GroupID, &m.GroupName, &m.Content, &m.CreatedAt); err != nil {
+			return err
+		}
+		messages = append(messages, &m)
+		return nil
+	}, `
+		SELECT m.id, m.group_id, COALESCE(g.name, ''), m.content, m.created_at
+		FROM group_chat_messages m
+		LEFT JOIN groups g ON g.id = m.group_id
+		WHERE m.user_id = ?
+		ORDER BY m.created_at
+	`, userID)
+	return messages, err
+}
+
+// GetNotifications gets the notifications a user received
+func (r *SQLiteRepository) GetNotifications(userID string) ([]*Notification, error) {
+	notifications := []*Notification{}
+	err := r.queryAll("notifications", func(row rowScanner) error {
+		var n Notification
+		var senderID sql.NullString
+		if err := row.Scan(&n.ID, &n.Type, &n.Message, &senderID, &n.IsRead, &n.CreatedAt); err != nil {
+			return err
+		}
+		n.SenderID = senderID.String
+		notifications = append(notifications, &n)
+		return nil
+	}, `
+		SELECT id, type, message, sender_id, is_read, created_at
+		FROM notifications
+		WHERE user_id = ?
+		ORDER BY created_at
+	`, userID)
+	return notifications, err
+}
@@ -0,0 +1,296 @@
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	notifications "github.com/Athooh/social-network/internal/notifcations"
+	"github.com/Athooh/social-network/pkg/filestore"
+	"github.com/Athooh/social-network/pkg/logger"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/scheduler"
+	"github.com/Athooh/social-network/pkg/websocket"
+	"github.com/Athooh/social-network/pkg/websocket/events"
+	"github.com/google/uuid"
+)
+
+// Kinds of the scheduled jobs of exports. A JobDataExport job builds the archive of an
+// export, a JobDataExportExpiry job deletes it once its download link expired.
+const (
+	JobDataExport       = "data_export"
+	JobDataExportExpiry = "data_export_expiry"
+)
+
+// maxBuildAttempts is how many times building an archive is tried before the export is
+// marked failed
+const maxBuildAttempts = 3
+
+var (
+	// ErrTooSoon is returned when a user asks for an export before their cooldown ended
+	ErrTooSoon = errors.New("an export was requested recently")
+	// ErrExportNotFound is returned for download tokens of unknown, unfinished or expired exports
+	ErrExportNotFound = errors.New("export not found or expired")
+)
+
+// exportJob is the payload of the jobs of an export
+type exportJob struct {
+	ExportID string `json:"exportId"`
+}
+
+// Config holds the settings of the export service
+type Config struct {
+	Dir      string        // Where archives are written
+	Cooldown time.Duration // Users can request one export in this period
+	TTL      time.Duration // Archives can be downloaded this long once ready
+}
+
+// Service prepares personal data exports in the background and serves their archives
+type Service struct {
+	repo                 Repository
+	fileStore            *filestore.FileStore
+	notificationsService notifications.Service
+	hub                  *websocket.Hub
+	jobs                 *scheduler.Scheduler
+	log                  *logger.Logger
+	cfg                  Config
+}
+
+// NewService creates a new export service, building and expiring exports through jobs
+func NewService(repo Repository, fileStore *filestore.FileStore, notificationsService notifications.Service, hub *websocket.Hub, jobs *scheduler.Scheduler, log *logger.Logger, cfg Config) (*Service, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	s := &Service{
+		repo:                 repo,
+		fileStore:            fileStore,
+		notificationsService: notificationsService,
+		hub:                  hub,
+		jobs:                 jobs,
+		log:                  log,
+		cfg:                  cfg,
+	}
+	jobs.Handle(JobDataExport, s.build)
+	jobs.Handle(JobDataExportExpiry, s.expire)
+
+	return s, nil
+}
+
+// RequestExport starts an export of the user's data. The export being prepared is
+// returned when there already is one, a new one can only be requested once the cooldown
+// since the last one ended.
+func (s *Service) RequestExport(ctx context.Context, userID string) (*models.DataExport, error) {
+	latest, err := s.repo.GetLatestExport(userID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get latest export: %v", err)
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if latest != nil {
+		if latest.Status == models.ExportPending {
+			return latest, nil
+		}
+		// Failed exports don't count, the user never got their data
+		if wait := latest.CreatedAt.Add(s.cfg.Cooldown).Sub(now); latest.Status != models.ExportFailed && wait > 0 {
+			return nil, fmt.Errorf("%w, try again in %s", ErrTooSoon, wait.Round(time.Minute))
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.DataExport{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    models.ExportPending,
+		Token:     token,
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateExport(export); err != nil {
+		s.log.WithContext(ctx).Error("Failed to create export: %v", err)
+		return nil, err
+	}
+
+	if err := s.jobs.Schedule(JobDataExport, exportJobKey(JobDataExport, export.ID), now, exportJob{ExportID: export.ID}); err != nil {
+		s.log.WithContext(ctx).Error("Failed to schedule export %s: %v", export.ID, err)
+		if err := s.repo.SetExportStatus(export.ID, models.ExportFailed); err != nil {
+			s.log.WithContext(ctx).Error("Failed to mark export %s failed: %v", export.ID, err)
+		}
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// GetLatestExport gets the export the user requested last, nil if there is none
+func (s *Service) GetLatestExport(ctx context.Context, userID string) (*models.DataExport, error) {
+	export, err := s.repo.GetLatestExport(userID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get latest export: %v", err)
+		return nil, err
+	}
+	return export, nil
+}
+
+// OpenDownload opens the archive a download token gives access to. The caller closes it.
+func (s *Service) OpenDownload(ctx context.Context, token string) (*models.DataExport, *os.File, error) {
+	if token == "" {
+		return nil, nil, ErrExportNotFound
+	}
+
+	export, err := s.repo.GetExportByToken(token)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get export: %v", err)
+		return nil, nil, err
+	}
+	if export == nil || export.Status != models.ExportReady || !time.Now().Before(export.ExpiresAt.Time) {
+		return nil, nil, ErrExportNotFound
+	}
+
+	file, err := os.Open(filepath.Join(s.cfg.Dir, export.FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return export, file, nil
+}
+
+// DownloadURL is where the archive of an export can be downloaded from
+func DownloadURL(export *models.DataExport) string {
+	return downloadPath + "?token=" + url.QueryEscape(export.Token)
+}
+
+// build runs a JobDataExport job, writing the archive of the export and letting its user
+// know where to download it
+func (s *Service) build(ctx context.Context, job *scheduler.Job) error {
+	var payload exportJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	export, err := s.repo.GetExport(payload.ExportID)
+	if err != nil {
+		return err
+	}
+	if export == nil || export.Status != models.ExportPending {
+		return nil
+	}
+
+	fileName := export.ID + ".zip"
+	size, err := s.writeArchive(ctx, export.UserID, filepath.Join(s.cfg.Dir, fileName))
+	if err != nil {
+		if job.Attempt < maxBuildAttempts {
+			return err
+		}
+		s.log.WithContext(ctx).Error("Giving up on export %s after %d attempts: %v", export.ID, job.Attempt, err)
+		return s.repo.SetExportStatus(export.ID, models.ExportFailed)
+	}
+
+	// The expiry is scheduled first so that the archive is deleted even if marking the
+	// export ready fails and the job is retried
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.cfg.TTL)
+	if err := s.jobs.Schedule(JobDataExportExpiry, exportJobKey(JobDataExportExpiry, export.ID), expiresAt, exportJob{ExportID: export.ID}); err != nil {
+		return err
+	}
+	if err := s.repo.CompleteExport(export.ID, fileName, size, now, expiresAt); err != nil {
+		return err
+	}
+	export.Status = models.ExportReady
+	export.FileName = fileName
+	export.Size = size
+	export.CompletedAt = sql.NullTime{Time: now, Valid: true}
+	export.ExpiresAt = sql.NullTime{Time: expiresAt, Valid: true}
+
+	s.notifyReady(ctx, export)
+	return nil
+}
+
+// expire runs a JobDataExportExpiry job, deleting the archive of the export
+func (s *Service) expire(ctx context.Context, job *scheduler.Job) error {
+	var payload exportJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	export, err := s.repo.GetExport(payload.ExportID)
+	if err != nil {
+		return err
+	}
+	if export == nil || export.FileName == "" {
+		return nil
+	}
+
+	if err := os.Remove(filepath.Join(s.cfg.Dir, export.FileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete export archive: %w", err)
+	}
+
+	return s.repo.SetExportStatus(export.ID, models.ExportExpired)
+}
+
+// notifyReady notifies the user of an export that it can be downloaded
+func (s *Service) notifyReady(ctx context.Context, export *models.DataExport) {
+	message := fmt.Sprintf("Your data export is ready, you can download it until %s", export.ExpiresAt.Time.Format("Jan 2, 2006 15:04 MST"))
+
+	// Notifications need a sender, exports come from the users themselves
+	err := s.notificationsService.CreateNotification(ctx, &notifications.NewNotification{
+		UserId:          export.UserID,
+		SenderId:        sql.NullString{String: export.UserID, Valid: true},
+		NotficationType: "dataExportReady",
+		Message:         message,
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to create export notification: %v", err)
+		return
+	}
+
+	if s.hub == nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"exportId":    export.ID,
+		"type":        "dataExportReady",
+		"message":     message,
+		"downloadUrl": DownloadURL(export),
+		"size":        export.Size,
+		"expiresAt":   export.ExpiresAt.Time.Format(time.RFC3339),
+		"isRead":      false,
+	}
+	if list, err := s.notificationsService.GetNotifications(ctx, export.UserID, 1, 0); err == nil && len(list) > 0 {
+		payload["id"] = list[0].ID
+		payload["createdAt"] = list[0].CreatedAt.Format(time.RFC3339)
+	}
+
+	s.hub.BroadcastToUser(export.UserID, events.Event{
+		Type:    events.DataExportReady,
+		Payload: payload,
+	})
+}
+
+func exportJobKey(kind, exportID string) string {
+	return kind + ":" + exportID
+}
+
+// newToken generates an unguessable download token
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate download token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,162 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/db/uow"
+)
+
+// ErrBlocked is returned for actions between users when either of them blocked the
+// other, and for actions with deactivated accounts
+var ErrBlocked = errors.New("you cannot interact with this user")
+
+// RelatedUser is a user someone blocked or muted
+type RelatedUser struct {
+	UserID     string    `json:"userId"`
+	UserName   string    `json:"userName"`
+	UserAvatar string    `json:"userAvatar"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// BlockUser blocks a user. Both stop following each other and their pending follow
+// requests are declined, in one transaction.
+func (s *FollowService) BlockUser(ctx context.Context, blockerID, blockedID string) error {
+	if blockerID == blockedID {
+		return errors.New("you cannot block yourself")
+	}
+
+	if _, err := s.userRepo.GetByID(blockedID); err != nil {
+		return errors.New("user not found")
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.CreateBlock(blockerID, blockedID); err != nil {
+			return err
+		}
+
+		for _, pair := range [][2]string{{blockerID, blockedID}, {blockedID, blockerID}} {
+			followerID, followingID := pair[0], pair[1]
+
+			isFollowing, err := repo.IsFollowing(followerID, followingID)
+			if err != nil {
+				return err
+			}
+			if isFollowing {
+				if err := repo.DeleteFollower(followerID, followingID); err != nil {
+					return err
+				}
+				tx.AfterCommit(func() {
+					s.notificationSvc.UpdateFollowerCounts(followerID, followingID, s.repo)
+				})
+			}
+
+			request, err := repo.GetFollowRequest(followerID, followingID)
+			if err != nil {
+				return err
+			}
+			if request != nil && request.Status == string(StatusPending) {
+				if err := repo.UpdateFollowRequestStatus(followerID, followingID, string(StatusDeclined)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// UnblockUser removes a block. The follows it removed are not restored.
+func (s *FollowService) UnblockUser(ctx context.Context, blockerID, blockedID string) error {
+	return s.repo.DeleteBlock(blockerID, blockedID)
+}
+
+// GetBlockedUsers retrieves the users a user blocked
+func (s *FollowService) GetBlockedUsers(ctx context.Context, userID string) ([]*RelatedUser, error) {
+	blocks, err := s.repo.GetBlocks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*RelatedUser, 0, len(blocks))
+	for _, block := range blocks {
+		if user := s.relatedUser(ctx, block.BlockedID, block.CreatedAt); user != nil {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+// MuteUser hides a user's posts from the muter's feed. The muted user is not told and
+// can still see and follow the muter.
+func (s *FollowService) MuteUser(ctx context.Context, muterID, mutedID string) error {
+	if muterID == mutedID {
+		return errors.New("you cannot mute yourself")
+	}
+
+	if _, err := s.userRepo.GetByID(mutedID); err != nil {
+		return errors.New("user not found")
+	}
+
+	return s.repo.CreateMute(muterID, mutedID)
+}
+
+// UnmuteUser removes a mute
+func (s *FollowService) UnmuteUser(ctx context.Context, muterID, mutedID string) error {
+	return s.repo.DeleteMute(muterID, mutedID)
+}
+
+// GetMutedUsers retrieves the users a user muted
+func (s *FollowService) GetMutedUsers(ctx context.Context, userID string) ([]*RelatedUser, error) {
+	mutes, err := s.repo.GetMutes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*RelatedUser, 0, len(mutes))
+	for _, mute := range mutes {
+		if user := s.relatedUser(ctx, mute.MutedID, mute.CreatedAt); user != nil {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+// relatedUser looks up the user of a block or mute, nil if that fails
+func (s *FollowService) relatedUser(ctx context.Context, userID string, since time.Time) *RelatedUser {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		s.log.WithContext(ctx).Warn("Failed to get user info for %s: %v", userID, err)
+		return nil
+	}
+
+	return &RelatedUser{
+		UserID:     user.ID,
+		UserName:   fmt.Sprintf("%s %s", user.FirstName, user.LastName),
+		UserAvatar: user.Avatar,
+		CreatedAt:  since,
+	}
+}
+
+// checkNotBlocked returns ErrBlocked when viewerID and userID blocked one another, a user
+// always sees their own lists
+func (s *FollowService) checkNotBlocked(userID, viewerID string) error {
+	if userID == viewerID {
+		return nil
+	}
+
+	blocked, err := s.repo.IsBlocked(viewerID, userID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
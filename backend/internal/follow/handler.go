@@ -1,7 +1,9 @@
 package follow
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/Athooh/social-network/internal/auth"
@@ -48,9 +50,13 @@ func (h *Handler) FollowUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Follow the user
-	autoFollowed, err := h.service.FollowUser(followerID, request.UserID)
+	autoFollowed, err := h.service.FollowUser(r.Context(), followerID, request.UserID)
 	if err != nil {
-		h.log.Error("Failed to follow user: %v", err)
+		if errors.Is(err, ErrBlocked) {
+			h.sendError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.WithContext(r.Context()).Error("Failed to follow user: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -92,8 +98,8 @@ func (h *Handler) UnfollowUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Unfollow the user
-	if err := h.service.UnfollowUser(followerID, request.UserID); err != nil {
-		h.log.Error("Failed to unfollow user: %v", err)
+	if err := h.service.UnfollowUser(r.Context(), followerID, request.UserID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to unfollow user: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -127,8 +133,8 @@ func (h *Handler) AcceptFollowRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Accept the follow request
-	if err := h.service.AcceptFollowRequest(request.UserID, followingID); err != nil {
-		h.log.Error("Failed to accept follow request: %v", err)
+	if err := h.service.AcceptFollowRequest(r.Context(), request.UserID, followingID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to accept follow request: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -162,8 +168,8 @@ func (h *Handler) DeclineFollowRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Decline the follow request
-	if err := h.service.DeclineFollowRequest(request.UserID, followingID); err != nil {
-		h.log.Error("Failed to decline follow request: %v", err)
+	if err := h.service.DeclineFollowRequest(r.Context(), request.UserID, followingID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to decline follow request: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -182,9 +188,9 @@ func (h *Handler) GetPendingFollowRequests(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Get pending follow requests
-	requests, err := h.service.GetPendingFollowRequests(userID)
+	requests, err := h.service.GetPendingFollowRequests(r.Context(), userID)
 	if err != nil {
-		h.log.Error("Failed to get pending follow requests: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get pending follow requests: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -207,9 +213,13 @@ func (h *Handler) GetFollowers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get followers
-	followers, err := h.service.GetFollowers(profileID)
+	followers, err := h.service.GetFollowers(r.Context(), profileID, userID)
+	if errors.Is(err, ErrBlocked) {
+		h.sendError(w, http.StatusNotFound, "User not found")
+		return
+	}
 	if err != nil {
-		h.log.Error("Failed to get followers: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get followers: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -232,9 +242,13 @@ func (h *Handler) GetFollowing(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get following
-	following, err := h.service.GetFollowing(profileID)
+	following, err := h.service.GetFollowing(r.Context(), profileID, userID)
+	if errors.Is(err, ErrBlocked) {
+		h.sendError(w, http.StatusNotFound, "User not found")
+		return
+	}
 	if err != nil {
-		h.log.Error("Failed to get following: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get following: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -260,9 +274,9 @@ func (h *Handler) IsFollowing(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if following
-	isFollowing, err := h.service.IsFollowing(followerID, targetID)
+	isFollowing, err := h.service.IsFollowing(r.Context(), followerID, targetID)
 	if err != nil {
-		h.log.Error("Failed to check if following: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to check if following: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -282,9 +296,9 @@ func (h *Handler) GetSuggestedFriends(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Get suggested friends
-	suggestions, err := h.service.GetSuggestedFriends(userID)
+	suggestions, err := h.service.GetSuggestedFriends(r.Context(), userID)
 	if err != nil {
-		h.log.Error("Failed to get suggested friends: %v", err)
+		h.log.WithContext(r.Context()).Error("Failed to get suggested friends: %v", err)
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -296,6 +310,80 @@ func (h *Handler) GetSuggestedFriends(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleBlocks handles listing (GET), adding (POST) and removing (DELETE) blocks
+func (h *Handler) HandleBlocks(w http.ResponseWriter, r *http.Request) {
+	h.handleUserList(w, r, "block", h.service.GetBlockedUsers, h.service.BlockUser, h.service.UnblockUser)
+}
+
+// HandleMutes handles listing (GET), adding (POST) and removing (DELETE) mutes
+func (h *Handler) HandleMutes(w http.ResponseWriter, r *http.Request) {
+	h.handleUserList(w, r, "mute", h.service.GetMutedUsers, h.service.MuteUser, h.service.UnmuteUser)
+}
+
+// handleUserList serves a list of users the current user keeps, such as their blocks
+func (h *Handler) handleUserList(
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	list func(ctx context.Context, userID string) ([]*RelatedUser, error),
+	add, remove func(ctx context.Context, userID, otherID string) error,
+) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		users, err := list(r.Context(), userID)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to get %ss: %v", name, err)
+			h.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendJSON(w, http.StatusOK, users)
+
+	case http.MethodPost:
+		var request struct {
+			UserID string `json:"userId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if request.UserID == "" {
+			h.sendError(w, http.StatusBadRequest, "User ID is required")
+			return
+		}
+
+		if err := add(r.Context(), userID, request.UserID); err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to %s user: %v", name, err)
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.sendJSON(w, http.StatusOK, map[string]bool{"success": true})
+
+	case http.MethodDelete:
+		otherID := r.URL.Query().Get("userId")
+		if otherID == "" {
+			h.sendError(w, http.StatusBadRequest, "User ID is required")
+			return
+		}
+
+		if err := remove(r.Context(), userID, otherID); err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to remove %s: %v", name, err)
+			h.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendJSON(w, http.StatusOK, map[string]bool{"success": true})
+
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
 func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	httputil.SendJSON(w, status, data)
 }
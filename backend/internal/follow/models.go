@@ -32,6 +32,22 @@ type Follower struct {
 	CreatedAt   time.Time
 }
 
+// Block hides two users from each other, whichever of them blocked the other
+type Block struct {
+	ID        int64
+	BlockerID string // User who blocked
+	BlockedID string // User who was blocked
+	CreatedAt time.Time
+}
+
+// Mute hides a user's posts from the feed of the user who muted them
+type Mute struct {
+	ID        int64
+	MuterID   string // User who muted
+	MutedID   string // User who was muted
+	CreatedAt time.Time
+}
+
 type BasicUser struct {
 	ID string
 }
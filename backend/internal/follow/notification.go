@@ -1,6 +1,8 @@
 package follow
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -30,22 +32,24 @@ func NewNotificationService(hub *websocket.Hub, userRepo user.Repository, notifc
 }
 
 // SendFollowRequestNotification sends a WebSocket notification for a follow request
-func (s *NotificationService) SendFollowRequestNotification(followerID, followingID string, notification *notifications.NewNotification, follower *user.User) {
+func (s *NotificationService) SendFollowRequestNotification(ctx context.Context, followerID, followingID string, notification *notifications.NewNotification, follower *user.User) {
 	if s.hub == nil {
-		s.log.Warn("WebSocket hub is nil, cannot send follow request notification")
+		s.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send follow request notification")
 		return
 	}
 
 	// Create notification in database
-	if err := s.notificationRepo.CreateNotification(notification); err != nil {
-		s.log.Error("Failed to create follow request notification: %v", err)
+	if err := s.notificationRepo.CreateNotification(ctx, notification); err != nil {
+		if !errors.Is(err, notifications.ErrSenderBlocked) {
+			s.log.WithContext(ctx).Error("Failed to create follow request notification: %v", err)
+		}
 		return
 	}
 
 	// Retrieve the newly created notification to get its ID and CreatedAt
-	notifications, err := s.notificationRepo.GetNotifications(followingID, 1, 0)
+	notifications, err := s.notificationRepo.GetNotifications(ctx, followingID, 1, 0)
 	if err != nil || len(notifications) == 0 {
-		s.log.Error("Failed to retrieve newly created notification: %v", err)
+		s.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
 		return
 	}
 	dbNotification := notifications[0]
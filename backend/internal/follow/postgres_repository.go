@@ -0,0 +1,23 @@
+package follow
+
+import (
+	"database/sql"
+
+	"github.com/Athooh/social-network/pkg/db/uow"
+)
+
+// PostgresRepository implements Repository interface for PostgreSQL. The follow queries
+// are portable so it reuses those of SQLiteRepository.
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository on a database opened with postgres.New
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{&SQLiteRepository{db: uow.NewDB(db)}}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *PostgresRepository) WithTx(tx *uow.Tx) Repository {
+	return &PostgresRepository{r.withTx(tx)}
+}
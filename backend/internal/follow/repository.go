@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/Athooh/social-network/pkg/db/uow"
+	"github.com/Athooh/social-network/pkg/user"
 )
 
 // Repository defines the interface for follow data access
@@ -19,8 +22,8 @@ type Repository interface {
 	CreateFollower(followerID, followingID string) error
 	DeleteFollower(followerID, followingID string) error
 	IsFollowing(followerID, followingID string) (bool, error)
-	GetFollowers(userID string) ([]*Follower, error)
-	GetFollowing(userID string) ([]*Follower, error)
+	GetFollowers(userID, viewerID string) ([]*Follower, error)
+	GetFollowing(userID, viewerID string) ([]*Follower, error)
 	GetFollowersCount(userID string) (int, error)
 	GetFollowingCount(userID string) (int, error)
 
@@ -32,16 +35,37 @@ type Repository interface {
 	GetMutualFollowersCount(userID1, userID2 string) (int, error)
 
 	GetUsersNotFollowed(userID string) ([]*BasicUser, error)
+
+	// Blocks and mutes
+	CreateBlock(blockerID, blockedID string) error
+	DeleteBlock(blockerID, blockedID string) error
+	IsBlocked(userID, otherID string) (bool, error)
+	GetBlocks(blockerID string) ([]*Block, error)
+	CreateMute(muterID, mutedID string) error
+	DeleteMute(muterID, mutedID string) error
+	GetMutes(muterID string) ([]*Mute, error)
+
+	// WithTx binds the repository to a unit of work
+	WithTx(tx *uow.Tx) Repository
 }
 
 // SQLiteRepository implements Repository interface for SQLite
 type SQLiteRepository struct {
-	db *sql.DB
+	db *uow.DB
 }
 
 // NewSQLiteRepository creates a new SQLite repository
 func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
-	return &SQLiteRepository{db: db}
+	return &SQLiteRepository{db: uow.NewDB(db)}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *SQLiteRepository) WithTx(tx *uow.Tx) Repository {
+	return r.withTx(tx)
+}
+
+func (r *SQLiteRepository) withTx(tx *uow.Tx) *SQLiteRepository {
+	return &SQLiteRepository{db: r.db.WithTx(tx)}
 }
 
 // CreateFollowRequest creates a new follow request
@@ -211,7 +235,7 @@ func (r *SQLiteRepository) DeleteFollower(followerID, followingID string) error
 	return tx.Commit()
 }
 
-func (r *SQLiteRepository) updateUserStats(tx *sql.Tx, userID string, statsType string, increment bool) error {
+func (r *SQLiteRepository) updateUserStats(tx *uow.Tx, userID string, statsType string, increment bool) error {
 	now := time.Now()
 
 	// Check if user stats record exists
@@ -262,16 +286,17 @@ func (r *SQLiteRepository) IsFollowing(followerID, followingID string) (bool, er
 	return count > 0, nil
 }
 
-// GetFollowers retrieves all followers of a user
-func (r *SQLiteRepository) GetFollowers(userID string) ([]*Follower, error) {
+// GetFollowers retrieves all followers of a user, except those blocked with viewerID
+func (r *SQLiteRepository) GetFollowers(userID, viewerID string) ([]*Follower, error) {
 	query := `
 		SELECT id, follower_id, following_id, created_at
 		FROM followers
 		WHERE following_id = ?
+		AND follower_id NOT IN (` + user.BlockedUsersSQL + `)
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, userID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -295,16 +320,17 @@ func (r *SQLiteRepository) GetFollowers(userID string) ([]*Follower, error) {
 	return followers, rows.Err()
 }
 
-// GetFollowing retrieves all users a user is following
-func (r *SQLiteRepository) GetFollowing(userID string) ([]*Follower, error) {
+// GetFollowing retrieves all users a user is following, except those blocked with viewerID
+func (r *SQLiteRepository) GetFollowing(userID, viewerID string) ([]*Follower, error) {
 	query := `
 		SELECT id, follower_id, following_id, created_at
 		FROM followers
 		WHERE follower_id = ?
+		AND following_id NOT IN (` + user.BlockedUsersSQL + `)
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, userID, viewerID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -448,9 +474,11 @@ func (r *SQLiteRepository) GetUsersNotFollowed(userID string) ([]*BasicUser, err
 			FROM follow_requests fr 
 			WHERE fr.follower_id = ? AND fr.status = 'pending'
 		)
+		AND u.id NOT IN (` + user.BlockedUsersSQL + `)
+		AND u.deactivated_at IS NULL
 		ORDER BY u.created_at DESC
 	`
-	rows, err := r.db.Query(query, userID, userID, userID)
+	rows, err := r.db.Query(query, userID, userID, userID, userID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -468,3 +496,98 @@ func (r *SQLiteRepository) GetUsersNotFollowed(userID string) ([]*BasicUser, err
 
 	return users, rows.Err()
 }
+
+// CreateBlock records that a user blocked another, blocking again changes nothing
+func (r *SQLiteRepository) CreateBlock(blockerID, blockedID string) error {
+	query := `
+		INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`
+
+	_, err := r.db.Exec(query, blockerID, blockedID, time.Now())
+	return err
+}
+
+// DeleteBlock removes a user's block of another
+func (r *SQLiteRepository) DeleteBlock(blockerID, blockedID string) error {
+	_, err := r.db.Exec("DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?", blockerID, blockedID)
+	return err
+}
+
+// IsBlocked checks if either user blocked the other
+func (r *SQLiteRepository) IsBlocked(userID, otherID string) (bool, error) {
+	return user.IsBlocked(r.db, userID, otherID)
+}
+
+// GetBlocks retrieves the users a user blocked, most recent first
+func (r *SQLiteRepository) GetBlocks(blockerID string) ([]*Block, error) {
+	query := `
+		SELECT id, blocker_id, blocked_id, created_at
+		FROM user_blocks
+		WHERE blocker_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*Block
+	for rows.Next() {
+		var block Block
+		if err := rows.Scan(&block.ID, &block.BlockerID, &block.BlockedID, &block.CreatedAt); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &block)
+	}
+
+	return blocks, rows.Err()
+}
+
+// CreateMute records that a user muted another, muting again changes nothing
+func (r *SQLiteRepository) CreateMute(muterID, mutedID string) error {
+	query := `
+		INSERT INTO user_mutes (muter_id, muted_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (muter_id, muted_id) DO NOTHING
+	`
+
+	_, err := r.db.Exec(query, muterID, mutedID, time.Now())
+	return err
+}
+
+// DeleteMute removes a user's mute of another
+func (r *SQLiteRepository) DeleteMute(muterID, mutedID string) error {
+	_, err := r.db.Exec("DELETE FROM user_mutes WHERE muter_id = ? AND muted_id = ?", muterID, mutedID)
+	return err
+}
+
+// GetMutes retrieves the users a user muted, most recent first
+func (r *SQLiteRepository) GetMutes(muterID string) ([]*Mute, error) {
+	query := `
+		SELECT id, muter_id, muted_id, created_at
+		FROM user_mutes
+		WHERE muter_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, muterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mutes []*Mute
+	for rows.Next() {
+		var mute Mute
+		if err := rows.Scan(&mute.ID, &mute.MuterID, &mute.MutedID, &mute.CreatedAt); err != nil {
+			return nil, err
+		}
+		mutes = append(mutes, &mute)
+	}
+
+	return mutes, rows.Err()
+}
@@ -1,11 +1,13 @@
 package follow
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 
 	notifications "github.com/Athooh/social-network/internal/notifcations"
+	"github.com/Athooh/social-network/pkg/db/uow"
 	"github.com/Athooh/social-network/pkg/logger"
 	"github.com/Athooh/social-network/pkg/user"
 	"github.com/Athooh/social-network/pkg/websocket"
@@ -14,22 +16,30 @@ import (
 // Service defines the follow service interface
 type Service interface {
 	// Follow/unfollow operations
-	FollowUser(followerID, followingID string) (bool, error)
-	UnfollowUser(followerID, followingID string) error
+	FollowUser(ctx context.Context, followerID, followingID string) (bool, error)
+	UnfollowUser(ctx context.Context, followerID, followingID string) error
 
 	// Follow request operations
-	AcceptFollowRequest(followerID, followingID string) error
-	DeclineFollowRequest(followerID, followingID string) error
-	GetPendingFollowRequests(userID string) ([]*FollowRequestWithUser, error)
+	AcceptFollowRequest(ctx context.Context, followerID, followingID string) error
+	DeclineFollowRequest(ctx context.Context, followerID, followingID string) error
+	GetPendingFollowRequests(ctx context.Context, userID string) ([]*FollowRequestWithUser, error)
 
 	// Status checks
-	IsFollowing(followerID, followingID string) (bool, error)
+	IsFollowing(ctx context.Context, followerID, followingID string) (bool, error)
 
 	// Retrieval operations
-	GetFollowers(userID string) ([]*FollowerWithUser, error)
-	GetFollowing(userID string) ([]*FollowerWithUser, error)
-
-	GetSuggestedFriends(userID string) ([]*SuggestedFriend, error)
+	GetFollowers(ctx context.Context, userID, viewerID string) ([]*FollowerWithUser, error)
+	GetFollowing(ctx context.Context, userID, viewerID string) ([]*FollowerWithUser, error)
+
+	GetSuggestedFriends(ctx context.Context, userID string) ([]*SuggestedFriend, error)
+
+	// Blocks and mutes
+	BlockUser(ctx context.Context, blockerID, blockedID string) error
+	UnblockUser(ctx context.Context, blockerID, blockedID string) error
+	GetBlockedUsers(ctx context.Context, userID string) ([]*RelatedUser, error)
+	MuteUser(ctx context.Context, muterID, mutedID string) error
+	UnmuteUser(ctx context.Context, muterID, mutedID string) error
+	GetMutedUsers(ctx context.Context, userID string) ([]*RelatedUser, error)
 }
 
 // FollowRequestWithUser extends FollowRequest with user information
@@ -51,6 +61,7 @@ type FollowerWithUser struct {
 // FollowService implements the Service interface
 type FollowService struct {
 	repo            Repository
+	work            *uow.UnitOfWork
 	userRepo        user.Repository
 	statusRepo      user.StatusRepository
 	log             *logger.Logger
@@ -58,11 +69,12 @@ type FollowService struct {
 }
 
 // NewService creates a new follow service
-func NewService(repo Repository, userRepo user.Repository, statusRepo user.StatusRepository, notificationRepo notifications.Service, log *logger.Logger, wsHub *websocket.Hub) Service {
+func NewService(repo Repository, work *uow.UnitOfWork, userRepo user.Repository, statusRepo user.StatusRepository, notificationRepo notifications.Service, log *logger.Logger, wsHub *websocket.Hub) Service {
 	notificationSvc := NewNotificationService(wsHub, userRepo, notificationRepo, log)
 
 	return &FollowService{
 		repo:            repo,
+		work:            work,
 		userRepo:        userRepo,
 		statusRepo:      statusRepo,
 		log:             log,
@@ -71,7 +83,7 @@ func NewService(repo Repository, userRepo user.Repository, statusRepo user.Statu
 }
 
 // FollowUser handles the logic for a user to follow another user
-func (s *FollowService) FollowUser(followerID, followingID string) (bool, error) {
+func (s *FollowService) FollowUser(ctx context.Context, followerID, followingID string) (bool, error) {
 	// Check if users are the same
 	if followerID == followingID {
 		return false, errors.New("you cannot follow yourself")
@@ -87,6 +99,26 @@ func (s *FollowService) FollowUser(followerID, followingID string) (bool, error)
 		return false, errors.New("already following this user")
 	}
 
+	// Blocked users are invisible to each other
+	blocked, err := s.repo.IsBlocked(followerID, followingID)
+	if err != nil {
+		return false, err
+	}
+
+	if blocked {
+		return false, ErrBlocked
+	}
+
+	// Deactivated accounts are hidden until their owner comes back
+	deactivated, err := s.userRepo.IsDeactivated(followingID)
+	if err != nil {
+		return false, err
+	}
+
+	if deactivated {
+		return false, ErrBlocked
+	}
+
 	// Check if the target user's profile is public
 	isPublic, err := s.repo.IsUserProfilePublic(followingID)
 	if err != nil {
@@ -118,7 +150,7 @@ func (s *FollowService) FollowUser(followerID, followingID string) (bool, error)
 	// Get follower info for notification
 	follower, err := s.userRepo.GetByID(followerID)
 	if err != nil {
-		s.log.Warn("Failed to get follower info for notification: %v", err)
+		s.log.WithContext(ctx).Warn("Failed to get follower info for notification: %v", err)
 		return false, err
 	}
 	followerName := fmt.Sprintf("%s %s", follower.FirstName, follower.LastName)
@@ -142,7 +174,7 @@ func (s *FollowService) FollowUser(followerID, followingID string) (bool, error)
 		}
 
 		// Send follow request notification with constructed notification
-		s.notificationSvc.SendFollowRequestNotification(followerID, followingID, newNotification, follower)
+		s.notificationSvc.SendFollowRequestNotification(ctx, followerID, followingID, newNotification, follower)
 	} else {
 		// Create new follow request
 		if err := s.repo.CreateFollowRequest(followerID, followingID); err != nil {
@@ -150,14 +182,14 @@ func (s *FollowService) FollowUser(followerID, followingID string) (bool, error)
 		}
 
 		// Send follow request notification with constructed notification
-		s.notificationSvc.SendFollowRequestNotification(followerID, followingID, newNotification, follower)
+		s.notificationSvc.SendFollowRequestNotification(ctx, followerID, followingID, newNotification, follower)
 	}
 
 	return false, nil
 }
 
 // UnfollowUser handles the logic for a user unfollowing another user
-func (s *FollowService) UnfollowUser(followerID, followingID string) error {
+func (s *FollowService) UnfollowUser(ctx context.Context, followerID, followingID string) error {
 	// Check if users are the same
 	if followerID == followingID {
 		return errors.New("you cannot unfollow yourself")
@@ -188,45 +220,52 @@ func (s *FollowService) UnfollowUser(followerID, followingID string) error {
 }
 
 // AcceptFollowRequest accepts a pending follow request
-func (s *FollowService) AcceptFollowRequest(followerID, followingID string) error {
+func (s *FollowService) AcceptFollowRequest(ctx context.Context, followerID, followingID string) error {
 	if followerID == "" || followingID == "" {
 		return errors.New("follower or following id is required")
 	}
-	// Verify the request exists and is pending
-	request, err := s.repo.GetFollowRequest(followerID, followingID)
-	if err != nil {
-		return err
-	}
+	// Accepting the request, following and the counts all succeed or none of them do
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
 
-	if request == nil {
-		return errors.New("follow request not found")
-	}
+		// Verify the request exists and is pending
+		request, err := repo.GetFollowRequest(followerID, followingID)
+		if err != nil {
+			return err
+		}
 
-	if request.Status != string(StatusPending) {
-		return errors.New("follow request is not pending")
-	}
+		if request == nil {
+			return errors.New("follow request not found")
+		}
 
-	// Update request status
-	if err := s.repo.UpdateFollowRequestStatus(followerID, followingID, string(StatusAccepted)); err != nil {
-		return err
-	}
+		if request.Status != string(StatusPending) {
+			return errors.New("follow request is not pending")
+		}
 
-	// Create follower relationship
-	if err := s.repo.CreateFollower(followerID, followingID); err != nil {
-		return err
-	}
+		// Update request status
+		if err := repo.UpdateFollowRequestStatus(followerID, followingID, string(StatusAccepted)); err != nil {
+			return err
+		}
 
-	// Update follower counts
-	s.notificationSvc.UpdateFollowerCounts(followerID, followingID, s.repo)
+		// Create follower relationship
+		if err := repo.CreateFollower(followerID, followingID); err != nil {
+			return err
+		}
 
-	// Send notification to the follower that their request was accepted
-	// s.notificationSvc.SendFollowRequestAcceptedNotification(followerID, followingID)
+		// Update follower counts once they are committed
+		tx.AfterCommit(func() {
+			s.notificationSvc.UpdateFollowerCounts(followerID, followingID, s.repo)
+		})
 
-	return nil
+		// Send notification to the follower that their request was accepted
+		// s.notificationSvc.SendFollowRequestAcceptedNotification(followerID, followingID)
+
+		return nil
+	})
 }
 
 // DeclineFollowRequest declines a pending follow request
-func (s *FollowService) DeclineFollowRequest(followerID, followingID string) error {
+func (s *FollowService) DeclineFollowRequest(ctx context.Context, followerID, followingID string) error {
 	// Verify the request exists and is pending
 	request, err := s.repo.GetFollowRequest(followerID, followingID)
 	if err != nil {
@@ -246,7 +285,7 @@ func (s *FollowService) DeclineFollowRequest(followerID, followingID string) err
 }
 
 // GetPendingFollowRequests retrieves all pending follow requests for a user with mutual friends count
-func (s *FollowService) GetPendingFollowRequests(userID string) ([]*FollowRequestWithUser, error) {
+func (s *FollowService) GetPendingFollowRequests(ctx context.Context, userID string) ([]*FollowRequestWithUser, error) {
 	requests, err := s.repo.GetPendingFollowRequests(userID)
 	if err != nil {
 		return nil, err
@@ -257,14 +296,14 @@ func (s *FollowService) GetPendingFollowRequests(userID string) ([]*FollowReques
 		// Get follower user info
 		follower, err := s.userRepo.GetByID(request.FollowerID)
 		if err != nil {
-			s.log.Warn("Failed to get follower info: %v", err)
+			s.log.WithContext(ctx).Warn("Failed to get follower info: %v", err)
 			continue
 		}
 
 		// Get mutual followers count
 		mutualCount, err := s.repo.GetMutualFollowersCount(userID, request.FollowerID)
 		if err != nil {
-			s.log.Warn("Failed to get mutual followers count: %v", err)
+			s.log.WithContext(ctx).Warn("Failed to get mutual followers count: %v", err)
 			mutualCount = 0
 		}
 
@@ -282,13 +321,19 @@ func (s *FollowService) GetPendingFollowRequests(userID string) ([]*FollowReques
 }
 
 // IsFollowing checks if a user is following another user
-func (s *FollowService) IsFollowing(followerID, followingID string) (bool, error) {
+func (s *FollowService) IsFollowing(ctx context.Context, followerID, followingID string) (bool, error) {
 	return s.repo.IsFollowing(followerID, followingID)
 }
 
-// GetFollowers retrieves all followers of a user with user information
-func (s *FollowService) GetFollowers(userID string) ([]*FollowerWithUser, error) {
-	followers, err := s.repo.GetFollowers(userID)
+// GetFollowers retrieves the followers of a user with user information, as seen by
+// viewerID: users blocked with the viewer are left out, and a user blocked with the viewer
+// has no list to show
+func (s *FollowService) GetFollowers(ctx context.Context, userID, viewerID string) ([]*FollowerWithUser, error) {
+	if err := s.checkNotBlocked(userID, viewerID); err != nil {
+		return nil, err
+	}
+
+	followers, err := s.repo.GetFollowers(userID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -298,14 +343,14 @@ func (s *FollowService) GetFollowers(userID string) ([]*FollowerWithUser, error)
 		// Get follower user info
 		user, err := s.userRepo.GetByID(follower.FollowerID)
 		if err != nil {
-			s.log.Warn("Failed to get follower info: %v", err)
+			s.log.WithContext(ctx).Warn("Failed to get follower info: %v", err)
 			continue
 		}
 
 		// Check if the user is online
 		isOnline, err := s.statusRepo.GetUserStatus(follower.FollowerID)
 		if err != nil {
-			s.log.Warn("Failed to get online status for user %s: %v", follower.FollowerID, err)
+			s.log.WithContext(ctx).Warn("Failed to get online status for user %s: %v", follower.FollowerID, err)
 			// Continue with isOnline = false as default
 		}
 
@@ -322,9 +367,14 @@ func (s *FollowService) GetFollowers(userID string) ([]*FollowerWithUser, error)
 	return followersWithUser, nil
 }
 
-// GetFollowing retrieves all users a user is following with user information
-func (s *FollowService) GetFollowing(userID string) ([]*FollowerWithUser, error) {
-	following, err := s.repo.GetFollowing(userID)
+// GetFollowing retrieves the users a user is following with user information, filtered
+// for viewerID like GetFollowers
+func (s *FollowService) GetFollowing(ctx context.Context, userID, viewerID string) ([]*FollowerWithUser, error) {
+	if err := s.checkNotBlocked(userID, viewerID); err != nil {
+		return nil, err
+	}
+
+	following, err := s.repo.GetFollowing(userID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -334,14 +384,14 @@ func (s *FollowService) GetFollowing(userID string) ([]*FollowerWithUser, error)
 		// Get following user info
 		user, err := s.userRepo.GetByID(follow.FollowingID)
 		if err != nil {
-			s.log.Warn("Failed to get following user info: %v", err)
+			s.log.WithContext(ctx).Warn("Failed to get following user info: %v", err)
 			continue
 		}
 
 		// Check if the user is online
 		isOnline, err := s.statusRepo.GetUserStatus(follow.FollowingID)
 		if err != nil {
-			s.log.Warn("Failed to get online status for user %s: %v", follow.FollowingID, err)
+			s.log.WithContext(ctx).Warn("Failed to get online status for user %s: %v", follow.FollowingID, err)
 			// Continue with isOnline = false as default
 		}
 
@@ -358,7 +408,7 @@ func (s *FollowService) GetFollowing(userID string) ([]*FollowerWithUser, error)
 	return followingWithUser, nil
 }
 
-func (s *FollowService) GetSuggestedFriends(userID string) ([]*SuggestedFriend, error) {
+func (s *FollowService) GetSuggestedFriends(ctx context.Context, userID string) ([]*SuggestedFriend, error) {
 	// Get users not followed by current user
 	suggestions, err := s.repo.GetUsersNotFollowed(userID)
 	if err != nil {
@@ -370,21 +420,21 @@ func (s *FollowService) GetSuggestedFriends(userID string) ([]*SuggestedFriend,
 		// Get user info
 		user, err := s.userRepo.GetByID(suggestion.ID)
 		if err != nil {
-			s.log.Warn("Failed to get user info for suggestion %s: %v", suggestion.ID, err)
+			s.log.WithContext(ctx).Warn("Failed to get user info for suggestion %s: %v", suggestion.ID, err)
 			continue
 		}
 
 		// Get mutual friends count
 		mutualCount, err := s.repo.GetMutualFollowersCount(userID, suggestion.ID)
 		if err != nil {
-			s.log.Warn("Failed to get mutual followers count for user %s: %v", suggestion.ID, err)
+			s.log.WithContext(ctx).Warn("Failed to get mutual followers count for user %s: %v", suggestion.ID, err)
 			mutualCount = 0
 		}
 
 		// Check if user is online
 		isOnline, err := s.statusRepo.GetUserStatus(suggestion.ID)
 		if err != nil {
-			s.log.Warn("Failed to get online status for user %s: %v", suggestion.ID, err)
+			s.log.WithContext(ctx).Warn("Failed to get online status for user %s: %v", suggestion.ID, err)
 			// Continue with isOnline = false as default
 		}
 		suggestedFriend := &SuggestedFriend{
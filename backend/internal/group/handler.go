@@ -2,9 +2,11 @@ package group
 
 import (
 	"encoding/json"
+	"errors"
 	"mime/multipart"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Athooh/social-network/internal/auth"
 	"github.com/Athooh/social-network/pkg/httputil"
@@ -62,10 +64,10 @@ func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create group
-	group, err := h.service.CreateGroup(userID, name, description, isPublic, banner, profilePic)
+	group, err := h.service.CreateGroup(r.Context(), userID, name, description, isPublic, banner, profilePic)
 	if err != nil {
-		h.log.Error("Failed to create group: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to create group: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -96,10 +98,10 @@ func (h *Handler) GetGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get group
-	group, err := h.service.GetGroup(groupID, userID)
+	group, err := h.service.GetGroup(r.Context(), groupID, userID)
 	if err != nil {
-		h.log.Error("Failed to get group: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to get group: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -129,10 +131,10 @@ func (h *Handler) GetUserGroups(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get groups
-	groups, err := h.service.GetUserGroups(userID, viewerID)
+	groups, err := h.service.GetUserGroups(r.Context(), userID, viewerID)
 	if err != nil {
-		h.log.Error("Failed to get user groups: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to get user groups: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -177,10 +179,10 @@ func (h *Handler) GetAllGroups(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get groups
-	groups, err := h.service.GetAllGroups(userID, limit, offset)
+	groups, err := h.service.GetAllGroups(r.Context(), userID, limit, offset)
 	if err != nil {
-		h.log.Error("Failed to get all groups: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to get all groups: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -231,10 +233,10 @@ func (h *Handler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update group
-	group, err := h.service.UpdateGroup(groupID, userID, name, description, isPublic, banner, profilePic)
+	group, err := h.service.UpdateGroup(r.Context(), groupID, userID, name, description, isPublic, banner, profilePic)
 	if err != nil {
-		h.log.Error("Failed to update group: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to update group: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -265,9 +267,9 @@ func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete group
-	if err := h.service.DeleteGroup(groupID, userID); err != nil {
-		h.log.Error("Failed to delete group: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.DeleteGroup(r.Context(), groupID, userID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to delete group: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -302,9 +304,9 @@ func (h *Handler) InviteToGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Invite user
-	if err := h.service.InviteToGroup(req.GroupID, userID, req.InviteeID); err != nil {
-		h.log.Error("Failed to invite user to group: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.InviteToGroup(r.Context(), req.GroupID, userID, req.InviteeID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to invite user to group: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -329,7 +331,8 @@ func (h *Handler) JoinGroup(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req struct {
-		GroupID string `json:"groupId"`
+		GroupID string            `json:"groupId"`
+		Answers map[string]string `json:"answers"` // Join question ID to answer
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -338,14 +341,19 @@ func (h *Handler) JoinGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Join group
-	if err := h.service.JoinGroup(req.GroupID, userID); err != nil {
-		h.log.Error("Failed to join group: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	member, err := h.service.JoinGroup(r.Context(), req.GroupID, userID, req.Answers)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to join group: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
 	// Return success response
-	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Join request sent successfully"})
+	message := "Join request sent successfully"
+	if member.Status == "accepted" {
+		message = "Joined group successfully"
+	}
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": message, "status": member.Status})
 }
 
 // LeaveGroup handles a user leaving a group
@@ -374,9 +382,9 @@ func (h *Handler) LeaveGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Leave group
-	if err := h.service.LeaveGroup(req.GroupID, userID); err != nil {
-		h.log.Error("Failed to leave group: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.LeaveGroup(r.Context(), req.GroupID, userID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to leave group: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -410,9 +418,9 @@ func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Accept invitation
-	if err := h.service.AcceptInvitation(req.GroupID, userID); err != nil {
-		h.log.Error("Failed to accept invitation: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.AcceptInvitation(r.Context(), req.GroupID, userID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to accept invitation: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -446,9 +454,9 @@ func (h *Handler) RejectInvitation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Reject invitation
-	if err := h.service.RejectInvitation(req.GroupID, userID); err != nil {
-		h.log.Error("Failed to reject invitation: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.RejectInvitation(r.Context(), req.GroupID, userID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to reject invitation: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -483,9 +491,9 @@ func (h *Handler) AcceptJoinRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Accept join request
-	if err := h.service.AcceptJoinRequest(req.GroupID, userID, req.UserID); err != nil {
-		h.log.Error("Failed to accept join request: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.AcceptJoinRequest(r.Context(), req.GroupID, userID, req.UserID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to accept join request: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -520,9 +528,9 @@ func (h *Handler) RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Reject join request
-	if err := h.service.RejectJoinRequest(req.GroupID, userID, req.UserID); err != nil {
-		h.log.Error("Failed to reject join request: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.RejectJoinRequest(r.Context(), req.GroupID, userID, req.UserID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to reject join request: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -558,9 +566,9 @@ func (h *Handler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update member role
-	if err := h.service.UpdateMemberRole(req.GroupID, userID, req.UserID, req.Role); err != nil {
-		h.log.Error("Failed to update member role: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.UpdateMemberRole(r.Context(), req.GroupID, userID, req.UserID, req.Role); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to update member role: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -593,9 +601,9 @@ func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Remove member
-	if err := h.service.RemoveMember(groupID, userID, memberID); err != nil {
-		h.log.Error("Failed to remove member: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.RemoveMember(r.Context(), groupID, userID, memberID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to remove member: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -628,10 +636,10 @@ func (h *Handler) GetGroupMembers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get members
-	members, err := h.service.GetGroupMembers(groupID, userID, status)
+	members, err := h.service.GetGroupMembers(r.Context(), groupID, userID, status)
 	if err != nil {
-		h.log.Error("Failed to get group members: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to get group members: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -679,10 +687,10 @@ func (h *Handler) CreateGroupPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create post
-	post, err := h.service.CreateGroupPost(groupID, userID, content, image, video)
+	post, err := h.service.CreateGroupPost(r.Context(), groupID, userID, content, image, video)
 	if err != nil {
-		h.log.Error("Failed to create group post: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to create group post: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -733,10 +741,10 @@ func (h *Handler) GetGroupPosts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get posts
-	posts, err := h.service.GetGroupPosts(groupID, userID, limit, offset)
+	posts, err := h.service.GetGroupPosts(r.Context(), groupID, userID, limit, offset)
 	if err != nil {
-		h.log.Error("Failed to get group posts: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to get group posts: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -744,6 +752,92 @@ func (h *Handler) GetGroupPosts(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, posts)
 }
 
+// UpdateGroupPost handles editing a post in a group
+func (h *Handler) UpdateGroupPost(w http.ResponseWriter, r *http.Request) {
+	// Only allow PUT method
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse multipart form
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	postID, err := strconv.ParseInt(r.FormValue("postId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	content := r.FormValue("content")
+
+	// Get file uploads
+	var image, video *multipart.FileHeader
+	if files := r.MultipartForm.File["image"]; len(files) > 0 {
+		image = files[0]
+	}
+	if files := r.MultipartForm.File["video"]; len(files) > 0 {
+		video = files[0]
+	}
+
+	if content == "" && image == nil && video == nil {
+		http.Error(w, "Content, image, or video is required", http.StatusBadRequest)
+		return
+	}
+
+	// Update post
+	post, err := h.service.UpdateGroupPost(r.Context(), postID, userID, content, image, video)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to update group post: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	// Return response
+	h.sendJSON(w, http.StatusOK, post)
+}
+
+// GetGroupPostRevisions handles getting the edit history of a group post
+func (h *Handler) GetGroupPostRevisions(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	postID, err := strconv.ParseInt(r.URL.Query().Get("postId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.service.GetGroupPostRevisions(r.Context(), postID, userID)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to get group post revisions: %v", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Return response
+	h.sendJSON(w, http.StatusOK, revisions)
+}
+
 // DeleteGroupPost handles deleting a post from a group
 func (h *Handler) DeleteGroupPost(w http.ResponseWriter, r *http.Request) {
 	// Only allow DELETE method
@@ -773,9 +867,9 @@ func (h *Handler) DeleteGroupPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete post
-	if err := h.service.DeleteGroupPost(postID, userID); err != nil {
-		h.log.Error("Failed to delete group post: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.DeleteGroupPost(r.Context(), postID, userID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to delete group post: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -815,10 +909,10 @@ func (h *Handler) SendChatMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send message
-	message, err := h.service.SendChatMessage(request.GroupID, userID, request.Content)
+	message, err := h.service.SendChatMessage(r.Context(), request.GroupID, userID, request.Content)
 	if err != nil {
-		h.log.Error("Failed to send chat message: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to send chat message: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -869,10 +963,10 @@ func (h *Handler) GetGroupChatMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get messages
-	messages, err := h.service.GetGroupChatMessages(groupID, userID, limit, offset)
+	messages, err := h.service.GetGroupChatMessages(r.Context(), groupID, userID, limit, offset)
 	if err != nil {
-		h.log.Error("Failed to get group chat messages: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.WithContext(r.Context()).Error("Failed to get group chat messages: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -881,6 +975,698 @@ func (h *Handler) GetGroupChatMessages(w http.ResponseWriter, r *http.Request) {
 }
 
 // Helper method to send JSON responses
+// GetGroupRoles handles listing the roles of a group
+func (h *Handler) GetGroupRoles(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	groupID := r.URL.Query().Get("groupId")
+	if groupID == "" {
+		http.Error(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	roles, err := h.service.GetGroupRoles(r.Context(), groupID, userID)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to get group roles: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, roles)
+}
+
+// roleRequest is the body of requests creating or changing a custom role
+type roleRequest struct {
+	GroupID     string   `json:"groupId"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+// CreateGroupRole handles defining a custom role
+func (h *Handler) CreateGroupRole(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.service.CreateGroupRole(r.Context(), req.GroupID, userID, req.Name, req.Permissions)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to create group role: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, role)
+}
+
+// UpdateGroupRole handles changing the permissions of a custom role
+func (h *Handler) UpdateGroupRole(w http.ResponseWriter, r *http.Request) {
+	// Only allow PUT method
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.service.UpdateGroupRole(r.Context(), req.GroupID, userID, req.Name, req.Permissions)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to update group role: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, role)
+}
+
+// DeleteGroupRole handles deleting a custom role
+func (h *Handler) DeleteGroupRole(w http.ResponseWriter, r *http.Request) {
+	// Only allow DELETE method
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	groupID := r.URL.Query().Get("groupId")
+	name := r.URL.Query().Get("name")
+	if groupID == "" || name == "" {
+		http.Error(w, "Group ID and role name are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteGroupRole(r.Context(), groupID, userID, name); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to delete group role: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Role deleted successfully"})
+}
+
+// TransferOwnership handles handing a group over to another member
+func (h *Handler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		GroupID string `json:"groupId"`
+		UserID  string `json:"userId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.TransferOwnership(r.Context(), req.GroupID, userID, req.UserID); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to transfer group ownership: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Ownership transferred successfully"})
+}
+
+// PinGroupPost handles pinning and unpinning a group post
+func (h *Handler) PinGroupPost(w http.ResponseWriter, r *http.Request) {
+	// Only allow PUT method
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		PostID int64 `json:"postId"`
+		Pinned bool  `json:"pinned"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.PinGroupPost(r.Context(), req.PostID, userID, req.Pinned); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to pin group post: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]bool{"pinned": req.Pinned})
+}
+
+// UpdateModerationSettings handles changing which posts wait for review
+func (h *Handler) UpdateModerationSettings(w http.ResponseWriter, r *http.Request) {
+	// Only allow PUT method
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		GroupID        string `json:"groupId"`
+		PostApproval   bool   `json:"postApproval"`
+		NewMemberPosts int    `json:"newMemberApprovalPosts"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateModerationSettings(r.Context(), req.GroupID, userID, req.PostApproval, req.NewMemberPosts); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to update moderation settings: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Moderation settings updated successfully"})
+}
+
+// GetModerationQueue handles listing the posts waiting for review
+func (h *Handler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	groupID := r.URL.Query().Get("groupId")
+	if groupID == "" {
+		http.Error(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := pagination(r, 20)
+	posts, err := h.service.GetModerationQueue(r.Context(), groupID, userID, limit, offset)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to get moderation queue: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, posts)
+}
+
+// ReviewGroupPost handles approving or rejecting a pending post
+func (h *Handler) ReviewGroupPost(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		PostID  int64  `json:"postId"`
+		Approve bool   `json:"approve"`
+		Reason  string `json:"reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ReviewGroupPost(r.Context(), req.PostID, userID, req.Approve, req.Reason); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to review group post: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Post reviewed successfully"})
+}
+
+// sanctionRequest is the body of requests banning or muting a user. A zero duration bans
+// permanently and is not allowed for mutes.
+type sanctionRequest struct {
+	GroupID         string `json:"groupId"`
+	UserID          string `json:"userId"`
+	Reason          string `json:"reason"`
+	DurationMinutes int    `json:"durationMinutes"`
+}
+
+// HandleBans handles listing (GET), issuing (POST) and lifting (DELETE) bans
+func (h *Handler) HandleBans(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		bans, err := h.service.GetBans(r.Context(), r.URL.Query().Get("groupId"), userID)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to get bans: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusOK, bans)
+
+	case http.MethodPost:
+		var req sanctionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ban, err := h.service.BanMember(r.Context(), req.GroupID, userID, req.UserID, req.Reason, time.Duration(req.DurationMinutes)*time.Minute)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to ban member: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusCreated, ban)
+
+	case http.MethodDelete:
+		groupID := r.URL.Query().Get("groupId")
+		memberID := r.URL.Query().Get("userId")
+		if groupID == "" || memberID == "" {
+			http.Error(w, "Group ID and User ID are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.UnbanMember(r.Context(), groupID, userID, memberID); err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to lift ban: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusOK, map[string]string{"message": "Ban lifted successfully"})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleMutes handles listing (GET), issuing (POST) and lifting (DELETE) mutes
+func (h *Handler) HandleMutes(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		mutes, err := h.service.GetMutes(r.Context(), r.URL.Query().Get("groupId"), userID)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to get mutes: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusOK, mutes)
+
+	case http.MethodPost:
+		var req sanctionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		mute, err := h.service.MuteMember(r.Context(), req.GroupID, userID, req.UserID, req.Reason, time.Duration(req.DurationMinutes)*time.Minute)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to mute member: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusCreated, mute)
+
+	case http.MethodDelete:
+		groupID := r.URL.Query().Get("groupId")
+		memberID := r.URL.Query().Get("userId")
+		if groupID == "" || memberID == "" {
+			http.Error(w, "Group ID and User ID are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.UnmuteMember(r.Context(), groupID, userID, memberID); err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to lift mute: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusOK, map[string]string{"message": "Mute lifted successfully"})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetAuditLog handles listing the moderation actions taken in a group
+func (h *Handler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	groupID := r.URL.Query().Get("groupId")
+	if groupID == "" {
+		http.Error(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := pagination(r, 50)
+	entries, err := h.service.GetAuditLog(r.Context(), groupID, userID, limit, offset)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to get audit log: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, entries)
+}
+
+// ReviewJoinRequests accepts or rejects several join requests at once
+func (h *Handler) ReviewJoinRequests(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse request body, no user IDs reviews every pending request
+	var req struct {
+		GroupID string   `json:"groupId"`
+		UserIDs []string `json:"userIds"`
+		Accept  bool     `json:"accept"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.ReviewJoinRequests(r.Context(), req.GroupID, userID, req.UserIDs, req.Accept)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to review join requests: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, result)
+}
+
+// HandleInviteLinks handles listing (GET), creating (POST) and revoking (DELETE) invite links
+func (h *Handler) HandleInviteLinks(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		links, err := h.service.GetInviteLinks(r.Context(), r.URL.Query().Get("groupId"), userID)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to get invite links: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusOK, links)
+
+	case http.MethodPost:
+		// Zero max uses or expiry means no limit
+		var req struct {
+			GroupID        string `json:"groupId"`
+			MaxUses        int    `json:"maxUses"`
+			ExpiresInHours int    `json:"expiresInHours"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		link, err := h.service.CreateInviteLink(r.Context(), req.GroupID, userID, req.MaxUses, time.Duration(req.ExpiresInHours)*time.Hour)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to create invite link: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusCreated, link)
+
+	case http.MethodDelete:
+		groupID := r.URL.Query().Get("groupId")
+		linkID := r.URL.Query().Get("linkId")
+		if groupID == "" || linkID == "" {
+			http.Error(w, "Group ID and Link ID are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.RevokeInviteLink(r.Context(), groupID, userID, linkID); err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to revoke invite link: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusOK, map[string]string{"message": "Invite link revoked successfully"})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// JoinWithInviteLink joins the group an invite link belongs to
+func (h *Handler) JoinWithInviteLink(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.service.JoinWithInviteLink(r.Context(), req.Token, userID)
+	if err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to join with invite link: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, group)
+}
+
+// HandleJoinQuestions handles listing (GET), adding (POST) and deleting (DELETE) join questions
+func (h *Handler) HandleJoinQuestions(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		questions, err := h.service.GetJoinQuestions(r.Context(), r.URL.Query().Get("groupId"))
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to get join questions: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusOK, questions)
+
+	case http.MethodPost:
+		var req struct {
+			GroupID  string `json:"groupId"`
+			Question string `json:"question"`
+			Required bool   `json:"required"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		question, err := h.service.AddJoinQuestion(r.Context(), req.GroupID, userID, req.Question, req.Required)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to add join question: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusCreated, question)
+
+	case http.MethodDelete:
+		groupID := r.URL.Query().Get("groupId")
+		questionID := r.URL.Query().Get("questionId")
+		if groupID == "" || questionID == "" {
+			http.Error(w, "Group ID and Question ID are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.DeleteJoinQuestion(r.Context(), groupID, userID, questionID); err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to delete join question: %v", err)
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		h.sendJSON(w, http.StatusOK, map[string]string{"message": "Join question deleted successfully"})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// UpdateAutoApproveRules sets the rules that accept join requests without an admin
+func (h *Handler) UpdateAutoApproveRules(w http.ResponseWriter, r *http.Request) {
+	// Only allow PUT method
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		GroupID string   `json:"groupId"`
+		Rules   []string `json:"rules"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateAutoApproveRules(r.Context(), req.GroupID, userID, req.Rules); err != nil {
+		h.log.WithContext(r.Context()).Error("Failed to update auto-approve rules: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Auto-approve rules updated successfully"})
+}
+
+// pagination reads the limit and offset query parameters, ignoring invalid values
+func pagination(r *http.Request, defaultLimit int) (limit, offset int) {
+	limit = defaultLimit
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && parsed > 0 {
+		limit = parsed
+	}
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+	return limit, offset
+}
+
+// errorStatus maps a service error to a response status
+func errorStatus(err error) int {
+	if errors.Is(err, ErrPermissionDenied) || errors.Is(err, ErrBanned) || errors.Is(err, ErrMuted) || errors.Is(err, ErrBlocked) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
 func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	httputil.SendJSON(w, status, data)
 }
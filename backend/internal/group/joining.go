@@ -0,0 +1,471 @@
+package group
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/db/uow"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// Auto-approve rules, a join request is accepted on its own when any rule of the group
+// matches the requester
+const (
+	AutoApproveFollowedByAdmin  = "followed_by_admin"  // A member who may approve joins follows the requester
+	AutoApproveFollowedByMember = "followed_by_member" // Any member follows the requester
+)
+
+var autoApproveRules = []string{AutoApproveFollowedByAdmin, AutoApproveFollowedByMember}
+
+const (
+	maxJoinQuestions      = 10
+	maxJoinQuestionLength = 500
+	maxJoinAnswerLength   = 2000
+	maxBulkReview         = 100
+)
+
+// JoinReviewResult reports which join requests a bulk review accepted or rejected and why
+// the others failed
+type JoinReviewResult struct {
+	Reviewed []string          `json:"reviewed"`
+	Failed   map[string]string `json:"failed,omitempty"`
+}
+
+// newInviteToken returns a random token that cannot be guessed
+func newInviteToken() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate invite token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// parseAutoApproveRules splits a group's stored rules, ignoring any no longer known
+func parseAutoApproveRules(s string) []string {
+	var rules []string
+	for _, rule := range strings.Split(s, ",") {
+		rule = strings.TrimSpace(rule)
+		for _, known := range autoApproveRules {
+			if rule == known {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// UpdateAutoApproveRules sets which join requests are accepted without an admin, no
+// rules means every request waits
+func (s *GroupService) UpdateAutoApproveRules(ctx context.Context, groupID, userID string, rules []string) error {
+	for _, rule := range rules {
+		if len(parseAutoApproveRules(rule)) == 0 {
+			return fmt.Errorf("unknown auto-approve rule %q", rule)
+		}
+	}
+
+	if err := s.auth.Require(groupID, userID, PermEditSettings, "changing auto-approve rules"); err != nil {
+		return err
+	}
+
+	stored := strings.Join(parseAutoApproveRules(strings.Join(rules, ",")), ",")
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.UpdateAutoApproveRules(groupID, stored); err != nil {
+			return err
+		}
+
+		details := "auto-approve rules: none"
+		if stored != "" {
+			details = "auto-approve rules: " + stored
+		}
+		return audit(repo, groupID, userID, AuditSettingsUpdated, auditEntry{details: details})
+	})
+}
+
+// autoApproves reports whether one of the group's auto-approve rules accepts userID
+func (s *GroupService) autoApproves(group *models.Group, userID string) (bool, error) {
+	rules := parseAutoApproveRules(group.AutoApprove)
+	if len(rules) == 0 {
+		return false, nil
+	}
+
+	members, err := s.repo.GetGroupMembers(group.ID, "accepted")
+	if err != nil {
+		return false, err
+	}
+
+	for _, rule := range rules {
+		var followerIDs []string
+		for _, member := range members {
+			if rule == AutoApproveFollowedByAdmin {
+				// Roles are per group and may be custom, so ask the authorizer
+				canApprove, err := s.auth.Can(group.ID, member.UserID, PermApproveJoins)
+				if err != nil {
+					return false, err
+				}
+				if !canApprove {
+					continue
+				}
+			}
+			followerIDs = append(followerIDs, member.UserID)
+		}
+
+		followed, err := s.repo.IsFollowedByAny(userID, followerIDs)
+		if err != nil || followed {
+			return followed, err
+		}
+	}
+
+	return false, nil
+}
+
+// joinAnswers checks given, answers keyed by question ID, against the group's join
+// questions and returns the answers to save
+func (s *GroupService) joinAnswers(groupID, userID string, given map[string]string) ([]*models.GroupJoinAnswer, error) {
+	questions, err := s.repo.GetJoinQuestions(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(questions))
+	var answers []*models.GroupJoinAnswer
+	for _, question := range questions {
+		known[question.ID] = true
+
+		text := strings.TrimSpace(given[question.ID])
+		if text == "" {
+			if question.Required {
+				return nil, fmt.Errorf("an answer to %q is required", question.Question)
+			}
+			continue
+		}
+
+		if len(text) > maxJoinAnswerLength {
+			return nil, fmt.Errorf("answers cannot be longer than %d characters", maxJoinAnswerLength)
+		}
+
+		answers = append(answers, &models.GroupJoinAnswer{
+			GroupID:    groupID,
+			QuestionID: question.ID,
+			UserID:     userID,
+			Answer:     text,
+		})
+	}
+
+	for id := range given {
+		if !known[id] {
+			return nil, errors.New("answer to an unknown join question")
+		}
+	}
+
+	return answers, nil
+}
+
+// GetJoinQuestions gets the questions asked of users who request to join a group
+func (s *GroupService) GetJoinQuestions(ctx context.Context, groupID string) ([]*models.GroupJoinQuestion, error) {
+	return s.repo.GetJoinQuestions(groupID)
+}
+
+// AddJoinQuestion adds a question to the end of a group's join questions
+func (s *GroupService) AddJoinQuestion(ctx context.Context, groupID, userID, text string, required bool) (*models.GroupJoinQuestion, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("question is required")
+	}
+
+	if len(text) > maxJoinQuestionLength {
+		return nil, fmt.Errorf("questions cannot be longer than %d characters", maxJoinQuestionLength)
+	}
+
+	if err := s.auth.Require(groupID, userID, PermEditSettings, "changing join questions"); err != nil {
+		return nil, err
+	}
+
+	question := &models.GroupJoinQuestion{
+		GroupID:  groupID,
+		Question: text,
+		Required: required,
+	}
+
+	err := s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		existing, err := repo.GetJoinQuestions(groupID)
+		if err != nil {
+			return err
+		}
+
+		if len(existing) >= maxJoinQuestions {
+			return fmt.Errorf("a group can ask at most %d join questions", maxJoinQuestions)
+		}
+
+		if len(existing) > 0 {
+			question.Position = existing[len(existing)-1].Position + 1
+		}
+
+		if err := repo.CreateJoinQuestion(question); err != nil {
+			return err
+		}
+
+		return audit(repo, groupID, userID, AuditJoinQuestionAdded, auditEntry{details: text})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return question, nil
+}
+
+// DeleteJoinQuestion removes a join question along with the answers given to it
+func (s *GroupService) DeleteJoinQuestion(ctx context.Context, groupID, userID, questionID string) error {
+	if err := s.auth.Require(groupID, userID, PermEditSettings, "changing join questions"); err != nil {
+		return err
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.DeleteJoinQuestion(groupID, questionID); err != nil {
+			return err
+		}
+
+		return audit(repo, groupID, userID, AuditJoinQuestionRemoved, auditEntry{details: questionID})
+	})
+}
+
+// attachJoinAnswers sets the answers of each pending join request among members
+func (s *GroupService) attachJoinAnswers(groupID string, members []*models.GroupMember) error {
+	answers, err := s.repo.GetJoinAnswers(groupID)
+	if err != nil {
+		return err
+	}
+
+	byUser := make(map[string][]*models.GroupJoinAnswer)
+	for _, answer := range answers {
+		byUser[answer.UserID] = append(byUser[answer.UserID], answer)
+	}
+
+	for _, member := range members {
+		if member.Status == "pending" && member.InvitedBy == "" {
+			member.Answers = byUser[member.UserID]
+		}
+	}
+
+	return nil
+}
+
+// ReviewJoinRequests accepts or rejects several join requests at once, every pending
+// request when userIDs is empty. Requests are reviewed one by one, so one that fails
+// does not hold up the others.
+func (s *GroupService) ReviewJoinRequests(ctx context.Context, groupID, adminID string, userIDs []string, accept bool) (*JoinReviewResult, error) {
+	if err := s.auth.Require(groupID, adminID, PermApproveJoins, "reviewing join requests"); err != nil {
+		return nil, err
+	}
+
+	if len(userIDs) == 0 {
+		pending, err := s.repo.GetGroupMembers(groupID, "pending")
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range pending {
+			if member.InvitedBy == "" {
+				userIDs = append(userIDs, member.UserID)
+			}
+		}
+	}
+
+	if len(userIDs) > maxBulkReview {
+		return nil, fmt.Errorf("at most %d join requests can be reviewed at once", maxBulkReview)
+	}
+
+	review := s.RejectJoinRequest
+	if accept {
+		review = s.AcceptJoinRequest
+	}
+
+	result := &JoinReviewResult{Reviewed: []string{}, Failed: map[string]string{}}
+	for _, userID := range userIDs {
+		if err := review(ctx, groupID, adminID, userID); err != nil {
+			result.Failed[userID] = err.Error()
+			continue
+		}
+		result.Reviewed = append(result.Reviewed, userID)
+	}
+
+	return result, nil
+}
+
+// CreateInviteLink creates a link that lets anyone holding it join a group without
+// approval, so it takes the approve_joins permission. maxUses 0 allows any number of
+// uses and a zero expiresIn keeps the link valid until it is revoked.
+func (s *GroupService) CreateInviteLink(ctx context.Context, groupID, userID string, maxUses int, expiresIn time.Duration) (*models.GroupInviteLink, error) {
+	if maxUses < 0 {
+		return nil, errors.New("maximum uses cannot be negative")
+	}
+
+	if expiresIn < 0 {
+		return nil, errors.New("expiry cannot be in the past")
+	}
+
+	if err := s.auth.Require(groupID, userID, PermApproveJoins, "creating invite links"); err != nil {
+		return nil, err
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := &models.GroupInviteLink{
+		GroupID:   groupID,
+		Token:     token,
+		CreatedBy: userID,
+		MaxUses:   maxUses,
+	}
+	if expiresIn > 0 {
+		link.ExpiresAt.Time, link.ExpiresAt.Valid = time.Now().Add(expiresIn), true
+	}
+
+	err = s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.CreateInviteLink(link); err != nil {
+			return err
+		}
+
+		details := fmt.Sprintf("max uses %d, %s", maxUses, expiryDetails("", link.ExpiresAt))
+		return audit(repo, groupID, userID, AuditInviteLinkCreated, auditEntry{details: details})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// GetInviteLinks lists the invite links of a group that were not revoked
+func (s *GroupService) GetInviteLinks(ctx context.Context, groupID, userID string) ([]*models.GroupInviteLink, error) {
+	if err := s.auth.Require(groupID, userID, PermApproveJoins, "viewing invite links"); err != nil {
+		return nil, err
+	}
+
+	links, err := s.repo.GetInviteLinks(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		if link.Creator, err = s.repo.GetUserBasicByID(link.CreatedBy); err != nil {
+			return nil, err
+		}
+	}
+
+	return links, nil
+}
+
+// RevokeInviteLink stops an invite link from letting anyone else in
+func (s *GroupService) RevokeInviteLink(ctx context.Context, groupID, userID, linkID string) error {
+	if err := s.auth.Require(groupID, userID, PermApproveJoins, "revoking invite links"); err != nil {
+		return err
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.RevokeInviteLink(groupID, linkID); err != nil {
+			return err
+		}
+
+		return audit(repo, groupID, userID, AuditInviteLinkRevoked, auditEntry{details: linkID})
+	})
+}
+
+// checkInviteLink returns why link cannot be used, nil if it can
+func checkInviteLink(link *models.GroupInviteLink) error {
+	switch {
+	case link == nil:
+		return errors.New("invite link not found")
+	case link.RevokedAt.Valid:
+		return errors.New("invite link has been revoked")
+	case link.ExpiresAt.Valid && !link.ExpiresAt.Time.After(time.Now()):
+		return errors.New("invite link has expired")
+	case link.MaxUses > 0 && link.Uses >= link.MaxUses:
+		return errors.New("invite link has been used up")
+	}
+	return nil
+}
+
+// JoinWithInviteLink makes userID a member of the group the link with token belongs to,
+// accepting any pending join request or invitation they have
+func (s *GroupService) JoinWithInviteLink(ctx context.Context, token, userID string) (*models.Group, error) {
+	link, err := s.repo.GetInviteLinkByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkInviteLink(link); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkNotBanned(link.GroupID, userID); err != nil {
+		return nil, err
+	}
+
+	group, err := s.repo.GetGroupByID(link.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		existing, err := repo.GetMemberByID(link.GroupID, userID)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil && existing.Status == "accepted" {
+			return errors.New("you are already a member of this group")
+		}
+
+		// Counting the use first keeps concurrent joins within the limit
+		if err := repo.UseInviteLink(link.ID); err != nil {
+			return err
+		}
+
+		if existing != nil {
+			err = repo.UpdateMemberStatus(link.GroupID, userID, "accepted")
+		} else {
+			err = repo.AddMember(&models.GroupMember{
+				GroupID:   link.GroupID,
+				UserID:    userID,
+				Role:      RoleMember,
+				Status:    "accepted",
+				InvitedBy: link.CreatedBy,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		tx.AfterCommit(func() {
+			s.notifications.NotifyGroupJoined(group, userID, "invite_link")
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	group.IsMember = true
+	group.MemberStatus = "accepted"
+	return group, nil
+}
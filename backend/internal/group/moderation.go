@@ -0,0 +1,467 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/db/uow"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// Audit log actions
+const (
+	AuditSettingsUpdated      = "settings_updated"
+	AuditPostApproved         = "post_approved"
+	AuditPostRejected         = "post_rejected"
+	AuditPostDeleted          = "post_deleted"
+	AuditPostPinned           = "post_pinned"
+	AuditPostUnpinned         = "post_unpinned"
+	AuditMemberRemoved        = "member_removed"
+	AuditMemberBanned         = "member_banned"
+	AuditMemberUnbanned       = "member_unbanned"
+	AuditMemberMuted          = "member_muted"
+	AuditMemberUnmuted        = "member_unmuted"
+	AuditRoleAssigned         = "role_assigned"
+	AuditRoleCreated          = "role_created"
+	AuditRoleUpdated          = "role_updated"
+	AuditRoleDeleted          = "role_deleted"
+	AuditOwnershipTransferred = "ownership_transferred"
+	AuditInviteLinkCreated    = "invite_link_created"
+	AuditInviteLinkRevoked    = "invite_link_revoked"
+	AuditJoinQuestionAdded    = "join_question_added"
+	AuditJoinQuestionRemoved  = "join_question_removed"
+)
+
+// maxNewMemberPosts bounds how many posts of a new member can be held for review
+const maxNewMemberPosts = 50
+
+var (
+	// ErrBanned is returned, wrapped, when a banned user tries to join a group
+	ErrBanned = errors.New("banned from this group")
+
+	// ErrMuted is returned, wrapped, when a muted member tries to post or chat
+	ErrMuted = errors.New("muted in this group")
+)
+
+// auditEntry describes what a moderation action was done to
+type auditEntry struct {
+	userID  string
+	postID  int64
+	details string
+}
+
+// audit records a moderation action through repo, which may be bound to a unit of work
+// so that the entry is only kept if the action is
+func audit(repo Repository, groupID, actorID, action string, target auditEntry) error {
+	entry := &models.GroupAuditLog{
+		GroupID:      groupID,
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: sql.NullString{String: target.userID, Valid: target.userID != ""},
+		TargetPostID: sql.NullInt64{Int64: target.postID, Valid: target.postID != 0},
+		Details:      target.details,
+	}
+	return repo.AddAuditLogEntry(entry)
+}
+
+// expiryDetails describes when a ban or mute ends for the audit log
+func expiryDetails(reason string, expiresAt sql.NullTime) string {
+	until := "permanent"
+	if expiresAt.Valid {
+		until = "until " + expiresAt.Time.UTC().Format(time.RFC3339)
+	}
+	if reason == "" {
+		return until
+	}
+	return fmt.Sprintf("%s (%s)", reason, until)
+}
+
+// UpdateModerationSettings sets whether every post, or the first newMemberPosts posts
+// of each member, waits in the moderation queue
+func (s *GroupService) UpdateModerationSettings(ctx context.Context, groupID, userID string, postApproval bool, newMemberPosts int) error {
+	if newMemberPosts < 0 || newMemberPosts > maxNewMemberPosts {
+		return fmt.Errorf("new member posts to review must be between 0 and %d", maxNewMemberPosts)
+	}
+
+	if err := s.auth.Require(groupID, userID, PermEditSettings, "changing moderation settings"); err != nil {
+		return err
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.UpdateModerationSettings(groupID, postApproval, newMemberPosts); err != nil {
+			return err
+		}
+
+		details := fmt.Sprintf("post approval %t, new member posts reviewed %d", postApproval, newMemberPosts)
+		return audit(repo, groupID, userID, AuditSettingsUpdated, auditEntry{details: details})
+	})
+}
+
+// needsApproval reports whether a new post by userID waits for a moderator. Members who
+// may moderate never wait.
+func (s *GroupService) needsApproval(group *models.Group, userID string) (bool, error) {
+	if !group.PostApproval && group.NewMemberPosts == 0 {
+		return false, nil
+	}
+
+	canModerate, err := s.auth.Can(group.ID, userID, PermModerate)
+	if err != nil || canModerate {
+		return false, err
+	}
+
+	if group.PostApproval {
+		return true, nil
+	}
+
+	approved, err := s.repo.CountApprovedGroupPosts(group.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	return approved < group.NewMemberPosts, nil
+}
+
+// checkNotMuted returns an error wrapping ErrMuted while userID is muted in groupID
+func (s *GroupService) checkNotMuted(groupID, userID string) error {
+	mute, err := s.repo.GetActiveMute(groupID, userID)
+	if err != nil {
+		return err
+	}
+	if mute != nil {
+		return fmt.Errorf("you are %w until %s", ErrMuted, mute.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkNotBanned returns an error wrapping ErrBanned while userID is banned from groupID
+func (s *GroupService) checkNotBanned(groupID, userID string) error {
+	ban, err := s.repo.GetActiveBan(groupID, userID)
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return fmt.Errorf("user is %w", ErrBanned)
+	}
+	return nil
+}
+
+// GetModerationQueue gets the posts of a group waiting for review, oldest first
+func (s *GroupService) GetModerationQueue(ctx context.Context, groupID, userID string, limit, offset int) ([]*models.GroupPost, error) {
+	if err := s.auth.Require(groupID, userID, PermModerate, "reviewing posts"); err != nil {
+		return nil, err
+	}
+
+	posts, err := s.repo.GetPendingGroupPosts(groupID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range posts {
+		user, err := s.repo.GetUserBasicByID(post.UserID)
+		if err != nil {
+			return nil, err
+		}
+		post.User = &models.PostUserData{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Avatar:    user.Avatar,
+		}
+	}
+
+	return posts, nil
+}
+
+// ReviewGroupPost approves a pending post, publishing it to the group, or rejects it
+func (s *GroupService) ReviewGroupPost(ctx context.Context, postID int64, userID string, approve bool, reason string) error {
+	post, err := s.repo.GetGroupPostByID(postID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.auth.Require(post.GroupID, userID, PermModerate, "reviewing posts"); err != nil {
+		return err
+	}
+
+	if post.Status != models.GroupPostPending {
+		return errors.New("post is not waiting for review")
+	}
+
+	status, action := models.GroupPostRejected, AuditPostRejected
+	if approve {
+		status, action = models.GroupPostApproved, AuditPostApproved
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.SetGroupPostStatus(postID, status); err != nil {
+			return err
+		}
+
+		if err := audit(repo, post.GroupID, userID, action, auditEntry{userID: post.UserID, postID: postID, details: reason}); err != nil {
+			return err
+		}
+
+		post.Status = status
+		tx.AfterCommit(func() {
+			s.notifications.NotifyGroupPostReviewed(post, reason)
+			if approve {
+				s.notifyGroupPostCreated(post)
+				go s.notifyGroupPostMentions(ctx, post, "")
+			}
+		})
+
+		return nil
+	})
+}
+
+// requireModeratorOver checks that actorID may moderate and holds every permission of the
+// member userID, so moderators cannot act against admins. Non-members need only the
+// moderate permission.
+func (s *GroupService) requireModeratorOver(groupID, actorID, userID, action string) error {
+	actorPerms, err := s.auth.Permissions(groupID, actorID)
+	if err != nil {
+		return err
+	}
+
+	if !actorPerms.Has(PermModerate) {
+		return fmt.Errorf("%w: %s requires the %s permission", ErrPermissionDenied, action, PermModerate)
+	}
+
+	target, err := s.repo.GetMemberAccess(groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	if target != nil && target.IsOwner {
+		return fmt.Errorf("%w: the group owner cannot be moderated", ErrPermissionDenied)
+	}
+
+	if !actorPerms.Covers(target.permissions()) {
+		return fmt.Errorf("%w: %s a member with permissions you do not have is not allowed", ErrPermissionDenied, action)
+	}
+
+	return nil
+}
+
+// BanMember removes a user from a group, or keeps a non-member out, and stops them from
+// joining or being invited again. A zero duration bans them permanently.
+func (s *GroupService) BanMember(ctx context.Context, groupID, actorID, userID, reason string, duration time.Duration) (*models.GroupBan, error) {
+	if actorID == userID {
+		return nil, errors.New("you cannot ban yourself")
+	}
+
+	if duration < 0 {
+		return nil, errors.New("ban duration cannot be negative")
+	}
+
+	if err := s.requireModeratorOver(groupID, actorID, userID, "banning"); err != nil {
+		return nil, err
+	}
+
+	ban := &models.GroupBan{
+		GroupID:  groupID,
+		UserID:   userID,
+		BannedBy: actorID,
+		Reason:   reason,
+	}
+	if duration > 0 {
+		ban.ExpiresAt = sql.NullTime{Time: time.Now().Add(duration), Valid: true}
+	}
+
+	err := s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		// Members, pending requests and invitations all go
+		member, err := repo.GetMemberByID(groupID, userID)
+		if err != nil {
+			return err
+		}
+
+		if member != nil {
+			if err := repo.RemoveMember(groupID, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := repo.SaveBan(ban); err != nil {
+			return err
+		}
+
+		if err := audit(repo, groupID, actorID, AuditMemberBanned, auditEntry{userID: userID, details: expiryDetails(reason, ban.ExpiresAt)}); err != nil {
+			return err
+		}
+
+		if member != nil && member.Status == "accepted" {
+			group, err := repo.GetGroupByID(groupID)
+			if err != nil {
+				return err
+			}
+			tx.AfterCommit(func() {
+				s.notifyGroupMemberRemoved(group, userID, actorID)
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ban, nil
+}
+
+// UnbanMember lifts a ban, the user may then ask to join again
+func (s *GroupService) UnbanMember(ctx context.Context, groupID, actorID, userID string) error {
+	if err := s.auth.Require(groupID, actorID, PermModerate, "lifting bans"); err != nil {
+		return err
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.DeleteBan(groupID, userID); err != nil {
+			return err
+		}
+
+		return audit(repo, groupID, actorID, AuditMemberUnbanned, auditEntry{userID: userID})
+	})
+}
+
+// GetBans lists the bans of a group that are in force
+func (s *GroupService) GetBans(ctx context.Context, groupID, userID string) ([]*models.GroupBan, error) {
+	if err := s.auth.Require(groupID, userID, PermModerate, "viewing bans"); err != nil {
+		return nil, err
+	}
+
+	bans, err := s.repo.GetActiveBans(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ban := range bans {
+		if ban.User, err = s.repo.GetUserBasicByID(ban.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return bans, nil
+}
+
+// MuteMember stops a member from posting and chatting in a group for duration
+func (s *GroupService) MuteMember(ctx context.Context, groupID, actorID, userID, reason string, duration time.Duration) (*models.GroupMute, error) {
+	if actorID == userID {
+		return nil, errors.New("you cannot mute yourself")
+	}
+
+	if duration <= 0 {
+		return nil, errors.New("mute duration must be positive")
+	}
+
+	if err := s.requireModeratorOver(groupID, actorID, userID, "muting"); err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.repo.IsGroupMember(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isMember {
+		return nil, errors.New("user is not a member of this group")
+	}
+
+	mute := &models.GroupMute{
+		GroupID:   groupID,
+		UserID:    userID,
+		MutedBy:   actorID,
+		Reason:    reason,
+		ExpiresAt: time.Now().Add(duration),
+	}
+
+	err = s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.SaveMute(mute); err != nil {
+			return err
+		}
+
+		expiry := sql.NullTime{Time: mute.ExpiresAt, Valid: true}
+		if err := audit(repo, groupID, actorID, AuditMemberMuted, auditEntry{userID: userID, details: expiryDetails(reason, expiry)}); err != nil {
+			return err
+		}
+
+		tx.AfterCommit(func() {
+			s.notifications.NotifyGroupMemberMuted(mute)
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mute, nil
+}
+
+// UnmuteMember lifts a mute before it expires
+func (s *GroupService) UnmuteMember(ctx context.Context, groupID, actorID, userID string) error {
+	if err := s.auth.Require(groupID, actorID, PermModerate, "lifting mutes"); err != nil {
+		return err
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.DeleteMute(groupID, userID); err != nil {
+			return err
+		}
+
+		return audit(repo, groupID, actorID, AuditMemberUnmuted, auditEntry{userID: userID})
+	})
+}
+
+// GetMutes lists the mutes of a group that are in force
+func (s *GroupService) GetMutes(ctx context.Context, groupID, userID string) ([]*models.GroupMute, error) {
+	if err := s.auth.Require(groupID, userID, PermModerate, "viewing mutes"); err != nil {
+		return nil, err
+	}
+
+	mutes, err := s.repo.GetActiveMutes(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mute := range mutes {
+		if mute.User, err = s.repo.GetUserBasicByID(mute.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return mutes, nil
+}
+
+// GetAuditLog gets the moderation actions taken in a group, newest first
+func (s *GroupService) GetAuditLog(ctx context.Context, groupID, userID string, limit, offset int) ([]*models.GroupAuditLog, error) {
+	if err := s.auth.Require(groupID, userID, PermViewAuditLog, "viewing the audit log"); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.GetAuditLog(groupID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.Actor, err = s.repo.GetUserBasicByID(entry.ActorID); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
@@ -1,6 +1,9 @@
 package group
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"time"
 
 	notifications "github.com/Athooh/social-network/internal/notifcations"
@@ -76,22 +79,24 @@ func (n *Notifications) NotifyGroupDeleted(group *models.Group, userID string) {
 }
 
 // NotifyGroupInvitation notifies about group invitation
-func (n *Notifications) NotifyGroupInvitation(group *models.Group, inviterID, inviteeID string, newNote *notifications.NewNotification, inviterInfo *models.UserBasic) {
+func (n *Notifications) NotifyGroupInvitation(ctx context.Context, group *models.Group, inviterID, inviteeID string, newNote *notifications.NewNotification, inviterInfo *models.UserBasic) {
 	if n.wsHub == nil {
-		n.log.Warn("WebSocket hub is nil, cannot send follow request notification")
+		n.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send follow request notification")
 		return
 	}
 
 	// Create notification in database
-	if err := n.notificationRepo.CreateNotification(newNote); err != nil {
-		n.log.Error("Failed to create follow request notification: %v", err)
+	if err := n.notificationRepo.CreateNotification(ctx, newNote); err != nil {
+		if !errors.Is(err, notifications.ErrSenderBlocked) {
+			n.log.WithContext(ctx).Error("Failed to create follow request notification: %v", err)
+		}
 		return
 	}
 
 	// Retrieve the newly created notification to get its ID and CreatedAt
-	notifications, err := n.notificationRepo.GetNotifications(inviteeID, 1, 0)
+	notifications, err := n.notificationRepo.GetNotifications(ctx, inviteeID, 1, 0)
 	if err != nil || len(notifications) == 0 {
-		n.log.Error("Failed to retrieve newly created notification: %v", err)
+		n.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
 		return
 	}
 	dbNotification := notifications[0]
@@ -136,22 +141,24 @@ func (n *Notifications) NotifyGroupInvitationAccepted(group *models.Group, userI
 }
 
 // NotifyGroupJoinRequest notifies about group join request
-func (n *Notifications) NotifyGroupJoinRequest(group *models.Group, inviterID, inviteeID string, newNote *notifications.NewNotification, inviterInfo *models.UserBasic) {
+func (n *Notifications) NotifyGroupJoinRequest(ctx context.Context, group *models.Group, inviterID, inviteeID string, newNote *notifications.NewNotification, inviterInfo *models.UserBasic) {
 	if n.wsHub == nil {
-		n.log.Warn("WebSocket hub is nil, cannot send follow request notification")
+		n.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send follow request notification")
 		return
 	}
 
 	// Create notification in database
-	if err := n.notificationRepo.CreateNotification(newNote); err != nil {
-		n.log.Error("Failed to create follow request notification: %v", err)
+	if err := n.notificationRepo.CreateNotification(ctx, newNote); err != nil {
+		if !errors.Is(err, notifications.ErrSenderBlocked) {
+			n.log.WithContext(ctx).Error("Failed to create follow request notification: %v", err)
+		}
 		return
 	}
 
 	// Retrieve the newly created notification to get its ID and CreatedAt
-	notifications, err := n.notificationRepo.GetNotifications(inviteeID, 1, 0)
+	notifications, err := n.notificationRepo.GetNotifications(ctx, inviteeID, 1, 0)
 	if err != nil || len(notifications) == 0 {
-		n.log.Error("Failed to retrieve newly created notification: %v", err)
+		n.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
 		return
 	}
 	dbNotification := notifications[0]
@@ -208,6 +215,35 @@ func (n *Notifications) NotifyGroupJoinRequestAccepted(group *models.Group, user
 	}
 }
 
+// NotifyGroupJoined notifies a user who joined without an admin accepting them, through
+// an invite link or an auto-approve rule, and the members of the group
+func (n *Notifications) NotifyGroupJoined(group *models.Group, userID, via string) {
+	user, _ := n.repo.GetUserBasicByID(userID)
+
+	n.wsHub.BroadcastToUser(userID, events.Event{
+		Type: "group_joined",
+		Payload: map[string]interface{}{
+			"group": group,
+			"via":   via,
+		},
+	})
+
+	memberEvent := events.Event{
+		Type: "new_group_member",
+		Payload: map[string]interface{}{
+			"group": group,
+			"user":  user,
+		},
+	}
+
+	members, _ := n.repo.GetGroupMembers(group.ID, "accepted")
+	for _, member := range members {
+		if member.UserID != userID {
+			n.wsHub.BroadcastToUser(member.UserID, memberEvent)
+		}
+	}
+}
+
 // NotifyGroupMemberLeft notifies about group member leaving
 func (n *Notifications) NotifyGroupMemberLeft(group *models.Group, userID string) {
 	user, _ := n.repo.GetUserBasicByID(userID)
@@ -249,6 +285,56 @@ func (n *Notifications) NotifyGroupMemberRoleUpdated(group *models.Group, userID
 	}
 }
 
+// NotifyGroupOwnershipTransferred tells members who owns the group now
+func (n *Notifications) NotifyGroupOwnershipTransferred(group *models.Group, previousOwnerID, newOwnerID string) {
+	previousOwner, _ := n.repo.GetUserBasicByID(previousOwnerID)
+	newOwner, _ := n.repo.GetUserBasicByID(newOwnerID)
+
+	event := events.Event{
+		Type: "group_ownership_transferred",
+		Payload: map[string]interface{}{
+			"group":         group,
+			"previousOwner": previousOwner,
+			"newOwner":      newOwner,
+		},
+	}
+
+	// Notify all members
+	members, _ := n.repo.GetGroupMembers(group.ID, "accepted")
+	for _, member := range members {
+		n.wsHub.BroadcastToUser(member.UserID, event)
+	}
+}
+
+// NotifyGroupPostReviewed tells the author whether a moderator approved or rejected their post
+func (n *Notifications) NotifyGroupPostReviewed(post *models.GroupPost, reason string) {
+	event := events.Event{
+		Type: "group_post_reviewed",
+		Payload: map[string]interface{}{
+			"postId":  post.ID,
+			"groupId": post.GroupID,
+			"status":  post.Status,
+			"reason":  reason,
+		},
+	}
+
+	n.wsHub.BroadcastToUser(post.UserID, event)
+}
+
+// NotifyGroupMemberMuted tells a member they cannot post or chat in a group for now
+func (n *Notifications) NotifyGroupMemberMuted(mute *models.GroupMute) {
+	event := events.Event{
+		Type: "group_member_muted",
+		Payload: map[string]interface{}{
+			"groupId":   mute.GroupID,
+			"reason":    mute.Reason,
+			"expiresAt": mute.ExpiresAt,
+		},
+	}
+
+	n.wsHub.BroadcastToUser(mute.UserID, event)
+}
+
 // NotifyGroupMemberRemoved notifies about group member removal
 func (n *Notifications) NotifyGroupMemberRemoved(group *models.Group, userID, adminID string) {
 	user, _ := n.repo.GetUserBasicByID(userID)
@@ -296,6 +382,80 @@ func (n *Notifications) NotifyGroupPostCreated(post *models.GroupPost) {
 	}
 }
 
+// NotifyGroupPostMention notifies a member that they were mentioned in a group post
+func (n *Notifications) NotifyGroupPostMention(ctx context.Context, post *models.GroupPost, mentionedID string) {
+	if n.wsHub == nil {
+		n.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send mention notification")
+		return
+	}
+
+	author, err := n.repo.GetUserBasicByID(post.UserID)
+	if err != nil {
+		n.log.WithContext(ctx).Error("Failed to get mention author: %v", err)
+		return
+	}
+	authorName := author.FirstName + " " + author.LastName
+
+	// Create notification in database
+	newNote := &notifications.NewNotification{
+		UserId:          mentionedID,
+		SenderId:        sql.NullString{String: post.UserID, Valid: true},
+		NotficationType: "mention",
+		Message:         authorName + " mentioned you in a group post.",
+		TargetGroupID:   sql.NullString{String: post.GroupID, Valid: true},
+		TargetPostID:    sql.NullInt64{Int64: post.ID, Valid: true},
+	}
+	if err := n.notificationRepo.CreateNotification(ctx, newNote); err != nil {
+		if !errors.Is(err, notifications.ErrSenderBlocked) {
+			n.log.WithContext(ctx).Error("Failed to create mention notification: %v", err)
+		}
+		return
+	}
+
+	// Retrieve the newly created notification to get its ID and CreatedAt
+	notifications, err := n.notificationRepo.GetNotifications(ctx, mentionedID, 1, 0)
+	if err != nil || len(notifications) == 0 {
+		n.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
+		return
+	}
+	dbNotification := notifications[0]
+
+	// Create WebSocket event
+	event := events.Event{
+		Type: events.HeaderNotificationUpdate,
+		Payload: map[string]interface{}{
+			"id":            dbNotification.ID,
+			"type":          newNote.NotficationType,
+			"senderId":      post.UserID,
+			"targetGroupId": post.GroupID,
+			"targetPostId":  post.ID,
+			"senderName":    authorName,
+			"senderAvatar":  author.Avatar,
+			"message":       newNote.Message,
+			"createdAt":     dbNotification.CreatedAt.Format(time.RFC3339),
+			"isRead":        dbNotification.IsRead,
+		},
+	}
+
+	n.wsHub.BroadcastToUser(mentionedID, event)
+}
+
+// NotifyGroupPostUpdated notifies about an edited group post
+func (n *Notifications) NotifyGroupPostUpdated(post *models.GroupPost) {
+	event := events.Event{
+		Type: "group_post_updated",
+		Payload: map[string]interface{}{
+			"post": post,
+		},
+	}
+
+	// Notify all members
+	members, _ := n.repo.GetGroupMembers(post.GroupID, "accepted")
+	for _, member := range members {
+		n.wsHub.BroadcastToUser(member.UserID, event)
+	}
+}
+
 // NotifyGroupChatMessage notifies about a new group chat message
 func (n *Notifications) NotifyGroupChatMessage(message *models.GroupChatMessage) {
 	event := events.Event{
@@ -0,0 +1,232 @@
+package group
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// Permission is something a group member may be allowed to do
+type Permission string
+
+const (
+	PermPost          Permission = "post"           // Create posts
+	PermComment       Permission = "comment"        // Comment on posts
+	PermInvite        Permission = "invite"         // Invite users to the group
+	PermApproveJoins  Permission = "approve_joins"  // Accept and reject join requests, see pending members, manage invite links
+	PermCreateEvents  Permission = "create_events"  // Create group events
+	PermPin           Permission = "pin"            // Pin and unpin posts
+	PermDeleteContent Permission = "delete_content" // Delete other members' posts, comments and events, see their edit history
+	PermManageRoles   Permission = "manage_roles"   // Assign roles, define custom roles and remove members
+	PermEditSettings  Permission = "edit_settings"  // Change the group's name, description, privacy, images, moderation and join settings and others' events
+	PermModerate      Permission = "moderate"       // Review pending posts, ban and mute members
+	PermViewAuditLog  Permission = "view_audit_log" // Read the log of moderation actions
+)
+
+// AllPermissions lists every permission in the order they are shown
+var AllPermissions = []Permission{
+	PermPost, PermComment, PermInvite, PermApproveJoins, PermCreateEvents,
+	PermPin, PermDeleteContent, PermManageRoles, PermEditSettings, PermModerate,
+	PermViewAuditLog,
+}
+
+// Built-in roles, every group has them and they cannot be changed
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+)
+
+// builtinRoles maps the built-in roles to their permissions
+var builtinRoles = map[string][]Permission{
+	RoleAdmin: AllPermissions,
+	RoleModerator: {
+		PermPost, PermComment, PermInvite, PermApproveJoins, PermCreateEvents,
+		PermPin, PermDeleteContent, PermModerate,
+	},
+	RoleMember: {PermPost, PermComment, PermInvite, PermCreateEvents},
+}
+
+// ErrPermissionDenied is returned, wrapped, when a member lacks a permission
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Role is a built-in or custom role with its permissions
+type Role struct {
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions"`
+	BuiltIn     bool         `json:"builtIn"`
+}
+
+// PermissionSet is a set of permissions
+type PermissionSet map[Permission]bool
+
+// Has reports whether the set holds p
+func (s PermissionSet) Has(p Permission) bool {
+	return s[p]
+}
+
+// Covers reports whether the set holds every permission of other
+func (s PermissionSet) Covers(other PermissionSet) bool {
+	for p := range other {
+		if !s[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// List returns the permissions in the order of AllPermissions
+func (s PermissionSet) List() []Permission {
+	var list []Permission
+	for _, p := range AllPermissions {
+		if s[p] {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// newPermissionSet builds a set from a list of permissions
+func newPermissionSet(perms []Permission) PermissionSet {
+	set := make(PermissionSet, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+	return set
+}
+
+// isBuiltinRole reports whether name is one of the built-in roles
+func isBuiltinRole(name string) bool {
+	_, ok := builtinRoles[name]
+	return ok
+}
+
+// parsePermissions validates names and returns them as a set
+func parsePermissions(names []string) (PermissionSet, error) {
+	set := make(PermissionSet, len(names))
+	known := newPermissionSet(AllPermissions)
+	for _, name := range names {
+		p := Permission(strings.TrimSpace(name))
+		if !known.Has(p) {
+			return nil, fmt.Errorf("unknown permission %q", name)
+		}
+		set[p] = true
+	}
+	return set, nil
+}
+
+// encodePermissions stores a set in GroupRole.Permissions
+func encodePermissions(set PermissionSet) string {
+	names := make([]string, 0, len(set))
+	for _, p := range set.List() {
+		names = append(names, string(p))
+	}
+	return strings.Join(names, ",")
+}
+
+// decodePermissions reads GroupRole.Permissions, names no longer known are dropped
+func decodePermissions(s string) PermissionSet {
+	set := make(PermissionSet)
+	known := newPermissionSet(AllPermissions)
+	for _, name := range strings.Split(s, ",") {
+		if p := Permission(strings.TrimSpace(name)); known.Has(p) {
+			set[p] = true
+		}
+	}
+	return set
+}
+
+// rolesOf lists the built-in roles followed by a group's custom roles sorted by name
+func rolesOf(custom []*models.GroupRole) []*Role {
+	roles := []*Role{
+		{Name: RoleAdmin, Permissions: builtinRoles[RoleAdmin], BuiltIn: true},
+		{Name: RoleModerator, Permissions: builtinRoles[RoleModerator], BuiltIn: true},
+		{Name: RoleMember, Permissions: builtinRoles[RoleMember], BuiltIn: true},
+	}
+
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Name < custom[j].Name })
+	for _, role := range custom {
+		roles = append(roles, &Role{Name: role.Name, Permissions: decodePermissions(role.Permissions).List()})
+	}
+	return roles
+}
+
+// MemberAccess is what decides a member's permissions
+type MemberAccess struct {
+	Role            string
+	IsOwner         bool
+	RolePermissions sql.NullString // Permissions of a custom role, not valid for built-in roles
+}
+
+// permissions returns the permissions access grants, none for a nil access. The owner
+// has every permission whatever their role, members whose custom role was deleted
+// have none.
+func (m *MemberAccess) permissions() PermissionSet {
+	switch {
+	case m == nil:
+		return PermissionSet{}
+	case m.IsOwner:
+		return newPermissionSet(AllPermissions)
+	case isBuiltinRole(m.Role):
+		return newPermissionSet(builtinRoles[m.Role])
+	case m.RolePermissions.Valid:
+		return decodePermissions(m.RolePermissions.String)
+	}
+	return PermissionSet{}
+}
+
+// Authorizer decides what users may do in a group. The group, event and post services
+// share it so that every action is checked against the same roles.
+type Authorizer struct {
+	repo Repository
+}
+
+// NewAuthorizer creates an authorizer reading memberships from repo
+func NewAuthorizer(repo Repository) *Authorizer {
+	return &Authorizer{repo: repo}
+}
+
+// Permissions returns what userID may do in groupID, nothing unless they are an accepted
+// member
+func (a *Authorizer) Permissions(groupID, userID string) (PermissionSet, error) {
+	access, err := a.repo.GetMemberAccess(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return access.permissions(), nil
+}
+
+// IsMember reports whether userID is an accepted member of groupID, whatever their role
+func (a *Authorizer) IsMember(groupID, userID string) (bool, error) {
+	access, err := a.repo.GetMemberAccess(groupID, userID)
+	if err != nil {
+		return false, err
+	}
+	return access != nil, nil
+}
+
+// Can reports whether userID may do perm in groupID
+func (a *Authorizer) Can(groupID, userID string, perm Permission) (bool, error) {
+	perms, err := a.Permissions(groupID, userID)
+	if err != nil {
+		return false, err
+	}
+	return perms.Has(perm), nil
+}
+
+// Require returns an error wrapping ErrPermissionDenied unless userID may do perm in
+// groupID, action describes what was attempted for the message
+func (a *Authorizer) Require(groupID, userID string, perm Permission, action string) error {
+	ok, err := a.Can(groupID, userID, perm)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s requires the %s permission", ErrPermissionDenied, action, perm)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package group
+
+import (
+	"database/sql"
+
+	"github.com/Athooh/social-network/pkg/db/uow"
+)
+
+// PostgresRepository implements Repository for PostgreSQL. Group queries are shared with
+// SQLiteRepository; chat message ids come back through RETURNING, which both support.
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL group repository, db must be opened with postgres.New
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{&SQLiteRepository{db: uow.NewDB(db)}}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *PostgresRepository) WithTx(tx *uow.Tx) Repository {
+	return &PostgresRepository{r.withTx(tx)}
+}
@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Athooh/social-network/pkg/db/uow"
+	"github.com/Athooh/social-network/pkg/user"
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
 	"github.com/google/uuid"
 )
@@ -18,6 +21,8 @@ type Repository interface {
 	GetGroupsByUserID(userID, viewerID string) ([]*models.Group, error)
 	GetAllGroups(userid string, limit, offset int) ([]*models.Group, error)
 	UpdateGroup(group *models.Group) error
+	UpdateModerationSettings(groupID string, postApproval bool, newMemberPosts int) error
+	UpdateAutoApproveRules(groupID, rules string) error
 	DeleteGroup(id string) error
 	DeleteMembers(groupID string) error
 	GetGroupMemberCount(groupID string) (int, error)
@@ -32,12 +37,55 @@ type Repository interface {
 	RemoveMember(groupID, userID string) error
 	IsGroupMember(groupID, userID string) (bool, error)
 	GetMemberRole(groupID, userID string) (string, error)
+	GetMemberAccess(groupID, userID string) (*MemberAccess, error)
+	GetMemberIDsByNicknames(groupID string, nicknames []string) ([]string, error)
+	UpdateGroupOwner(groupID, userID string) error
+	IsFollowedByAny(userID string, followerIDs []string) (bool, error)
+
+	// Invite link and join question operations
+	CreateInviteLink(link *models.GroupInviteLink) error
+	GetInviteLinks(groupID string) ([]*models.GroupInviteLink, error)
+	GetInviteLinkByToken(token string) (*models.GroupInviteLink, error)
+	RevokeInviteLink(groupID, linkID string) error
+	UseInviteLink(linkID string) error
+	GetJoinQuestions(groupID string) ([]*models.GroupJoinQuestion, error)
+	CreateJoinQuestion(question *models.GroupJoinQuestion) error
+	DeleteJoinQuestion(groupID, questionID string) error
+	SaveJoinAnswers(answers []*models.GroupJoinAnswer) error
+	GetJoinAnswers(groupID string) ([]*models.GroupJoinAnswer, error)
+
+	// Custom role operations
+	GetGroupRoles(groupID string) ([]*models.GroupRole, error)
+	GetGroupRole(groupID, name string) (*models.GroupRole, error)
+	CreateGroupRole(role *models.GroupRole) error
+	UpdateGroupRolePermissions(groupID, name, permissions string) error
+	DeleteGroupRole(groupID, name string) error
+	ReassignMemberRole(groupID, fromRole, toRole string) error
 
 	// Group posts operations
 	CreateGroupPost(post *models.GroupPost) error
 	GetGroupPosts(groupID string, currentUserID string, limit, offset int) ([]*models.GroupPost, error)
 	GetGroupPostByID(id int64) (*models.GroupPost, error)
+	GetPostReactionCounts(postID int64) (map[string]int, error)
+	UpdateGroupPost(post *models.GroupPost, revision *models.PostRevision) error
+	GetGroupPostRevisions(postID int64) ([]*models.PostRevision, error)
 	DeleteGroupPost(id int64) error
+	SetGroupPostPinned(id int64, pinned bool) error
+	GetPendingGroupPosts(groupID string, limit, offset int) ([]*models.GroupPost, error)
+	SetGroupPostStatus(id int64, status string) error
+	CountApprovedGroupPosts(groupID, userID string) (int, error)
+
+	// Moderation operations
+	SaveBan(ban *models.GroupBan) error
+	DeleteBan(groupID, userID string) error
+	GetActiveBan(groupID, userID string) (*models.GroupBan, error)
+	GetActiveBans(groupID string) ([]*models.GroupBan, error)
+	SaveMute(mute *models.GroupMute) error
+	DeleteMute(groupID, userID string) error
+	GetActiveMute(groupID, userID string) (*models.GroupMute, error)
+	GetActiveMutes(groupID string) ([]*models.GroupMute, error)
+	AddAuditLogEntry(entry *models.GroupAuditLog) error
+	GetAuditLog(groupID string, limit, offset int) ([]*models.GroupAuditLog, error)
 
 	// Group chat operations
 	AddChatMessage(message *models.GroupChatMessage) error
@@ -45,18 +93,31 @@ type Repository interface {
 
 	// User data operations
 	GetUserBasicByID(userID string) (*models.UserBasic, error)
+	IsBlocked(userID, otherID string) (bool, error)
 	UpdateUserGroupCount(userID string, increment bool) (int, error)
 	getNextAvailableID() (int64, error)
+
+	// WithTx binds the repository to a unit of work
+	WithTx(tx *uow.Tx) Repository
 }
 
 // SQLiteRepository implements Repository interface for SQLite
 type SQLiteRepository struct {
-	db *sql.DB
+	db *uow.DB
 }
 
 // NewSQLiteRepository creates a new SQLite repository
 func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
-	return &SQLiteRepository{db: db}
+	return &SQLiteRepository{db: uow.NewDB(db)}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *SQLiteRepository) WithTx(tx *uow.Tx) Repository {
+	return r.withTx(tx)
+}
+
+func (r *SQLiteRepository) withTx(tx *uow.Tx) *SQLiteRepository {
+	return &SQLiteRepository{db: r.db.WithTx(tx)}
 }
 
 // CreateGroup creates a new group
@@ -120,7 +181,8 @@ func (r *SQLiteRepository) CreateGroup(group *models.Group) error {
 func (r *SQLiteRepository) GetGroupByID(id string) (*models.Group, error) {
 	query := `
 		SELECT id, name, description, creator_id, banner_path, profile_pic_path, 
-		       is_public, created_at, updated_at
+		       is_public, post_approval, new_member_approval_posts, auto_approve_rules,
+		       created_at, updated_at
 		FROM groups
 		WHERE id = ?
 	`
@@ -136,6 +198,9 @@ func (r *SQLiteRepository) GetGroupByID(id string) (*models.Group, error) {
 		&bannerPath,
 		&profilePicPath,
 		&group.IsPublic,
+		&group.PostApproval,
+		&group.NewMemberPosts,
+		&group.AutoApprove,
 		&group.CreatedAt,
 		&group.UpdatedAt,
 	)
@@ -359,6 +424,30 @@ func (r *SQLiteRepository) UpdateGroup(group *models.Group) error {
 	return nil
 }
 
+// UpdateModerationSettings sets which posts of a group wait for a moderator
+func (r *SQLiteRepository) UpdateModerationSettings(groupID string, postApproval bool, newMemberPosts int) error {
+	_, err := r.db.Exec(
+		"UPDATE groups SET post_approval = ?, new_member_approval_posts = ?, updated_at = ? WHERE id = ?",
+		postApproval, newMemberPosts, time.Now(), groupID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update moderation settings: %w", err)
+	}
+	return nil
+}
+
+// UpdateAutoApproveRules sets the rules that accept join requests without an admin
+func (r *SQLiteRepository) UpdateAutoApproveRules(groupID, rules string) error {
+	_, err := r.db.Exec(
+		"UPDATE groups SET auto_approve_rules = ?, updated_at = ? WHERE id = ?",
+		rules, time.Now(), groupID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update auto-approve rules: %w", err)
+	}
+	return nil
+}
+
 // DeleteGroup deletes a group and all its members
 func (r *SQLiteRepository) DeleteGroup(id string) error {
 	// Get all members to update their group counts
@@ -380,6 +469,17 @@ func (r *SQLiteRepository) DeleteGroup(id string) error {
 		return fmt.Errorf("failed to delete group: %w", err)
 	}
 
+	// Delete its custom roles, moderation records, invite links and join questions
+	tables := []string{
+		"group_roles", "group_bans", "group_mutes", "group_audit_logs",
+		"group_invite_links", "group_join_questions", "group_join_answers",
+	}
+	for _, table := range tables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE group_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -636,6 +736,7 @@ func (r *SQLiteRepository) UpdateMemberStatus(groupID, userID, status string) er
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
 	// Update member status
 	_, err = tx.Exec(
@@ -646,32 +747,27 @@ func (r *SQLiteRepository) UpdateMemberStatus(groupID, userID, status string) er
 		userID,
 	)
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("failed to update member status: %w", err)
 	}
 
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	// Update user's group count if status changed to/from accepted
+	// Update user's group count if status changed to/from accepted, in the same transaction
 	if member.Status != "accepted" && status == "accepted" {
 		// Increment group count
-		_, err = r.UpdateUserGroupCount(userID, true)
-		if err != nil {
-			tx.Rollback()
+		if _, err = r.withTx(tx).UpdateUserGroupCount(userID, true); err != nil {
 			return fmt.Errorf("failed to increment user group count: %w", err)
 		}
 	} else if member.Status == "accepted" && status != "accepted" {
 		// Decrement group count
-		_, err = r.UpdateUserGroupCount(userID, false)
-		if err != nil {
-			tx.Rollback()
+		if _, err = r.withTx(tx).UpdateUserGroupCount(userID, false); err != nil {
 			return fmt.Errorf("failed to decrement user group count: %w", err)
 		}
 	}
 
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -742,6 +838,44 @@ func (r *SQLiteRepository) RemoveMember(groupID, userID string) error {
 	return nil
 }
 
+// GetMemberIDsByNicknames resolves nicknames (case-insensitive) to the IDs of accepted group members
+func (r *SQLiteRepository) GetMemberIDsByNicknames(groupID string, nicknames []string) ([]string, error) {
+	if len(nicknames) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{groupID}
+	for _, nickname := range nicknames {
+		args = append(args, strings.ToLower(nickname))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(nicknames)), ",")
+
+	query := `
+		SELECT u.id
+		FROM users u
+		JOIN group_members gm ON gm.user_id = u.id
+		WHERE gm.group_id = ? AND gm.status = 'accepted'
+		AND LOWER(u.nickname) IN (` + placeholders + `)
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mentions: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan mentioned user: %w", err)
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
+}
+
 // IsGroupMember checks if a user is a member of a group
 func (r *SQLiteRepository) IsGroupMember(groupID, userID string) (bool, error) {
 	query := `
@@ -779,6 +913,148 @@ func (r *SQLiteRepository) GetMemberRole(groupID, userID string) (string, error)
 	return role, nil
 }
 
+// GetMemberAccess gets the role of an accepted member, whether they own the group and
+// the permissions of their role if it is a custom one. It returns nil for non-members.
+func (r *SQLiteRepository) GetMemberAccess(groupID, userID string) (*MemberAccess, error) {
+	query := `
+		SELECT gm.role, g.creator_id, gr.permissions
+		FROM group_members gm
+		JOIN groups g ON g.id = gm.group_id
+		LEFT JOIN group_roles gr ON gr.group_id = gm.group_id AND gr.name = gm.role
+		WHERE gm.group_id = ? AND gm.user_id = ? AND gm.status = 'accepted'
+	`
+
+	var access MemberAccess
+	var creatorID string
+	err := r.db.QueryRow(query, groupID, userID).Scan(&access.Role, &creatorID, &access.RolePermissions)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not a member
+		}
+		return nil, fmt.Errorf("failed to get member access: %w", err)
+	}
+
+	access.IsOwner = creatorID == userID
+	return &access, nil
+}
+
+// UpdateGroupOwner makes userID the owner of a group
+func (r *SQLiteRepository) UpdateGroupOwner(groupID, userID string) error {
+	result, err := r.db.Exec(
+		"UPDATE groups SET creator_id = ?, updated_at = ? WHERE id = ?",
+		userID, time.Now(), groupID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update group owner: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("group not found")
+	}
+	return nil
+}
+
+// GetGroupRoles gets the custom roles of a group
+func (r *SQLiteRepository) GetGroupRoles(groupID string) ([]*models.GroupRole, error) {
+	rows, err := r.db.Query(`
+		SELECT id, group_id, name, permissions, created_at, updated_at
+		FROM group_roles
+		WHERE group_id = ?
+		ORDER BY name
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []*models.GroupRole
+	for rows.Next() {
+		var role models.GroupRole
+		if err := rows.Scan(&role.ID, &role.GroupID, &role.Name, &role.Permissions, &role.CreatedAt, &role.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan group role: %w", err)
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles, rows.Err()
+}
+
+// GetGroupRole gets a custom role of a group by name, nil if there is none
+func (r *SQLiteRepository) GetGroupRole(groupID, name string) (*models.GroupRole, error) {
+	var role models.GroupRole
+	err := r.db.QueryRow(`
+		SELECT id, group_id, name, permissions, created_at, updated_at
+		FROM group_roles
+		WHERE group_id = ? AND name = ?
+	`, groupID, name).Scan(&role.ID, &role.GroupID, &role.Name, &role.Permissions, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get group role: %w", err)
+	}
+
+	return &role, nil
+}
+
+// CreateGroupRole creates a custom role
+func (r *SQLiteRepository) CreateGroupRole(role *models.GroupRole) error {
+	if role.ID == "" {
+		role.ID = uuid.New().String()
+	}
+	now := time.Now()
+	role.CreatedAt = now
+	role.UpdatedAt = now
+
+	_, err := r.db.Exec(`
+		INSERT INTO group_roles (id, group_id, name, permissions, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, role.ID, role.GroupID, role.Name, role.Permissions, role.CreatedAt, role.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create group role: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateGroupRolePermissions replaces the permissions of a custom role
+func (r *SQLiteRepository) UpdateGroupRolePermissions(groupID, name, permissions string) error {
+	result, err := r.db.Exec(
+		"UPDATE group_roles SET permissions = ?, updated_at = ? WHERE group_id = ? AND name = ?",
+		permissions, time.Now(), groupID, name,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update group role: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("role not found")
+	}
+	return nil
+}
+
+// DeleteGroupRole deletes a custom role
+func (r *SQLiteRepository) DeleteGroupRole(groupID, name string) error {
+	result, err := r.db.Exec("DELETE FROM group_roles WHERE group_id = ? AND name = ?", groupID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete group role: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("role not found")
+	}
+	return nil
+}
+
+// ReassignMemberRole gives every member of a group with fromRole the role toRole
+func (r *SQLiteRepository) ReassignMemberRole(groupID, fromRole, toRole string) error {
+	_, err := r.db.Exec(
+		"UPDATE group_members SET role = ?, updated_at = ? WHERE group_id = ? AND role = ?",
+		toRole, time.Now(), groupID, fromRole,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reassign member roles: %w", err)
+	}
+	return nil
+}
+
 // CreateGroupPost creates a new post in a group
 func (r *SQLiteRepository) CreateGroupPost(post *models.GroupPost) error {
 	now := time.Now()
@@ -789,11 +1065,14 @@ func (r *SQLiteRepository) CreateGroupPost(post *models.GroupPost) error {
 		return err
 	}
 	post.ID = newid
+	if post.Status == "" {
+		post.Status = models.GroupPostApproved
+	}
 
 	query := `
 		INSERT INTO group_posts (
-			id, group_id, user_id, content, image_path, video_path, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			id, group_id, user_id, content, image_path, video_path, status, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err = r.db.Exec(
@@ -804,6 +1083,7 @@ func (r *SQLiteRepository) CreateGroupPost(post *models.GroupPost) error {
 		post.Content,
 		post.ImagePath,
 		post.VideoPath,
+		post.Status,
 		post.CreatedAt,
 		post.UpdatedAt,
 	)
@@ -818,16 +1098,16 @@ func (r *SQLiteRepository) CreateGroupPost(post *models.GroupPost) error {
 func (r *SQLiteRepository) GetGroupPosts(groupID string, currentUserID string, limit, offset int) ([]*models.GroupPost, error) {
 	query := `
         SELECT gp.id, gp.group_id, gp.user_id, gp.content, gp.image_path, gp.video_path, 
-               gp.likes_count, gp.comments_count, gp.created_at, gp.updated_at,
-               CASE WHEN pl.user_id IS NOT NULL THEN 1 ELSE 0 END as is_liked
+               gp.likes_count, gp.comments_count, gp.is_edited, gp.is_pinned, gp.status, gp.created_at, gp.updated_at,
+               CASE WHEN pl.user_id IS NOT NULL THEN 1 ELSE 0 END as is_liked, pl.reaction_type
         FROM group_posts gp
         LEFT JOIN post_likes pl ON pl.post_id = gp.id AND pl.user_id = ?
-        WHERE gp.group_id = ?
-        ORDER BY gp.created_at DESC
+        WHERE gp.group_id = ? AND (gp.status = 'approved' OR gp.user_id = ?)
+        ORDER BY gp.is_pinned DESC, gp.created_at DESC
         LIMIT ? OFFSET ?
     `
 
-	rows, err := r.db.Query(query, currentUserID, groupID, limit, offset)
+	rows, err := r.db.Query(query, currentUserID, groupID, currentUserID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get group posts: %w", err)
 	}
@@ -839,6 +1119,7 @@ func (r *SQLiteRepository) GetGroupPosts(groupID string, currentUserID string, l
 		var post models.GroupPost
 		var imagePath, videoPath sql.NullString
 		var isLiked bool
+		var reactionType sql.NullString
 
 		err := rows.Scan(
 			&post.ID,
@@ -849,9 +1130,13 @@ func (r *SQLiteRepository) GetGroupPosts(groupID string, currentUserID string, l
 			&videoPath,
 			&post.LikesCount,
 			&post.CommentsCount,
+			&post.IsEdited,
+			&post.IsPinned,
+			&post.Status,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 			&isLiked,
+			&reactionType,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan post row: %w", err)
@@ -860,6 +1145,7 @@ func (r *SQLiteRepository) GetGroupPosts(groupID string, currentUserID string, l
 		post.ImagePath = imagePath
 		post.VideoPath = videoPath
 		post.Isliked = isLiked
+		post.UserReaction = reactionType.String
 
 		// Get user data
 		userData, err := r.GetUserBasicByID(post.UserID)
@@ -880,14 +1166,53 @@ func (r *SQLiteRepository) GetGroupPosts(groupID string, currentUserID string, l
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating post rows: %w", err)
 	}
+	rows.Close()
+
+	for _, post := range posts {
+		counts, err := r.GetPostReactionCounts(post.ID)
+		if err != nil {
+			return nil, err
+		}
+		post.ReactionCounts = counts
+	}
 
 	return posts, nil
 }
 
+// GetPostReactionCounts gets the number of reactions of each type on a group post
+func (r *SQLiteRepository) GetPostReactionCounts(postID int64) (map[string]int, error) {
+	rows, err := r.db.Query(`
+		SELECT reaction_type, COUNT(*)
+		FROM post_likes
+		WHERE post_id = ?
+		GROUP BY reaction_type
+	`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reaction counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(models.ReactionTypes))
+	for _, reactionType := range models.ReactionTypes {
+		counts[reactionType] = 0
+	}
+
+	for rows.Next() {
+		var reactionType string
+		var count int
+		if err := rows.Scan(&reactionType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		counts[reactionType] = count
+	}
+
+	return counts, rows.Err()
+}
+
 // GetGroupPostByID gets a post by ID
 func (r *SQLiteRepository) GetGroupPostByID(id int64) (*models.GroupPost, error) {
 	query := `
-		SELECT id, group_id, user_id, content, image_path, video_path, likes_count, comments_count, created_at, updated_at
+		SELECT id, group_id, user_id, content, image_path, video_path, likes_count, comments_count, is_edited, is_pinned, status, created_at, updated_at
 		FROM group_posts
 		WHERE id = ?
 	`
@@ -904,6 +1229,9 @@ func (r *SQLiteRepository) GetGroupPostByID(id int64) (*models.GroupPost, error)
 		&videoPath,
 		&post.LikesCount,
 		&post.CommentsCount,
+		&post.IsEdited,
+		&post.IsPinned,
+		&post.Status,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 	)
@@ -933,41 +1261,683 @@ func (r *SQLiteRepository) GetGroupPostByID(id int64) (*models.GroupPost, error)
 	return &post, nil
 }
 
+// UpdateGroupPost updates a group post and stores its previous version as a revision
+func (r *SQLiteRepository) UpdateGroupPost(post *models.GroupPost, revision *models.PostRevision) error {
+	now := time.Now()
+	post.UpdatedAt = now
+	post.IsEdited = true
+	revision.CreatedAt = now
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE group_posts
+		SET content = ?, image_path = ?, video_path = ?, is_edited = TRUE, updated_at = ?
+		WHERE id = ?
+	`,
+		post.Content,
+		post.ImagePath,
+		post.VideoPath,
+		post.UpdatedAt,
+		post.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update group post: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO post_revisions (target_type, target_id, editor_id, content, image_path, video_path, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`,
+		revision.TargetType,
+		revision.TargetID,
+		revision.EditorID,
+		revision.Content,
+		revision.ImagePath,
+		revision.VideoPath,
+		revision.CreatedAt,
+	).Scan(&revision.ID)
+	if err != nil {
+		return fmt.Errorf("failed to store group post revision: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetGroupPostRevisions gets the stored revisions of a group post, newest first
+func (r *SQLiteRepository) GetGroupPostRevisions(postID int64) ([]*models.PostRevision, error) {
+	query := `
+		SELECT id, target_type, target_id, editor_id, content, image_path, video_path, created_at
+		FROM post_revisions
+		WHERE target_type = ? AND target_id = ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(query, models.RevisionTargetGroupPost, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group post revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*models.PostRevision
+
+	for rows.Next() {
+		var revision models.PostRevision
+
+		err := rows.Scan(
+			&revision.ID,
+			&revision.TargetType,
+			&revision.TargetID,
+			&revision.EditorID,
+			&revision.Content,
+			&revision.ImagePath,
+			&revision.VideoPath,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan revision row: %w", err)
+		}
+
+		editor, err := r.GetUserBasicByID(revision.EditorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get editor info: %w", err)
+		}
+		revision.EditorData = &models.PostUserData{
+			ID:        editor.ID,
+			FirstName: editor.FirstName,
+			LastName:  editor.LastName,
+			Avatar:    editor.Avatar,
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revision rows: %w", err)
+	}
+
+	return revisions, nil
+}
+
 // DeleteGroupPost deletes a post
 func (r *SQLiteRepository) DeleteGroupPost(id int64) error {
-	_, err := r.db.Exec("DELETE FROM group_posts WHERE id = ?", id)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM post_revisions WHERE target_type = ? AND target_id = ?", models.RevisionTargetGroupPost, id)
 	if err != nil {
+		return fmt.Errorf("failed to delete post history: %w", err)
+	}
+	if _, err = tx.Exec("DELETE FROM group_posts WHERE id = ?", id); err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
 
+	return tx.Commit()
+}
+
+// SetGroupPostPinned pins or unpins a group post, pinned posts are listed first
+func (r *SQLiteRepository) SetGroupPostPinned(id int64, pinned bool) error {
+	result, err := r.db.Exec("UPDATE group_posts SET is_pinned = ? WHERE id = ?", pinned, id)
+	if err != nil {
+		return fmt.Errorf("failed to pin group post: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("post not found")
+	}
+	return nil
+}
+
+// GetPendingGroupPosts gets the posts of a group waiting for a moderator, oldest first
+func (r *SQLiteRepository) GetPendingGroupPosts(groupID string, limit, offset int) ([]*models.GroupPost, error) {
+	rows, err := r.db.Query(`
+		SELECT id, group_id, user_id, content, image_path, video_path, status, created_at, updated_at
+		FROM group_posts
+		WHERE group_id = ? AND status = 'pending'
+		ORDER BY created_at ASC
+		LIMIT ? OFFSET ?
+	`, groupID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending group posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*models.GroupPost
+	for rows.Next() {
+		var post models.GroupPost
+		if err := rows.Scan(
+			&post.ID,
+			&post.GroupID,
+			&post.UserID,
+			&post.Content,
+			&post.ImagePath,
+			&post.VideoPath,
+			&post.Status,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan pending group post: %w", err)
+		}
+		posts = append(posts, &post)
+	}
+
+	return posts, rows.Err()
+}
+
+// SetGroupPostStatus approves or rejects a group post
+func (r *SQLiteRepository) SetGroupPostStatus(id int64, status string) error {
+	result, err := r.db.Exec("UPDATE group_posts SET status = ? WHERE id = ?", status, id)
+	if err != nil {
+		return fmt.Errorf("failed to update group post status: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("post not found")
+	}
+	return nil
+}
+
+// CountApprovedGroupPosts counts the approved posts of a user in a group
+func (r *SQLiteRepository) CountApprovedGroupPosts(groupID, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM group_posts WHERE group_id = ? AND user_id = ? AND status = 'approved'",
+		groupID, userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count group posts: %w", err)
+	}
+	return count, nil
+}
+
+// SaveBan bans a user from a group, replacing an earlier ban
+func (r *SQLiteRepository) SaveBan(ban *models.GroupBan) error {
+	if ban.ID == "" {
+		ban.ID = uuid.New().String()
+	}
+	ban.CreatedAt = time.Now()
+
+	err := r.db.QueryRow(`
+		INSERT INTO group_bans (id, group_id, user_id, banned_by, reason, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (group_id, user_id) DO UPDATE SET
+			banned_by = excluded.banned_by, reason = excluded.reason,
+			expires_at = excluded.expires_at, created_at = excluded.created_at
+		RETURNING id
+	`, ban.ID, ban.GroupID, ban.UserID, ban.BannedBy, ban.Reason, ban.ExpiresAt, ban.CreatedAt).Scan(&ban.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save ban: %w", err)
+	}
+	return nil
+}
+
+// DeleteBan lifts a ban
+func (r *SQLiteRepository) DeleteBan(groupID, userID string) error {
+	result, err := r.db.Exec("DELETE FROM group_bans WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete ban: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("user is not banned")
+	}
+	return nil
+}
+
+// GetActiveBan gets the ban keeping a user out of a group, nil if there is none
+func (r *SQLiteRepository) GetActiveBan(groupID, userID string) (*models.GroupBan, error) {
+	bans, err := r.queryBans(`
+		SELECT id, group_id, user_id, banned_by, reason, expires_at, created_at
+		FROM group_bans
+		WHERE group_id = ? AND user_id = ? AND (expires_at IS NULL OR expires_at > ?)
+	`, groupID, userID, time.Now())
+	if err != nil || len(bans) == 0 {
+		return nil, err
+	}
+	return bans[0], nil
+}
+
+// GetActiveBans gets the bans of a group that have not expired, newest first
+func (r *SQLiteRepository) GetActiveBans(groupID string) ([]*models.GroupBan, error) {
+	return r.queryBans(`
+		SELECT id, group_id, user_id, banned_by, reason, expires_at, created_at
+		FROM group_bans
+		WHERE group_id = ? AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY created_at DESC
+	`, groupID, time.Now())
+}
+
+func (r *SQLiteRepository) queryBans(query string, args ...interface{}) ([]*models.GroupBan, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bans: %w", err)
+	}
+	defer rows.Close()
+
+	var bans []*models.GroupBan
+	for rows.Next() {
+		var ban models.GroupBan
+		if err := rows.Scan(&ban.ID, &ban.GroupID, &ban.UserID, &ban.BannedBy, &ban.Reason, &ban.ExpiresAt, &ban.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ban: %w", err)
+		}
+		bans = append(bans, &ban)
+	}
+
+	return bans, rows.Err()
+}
+
+// SaveMute mutes a member of a group, replacing an earlier mute
+func (r *SQLiteRepository) SaveMute(mute *models.GroupMute) error {
+	if mute.ID == "" {
+		mute.ID = uuid.New().String()
+	}
+	mute.CreatedAt = time.Now()
+
+	err := r.db.QueryRow(`
+		INSERT INTO group_mutes (id, group_id, user_id, muted_by, reason, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (group_id, user_id) DO UPDATE SET
+			muted_by = excluded.muted_by, reason = excluded.reason,
+			expires_at = excluded.expires_at, created_at = excluded.created_at
+		RETURNING id
+	`, mute.ID, mute.GroupID, mute.UserID, mute.MutedBy, mute.Reason, mute.ExpiresAt, mute.CreatedAt).Scan(&mute.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save mute: %w", err)
+	}
+	return nil
+}
+
+// DeleteMute lifts a mute
+func (r *SQLiteRepository) DeleteMute(groupID, userID string) error {
+	result, err := r.db.Exec("DELETE FROM group_mutes WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete mute: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("user is not muted")
+	}
+	return nil
+}
+
+// GetActiveMute gets the mute silencing a member of a group, nil if there is none
+func (r *SQLiteRepository) GetActiveMute(groupID, userID string) (*models.GroupMute, error) {
+	mutes, err := r.queryMutes(`
+		SELECT id, group_id, user_id, muted_by, reason, expires_at, created_at
+		FROM group_mutes
+		WHERE group_id = ? AND user_id = ? AND expires_at > ?
+	`, groupID, userID, time.Now())
+	if err != nil || len(mutes) == 0 {
+		return nil, err
+	}
+	return mutes[0], nil
+}
+
+// GetActiveMutes gets the mutes of a group that have not expired, newest first
+func (r *SQLiteRepository) GetActiveMutes(groupID string) ([]*models.GroupMute, error) {
+	return r.queryMutes(`
+		SELECT id, group_id, user_id, muted_by, reason, expires_at, created_at
+		FROM group_mutes
+		WHERE group_id = ? AND expires_at > ?
+		ORDER BY created_at DESC
+	`, groupID, time.Now())
+}
+
+func (r *SQLiteRepository) queryMutes(query string, args ...interface{}) ([]*models.GroupMute, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mutes: %w", err)
+	}
+	defer rows.Close()
+
+	var mutes []*models.GroupMute
+	for rows.Next() {
+		var mute models.GroupMute
+		if err := rows.Scan(&mute.ID, &mute.GroupID, &mute.UserID, &mute.MutedBy, &mute.Reason, &mute.ExpiresAt, &mute.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan mute: %w", err)
+		}
+		mutes = append(mutes, &mute)
+	}
+
+	return mutes, rows.Err()
+}
+
+// AddAuditLogEntry records a moderation action
+func (r *SQLiteRepository) AddAuditLogEntry(entry *models.GroupAuditLog) error {
+	entry.CreatedAt = time.Now()
+
+	err := r.db.QueryRow(`
+		INSERT INTO group_audit_logs (group_id, actor_id, action, target_user_id, target_post_id, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, entry.GroupID, entry.ActorID, entry.Action, entry.TargetUserID, entry.TargetPostID, entry.Details, entry.CreatedAt).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to add audit log entry: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLog gets the moderation actions taken in a group, newest first
+func (r *SQLiteRepository) GetAuditLog(groupID string, limit, offset int) ([]*models.GroupAuditLog, error) {
+	rows, err := r.db.Query(`
+		SELECT id, group_id, actor_id, action, target_user_id, target_post_id, details, created_at
+		FROM group_audit_logs
+		WHERE group_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, groupID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.GroupAuditLog
+	for rows.Next() {
+		var entry models.GroupAuditLog
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.GroupID,
+			&entry.ActorID,
+			&entry.Action,
+			&entry.TargetUserID,
+			&entry.TargetPostID,
+			&entry.Details,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit log entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// IsFollowedByAny reports whether any of followerIDs follows userID
+func (r *SQLiteRepository) IsFollowedByAny(userID string, followerIDs []string) (bool, error) {
+	if len(followerIDs) == 0 {
+		return false, nil
+	}
+
+	args := []interface{}{userID}
+	for _, id := range followerIDs {
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf(
+		"SELECT COUNT(*) FROM followers WHERE following_id = ? AND follower_id IN (?%s)",
+		strings.Repeat(", ?", len(followerIDs)-1),
+	)
+
+	var count int
+	if err := r.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check followers: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CreateInviteLink saves a new invite link
+func (r *SQLiteRepository) CreateInviteLink(link *models.GroupInviteLink) error {
+	if link.ID == "" {
+		link.ID = uuid.New().String()
+	}
+	link.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		INSERT INTO group_invite_links (id, group_id, token, created_by, max_uses, uses, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, ?)
+	`, link.ID, link.GroupID, link.Token, link.CreatedBy, link.MaxUses, link.ExpiresAt, link.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invite link: %w", err)
+	}
+	return nil
+}
+
+// GetInviteLinks gets the invite links of a group that were not revoked, newest first
+func (r *SQLiteRepository) GetInviteLinks(groupID string) ([]*models.GroupInviteLink, error) {
+	return r.queryInviteLinks(`
+		SELECT id, group_id, token, created_by, max_uses, uses, expires_at, revoked_at, created_at
+		FROM group_invite_links
+		WHERE group_id = ? AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, groupID)
+}
+
+// GetInviteLinkByToken gets the invite link with token, nil if there is none
+func (r *SQLiteRepository) GetInviteLinkByToken(token string) (*models.GroupInviteLink, error) {
+	links, err := r.queryInviteLinks(`
+		SELECT id, group_id, token, created_by, max_uses, uses, expires_at, revoked_at, created_at
+		FROM group_invite_links
+		WHERE token = ?
+	`, token)
+	if err != nil || len(links) == 0 {
+		return nil, err
+	}
+	return links[0], nil
+}
+
+func (r *SQLiteRepository) queryInviteLinks(query string, args ...interface{}) ([]*models.GroupInviteLink, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []*models.GroupInviteLink
+	for rows.Next() {
+		var link models.GroupInviteLink
+		if err := rows.Scan(
+			&link.ID,
+			&link.GroupID,
+			&link.Token,
+			&link.CreatedBy,
+			&link.MaxUses,
+			&link.Uses,
+			&link.ExpiresAt,
+			&link.RevokedAt,
+			&link.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan invite link: %w", err)
+		}
+		links = append(links, &link)
+	}
+
+	return links, rows.Err()
+}
+
+// RevokeInviteLink stops an invite link from being used
+func (r *SQLiteRepository) RevokeInviteLink(groupID, linkID string) error {
+	result, err := r.db.Exec(
+		"UPDATE group_invite_links SET revoked_at = ? WHERE id = ? AND group_id = ? AND revoked_at IS NULL",
+		time.Now(), linkID, groupID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite link: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("invite link not found")
+	}
+	return nil
+}
+
+// UseInviteLink counts a use of an invite link. It fails if the link was revoked, has
+// expired or is used up, so concurrent joins cannot go over the limit.
+func (r *SQLiteRepository) UseInviteLink(linkID string) error {
+	result, err := r.db.Exec(`
+		UPDATE group_invite_links SET uses = uses + 1
+		WHERE id = ? AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > ?)
+			AND (max_uses = 0 OR uses < max_uses)
+	`, linkID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to use invite link: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("invite link is no longer valid")
+	}
+	return nil
+}
+
+// GetJoinQuestions gets the join questions of a group in the order they are asked
+func (r *SQLiteRepository) GetJoinQuestions(groupID string) ([]*models.GroupJoinQuestion, error) {
+	rows, err := r.db.Query(`
+		SELECT id, group_id, question, required, position, created_at
+		FROM group_join_questions
+		WHERE group_id = ?
+		ORDER BY position, created_at
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join questions: %w", err)
+	}
+	defer rows.Close()
+
+	var questions []*models.GroupJoinQuestion
+	for rows.Next() {
+		var question models.GroupJoinQuestion
+		if err := rows.Scan(
+			&question.ID,
+			&question.GroupID,
+			&question.Question,
+			&question.Required,
+			&question.Position,
+			&question.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan join question: %w", err)
+		}
+		questions = append(questions, &question)
+	}
+
+	return questions, rows.Err()
+}
+
+// CreateJoinQuestion adds a join question to a group
+func (r *SQLiteRepository) CreateJoinQuestion(question *models.GroupJoinQuestion) error {
+	if question.ID == "" {
+		question.ID = uuid.New().String()
+	}
+	question.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		INSERT INTO group_join_questions (id, group_id, question, required, position, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, question.ID, question.GroupID, question.Question, question.Required, question.Position, question.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create join question: %w", err)
+	}
 	return nil
 }
 
+// DeleteJoinQuestion deletes a join question and the answers given to it
+func (r *SQLiteRepository) DeleteJoinQuestion(groupID, questionID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM group_join_questions WHERE id = ? AND group_id = ?", questionID, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete join question: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("join question not found")
+	}
+
+	if _, err := tx.Exec("DELETE FROM group_join_answers WHERE question_id = ?", questionID); err != nil {
+		return fmt.Errorf("failed to delete join answers: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SaveJoinAnswers saves answers to join questions, replacing earlier answers of the same
+// user to the same questions
+func (r *SQLiteRepository) SaveJoinAnswers(answers []*models.GroupJoinAnswer) error {
+	now := time.Now()
+	for _, answer := range answers {
+		if answer.ID == "" {
+			answer.ID = uuid.New().String()
+		}
+		answer.CreatedAt = now
+
+		_, err := r.db.Exec(`
+			INSERT INTO group_join_answers (id, group_id, question_id, user_id, answer, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (question_id, user_id) DO UPDATE SET
+				answer = excluded.answer, created_at = excluded.created_at
+		`, answer.ID, answer.GroupID, answer.QuestionID, answer.UserID, answer.Answer, answer.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save join answer: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetJoinAnswers gets every answer given to the join questions of a group that still
+// exist, with the question text, in question order
+func (r *SQLiteRepository) GetJoinAnswers(groupID string) ([]*models.GroupJoinAnswer, error) {
+	rows, err := r.db.Query(`
+		SELECT a.id, a.group_id, a.question_id, a.user_id, a.answer, a.created_at, q.question
+		FROM group_join_answers a
+		JOIN group_join_questions q ON q.id = a.question_id
+		WHERE a.group_id = ?
+		ORDER BY q.position, q.created_at
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join answers: %w", err)
+	}
+	defer rows.Close()
+
+	var answers []*models.GroupJoinAnswer
+	for rows.Next() {
+		var answer models.GroupJoinAnswer
+		if err := rows.Scan(
+			&answer.ID,
+			&answer.GroupID,
+			&answer.QuestionID,
+			&answer.UserID,
+			&answer.Answer,
+			&answer.CreatedAt,
+			&answer.Question,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan join answer: %w", err)
+		}
+		answers = append(answers, &answer)
+	}
+
+	return answers, rows.Err()
+}
+
 // AddChatMessage adds a message to a group chat
 func (r *SQLiteRepository) AddChatMessage(message *models.GroupChatMessage) error {
 	query := `
 		INSERT INTO group_chat_messages (
 			group_id, user_id, content, created_at
 		) VALUES (?, ?, ?, ?)
+		RETURNING id
 	`
 
-	result, err := r.db.Exec(
+	err := r.db.QueryRow(
 		query,
 		message.GroupID,
 		message.UserID,
 		message.Content,
 		time.Now(),
-	)
+	).Scan(&message.ID)
 	if err != nil {
 		return fmt.Errorf("failed to add chat message: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert ID: %w", err)
-	}
-
-	message.ID = id
 	return nil
 }
 
@@ -1025,6 +1995,11 @@ func (r *SQLiteRepository) GetGroupChatMessages(groupID string, limit, offset in
 	return messages, nil
 }
 
+// IsBlocked checks if either user blocked the other
+func (r *SQLiteRepository) IsBlocked(userID, otherID string) (bool, error) {
+	return user.IsBlocked(r.db, userID, otherID)
+}
+
 // GetUserBasicByID gets basic user information by ID
 func (r *SQLiteRepository) GetUserBasicByID(userID string) (*models.UserBasic, error) {
 	query := `
@@ -1125,30 +2100,14 @@ func (r *SQLiteRepository) UpdateUserGroupCount(userID string, increment bool) (
 	return currentCount, nil
 }
 
-// getNextAvailableID checks 'posts' and 'group_posts' for max(id) and returns next available int64 ID.
+// getNextAvailableID reserves the id of a new post or group post. Ids come from the
+// post_keys sequence rather than the largest id in use, so the id of a deleted post is
+// never handed out again while reshares, likes or comments may still refer to it.
 func (r *SQLiteRepository) getNextAvailableID() (int64, error) {
-	var maxPostID, maxGroupPostID sql.NullInt64
-
-	// Query max id from 'posts'
-	err := r.db.QueryRow("SELECT MAX(id) FROM posts").Scan(&maxPostID)
+	var id int64
+	err := r.db.QueryRow("INSERT INTO post_keys DEFAULT VALUES RETURNING id").Scan(&id)
 	if err != nil {
-		return 0, fmt.Errorf("error querying posts: %v", err)
+		return 0, fmt.Errorf("error reserving post id: %v", err)
 	}
-
-	// Query max id from 'group_posts'
-	err = r.db.QueryRow("SELECT MAX(id) FROM group_posts").Scan(&maxGroupPostID)
-	if err != nil {
-		return 0, fmt.Errorf("error querying group_posts: %v", err)
-	}
-
-	// Calculate the next ID
-	maxID := int64(0)
-	if maxPostID.Valid && maxPostID.Int64 > maxID {
-		maxID = maxPostID.Int64
-	}
-	if maxGroupPostID.Valid && maxGroupPostID.Int64 > maxID {
-		maxID = maxGroupPostID.Int64
-	}
-
-	return maxID + 1, nil
+	return id, nil
 }
@@ -1,16 +1,20 @@
 package group
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"mime/multipart"
+	"strings"
 	"time"
 
 	notifications "github.com/Athooh/social-network/internal/notifcations"
+	"github.com/Athooh/social-network/pkg/db/uow"
 	"github.com/Athooh/social-network/pkg/filestore"
 	"github.com/Athooh/social-network/pkg/logger"
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/utils"
 	"github.com/Athooh/social-network/pkg/websocket"
 	"github.com/google/uuid"
 )
@@ -18,59 +22,96 @@ import (
 // Service defines the interface for group business logic
 type Service interface {
 	// Group operations
-	CreateGroup(userID, name, description string, isPublic bool, banner, profilePic *multipart.FileHeader) (*models.Group, error)
-	GetGroup(id, userID string) (*models.Group, error)
-	GetUserGroups(userID, viewerID string) ([]*models.Group, error)
-	GetAllGroups(userID string, limit, offset int) ([]*models.Group, error)
-	UpdateGroup(id, userID, name, description string, isPublic bool, banner, profilePic *multipart.FileHeader) (*models.Group, error)
-	DeleteGroup(id, userID string) error
+	CreateGroup(ctx context.Context, userID, name, description string, isPublic bool, banner, profilePic *multipart.FileHeader) (*models.Group, error)
+	GetGroup(ctx context.Context, id, userID string) (*models.Group, error)
+	GetUserGroups(ctx context.Context, userID, viewerID string) ([]*models.Group, error)
+	GetAllGroups(ctx context.Context, userID string, limit, offset int) ([]*models.Group, error)
+	UpdateGroup(ctx context.Context, id, userID, name, description string, isPublic bool, banner, profilePic *multipart.FileHeader) (*models.Group, error)
+	DeleteGroup(ctx context.Context, id, userID string) error
 
 	// Group membership operations
-	InviteToGroup(groupID, inviterID, inviteeID string) error
-	JoinGroup(groupID, userID string) error
-	LeaveGroup(groupID, userID string) error
-	AcceptInvitation(groupID, userID string) error
-	RejectInvitation(groupID, userID string) error
-	AcceptJoinRequest(groupID, adminID, userID string) error
-	RejectJoinRequest(groupID, adminID, userID string) error
-	UpdateMemberRole(groupID, adminID, userID, role string) error
-	RemoveMember(groupID, adminID, userID string) error
-	GetGroupMembers(groupID, userID string, status string) ([]*models.GroupMember, error)
+	InviteToGroup(ctx context.Context, groupID, inviterID, inviteeID string) error
+	JoinGroup(ctx context.Context, groupID, userID string, answers map[string]string) (*models.GroupMember, error)
+	LeaveGroup(ctx context.Context, groupID, userID string) error
+	AcceptInvitation(ctx context.Context, groupID, userID string) error
+	RejectInvitation(ctx context.Context, groupID, userID string) error
+	AcceptJoinRequest(ctx context.Context, groupID, adminID, userID string) error
+	RejectJoinRequest(ctx context.Context, groupID, adminID, userID string) error
+	UpdateMemberRole(ctx context.Context, groupID, adminID, userID, role string) error
+	RemoveMember(ctx context.Context, groupID, adminID, userID string) error
+	GetGroupMembers(ctx context.Context, groupID, userID string, status string) ([]*models.GroupMember, error)
+	TransferOwnership(ctx context.Context, groupID, ownerID, newOwnerID string) error
+	ReviewJoinRequests(ctx context.Context, groupID, adminID string, userIDs []string, accept bool) (*JoinReviewResult, error)
+
+	// Invite link, join question and auto-approve operations
+	CreateInviteLink(ctx context.Context, groupID, userID string, maxUses int, expiresIn time.Duration) (*models.GroupInviteLink, error)
+	GetInviteLinks(ctx context.Context, groupID, userID string) ([]*models.GroupInviteLink, error)
+	RevokeInviteLink(ctx context.Context, groupID, userID, linkID string) error
+	JoinWithInviteLink(ctx context.Context, token, userID string) (*models.Group, error)
+	GetJoinQuestions(ctx context.Context, groupID string) ([]*models.GroupJoinQuestion, error)
+	AddJoinQuestion(ctx context.Context, groupID, userID, question string, required bool) (*models.GroupJoinQuestion, error)
+	DeleteJoinQuestion(ctx context.Context, groupID, userID, questionID string) error
+	UpdateAutoApproveRules(ctx context.Context, groupID, userID string, rules []string) error
+
+	// Group role operations
+	GetGroupRoles(ctx context.Context, groupID, userID string) ([]*Role, error)
+	CreateGroupRole(ctx context.Context, groupID, userID, name string, permissions []string) (*Role, error)
+	UpdateGroupRole(ctx context.Context, groupID, userID, name string, permissions []string) (*Role, error)
+	DeleteGroupRole(ctx context.Context, groupID, userID, name string) error
 
 	// Group posts operations
-	CreateGroupPost(groupID, userID, content string, image, video *multipart.FileHeader) (*models.GroupPost, error)
-	GetGroupPosts(groupID, userID string, limit, offset int) ([]*models.GroupPost, error)
-	DeleteGroupPost(postID int64, userID string) error
+	CreateGroupPost(ctx context.Context, groupID, userID, content string, image, video *multipart.FileHeader) (*models.GroupPost, error)
+	GetGroupPosts(ctx context.Context, groupID, userID string, limit, offset int) ([]*models.GroupPost, error)
+	UpdateGroupPost(ctx context.Context, postID int64, userID, content string, image, video *multipart.FileHeader) (*models.GroupPost, error)
+	GetGroupPostRevisions(ctx context.Context, postID int64, userID string) ([]*models.PostRevision, error)
+	DeleteGroupPost(ctx context.Context, postID int64, userID string) error
+	PinGroupPost(ctx context.Context, postID int64, userID string, pinned bool) error
+
+	// Moderation operations
+	UpdateModerationSettings(ctx context.Context, groupID, userID string, postApproval bool, newMemberPosts int) error
+	GetModerationQueue(ctx context.Context, groupID, userID string, limit, offset int) ([]*models.GroupPost, error)
+	ReviewGroupPost(ctx context.Context, postID int64, userID string, approve bool, reason string) error
+	BanMember(ctx context.Context, groupID, actorID, userID, reason string, duration time.Duration) (*models.GroupBan, error)
+	UnbanMember(ctx context.Context, groupID, actorID, userID string) error
+	GetBans(ctx context.Context, groupID, userID string) ([]*models.GroupBan, error)
+	MuteMember(ctx context.Context, groupID, actorID, userID, reason string, duration time.Duration) (*models.GroupMute, error)
+	UnmuteMember(ctx context.Context, groupID, actorID, userID string) error
+	GetMutes(ctx context.Context, groupID, userID string) ([]*models.GroupMute, error)
+	GetAuditLog(ctx context.Context, groupID, userID string, limit, offset int) ([]*models.GroupAuditLog, error)
 
 	// Group chat operations
-	SendChatMessage(groupID, userID, content string) (*models.GroupChatMessage, error)
-	GetGroupChatMessages(groupID, userID string, limit, offset int) ([]*models.GroupChatMessage, error)
+	SendChatMessage(ctx context.Context, groupID, userID, content string) (*models.GroupChatMessage, error)
+	GetGroupChatMessages(ctx context.Context, groupID, userID string, limit, offset int) ([]*models.GroupChatMessage, error)
 }
 
 // GroupService implements the Service interface
 type GroupService struct {
 	repo          Repository
+	work          *uow.UnitOfWork
 	fileStore     *filestore.FileStore
 	log           *logger.Logger
 	wsHub         *websocket.Hub
 	notifications *Notifications
+	auth          *Authorizer
 }
 
 // NewService creates a new group service
-func NewService(repo Repository, fileStore *filestore.FileStore, log *logger.Logger, wsHub *websocket.Hub, notificationRepo notifications.Service) *GroupService {
+func NewService(repo Repository, work *uow.UnitOfWork, fileStore *filestore.FileStore, log *logger.Logger, wsHub *websocket.Hub, notificationRepo notifications.Service) *GroupService {
 	notifications := NewNotifications(repo, wsHub, log, notificationRepo)
 
 	return &GroupService{
 		repo:          repo,
+		work:          work,
 		fileStore:     fileStore,
 		log:           log,
 		wsHub:         wsHub,
 		notifications: notifications,
+		auth:          NewAuthorizer(repo),
 	}
 }
 
 // CreateGroup creates a new group
-func (s *GroupService) CreateGroup(userID, name, description string, isPublic bool, banner, profilePic *multipart.FileHeader) (*models.Group, error) {
+func (s *GroupService) CreateGroup(ctx context.Context, userID, name, description string, isPublic bool, banner, profilePic *multipart.FileHeader) (*models.Group, error) {
 	if name == "" {
 		return nil, errors.New("group name is required")
 	}
@@ -123,7 +164,7 @@ func (s *GroupService) CreateGroup(userID, name, description string, isPublic bo
 }
 
 // GetGroup gets a group by ID
-func (s *GroupService) GetGroup(id, userID string) (*models.Group, error) {
+func (s *GroupService) GetGroup(ctx context.Context, id, userID string) (*models.Group, error) {
 	group, err := s.repo.GetGroupByID(id)
 	if err != nil {
 		return nil, err
@@ -152,16 +193,26 @@ func (s *GroupService) GetGroup(id, userID string) (*models.Group, error) {
 		}
 	}
 
+	// Tell the client what the viewer may do, so it only offers allowed actions
+	perms, err := s.auth.Permissions(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	group.Permissions = []string{}
+	for _, p := range perms.List() {
+		group.Permissions = append(group.Permissions, string(p))
+	}
+
 	return group, nil
 }
 
 // GetUserGroups gets all groups a user is a member of
-func (s *GroupService) GetUserGroups(userID, viewerID string) ([]*models.Group, error) {
+func (s *GroupService) GetUserGroups(ctx context.Context, userID, viewerID string) ([]*models.Group, error) {
 	return s.repo.GetUserGroups(userID, viewerID)
 }
 
 // GetAllGroups gets all public groups and private groups the user is a member of
-func (s *GroupService) GetAllGroups(userID string, limit, offset int) ([]*models.Group, error) {
+func (s *GroupService) GetAllGroups(ctx context.Context, userID string, limit, offset int) ([]*models.Group, error) {
 	group, err := s.repo.GetAllGroups(userID, limit, offset)
 	if err != nil {
 		return nil, err
@@ -170,17 +221,11 @@ func (s *GroupService) GetAllGroups(userID string, limit, offset int) ([]*models
 }
 
 // UpdateGroup updates a group's information
-func (s *GroupService) UpdateGroup(id, userID, name, description string, isPublic bool, banner, profilePic *multipart.FileHeader) (*models.Group, error) {
-	// Check if user is admin or creator
-	role, err := s.repo.GetMemberRole(id, userID)
-	if err != nil {
+func (s *GroupService) UpdateGroup(ctx context.Context, id, userID, name, description string, isPublic bool, banner, profilePic *multipart.FileHeader) (*models.Group, error) {
+	if err := s.auth.Require(id, userID, PermEditSettings, "updating the group"); err != nil {
 		return nil, err
 	}
 
-	if role != "admin" {
-		return nil, errors.New("only group admins can update the group")
-	}
-
 	// Get current group
 	group, err := s.repo.GetGroupByID(id)
 	if err != nil {
@@ -225,7 +270,7 @@ func (s *GroupService) UpdateGroup(id, userID, name, description string, isPubli
 }
 
 // DeleteGroup deletes a group
-func (s *GroupService) DeleteGroup(id, userID string) error {
+func (s *GroupService) DeleteGroup(ctx context.Context, id, userID string) error {
 	// Check if user is the creator
 	group, err := s.repo.GetGroupByID(id)
 	if err != nil {
@@ -247,16 +292,27 @@ func (s *GroupService) DeleteGroup(id, userID string) error {
 	return nil
 }
 
+// ErrBlocked is returned when the inviter and the invitee blocked one another
+var ErrBlocked = errors.New("you cannot invite this user")
+
 // InviteToGroup invites a user to a group
-func (s *GroupService) InviteToGroup(groupID, inviterID, inviteeID string) error {
-	// Check if inviter is a member
-	isMember, err := s.repo.IsGroupMember(groupID, inviterID)
-	if err != nil {
+func (s *GroupService) InviteToGroup(ctx context.Context, groupID, inviterID, inviteeID string) error {
+	if err := s.auth.Require(groupID, inviterID, PermInvite, "inviting users"); err != nil {
 		return err
 	}
 
-	if !isMember {
-		return errors.New("only group members can invite others")
+	// Banned users cannot be invited back
+	if err := s.checkNotBanned(groupID, inviteeID); err != nil {
+		return err
+	}
+
+	// Users who blocked one another cannot invite each other
+	blocked, err := s.repo.IsBlocked(inviterID, inviteeID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
 	}
 
 	// Check if invitee is already a member or has a pending invitation
@@ -303,37 +359,53 @@ func (s *GroupService) InviteToGroup(groupID, inviterID, inviteeID string) error
 		return err
 	}
 	// Notify invitee
-	s.notifications.NotifyGroupInvitation(group, inviterID, inviteeID, newNotification, inviterInfo)
+	s.notifications.NotifyGroupInvitation(ctx, group, inviterID, inviteeID, newNotification, inviterInfo)
 
 	return nil
 }
 
-// JoinGroup sends a request to join a group
-func (s *GroupService) JoinGroup(groupID, userID string) error {
+// JoinGroup sends a request to join a group with answers to its join questions, keyed by
+// question ID. The request is accepted at once when an auto-approve rule matches.
+func (s *GroupService) JoinGroup(ctx context.Context, groupID, userID string, answers map[string]string) (*models.GroupMember, error) {
+	if err := s.checkNotBanned(groupID, userID); err != nil {
+		return nil, err
+	}
+
 	// Check if user is already a member or has a pending request
 	existingMember, err := s.repo.GetMemberByID(groupID, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if existingMember != nil {
 		if existingMember.Status == "accepted" {
-			return errors.New("you are already a member of this group")
+			return nil, errors.New("you are already a member of this group")
 		} else if existingMember.Status == "pending" && existingMember.InvitedBy == "" {
-			return errors.New("you already have a pending join request")
+			return nil, errors.New("you already have a pending join request")
 		} else if existingMember.Status == "pending" && existingMember.InvitedBy != "" {
-			return errors.New("you have a pending invitation to this group")
+			return nil, errors.New("you have a pending invitation to this group")
 		}
 	}
 
-	// Get group to check if it's public
 	group, err := s.repo.GetGroupByID(groupID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	joinAnswers, err := s.joinAnswers(groupID, userID, answers)
+	if err != nil {
+		return nil, err
+	}
+
+	approved, err := s.autoApproves(group, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	// If group is public, automatically accept
 	status := "pending"
+	if approved {
+		status = "accepted"
+	}
 
 	// Create member
 	member := &models.GroupMember{
@@ -343,8 +415,23 @@ func (s *GroupService) JoinGroup(groupID, userID string) error {
 		Status:  status,
 	}
 
-	if err := s.repo.AddMember(member); err != nil {
-		return err
+	// The request and its answers are kept together
+	err = s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.AddMember(member); err != nil {
+			return err
+		}
+
+		return repo.SaveJoinAnswers(joinAnswers)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if approved {
+		s.notifications.NotifyGroupJoined(group, userID, "auto_approved")
+		return member, nil
 	}
 
 	newNotification := &notifications.NewNotification{
@@ -356,16 +443,16 @@ func (s *GroupService) JoinGroup(groupID, userID string) error {
 	}
 	requesterInfo, err := s.repo.GetUserBasicByID(userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	s.notifications.NotifyGroupJoinRequest(group, userID, group.CreatorID, newNotification, requesterInfo)
+	s.notifications.NotifyGroupJoinRequest(ctx, group, userID, group.CreatorID, newNotification, requesterInfo)
 
-	return nil
+	return member, nil
 }
 
 // LeaveGroup allows a user to leave a group
-func (s *GroupService) LeaveGroup(groupID, userID string) error {
+func (s *GroupService) LeaveGroup(ctx context.Context, groupID, userID string) error {
 	// Check if user is a member
 	isMember, err := s.repo.IsGroupMember(groupID, userID)
 	if err != nil {
@@ -398,7 +485,7 @@ func (s *GroupService) LeaveGroup(groupID, userID string) error {
 }
 
 // AcceptInvitation accepts a group invitation
-func (s *GroupService) AcceptInvitation(groupID, userID string) error {
+func (s *GroupService) AcceptInvitation(ctx context.Context, groupID, userID string) error {
 	// Check if invitation exists
 	member, err := s.repo.GetMemberByID(groupID, userID)
 	if err != nil {
@@ -431,7 +518,7 @@ func (s *GroupService) AcceptInvitation(groupID, userID string) error {
 }
 
 // RejectInvitation rejects a group invitation
-func (s *GroupService) RejectInvitation(groupID, userID string) error {
+func (s *GroupService) RejectInvitation(ctx context.Context, groupID, userID string) error {
 	// Check if invitation exists
 	member, err := s.repo.GetMemberByID(groupID, userID)
 	if err != nil {
@@ -464,61 +551,55 @@ func (s *GroupService) RejectInvitation(groupID, userID string) error {
 }
 
 // AcceptJoinRequest accepts a request to join a group
-func (s *GroupService) AcceptJoinRequest(groupID, adminID, userID string) error {
-	// Check if admin is an admin
-	role, err := s.repo.GetMemberRole(groupID, adminID)
-	if err != nil {
-		return err
-	}
+func (s *GroupService) AcceptJoinRequest(ctx context.Context, groupID, adminID, userID string) error {
+	// The membership and the user's group count change together
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
 
-	if role != "admin" {
-		return errors.New("only group admins can accept join requests")
-	}
+		if err := NewAuthorizer(repo).Require(groupID, adminID, PermApproveJoins, "accepting join requests"); err != nil {
+			return err
+		}
 
-	// Check if join request exists
-	member, err := s.repo.GetMemberByID(groupID, userID)
-	if err != nil {
-		return err
-	}
+		// Check if join request exists
+		member, err := repo.GetMemberByID(groupID, userID)
+		if err != nil {
+			return err
+		}
 
-	if member == nil {
-		return errors.New("join request not found")
-	}
+		if member == nil {
+			return errors.New("join request not found")
+		}
 
-	fmt.Println(member.Status, member.InvitedBy)
-	if member.Status != "pending" || member.InvitedBy != "" {
-		return errors.New("no pending join request found")
-	}
+		if member.Status != "pending" || member.InvitedBy != "" {
+			return errors.New("no pending join request found")
+		}
 
-	// Update status to accepted
-	if err := s.repo.UpdateMemberStatus(groupID, userID, "accepted"); err != nil {
-		return err
-	}
+		// Update status to accepted
+		if err := repo.UpdateMemberStatus(groupID, userID, "accepted"); err != nil {
+			return err
+		}
 
-	// Get group info for notification
-	group, err := s.repo.GetGroupByID(groupID)
-	if err != nil {
-		return err
-	}
+		// Get group info for notification
+		group, err := repo.GetGroupByID(groupID)
+		if err != nil {
+			return err
+		}
 
-	// Notify about join request acceptance
-	s.notifyGroupJoinRequestAccepted(group, userID, adminID)
+		// Notify about join request acceptance, members must not hear of it if it rolls back
+		tx.AfterCommit(func() {
+			s.notifyGroupJoinRequestAccepted(group, userID, adminID)
+		})
 
-	return nil
+		return nil
+	})
 }
 
 // RejectJoinRequest rejects a request to join a group
-func (s *GroupService) RejectJoinRequest(groupID, adminID, userID string) error {
-	// Check if admin is an admin
-	role, err := s.repo.GetMemberRole(groupID, adminID)
-	if err != nil {
+func (s *GroupService) RejectJoinRequest(ctx context.Context, groupID, adminID, userID string) error {
+	if err := s.auth.Require(groupID, adminID, PermApproveJoins, "rejecting join requests"); err != nil {
 		return err
 	}
 
-	if role != "admin" {
-		return errors.New("only group admins can reject join requests")
-	}
-
 	// Check if join request exists
 	member, err := s.repo.GetMemberByID(groupID, userID)
 	if err != nil {
@@ -550,113 +631,205 @@ func (s *GroupService) RejectJoinRequest(groupID, adminID, userID string) error
 	return nil
 }
 
-// UpdateMemberRole updates a member's role
-func (s *GroupService) UpdateMemberRole(groupID, adminID, userID, role string) error {
-	// Check if admin is an admin
-	adminRole, err := s.repo.GetMemberRole(groupID, adminID)
+// UpdateMemberRole gives a member a built-in or custom role. Members cannot hand out or
+// take away permissions they do not hold themselves.
+func (s *GroupService) UpdateMemberRole(ctx context.Context, groupID, adminID, userID, role string) error {
+	actorPerms, err := s.auth.Permissions(groupID, adminID)
 	if err != nil {
 		return err
 	}
 
-	if adminRole != "admin" {
-		return errors.New("only group admins can update member roles")
+	if !actorPerms.Has(PermManageRoles) {
+		return fmt.Errorf("%w: updating member roles requires the %s permission", ErrPermissionDenied, PermManageRoles)
 	}
 
 	// Check if user is a member
-	isMember, err := s.repo.IsGroupMember(groupID, userID)
+	target, err := s.repo.GetMemberAccess(groupID, userID)
 	if err != nil {
 		return err
 	}
 
-	if !isMember {
+	if target == nil {
 		return errors.New("user is not a member of this group")
 	}
 
-	// Get group to check if user is the creator
-	group, err := s.repo.GetGroupByID(groupID)
-	if err != nil {
-		return err
+	if target.IsOwner {
+		return errors.New("cannot change the role of the group owner")
 	}
 
-	if group.CreatorID == userID {
-		return errors.New("cannot change the role of the group creator")
+	// Validate role
+	rolePerms, err := s.rolePermissions(s.repo, groupID, role)
+	if err != nil {
+		return err
 	}
 
-	// Validate role
-	if role != "admin" && role != "moderator" && role != "member" {
-		return errors.New("invalid role")
+	if !actorPerms.Covers(target.permissions()) || !actorPerms.Covers(rolePerms) {
+		return fmt.Errorf("%w: cannot change the role of a member to or from a role with permissions you do not have", ErrPermissionDenied)
 	}
 
-	// Update role
-	if err := s.repo.UpdateMemberRole(groupID, userID, role); err != nil {
+	// Get group for the notification
+	group, err := s.repo.GetGroupByID(groupID)
+	if err != nil {
 		return err
 	}
 
-	// Notify about role update
-	s.notifyGroupMemberRoleUpdated(group, userID, role, adminID)
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
 
-	return nil
+		// Update role
+		if err := repo.UpdateMemberRole(groupID, userID, role); err != nil {
+			return err
+		}
+
+		if err := audit(repo, groupID, adminID, AuditRoleAssigned, auditEntry{userID: userID, details: role}); err != nil {
+			return err
+		}
+
+		// Notify about role update
+		tx.AfterCommit(func() {
+			s.notifyGroupMemberRoleUpdated(group, userID, role, adminID)
+		})
+
+		return nil
+	})
 }
 
 // RemoveMember removes a member from a group
-func (s *GroupService) RemoveMember(groupID, adminID, userID string) error {
-	// Check if admin is an admin
-	adminRole, err := s.repo.GetMemberRole(groupID, adminID)
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, adminID, userID string) error {
+	actorPerms, err := s.auth.Permissions(groupID, adminID)
 	if err != nil {
 		return err
 	}
 
-	if adminRole != "admin" {
-		return errors.New("only group admins can remove members")
+	if !actorPerms.Has(PermManageRoles) {
+		return fmt.Errorf("%w: removing members requires the %s permission", ErrPermissionDenied, PermManageRoles)
 	}
 
 	// Check if user is a member
-	isMember, err := s.repo.IsGroupMember(groupID, userID)
+	target, err := s.repo.GetMemberAccess(groupID, userID)
 	if err != nil {
 		return err
 	}
 
-	if !isMember {
+	if target == nil {
 		return errors.New("user is not a member of this group")
 	}
 
-	// Get group to check if user is the creator
+	if target.IsOwner {
+		return errors.New("cannot remove the group owner")
+	}
+
+	if !actorPerms.Covers(target.permissions()) {
+		return fmt.Errorf("%w: cannot remove a member with permissions you do not have", ErrPermissionDenied)
+	}
+
+	// Get group for the notification
 	group, err := s.repo.GetGroupByID(groupID)
 	if err != nil {
 		return err
 	}
 
-	if group.CreatorID == userID {
-		return errors.New("cannot remove the group creator")
-	}
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
 
-	// Remove member
-	if err := s.repo.RemoveMember(groupID, userID); err != nil {
-		return err
+		// Remove member
+		if err := repo.RemoveMember(groupID, userID); err != nil {
+			return err
+		}
+
+		if err := audit(repo, groupID, adminID, AuditMemberRemoved, auditEntry{userID: userID}); err != nil {
+			return err
+		}
+
+		// Notify about member removal
+		tx.AfterCommit(func() {
+			s.notifyGroupMemberRemoved(group, userID, adminID)
+		})
+
+		return nil
+	})
+}
+
+// TransferOwnership makes another member the owner of a group. The new owner becomes an
+// admin, the previous owner keeps their role and loses only what ownership gave them.
+func (s *GroupService) TransferOwnership(ctx context.Context, groupID, ownerID, newOwnerID string) error {
+	if ownerID == newOwnerID {
+		return errors.New("you already own this group")
 	}
 
-	// Notify about member removal
-	s.notifyGroupMemberRemoved(group, userID, adminID)
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
 
-	return nil
+		group, err := repo.GetGroupByID(groupID)
+		if err != nil {
+			return err
+		}
+
+		if group.CreatorID != ownerID {
+			return fmt.Errorf("%w: only the group owner can transfer ownership", ErrPermissionDenied)
+		}
+
+		newOwner, err := repo.GetMemberAccess(groupID, newOwnerID)
+		if err != nil {
+			return err
+		}
+
+		if newOwner == nil {
+			return errors.New("the new owner must be a member of this group")
+		}
+
+		if err := repo.UpdateGroupOwner(groupID, newOwnerID); err != nil {
+			return err
+		}
+
+		if err := repo.UpdateMemberRole(groupID, newOwnerID, RoleAdmin); err != nil {
+			return err
+		}
+
+		if err := audit(repo, groupID, ownerID, AuditOwnershipTransferred, auditEntry{userID: newOwnerID}); err != nil {
+			return err
+		}
+
+		group.CreatorID = newOwnerID
+		tx.AfterCommit(func() {
+			s.notifications.NotifyGroupOwnershipTransferred(group, ownerID, newOwnerID)
+		})
+
+		return nil
+	})
 }
 
-// GetGroupMembers gets all members of a group
-func (s *GroupService) GetGroupMembers(groupID, userID string, status string) ([]*models.GroupMember, error) {
-	// Check if user is a member or admin
-	isMember, err := s.repo.IsGroupMember(groupID, userID)
+// rolePermissions returns the permissions of a built-in or custom role of a group
+func (s *GroupService) rolePermissions(repo Repository, groupID, name string) (PermissionSet, error) {
+	if isBuiltinRole(name) {
+		return newPermissionSet(builtinRoles[name]), nil
+	}
+
+	role, err := repo.GetGroupRole(groupID, name)
 	if err != nil {
 		return nil, err
 	}
 
-	role, err := s.repo.GetMemberRole(groupID, userID)
+	if role == nil {
+		return nil, errors.New("invalid role")
+	}
+
+	return decodePermissions(role.Permissions), nil
+}
+
+// GetGroupMembers gets all members of a group
+func (s *GroupService) GetGroupMembers(ctx context.Context, groupID, userID string, status string) ([]*models.GroupMember, error) {
+	// Check if user is a member or admin
+	isMember, err := s.repo.IsGroupMember(groupID, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Only admins can see pending members
-	if status == "pending" && role != "admin" {
-		return nil, errors.New("only group admins can view pending members")
+	// Only members who approve joins can see pending members
+	if status == "pending" {
+		if err := s.auth.Require(groupID, userID, PermApproveJoins, "viewing pending members"); err != nil {
+			return nil, err
+		}
 	}
 
 	// Non-members can't view member list
@@ -670,28 +843,47 @@ func (s *GroupService) GetGroupMembers(groupID, userID string, status string) ([
 		return nil, err
 	}
 
+	// Pending join requests come with the answers to the join questions
+	if status == "pending" {
+		if err := s.attachJoinAnswers(groupID, members); err != nil {
+			return nil, err
+		}
+	}
+
 	return members, nil
 }
 
 // CreateGroupPost creates a new post in a group
-func (s *GroupService) CreateGroupPost(groupID, userID, content string, image, video *multipart.FileHeader) (*models.GroupPost, error) {
-	// Check if user is a member
-	isMember, err := s.repo.IsGroupMember(groupID, userID)
+func (s *GroupService) CreateGroupPost(ctx context.Context, groupID, userID, content string, image, video *multipart.FileHeader) (*models.GroupPost, error) {
+	if err := s.auth.Require(groupID, userID, PermPost, "posting"); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkNotMuted(groupID, userID); err != nil {
+		return nil, err
+	}
+
+	group, err := s.repo.GetGroupByID(groupID)
 	if err != nil {
 		return nil, err
 	}
 
-	if !isMember {
-		return nil, errors.New("only group members can create posts")
+	pending, err := s.needsApproval(group, userID)
+	if err != nil {
+		return nil, err
 	}
 
 	post := &models.GroupPost{
 		GroupID:   groupID,
 		UserID:    userID,
 		Content:   content,
+		Status:    models.GroupPostApproved,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	if pending {
+		post.Status = models.GroupPostPending
+	}
 
 	// Handle image upload if provided
 	if image != nil {
@@ -730,14 +922,17 @@ func (s *GroupService) CreateGroupPost(groupID, userID, content string, image, v
 		Avatar:    user.Avatar,
 	}
 
-	// Notify about post creation
-	s.notifyGroupPostCreated(post)
+	// Pending posts are announced when a moderator approves them
+	if !pending {
+		s.notifyGroupPostCreated(post)
+		go s.notifyGroupPostMentions(ctx, post, "")
+	}
 
 	return post, nil
 }
 
 // GetGroupPosts gets all posts in a group
-func (s *GroupService) GetGroupPosts(groupID, userID string, limit, offset int) ([]*models.GroupPost, error) {
+func (s *GroupService) GetGroupPosts(ctx context.Context, groupID, userID string, limit, offset int) ([]*models.GroupPost, error) {
 	// Check if user is a member
 	isMember, err := s.repo.IsGroupMember(groupID, userID)
 	if err != nil {
@@ -771,8 +966,119 @@ func (s *GroupService) GetGroupPosts(groupID, userID string, limit, offset int)
 	return posts, nil
 }
 
+// UpdateGroupPost edits a group post, keeping its previous version in the revision history
+func (s *GroupService) UpdateGroupPost(ctx context.Context, postID int64, userID, content string, image, video *multipart.FileHeader) (*models.GroupPost, error) {
+	post, err := s.repo.GetGroupPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.UserID != userID {
+		return nil, errors.New("only the post creator can edit this post")
+	}
+
+	// Check if user is still a member
+	isMember, err := s.repo.IsGroupMember(post.GroupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isMember {
+		return nil, errors.New("only group members can edit posts")
+	}
+
+	// Snapshot the current version before changing anything
+	previousContent := post.Content
+	revision := &models.PostRevision{
+		TargetType: models.RevisionTargetGroupPost,
+		TargetID:   post.ID,
+		EditorID:   userID,
+		Content:    post.Content,
+		ImagePath:  post.ImagePath,
+		VideoPath:  post.VideoPath,
+	}
+
+	post.Content = content
+
+	// Handle image upload if provided, old files are kept for the revision history
+	if image != nil {
+		imagePath, err := s.fileStore.SaveFile(image, "group_post_images")
+		if err != nil {
+			return nil, fmt.Errorf("failed to save image: %w", err)
+		}
+		post.ImagePath.String = imagePath
+		post.ImagePath.Valid = true
+	}
+
+	// Handle video upload if provided
+	if video != nil {
+		videoPath, err := s.fileStore.SaveFile(video, "group_post_videos")
+		if err != nil {
+			return nil, fmt.Errorf("failed to save video: %w", err)
+		}
+		post.VideoPath.String = videoPath
+		post.VideoPath.Valid = true
+	}
+
+	if err := s.repo.UpdateGroupPost(post, revision); err != nil {
+		return nil, err
+	}
+
+	// Notify about post update, pending posts are only seen by their author so far
+	if post.Status == models.GroupPostApproved {
+		s.notifications.NotifyGroupPostUpdated(post)
+		go s.notifyGroupPostMentions(ctx, post, previousContent)
+	}
+
+	return post, nil
+}
+
+// notifyGroupPostMentions notifies group members @mentioned in a post.
+// Members already mentioned in previousContent were notified before and are skipped.
+func (s *GroupService) notifyGroupPostMentions(ctx context.Context, post *models.GroupPost, previousContent string) {
+	memberIDs, err := s.repo.GetMemberIDsByNicknames(post.GroupID, utils.ParseMentions(post.Content))
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to resolve group post mentions: %v", err)
+		return
+	}
+
+	previousIDs, err := s.repo.GetMemberIDsByNicknames(post.GroupID, utils.ParseMentions(previousContent))
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to resolve previous group post mentions: %v", err)
+		return
+	}
+	alreadyMentioned := make(map[string]bool)
+	for _, id := range previousIDs {
+		alreadyMentioned[id] = true
+	}
+
+	for _, id := range memberIDs {
+		if id == post.UserID || alreadyMentioned[id] {
+			continue
+		}
+		s.notifications.NotifyGroupPostMention(ctx, post, id)
+	}
+}
+
+// GetGroupPostRevisions gets the edit history of a group post for its author and members
+// who may delete it
+func (s *GroupService) GetGroupPostRevisions(ctx context.Context, postID int64, userID string) ([]*models.PostRevision, error) {
+	post, err := s.repo.GetGroupPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.UserID != userID {
+		if err := s.auth.Require(post.GroupID, userID, PermDeleteContent, "viewing the history of others' posts"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.GetGroupPostRevisions(postID)
+}
+
 // DeleteGroupPost deletes a post from a group
-func (s *GroupService) DeleteGroupPost(postID int64, userID string) error {
+func (s *GroupService) DeleteGroupPost(ctx context.Context, postID int64, userID string) error {
 	// Get the post
 	post, err := s.repo.GetGroupPostByID(postID)
 	if err != nil {
@@ -783,28 +1089,232 @@ func (s *GroupService) DeleteGroupPost(postID int64, userID string) error {
 		return errors.New("post not found")
 	}
 
-	// Check if user is the post creator or an admin
-	if post.UserID != userID {
-		role, err := s.repo.GetMemberRole(post.GroupID, userID)
-		if err != nil {
+	// Authors delete their own posts, others' posts are deleted by moderators and audited
+	if post.UserID == userID {
+		return s.repo.DeleteGroupPost(postID)
+	}
+
+	if err := s.auth.Require(post.GroupID, userID, PermDeleteContent, "deleting others' posts"); err != nil {
+		return err
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.DeleteGroupPost(postID); err != nil {
+			return err
+		}
+
+		return audit(repo, post.GroupID, userID, AuditPostDeleted, auditEntry{userID: post.UserID, postID: postID})
+	})
+}
+
+// PinGroupPost pins a post to the top of the group feed, or unpins it
+func (s *GroupService) PinGroupPost(ctx context.Context, postID int64, userID string, pinned bool) error {
+	post, err := s.repo.GetGroupPostByID(postID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.auth.Require(post.GroupID, userID, PermPin, "pinning posts"); err != nil {
+		return err
+	}
+
+	if post.IsPinned == pinned {
+		return nil
+	}
+
+	if post.Status != models.GroupPostApproved {
+		return errors.New("only approved posts can be pinned")
+	}
+
+	action := AuditPostUnpinned
+	if pinned {
+		action = AuditPostPinned
+	}
+
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.SetGroupPostPinned(postID, pinned); err != nil {
+			return err
+		}
+
+		if err := audit(repo, post.GroupID, userID, action, auditEntry{userID: post.UserID, postID: postID}); err != nil {
+			return err
+		}
+
+		post.IsPinned = pinned
+		tx.AfterCommit(func() {
+			s.notifications.NotifyGroupPostUpdated(post)
+		})
+
+		return nil
+	})
+}
+
+// maxRoleNameLength bounds custom role names so they fit on a member badge
+const maxRoleNameLength = 32
+
+// GetGroupRoles lists the built-in and custom roles of a group to its members
+func (s *GroupService) GetGroupRoles(ctx context.Context, groupID, userID string) ([]*Role, error) {
+	isMember, err := s.repo.IsGroupMember(groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isMember {
+		return nil, errors.New("only group members can view roles")
+	}
+
+	custom, err := s.repo.GetGroupRoles(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return rolesOf(custom), nil
+}
+
+// CreateGroupRole defines a custom role, with no more permissions than its creator has
+func (s *GroupService) CreateGroupRole(ctx context.Context, groupID, userID, name string, permissions []string) (*Role, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxRoleNameLength {
+		return nil, fmt.Errorf("role name must be between 1 and %d characters", maxRoleNameLength)
+	}
+
+	if isBuiltinRole(name) {
+		return nil, fmt.Errorf("%s is a built-in role", name)
+	}
+
+	perms, err := parsePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.requireRoleManager(groupID, userID, perms); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetGroupRole(groupID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, fmt.Errorf("role %s already exists", name)
+	}
+
+	role := &models.GroupRole{
+		GroupID:     groupID,
+		Name:        name,
+		Permissions: encodePermissions(perms),
+	}
+	err = s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.CreateGroupRole(role); err != nil {
 			return err
 		}
 
-		if role != "admin" && role != "moderator" {
-			return errors.New("only the post creator, moderators, or admins can delete posts")
+		return audit(repo, groupID, userID, AuditRoleCreated, auditEntry{details: name + ": " + role.Permissions})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Role{Name: name, Permissions: perms.List()}, nil
+}
+
+// UpdateGroupRole replaces the permissions of a custom role. Members holding it gain or
+// lose the permissions at once.
+func (s *GroupService) UpdateGroupRole(ctx context.Context, groupID, userID, name string, permissions []string) (*Role, error) {
+	perms, err := parsePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.repo.GetGroupRole(groupID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+
+	// Both what the role had and what it will have must be within the actor's reach
+	if err := s.requireRoleManager(groupID, userID, decodePermissions(role.Permissions), perms); err != nil {
+		return nil, err
+	}
+
+	err = s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.UpdateGroupRolePermissions(groupID, name, encodePermissions(perms)); err != nil {
+			return err
 		}
+
+		return audit(repo, groupID, userID, AuditRoleUpdated, auditEntry{details: name + ": " + encodePermissions(perms)})
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Delete the post
-	if err := s.repo.DeleteGroupPost(postID); err != nil {
+	return &Role{Name: name, Permissions: perms.List()}, nil
+}
+
+// DeleteGroupRole deletes a custom role, members who held it become plain members
+func (s *GroupService) DeleteGroupRole(ctx context.Context, groupID, userID, name string) error {
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		role, err := repo.GetGroupRole(groupID, name)
+		if err != nil {
+			return err
+		}
+
+		if role == nil {
+			return errors.New("role not found")
+		}
+
+		if err := s.requireRoleManager(groupID, userID, decodePermissions(role.Permissions)); err != nil {
+			return err
+		}
+
+		if err := repo.ReassignMemberRole(groupID, name, RoleMember); err != nil {
+			return err
+		}
+
+		if err := repo.DeleteGroupRole(groupID, name); err != nil {
+			return err
+		}
+
+		return audit(repo, groupID, userID, AuditRoleDeleted, auditEntry{details: name})
+	})
+}
+
+// requireRoleManager checks that userID may manage roles and holds every permission in sets
+func (s *GroupService) requireRoleManager(groupID, userID string, sets ...PermissionSet) error {
+	actorPerms, err := s.auth.Permissions(groupID, userID)
+	if err != nil {
 		return err
 	}
 
+	if !actorPerms.Has(PermManageRoles) {
+		return fmt.Errorf("%w: managing roles requires the %s permission", ErrPermissionDenied, PermManageRoles)
+	}
+
+	for _, set := range sets {
+		if !actorPerms.Covers(set) {
+			return fmt.Errorf("%w: a role cannot have permissions you do not have", ErrPermissionDenied)
+		}
+	}
+
 	return nil
 }
 
 // SendChatMessage sends a message to a group chat
-func (s *GroupService) SendChatMessage(groupID, userID, content string) (*models.GroupChatMessage, error) {
+func (s *GroupService) SendChatMessage(ctx context.Context, groupID, userID, content string) (*models.GroupChatMessage, error) {
 	// Check if user is a member
 	isMember, err := s.repo.IsGroupMember(groupID, userID)
 	if err != nil {
@@ -815,6 +1325,10 @@ func (s *GroupService) SendChatMessage(groupID, userID, content string) (*models
 		return nil, errors.New("only group members can send messages")
 	}
 
+	if err := s.checkNotMuted(groupID, userID); err != nil {
+		return nil, err
+	}
+
 	if content == "" {
 		return nil, errors.New("message content is required")
 	}
@@ -846,7 +1360,7 @@ func (s *GroupService) SendChatMessage(groupID, userID, content string) (*models
 }
 
 // GetGroupChatMessages gets messages from a group chat
-func (s *GroupService) GetGroupChatMessages(groupID, userID string, limit, offset int) ([]*models.GroupChatMessage, error) {
+func (s *GroupService) GetGroupChatMessages(ctx context.Context, groupID, userID string, limit, offset int) ([]*models.GroupChatMessage, error) {
 	// Check if user is a member
 	isMember, err := s.repo.IsGroupMember(groupID, userID)
 	if err != nil {
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// Database checks that db accepts connections and can read a table. A ping alone succeeds
+// on a pooled SQLite connection even when the database file is locked or unreadable.
+func Database(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("ping failed: %w", err)
+		}
+		var n int64
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&n); err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+		return nil
+	}
+}
+
+// WritableDir checks that files can be created in dir
+func WritableDir(dir string) Check {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".health-*")
+		if err != nil {
+			return fmt.Errorf("directory is not writable: %w", err)
+		}
+		name := f.Name()
+		_, err = f.Write([]byte("ok"))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		os.Remove(name)
+		if err != nil {
+			return fmt.Errorf("directory is not writable: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/httputil"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+// checkTimeout bounds each check so a hung dependency fails the probe instead of hanging it
+const checkTimeout = 2 * time.Second
+
+// Check reports why a dependency is unusable, or nil when it is fine
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Report is the body of a probe response
+type Report struct {
+	Status string            `json:"status"` // ok or unavailable
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Handler serves the liveness and readiness probes. Checks are added while the server
+// is set up, before it serves requests.
+type Handler struct {
+	liveness  []namedCheck
+	readiness []namedCheck
+	ready     atomic.Bool
+	log       *logger.Logger
+}
+
+// NewHandler creates a handler that reports ready until SetReady(false) is called
+func NewHandler(log *logger.Logger) *Handler {
+	h := &Handler{log: log}
+	h.ready.Store(true)
+	return h
+}
+
+// AddLivenessCheck adds a check whose failure means the process needs a restart, such as
+// a goroutine that stopped. Liveness checks are run by the readiness probe too.
+func (h *Handler) AddLivenessCheck(name string, check Check) {
+	h.liveness = append(h.liveness, namedCheck{name, check})
+}
+
+// AddReadinessCheck adds a check whose failure means the process should not get traffic
+// for now, such as a database that is locked or unreachable
+func (h *Handler) AddReadinessCheck(name string, check Check) {
+	h.readiness = append(h.readiness, namedCheck{name, check})
+}
+
+// SetReady sets whether the server accepts traffic, it is cleared on shutdown so load
+// balancers stop sending requests before connections are closed
+func (h *Handler) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Livez handles GET /livez
+func (h *Handler) Livez(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, h.liveness, false)
+}
+
+// Readyz handles GET /readyz
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := append(append([]namedCheck{}, h.liveness...), h.readiness...)
+	h.serve(w, r, checks, true)
+}
+
+// serve runs checks concurrently and responds 200 when all pass, 503 otherwise
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, checks []namedCheck, readiness bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		httputil.SendError(w, http.StatusMethodNotAllowed, "Method not allowed", true)
+		return
+	}
+
+	report := Report{Status: "ok", Checks: make(map[string]string, len(checks)+1)}
+	if readiness && !h.ready.Load() {
+		report.Status = "unavailable"
+		report.Checks["shutdown"] = "server is shutting down"
+	}
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+			defer cancel()
+			results[i] = c.check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		if err := results[i]; err != nil {
+			report.Status = "unavailable"
+			report.Checks[c.name] = err.Error()
+			h.log.WithContext(r.Context()).Warn("Health check %s failed: %v", c.name, err)
+			continue
+		}
+		report.Checks[c.name] = "ok"
+	}
+
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	httputil.SendJSON(w, status, report)
+}
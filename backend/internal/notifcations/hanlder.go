@@ -35,6 +35,7 @@ type NotificationResponse struct {
 	CreatedAt     string `json:"createdAt"`
 	TargetGroupID string `json:"targetGroupId,omitempty"`
 	TargetEventID string `json:"targetEventId,omitempty"`
+	TargetPostID  int64  `json:"targetPostId,omitempty"`
 	SenderName    string `json:"senderName,omitempty"`
 	SenderAvatar  string `json:"senderAvatar,omitempty"`
 }
@@ -69,7 +70,7 @@ func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get notifications
-	notifications, err := h.service.GetNotifications(userID, limit, offset)
+	notifications, err := h.service.GetNotifications(r.Context(), userID, limit, offset)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -87,6 +88,7 @@ func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
 			CreatedAt:     notification.CreatedAt.Format(time.RFC3339),
 			TargetGroupID: notification.TargetGroupID.String,
 			TargetEventID: notification.TargetEventID.String,
+			TargetPostID:  notification.TargetPostID.Int64,
 			SenderName:    notification.SenderName,
 			SenderAvatar:  notification.SenderAvatar,
 		}
@@ -126,7 +128,7 @@ func (h *Handler) MarkNotificationAsRead(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Mark notification as read
-	if err := h.service.MarkNotificationAsRead(notificationID); err != nil {
+	if err := h.service.MarkNotificationAsRead(r.Context(), notificationID); err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -145,7 +147,7 @@ func (h *Handler) MarkAllNotificationsAsRead(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Mark all notifications as read
-	if err := h.service.MarkAllNotificationsAsRead(userID); err != nil {
+	if err := h.service.MarkAllNotificationsAsRead(r.Context(), userID); err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -164,7 +166,7 @@ func (h *Handler) ClearAllNotifications(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Clear all notifications
-	if err := h.service.ClearAllNotifications(userID); err != nil {
+	if err := h.service.ClearAllNotifications(r.Context(), userID); err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -180,7 +182,7 @@ func (h *Handler) DeleteNotification(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteNotification(notificationID); err != nil {
+	if err := h.service.DeleteNotification(r.Context(), notificationID); err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -33,9 +33,9 @@ func (r *SQLiteRepository) CreateNotification(notification *models.Notification)
 
 	query := `
 		INSERT INTO notifications (
-			user_id, sender_id, type, message, is_read, created_at, target_group_id, target_event_id
+			user_id, sender_id, type, message, is_read, created_at, target_group_id, target_event_id, target_post_id
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := r.db.Exec(
@@ -48,6 +48,7 @@ func (r *SQLiteRepository) CreateNotification(notification *models.Notification)
 		notification.CreatedAt,
 		utils.NullableString(notification.TargetGroupID),
 		utils.NullableString(notification.TargetEventID),
+		utils.NullableInt64(notification.TargetPostID),
 	)
 	if err != nil {
 		return err
@@ -59,7 +60,7 @@ func (r *SQLiteRepository) CreateNotification(notification *models.Notification)
 func (r *SQLiteRepository) GetNotifications(userID string, limit, offset int) ([]*models.Notification, error) {
 	query := `
 		SELECT 
-			id, user_id, sender_id, type, message, is_read, created_at, target_group_id, target_event_id
+			id, user_id, sender_id, type, message, is_read, created_at, target_group_id, target_event_id, target_post_id
 		FROM notifications
 		WHERE user_id = ?
 		ORDER BY created_at DESC
@@ -86,6 +87,7 @@ func (r *SQLiteRepository) GetNotifications(userID string, limit, offset int) ([
 			&n.CreatedAt,
 			&n.TargetGroupID,
 			&n.TargetEventID,
+			&n.TargetPostID,
 		)
 		if err != nil {
 			return nil, err
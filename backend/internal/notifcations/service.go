@@ -1,11 +1,13 @@
 package notifications
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 
 	"github.com/Athooh/social-network/pkg/logger"
+	"github.com/Athooh/social-network/pkg/metrics"
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
 	"github.com/Athooh/social-network/pkg/user"
 	"github.com/Athooh/social-network/pkg/websocket"
@@ -13,12 +15,12 @@ import (
 
 // Service defines the notification service interface
 type Service interface {
-	CreateNotification(notification *NewNotification) error
-	GetNotifications(userID string, limit, offset int) ([]*NotificationWithUser, error)
-	MarkNotificationAsRead(notificationID int64) error
-	MarkAllNotificationsAsRead(userID string) error
-	ClearAllNotifications(userID string) error
-	DeleteNotification(notificationID int64) error
+	CreateNotification(ctx context.Context, notification *NewNotification) error
+	GetNotifications(ctx context.Context, userID string, limit, offset int) ([]*NotificationWithUser, error)
+	MarkNotificationAsRead(ctx context.Context, notificationID int64) error
+	MarkAllNotificationsAsRead(ctx context.Context, userID string) error
+	ClearAllNotifications(ctx context.Context, userID string) error
+	DeleteNotification(ctx context.Context, notificationID int64) error
 }
 
 // NotificationWithUser extends Notification with user information
@@ -43,6 +45,7 @@ type NewNotification struct {
 	Message         string
 	TargetGroupID   sql.NullString
 	TargetEventID   sql.NullString
+	TargetPostID    sql.NullInt64
 }
 
 // NewService creates a new notification service
@@ -55,12 +58,28 @@ func NewService(repo Repository, userRepo user.Repository, log *logger.Logger, w
 	}
 }
 
-// CreateNotification creates a new notification
-func (s *NotificationService) CreateNotification(notification *NewNotification) error {
+// ErrSenderBlocked is returned instead of creating a notification between users who
+// blocked one another
+var ErrSenderBlocked = errors.New("notification sender is blocked")
+
+// CreateNotification creates a new notification, unless its sender and recipient blocked
+// one another
+func (s *NotificationService) CreateNotification(ctx context.Context, notification *NewNotification) error {
 	if notification.UserId == "" {
 		return errors.New("user ID cannot be empty")
 	}
 
+	if notification.SenderId.Valid && notification.SenderId.String != notification.UserId {
+		blocked, err := s.userRepo.IsBlocked(notification.UserId, notification.SenderId.String)
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to check blocks for notification: %v", err)
+			return err
+		}
+		if blocked {
+			return ErrSenderBlocked
+		}
+	}
+
 	newNotification := &models.Notification{
 		UserID:  notification.UserId,
 		Type:    notification.NotficationType,
@@ -83,23 +102,29 @@ func (s *NotificationService) CreateNotification(notification *NewNotification)
 		newNotification.TargetEventID = notification.TargetEventID
 	}
 
+	// Handle nullable TargetPostID
+	if notification.TargetPostID.Valid {
+		newNotification.TargetPostID = notification.TargetPostID
+	}
+
 	if err := s.repo.CreateNotification(newNotification); err != nil {
-		s.log.Error("Failed to create notification: %v", err)
+		s.log.WithContext(ctx).Error("Failed to create notification: %v", err)
 		return err
 	}
+	metrics.NotificationsCreated.WithLabelValues(newNotification.Type).Inc()
 
 	return nil
 }
 
 // GetNotifications retrieves notifications for a user with sender information
-func (s *NotificationService) GetNotifications(userID string, limit, offset int) ([]*NotificationWithUser, error) {
+func (s *NotificationService) GetNotifications(ctx context.Context, userID string, limit, offset int) ([]*NotificationWithUser, error) {
 	if userID == "" {
 		return nil, errors.New("user ID cannot be empty")
 	}
 
 	notifications, err := s.repo.GetNotifications(userID, limit, offset)
 	if err != nil {
-		s.log.Error("Failed to get notifications: %v", err)
+		s.log.WithContext(ctx).Error("Failed to get notifications: %v", err)
 		return nil, err
 	}
 
@@ -111,7 +136,7 @@ func (s *NotificationService) GetNotifications(userID string, limit, offset int)
 		if notification.SenderID.Valid && *&notification.SenderID.String != "" {
 			sender, err := s.userRepo.GetByID(*&notification.SenderID.String)
 			if err != nil {
-				s.log.Warn("Failed to get sender info for notification %d: %v", notification.ID, err)
+				s.log.WithContext(ctx).Warn("Failed to get sender info for notification %d: %v", notification.ID, err)
 				continue
 			}
 			senderName = fmt.Sprintf("%s %s", sender.FirstName, sender.LastName)
@@ -131,13 +156,13 @@ func (s *NotificationService) GetNotifications(userID string, limit, offset int)
 }
 
 // MarkNotificationAsRead marks a single notification as read
-func (s *NotificationService) MarkNotificationAsRead(notificationID int64) error {
+func (s *NotificationService) MarkNotificationAsRead(ctx context.Context, notificationID int64) error {
 	if notificationID <= 0 {
 		return errors.New("invalid notification ID")
 	}
 
 	if err := s.repo.MarkNotificationAsRead(notificationID); err != nil {
-		s.log.Error("Failed to mark notification as read: %v", err)
+		s.log.WithContext(ctx).Error("Failed to mark notification as read: %v", err)
 		return err
 	}
 
@@ -145,13 +170,13 @@ func (s *NotificationService) MarkNotificationAsRead(notificationID int64) error
 }
 
 // MarkAllNotificationsAsRead marks all notifications for a user as read
-func (s *NotificationService) MarkAllNotificationsAsRead(userID string) error {
+func (s *NotificationService) MarkAllNotificationsAsRead(ctx context.Context, userID string) error {
 	if userID == "" {
 		return errors.New("user ID cannot be empty")
 	}
 
 	if err := s.repo.MarkAllNotificationsAsRead(userID); err != nil {
-		s.log.Error("Failed to mark all notifications as read: %v", err)
+		s.log.WithContext(ctx).Error("Failed to mark all notifications as read: %v", err)
 		return err
 	}
 
@@ -159,26 +184,26 @@ func (s *NotificationService) MarkAllNotificationsAsRead(userID string) error {
 }
 
 // ClearAllNotifications removes all notifications for a user
-func (s *NotificationService) ClearAllNotifications(userID string) error {
+func (s *NotificationService) ClearAllNotifications(ctx context.Context, userID string) error {
 	if userID == "" {
 		return errors.New("user ID cannot be empty")
 	}
 
 	if err := s.repo.ClearAllNotificationsDB(userID); err != nil {
-		s.log.Error("Failed to clear all notifications: %v", err)
+		s.log.WithContext(ctx).Error("Failed to clear all notifications: %v", err)
 		return err
 	}
 
 	return nil
 }
 
-func (s *NotificationService) DeleteNotification(notificationID int64) error {
+func (s *NotificationService) DeleteNotification(ctx context.Context, notificationID int64) error {
 	if notificationID <= 0 {
 		return errors.New("invalid notification ID")
 	}
 
 	if err := s.repo.DeleteNotificationDb(notificationID); err != nil {
-		s.log.Error("Failed to delete notification: %v", err)
+		s.log.WithContext(ctx).Error("Failed to delete notification: %v", err)
 		return err
 	}
 	return nil
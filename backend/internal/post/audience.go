@@ -0,0 +1,195 @@
+package post
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Athooh/social-network/pkg/db/uow"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// maxAudienceListName is the longest name an audience list can have
+const maxAudienceListName = 50
+
+// ErrAudienceListNotFound is returned for audience lists that don't exist or belong to
+// another user
+var ErrAudienceListNotFound = errors.New("audience list not found")
+
+// CreateAudienceList creates a named audience list with its first members
+func (s *PostService) CreateAudienceList(ctx context.Context, userID, name string, memberIDs []string) (*models.AudienceList, error) {
+	name, err := s.audienceListName(userID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.AudienceList{
+		OwnerID: userID,
+		Name:    name,
+	}
+
+	err = s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.CreateAudienceList(list); err != nil {
+			s.log.WithContext(ctx).Error("Failed to create audience list: %v", err)
+			return err
+		}
+
+		return s.addAudienceListMembers(ctx, repo, list, memberIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetAudienceLists retrieves the audience lists of a user
+func (s *PostService) GetAudienceLists(ctx context.Context, userID string) ([]*models.AudienceList, error) {
+	lists, err := s.repo.GetAudienceLists(userID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get audience lists: %v", err)
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+// RenameAudienceList renames one of the user's audience lists
+func (s *PostService) RenameAudienceList(ctx context.Context, userID string, listID int64, name string) error {
+	list, err := s.ownAudienceList(s.repo, userID, listID)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(name) == list.Name {
+		return nil
+	}
+
+	name, err = s.audienceListName(userID, name)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.RenameAudienceList(list.ID, name)
+}
+
+// DeleteAudienceList deletes one of the user's audience lists. Its members lose access
+// to the posts shared with it unless they were also picked as viewers.
+func (s *PostService) DeleteAudienceList(ctx context.Context, userID string, listID int64) error {
+	list, err := s.ownAudienceList(s.repo, userID, listID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteAudienceList(list.ID); err != nil {
+		s.log.WithContext(ctx).Error("Failed to delete audience list: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddAudienceListMembers adds users to one of the user's audience lists. They can see
+// the posts already shared with the list straight away.
+func (s *PostService) AddAudienceListMembers(ctx context.Context, userID string, listID int64, memberIDs []string) error {
+	return s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
+
+		list, err := s.ownAudienceList(repo, userID, listID)
+		if err != nil {
+			return err
+		}
+
+		return s.addAudienceListMembers(ctx, repo, list, memberIDs)
+	})
+}
+
+// RemoveAudienceListMember removes a user from one of the user's audience lists, along
+// with their access to the posts shared with it
+func (s *PostService) RemoveAudienceListMember(ctx context.Context, userID string, listID int64, memberID string) error {
+	list, err := s.ownAudienceList(s.repo, userID, listID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.RemoveAudienceListMember(list.ID, memberID)
+}
+
+// addAudienceListMembers adds existing users other than the list owner to a list
+func (s *PostService) addAudienceListMembers(ctx context.Context, repo Repository, list *models.AudienceList, memberIDs []string) error {
+	for _, id := range memberIDs {
+		if id == list.OwnerID {
+			return errors.New("you cannot add yourself to an audience list")
+		}
+
+		member, err := repo.GetUserDataByID(id)
+		if err != nil {
+			return err
+		}
+		if member == nil {
+			return errors.New("user not found")
+		}
+
+		if err := repo.AddAudienceListMember(list.ID, id); err != nil {
+			s.log.WithContext(ctx).Error("Failed to add audience list member: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ownAudienceList retrieves an audience list through repo, making sure it belongs to the user
+func (s *PostService) ownAudienceList(repo Repository, userID string, listID int64) (*models.AudienceList, error) {
+	list, err := repo.GetAudienceList(listID)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil || list.OwnerID != userID {
+		return nil, ErrAudienceListNotFound
+	}
+
+	return list, nil
+}
+
+// audienceListName validates the name of a new or renamed list, which must be unique
+// among the user's lists
+func (s *PostService) audienceListName(userID, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("audience list name is required")
+	}
+	if len(name) > maxAudienceListName {
+		return "", errors.New("audience list name is too long")
+	}
+
+	lists, err := s.repo.GetAudienceLists(userID)
+	if err != nil {
+		return "", err
+	}
+	for _, list := range lists {
+		if strings.EqualFold(list.Name, name) {
+			return "", errors.New("you already have an audience list with this name")
+		}
+	}
+
+	return name, nil
+}
+
+// postAudienceList resolves the audience list a private post is shared with, which must
+// belong to its author. A zero listID means none.
+func (s *PostService) postAudienceList(userID, privacy string, listID int64) (sql.NullInt64, error) {
+	if privacy != models.PrivacyPrivate || listID == 0 {
+		return sql.NullInt64{}, nil
+	}
+
+	list, err := s.ownAudienceList(s.repo, userID, listID)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	return sql.NullInt64{Int64: list.ID, Valid: true}, nil
+}
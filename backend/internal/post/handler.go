@@ -1,7 +1,9 @@
 package post
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
@@ -13,6 +15,7 @@ import (
 	"github.com/Athooh/social-network/pkg/httputil"
 	"github.com/Athooh/social-network/pkg/logger"
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/utils"
 )
 
 // Handler handles HTTP requests for posts
@@ -44,22 +47,102 @@ type PostResponse struct {
 	VideoURL   string               `json:"videoUrl,omitempty"`
 	Privacy    string               `json:"privacy"`
 	LikesCount int                  `json:"likesCount"`
+	IsEdited   bool                 `json:"isEdited"`
 	Comments   []CommentResponse    `json:"comments"`
 	CreatedAt  string               `json:"createdAt"`
 	UpdatedAt  string               `json:"updatedAt"`
 	UserData   *models.PostUserData `json:"userData"`
+
+	AudienceListID int64 `json:"audienceListId,omitempty"`
+
+	ReactionCounts map[string]int `json:"reactionCounts"`
+	UserReaction   string         `json:"userReaction,omitempty"`
+
+	SharesCount           int           `json:"sharesCount"`
+	SharedPostID          int64         `json:"sharedPostId,omitempty"`
+	SharedPost            *PostResponse `json:"sharedPost,omitempty"`
+	SharedPostUnavailable bool          `json:"sharedPostUnavailable,omitempty"`
+}
+
+// TrendingTopicResponse represents a trending hashtag
+type TrendingTopicResponse struct {
+	Tag        string  `json:"tag"`
+	Score      float64 `json:"score"`
+	PostsCount int     `json:"postsCount"`
+	UpdatedAt  string  `json:"updatedAt"`
 }
 
 // CommentResponse represents the response for a comment
 type CommentResponse struct {
-	ID        int64                `json:"id"`
-	PostID    int64                `json:"postId"`
-	UserID    string               `json:"userId"`
-	Content   string               `json:"content"`
-	ImageURL  string               `json:"imageUrl,omitempty"`
-	CreatedAt string               `json:"createdAt"`
-	UpdatedAt string               `json:"updatedAt"`
-	UserData  *models.PostUserData `json:"userData"`
+	ID           int64                `json:"id"`
+	PostID       int64                `json:"postId"`
+	UserID       string               `json:"userId"`
+	ParentID     int64                `json:"parentId,omitempty"`
+	Depth        int                  `json:"depth"`
+	Content      string               `json:"content"`
+	ImageURL     string               `json:"imageUrl,omitempty"`
+	IsEdited     bool                 `json:"isEdited"`
+	LikesCount   int                  `json:"likesCount"`
+	RepliesCount int                  `json:"repliesCount"`
+	IsLiked      bool                 `json:"isLiked"`
+	CreatedAt    string               `json:"createdAt"`
+	UpdatedAt    string               `json:"updatedAt"`
+	UserData     *models.PostUserData `json:"userData"`
+}
+
+// newSharedPostResponse builds the embedded response for the original of a reshare
+func newSharedPostResponse(post *models.Post) *PostResponse {
+	if post == nil {
+		return nil
+	}
+
+	response := &PostResponse{
+		ID:          post.ID,
+		UserID:      post.UserID,
+		Content:     post.Content,
+		Privacy:     post.Privacy,
+		LikesCount:  int(post.LikesCount),
+		IsEdited:    post.IsEdited,
+		SharesCount: int(post.SharesCount),
+		CreatedAt:   post.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   post.UpdatedAt.Format(time.RFC3339),
+		UserData:    post.UserData,
+	}
+
+	if post.ImagePath.String != "" {
+		response.ImageURL = "/uploads/" + post.ImagePath.String
+	}
+
+	if post.VideoPath.String != "" {
+		response.VideoURL = "/uploads/" + post.VideoPath.String
+	}
+
+	return response
+}
+
+// newCommentResponse builds the response for a comment
+func newCommentResponse(comment *models.Comment) CommentResponse {
+	response := CommentResponse{
+		ID:           comment.ID,
+		PostID:       comment.PostID,
+		UserID:       comment.UserID,
+		ParentID:     comment.ParentID.Int64,
+		Depth:        comment.Depth,
+		Content:      comment.Content,
+		IsEdited:     comment.IsEdited,
+		LikesCount:   int(comment.LikesCount),
+		RepliesCount: int(comment.RepliesCount),
+		IsLiked:      comment.IsLiked,
+		CreatedAt:    comment.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    comment.UpdatedAt.Format(time.RFC3339),
+		UserData:     comment.UserData,
+	}
+
+	if comment.ImagePath.String != "" {
+		response.ImageURL = "/uploads/" + comment.ImagePath.String
+	}
+
+	return response
 }
 
 // PostWithCommentsResponse represents the response for a post with its comments
@@ -73,8 +156,30 @@ type PostWithCommentsResponse struct {
 	CreatedAt  string               `json:"createdAt"`
 	UpdatedAt  string               `json:"updatedAt"`
 	LikesCount int                  `json:"likesCount"`
+	IsEdited   bool                 `json:"isEdited"`
 	Comments   []CommentResponse    `json:"comments"`
 	UserData   *models.PostUserData `json:"userData"`
+
+	ReactionCounts map[string]int `json:"reactionCounts"`
+	UserReaction   string         `json:"userReaction,omitempty"`
+
+	SharesCount           int           `json:"sharesCount"`
+	SharedPostID          int64         `json:"sharedPostId,omitempty"`
+	SharedPost            *PostResponse `json:"sharedPost,omitempty"`
+	SharedPostUnavailable bool          `json:"sharedPostUnavailable,omitempty"`
+}
+
+// RevisionResponse represents a prior version of an edited post or comment
+type RevisionResponse struct {
+	ID         int64                `json:"id"`
+	TargetType string               `json:"targetType"`
+	TargetID   int64                `json:"targetId"`
+	Content    string               `json:"content"`
+	ImageURL   string               `json:"imageUrl,omitempty"`
+	VideoURL   string               `json:"videoUrl,omitempty"`
+	Privacy    string               `json:"privacy,omitempty"`
+	EditedAt   string               `json:"editedAt"`
+	EditorData *models.PostUserData `json:"editorData"`
 }
 
 // CreatePost handles the creation of a new post
@@ -102,6 +207,12 @@ func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audienceListID, err := parseAudienceListID(r.FormValue("audienceListId"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Get image file if provided
 	var imageFile *multipart.FileHeader
 	if file, header, err := r.FormFile("image"); err == nil {
@@ -123,27 +234,21 @@ func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create post
-	post, err := h.service.CreatePost(userID, content, privacy, imageFile, videoFile)
+	post, err := h.service.CreatePost(r.Context(), userID, content, privacy, viewers, audienceListID, imageFile, videoFile)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	if len(viewers) > 0 {
-		if err := h.service.SetPostViewers(post.ID, userID, viewers); err != nil {
-			h.sendError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-	}
-
 	// Prepare response
 	response := PostResponse{
-		ID:        post.ID,
-		UserID:    post.UserID,
-		Content:   post.Content,
-		Privacy:   post.Privacy,
-		CreatedAt: post.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: post.UpdatedAt.Format(time.RFC3339),
+		ID:             post.ID,
+		UserID:         post.UserID,
+		Content:        post.Content,
+		Privacy:        post.Privacy,
+		AudienceListID: post.AudienceListID.Int64,
+		CreatedAt:      post.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      post.UpdatedAt.Format(time.RFC3339),
 	}
 
 	if post.ImagePath.String != "" {
@@ -158,6 +263,67 @@ func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusCreated, response)
 }
 
+// SharePostRequest represents the request to reshare or quote a post
+type SharePostRequest struct {
+	PostID  int64    `json:"postId"`
+	Content string   `json:"content"`
+	Privacy string   `json:"privacy"`
+	Viewers []string `json:"viewers"`
+
+	AudienceListID int64 `json:"audienceListId"`
+}
+
+// SharePost handles resharing a post, or quoting it when content is provided
+func (h *Handler) SharePost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method not allowed: %s", r.Method))
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req SharePostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.PostID <= 0 {
+		h.sendError(w, http.StatusBadRequest, "Post ID is required")
+		return
+	}
+
+	if req.Privacy == "" {
+		req.Privacy = models.PrivacyPublic
+	}
+
+	post, err := h.service.SharePost(r.Context(), req.PostID, userID, req.Content, req.Privacy, req.Viewers, req.AudienceListID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := PostResponse{
+		ID:             post.ID,
+		UserID:         post.UserID,
+		Content:        post.Content,
+		Privacy:        post.Privacy,
+		AudienceListID: post.AudienceListID.Int64,
+		CreatedAt:      post.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      post.UpdatedAt.Format(time.RFC3339),
+		UserData:       post.UserData,
+		SharedPostID:   post.SharedPostID.Int64,
+		SharedPost:     newSharedPostResponse(post.SharedPost),
+	}
+
+	h.sendJSON(w, http.StatusCreated, response)
+}
+
 // GetPost handles retrieving a post by ID
 func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (set by auth middleware)
@@ -180,7 +346,7 @@ func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get post with comments
-	post, comments, err := h.service.GetPostWithComments(postID, userID)
+	post, comments, err := h.service.GetPostWithComments(r.Context(), postID, userID)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -188,15 +354,22 @@ func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
 
 	// Prepare response
 	response := PostWithCommentsResponse{
-		ID:         post.ID,
-		UserID:     post.UserID,
-		Content:    post.Content,
-		Privacy:    post.Privacy,
-		LikesCount: int(post.LikesCount),
-		CreatedAt:  post.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:  post.UpdatedAt.Format(time.RFC3339),
-		Comments:   make([]CommentResponse, 0, len(comments)),
-		UserData:   post.UserData,
+		ID:                    post.ID,
+		UserID:                post.UserID,
+		Content:               post.Content,
+		Privacy:               post.Privacy,
+		LikesCount:            int(post.LikesCount),
+		ReactionCounts:        post.ReactionCounts,
+		UserReaction:          post.UserReaction,
+		SharesCount:           int(post.SharesCount),
+		SharedPostID:          post.SharedPostID.Int64,
+		SharedPost:            newSharedPostResponse(post.SharedPost),
+		SharedPostUnavailable: post.SharedPostUnavailable,
+		IsEdited:              post.IsEdited,
+		CreatedAt:             post.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:             post.UpdatedAt.Format(time.RFC3339),
+		Comments:              make([]CommentResponse, 0, len(comments)),
+		UserData:              post.UserData,
 	}
 
 	if post.ImagePath.String != "" {
@@ -209,17 +382,7 @@ func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
 
 	// Add comments to response
 	for _, comment := range comments {
-		commentResp := CommentResponse{
-			ID:        comment.ID,
-			PostID:    comment.PostID,
-			UserID:    comment.UserID,
-			Content:   comment.Content,
-			CreatedAt: comment.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: comment.UpdatedAt.Format(time.RFC3339),
-		}
-		if comment.ImagePath.String != "" {
-			commentResp.ImageURL = "/uploads/" + comment.ImagePath.String
-		}
+		commentResp := newCommentResponse(comment)
 		response.Comments = append(response.Comments, commentResp)
 	}
 
@@ -249,7 +412,7 @@ func (h *Handler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get posts
-	posts, err := h.service.GetUserPosts(targetID, viewerID)
+	posts, err := h.service.GetUserPosts(r.Context(), targetID, viewerID)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -259,21 +422,28 @@ func (h *Handler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
 	var response []PostResponse
 	for _, post := range posts {
 		// Get comments for each post
-		comments, err := h.service.GetPostComments(post.ID, viewerID)
+		comments, err := h.service.GetPostComments(r.Context(), post.ID, viewerID)
 		if err != nil {
-			h.log.Error("Failed to get comments for post %d: %v", post.ID, err)
+			h.log.WithContext(r.Context()).Error("Failed to get comments for post %d: %v", post.ID, err)
 			continue
 		}
 		postResp := PostResponse{
-			ID:         post.ID,
-			UserID:     post.UserID,
-			Content:    post.Content,
-			Privacy:    post.Privacy,
-			LikesCount: int(post.LikesCount),
-			CreatedAt:  post.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:  post.UpdatedAt.Format(time.RFC3339),
-			Comments:   make([]CommentResponse, 0, len(comments)),
-			UserData:   post.UserData,
+			ID:                    post.ID,
+			UserID:                post.UserID,
+			Content:               post.Content,
+			Privacy:               post.Privacy,
+			LikesCount:            int(post.LikesCount),
+			ReactionCounts:        post.ReactionCounts,
+			UserReaction:          post.UserReaction,
+			SharesCount:           int(post.SharesCount),
+			SharedPostID:          post.SharedPostID.Int64,
+			SharedPost:            newSharedPostResponse(post.SharedPost),
+			SharedPostUnavailable: post.SharedPostUnavailable,
+			IsEdited:              post.IsEdited,
+			CreatedAt:             post.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:             post.UpdatedAt.Format(time.RFC3339),
+			Comments:              make([]CommentResponse, 0, len(comments)),
+			UserData:              post.UserData,
 		}
 
 		if post.ImagePath.String != "" {
@@ -288,18 +458,7 @@ func (h *Handler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
 		}
 		// Add comments to response
 		for _, comment := range comments {
-			commentResp := CommentResponse{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				UserID:    comment.UserID,
-				Content:   comment.Content,
-				CreatedAt: comment.CreatedAt.Format(time.RFC3339),
-				UpdatedAt: comment.UpdatedAt.Format(time.RFC3339),
-				UserData:  comment.UserData,
-			}
-			if comment.ImagePath.String != "" {
-				commentResp.ImageURL = "/uploads/" + comment.ImagePath.String
-			}
+			commentResp := newCommentResponse(comment)
 			postResp.Comments = append(postResp.Comments, commentResp)
 		}
 
@@ -332,7 +491,7 @@ func (h *Handler) GetUserPhotos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get posts
-	posts, err := h.service.GetUserPosts(targetID, viewerID)
+	posts, err := h.service.GetUserPosts(r.Context(), targetID, viewerID)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -389,7 +548,7 @@ func (h *Handler) GetPublicPosts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get posts
-	posts, err := h.service.GetPublicPosts(limit, offset)
+	posts, err := h.service.GetPublicPosts(r.Context(), limit, offset)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -399,22 +558,29 @@ func (h *Handler) GetPublicPosts(w http.ResponseWriter, r *http.Request) {
 	var response []PostWithCommentsResponse
 	for _, post := range posts {
 		// Get comments for each post
-		comments, err := h.service.GetPostComments(post.ID, userID)
+		comments, err := h.service.GetPostComments(r.Context(), post.ID, userID)
 		if err != nil {
-			h.log.Error("Failed to get comments for post %d: %v", post.ID, err)
+			h.log.WithContext(r.Context()).Error("Failed to get comments for post %d: %v", post.ID, err)
 			continue
 		}
 
 		postResp := PostWithCommentsResponse{
-			ID:         post.ID,
-			UserID:     post.UserID,
-			Content:    post.Content,
-			Privacy:    post.Privacy,
-			LikesCount: int(post.LikesCount),
-			CreatedAt:  post.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:  post.UpdatedAt.Format(time.RFC3339),
-			Comments:   make([]CommentResponse, 0, len(comments)),
-			UserData:   post.UserData,
+			ID:                    post.ID,
+			UserID:                post.UserID,
+			Content:               post.Content,
+			Privacy:               post.Privacy,
+			LikesCount:            int(post.LikesCount),
+			ReactionCounts:        post.ReactionCounts,
+			UserReaction:          post.UserReaction,
+			SharesCount:           int(post.SharesCount),
+			SharedPostID:          post.SharedPostID.Int64,
+			SharedPost:            newSharedPostResponse(post.SharedPost),
+			SharedPostUnavailable: post.SharedPostUnavailable,
+			IsEdited:              post.IsEdited,
+			CreatedAt:             post.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:             post.UpdatedAt.Format(time.RFC3339),
+			Comments:              make([]CommentResponse, 0, len(comments)),
+			UserData:              post.UserData,
 		}
 
 		if post.ImagePath.String != "" {
@@ -427,17 +593,7 @@ func (h *Handler) GetPublicPosts(w http.ResponseWriter, r *http.Request) {
 
 		// Add comments to response
 		for _, comment := range comments {
-			commentResp := CommentResponse{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				UserID:    comment.UserID,
-				Content:   comment.Content,
-				CreatedAt: comment.CreatedAt.Format(time.RFC3339),
-				UpdatedAt: comment.UpdatedAt.Format(time.RFC3339),
-			}
-			if comment.ImagePath.String != "" {
-				commentResp.ImageURL = "/uploads/" + comment.ImagePath.String
-			}
+			commentResp := newCommentResponse(comment)
 			postResp.Comments = append(postResp.Comments, commentResp)
 		}
 
@@ -457,32 +613,33 @@ func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get post ID from URL
-	pathParts := strings.Split(r.URL.Path, "/")
-	if len(pathParts) < 3 {
-		h.sendError(w, http.StatusBadRequest, "Invalid URL")
-		return
-	}
-	postID, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
-	if err != nil {
+	// Parse multipart form
+	if err := r.ParseMultipartForm(20 << 20); err != nil { // 20 MB max for videos
 		h.sendError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(20 << 20); err != nil { // 20 MB max for videos
-		h.sendError(w, http.StatusBadRequest, err.Error())
+	// Get post ID from form
+	postID, err := strconv.ParseInt(r.FormValue("postId"), 10, 64)
+	if err != nil || postID <= 0 {
+		h.sendError(w, http.StatusBadRequest, "Invalid or missing Post ID")
 		return
 	}
 
 	// Get form values
 	content := r.FormValue("content")
 	privacy := r.FormValue("privacy")
+	viewers := r.Form["viewers"]
 
-	// Validate required fields
-	if content == "" {
-		h.sendError(w, http.StatusBadRequest, "Content field is missing")
-		return
+	// The audience list is only changed when the field is sent
+	var audienceListID *int64
+	if _, ok := r.Form["audienceListId"]; ok {
+		id, err := parseAudienceListID(r.FormValue("audienceListId"))
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		audienceListID = &id
 	}
 
 	// Get image file if provided
@@ -499,8 +656,14 @@ func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		videoFile = header
 	}
 
+	// Validate required fields
+	if content == "" && imageFile == nil && videoFile == nil {
+		h.sendError(w, http.StatusBadRequest, "Content, image, or video field is required")
+		return
+	}
+
 	// Update post
-	post, err := h.service.UpdatePost(postID, userID, content, privacy, imageFile, videoFile)
+	post, err := h.service.UpdatePost(r.Context(), postID, userID, content, privacy, viewers, audienceListID, imageFile, videoFile)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -508,12 +671,22 @@ func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 
 	// Prepare response
 	response := PostResponse{
-		ID:        post.ID,
-		UserID:    post.UserID,
-		Content:   post.Content,
-		Privacy:   post.Privacy,
-		CreatedAt: post.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: post.UpdatedAt.Format(time.RFC3339),
+		ID:                    post.ID,
+		UserID:                post.UserID,
+		Content:               post.Content,
+		Privacy:               post.Privacy,
+		AudienceListID:        post.AudienceListID.Int64,
+		LikesCount:            int(post.LikesCount),
+		ReactionCounts:        post.ReactionCounts,
+		UserReaction:          post.UserReaction,
+		SharesCount:           int(post.SharesCount),
+		SharedPostID:          post.SharedPostID.Int64,
+		SharedPost:            newSharedPostResponse(post.SharedPost),
+		SharedPostUnavailable: post.SharedPostUnavailable,
+		IsEdited:              post.IsEdited,
+		CreatedAt:             post.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:             post.UpdatedAt.Format(time.RFC3339),
+		UserData:              post.UserData,
 	}
 
 	if post.ImagePath.String != "" {
@@ -528,6 +701,66 @@ func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, response)
 }
 
+// GetRevisions handles retrieving the edit history of a post or a comment
+func (h *Handler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method not allowed: %s", r.Method))
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var revisions []*models.PostRevision
+	var err error
+
+	if commentIDStr := r.URL.Query().Get("commentId"); commentIDStr != "" {
+		commentID, parseErr := strconv.ParseInt(commentIDStr, 10, 64)
+		if parseErr != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid comment ID")
+			return
+		}
+		revisions, err = h.service.GetCommentRevisions(r.Context(), commentID, userID)
+	} else {
+		postID, parseErr := strconv.ParseInt(r.URL.Query().Get("postId"), 10, 64)
+		if parseErr != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid or missing Post ID")
+			return
+		}
+		revisions, err = h.service.GetPostRevisions(r.Context(), postID, userID)
+	}
+	if err != nil {
+		h.sendError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	response := make([]RevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		revisionResp := RevisionResponse{
+			ID:         revision.ID,
+			TargetType: revision.TargetType,
+			TargetID:   revision.TargetID,
+			Content:    revision.Content,
+			Privacy:    revision.Privacy,
+			EditedAt:   revision.CreatedAt.Format(time.RFC3339),
+			EditorData: revision.EditorData,
+		}
+		if revision.ImagePath.String != "" {
+			revisionResp.ImageURL = "/uploads/" + revision.ImagePath.String
+		}
+		if revision.VideoPath.String != "" {
+			revisionResp.VideoURL = "/uploads/" + revision.VideoPath.String
+		}
+		response = append(response, revisionResp)
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
 // DeletePost handles deleting a post
 func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (set by auth middleware)
@@ -553,7 +786,7 @@ func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete post
-	if err := h.service.DeletePost(request.PostID, userID); err != nil {
+	if err := h.service.DeletePost(r.Context(), request.PostID, userID); err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -594,7 +827,7 @@ func (h *Handler) SetPostViewers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set post viewers
-	if err := h.service.SetPostViewers(postID, userID, request.ViewerIDs); err != nil {
+	if err := h.service.SetPostViewers(r.Context(), postID, userID, request.ViewerIDs); err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -633,6 +866,16 @@ func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	// Get form values
 	content := r.FormValue("content")
 
+	// A parent comment ID makes this a reply
+	var parentID int64
+	if parentIDStr := r.FormValue("parentId"); parentIDStr != "" {
+		parentID, err = strconv.ParseInt(parentIDStr, 10, 64)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid parent comment ID")
+			return
+		}
+	}
+
 	// Get image file if provided
 	var imageFile *multipart.FileHeader
 	if file, header, err := r.FormFile("image"); err == nil {
@@ -647,26 +890,14 @@ func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create comment
-	comment, err := h.service.CreateComment(postID, userID, content, imageFile)
+	comment, err := h.service.CreateComment(r.Context(), postID, userID, parentID, content, imageFile)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	// Prepare response
-	response := CommentResponse{
-		ID:        comment.ID,
-		PostID:    comment.PostID,
-		UserID:    comment.UserID,
-		Content:   comment.Content,
-		CreatedAt: comment.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: comment.UpdatedAt.Format(time.RFC3339),
-		UserData:  comment.UserData,
-	}
-
-	if comment.ImagePath.String != "" {
-		response.ImageURL = "/uploads/" + comment.ImagePath.String
-	}
+	response := newCommentResponse(comment)
 
 	// Return response
 	h.sendJSON(w, http.StatusCreated, response)
@@ -694,7 +925,7 @@ func (h *Handler) GetPostComments(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get comments
-	comments, err := h.service.GetPostComments(postID, userID)
+	comments, err := h.service.GetPostComments(r.Context(), postID, userID)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -703,19 +934,7 @@ func (h *Handler) GetPostComments(w http.ResponseWriter, r *http.Request) {
 	// Prepare response
 	var response []CommentResponse
 	for _, comment := range comments {
-		commentResp := CommentResponse{
-			ID:        comment.ID,
-			PostID:    comment.PostID,
-			UserID:    comment.UserID,
-			Content:   comment.Content,
-			CreatedAt: comment.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: comment.UpdatedAt.Format(time.RFC3339),
-			UserData:  comment.UserData,
-		}
-
-		if comment.ImagePath.String != "" {
-			commentResp.ImageURL = "/uploads/" + comment.ImagePath.String
-		}
+		commentResp := newCommentResponse(comment)
 
 		response = append(response, commentResp)
 	}
@@ -724,6 +943,155 @@ func (h *Handler) GetPostComments(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, response)
 }
 
+// UpdateComment handles editing a comment
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get comment ID from URL
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 {
+		h.sendError(w, http.StatusBadRequest, "Invalid URL")
+		return
+	}
+	commentID, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Parse multipart form
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB max
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	content := r.FormValue("content")
+
+	// Get image file if provided
+	var imageFile *multipart.FileHeader
+	if file, header, err := r.FormFile("image"); err == nil {
+		defer file.Close()
+		imageFile = header
+	}
+
+	if content == "" && imageFile == nil {
+		h.sendError(w, http.StatusBadRequest, "Content or image field is missing please provide one")
+		return
+	}
+
+	comment, err := h.service.UpdateComment(r.Context(), commentID, userID, content, imageFile)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := newCommentResponse(comment)
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// GetCommentReplies handles loading a page of replies to a comment
+func (h *Handler) GetCommentReplies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method not allowed: %s", r.Method))
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get comment ID from URL
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 {
+		h.sendError(w, http.StatusBadRequest, "Invalid URL")
+		return
+	}
+	commentID, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Get pagination parameters
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10 // Default limit
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	replies, err := h.service.GetCommentReplies(r.Context(), commentID, userID, limit, offset)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]CommentResponse, 0, len(replies))
+	for _, reply := range replies {
+		response = append(response, newCommentResponse(reply))
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// LikeComment handles liking or unliking a comment
+func (h *Handler) LikeComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method not allowed: %s", r.Method))
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get comment ID from URL
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 {
+		h.sendError(w, http.StatusBadRequest, "Invalid URL")
+		return
+	}
+	commentID, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Toggle like status
+	isLiked, likesCount, err := h.service.LikeComment(r.Context(), commentID, userID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := struct {
+		CommentID  int64 `json:"commentId"`
+		LikesCount int   `json:"likesCount"`
+		IsLiked    bool  `json:"isLiked"`
+	}{
+		CommentID:  commentID,
+		LikesCount: likesCount,
+		IsLiked:    isLiked,
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
 // DeleteComment handles deleting a comment
 func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (set by auth middleware)
@@ -748,7 +1116,7 @@ func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete comment
-	if err := h.service.DeleteComment(commentID, userID, postid); err != nil {
+	if err := h.service.DeleteComment(r.Context(), commentID, userID, postid); err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -764,6 +1132,8 @@ func (h *Handler) HandleComments(w http.ResponseWriter, r *http.Request) {
 		h.CreateComment(w, r)
 	case http.MethodGet:
 		h.GetPostComments(w, r)
+	case http.MethodPut:
+		h.UpdateComment(w, r)
 	case http.MethodDelete:
 		h.DeleteComment(w, r)
 	default:
@@ -792,7 +1162,7 @@ func (h *Handler) GetFeedPosts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get feed posts
-	posts, err := h.service.GetFeedPosts(userID, page, pageSize)
+	posts, err := h.service.GetFeedPosts(r.Context(), userID, page, pageSize)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -802,22 +1172,29 @@ func (h *Handler) GetFeedPosts(w http.ResponseWriter, r *http.Request) {
 	var response []PostWithCommentsResponse
 	for _, post := range posts {
 		// Get comments for each post
-		comments, err := h.service.GetPostComments(post.ID, userID)
+		comments, err := h.service.GetPostComments(r.Context(), post.ID, userID)
 		if err != nil {
-			h.log.Error("Failed to get comments for post %d: %v", post.ID, err)
+			h.log.WithContext(r.Context()).Error("Failed to get comments for post %d: %v", post.ID, err)
 			continue
 		}
 
 		postResp := PostWithCommentsResponse{
-			ID:         post.ID,
-			UserID:     post.UserID,
-			Content:    post.Content,
-			Privacy:    post.Privacy,
-			LikesCount: int(post.LikesCount),
-			CreatedAt:  post.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:  post.UpdatedAt.Format(time.RFC3339),
-			Comments:   make([]CommentResponse, 0, len(comments)),
-			UserData:   post.UserData,
+			ID:                    post.ID,
+			UserID:                post.UserID,
+			Content:               post.Content,
+			Privacy:               post.Privacy,
+			LikesCount:            int(post.LikesCount),
+			ReactionCounts:        post.ReactionCounts,
+			UserReaction:          post.UserReaction,
+			SharesCount:           int(post.SharesCount),
+			SharedPostID:          post.SharedPostID.Int64,
+			SharedPost:            newSharedPostResponse(post.SharedPost),
+			SharedPostUnavailable: post.SharedPostUnavailable,
+			IsEdited:              post.IsEdited,
+			CreatedAt:             post.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:             post.UpdatedAt.Format(time.RFC3339),
+			Comments:              make([]CommentResponse, 0, len(comments)),
+			UserData:              post.UserData,
 		}
 
 		if post.ImagePath.String != "" {
@@ -834,18 +1211,7 @@ func (h *Handler) GetFeedPosts(w http.ResponseWriter, r *http.Request) {
 
 		// Add comments to response
 		for _, comment := range comments {
-			commentResp := CommentResponse{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				UserID:    comment.UserID,
-				Content:   comment.Content,
-				CreatedAt: comment.CreatedAt.Format(time.RFC3339),
-				UpdatedAt: comment.UpdatedAt.Format(time.RFC3339),
-				UserData:  comment.UserData,
-			}
-			if comment.ImagePath.String != "" {
-				commentResp.ImageURL = "/uploads/" + comment.ImagePath.String
-			}
+			commentResp := newCommentResponse(comment)
 			postResp.Comments = append(postResp.Comments, commentResp)
 		}
 
@@ -856,6 +1222,127 @@ func (h *Handler) GetFeedPosts(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, response)
 }
 
+// GetTagPosts handles getting the posts for a hashtag, the path is /api/tags/{tag}/posts
+func (h *Handler) GetTagPosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/tags/")
+	rawTag, rest, _ := strings.Cut(path, "/")
+	if rest != "posts" {
+		h.sendError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	tag := utils.NormalizeHashtag(rawTag)
+	if tag == "" {
+		h.sendError(w, http.StatusBadRequest, "Invalid hashtag")
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	posts, err := h.service.GetTagPosts(r.Context(), tag, userID, page, pageSize)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]PostWithCommentsResponse, 0, len(posts))
+	for _, post := range posts {
+		comments, err := h.service.GetPostComments(r.Context(), post.ID, userID)
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to get comments for post %d: %v", post.ID, err)
+			continue
+		}
+
+		postResp := PostWithCommentsResponse{
+			ID:                    post.ID,
+			UserID:                post.UserID,
+			Content:               post.Content,
+			Privacy:               post.Privacy,
+			LikesCount:            int(post.LikesCount),
+			ReactionCounts:        post.ReactionCounts,
+			UserReaction:          post.UserReaction,
+			SharesCount:           int(post.SharesCount),
+			SharedPostID:          post.SharedPostID.Int64,
+			SharedPost:            newSharedPostResponse(post.SharedPost),
+			SharedPostUnavailable: post.SharedPostUnavailable,
+			IsEdited:              post.IsEdited,
+			CreatedAt:             post.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:             post.UpdatedAt.Format(time.RFC3339),
+			Comments:              make([]CommentResponse, 0, len(comments)),
+			UserData:              post.UserData,
+		}
+
+		if post.ImagePath.String != "" {
+			postResp.ImageURL = "/uploads/" + post.ImagePath.String
+		}
+
+		if post.VideoPath.String != "" {
+			postResp.VideoURL = "/uploads/" + post.VideoPath.String
+		}
+
+		for _, comment := range comments {
+			postResp.Comments = append(postResp.Comments, newCommentResponse(comment))
+		}
+
+		response = append(response, postResp)
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"tag":   tag,
+		"posts": response,
+	})
+}
+
+// GetTrendingTopics handles getting the current trending hashtags
+func (h *Handler) GetTrendingTopics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	topics, err := h.service.GetTrendingTopics(r.Context(), limit)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to get trending topics")
+		return
+	}
+
+	response := make([]TrendingTopicResponse, 0, len(topics))
+	for _, topic := range topics {
+		response = append(response, TrendingTopicResponse{
+			Tag:        topic.Tag,
+			Score:      topic.Score,
+			PostsCount: topic.PostsCount,
+			UpdatedAt:  topic.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
 // LikePost handles liking or unliking a post
 func (h *Handler) LikePost(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (set by auth middleware)
@@ -878,14 +1365,14 @@ func (h *Handler) LikePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Toggle like status
-	isLiked, err := h.service.LikePost(postID, userID)
+	isLiked, err := h.service.LikePost(r.Context(), postID, userID)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	// Get updated likes count
-	post, err := h.service.GetPost(postID, userID)
+	post, err := h.service.GetPost(r.Context(), postID, userID)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -905,6 +1392,277 @@ func (h *Handler) LikePost(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, response)
 }
 
+// ReactionRequest represents the request to react to a post
+type ReactionRequest struct {
+	ReactionType string `json:"reactionType"`
+}
+
+// ReactorsResponse represents who reacted to a post along with the counts per type
+type ReactorsResponse struct {
+	PostID         int64                 `json:"postId"`
+	ReactionCounts map[string]int        `json:"reactionCounts"`
+	Reactors       []*models.PostReactor `json:"reactors"`
+}
+
+// HandleReactions handles reacting to a post (POST), removing a reaction (DELETE)
+// and listing who reacted (GET) at /api/posts/reactions/{postId}
+func (h *Handler) HandleReactions(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get post ID from URL
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 {
+		h.sendError(w, http.StatusBadRequest, "Invalid URL")
+		return
+	}
+	postID, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getReactors(w, r, postID, userID)
+	case http.MethodPost:
+		var req ReactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if !models.IsValidReaction(req.ReactionType) {
+			h.sendError(w, http.StatusBadRequest, "Invalid reaction type")
+			return
+		}
+		h.react(r.Context(), w, postID, userID, req.ReactionType)
+	case http.MethodDelete:
+		h.react(r.Context(), w, postID, userID, "")
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method not allowed: %s", r.Method))
+	}
+}
+
+// react sets or removes the user's reaction and writes the updated counts
+func (h *Handler) react(ctx context.Context, w http.ResponseWriter, postID int64, userID, reactionType string) {
+	reaction, counts, err := h.service.ReactToPost(ctx, postID, userID, reactionType)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := struct {
+		PostID         int64          `json:"postId"`
+		UserReaction   string         `json:"userReaction"`
+		ReactionCounts map[string]int `json:"reactionCounts"`
+	}{
+		PostID:         postID,
+		UserReaction:   reaction,
+		ReactionCounts: counts,
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// getReactors writes a page of users who reacted to a post, filtered by the optional type query parameter
+func (h *Handler) getReactors(w http.ResponseWriter, r *http.Request, postID int64, userID string) {
+	reactionType := r.URL.Query().Get("type")
+
+	// Get pagination parameters
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20 // Default limit
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	reactors, counts, err := h.service.GetPostReactors(r.Context(), postID, userID, reactionType, limit, offset)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for _, reactor := range reactors {
+		if reactor.User.Avatar != "" {
+			reactor.User.Avatar = "/uploads/" + reactor.User.Avatar
+		}
+	}
+
+	if reactors == nil {
+		reactors = []*models.PostReactor{}
+	}
+
+	h.sendJSON(w, http.StatusOK, ReactorsResponse{
+		PostID:         postID,
+		ReactionCounts: counts,
+		Reactors:       reactors,
+	})
+}
+
+// parseAudienceListID parses the optional audience list of a post, zero when empty
+func parseAudienceListID(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid audience list ID")
+	}
+	return id, nil
+}
+
+// AudienceListRequest represents the request to create or rename an audience list
+type AudienceListRequest struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	MemberIDs []string `json:"memberIds"`
+}
+
+// HandleAudienceLists handles listing (GET), creating (POST), renaming (PUT) and
+// deleting (DELETE ?id=) the user's audience lists at /api/posts/audiences
+func (h *Handler) HandleAudienceLists(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		lists, err := h.service.GetAudienceLists(r.Context(), userID)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if lists == nil {
+			lists = []*models.AudienceList{}
+		}
+		for _, list := range lists {
+			for _, member := range list.Members {
+				if member.Avatar != "" {
+					member.Avatar = "/uploads/" + member.Avatar
+				}
+			}
+		}
+
+		h.sendJSON(w, http.StatusOK, lists)
+	case http.MethodPost:
+		var req AudienceListRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		list, err := h.service.CreateAudienceList(r.Context(), userID, req.Name, req.MemberIDs)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		h.sendJSON(w, http.StatusCreated, list)
+	case http.MethodPut:
+		var req AudienceListRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if err := h.service.RenameAudienceList(r.Context(), userID, req.ID, req.Name); err != nil {
+			h.sendError(w, audienceListErrorStatus(err), err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		listID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid audience list ID")
+			return
+		}
+
+		if err := h.service.DeleteAudienceList(r.Context(), userID, listID); err != nil {
+			h.sendError(w, audienceListErrorStatus(err), err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method not allowed: %s", r.Method))
+	}
+}
+
+// AudienceListMembersRequest represents the request to add users to an audience list
+type AudienceListMembersRequest struct {
+	ListID  int64    `json:"listId"`
+	UserIDs []string `json:"userIds"`
+}
+
+// HandleAudienceListMembers handles adding users to (POST) and removing a user from
+// (DELETE ?listId=&userId=) an audience list at /api/posts/audiences/members
+func (h *Handler) HandleAudienceListMembers(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var req AudienceListMembersRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if err := h.service.AddAudienceListMembers(r.Context(), userID, req.ListID, req.UserIDs); err != nil {
+			h.sendError(w, audienceListErrorStatus(err), err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		listID, err := strconv.ParseInt(r.URL.Query().Get("listId"), 10, 64)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid audience list ID")
+			return
+		}
+
+		memberID := r.URL.Query().Get("userId")
+		if memberID == "" {
+			h.sendError(w, http.StatusBadRequest, "User ID is required")
+			return
+		}
+
+		if err := h.service.RemoveAudienceListMember(r.Context(), userID, listID, memberID); err != nil {
+			h.sendError(w, audienceListErrorStatus(err), err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method not allowed: %s", r.Method))
+	}
+}
+
+// audienceListErrorStatus maps audience list errors to HTTP status codes
+func audienceListErrorStatus(err error) int {
+	if errors.Is(err, ErrAudienceListNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 // Helper method to send JSON responses
 func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	httputil.SendJSON(w, status, data)
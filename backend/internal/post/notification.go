@@ -1,8 +1,10 @@
 package post
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -78,38 +80,68 @@ func (s *NotificationService) NotifyPostCreatedToSpecificUsers(post *models.Post
 	return nil
 }
 
-// NotifyPostLiked sends a notification when a post is liked or unliked
-func (s *NotificationService) NotifyPostLiked(post *models.Post, userID string, userName string, isLiked bool) error {
-	// Create event payload
-	payload := events.PostLikedPayload{
-		PostID:     post.ID,
-		UserID:     userID,
-		UserName:   userName,
-		IsLiked:    isLiked,
-		LikesCount: int(post.LikesCount),
+// NotifyPostUpdated sends an edited post to the given recipients, or to everyone for public posts
+func (s *NotificationService) NotifyPostUpdated(post *models.Post, recipientIDs []string, isPublic bool) error {
+	event := events.Event{
+		Type: events.PostUpdated,
+		Payload: events.PostUpdatedPayload{
+			Post:     post,
+			PostID:   post.ID,
+			IsEdited: post.IsEdited,
+		},
 	}
 
-	// Create event
+	return s.sendToAudience(event, recipientIDs, isPublic)
+}
+
+// NotifyCommentUpdated sends an edited comment to the given recipients, or to everyone for public posts
+func (s *NotificationService) NotifyCommentUpdated(comment *models.Comment, recipientIDs []string, isPublic bool) error {
 	event := events.Event{
-		Type:    events.PostLiked,
-		Payload: payload,
+		Type: events.CommentUpdated,
+		Payload: events.CommentUpdatedPayload{
+			Comment:  comment,
+			PostID:   comment.PostID,
+			IsEdited: comment.IsEdited,
+		},
 	}
 
-	// Notify the post owner if they're not the one who liked/unliked
-	// if post.UserID != userID {
-	// 	s.hub.BroadcastToUser(post.UserID, event)
-	// }
+	return s.sendToAudience(event, recipientIDs, isPublic)
+}
+
+// sendToAudience broadcasts an event to all clients or to each recipient once
+func (s *NotificationService) sendToAudience(event events.Event, recipientIDs []string, isPublic bool) error {
+	if isPublic {
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
 
-	// Also broadcast to anyone viewing the post
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		return err
+		s.hub.Broadcast <- eventJSON
+		return nil
+	}
+
+	sent := make(map[string]bool)
+	for _, recipientID := range recipientIDs {
+		if sent[recipientID] {
+			continue
+		}
+		sent[recipientID] = true
+		s.hub.BroadcastToUser(recipientID, event)
 	}
 
-	s.hub.Broadcast <- eventJSON
 	return nil
 }
 
+// NotifyPostReactionChanged sends the new reaction counts when a reaction is added, changed or removed
+func (s *NotificationService) NotifyPostReactionChanged(payload events.PostReactionChangedPayload, recipientIDs []string, isPublic bool) error {
+	event := events.Event{
+		Type:    events.PostReactionChanged,
+		Payload: payload,
+	}
+
+	return s.sendToAudience(event, recipientIDs, isPublic)
+}
+
 // NotifyUserStatsUpdated sends a notification when a user's stats are updated
 func (s *NotificationService) NotifyUserStatsUpdated(userID string, statsType string, count int) error {
 	// Create event payload
@@ -174,9 +206,9 @@ func (s *NotificationService) NotifyPostsCommentUpdate(userID string, statsType
 }
 
 // SendCommentNotification sends a WebSocket notification when a user comments on a post
-func (s *NotificationService) SendCommentNotificationToOwner(userID, commenterID string) {
+func (s *NotificationService) SendCommentNotificationToOwner(ctx context.Context, userID, commenterID string) {
 	if s.hub == nil {
-		s.log.Warn("WebSocket hub is nil, cannot send comment notification")
+		s.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot send comment notification")
 		return
 	}
 
@@ -187,7 +219,7 @@ func (s *NotificationService) SendCommentNotificationToOwner(userID, commenterID
 	// Fetch commenter details
 	commenter, err := s.userRepo.GetByID(commenterID)
 	if err != nil {
-		s.log.Error("Failed to fetch commenter details: %v", err)
+		s.log.WithContext(ctx).Error("Failed to fetch commenter details: %v", err)
 		return
 	}
 	commenterName := commenter.FirstName + " " + commenter.LastName
@@ -199,15 +231,17 @@ func (s *NotificationService) SendCommentNotificationToOwner(userID, commenterID
 		SenderId:        sql.NullString{String: commenterID, Valid: true},
 		Message:         fmt.Sprintf("%s commented on your post.", commenterName),
 	}
-	if err := s.notificationSRVC.CreateNotification(notification); err != nil {
-		s.log.Error("Failed to create comment notification: %v", err)
+	if err := s.notificationSRVC.CreateNotification(ctx, notification); err != nil {
+		if !errors.Is(err, notifications.ErrSenderBlocked) {
+			s.log.WithContext(ctx).Error("Failed to create comment notification: %v", err)
+		}
 		return
 	}
 
 	// Retrieve the newly created notification to get its ID and CreatedAt
-	notifications, err := s.notificationSRVC.GetNotifications(userID, 1, 0)
+	notifications, err := s.notificationSRVC.GetNotifications(ctx, userID, 1, 0)
 	if err != nil || len(notifications) == 0 {
-		s.log.Error("Failed to retrieve newly created notification: %v", err)
+		s.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
 		return
 	}
 	dbNotification := notifications[0]
@@ -229,3 +263,98 @@ func (s *NotificationService) SendCommentNotificationToOwner(userID, commenterID
 
 	s.hub.BroadcastToUser(userID, event)
 }
+
+// SendMentionNotification notifies a user that they were mentioned in a post or comment
+func (s *NotificationService) SendMentionNotification(ctx context.Context, userID, authorID string, postID int64, inComment bool) {
+	target := "a post"
+	if inComment {
+		target = "a comment"
+	}
+
+	s.sendPostNotification(ctx, userID, authorID, postID, "mention", "%s mentioned you in "+target+".")
+}
+
+// SendShareNotificationToOwner notifies a post's author that someone shared it
+func (s *NotificationService) SendShareNotificationToOwner(ctx context.Context, ownerID, sharerID string, postID int64) {
+	if ownerID == sharerID {
+		return
+	}
+
+	s.sendPostNotification(ctx, ownerID, sharerID, postID, "share", "%s shared your post.")
+}
+
+// sendPostNotification stores a notification that links to a post and pushes it to the user.
+// messageFormat receives the sender's full name. Without a websocket hub the notification
+// is still stored and shows up the next time the user loads their notifications.
+func (s *NotificationService) sendPostNotification(ctx context.Context, userID, senderID string, postID int64, notificationType, messageFormat string) {
+	// Fetch sender details
+	sender, err := s.userRepo.GetByID(senderID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to fetch %s sender details: %v", notificationType, err)
+		return
+	}
+	senderName := sender.FirstName + " " + sender.LastName
+
+	// Create notification in database
+	notification := &notifications.NewNotification{
+		UserId:          userID,
+		NotficationType: notificationType,
+		SenderId:        sql.NullString{String: senderID, Valid: true},
+		Message:         fmt.Sprintf(messageFormat, senderName),
+		TargetPostID:    sql.NullInt64{Int64: postID, Valid: true},
+	}
+	if err := s.notificationSRVC.CreateNotification(ctx, notification); err != nil {
+		if !errors.Is(err, notifications.ErrSenderBlocked) {
+			s.log.WithContext(ctx).Error("Failed to create %s notification: %v", notificationType, err)
+		}
+		return
+	}
+
+	if s.hub == nil {
+		s.log.WithContext(ctx).Warn("WebSocket hub is nil, cannot push %s notification", notificationType)
+		return
+	}
+
+	// Retrieve the newly created notification to get its ID and CreatedAt
+	notifications, err := s.notificationSRVC.GetNotifications(ctx, userID, 1, 0)
+	if err != nil || len(notifications) == 0 {
+		s.log.WithContext(ctx).Error("Failed to retrieve newly created notification: %v", err)
+		return
+	}
+	dbNotification := notifications[0]
+
+	// Create WebSocket event
+	event := events.Event{
+		Type: events.HeaderNotificationUpdate,
+		Payload: map[string]interface{}{
+			"id":           dbNotification.ID,
+			"type":         notificationType,
+			"senderId":     senderID,
+			"senderName":   senderName,
+			"senderAvatar": sender.Avatar,
+			"targetPostId": postID,
+			"message":      notification.Message,
+			"createdAt":    dbNotification.CreatedAt.Format(time.RFC3339),
+			"isRead":       dbNotification.IsRead,
+		},
+	}
+
+	s.hub.BroadcastToUser(userID, event)
+}
+
+// NotifyCommentLiked sends a notification when a comment is liked or unliked
+func (s *NotificationService) NotifyCommentLiked(comment *models.Comment, userID string, userName string, isLiked bool, recipientIDs []string, isPublic bool) error {
+	event := events.Event{
+		Type: events.CommentLiked,
+		Payload: events.CommentLikedPayload{
+			CommentID:  comment.ID,
+			PostID:     comment.PostID,
+			UserID:     userID,
+			UserName:   userName,
+			IsLiked:    isLiked,
+			LikesCount: int(comment.LikesCount),
+		},
+	}
+
+	return s.sendToAudience(event, recipientIDs, isPublic)
+}
@@ -0,0 +1,24 @@
+package post
+
+import (
+	"database/sql"
+
+	"github.com/Athooh/social-network/pkg/db/uow"
+)
+
+// PostgresRepository implements Repository for PostgreSQL. It shares the post queries of
+// SQLiteRepository, including the explicit ids CreatePost assigns, which the identity
+// columns of the PostgreSQL schema accept.
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL post repository, db must be opened with postgres.New
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{&SQLiteRepository{db: uow.NewDB(db)}}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *PostgresRepository) WithTx(tx *uow.Tx) Repository {
+	return &PostgresRepository{r.withTx(tx)}
+}
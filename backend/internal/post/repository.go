@@ -4,9 +4,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Athooh/social-network/pkg/db/uow"
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/user"
 )
 
 // Repository defines the interface for post data access
@@ -15,28 +18,68 @@ type Repository interface {
 	GetPostByID(id int64) (*models.Post, error)
 	GetPostsByUserID(userID string) ([]*models.Post, error)
 	GetPublicPosts(limit, offset int) ([]*models.Post, error)
-	UpdatePost(post *models.Post) error
+	UpdatePost(post *models.Post, revision *models.PostRevision) error
 	DeletePost(id int64) error
 
 	// Privacy related methods
 	AddPostViewer(postID int64, userID string) error
 	RemovePostViewer(postID int64, userID string) error
+	ClearPostViewers(postID int64) error
 	GetPostViewers(postID int64) ([]string, error)
 	CanViewPost(postID int64, userID string) (bool, error)
+	GetPostAudience(postID int64) ([]string, error)
+
+	// Audience list methods
+	CreateAudienceList(list *models.AudienceList) error
+	GetAudienceList(id int64) (*models.AudienceList, error)
+	GetAudienceLists(ownerID string) ([]*models.AudienceList, error)
+	RenameAudienceList(id int64, name string) error
+	DeleteAudienceList(id int64) error
+	AddAudienceListMember(listID int64, userID string) error
+	RemoveAudienceListMember(listID int64, userID string) error
+	GetAudienceListMembers(listID int64) ([]string, error)
 
 	// Comment methods
 	CreateComment(comment *models.Comment) error
+	GetCommentByID(id int64) (*models.Comment, error)
+	UpdateComment(comment *models.Comment, revision *models.PostRevision) error
 	UpdatePostCommentCount(postId int64, increase bool) (int, error)
-	GetCommentsByPostID(postID int64) ([]*models.Comment, error)
+	GetCommentsByPostID(postID int64, viewerID string) ([]*models.Comment, error)
+	GetCommentReplies(parentID int64, viewerID string, limit, offset int) ([]*models.Comment, error)
 	DeleteComment(id int64) error
 
-	// Like-related methods
-	LikePost(postID int64, userID string) error
-	UnlikePost(postID int64, userID string) error
-	HasLiked(postID int64, userID string) (bool, error)
+	// Comment like methods
+	LikeComment(commentID int64, userID string) error
+	UnlikeComment(commentID int64, userID string) error
+	HasLikedComment(commentID int64, userID string) (bool, error)
+	GetCommentLikesCount(commentID int64) (int, error)
+
+	// Mention methods
+	GetUserIDsByNicknames(nicknames []string) ([]string, error)
+	GetGroupMemberIDsByNicknames(groupID string, nicknames []string) ([]string, error)
+
+	// Revision methods
+	GetRevisions(targetType string, targetID int64) ([]*models.PostRevision, error)
+	GetPostGroupID(postID int64) (string, error)
+	IsGroupPost(postID int64) (bool, error)
+	GetGroupMemberIDs(groupID string) ([]string, error)
+
+	// Reaction methods
+	SetReaction(postID int64, userID, reactionType string) (string, error)
+	RemoveReaction(postID int64, userID string) (string, error)
+	GetUserReaction(postID int64, userID string) (string, error)
+	GetReactionCounts(postID int64) (map[string]int, error)
+	GetReactors(postID int64, viewerID, reactionType string, limit, offset int) ([]*models.PostReactor, error)
 	GetLikesCount(postID int64) (int, error)
 	GetFeedPosts(userID string, limit, offset int) ([]*models.Post, error)
 
+	// Hashtag methods
+	SetPostTags(postID int64, tags []string, createdAt time.Time) error
+	GetPostsByTag(tag, userID string, limit, offset int) ([]*models.Post, error)
+	GetPublicTagUsage(since time.Time) ([]*models.PostTag, error)
+	ReplaceTrendingTopics(topics []*models.TrendingTopic) error
+	GetTrendingTopics(limit int) ([]*models.TrendingTopic, error)
+
 	// User data method
 	GetUserDataByID(userID string) (*models.PostUserData, error)
 
@@ -44,16 +87,28 @@ type Repository interface {
 	GetUserFollowers(userID string) ([]string, error)
 	UpdateUserStats(userID string, statsType string, increment bool) (int, error)
 	getNextAvailableID() (int64, error)
+
+	// WithTx binds the repository to a unit of work
+	WithTx(tx *uow.Tx) Repository
 }
 
 // SQLiteRepository implements Repository interface for SQLite
 type SQLiteRepository struct {
-	db *sql.DB
+	db *uow.DB
 }
 
 // NewSQLiteRepository creates a new SQLite repository
 func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
-	return &SQLiteRepository{db: db}
+	return &SQLiteRepository{db: uow.NewDB(db)}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *SQLiteRepository) WithTx(tx *uow.Tx) Repository {
+	return r.withTx(tx)
+}
+
+func (r *SQLiteRepository) withTx(tx *uow.Tx) *SQLiteRepository {
+	return &SQLiteRepository{db: r.db.WithTx(tx)}
 }
 
 // CreatePost creates a new post
@@ -66,12 +121,18 @@ func (r *SQLiteRepository) CreatePost(post *models.Post) error {
 		return err
 	}
 	post.ID = newid
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO posts (id, user_id, content, image_path, video_path, privacy, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO posts (id, user_id, content, image_path, video_path, privacy, shared_post_id, audience_list_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = r.db.Exec(
+	_, err = tx.Exec(
 		query,
 		post.ID,
 		post.UserID,
@@ -79,6 +140,8 @@ func (r *SQLiteRepository) CreatePost(post *models.Post) error {
 		post.ImagePath.String,
 		post.VideoPath.String,
 		post.Privacy,
+		post.SharedPostID,
+		post.AudienceListID,
 		post.CreatedAt,
 		post.UpdatedAt,
 	)
@@ -86,22 +149,36 @@ func (r *SQLiteRepository) CreatePost(post *models.Post) error {
 		return err
 	}
 
-	return nil
+	// Keep the reshare count with the original post
+	if post.SharedPostID.Valid {
+		_, err = tx.Exec(`
+			UPDATE posts
+			SET shares_count = shares_count + 1
+			WHERE id = ?
+		`, post.SharedPostID.Int64)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // GetPostByID retrieves a post by ID
 func (r *SQLiteRepository) GetPostByID(id int64) (*models.Post, error) {
 	query := `
-		SELECT id, user_id, content, image_path, video_path, privacy, likes_count, created_at, updated_at
+		SELECT id, user_id, content, image_path, video_path, privacy, likes_count, comments_count, is_edited, shared_post_id, shares_count, audience_list_id, created_at, updated_at
 		FROM (
-			SELECT id, user_id, content, image_path, video_path, privacy, likes_count, created_at, updated_at
+			SELECT id, user_id, content, image_path, video_path, privacy, likes_count, comments_count, is_edited, shared_post_id, shares_count, audience_list_id, created_at, updated_at
 			FROM posts
 			WHERE id = ?
 			UNION ALL
-			SELECT id, user_id, content, image_path, video_path, 'public' as privacy, likes_count, created_at, updated_at
+			SELECT id, user_id, content, image_path, video_path,
+			       CASE WHEN status = 'approved' THEN 'public' ELSE 'private' END as privacy,
+			       likes_count, comments_count, is_edited, NULL as shared_post_id, 0 as shares_count, NULL as audience_list_id, created_at, updated_at
 			FROM group_posts
 			WHERE id = ?
-		)
+		) AS p
 		LIMIT 1
 	`
 
@@ -116,6 +193,11 @@ func (r *SQLiteRepository) GetPostByID(id int64) (*models.Post, error) {
 		&videoPath,
 		&post.Privacy,
 		&post.LikesCount,
+		&post.CommentsCount,
+		&post.IsEdited,
+		&post.SharedPostID,
+		&post.SharesCount,
+		&post.AudienceListID,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 	)
@@ -140,7 +222,7 @@ func (r *SQLiteRepository) GetPostByID(id int64) (*models.Post, error) {
 // GetPostsByUserID retrieves all posts by a user
 func (r *SQLiteRepository) GetPostsByUserID(userID string) ([]*models.Post, error) {
 	query := `
-		SELECT id, user_id, content, image_path, video_path, privacy, likes_count, created_at, updated_at
+		SELECT id, user_id, content, image_path, video_path, privacy, likes_count, comments_count, is_edited, shared_post_id, shares_count, created_at, updated_at
 		FROM posts
 		WHERE user_id = ?
 		ORDER BY created_at DESC
@@ -164,6 +246,10 @@ func (r *SQLiteRepository) GetPostsByUserID(userID string) ([]*models.Post, erro
 			&videoPath,
 			&post.Privacy,
 			&post.LikesCount,
+			&post.CommentsCount,
+			&post.IsEdited,
+			&post.SharedPostID,
+			&post.SharesCount,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 		)
@@ -189,7 +275,7 @@ func (r *SQLiteRepository) GetPostsByUserID(userID string) ([]*models.Post, erro
 // GetPublicPosts retrieves public posts with pagination
 func (r *SQLiteRepository) GetPublicPosts(limit, offset int) ([]*models.Post, error) {
 	query := `
-		SELECT id, user_id, content, image_path, video_path, privacy, likes_count, created_at, updated_at
+		SELECT id, user_id, content, image_path, video_path, privacy, likes_count, comments_count, is_edited, shared_post_id, shares_count, created_at, updated_at
 		FROM posts
 		WHERE privacy = 'public'
 		ORDER BY created_at DESC
@@ -214,6 +300,10 @@ func (r *SQLiteRepository) GetPublicPosts(limit, offset int) ([]*models.Post, er
 			&videoPath,
 			&post.Privacy,
 			&post.LikesCount,
+			&post.CommentsCount,
+			&post.IsEdited,
+			&post.SharedPostID,
+			&post.SharesCount,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 		)
@@ -230,33 +320,117 @@ func (r *SQLiteRepository) GetPublicPosts(limit, offset int) ([]*models.Post, er
 	return posts, nil
 }
 
-// UpdatePost updates an existing post
-func (r *SQLiteRepository) UpdatePost(post *models.Post) error {
-	post.UpdatedAt = time.Now()
+// UpdatePost updates an existing post and stores its previous version as a revision
+func (r *SQLiteRepository) UpdatePost(post *models.Post, revision *models.PostRevision) error {
+	now := time.Now()
+	post.UpdatedAt = now
+	post.IsEdited = true
 
-	query := `
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
 		UPDATE posts
-		SET content = ?, image_path = ?, privacy = ?, updated_at = ?
+		SET content = ?, image_path = ?, video_path = ?, privacy = ?, audience_list_id = ?, is_edited = TRUE, updated_at = ?
 		WHERE id = ?
-	`
-
-	_, err := r.db.Exec(
-		query,
+	`,
 		post.Content,
-		post.ImagePath,
+		post.ImagePath.String,
+		post.VideoPath.String,
 		post.Privacy,
+		post.AudienceListID,
 		post.UpdatedAt,
 		post.ID,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("post not found")
+	}
+
+	if err := insertRevision(tx, revision, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertRevision stores a prior version of a post, group post or comment within a transaction
+func insertRevision(tx *uow.Tx, revision *models.PostRevision, createdAt time.Time) error {
+	revision.CreatedAt = createdAt
+
+	return tx.QueryRow(`
+		INSERT INTO post_revisions (target_type, target_id, editor_id, content, image_path, video_path, privacy, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`,
+		revision.TargetType,
+		revision.TargetID,
+		revision.EditorID,
+		revision.Content,
+		revision.ImagePath.String,
+		revision.VideoPath.String,
+		revision.Privacy,
+		revision.CreatedAt,
+	).Scan(&revision.ID)
 }
 
 // DeletePost deletes a post
 func (r *SQLiteRepository) DeletePost(id int64) error {
-	query := "DELETE FROM posts WHERE id = ?"
-	_, err := r.db.Exec(query, id)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM post_tags WHERE post_id = ?", id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM post_viewers WHERE post_id = ?", id); err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM post_revisions WHERE target_type = ? AND target_id = ?", models.RevisionTargetPost, id)
+	if err != nil {
+		return err
+	}
+
+	var sharedPostID sql.NullInt64
+	err = tx.QueryRow("DELETE FROM posts WHERE id = ? RETURNING shared_post_id", id).Scan(&sharedPostID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	// Reshares are kept and shown as unavailable, their reference to the original is
+	// tombstoned so it can't end up pointing at another post
+	_, err = tx.Exec("UPDATE posts SET shared_post_id = ? WHERE shared_post_id = ?", models.DeletedSharedPostID, id)
+	if err != nil {
+		return err
+	}
+
+	if sharedPostID.Valid {
+		_, err = tx.Exec(`
+			UPDATE posts
+			SET shares_count = shares_count - 1
+			WHERE id = ? AND shares_count > 0
+		`, sharedPostID.Int64)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // AddPostViewer adds a user who can view a private post
@@ -276,6 +450,12 @@ func (r *SQLiteRepository) RemovePostViewer(postID int64, userID string) error {
 	return err
 }
 
+// ClearPostViewers removes every explicit viewer of a post
+func (r *SQLiteRepository) ClearPostViewers(postID int64) error {
+	_, err := r.db.Exec("DELETE FROM post_viewers WHERE post_id = ?", postID)
+	return err
+}
+
 // GetPostViewers gets all users who can view a private post
 func (r *SQLiteRepository) GetPostViewers(postID int64) ([]string, error) {
 	query := "SELECT user_id FROM post_viewers WHERE post_id = ?"
@@ -317,6 +497,24 @@ func (r *SQLiteRepository) CanViewPost(postID int64, userID string) (bool, error
 		return true, nil
 	}
 
+	// Blocked users cannot see each other's posts whatever their privacy
+	blocked, err := user.IsBlocked(r.db, post.UserID, userID)
+	if err != nil {
+		return false, err
+	}
+	if blocked {
+		return false, nil
+	}
+
+	// Posts of deactivated accounts are hidden along with their profile
+	deactivated, err := user.IsDeactivated(r.db, post.UserID)
+	if err != nil {
+		return false, err
+	}
+	if deactivated {
+		return false, nil
+	}
+
 	// Check based on privacy setting
 	switch post.Privacy {
 	case models.PrivacyPublic:
@@ -331,180 +529,862 @@ func (r *SQLiteRepository) CanViewPost(postID int64, userID string) (bool, error
 		}
 		return count > 0, nil
 	case models.PrivacyPrivate:
-		// Check if the user is in the post_viewers table
-		query := "SELECT COUNT(*) FROM post_viewers WHERE post_id = ? AND user_id = ?"
-		var count int
-		err := r.db.QueryRow(query, postID, userID).Scan(&count)
+		// Check if the user was picked as a viewer or is in the post's audience list
+		query := `
+			SELECT EXISTS (SELECT 1 FROM post_viewers WHERE post_id = ? AND user_id = ?)
+				OR EXISTS (SELECT 1 FROM audience_list_members WHERE list_id = ? AND user_id = ?)
+		`
+		var canView bool
+		err := r.db.QueryRow(query, postID, userID, post.AudienceListID, userID).Scan(&canView)
 		if err != nil {
 			return false, err
 		}
-		return count > 0, nil
+		return canView, nil
 	default:
 		return false, errors.New("invalid privacy setting")
 	}
 }
 
-// CreateComment creates a new comment
-func (r *SQLiteRepository) CreateComment(comment *models.Comment) error {
-	now := time.Now()
-	comment.CreatedAt = now
-	comment.UpdatedAt = now
+// audienceListsOfUserSQL selects the audience lists the user bound to its placeholder is in
+const audienceListsOfUserSQL = `SELECT list_id FROM audience_list_members WHERE user_id = ?`
+
+// GetPostAudience gets the users a private post is shared with, picked one by one or
+// through its audience list
+func (r *SQLiteRepository) GetPostAudience(postID int64) ([]string, error) {
+	return r.queryUserIDs(`
+		SELECT user_id FROM post_viewers WHERE post_id = ?
+		UNION
+		SELECT m.user_id
+		FROM audience_list_members m
+		JOIN posts p ON p.audience_list_id = m.list_id
+		WHERE p.id = ?
+	`, postID, postID)
+}
+
+// CreateAudienceList creates an empty audience list
+func (r *SQLiteRepository) CreateAudienceList(list *models.AudienceList) error {
+	list.CreatedAt = time.Now().UTC()
+
+	return r.db.QueryRow(`
+		INSERT INTO audience_lists (owner_id, name, created_at)
+		VALUES (?, ?, ?)
+		RETURNING id
+	`, list.OwnerID, list.Name, list.CreatedAt).Scan(&list.ID)
+}
+
+// GetAudienceList retrieves an audience list without its members, nil if it doesn't exist
+func (r *SQLiteRepository) GetAudienceList(id int64) (*models.AudienceList, error) {
+	list := &models.AudienceList{}
+	err := r.db.QueryRow(`
+		SELECT id, owner_id, name, created_at
+		FROM audience_lists
+		WHERE id = ?
+	`, id).Scan(&list.ID, &list.OwnerID, &list.Name, &list.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetAudienceLists retrieves the audience lists of a user, with their members
+func (r *SQLiteRepository) GetAudienceLists(ownerID string) ([]*models.AudienceList, error) {
+	rows, err := r.db.Query(`
+		SELECT id, owner_id, name, created_at
+		FROM audience_lists
+		WHERE owner_id = ?
+		ORDER BY name
+	`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []*models.AudienceList
+	for rows.Next() {
+		list := &models.AudienceList{}
+		if err := rows.Scan(&list.ID, &list.OwnerID, &list.Name, &list.CreatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, list := range lists {
+		memberIDs, err := r.GetAudienceListMembers(list.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		list.Members = make([]*models.PostUserData, 0, len(memberIDs))
+		for _, id := range memberIDs {
+			member, err := r.GetUserDataByID(id)
+			if err != nil {
+				return nil, err
+			}
+			if member != nil {
+				list.Members = append(list.Members, member)
+			}
+		}
+	}
+
+	return lists, nil
+}
+
+// RenameAudienceList changes the name of an audience list
+func (r *SQLiteRepository) RenameAudienceList(id int64, name string) error {
+	_, err := r.db.Exec("UPDATE audience_lists SET name = ? WHERE id = ?", name, id)
+	return err
+}
+
+// DeleteAudienceList deletes an audience list. Posts shared with it stay private and
+// are left with their individually picked viewers.
+func (r *SQLiteRepository) DeleteAudienceList(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("UPDATE posts SET audience_list_id = NULL WHERE audience_list_id = ?", id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM audience_list_members WHERE list_id = ?", id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM audience_lists WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddAudienceListMember adds a user to an audience list, doing nothing if they are in it
+func (r *SQLiteRepository) AddAudienceListMember(listID int64, userID string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO audience_list_members (list_id, user_id, added_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (list_id, user_id) DO NOTHING
+	`, listID, userID, time.Now().UTC())
+	return err
+}
+
+// RemoveAudienceListMember removes a user from an audience list
+func (r *SQLiteRepository) RemoveAudienceListMember(listID int64, userID string) error {
+	_, err := r.db.Exec("DELETE FROM audience_list_members WHERE list_id = ? AND user_id = ?", listID, userID)
+	return err
+}
+
+// GetAudienceListMembers gets the IDs of the users in an audience list
+func (r *SQLiteRepository) GetAudienceListMembers(listID int64) ([]string, error) {
+	return r.queryUserIDs("SELECT user_id FROM audience_list_members WHERE list_id = ? ORDER BY added_at", listID)
+}
+
+// queryUserIDs runs a query selecting a single user ID column
+func (r *SQLiteRepository) queryUserIDs(query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// CreateComment creates a new comment and, for replies, increments the parent's reply count
+func (r *SQLiteRepository) CreateComment(comment *models.Comment) error {
+	now := time.Now()
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO comments (post_id, user_id, parent_id, depth, content, image_path, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+
+	err = tx.QueryRow(
+		query,
+		comment.PostID,
+		comment.UserID,
+		comment.ParentID,
+		comment.Depth,
+		comment.Content,
+		comment.ImagePath.String,
+		comment.CreatedAt,
+		comment.UpdatedAt,
+	).Scan(&comment.ID)
+	if err != nil {
+		return err
+	}
+
+	if comment.ParentID.Valid {
+		_, err = tx.Exec(`
+			UPDATE comments
+			SET replies_count = replies_count + 1
+			WHERE id = ?
+		`, comment.ParentID.Int64)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+const commentColumns = `id, post_id, user_id, parent_id, depth, content, image_path, is_edited, likes_count, replies_count, created_at, updated_at`
+
+// scanComment scans a row selected with commentColumns
+func scanComment(scanner interface{ Scan(...interface{}) error }) (*models.Comment, error) {
+	comment := &models.Comment{}
+	var imagePath sql.NullString
+
+	err := scanner.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.ParentID,
+		&comment.Depth,
+		&comment.Content,
+		&imagePath,
+		&comment.IsEdited,
+		&comment.LikesCount,
+		&comment.RepliesCount,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if imagePath.Valid {
+		comment.ImagePath = imagePath
+	}
+
+	return comment, nil
+}
+
+// queryComments runs a comment query and scans every row
+func (r *SQLiteRepository) queryComments(query string, args ...interface{}) ([]*models.Comment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*models.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// GetCommentsByPostID retrieves the top-level comments for a post, replies are loaded with GetCommentReplies.
+// Comments by users the viewer blocked or was blocked by are left out.
+func (r *SQLiteRepository) GetCommentsByPostID(postID int64, viewerID string) ([]*models.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE post_id = ? AND parent_id IS NULL
+		AND user_id NOT IN (` + user.BlockedUsersSQL + `)
+		ORDER BY created_at DESC
+	`
+
+	return r.queryComments(query, postID, viewerID, viewerID)
+}
+
+// GetCommentReplies retrieves a page of direct replies to a comment, oldest first, without
+// the replies of users blocked either way by the viewer
+func (r *SQLiteRepository) GetCommentReplies(parentID int64, viewerID string, limit, offset int) ([]*models.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE parent_id = ?
+		AND user_id NOT IN (` + user.BlockedUsersSQL + `)
+		ORDER BY created_at ASC
+		LIMIT ? OFFSET ?
+	`
+
+	return r.queryComments(query, parentID, viewerID, viewerID, limit, offset)
+}
+
+// GetCommentByID retrieves a comment by ID
+func (r *SQLiteRepository) GetCommentByID(id int64) (*models.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE id = ?
+	`
+
+	comment, err := scanComment(r.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// UpdateComment updates a comment and stores its previous version as a revision
+func (r *SQLiteRepository) UpdateComment(comment *models.Comment, revision *models.PostRevision) error {
+	now := time.Now()
+	comment.UpdatedAt = now
+	comment.IsEdited = true
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE comments
+		SET content = ?, image_path = ?, is_edited = TRUE, updated_at = ?
+		WHERE id = ?
+	`,
+		comment.Content,
+		comment.ImagePath.String,
+		comment.UpdatedAt,
+		comment.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := insertRevision(tx, revision, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetRevisions retrieves the stored revisions of a post, group post or comment, newest first
+func (r *SQLiteRepository) GetRevisions(targetType string, targetID int64) ([]*models.PostRevision, error) {
+	query := `
+		SELECT id, target_type, target_id, editor_id, content, image_path, video_path, privacy, created_at
+		FROM post_revisions
+		WHERE target_type = ? AND target_id = ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(query, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*models.PostRevision
+	for rows.Next() {
+		revision := &models.PostRevision{}
+		var imagePath, videoPath, privacy sql.NullString
+		err := rows.Scan(
+			&revision.ID,
+			&revision.TargetType,
+			&revision.TargetID,
+			&revision.EditorID,
+			&revision.Content,
+			&imagePath,
+			&videoPath,
+			&privacy,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if imagePath.Valid {
+			revision.ImagePath = imagePath
+		}
+		if videoPath.Valid {
+			revision.VideoPath = videoPath
+		}
+		revision.Privacy = privacy.String
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetPostGroupID gets the group a post belongs to, empty for posts outside groups
+func (r *SQLiteRepository) GetPostGroupID(postID int64) (string, error) {
+	var groupID string
+	err := r.db.QueryRow("SELECT group_id FROM group_posts WHERE id = ?", postID).Scan(&groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return groupID, err
+}
+
+// IsGroupPost checks if a post ID belongs to a group post
+func (r *SQLiteRepository) IsGroupPost(postID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM group_posts WHERE id = ?)", postID).Scan(&exists)
+	return exists, err
+}
+
+// DeleteComment deletes a comment
+func (r *SQLiteRepository) DeleteComment(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var postID int64
+	var parentID sql.NullInt64
+	err = tx.QueryRow("SELECT post_id, parent_id FROM comments WHERE id = ?", id).Scan(&postID, &parentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("comment not found")
+		}
+		return err
+	}
+
+	// Collect the comment together with all of its nested replies
+	rows, err := tx.Query(`
+		WITH RECURSIVE thread(id) AS (
+			SELECT id FROM comments WHERE id = ?
+			UNION ALL
+			SELECT c.id FROM comments c JOIN thread t ON c.parent_id = t.id
+		)
+		SELECT id FROM thread
+	`, id)
+	if err != nil {
+		return err
+	}
+	var ids []interface{}
+	for rows.Next() {
+		var commentID int64
+		if err := rows.Scan(&commentID); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, commentID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	if _, err = tx.Exec("DELETE FROM comment_likes WHERE comment_id IN ("+placeholders+")", ids...); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM comments WHERE id IN ("+placeholders+")", ids...); err != nil {
+		return err
+	}
+
+	if parentID.Valid {
+		_, err = tx.Exec(`
+			UPDATE comments
+			SET replies_count = replies_count - 1
+			WHERE id = ? AND replies_count > 0
+		`, parentID.Int64)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Keep the post's comment count in step with the number of removed comments
+	for _, table := range []string{"posts", "group_posts"} {
+		_, err = tx.Exec(`
+			UPDATE `+table+`
+			SET comments_count = CASE WHEN comments_count > ? THEN comments_count - ? ELSE 0 END
+			WHERE id = ?
+		`, len(ids), len(ids), postID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LikeComment adds a like to a comment and increments its likes count, doing nothing if
+// the user already liked it
+func (r *SQLiteRepository) LikeComment(commentID int64, userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Insert like, a concurrent like by the same user already counted
+	result, err := tx.Exec(`
+		INSERT INTO comment_likes (comment_id, user_id)
+		VALUES (?, ?)
+		ON CONFLICT (comment_id, user_id) DO NOTHING
+	`, commentID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 1 {
+		return err
+	}
+
+	// Increment likes count
+	_, err = tx.Exec(`
+		UPDATE comments
+		SET likes_count = likes_count + 1
+		WHERE id = ?
+	`, commentID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnlikeComment removes a like from a comment and decrements its likes count
+func (r *SQLiteRepository) UnlikeComment(commentID int64, userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Remove like
+	result, err := tx.Exec(`
+		DELETE FROM comment_likes
+		WHERE comment_id = ? AND user_id = ?
+	`, commentID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected > 0 {
+		// Decrement likes count if a like was actually removed
+		_, err = tx.Exec(`
+			UPDATE comments
+			SET likes_count = likes_count - 1
+			WHERE id = ? AND likes_count > 0
+		`, commentID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// HasLikedComment checks if a user has liked a comment
+func (r *SQLiteRepository) HasLikedComment(commentID int64, userID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM comment_likes
+			WHERE comment_id = ? AND user_id = ?
+		)
+	`, commentID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetCommentLikesCount gets the number of likes for a comment
+func (r *SQLiteRepository) GetCommentLikesCount(commentID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COALESCE((SELECT likes_count FROM comments WHERE id = ?), 0)
+	`, commentID).Scan(&count)
+	return count, err
+}
+
+// GetUserIDsByNicknames resolves nicknames (case-insensitive) to user IDs
+func (r *SQLiteRepository) GetUserIDsByNicknames(nicknames []string) ([]string, error) {
+	if len(nicknames) == 0 {
+		return nil, nil
+	}
 
-	query := `
-		INSERT INTO comments (post_id, user_id, content, image_path, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-		RETURNING id
-	`
+	args := make([]interface{}, len(nicknames))
+	for i, nickname := range nicknames {
+		args[i] = strings.ToLower(nickname)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(nicknames)), ",")
 
-	err := r.db.QueryRow(
-		query,
-		comment.PostID,
-		comment.UserID,
-		comment.Content,
-		comment.ImagePath.String,
-		comment.CreatedAt,
-		comment.UpdatedAt,
-	).Scan(&comment.ID)
+	rows, err := r.db.Query(`
+		SELECT id FROM users
+		WHERE LOWER(nickname) IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return err
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
 }
 
-// GetCommentsByPostID retrieves all comments for a post
-func (r *SQLiteRepository) GetCommentsByPostID(postID int64) ([]*models.Comment, error) {
-	query := `
-		SELECT id, post_id, user_id, content, image_path, created_at, updated_at
-		FROM comments
-		WHERE post_id = ?
-		ORDER BY created_at DESC
-	`
+// GetGroupMemberIDsByNicknames resolves nicknames (case-insensitive) to the IDs of users
+// who are accepted members of the group
+func (r *SQLiteRepository) GetGroupMemberIDsByNicknames(groupID string, nicknames []string) ([]string, error) {
+	if len(nicknames) == 0 {
+		return nil, nil
+	}
 
-	rows, err := r.db.Query(query, postID)
+	args := []interface{}{groupID}
+	for _, nickname := range nicknames {
+		args = append(args, strings.ToLower(nickname))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(nicknames)), ",")
+
+	rows, err := r.db.Query(`
+		SELECT u.id FROM users u
+		JOIN group_members gm ON gm.user_id = u.id
+		WHERE gm.group_id = ? AND gm.status = 'accepted'
+		AND LOWER(u.nickname) IN (`+placeholders+`)
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var comments []*models.Comment
-	var imagePath sql.NullString
+	var userIDs []string
 	for rows.Next() {
-		comment := &models.Comment{}
-		err := rows.Scan(
-			&comment.ID,
-			&comment.PostID,
-			&comment.UserID,
-			&comment.Content,
-			&imagePath,
-			&comment.CreatedAt,
-			&comment.UpdatedAt,
-		)
-		if err != nil {
+		var id string
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		if imagePath.Valid {
-			comment.ImagePath.String = imagePath.String
-		}
-		comments = append(comments, comment)
+		userIDs = append(userIDs, id)
 	}
 
-	if err = rows.Err(); err != nil {
+	return userIDs, rows.Err()
+}
+
+// GetGroupMemberIDs returns the IDs of the accepted members of a group
+func (r *SQLiteRepository) GetGroupMemberIDs(groupID string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id FROM group_members
+		WHERE group_id = ? AND status = 'accepted'
+	`, groupID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return comments, nil
-}
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
 
-// DeleteComment deletes a comment
-func (r *SQLiteRepository) DeleteComment(id int64) error {
-	query := "DELETE FROM comments WHERE id = ?"
-	_, err := r.db.Exec(query, id)
-	return err
+	return userIDs, rows.Err()
 }
 
-// LikePost adds a like to a post and increments the likes count
-func (r *SQLiteRepository) LikePost(postID int64, userID string) error {
+// SetReaction adds or changes a user's reaction on a post and returns the previous reaction, if any.
+// The likes count holds the total number of reactions so it only changes for new reactions.
+func (r *SQLiteRepository) SetReaction(postID int64, userID, reactionType string) (string, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer tx.Rollback()
 
-	// Insert like
+	var previous string
+	err = tx.QueryRow(`
+		SELECT reaction_type FROM post_likes
+		WHERE post_id = ? AND user_id = ?
+	`, postID, userID).Scan(&previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	if previous != "" {
+		// Change the existing reaction
+		_, err = tx.Exec(`
+			UPDATE post_likes
+			SET reaction_type = ?
+			WHERE post_id = ? AND user_id = ?
+		`, reactionType, postID, userID)
+		if err != nil {
+			return "", err
+		}
+		return previous, tx.Commit()
+	}
+
+	// Insert reaction
 	_, err = tx.Exec(`
-		INSERT INTO post_likes (post_id, user_id)
-		VALUES (?, ?)
-	`, postID, userID)
+		INSERT INTO post_likes (post_id, user_id, reaction_type)
+		VALUES (?, ?, ?)
+	`, postID, userID, reactionType)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Increment likes count in both tables
-	_, err = tx.Exec(`
-		UPDATE posts 
-		SET likes_count = likes_count + 1 
-		WHERE id = ?;
-
-		UPDATE group_posts 
-		SET likes_count = likes_count + 1 
-		WHERE id = ?;
-	`, postID, postID)
-	if err != nil {
-		return err
+	for _, table := range []string{"posts", "group_posts"} {
+		_, err = tx.Exec("UPDATE "+table+" SET likes_count = likes_count + 1 WHERE id = ?", postID)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	return tx.Commit()
+	return "", tx.Commit()
 }
 
-// UnlikePost removes a like from a post and decrements the likes count
-func (r *SQLiteRepository) UnlikePost(postID int64, userID string) error {
+// RemoveReaction removes a user's reaction from a post and returns the removed reaction, if any
+func (r *SQLiteRepository) RemoveReaction(postID int64, userID string) (string, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer tx.Rollback()
 
-	// Remove like
-	result, err := tx.Exec(`
+	var previous string
+	err = tx.QueryRow(`
 		DELETE FROM post_likes 
 		WHERE post_id = ? AND user_id = ?
-	`, postID, userID)
+		RETURNING reaction_type
+	`, postID, userID).Scan(&previous)
 	if err != nil {
-		return err
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	// Decrement likes count in both tables since a reaction was actually removed
+	for _, table := range []string{"posts", "group_posts"} {
+		_, err = tx.Exec("UPDATE "+table+" SET likes_count = likes_count - 1 WHERE id = ? AND likes_count > 0", postID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return previous, tx.Commit()
+}
+
+// GetUserReaction gets a user's reaction on a post, empty if they haven't reacted
+func (r *SQLiteRepository) GetUserReaction(postID int64, userID string) (string, error) {
+	var reactionType string
+	err := r.db.QueryRow(`
+		SELECT reaction_type FROM post_likes 
+		WHERE post_id = ? AND user_id = ?
+	`, postID, userID).Scan(&reactionType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return reactionType, err
+}
+
+// GetReactionCounts gets the number of reactions of each type on a post
+func (r *SQLiteRepository) GetReactionCounts(postID int64) (map[string]int, error) {
+	rows, err := r.db.Query(`
+		SELECT reaction_type, COUNT(*)
+		FROM post_likes
+		WHERE post_id = ?
+		GROUP BY reaction_type
+	`, postID)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	if rowsAffected > 0 {
-		// Decrement likes count in both tables if a like was actually removed
-		_, err = tx.Exec(`
-			UPDATE posts 
-			SET likes_count = likes_count - 1 
-			WHERE id = ? AND likes_count > 0;
-
-			UPDATE group_posts 
-			SET likes_count = likes_count - 1 
-			WHERE id = ? AND likes_count > 0;
-		`, postID, postID)
-		if err != nil {
-			return err
+	counts := make(map[string]int, len(models.ReactionTypes))
+	for _, reactionType := range models.ReactionTypes {
+		counts[reactionType] = 0
+	}
+
+	for rows.Next() {
+		var reactionType string
+		var count int
+		if err := rows.Scan(&reactionType, &count); err != nil {
+			return nil, err
 		}
+		counts[reactionType] = count
 	}
 
-	return tx.Commit()
+	return counts, rows.Err()
 }
 
-// HasLiked checks if a user has liked a post
-func (r *SQLiteRepository) HasLiked(postID int64, userID string) (bool, error) {
-	var exists bool
-	err := r.db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM post_likes 
-			WHERE post_id = ? AND user_id = ?
+// GetReactors gets the users who reacted to a post, newest first, optionally filtered by reaction type.
+// Users blocked either way by the viewer are not listed.
+func (r *SQLiteRepository) GetReactors(postID int64, viewerID, reactionType string, limit, offset int) ([]*models.PostReactor, error) {
+	query := `
+		SELECT u.id, u.first_name, u.last_name, u.avatar, pl.reaction_type, pl.created_at
+		FROM post_likes pl
+		JOIN users u ON u.id = pl.user_id
+		WHERE pl.post_id = ? AND (? = '' OR pl.reaction_type = ?)
+		AND pl.user_id NOT IN (` + user.BlockedUsersSQL + `)
+		ORDER BY pl.created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, postID, reactionType, reactionType, viewerID, viewerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reactors []*models.PostReactor
+	for rows.Next() {
+		reactor := &models.PostReactor{User: &models.PostUserData{}}
+		var avatar sql.NullString
+		err := rows.Scan(
+			&reactor.User.ID,
+			&reactor.User.FirstName,
+			&reactor.User.LastName,
+			&avatar,
+			&reactor.ReactionType,
+			&reactor.CreatedAt,
 		)
-	`, postID, userID).Scan(&exists)
-	return exists, err
+		if err != nil {
+			return nil, err
+		}
+		reactor.User.Avatar = avatar.String
+		reactors = append(reactors, reactor)
+	}
+
+	return reactors, rows.Err()
 }
 
 // GetLikesCount gets the number of likes for a post
@@ -523,20 +1403,27 @@ func (r *SQLiteRepository) GetLikesCount(postID int64) (int, error) {
 // GetFeedPosts gets posts visible to the user
 func (r *SQLiteRepository) GetFeedPosts(userID string, limit, offset int) ([]*models.Post, error) {
 	query := `
-		SELECT DISTINCT p.* 
+		SELECT DISTINCT p.id, p.user_id, p.content, p.image_path, p.video_path, p.privacy,
+			p.likes_count, p.comments_count, p.is_edited, p.shared_post_id, p.shares_count, p.created_at, p.updated_at
 		FROM posts p
 		LEFT JOIN followers f ON p.user_id = f.following_id
 		LEFT JOIN post_viewers pv ON p.id = pv.post_id
 		WHERE 
-			p.privacy = 'public'
-			OR p.user_id = ?
-			OR (p.privacy = 'almost_private' AND f.follower_id = ?)
-			OR (p.privacy = 'private' AND pv.user_id = ?)
+			(
+				p.privacy = 'public'
+				OR p.user_id = ?
+				OR (p.privacy = 'almost_private' AND f.follower_id = ?)
+				OR (p.privacy = 'private' AND pv.user_id = ?)
+				OR (p.privacy = 'private' AND p.audience_list_id IN (`+audienceListsOfUserSQL+`))
+			)
+			AND p.user_id NOT IN (`+user.BlockedUsersSQL+`)
+			AND p.user_id NOT IN (`+user.MutedUsersSQL+`)
+			AND p.user_id NOT IN (`+user.DeactivatedUsersSQL+`)
 		ORDER BY p.created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.Query(query, userID, userID, userID, limit, offset)
+	rows, err := r.db.Query(query, userID, userID, userID, userID, userID, userID, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -557,6 +1444,9 @@ func (r *SQLiteRepository) GetFeedPosts(userID string, limit, offset int) ([]*mo
 			&post.Privacy,
 			&post.LikesCount,
 			&post.CommentsCount,
+			&post.IsEdited,
+			&post.SharedPostID,
+			&post.SharesCount,
 			&post.CreatedAt,
 			&post.UpdatedAt,
 		)
@@ -584,6 +1474,182 @@ func (r *SQLiteRepository) GetFeedPosts(userID string, limit, offset int) ([]*mo
 	return posts, rows.Err()
 }
 
+// SetPostTags replaces the hashtags indexed for a post
+func (r *SQLiteRepository) SetPostTags(postID int64, tags []string, createdAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM post_tags WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.Exec(`
+			INSERT INTO post_tags (post_id, tag, created_at)
+			VALUES (?, ?, ?)
+		`, postID, tag, createdAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPostsByTag gets the posts tagged with a hashtag that the user can view
+func (r *SQLiteRepository) GetPostsByTag(tag, userID string, limit, offset int) ([]*models.Post, error) {
+	query := `
+		SELECT DISTINCT p.id, p.user_id, p.content, p.image_path, p.video_path, p.privacy,
+			p.likes_count, p.comments_count, p.is_edited, p.shared_post_id, p.shares_count, p.created_at, p.updated_at
+		FROM post_tags pt
+		JOIN posts p ON p.id = pt.post_id
+		LEFT JOIN followers f ON p.user_id = f.following_id AND f.follower_id = ?
+		LEFT JOIN post_viewers pv ON p.id = pv.post_id AND pv.user_id = ?
+		WHERE pt.tag = ?
+			AND (
+				p.privacy = 'public'
+				OR p.user_id = ?
+				OR (p.privacy = 'almost_private' AND f.follower_id IS NOT NULL)
+				OR (p.privacy = 'private' AND pv.user_id IS NOT NULL)
+				OR (p.privacy = 'private' AND p.audience_list_id IN (`+audienceListsOfUserSQL+`))
+			)
+			AND p.user_id NOT IN (`+user.BlockedUsersSQL+`)
+			AND p.user_id NOT IN (`+user.MutedUsersSQL+`)
+			AND p.user_id NOT IN (`+user.DeactivatedUsersSQL+`)
+		ORDER BY p.created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, userID, userID, tag, userID, userID, userID, userID, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post := &models.Post{}
+		var imagePath, videoPath sql.NullString
+
+		err := rows.Scan(
+			&post.ID,
+			&post.UserID,
+			&post.Content,
+			&imagePath,
+			&videoPath,
+			&post.Privacy,
+			&post.LikesCount,
+			&post.CommentsCount,
+			&post.IsEdited,
+			&post.SharedPostID,
+			&post.SharesCount,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if imagePath.Valid {
+			post.ImagePath = imagePath
+		}
+
+		if videoPath.Valid {
+			post.VideoPath = videoPath
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// GetPublicTagUsage gets the hashtags used in public posts created since the given time.
+// Only public posts are counted so trends never reveal private content.
+func (r *SQLiteRepository) GetPublicTagUsage(since time.Time) ([]*models.PostTag, error) {
+	query := `
+		SELECT pt.id, pt.post_id, pt.tag, pt.created_at
+		FROM post_tags pt
+		JOIN posts p ON p.id = pt.post_id
+		WHERE p.privacy = 'public' AND pt.created_at >= ?
+	`
+
+	rows, err := r.db.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []*models.PostTag
+	for rows.Next() {
+		postTag := &models.PostTag{}
+		if err := rows.Scan(&postTag.ID, &postTag.PostID, &postTag.Tag, &postTag.CreatedAt); err != nil {
+			return nil, err
+		}
+		usage = append(usage, postTag)
+	}
+
+	return usage, rows.Err()
+}
+
+// ReplaceTrendingTopics replaces the stored trending topics with a freshly computed set
+func (r *SQLiteRepository) ReplaceTrendingTopics(topics []*models.TrendingTopic) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM trending_topics"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, topic := range topics {
+		topic.UpdatedAt = now
+		err = tx.QueryRow(`
+			INSERT INTO trending_topics (tag, score, posts_count, updated_at)
+			VALUES (?, ?, ?, ?)
+			RETURNING id
+		`, topic.Tag, topic.Score, topic.PostsCount, topic.UpdatedAt).Scan(&topic.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetTrendingTopics gets the highest scoring trending topics
+func (r *SQLiteRepository) GetTrendingTopics(limit int) ([]*models.TrendingTopic, error) {
+	query := `
+		SELECT id, tag, score, posts_count, updated_at
+		FROM trending_topics
+		ORDER BY score DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var topics []*models.TrendingTopic
+	for rows.Next() {
+		topic := &models.TrendingTopic{}
+		if err := rows.Scan(&topic.ID, &topic.Tag, &topic.Score, &topic.PostsCount, &topic.UpdatedAt); err != nil {
+			return nil, err
+		}
+		topics = append(topics, topic)
+	}
+
+	return topics, rows.Err()
+}
+
 // GetUserDataByID retrieves user data by ID
 func (r *SQLiteRepository) GetUserDataByID(userID string) (*models.PostUserData, error) {
 	query := `
@@ -850,30 +1916,14 @@ func (r *SQLiteRepository) updateUserStatsWithValue(userID string, statsType str
 	return value, nil
 }
 
-// getNextAvailableID checks 'posts' and 'group_posts' for max(id) and returns next available int64 ID.
+// getNextAvailableID reserves the id of a new post or group post. Ids come from the
+// post_keys sequence rather than the largest id in use, so the id of a deleted post is
+// never handed out again while reshares, likes or comments may still refer to it.
 func (r *SQLiteRepository) getNextAvailableID() (int64, error) {
-    var maxPostID, maxGroupPostID sql.NullInt64
-
-    // Query max id from 'posts'
-    err := r.db.QueryRow("SELECT MAX(id) FROM posts").Scan(&maxPostID)
-    if err != nil {
-        return 0, fmt.Errorf("error querying posts: %v", err)
-    }
-
-    // Query max id from 'group_posts'
-    err = r.db.QueryRow("SELECT MAX(id) FROM group_posts").Scan(&maxGroupPostID)
-    if err != nil {
-        return 0, fmt.Errorf("error querying group_posts: %v", err)
-    }
-
-    // Calculate the next ID
-    maxID := int64(0)
-    if maxPostID.Valid && maxPostID.Int64 > maxID {
-        maxID = maxPostID.Int64
-    }
-    if maxGroupPostID.Valid && maxGroupPostID.Int64 > maxID {
-        maxID = maxGroupPostID.Int64
-    }
-
-    return maxID + 1, nil
-}
\ No newline at end of file
+	var id int64
+	err := r.db.QueryRow("INSERT INTO post_keys DEFAULT VALUES RETURNING id").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error reserving post id: %v", err)
+	}
+	return id, nil
+}
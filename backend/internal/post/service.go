@@ -1,62 +1,92 @@
 package post
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"mime/multipart"
-	"strconv"
+	"strings"
 
+	"github.com/Athooh/social-network/internal/group"
+	"github.com/Athooh/social-network/pkg/db/uow"
 	"github.com/Athooh/social-network/pkg/filestore"
 	"github.com/Athooh/social-network/pkg/logger"
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/utils"
+	"github.com/Athooh/social-network/pkg/websocket/events"
 )
 
 // Service defines the post service interface
 type Service interface {
-	CreatePost(userID string, content, privacy string, image, video *multipart.FileHeader) (*models.Post, error)
-	GetPost(postID int64, userID string) (*models.Post, error)
-	GetUserPosts(userID, viewerID string) ([]*models.Post, error)
-	GetPublicPosts(limit, offset int) ([]*models.Post, error)
-	UpdatePost(postID int64, userID string, content, privacy string, image, video *multipart.FileHeader) (*models.Post, error)
-	DeletePost(postID int64, userID string) error
+	CreatePost(ctx context.Context, userID string, content, privacy string, viewerIDs []string, audienceListID int64, image, video *multipart.FileHeader) (*models.Post, error)
+	GetPost(ctx context.Context, postID int64, userID string) (*models.Post, error)
+	GetUserPosts(ctx context.Context, userID, viewerID string) ([]*models.Post, error)
+	GetPublicPosts(ctx context.Context, limit, offset int) ([]*models.Post, error)
+	UpdatePost(ctx context.Context, postID int64, userID string, content, privacy string, viewerIDs []string, audienceListID *int64, image, video *multipart.FileHeader) (*models.Post, error)
+	DeletePost(ctx context.Context, postID int64, userID string) error
+	GetPostRevisions(ctx context.Context, postID int64, userID string) ([]*models.PostRevision, error)
+	SharePost(ctx context.Context, postID int64, userID string, content, privacy string, viewerIDs []string, audienceListID int64) (*models.Post, error)
+
+	// Hashtags
+	GetTagPosts(ctx context.Context, tag, userID string, page, pageSize int) ([]*models.Post, error)
+	GetTrendingTopics(ctx context.Context, limit int) ([]*models.TrendingTopic, error)
 
 	// Privacy management
-	SetPostViewers(postID int64, userID string, viewerIDs []string) error
+	SetPostViewers(ctx context.Context, postID int64, userID string, viewerIDs []string) error
+
+	// Audience lists
+	CreateAudienceList(ctx context.Context, userID, name string, memberIDs []string) (*models.AudienceList, error)
+	GetAudienceLists(ctx context.Context, userID string) ([]*models.AudienceList, error)
+	RenameAudienceList(ctx context.Context, userID string, listID int64, name string) error
+	DeleteAudienceList(ctx context.Context, userID string, listID int64) error
+	AddAudienceListMembers(ctx context.Context, userID string, listID int64, memberIDs []string) error
+	RemoveAudienceListMember(ctx context.Context, userID string, listID int64, memberID string) error
 
 	// Comments
-	CreateComment(postID int64, userID string, content string, image *multipart.FileHeader) (*models.Comment, error)
-	GetPostComments(postID int64, userID string) ([]*models.Comment, error)
-	DeleteComment(commentID int64, userID, postid string) error
+	CreateComment(ctx context.Context, postID int64, userID string, parentID int64, content string, image *multipart.FileHeader) (*models.Comment, error)
+	GetPostComments(ctx context.Context, postID int64, userID string) ([]*models.Comment, error)
+	GetCommentReplies(ctx context.Context, commentID int64, userID string, limit, offset int) ([]*models.Comment, error)
+	UpdateComment(ctx context.Context, commentID int64, userID string, content string, image *multipart.FileHeader) (*models.Comment, error)
+	DeleteComment(ctx context.Context, commentID int64, userID, postid string) error
+	GetCommentRevisions(ctx context.Context, commentID int64, userID string) ([]*models.PostRevision, error)
 
 	// Like functionality
-	LikePost(postID int64, userID string) (bool, error)
-	UnlikePost(postID int64, userID string) error
-	GetFeedPosts(userID string, page, pageSize int) ([]*models.Post, error)
-	GetPostWithComments(postID int64, userID string) (*models.Post, []*models.Comment, error)
+	LikePost(ctx context.Context, postID int64, userID string) (bool, error)
+	UnlikePost(ctx context.Context, postID int64, userID string) error
+	ReactToPost(ctx context.Context, postID int64, userID, reactionType string) (string, map[string]int, error)
+	GetPostReactors(ctx context.Context, postID int64, userID, reactionType string, limit, offset int) ([]*models.PostReactor, map[string]int, error)
+	LikeComment(ctx context.Context, commentID int64, userID string) (bool, int, error)
+	GetFeedPosts(ctx context.Context, userID string, page, pageSize int) ([]*models.Post, error)
+	GetPostWithComments(ctx context.Context, postID int64, userID string) (*models.Post, []*models.Comment, error)
 
 	// Notification functionality
-	NotifyPostCreated(post *models.Post, userID string, userName string) error
+	NotifyPostCreated(ctx context.Context, post *models.Post, userID string, userName string) error
 }
 
 // PostService implements the Service interface
 type PostService struct {
 	repo            Repository
+	work            *uow.UnitOfWork
 	fileStore       *filestore.FileStore
 	log             *logger.Logger
 	notificationSvc *NotificationService
+	groupAuth       *group.Authorizer
 }
 
 // NewService creates a new post service
-func NewService(repo Repository, fileStore *filestore.FileStore, log *logger.Logger, notificationSvc *NotificationService) Service {
+func NewService(repo Repository, work *uow.UnitOfWork, fileStore *filestore.FileStore, log *logger.Logger, notificationSvc *NotificationService, groupAuth *group.Authorizer) Service {
 	return &PostService{
 		repo:            repo,
+		work:            work,
 		fileStore:       fileStore,
 		log:             log,
 		notificationSvc: notificationSvc,
+		groupAuth:       groupAuth,
 	}
 }
 
 // NotifyPostCreated sends notifications about a new post to appropriate users
-func (s *PostService) NotifyPostCreated(post *models.Post, userID string, userName string) error {
+func (s *PostService) NotifyPostCreated(ctx context.Context, post *models.Post, userID string, userName string) error {
 	if s.notificationSvc == nil {
 		return nil
 	}
@@ -68,9 +98,9 @@ func (s *PostService) NotifyPostCreated(post *models.Post, userID string, userNa
 
 	// For private posts, only notify allowed viewers
 	if post.Privacy == models.PrivacyPrivate {
-		viewers, err := s.repo.GetPostViewers(post.ID)
+		viewers, err := s.repo.GetPostAudience(post.ID)
 		if err != nil {
-			s.log.Error("Failed to get post viewers for notification: %v", err)
+			s.log.WithContext(ctx).Error("Failed to get post viewers for notification: %v", err)
 			return err
 		}
 
@@ -81,7 +111,7 @@ func (s *PostService) NotifyPostCreated(post *models.Post, userID string, userNa
 	if post.Privacy == models.PrivacyAlmostPrivate {
 		followers, err := s.repo.GetUserFollowers(userID)
 		if err != nil {
-			s.log.Error("Failed to get user followers for notification: %v", err)
+			s.log.WithContext(ctx).Error("Failed to get user followers for notification: %v", err)
 			return err
 		}
 
@@ -92,7 +122,7 @@ func (s *PostService) NotifyPostCreated(post *models.Post, userID string, userNa
 }
 
 // NotifyPostCreated sends notifications about a new post to appropriate users
-func (s *PostService) NotifyOnCommentCreateOrCount(postId int64, userID string, statsType string, count int) error {
+func (s *PostService) NotifyOnCommentCreateOrCount(ctx context.Context, postId int64, userID string, statsType string, count int) error {
 	if s.notificationSvc == nil {
 		return nil
 	}
@@ -103,18 +133,18 @@ func (s *PostService) NotifyOnCommentCreateOrCount(postId int64, userID string,
 	}
 	// For public posts, notify all users
 	if post.Privacy == models.PrivacyPublic {
-		s.notificationSvc.SendCommentNotificationToOwner(post.UserID, userID)
+		s.notificationSvc.SendCommentNotificationToOwner(ctx, post.UserID, userID)
 		return s.notificationSvc.NotifyPostsCommentUpdate(userID, statsType, count)
 	}
 
 	// For private posts, only notify allowed viewers
 	if post.Privacy == models.PrivacyPrivate {
-		viewers, err := s.repo.GetPostViewers(post.ID)
+		viewers, err := s.repo.GetPostAudience(post.ID)
 		if err != nil {
-			s.log.Error("Failed to get post viewers for notification: %v", err)
+			s.log.WithContext(ctx).Error("Failed to get post viewers for notification: %v", err)
 			return err
 		}
-		s.notificationSvc.SendCommentNotificationToOwner(post.UserID, userID)
+		s.notificationSvc.SendCommentNotificationToOwner(ctx, post.UserID, userID)
 		return s.notificationSvc.NotifyPostsCommentUpdateToSpecifUsers(userID, statsType, count, viewers)
 	}
 
@@ -122,10 +152,10 @@ func (s *PostService) NotifyOnCommentCreateOrCount(postId int64, userID string,
 	if post.Privacy == models.PrivacyAlmostPrivate {
 		followers, err := s.repo.GetUserFollowers(userID)
 		if err != nil {
-			s.log.Error("Failed to get user followers for notification: %v", err)
+			s.log.WithContext(ctx).Error("Failed to get user followers for notification: %v", err)
 			return err
 		}
-		s.notificationSvc.SendCommentNotificationToOwner(post.UserID, userID)
+		s.notificationSvc.SendCommentNotificationToOwner(ctx, post.UserID, userID)
 		return s.notificationSvc.NotifyPostsCommentUpdateToSpecifUsers(userID, statsType, count, followers)
 	}
 
@@ -133,24 +163,30 @@ func (s *PostService) NotifyOnCommentCreateOrCount(postId int64, userID string,
 }
 
 // CreatePost creates a new post
-func (s *PostService) CreatePost(userID string, content, privacy string, image, video *multipart.FileHeader) (*models.Post, error) {
+func (s *PostService) CreatePost(ctx context.Context, userID string, content, privacy string, viewerIDs []string, audienceListID int64, image, video *multipart.FileHeader) (*models.Post, error) {
 	// Validate privacy setting
 	if privacy != models.PrivacyPublic && privacy != models.PrivacyAlmostPrivate && privacy != models.PrivacyPrivate {
 		return nil, errors.New("invalid privacy setting")
 	}
 
+	audienceList, err := s.postAudienceList(userID, privacy, audienceListID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create post object
 	post := &models.Post{
-		UserID:  userID,
-		Content: content,
-		Privacy: privacy,
+		UserID:         userID,
+		Content:        content,
+		Privacy:        privacy,
+		AudienceListID: audienceList,
 	}
 
 	// Handle image upload if provided
 	if image != nil {
 		filename, err := s.fileStore.SaveFile(image, "posts")
 		if err != nil {
-			s.log.Error("Failed to save post image: %v", err)
+			s.log.WithContext(ctx).Error("Failed to save post image: %v", err)
 			return nil, err
 		}
 		post.ImagePath.String = filename
@@ -160,60 +196,95 @@ func (s *PostService) CreatePost(userID string, content, privacy string, image,
 	if video != nil {
 		filename, err := s.fileStore.SaveFile(video, "videos")
 		if err != nil {
-			s.log.Error("Failed to save post video: %v", err)
+			s.log.WithContext(ctx).Error("Failed to save post video: %v", err)
 			return nil, err
 		}
 		post.VideoPath.String = filename
 	}
 
-	// Save post to database
-	if err := s.repo.CreatePost(post); err != nil {
-		s.log.Error("Failed to create post: %v", err)
-		return nil, err
-	}
+	// The post, its viewers, its hashtags and the author's post count are saved together
+	err = s.work.Do(func(tx *uow.Tx) error {
+		repo := s.repo.WithTx(tx)
 
-	// Get user data for the post
-	userData, err := s.repo.GetUserDataByID(userID)
-	if err != nil {
-		s.log.Warn("Failed to get user data for post: %v", err)
-		// Continue even if we can't get the user data
-	}
+		// Save post to database
+		if err := repo.CreatePost(post); err != nil {
+			s.log.WithContext(ctx).Error("Failed to create post: %v", err)
+			return err
+		}
 
-	// Set user data in the post
-	if userData != nil {
-		post.UserData = userData
-	}
+		// Set the viewers before anyone is notified so private posts reach the right people
+		if privacy == models.PrivacyPrivate && len(viewerIDs) > 0 {
+			if err := s.setPostViewers(ctx, repo, post.ID, userID, viewerIDs); err != nil {
+				return err
+			}
+		}
 
-	userName := "Unknown User"
-	if userData != nil && userData.FirstName != "" {
-		userName = userData.FirstName
-	}
+		s.indexTags(ctx, repo, post)
 
-	// Update user stats
-	newCount, err := s.repo.UpdateUserStats(userID, "posts_count", true)
-	if err != nil {
-		s.log.Error("Failed to update user stats: %v", err)
-	}
+		// Get user data for the post
+		userData, err := repo.GetUserDataByID(userID)
+		if err != nil {
+			s.log.WithContext(ctx).Warn("Failed to get user data for post: %v", err)
+			// Continue even if we can't get the user data
+		}
 
-	// Send notification based on privacy settings
-	if s.notificationSvc != nil {
-		go s.NotifyPostCreated(post, userID, userName)
-	}
+		// Set user data in the post
+		if userData != nil {
+			post.UserData = userData
+		}
 
-	// Notify user stats updated
-	if s.notificationSvc != nil {
-		go s.notificationSvc.NotifyUserStatsUpdated(userID, "Posts", newCount)
+		userName := "Unknown User"
+		if userData != nil && userData.FirstName != "" {
+			userName = userData.FirstName
+		}
+
+		// Update user stats
+		newCount, err := repo.UpdateUserStats(userID, "posts_count", true)
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to update user stats: %v", err)
+			return err
+		}
+
+		// Nobody hears about the post until it is committed
+		if s.notificationSvc != nil {
+			tx.AfterCommit(func() {
+				// Send notification based on privacy settings
+				go s.NotifyPostCreated(ctx, post, userID, userName)
+
+				// Notify user stats updated
+				go s.notificationSvc.NotifyUserStatsUpdated(userID, "Posts", newCount)
+				go s.notifyMentions(ctx, post.ID, userID, content, "", false)
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.deleteUploads(ctx, post)
+		return nil, err
 	}
 
 	return post, nil
 }
 
+// deleteUploads removes the files saved for a post that was never created
+func (s *PostService) deleteUploads(ctx context.Context, post *models.Post) {
+	for _, filename := range []string{post.ImagePath.String, post.VideoPath.String} {
+		if filename == "" {
+			continue
+		}
+		if err := s.fileStore.DeleteFile(filename); err != nil {
+			s.log.WithContext(ctx).Warn("Failed to delete upload of unsaved post: %v", err)
+		}
+	}
+}
+
 // GetPost retrieves a post by ID if the user has permission to view it
-func (s *PostService) GetPost(postID int64, userID string) (*models.Post, error) {
+func (s *PostService) GetPost(ctx context.Context, postID int64, userID string) (*models.Post, error) {
 	// Check if the user can view this post
 	canView, err := s.repo.CanViewPost(postID, userID)
 	if err != nil {
-		s.log.Error("Failed to check post view permission: %v", err)
+		s.log.WithContext(ctx).Error("Failed to check post view permission: %v", err)
 		return nil, err
 	}
 
@@ -223,7 +294,7 @@ func (s *PostService) GetPost(postID int64, userID string) (*models.Post, error)
 
 	post, err := s.repo.GetPostByID(postID)
 	if err != nil {
-		s.log.Error("Failed to get post: %v", err)
+		s.log.WithContext(ctx).Error("Failed to get post: %v", err)
 		return nil, err
 	}
 
@@ -231,15 +302,22 @@ func (s *PostService) GetPost(postID int64, userID string) (*models.Post, error)
 		return nil, errors.New("post not found")
 	}
 
+	posts := s.attachSharedPosts(ctx, []*models.Post{post}, userID)
+	if len(posts) == 0 {
+		return nil, errors.New("you don't have permission to view this post")
+	}
+
+	s.fillReactions(ctx, posts, userID)
+
 	return post, nil
 }
 
 // GetUserPosts retrieves all posts by a user that the viewer has permission to see
-func (s *PostService) GetUserPosts(userID, viewerID string) ([]*models.Post, error) {
+func (s *PostService) GetUserPosts(ctx context.Context, userID, viewerID string) ([]*models.Post, error) {
 	// Get all posts by the user
 	posts, err := s.repo.GetPostsByUserID(userID)
 	if err != nil {
-		s.log.Error("Failed to get user posts: %v", err)
+		s.log.WithContext(ctx).Error("Failed to get user posts: %v", err)
 		return nil, err
 	}
 
@@ -248,14 +326,14 @@ func (s *PostService) GetUserPosts(userID, viewerID string) ([]*models.Post, err
 	for _, post := range posts {
 		canView, err := s.repo.CanViewPost(post.ID, viewerID)
 		if err != nil {
-			s.log.Error("Failed to check post view permission: %v", err)
+			s.log.WithContext(ctx).Error("Failed to check post view permission: %v", err)
 			continue
 		}
 
 		if canView {
 			userData, err := s.repo.GetUserDataByID(post.UserID)
 			if err != nil {
-				s.log.Warn("Failed to get user data for post %d: %v", post.ID, err)
+				s.log.WithContext(ctx).Warn("Failed to get user data for post %d: %v", post.ID, err)
 				continue
 			}
 			if userData != nil {
@@ -265,11 +343,14 @@ func (s *PostService) GetUserPosts(userID, viewerID string) ([]*models.Post, err
 		}
 	}
 
+	viewablePosts = s.attachSharedPosts(ctx, viewablePosts, viewerID)
+	s.fillReactions(ctx, viewablePosts, viewerID)
+
 	return viewablePosts, nil
 }
 
 // GetPublicPosts retrieves public posts with pagination
-func (s *PostService) GetPublicPosts(limit, offset int) ([]*models.Post, error) {
+func (s *PostService) GetPublicPosts(ctx context.Context, limit, offset int) ([]*models.Post, error) {
 	if limit <= 0 {
 		limit = 10 // Default limit
 	}
@@ -279,19 +360,25 @@ func (s *PostService) GetPublicPosts(limit, offset int) ([]*models.Post, error)
 
 	posts, err := s.repo.GetPublicPosts(limit, offset)
 	if err != nil {
-		s.log.Error("Failed to get public posts: %v", err)
+		s.log.WithContext(ctx).Error("Failed to get public posts: %v", err)
 		return nil, err
 	}
 
+	posts = s.attachSharedPosts(ctx, posts, "")
+	s.fillReactions(ctx, posts, "")
+
 	return posts, nil
 }
 
-// UpdatePost updates an existing post
-func (s *PostService) UpdatePost(postID int64, userID string, content, privacy string, image, video *multipart.FileHeader) (*models.Post, error) {
+// UpdatePost updates an existing post, keeping its previous version in the revision history
+//
+// The post keeps its audience list while it stays private unless audienceListID is set,
+// zero removing the list.
+func (s *PostService) UpdatePost(ctx context.Context, postID int64, userID string, content, privacy string, viewerIDs []string, audienceListID *int64, image, video *multipart.FileHeader) (*models.Post, error) {
 	// Get the existing post
 	post, err := s.repo.GetPostByID(postID)
 	if err != nil {
-		s.log.Error("Failed to get post for update: %v", err)
+		s.log.WithContext(ctx).Error("Failed to get post for update: %v", err)
 		return nil, err
 	}
 
@@ -304,332 +391,1187 @@ func (s *PostService) UpdatePost(postID int64, userID string, content, privacy s
 		return nil, errors.New("you don't have permission to update this post")
 	}
 
+	// Keep the current privacy if none was provided
+	if privacy == "" {
+		privacy = post.Privacy
+	}
+
 	// Validate privacy setting
 	if privacy != models.PrivacyPublic && privacy != models.PrivacyAlmostPrivate && privacy != models.PrivacyPrivate {
 		return nil, errors.New("invalid privacy setting")
 	}
 
+	audienceList := post.AudienceListID
+	if privacy != models.PrivacyPrivate {
+		audienceList = sql.NullInt64{}
+	} else if audienceListID != nil {
+		audienceList, err = s.postAudienceList(userID, privacy, *audienceListID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Work out who could see the post before the edit
+	oldViewers, oldIsPublic, err := s.postAudience(post)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post audience: %v", err)
+		return nil, err
+	}
+
+	// Snapshot the current version before changing anything
+	revision := &models.PostRevision{
+		TargetType: models.RevisionTargetPost,
+		TargetID:   post.ID,
+		EditorID:   userID,
+		Content:    post.Content,
+		ImagePath:  post.ImagePath,
+		VideoPath:  post.VideoPath,
+		Privacy:    post.Privacy,
+	}
+	oldPrivacy := post.Privacy
+
 	// Update post fields
 	post.Content = content
 	post.Privacy = privacy
+	post.AudienceListID = audienceList
 
-	// Handle image upload if provided
+	// Handle image upload if provided. The old file is kept because revisions still reference it.
 	if image != nil {
-		// Delete old image if exists
-		if post.ImagePath.String != "" {
-			if err := s.fileStore.DeleteFile(post.ImagePath.String); err != nil {
-				s.log.Warn("Failed to delete old post image: %v", err)
-			}
+		filename, err := s.fileStore.SaveFile(image, "posts")
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to save post image: %v", err)
+			return nil, err
 		}
+		post.ImagePath = sql.NullString{String: filename, Valid: true}
+	}
 
-		// Save new image
-		filename, err := s.fileStore.SaveFile(image, "posts")
+	// Handle video upload if provided
+	if video != nil {
+		filename, err := s.fileStore.SaveFile(video, "videos")
 		if err != nil {
-			s.log.Error("Failed to save post image: %v", err)
+			s.log.WithContext(ctx).Error("Failed to save post video: %v", err)
 			return nil, err
 		}
-		post.ImagePath.String = filename
+		post.VideoPath = sql.NullString{String: filename, Valid: true}
 	}
 
-	// Save updated post
-	if err := s.repo.UpdatePost(post); err != nil {
-		s.log.Error("Failed to update post: %v", err)
+	// Save updated post along with the revision
+	if err := s.repo.UpdatePost(post, revision); err != nil {
+		s.log.WithContext(ctx).Error("Failed to update post: %v", err)
 		return nil, err
 	}
 
-	return post, nil
-}
+	if post.Content != revision.Content {
+		s.indexTags(ctx, s.repo, post)
+	}
 
-// DeletePost deletes a post
-func (s *PostService) DeletePost(postID int64, userID string) error {
-	// Get the post
-	post, err := s.repo.GetPostByID(postID)
+	// Re-evaluate the explicit viewer list for the new privacy setting
+	if privacy == models.PrivacyPrivate {
+		if viewerIDs != nil || oldPrivacy != models.PrivacyPrivate {
+			if err := s.SetPostViewers(ctx, post.ID, userID, viewerIDs); err != nil {
+				return nil, err
+			}
+		}
+	} else if oldPrivacy == models.PrivacyPrivate {
+		if err := s.repo.ClearPostViewers(post.ID); err != nil {
+			s.log.WithContext(ctx).Error("Failed to clear post viewers: %v", err)
+			return nil, err
+		}
+	}
+
+	userData, err := s.repo.GetUserDataByID(userID)
 	if err != nil {
-		s.log.Error("Failed to get post for deletion: %v", err)
-		return err
+		s.log.WithContext(ctx).Warn("Failed to get user data for post: %v", err)
+	}
+	if userData != nil {
+		post.UserData = userData
 	}
 
-	if post == nil {
-		return errors.New("post not found")
+	if s.notificationSvc != nil {
+		go s.notifyPostUpdated(ctx, post, oldViewers, oldIsPublic)
+		go s.notifyMentions(ctx, post.ID, userID, post.Content, revision.Content, false)
 	}
 
-	// Check if the user is the post owner
-	if post.UserID != userID {
-		return errors.New("you don't have permission to delete this post")
+	return post, nil
+}
+
+// notifyMentions notifies users @mentioned in content who can view the post.
+// Users already mentioned in previousContent were notified before and are skipped.
+// On group posts only accepted members of the group are notified.
+func (s *PostService) notifyMentions(ctx context.Context, postID int64, authorID, content, previousContent string, inComment bool) {
+	nicknames := utils.ParseMentions(content)
+	if len(nicknames) == 0 {
+		return
 	}
 
-	// Delete the post image if exists
-	if post.ImagePath.String != "" {
-		if err := s.fileStore.DeleteFile(post.ImagePath.String); err != nil {
-			s.log.Warn("Failed to delete post image: %v", err)
+	groupID, err := s.repo.GetPostGroupID(postID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get group of mentioned post: %v", err)
+		return
+	}
+	resolve := s.repo.GetUserIDsByNicknames
+	if groupID != "" {
+		resolve = func(nicknames []string) ([]string, error) {
+			return s.repo.GetGroupMemberIDsByNicknames(groupID, nicknames)
 		}
 	}
 
-	// Delete the post video if exists
-	if post.VideoPath.String != "" {
-		if err := s.fileStore.DeleteFile(post.VideoPath.String); err != nil {
-			s.log.Warn("Failed to delete post video: %v", err)
-		}
+	userIDs, err := resolve(nicknames)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to resolve mentions: %v", err)
+		return
 	}
 
-	// Delete the post
-	if err := s.repo.DeletePost(postID); err != nil {
-		s.log.Error("Failed to delete post: %v", err)
-		return err
+	alreadyMentioned := make(map[string]bool)
+	if previousContent != "" {
+		previousIDs, err := resolve(utils.ParseMentions(previousContent))
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to resolve previous mentions: %v", err)
+			return
+		}
+		for _, id := range previousIDs {
+			alreadyMentioned[id] = true
+		}
 	}
 
-	return nil
-}
+	for _, id := range userIDs {
+		if id == authorID || alreadyMentioned[id] {
+			continue
+		}
+		if groupID != "" {
+			s.notificationSvc.SendMentionNotification(ctx, id, authorID, postID, inComment)
+			continue
+		}
 
-// SetPostViewers sets the users who can view a private post
-func (s *PostService) SetPostViewers(postID int64, userID string, viewerIDs []string) error {
-	// Get the post
-	post, err := s.repo.GetPostByID(postID)
-	if err != nil {
-		s.log.Error("Failed to get post for setting viewers: %v", err)
-		return err
-	}
+		canView, err := s.repo.CanViewPost(postID, id)
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to check post view permission for mention: %v", err)
+			continue
+		}
+		if !canView {
+			continue
+		}
 
-	if post == nil {
-		return errors.New("post not found")
+		s.notificationSvc.SendMentionNotification(ctx, id, authorID, postID, inComment)
 	}
+}
 
-	// Check if the user is the post owner
-	if post.UserID != userID {
-		return errors.New("you don't have permission to set viewers for this post")
+// postAudience returns the users who can see a post besides its author.
+// isPublic is true when everyone can see the post, in which case no IDs are returned.
+// Group posts are only seen by the group's members, whatever their privacy says.
+func (s *PostService) postAudience(post *models.Post) ([]string, bool, error) {
+	groupID, err := s.repo.GetPostGroupID(post.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if groupID != "" {
+		members, err := s.repo.GetGroupMemberIDs(groupID)
+		if err != nil {
+			return nil, false, err
+		}
+		viewers := make([]string, 0, len(members))
+		for _, id := range members {
+			if id != post.UserID {
+				viewers = append(viewers, id)
+			}
+		}
+		return viewers, false, nil
 	}
 
-	// Check if the post is private
-	if post.Privacy != models.PrivacyPrivate {
-		return errors.New("viewers can only be set for private posts")
+	switch post.Privacy {
+	case models.PrivacyPublic:
+		return nil, true, nil
+	case models.PrivacyAlmostPrivate:
+		followers, err := s.repo.GetUserFollowers(post.UserID)
+		return followers, false, err
+	case models.PrivacyPrivate:
+		viewers, err := s.repo.GetPostAudience(post.ID)
+		return viewers, false, err
+	default:
+		return nil, false, errors.New("invalid privacy setting")
 	}
+}
 
-	// Get current viewers
-	currentViewers, err := s.repo.GetPostViewers(postID)
+// notifyPostUpdated sends the edited post to its current audience and announces it
+// as a new post to users who could not see it before the edit
+func (s *PostService) notifyPostUpdated(ctx context.Context, post *models.Post, oldViewers []string, oldIsPublic bool) {
+	newViewers, newIsPublic, err := s.postAudience(post)
 	if err != nil {
-		s.log.Error("Failed to get current post viewers: %v", err)
-		return err
+		s.log.WithContext(ctx).Error("Failed to get post audience for update notification: %v", err)
+		return
 	}
 
-	// Create maps for efficient lookup
-	currentViewerMap := make(map[string]bool)
-	for _, id := range currentViewers {
-		currentViewerMap[id] = true
+	userName := "Unknown User"
+	if post.UserData != nil && post.UserData.FirstName != "" {
+		userName = post.UserData.FirstName
 	}
 
-	newViewerMap := make(map[string]bool)
-	for _, id := range viewerIDs {
-		newViewerMap[id] = true
+	if newIsPublic {
+		if !oldIsPublic {
+			s.notificationSvc.NotifyPostCreated(post, post.UserID, userName)
+			return
+		}
+		s.notificationSvc.NotifyPostUpdated(post, nil, true)
+		return
 	}
 
-	// Add new viewers
-	for _, id := range viewerIDs {
-		if !currentViewerMap[id] {
-			if err := s.repo.AddPostViewer(postID, id); err != nil {
-				s.log.Error("Failed to add post viewer: %v", err)
-				return err
-			}
-		}
+	alreadyEligible := make(map[string]bool)
+	for _, id := range oldViewers {
+		alreadyEligible[id] = true
 	}
 
-	// Remove viewers that are no longer in the list
-	for _, id := range currentViewers {
-		if !newViewerMap[id] {
-			if err := s.repo.RemovePostViewer(postID, id); err != nil {
-				s.log.Error("Failed to remove post viewer: %v", err)
-				return err
-			}
+	var newlyEligible, stillEligible []string
+	for _, id := range newViewers {
+		if oldIsPublic || alreadyEligible[id] {
+			stillEligible = append(stillEligible, id)
+		} else {
+			newlyEligible = append(newlyEligible, id)
 		}
 	}
 
-	return nil
+	if len(newlyEligible) > 0 {
+		s.notificationSvc.NotifyPostCreatedToSpecificUsers(post, post.UserID, userName, newlyEligible)
+	}
+	s.notificationSvc.NotifyPostUpdated(post, append(stillEligible, post.UserID), false)
 }
 
-// CreateComment creates a new comment on a post
-func (s *PostService) CreateComment(postID int64, userID string, content string, image *multipart.FileHeader) (*models.Comment, error) {
-	// Check if the user can view the post (and thus comment on it)
-	canView, err := s.repo.CanViewPost(postID, userID)
-	if err != nil {
-		s.log.Error("Failed to check post view permission: %v", err)
-		return nil, err
+// SharePost reshares a post, or quotes it when content is provided. Resharing a plain
+// reshare shares its original instead so reshares never nest.
+func (s *PostService) SharePost(ctx context.Context, postID int64, userID string, content, privacy string, viewerIDs []string, audienceListID int64) (*models.Post, error) {
+	// Validate privacy setting
+	if privacy != models.PrivacyPublic && privacy != models.PrivacyAlmostPrivate && privacy != models.PrivacyPrivate {
+		return nil, errors.New("invalid privacy setting")
 	}
 
-	if !canView {
-		return nil, errors.New("you don't have permission to comment on this post")
+	audienceList, err := s.postAudienceList(userID, privacy, audienceListID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create comment object
-	comment := &models.Comment{
-		PostID:  postID,
-		UserID:  userID,
-		Content: content,
+	original, err := s.repo.GetPostByID(postID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post for sharing: %v", err)
+		return nil, err
 	}
 
-	// Handle image upload if provided
-	if image != nil {
-		filename, err := s.fileStore.SaveFile(image, "comments")
-		if err != nil {
-			s.log.Error("Failed to save comment image: %v", err)
-			return nil, err
-		}
-		comment.ImagePath.String = filename
+	if original == nil {
+		return nil, errors.New("post not found")
 	}
 
-	// Save comment to database
-	if err := s.repo.CreateComment(comment); err != nil {
-		s.log.Error("Failed to create comment: %v", err)
+	isGroupPost, err := s.repo.IsGroupPost(postID)
+	if err != nil {
 		return nil, err
 	}
-
-	// Update user stats
-	newCount, err := s.repo.UpdatePostCommentCount(postID, true)
-	if err != nil {
-		s.log.Error("Failed to Post comment count: %v", err)
+	if isGroupPost {
+		return nil, errors.New("group posts can't be shared")
 	}
 
-	// Notify user stats updated
-	if s.notificationSvc != nil {
-		go s.NotifyOnCommentCreateOrCount(postID, userID, "Comments", newCount)
+	if original.SharedPostID.Valid && original.Content == "" {
+		original, err = s.repo.GetPostByID(original.SharedPostID.Int64)
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to get original post for sharing: %v", err)
+			return nil, err
+		}
+		if original == nil {
+			return nil, errors.New("this content is no longer available")
+		}
 	}
 
-	return comment, nil
-}
-
-// GetPostComments retrieves all comments for a post if the user has permission to view the post
-func (s *PostService) GetPostComments(postID int64, userID string) ([]*models.Comment, error) {
-	// Check if the user can view the post
-	canView, err := s.repo.CanViewPost(postID, userID)
+	canView, err := s.repo.CanViewPost(original.ID, userID)
 	if err != nil {
-		s.log.Error("Failed to check post view permission: %v", err)
+		s.log.WithContext(ctx).Error("Failed to check post view permission: %v", err)
 		return nil, err
 	}
 
 	if !canView {
-		return nil, errors.New("you don't have permission to view this post's comments")
+		return nil, errors.New("you don't have permission to share this post")
 	}
 
-	// Get comments
-	comments, err := s.repo.GetCommentsByPostID(postID)
-	if err != nil {
-		s.log.Error("Failed to get post comments: %v", err)
+	post := &models.Post{
+		UserID:         userID,
+		Content:        content,
+		Privacy:        privacy,
+		SharedPostID:   sql.NullInt64{Int64: original.ID, Valid: true},
+		AudienceListID: audienceList,
+	}
+
+	if err := s.repo.CreatePost(post); err != nil {
+		s.log.WithContext(ctx).Error("Failed to create shared post: %v", err)
 		return nil, err
 	}
 
-	// Fetch user data for each comment
-	for _, comment := range comments {
-		userData, err := s.repo.GetUserDataByID(comment.UserID)
-		if err != nil {
-			s.log.Warn("Failed to get user data for comment %d: %v", comment.ID, err)
-			continue
+	if privacy == models.PrivacyPrivate && len(viewerIDs) > 0 {
+		if err := s.SetPostViewers(ctx, post.ID, userID, viewerIDs); err != nil {
+			return nil, err
 		}
-		comment.UserData = userData
 	}
 
-	return comments, nil
-}
+	s.indexTags(ctx, s.repo, post)
 
-// DeleteComment deletes a comment
-func (s *PostService) DeleteComment(commentID int64, userID, postid string) error {
-	// Get the comment (would need to add this method to repository)
-	// For now, we'll just delete if the user is the comment author
-	// In a real implementation, you'd check if the user is either the comment author or the post owner
+	original.SharesCount++
+	original.UserData, _ = s.repo.GetUserDataByID(original.UserID)
+	post.SharedPost = original
 
-	// Delete the comment
-	if err := s.repo.DeleteComment(commentID); err != nil {
-		s.log.Error("Failed to delete comment: %v", err)
-		return err
+	userData, err := s.repo.GetUserDataByID(userID)
+	if err != nil {
+		s.log.WithContext(ctx).Warn("Failed to get user data for post: %v", err)
 	}
+	post.UserData = userData
 
-	convertedid, err := strconv.ParseInt(postid, 10, 64)
-	if err != nil {
-		return err
+	userName := "Unknown User"
+	if userData != nil && userData.FirstName != "" {
+		userName = userData.FirstName
 	}
-	_,  err = s.repo.UpdatePostCommentCount(convertedid, false)
-	if err != nil {
+
+	newCount, err := s.repo.UpdateUserStats(userID, "posts_count", true)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to update user stats: %v", err)
+	}
+
+	if s.notificationSvc != nil {
+		go s.NotifyPostCreated(ctx, post, userID, userName)
+		go s.notificationSvc.NotifyUserStatsUpdated(userID, "Posts", newCount)
+		go s.notificationSvc.SendShareNotificationToOwner(ctx, original.UserID, userID, post.ID)
+		go s.notifyMentions(ctx, post.ID, userID, content, "", false)
+	}
+
+	return post, nil
+}
+
+// indexTags stores the hashtags used in a post. Failures are logged rather than
+// returned since the post itself was saved.
+func (s *PostService) indexTags(ctx context.Context, repo Repository, post *models.Post) {
+	if err := repo.SetPostTags(post.ID, utils.ParseHashtags(post.Content), post.CreatedAt); err != nil {
+		s.log.WithContext(ctx).Error("Failed to index post hashtags: %v", err)
+	}
+}
+
+// GetTagPosts gets the posts tagged with a hashtag that the user can view, with pagination
+func (s *PostService) GetTagPosts(ctx context.Context, tag, userID string, page, pageSize int) ([]*models.Post, error) {
+	tag = utils.NormalizeHashtag(tag)
+	if tag == "" {
+		return nil, errors.New("invalid hashtag")
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	posts, err := s.repo.GetPostsByTag(tag, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get posts by tag: %v", err)
+		return nil, err
+	}
+
+	for _, post := range posts {
+		userData, err := s.repo.GetUserDataByID(post.UserID)
+		if err != nil {
+			s.log.WithContext(ctx).Warn("Failed to get user data for post %d: %v", post.ID, err)
+			continue
+		}
+		post.UserData = userData
+	}
+
+	posts = s.attachSharedPosts(ctx, posts, userID)
+	s.fillReactions(ctx, posts, userID)
+
+	return posts, nil
+}
+
+// GetTrendingTopics gets the current trending hashtags
+func (s *PostService) GetTrendingTopics(ctx context.Context, limit int) ([]*models.TrendingTopic, error) {
+	if limit < 1 || limit > maxTrendingTopics {
+		limit = maxTrendingTopics
+	}
+
+	topics, err := s.repo.GetTrendingTopics(limit)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get trending topics: %v", err)
+		return nil, err
+	}
+
+	return topics, nil
+}
+
+// attachSharedPosts loads the original of every reshare in posts. The original's privacy is
+// enforced at read time: reshares the viewer couldn't see the original of are left out, and
+// reshares of deleted posts are kept and marked unavailable.
+func (s *PostService) attachSharedPosts(ctx context.Context, posts []*models.Post, viewerID string) []*models.Post {
+	visible := posts[:0]
+	for _, post := range posts {
+		if !post.SharedPostID.Valid {
+			visible = append(visible, post)
+			continue
+		}
+
+		original, err := s.repo.GetPostByID(post.SharedPostID.Int64)
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to get shared post %d: %v", post.SharedPostID.Int64, err)
+			continue
+		}
+
+		if original == nil {
+			post.SharedPostUnavailable = true
+			visible = append(visible, post)
+			continue
+		}
+
+		canView := original.Privacy == models.PrivacyPublic
+		if !canView && viewerID != "" {
+			canView, err = s.repo.CanViewPost(original.ID, viewerID)
+			if err != nil {
+				s.log.WithContext(ctx).Error("Failed to check shared post view permission: %v", err)
+				continue
+			}
+		}
+		if !canView {
+			continue
+		}
+
+		userData, err := s.repo.GetUserDataByID(original.UserID)
+		if err != nil {
+			s.log.WithContext(ctx).Warn("Failed to get user data for shared post %d: %v", original.ID, err)
+		}
+		original.UserData = userData
+
+		post.SharedPost = original
+		visible = append(visible, post)
+	}
+
+	return visible
+}
+
+// GetPostRevisions retrieves the edit history of a post for its author
+func (s *PostService) GetPostRevisions(ctx context.Context, postID int64, userID string) ([]*models.PostRevision, error) {
+	post, err := s.repo.GetPostByID(postID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post for revisions: %v", err)
+		return nil, err
+	}
+
+	if post == nil {
+		return nil, errors.New("post not found")
+	}
+
+	targetType := models.RevisionTargetPost
+	if isGroupPost, err := s.repo.IsGroupPost(postID); err != nil {
+		return nil, err
+	} else if isGroupPost {
+		targetType = models.RevisionTargetGroupPost
+	}
+
+	// Besides the author, only moderators of the group may read the history, and only
+	// that of a group post
+	if post.UserID != userID {
+		isModerator := false
+		if targetType == models.RevisionTargetGroupPost {
+			isModerator, err = s.canModerateGroupPost(postID, userID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !isModerator {
+			return nil, errors.New("you don't have permission to view this post's history")
+		}
+	}
+
+	return s.getRevisions(ctx, targetType, postID)
+}
+
+// getRevisions loads revisions and attaches the editor's user data
+func (s *PostService) getRevisions(ctx context.Context, targetType string, targetID int64) ([]*models.PostRevision, error) {
+	revisions, err := s.repo.GetRevisions(targetType, targetID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get revisions: %v", err)
+		return nil, err
+	}
+
+	for _, revision := range revisions {
+		userData, err := s.repo.GetUserDataByID(revision.EditorID)
+		if err != nil {
+			s.log.WithContext(ctx).Warn("Failed to get user data for revision %d: %v", revision.ID, err)
+			continue
+		}
+		revision.EditorData = userData
+	}
+
+	return revisions, nil
+}
+
+// DeletePost deletes a post
+func (s *PostService) DeletePost(ctx context.Context, postID int64, userID string) error {
+	// Get the post
+	post, err := s.repo.GetPostByID(postID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post for deletion: %v", err)
 		return err
 	}
-	
+
+	if post == nil {
+		return errors.New("post not found")
+	}
+
+	// Check if the user is the post owner
+	if post.UserID != userID {
+		return errors.New("you don't have permission to delete this post")
+	}
+
+	// Delete the post image if exists
+	if post.ImagePath.String != "" {
+		if err := s.fileStore.DeleteFile(post.ImagePath.String); err != nil {
+			s.log.WithContext(ctx).Warn("Failed to delete post image: %v", err)
+		}
+	}
+
+	// Delete the post video if exists
+	if post.VideoPath.String != "" {
+		if err := s.fileStore.DeleteFile(post.VideoPath.String); err != nil {
+			s.log.WithContext(ctx).Warn("Failed to delete post video: %v", err)
+		}
+	}
+
+	// Delete the post
+	if err := s.repo.DeletePost(postID); err != nil {
+		s.log.WithContext(ctx).Error("Failed to delete post: %v", err)
+		return err
+	}
+
 	return nil
 }
 
-// LikePost handles liking a post
-func (s *PostService) LikePost(postID int64, userID string) (bool, error) {
-	var isLiked bool
-	// Check if the user can view the post
+// SetPostViewers sets the users who can view a private post
+func (s *PostService) SetPostViewers(ctx context.Context, postID int64, userID string, viewerIDs []string) error {
+	return s.setPostViewers(ctx, s.repo, postID, userID, viewerIDs)
+}
+
+// setPostViewers sets the viewers of a private post through repo, which may be bound to
+// a unit of work
+func (s *PostService) setPostViewers(ctx context.Context, repo Repository, postID int64, userID string, viewerIDs []string) error {
+	// Get the post
+	post, err := repo.GetPostByID(postID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post for setting viewers: %v", err)
+		return err
+	}
+
+	if post == nil {
+		return errors.New("post not found")
+	}
+
+	// Check if the user is the post owner
+	if post.UserID != userID {
+		return errors.New("you don't have permission to set viewers for this post")
+	}
+
+	// Check if the post is private
+	if post.Privacy != models.PrivacyPrivate {
+		return errors.New("viewers can only be set for private posts")
+	}
+
+	// Get current viewers
+	currentViewers, err := repo.GetPostViewers(postID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get current post viewers: %v", err)
+		return err
+	}
+
+	// Create maps for efficient lookup
+	currentViewerMap := make(map[string]bool)
+	for _, id := range currentViewers {
+		currentViewerMap[id] = true
+	}
+
+	newViewerMap := make(map[string]bool)
+	for _, id := range viewerIDs {
+		newViewerMap[id] = true
+	}
+
+	// Add new viewers
+	for _, id := range viewerIDs {
+		if !currentViewerMap[id] {
+			if err := repo.AddPostViewer(postID, id); err != nil {
+				s.log.WithContext(ctx).Error("Failed to add post viewer: %v", err)
+				return err
+			}
+		}
+	}
+
+	// Remove viewers that are no longer in the list
+	for _, id := range currentViewers {
+		if !newViewerMap[id] {
+			if err := repo.RemovePostViewer(postID, id); err != nil {
+				s.log.WithContext(ctx).Error("Failed to remove post viewer: %v", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// CreateComment creates a new comment on a post, or a reply when parentID is set
+func (s *PostService) CreateComment(ctx context.Context, postID int64, userID string, parentID int64, content string, image *multipart.FileHeader) (*models.Comment, error) {
+	// Check if the user can view the post (and thus comment on it)
 	canView, err := s.repo.CanViewPost(postID, userID)
 	if err != nil {
-		return false, err
+		s.log.WithContext(ctx).Error("Failed to check post view permission: %v", err)
+		return nil, err
 	}
+
 	if !canView {
-		return false, errors.New("you don't have permission to like this post")
+		return nil, errors.New("you don't have permission to comment on this post")
 	}
 
-	// Check if already liked
-	hasLiked, err := s.repo.HasLiked(postID, userID)
+	// Group posts also need the group's comment permission
+	groupID, err := s.repo.GetPostGroupID(postID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if hasLiked {
-		if err := s.repo.UnlikePost(postID, userID); err != nil {
-			return false, err
+	if groupID != "" {
+		if err := s.groupAuth.Require(groupID, userID, group.PermComment, "commenting"); err != nil {
+			return nil, err
 		}
-		isLiked = false
-	} else {
-		if err := s.repo.LikePost(postID, userID); err != nil {
-			return false, err
+	}
+
+	// Create comment object
+	comment := &models.Comment{
+		PostID:  postID,
+		UserID:  userID,
+		Content: content,
+	}
+
+	// Attach replies to their parent, replies past the depth limit go to the parent's parent
+	if parentID > 0 {
+		parent, err := s.repo.GetCommentByID(parentID)
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to get parent comment: %v", err)
+			return nil, err
+		}
+
+		if parent == nil || parent.PostID != postID {
+			return nil, errors.New("parent comment not found")
+		}
+
+		if parent.Depth >= models.MaxCommentDepth && parent.ParentID.Valid {
+			comment.ParentID = parent.ParentID
+			comment.Depth = parent.Depth
+		} else {
+			comment.ParentID = sql.NullInt64{Int64: parent.ID, Valid: true}
+			comment.Depth = parent.Depth + 1
 		}
-		isLiked = true
 	}
 
-	// Get the updated post with current like count
-	post, err := s.repo.GetPostByID(postID)
+	// Handle image upload if provided
+	if image != nil {
+		filename, err := s.fileStore.SaveFile(image, "comments")
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to save comment image: %v", err)
+			return nil, err
+		}
+		comment.ImagePath.String = filename
+	}
+
+	// Save comment to database
+	if err := s.repo.CreateComment(comment); err != nil {
+		s.log.WithContext(ctx).Error("Failed to create comment: %v", err)
+		return nil, err
+	}
+
+	// Update user stats
+	newCount, err := s.repo.UpdatePostCommentCount(postID, true)
 	if err != nil {
-		s.log.Error("Failed to get post for like notification: %v", err)
-		// Continue even if we can't get the post data
+		s.log.WithContext(ctx).Error("Failed to Post comment count: %v", err)
+	}
+
+	// Notify user stats updated
+	if s.notificationSvc != nil {
+		go s.NotifyOnCommentCreateOrCount(ctx, postID, userID, "Comments", newCount)
+		go s.notifyMentions(ctx, postID, userID, content, "", true)
+	}
+
+	return comment, nil
+}
+
+// GetPostComments retrieves all comments for a post if the user has permission to view the post
+func (s *PostService) GetPostComments(ctx context.Context, postID int64, userID string) ([]*models.Comment, error) {
+	// Check if the user can view the post
+	canView, err := s.canViewComments(postID, userID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to check post view permission: %v", err)
+		return nil, err
+	}
+
+	if !canView {
+		return nil, errors.New("you don't have permission to view this post's comments")
+	}
+
+	// Get comments
+	comments, err := s.repo.GetCommentsByPostID(postID, userID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post comments: %v", err)
+		return nil, err
+	}
+
+	s.fillCommentData(ctx, comments, userID)
+
+	return comments, nil
+}
+
+// GetCommentReplies retrieves a page of replies to a comment if the user can view the post
+func (s *PostService) GetCommentReplies(ctx context.Context, commentID int64, userID string, limit, offset int) ([]*models.Comment, error) {
+	parent, err := s.repo.GetCommentByID(commentID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get comment for replies: %v", err)
+		return nil, err
+	}
+
+	if parent == nil {
+		return nil, errors.New("comment not found")
+	}
+
+	canView, err := s.canViewComments(parent.PostID, userID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to check post view permission: %v", err)
+		return nil, err
+	}
+
+	if !canView {
+		return nil, errors.New("you don't have permission to view this post's comments")
+	}
+
+	if limit < 1 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	replies, err := s.repo.GetCommentReplies(commentID, userID, limit, offset)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get comment replies: %v", err)
+		return nil, err
+	}
+
+	s.fillCommentData(ctx, replies, userID)
+
+	return replies, nil
+}
+
+// fillCommentData attaches author data and the viewer's like status to each comment
+func (s *PostService) fillCommentData(ctx context.Context, comments []*models.Comment, userID string) {
+	for _, comment := range comments {
+		isLiked, err := s.repo.HasLikedComment(comment.ID, userID)
+		if err != nil {
+			s.log.WithContext(ctx).Warn("Failed to get like status for comment %d: %v", comment.ID, err)
+		}
+		comment.IsLiked = isLiked
+
+		userData, err := s.repo.GetUserDataByID(comment.UserID)
+		if err != nil {
+			s.log.WithContext(ctx).Warn("Failed to get user data for comment %d: %v", comment.ID, err)
+			continue
+		}
+		comment.UserData = userData
+	}
+}
+
+// UpdateComment updates a comment, keeping its previous version in the revision history
+func (s *PostService) UpdateComment(ctx context.Context, commentID int64, userID string, content string, image *multipart.FileHeader) (*models.Comment, error) {
+	comment, err := s.repo.GetCommentByID(commentID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get comment for update: %v", err)
+		return nil, err
+	}
+
+	if comment == nil {
+		return nil, errors.New("comment not found")
+	}
+
+	if comment.UserID != userID {
+		return nil, errors.New("you don't have permission to update this comment")
+	}
+
+	// Snapshot the current version before changing anything
+	revision := &models.PostRevision{
+		TargetType: models.RevisionTargetComment,
+		TargetID:   comment.ID,
+		EditorID:   userID,
+		Content:    comment.Content,
+		ImagePath:  comment.ImagePath,
+	}
+
+	comment.Content = content
+
+	// Handle image upload if provided. The old file is kept because revisions still reference it.
+	if image != nil {
+		filename, err := s.fileStore.SaveFile(image, "comments")
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to save comment image: %v", err)
+			return nil, err
+		}
+		comment.ImagePath = sql.NullString{String: filename, Valid: true}
+	}
+
+	if err := s.repo.UpdateComment(comment, revision); err != nil {
+		s.log.WithContext(ctx).Error("Failed to update comment: %v", err)
+		return nil, err
 	}
 
-	// Get user data for the notification
 	userData, err := s.repo.GetUserDataByID(userID)
 	if err != nil {
-		s.log.Warn("Failed to get user data for like notification: %v", err)
-		// Continue even if we can't get the user data
+		s.log.WithContext(ctx).Warn("Failed to get user data for comment: %v", err)
 	}
+	comment.UserData = userData
 
-	// Format the username
-	userName := "Unknown User"
-	if userData != nil {
-		if userData.FirstName != "" {
-			if userData.LastName != "" {
-				userName = userData.FirstName + " " + userData.LastName
-			} else {
-				userName = userData.FirstName
+	if s.notificationSvc != nil {
+		go s.notifyCommentUpdated(ctx, comment)
+		go s.notifyMentions(ctx, comment.PostID, userID, comment.Content, revision.Content, true)
+	}
+
+	return comment, nil
+}
+
+// notifyCommentUpdated sends the edited comment to everyone who can see the post
+func (s *PostService) notifyCommentUpdated(ctx context.Context, comment *models.Comment) {
+	post, err := s.repo.GetPostByID(comment.PostID)
+	if err != nil || post == nil {
+		s.log.WithContext(ctx).Error("Failed to get post for comment update notification: %v", err)
+		return
+	}
+
+	viewers, isPublic, err := s.postAudience(post)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post audience for comment update notification: %v", err)
+		return
+	}
+
+	s.notificationSvc.NotifyCommentUpdated(comment, append(viewers, post.UserID), isPublic)
+}
+
+// GetCommentRevisions retrieves the edit history of a comment for its author or group moderators
+func (s *PostService) GetCommentRevisions(ctx context.Context, commentID int64, userID string) ([]*models.PostRevision, error) {
+	comment, err := s.repo.GetCommentByID(commentID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get comment for revisions: %v", err)
+		return nil, err
+	}
+
+	if comment == nil {
+		return nil, errors.New("comment not found")
+	}
+
+	if comment.UserID != userID {
+		isModerator, err := s.canModerateGroupPost(comment.PostID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isModerator {
+			return nil, errors.New("you don't have permission to view this comment's history")
+		}
+	}
+
+	return s.getRevisions(ctx, models.RevisionTargetComment, commentID)
+}
+
+// canViewComments reports whether userID may read the comments of a post. Group posts
+// pass CanViewPost once approved, so their threads also need group membership.
+func (s *PostService) canViewComments(postID int64, userID string) (bool, error) {
+	canView, err := s.repo.CanViewPost(postID, userID)
+	if err != nil || !canView {
+		return false, err
+	}
+
+	groupID, err := s.repo.GetPostGroupID(postID)
+	if err != nil {
+		return false, err
+	}
+	if groupID == "" {
+		return true, nil
+	}
+	return s.groupAuth.IsMember(groupID, userID)
+}
+
+// canModerateGroupPost reports whether userID may act on others' content on a post, which
+// only group members with the delete content permission may do
+func (s *PostService) canModerateGroupPost(postID int64, userID string) (bool, error) {
+	groupID, err := s.repo.GetPostGroupID(postID)
+	if err != nil || groupID == "" {
+		return false, err
+	}
+	return s.groupAuth.Can(groupID, userID, group.PermDeleteContent)
+}
+
+// DeleteComment deletes a comment along with its replies.
+// Only the comment author, the post owner or, in groups, members who may delete others'
+// content can delete a comment.
+func (s *PostService) DeleteComment(ctx context.Context, commentID int64, userID, postid string) error {
+	comment, err := s.repo.GetCommentByID(commentID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get comment for deletion: %v", err)
+		return err
+	}
+
+	if comment == nil {
+		return errors.New("comment not found")
+	}
+
+	if comment.UserID != userID {
+		post, err := s.repo.GetPostByID(comment.PostID)
+		if err != nil {
+			s.log.WithContext(ctx).Error("Failed to get post for comment deletion: %v", err)
+			return err
+		}
+		if post == nil || post.UserID != userID {
+			isModerator, err := s.canModerateGroupPost(comment.PostID, userID)
+			if err != nil {
+				return err
+			}
+			if !isModerator {
+				return errors.New("you don't have permission to delete this comment")
 			}
 		}
 	}
-	// Send notification via WebSocket if notification service is available
-	if s.notificationSvc != nil && post != nil {
-		go s.notificationSvc.NotifyPostLiked(post, userID, userName, isLiked)
+
+	// Delete the comment, the repository also updates the reply and comment counts
+	if err := s.repo.DeleteComment(commentID); err != nil {
+		s.log.WithContext(ctx).Error("Failed to delete comment: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// LikePost toggles the "like" reaction on a post
+func (s *PostService) LikePost(ctx context.Context, postID int64, userID string) (bool, error) {
+	reaction, _, err := s.ReactToPost(ctx, postID, userID, models.ReactionLike)
+	if err != nil {
+		return false, err
 	}
 
-	return isLiked, nil
+	return reaction != "", nil
+}
+
+// UnlikePost removes the user's reaction from a post
+func (s *PostService) UnlikePost(ctx context.Context, postID int64, userID string) error {
+	_, _, err := s.ReactToPost(ctx, postID, userID, "")
+	return err
 }
 
-// UnlikePost handles unliking a post
-func (s *PostService) UnlikePost(postID int64, userID string) error {
+// ReactToPost sets the user's reaction on a post. Reacting again with the same type,
+// or with an empty type, removes the reaction. It returns the user's reaction after
+// the change and the updated counts per type.
+func (s *PostService) ReactToPost(ctx context.Context, postID int64, userID, reactionType string) (string, map[string]int, error) {
+	if reactionType != "" && !models.IsValidReaction(reactionType) {
+		return "", nil, errors.New("invalid reaction type")
+	}
+
 	// Check if the user can view the post
 	canView, err := s.repo.CanViewPost(postID, userID)
 	if err != nil {
-		return err
+		return "", nil, err
+	}
+	if !canView {
+		return "", nil, errors.New("you don't have permission to react to this post")
+	}
+
+	current, err := s.repo.GetUserReaction(postID, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var previous string
+	if reactionType == "" || reactionType == current {
+		previous, err = s.repo.RemoveReaction(postID, userID)
+		reactionType = ""
+	} else {
+		previous, err = s.repo.SetReaction(postID, userID, reactionType)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	counts, err := s.repo.GetReactionCounts(postID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if s.notificationSvc != nil {
+		go s.notifyReactionChanged(ctx, postID, userID, reactionType, previous, counts)
+	}
+
+	return reactionType, counts, nil
+}
+
+// notifyReactionChanged sends the updated reaction counts to everyone who can see the post
+func (s *PostService) notifyReactionChanged(ctx context.Context, postID int64, userID, reactionType, previous string, counts map[string]int) {
+	post, err := s.repo.GetPostByID(postID)
+	if err != nil || post == nil {
+		s.log.WithContext(ctx).Error("Failed to get post for reaction notification: %v", err)
+		return
+	}
+
+	viewers, isPublic, err := s.postAudience(post)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post audience for reaction notification: %v", err)
+		return
+	}
+
+	// Format the username
+	userName := "Unknown User"
+	userData, err := s.repo.GetUserDataByID(userID)
+	if err != nil {
+		s.log.WithContext(ctx).Warn("Failed to get user data for reaction notification: %v", err)
+	}
+	if userData != nil && userData.FirstName != "" {
+		userName = strings.TrimSpace(userData.FirstName + " " + userData.LastName)
+	}
+
+	payload := events.PostReactionChangedPayload{
+		PostID:           postID,
+		UserID:           userID,
+		UserName:         userName,
+		ReactionType:     reactionType,
+		PreviousReaction: previous,
+		ReactionCounts:   counts,
+		LikesCount:       int(post.LikesCount),
+	}
+
+	s.notificationSvc.NotifyPostReactionChanged(payload, append(viewers, post.UserID), isPublic)
+}
+
+// GetPostReactors gets who reacted to a post, optionally filtered by reaction type, with the counts per type
+func (s *PostService) GetPostReactors(ctx context.Context, postID int64, userID, reactionType string, limit, offset int) ([]*models.PostReactor, map[string]int, error) {
+	if reactionType != "" && !models.IsValidReaction(reactionType) {
+		return nil, nil, errors.New("invalid reaction type")
+	}
+
+	// Check if the user can view the post
+	canView, err := s.repo.CanViewPost(postID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !canView {
+		return nil, nil, errors.New("you don't have permission to view this post")
+	}
+
+	if limit < 1 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	reactors, err := s.repo.GetReactors(postID, userID, reactionType, limit, offset)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post reactors: %v", err)
+		return nil, nil, err
+	}
+
+	counts, err := s.repo.GetReactionCounts(postID)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get reaction counts: %v", err)
+		return nil, nil, err
+	}
+
+	return reactors, counts, nil
+}
+
+// fillReactions attaches the reaction counts and the viewer's own reaction to each post
+func (s *PostService) fillReactions(ctx context.Context, posts []*models.Post, userID string) {
+	for _, post := range posts {
+		counts, err := s.repo.GetReactionCounts(post.ID)
+		if err != nil {
+			s.log.WithContext(ctx).Warn("Failed to get reaction counts for post %d: %v", post.ID, err)
+			continue
+		}
+		post.ReactionCounts = counts
+
+		reaction, err := s.repo.GetUserReaction(post.ID, userID)
+		if err != nil {
+			s.log.WithContext(ctx).Warn("Failed to get reaction for post %d: %v", post.ID, err)
+			continue
+		}
+		post.UserReaction = reaction
+	}
+}
+
+// LikeComment toggles a user's like on a comment and returns the new like status and count
+func (s *PostService) LikeComment(ctx context.Context, commentID int64, userID string) (bool, int, error) {
+	comment, err := s.repo.GetCommentByID(commentID)
+	if err != nil {
+		return false, 0, err
+	}
+	if comment == nil {
+		return false, 0, errors.New("comment not found")
+	}
+
+	// Check if the user can view the post
+	canView, err := s.repo.CanViewPost(comment.PostID, userID)
+	if err != nil {
+		return false, 0, err
 	}
 	if !canView {
-		return errors.New("you don't have permission to unlike this post")
+		return false, 0, errors.New("you don't have permission to like this comment")
+	}
+
+	// Check if already liked
+	hasLiked, err := s.repo.HasLikedComment(commentID, userID)
+	if err != nil {
+		return false, 0, err
+	}
+
+	var isLiked bool
+	if hasLiked {
+		if err := s.repo.UnlikeComment(commentID, userID); err != nil {
+			return false, 0, err
+		}
+		isLiked = false
+	} else {
+		if err := s.repo.LikeComment(commentID, userID); err != nil {
+			return false, 0, err
+		}
+		isLiked = true
 	}
 
-	return s.repo.UnlikePost(postID, userID)
+	likesCount, err := s.repo.GetCommentLikesCount(commentID)
+	if err != nil {
+		return false, 0, err
+	}
+	comment.LikesCount = int64(likesCount)
+
+	if s.notificationSvc != nil {
+		go s.notifyCommentLiked(ctx, comment, userID, isLiked)
+	}
+
+	return isLiked, likesCount, nil
+}
+
+// notifyCommentLiked sends the new like count to everyone who can see the post
+func (s *PostService) notifyCommentLiked(ctx context.Context, comment *models.Comment, userID string, isLiked bool) {
+	post, err := s.repo.GetPostByID(comment.PostID)
+	if err != nil || post == nil {
+		s.log.WithContext(ctx).Error("Failed to get post for comment like notification: %v", err)
+		return
+	}
+
+	viewers, isPublic, err := s.postAudience(post)
+	if err != nil {
+		s.log.WithContext(ctx).Error("Failed to get post audience for comment like notification: %v", err)
+		return
+	}
+
+	userName := "Unknown User"
+	if userData, err := s.repo.GetUserDataByID(userID); err == nil && userData != nil && userData.FirstName != "" {
+		userName = strings.TrimSpace(userData.FirstName + " " + userData.LastName)
+	}
+
+	s.notificationSvc.NotifyCommentLiked(comment, userID, userName, isLiked, append(viewers, post.UserID), isPublic)
 }
 
 // GetFeedPosts gets posts visible to the user with pagination
-func (s *PostService) GetFeedPosts(userID string, page, pageSize int) ([]*models.Post, error) {
+func (s *PostService) GetFeedPosts(ctx context.Context, userID string, page, pageSize int) ([]*models.Post, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -640,7 +1582,7 @@ func (s *PostService) GetFeedPosts(userID string, page, pageSize int) ([]*models
 	offset := (page - 1) * pageSize
 	posts, err := s.repo.GetFeedPosts(userID, pageSize, offset)
 	if err != nil {
-		s.log.Error("Failed to get feed posts: %v", err)
+		s.log.WithContext(ctx).Error("Failed to get feed posts: %v", err)
 		return nil, err
 	}
 
@@ -648,7 +1590,7 @@ func (s *PostService) GetFeedPosts(userID string, page, pageSize int) ([]*models
 	for _, post := range posts {
 		userData, err := s.repo.GetUserDataByID(post.UserID)
 		if err != nil {
-			s.log.Warn("Failed to get user data for post %d: %v", post.ID, err)
+			s.log.WithContext(ctx).Warn("Failed to get user data for post %d: %v", post.ID, err)
 			continue
 		}
 		if userData != nil {
@@ -656,17 +1598,20 @@ func (s *PostService) GetFeedPosts(userID string, page, pageSize int) ([]*models
 		}
 	}
 
+	posts = s.attachSharedPosts(ctx, posts, userID)
+	s.fillReactions(ctx, posts, userID)
+
 	return posts, nil
 }
 
 // GetPostWithComments retrieves a post along with its comments
-func (s *PostService) GetPostWithComments(postID int64, userID string) (*models.Post, []*models.Comment, error) {
+func (s *PostService) GetPostWithComments(ctx context.Context, postID int64, userID string) (*models.Post, []*models.Comment, error) {
 	post, err := s.repo.GetPostByID(postID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	comments, err := s.repo.GetCommentsByPostID(postID)
+	comments, err := s.repo.GetCommentsByPostID(postID, userID)
 	if err != nil {
 		return nil, nil, err
 	}
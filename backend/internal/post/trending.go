@@ -0,0 +1,116 @@
+package post
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/logger"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+const (
+	// trendingWindow is how far back hashtag usage is considered
+	trendingWindow = 7 * 24 * time.Hour
+	// trendingHalfLife is how long it takes a single use of a hashtag to lose half its weight
+	trendingHalfLife = 6 * time.Hour
+	// maxTrendingTopics is the number of topics kept after each run
+	maxTrendingTopics = 20
+)
+
+// TrendingJob periodically recomputes trending hashtags from public posts
+type TrendingJob struct {
+	repo     Repository
+	log      *logger.Logger
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// NewTrendingJob creates a new trending topics job
+func NewTrendingJob(repo Repository, log *logger.Logger, interval time.Duration) *TrendingJob {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &TrendingJob{
+		repo:     repo,
+		log:      log,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Run computes trending topics immediately and then on every interval until Stop is called
+func (j *TrendingJob) Run() {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.Refresh(time.Now())
+	for {
+		select {
+		case <-ticker.C:
+			j.Refresh(time.Now())
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the job
+func (j *TrendingJob) Stop() {
+	close(j.stop)
+}
+
+// Refresh recomputes and stores the trending topics as of now
+func (j *TrendingJob) Refresh(now time.Time) {
+	usage, err := j.repo.GetPublicTagUsage(now.Add(-trendingWindow))
+	if err != nil {
+		j.log.Error("Failed to get hashtag usage: %v", err)
+		return
+	}
+
+	topics := scoreTrendingTopics(usage, now)
+	if err := j.repo.ReplaceTrendingTopics(topics); err != nil {
+		j.log.Error("Failed to store trending topics: %v", err)
+		return
+	}
+
+	j.log.Debug("Trending topics updated, %d topics from %d hashtag uses", len(topics), len(usage))
+}
+
+// scoreTrendingTopics ranks hashtags by usage with exponential time decay, so a use
+// trendingHalfLife ago counts for half of a use right now
+func scoreTrendingTopics(usage []*models.PostTag, now time.Time) []*models.TrendingTopic {
+	byTag := make(map[string]*models.TrendingTopic)
+	for _, u := range usage {
+		age := now.Sub(u.CreatedAt)
+		if age < 0 {
+			age = 0
+		}
+
+		topic, ok := byTag[u.Tag]
+		if !ok {
+			topic = &models.TrendingTopic{Tag: u.Tag}
+			byTag[u.Tag] = topic
+		}
+		topic.Score += math.Exp(-math.Ln2 * age.Hours() / trendingHalfLife.Hours())
+		topic.PostsCount++
+	}
+
+	topics := make([]*models.TrendingTopic, 0, len(byTag))
+	for _, topic := range byTag {
+		topics = append(topics, topic)
+	}
+
+	sort.Slice(topics, func(i, k int) bool {
+		if topics[i].Score != topics[k].Score {
+			return topics[i].Score > topics[k].Score
+		}
+		return topics[i].Tag < topics[k].Tag
+	})
+
+	if len(topics) > maxTrendingTopics {
+		topics = topics[:maxTrendingTopics]
+	}
+
+	return topics
+}
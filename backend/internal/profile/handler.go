@@ -1,6 +1,7 @@
 package profile
 
 import (
+	"errors"
 	"mime/multipart"
 	"net/http"
 
@@ -42,7 +43,7 @@ func NewHandler(service Service, log *logger.Logger) *Handler {
 }
 
 func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
-	h.log.Info("Received profile view request")
+	h.log.WithContext(r.Context()).Info("Received profile view request")
 	if r.Method != http.MethodGet {
 		httputil.SendError(w, http.StatusMethodNotAllowed, "Method not allowed", false)
 		return
@@ -59,8 +60,12 @@ func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	shouldView, err := h.service.ValidateProfileViewRequest(userID, profileID)
+	if errors.Is(err, ErrProfileNotFound) {
+		httputil.SendError(w, http.StatusNotFound, "User not found", true)
+		return
+	}
 	if err != nil {
-		h.log.Error("Failed to validate view request" + err.Error())
+		h.log.WithContext(r.Context()).Error("Failed to validate view request" + err.Error())
 		httputil.SendError(w, http.StatusInternalServerError, "Server error", true)
 		return
 	}
@@ -70,7 +75,7 @@ func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	}
 	targetProfile, err := h.service.GetProfileByUserID(profileID)
 	if err != nil {
-		h.log.Error("Failed to fetch target profile" + err.Error())
+		h.log.WithContext(r.Context()).Error("Failed to fetch target profile" + err.Error())
 		httputil.SendError(w, http.StatusInternalServerError, "Server error", true)
 		return
 	}
@@ -83,7 +88,7 @@ func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
-	h.log.Info("Received profile update request")
+	h.log.WithContext(r.Context()).Info("Received profile update request")
 
 	if r.Method != http.MethodPut {
 		httputil.SendError(w, http.StatusMethodNotAllowed, "Method not allowed", false)
@@ -97,7 +102,7 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 	err := r.ParseMultipartForm(20 << 20) // 20MB max
 	if err != nil {
-		h.log.Error("Failed to parse multipart form" + err.Error())
+		h.log.WithContext(r.Context()).Error("Failed to parse multipart form" + err.Error())
 		httputil.SendError(w, http.StatusBadRequest, "Failed to parse form data", false)
 		return
 	}
@@ -154,7 +159,7 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	if profileImageHeader != nil {
 		profileImagePath, err = h.service.SaveProfileImage(userID, profileImageHeader)
 		if err != nil {
-			h.log.Error("Failed to save profile image: " + err.Error())
+			h.log.WithContext(r.Context()).Error("Failed to save profile image: " + err.Error())
 			httputil.SendError(w, http.StatusInternalServerError, "Failed to save profile image", false)
 			return
 		}
@@ -168,7 +173,7 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	if bannerImageHeader != nil {
 		bannerImagePath, err = h.service.SaveBannerImage(userID, bannerImageHeader)
 		if err != nil {
-			h.log.Error("Failed to save banner image: " + err.Error())
+			h.log.WithContext(r.Context()).Error("Failed to save banner image: " + err.Error())
 			httputil.SendError(w, http.StatusInternalServerError, "Failed to save banner image", false)
 			return
 		}
@@ -180,7 +185,7 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	err = h.service.UpdateProfile(userID, profileData)
 	if err != nil {
-		h.log.Error("Failed to update profile: " + err.Error())
+		h.log.WithContext(r.Context()).Error("Failed to update profile: " + err.Error())
 		httputil.SendError(w, http.StatusInternalServerError, "Failed to update profile", false)
 		return
 	}
@@ -188,7 +193,7 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	// Fetch the complete updated profile data
 	updatedProfile, err := h.service.GetProfileByUserID(userID)
 	if err != nil {
-		h.log.Error("Failed to get updated profile: " + err.Error())
+		h.log.WithContext(r.Context()).Error("Failed to get updated profile: " + err.Error())
 		// Even if this fails, we still return success since the update worked
 		httputil.SendJSON(w, http.StatusOK, map[string]interface{}{
 			"success": true,
@@ -0,0 +1,15 @@
+package profile
+
+import (
+	"database/sql"
+)
+
+// PostgresRepository implements Repository for PostgreSQL using the profile queries of SQLiteRepository
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository on a database opened with postgres.New
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &PostgresRepository{&SQLiteRepository{db: db}}
+}
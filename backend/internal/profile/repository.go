@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/Athooh/social-network/pkg/user"
 )
 
 // SQLiteRepository implements Repository interface for SQLite
@@ -18,6 +20,8 @@ type Repository interface {
 	UpdateUserProfile(userID string, profileData map[string]interface{}) error
 	GetUserProfileByID(userID string) (*UserProfileData, error)
 	IsUserProfilePublic(userID string) (bool, error)
+	IsUserDeactivated(userID string) (bool, error)
+	IsUserBlocked(userID, otherID string) (bool, error)
 	IsUserFollowing(followerID string, followingID string) (bool, error)
 }
 
@@ -41,6 +45,16 @@ func (r *SQLiteRepository) IsUserProfilePublic(userID string) (bool, error) {
 	return isPublic, nil
 }
 
+// IsUserDeactivated reports whether a user deactivated their account, or had it deleted
+func (r *SQLiteRepository) IsUserDeactivated(userID string) (bool, error) {
+	return user.IsDeactivated(r.db, userID)
+}
+
+// IsUserBlocked reports whether either user blocked the other
+func (r *SQLiteRepository) IsUserBlocked(userID, otherID string) (bool, error) {
+	return user.IsBlocked(r.db, userID, otherID)
+}
+
 func (r *SQLiteRepository) IsUserFollowing(followerID string, followingID string) (bool, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM followers WHERE follower_id = ? AND following_id = ?`
@@ -179,7 +193,7 @@ func (r *SQLiteRepository) GetUserProfileByID(userID string) (*UserProfileData,
 		COALESCE(p.interests, '') as interests, 
 		COALESCE(p.banner_image, '') as banner_image, 
 		COALESCE(p.profile_image, '') as profile_image, 
-		COALESCE(p.is_private, 0) as is_private, 
+		COALESCE(p.is_private, FALSE) as is_private, 
 		COALESCE(p.created_at, u.created_at) as profile_created_at, 
 		COALESCE(p.updated_at, u.updated_at) as profile_updated_at,
 		COALESCE(us.followers_count, 0) AS followers_count,
@@ -10,6 +10,9 @@ import (
 	"time"
 )
 
+// ErrProfileNotFound is returned for profiles of deactivated or deleted accounts
+var ErrProfileNotFound = errors.New("profile not found")
+
 // Service interface defines the operations for profile management
 type Service interface {
 	UpdateProfile(userID string, profileData map[string]interface{}) error
@@ -142,6 +145,21 @@ func (s *ProfileService) ValidateProfileViewRequest(userID string, targetID stri
 	if userID == targetID {
 		return true, nil
 	}
+	deactivated, err := s.repo.IsUserDeactivated(targetID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check account status: %w", err)
+	}
+	if deactivated {
+		return false, ErrProfileNotFound
+	}
+	// Users who blocked one another don't exist for each other
+	blocked, err := s.repo.IsUserBlocked(userID, targetID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if blocked {
+		return false, ErrProfileNotFound
+	}
 	isPublic, err := s.repo.IsUserProfilePublic(targetID)
 	if err != nil {
 		return false, fmt.Errorf("failed to check profile visibility: %w", err)
@@ -3,17 +3,23 @@ package server
 import (
 	"net/http"
 
+	"github.com/Athooh/social-network/internal/account"
 	"github.com/Athooh/social-network/internal/auth"
+	"github.com/Athooh/social-network/internal/backup"
 	"github.com/Athooh/social-network/internal/chat"
 	"github.com/Athooh/social-network/internal/event"
+	"github.com/Athooh/social-network/internal/export"
 	"github.com/Athooh/social-network/internal/follow"
 	"github.com/Athooh/social-network/internal/group"
+	"github.com/Athooh/social-network/internal/health"
 	notifications "github.com/Athooh/social-network/internal/notifcations"
 	"github.com/Athooh/social-network/internal/post"
 	"github.com/Athooh/social-network/internal/profile"
+	"github.com/Athooh/social-network/internal/stats"
 	websocketHandler "github.com/Athooh/social-network/internal/websocket"
 	"github.com/Athooh/social-network/pkg/httputil"
 	"github.com/Athooh/social-network/pkg/logger"
+	"github.com/Athooh/social-network/pkg/metrics"
 	"github.com/Athooh/social-network/pkg/middleware"
 )
 
@@ -47,7 +53,7 @@ func (rg *RouteGroup) HandleFunc(pattern string, handlerFunc http.HandlerFunc) {
 func (rg *RouteGroup) Register(mux *http.ServeMux) {
 	for pattern, handler := range rg.routes {
 		fullPattern := rg.prefix + pattern
-		mux.Handle(fullPattern, rg.middleware(handler))
+		mux.Handle(fullPattern, metrics.InstrumentRoute(fullPattern, rg.middleware(handler)))
 	}
 }
 
@@ -62,6 +68,11 @@ type RouterConfig struct {
 	ChatHandler         *chat.Handler
 	ProfileHandler      *profile.Handler
 	NotificationHanlder *notifications.Handler
+	BackupHandler       *backup.Handler
+	StatsHandler        *stats.Handler
+	ExportHandler       *export.Handler
+	AccountHandler      *account.Handler
+	HealthHandler       *health.Handler
 	AuthMiddleware      func(http.Handler) http.Handler
 	JWTMiddleware       func(http.Handler) http.Handler
 	Logger              *logger.Logger
@@ -77,27 +88,20 @@ func Router(config RouterConfig) http.Handler {
 	loggingMiddleware := config.Logger.HTTPMiddleware
 	publicRouteMiddleware := middlewareChain(middleware.CorsMiddleware, loggingMiddleware)
 	authenticatedRouteMiddleware := middlewareChain(middleware.CorsMiddleware, config.JWTMiddleware, config.AuthMiddleware, loggingMiddleware)
+	// Routes authenticated by a token in their URL keep it out of the access logs
+	tokenRouteMiddleware := middlewareChain(middleware.CorsMiddleware, config.Logger.HTTPMiddlewareRedacting("token"))
 	wsMiddleware := middlewareChain(middleware.CorsMiddleware, config.JWTMiddleware, config.AuthMiddleware)
 
-	// Health check
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	// Health checks, /health predates the probes and reports readiness
+	mux.HandleFunc("/livez", config.HealthHandler.Livez)
+	mux.HandleFunc("/readyz", config.HealthHandler.Readyz)
+	mux.HandleFunc("/health", config.HealthHandler.Readyz)
 
 	// Create route groups
 	publicAuthGroup := NewRouteGroup("/api/auth", publicRouteMiddleware)
 	publicAuthGroup.HandleFunc("/register", config.AuthHandler.Register)
 	publicAuthGroup.HandleFunc("/login", config.AuthHandler.LoginJWT)
-	publicAuthGroup.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
-	})
+	publicAuthGroup.HandleFunc("/health", config.HealthHandler.Readyz)
 
 	protectedAuthGroup := NewRouteGroup("/api/auth", authenticatedRouteMiddleware)
 	protectedAuthGroup.HandleFunc("/logout", config.AuthHandler.Logout)
@@ -127,6 +131,8 @@ func Router(config RouterConfig) http.Handler {
 	protectedFollowGroup.HandleFunc("/followers", config.FollowHandler.GetFollowers)
 	protectedFollowGroup.HandleFunc("/following", config.FollowHandler.GetFollowing)
 	protectedFollowGroup.HandleFunc("/is-following", config.FollowHandler.IsFollowing)
+	protectedFollowGroup.HandleFunc("/blocks", config.FollowHandler.HandleBlocks)
+	protectedFollowGroup.HandleFunc("/mutes", config.FollowHandler.HandleMutes)
 
 	protectedPostGroup := NewRouteGroup("/api/posts", authenticatedRouteMiddleware)
 	protectedPostGroup.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
@@ -135,6 +141,8 @@ func Router(config RouterConfig) http.Handler {
 			config.PostHandler.CreatePost(w, r)
 		case http.MethodGet:
 			config.PostHandler.GetFeedPosts(w, r)
+		case http.MethodPut:
+			config.PostHandler.UpdatePost(w, r)
 		case http.MethodDelete:
 			config.PostHandler.DeletePost(w, r)
 		default:
@@ -142,9 +150,20 @@ func Router(config RouterConfig) http.Handler {
 		}
 	})
 	protectedPostGroup.HandleFunc("/comments/", config.PostHandler.HandleComments)
+	protectedPostGroup.HandleFunc("/comments/replies/", config.PostHandler.GetCommentReplies)
+	protectedPostGroup.HandleFunc("/comments/like/", config.PostHandler.LikeComment)
 	protectedPostGroup.HandleFunc("/user/", config.PostHandler.GetUserPosts)
 	protectedPostGroup.HandleFunc("/photos/", config.PostHandler.GetUserPhotos)
 	protectedPostGroup.HandleFunc("/like/", config.PostHandler.LikePost)
+	protectedPostGroup.HandleFunc("/reactions/", config.PostHandler.HandleReactions)
+	protectedPostGroup.HandleFunc("/share", config.PostHandler.SharePost)
+	protectedPostGroup.HandleFunc("/revisions", config.PostHandler.GetRevisions)
+	protectedPostGroup.HandleFunc("/audiences", config.PostHandler.HandleAudienceLists)
+	protectedPostGroup.HandleFunc("/audiences/members", config.PostHandler.HandleAudienceListMembers)
+
+	protectedTagGroup := NewRouteGroup("/api/tags", authenticatedRouteMiddleware)
+	protectedTagGroup.HandleFunc("/trending", config.PostHandler.GetTrendingTopics)
+	protectedTagGroup.HandleFunc("/", config.PostHandler.GetTagPosts)
 
 	// Add group routes
 	protectedGroupGroup := NewRouteGroup("/api/groups", authenticatedRouteMiddleware)
@@ -187,6 +206,26 @@ func Router(config RouterConfig) http.Handler {
 	protectedGroupGroup.HandleFunc("/reject-request", config.GroupHandler.RejectJoinRequest)
 	protectedGroupGroup.HandleFunc("/update-role", config.GroupHandler.UpdateMemberRole)
 	protectedGroupGroup.HandleFunc("/remove-member", config.GroupHandler.RemoveMember)
+	protectedGroupGroup.HandleFunc("/transfer-ownership", config.GroupHandler.TransferOwnership)
+	protectedGroupGroup.HandleFunc("/review-requests", config.GroupHandler.ReviewJoinRequests)
+	protectedGroupGroup.HandleFunc("/invite-links", config.GroupHandler.HandleInviteLinks)
+	protectedGroupGroup.HandleFunc("/join-link", config.GroupHandler.JoinWithInviteLink)
+	protectedGroupGroup.HandleFunc("/join-questions", config.GroupHandler.HandleJoinQuestions)
+	protectedGroupGroup.HandleFunc("/auto-approve", config.GroupHandler.UpdateAutoApproveRules)
+	protectedGroupGroup.HandleFunc("/roles", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			config.GroupHandler.GetGroupRoles(w, r)
+		case http.MethodPost:
+			config.GroupHandler.CreateGroupRole(w, r)
+		case http.MethodPut:
+			config.GroupHandler.UpdateGroupRole(w, r)
+		case http.MethodDelete:
+			config.GroupHandler.DeleteGroupRole(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
 
 	protectedGroupGroup.HandleFunc("/posts", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -194,12 +233,24 @@ func Router(config RouterConfig) http.Handler {
 			config.GroupHandler.CreateGroupPost(w, r)
 		case http.MethodGet:
 			config.GroupHandler.GetGroupPosts(w, r)
+		case http.MethodPut:
+			config.GroupHandler.UpdateGroupPost(w, r)
 		case http.MethodDelete:
 			config.GroupHandler.DeleteGroupPost(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	protectedGroupGroup.HandleFunc("/posts/revisions", config.GroupHandler.GetGroupPostRevisions)
+	protectedGroupGroup.HandleFunc("/posts/pin", config.GroupHandler.PinGroupPost)
+
+	// Moderation routes
+	protectedGroupGroup.HandleFunc("/moderation/settings", config.GroupHandler.UpdateModerationSettings)
+	protectedGroupGroup.HandleFunc("/moderation/queue", config.GroupHandler.GetModerationQueue)
+	protectedGroupGroup.HandleFunc("/moderation/review", config.GroupHandler.ReviewGroupPost)
+	protectedGroupGroup.HandleFunc("/bans", config.GroupHandler.HandleBans)
+	protectedGroupGroup.HandleFunc("/mutes", config.GroupHandler.HandleMutes)
+	protectedGroupGroup.HandleFunc("/audit-log", config.GroupHandler.GetAuditLog)
 
 	// Add Event routes
 	protectedGroupGroup.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
@@ -226,10 +277,20 @@ func Router(config RouterConfig) http.Handler {
 			config.EventHandler.RespondToEvent(w, r)
 		case http.MethodGet:
 			config.EventHandler.GetEventResponses(w, r)
+		case http.MethodDelete:
+			config.EventHandler.RemoveEventResponse(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	protectedGroupGroup.HandleFunc("/events/cancel-occurrence", config.EventHandler.CancelOccurrence)
+	protectedGroupGroup.HandleFunc("/events/ics", config.EventHandler.ExportEvent)
+
+	// Calendar feed routes, the feed itself is read by calendar apps with the token in its URL
+	protectedCalendarGroup := NewRouteGroup("/api/calendar", authenticatedRouteMiddleware)
+	protectedCalendarGroup.HandleFunc("/feed-url", config.EventHandler.HandleFeedURL)
+	publicCalendarGroup := NewRouteGroup("/api/calendar", tokenRouteMiddleware)
+	publicCalendarGroup.HandleFunc("/feed.ics", config.EventHandler.CalendarFeed)
 
 	// Add Chat routes
 	chatGroup := NewRouteGroup("/api/chat", authenticatedRouteMiddleware)
@@ -255,6 +316,26 @@ func Router(config RouterConfig) http.Handler {
 		}
 	})
 	protectedNotificationGroup.HandleFunc("/read", config.NotificationHanlder.MarkAllNotificationsAsRead)
+
+	// Personal data export routes, archives are downloaded with the token of their link
+	protectedExportGroup := NewRouteGroup("/api/exports", authenticatedRouteMiddleware)
+	protectedExportGroup.HandleFunc("", config.ExportHandler.HandleExports)
+	publicExportGroup := NewRouteGroup("/api/exports", tokenRouteMiddleware)
+	publicExportGroup.HandleFunc("/download", config.ExportHandler.HandleDownload)
+
+	// Account routes, both ask for the password again before logging the user out
+	protectedAccountGroup := NewRouteGroup("/api/account", authenticatedRouteMiddleware)
+	protectedAccountGroup.HandleFunc("/deactivate", config.AccountHandler.HandleDeactivate)
+	protectedAccountGroup.HandleFunc("/delete", config.AccountHandler.HandleDelete)
+
+	// Admin routes, the handlers check that the user is an administrator. Backups are only
+	// set up for SQLite.
+	protectedAdminGroup := NewRouteGroup("/api/admin", authenticatedRouteMiddleware)
+	if config.BackupHandler != nil {
+		protectedAdminGroup.HandleFunc("/backups", config.BackupHandler.HandleBackups)
+	}
+	protectedAdminGroup.HandleFunc("/stats/reconcile", config.StatsHandler.HandleReconcile)
+
 	// Add WebSocket route
 	wsRoute := NewRouteGroup("/ws", wsMiddleware)
 	wsRoute.HandleFunc("", config.WSHandler.HandleConnection)
@@ -263,18 +344,25 @@ func Router(config RouterConfig) http.Handler {
 	publicAuthGroup.Register(mux)
 	protectedAuthGroup.Register(mux)
 	protectedPostGroup.Register(mux)
+	protectedTagGroup.Register(mux)
 	protectedFollowGroup.Register(mux)
 	protectedGroupGroup.Register(mux)
+	protectedCalendarGroup.Register(mux)
+	publicCalendarGroup.Register(mux)
 	protectedNotificationGroup.Register(mux)
 	protectedUserGroup.Register(mux)
 	chatGroup.Register(mux)
+	protectedExportGroup.Register(mux)
+	publicExportGroup.Register(mux)
+	protectedAccountGroup.Register(mux)
+	protectedAdminGroup.Register(mux)
 	wsRoute.Register(mux)
 
 	// Serve static files
 	fileServer := http.FileServer(http.Dir(config.UploadDir))
 	mux.Handle("/uploads/", http.StripPrefix("/uploads/", fileServer))
 
-	return mux
+	return logger.RequestIDMiddleware(mux)
 }
 
 func middlewareChain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
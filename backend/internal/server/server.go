@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -16,6 +17,10 @@ import (
 type Server struct {
 	server *http.Server
 	logger *logger.Logger
+	config Config
+
+	beforeShutdown []func()
+	afterShutdown  []func(ctx context.Context) error
 }
 
 // Config holds the server configuration
@@ -25,6 +30,11 @@ type Config struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+
+	// ShutdownDelay keeps serving after the server is marked not ready so load balancers
+	// notice before the listener closes, ShutdownTimeout bounds the rest of the shutdown
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
 }
 
 // DefaultConfig returns the default server configuration
@@ -35,6 +45,8 @@ func DefaultConfig() Config {
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
+
+		ShutdownTimeout: 30 * time.Second,
 	}
 }
 
@@ -50,12 +62,30 @@ func New(config Config, handler http.Handler, logger *logger.Logger) *Server {
 		IdleTimeout:  config.IdleTimeout,
 	}
 
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = 30 * time.Second
+	}
+
 	return &Server{
 		server: server,
 		logger: logger,
+		config: config,
 	}
 }
 
+// BeforeShutdown registers fn to run as soon as shutdown starts, while requests are
+// still served, e.g. to fail readiness probes
+func (s *Server) BeforeShutdown(fn func()) {
+	s.beforeShutdown = append(s.beforeShutdown, fn)
+}
+
+// AfterShutdown registers fn to run once the listener is closed and in-flight requests
+// have finished, for connections http.Server does not track such as hijacked websockets.
+// Functions run in the order they were registered within the shutdown timeout.
+func (s *Server) AfterShutdown(fn func(ctx context.Context) error) {
+	s.afterShutdown = append(s.afterShutdown, fn)
+}
+
 // Start starts the server
 func (s *Server) Start() error {
 	// Channel for server errors
@@ -87,16 +117,36 @@ func (s *Server) Start() error {
 func (s *Server) Shutdown() error {
 	s.logger.Info("Shutting down server...")
 
+	for _, fn := range s.beforeShutdown {
+		fn()
+	}
+	if s.config.ShutdownDelay > 0 {
+		s.logger.Info("Waiting %s before closing the listener", s.config.ShutdownDelay)
+		time.Sleep(s.config.ShutdownDelay)
+	}
+
 	// Create a context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
 	defer cancel()
 
 	// Shutdown the server
+	var errs []error
 	if err := s.server.Shutdown(ctx); err != nil {
 		s.logger.Error("Server shutdown error: %v", err)
-		return err
+		errs = append(errs, err)
 	}
 
+	// Close what http.Server left open, even if in-flight requests ran out of time
+	for _, fn := range s.afterShutdown {
+		if err := fn(ctx); err != nil {
+			s.logger.Error("Server shutdown error: %v", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
 	s.logger.Info("Server shutdown complete")
 	return nil
 }
@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"net/http"
+
+	"github.com/Athooh/social-network/internal/auth"
+	"github.com/Athooh/social-network/pkg/httputil"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+// Handler handles HTTP requests for counter reconciliation
+type Handler struct {
+	reconciler *Reconciler
+	log        *logger.Logger
+	admins     map[string]bool
+}
+
+// NewHandler creates a new reconciliation handler that only lets adminIDs in
+func NewHandler(reconciler *Reconciler, log *logger.Logger, adminIDs []string) *Handler {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+
+	return &Handler{
+		reconciler: reconciler,
+		log:        log,
+		admins:     admins,
+	}
+}
+
+// HandleReconcile reports drifted counters on GET and repairs them on POST
+func (h *Handler) HandleReconcile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok || userID <= "" {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if !h.admins[userID] {
+		h.sendError(w, http.StatusForbidden, "Only administrators can reconcile counters")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		report, err := h.reconciler.Check()
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to check counters: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "Failed to check counters")
+			return
+		}
+		h.sendJSON(w, http.StatusOK, report)
+
+	case http.MethodPost:
+		report, err := h.reconciler.Repair()
+		if err != nil {
+			h.log.WithContext(r.Context()).Error("Failed to repair counters: %v", err)
+			h.sendError(w, http.StatusInternalServerError, "Failed to repair counters")
+			return
+		}
+		h.log.WithContext(r.Context()).Info("Counters reconciled by %s, %d users and %d posts repaired", userID, len(report.Users), len(report.Posts))
+		h.sendJSON(w, http.StatusOK, report)
+
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// Helper method to send JSON responses
+func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	httputil.SendJSON(w, status, data)
+}
+
+// Helper method to send error responses
+func (h *Handler) sendError(w http.ResponseWriter, status int, message string) {
+	httputil.SendError(w, status, message, status < 500)
+}
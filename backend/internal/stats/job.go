@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+// Job periodically repairs counters that have drifted
+type Job struct {
+	reconciler *Reconciler
+	log        *logger.Logger
+	interval   time.Duration
+	stop       chan struct{}
+}
+
+// NewJob creates a new scheduled reconciliation job
+func NewJob(reconciler *Reconciler, log *logger.Logger, interval time.Duration) *Job {
+	return &Job{
+		reconciler: reconciler,
+		log:        log,
+		interval:   interval,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Run reconciles the counters immediately and then on every interval until Stop is called
+func (j *Job) Run() {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.Reconcile()
+	for {
+		select {
+		case <-ticker.C:
+			j.Reconcile()
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the job
+func (j *Job) Stop() {
+	close(j.stop)
+}
+
+// Reconcile repairs the counters that have drifted and logs what it found
+func (j *Job) Reconcile() {
+	report, err := j.reconciler.Repair()
+	if err != nil {
+		j.log.Error("Failed to reconcile counters: %v", err)
+	}
+	if report == nil {
+		return
+	}
+
+	if report.Drifted() {
+		j.log.Warn("Repaired counters that had drifted, %d users and %d posts", len(report.Users), len(report.Posts))
+		return
+	}
+	j.log.Debug("Counters checked, nothing had drifted")
+}
@@ -0,0 +1,45 @@
+package stats
+
+import "time"
+
+// UserCounts are the counters kept in user_stats for one user
+type UserCounts struct {
+	PostsCount     int `json:"postsCount"`
+	GroupsJoined   int `json:"groupsJoined"`
+	FollowersCount int `json:"followersCount"`
+	FollowingCount int `json:"followingCount"`
+}
+
+// UserDrift is a user whose stored counters differ from the source tables
+type UserDrift struct {
+	UserID string     `json:"userId"`
+	Stored UserCounts `json:"stored"`
+	Actual UserCounts `json:"actual"`
+}
+
+// PostCounts are the counters kept on a post or group post
+type PostCounts struct {
+	LikesCount    int `json:"likesCount"`
+	CommentsCount int `json:"commentsCount"`
+}
+
+// PostDrift is a post whose stored counters differ from the source tables
+type PostDrift struct {
+	Table  string     `json:"table"` // posts or group_posts
+	PostID int64      `json:"postId"`
+	Stored PostCounts `json:"stored"`
+	Actual PostCounts `json:"actual"`
+}
+
+// Report lists the counters that had drifted when a reconciliation ran
+type Report struct {
+	CheckedAt time.Time    `json:"checkedAt"`
+	Repaired  bool         `json:"repaired"`
+	Users     []*UserDrift `json:"users"`
+	Posts     []*PostDrift `json:"posts"`
+}
+
+// Drifted reports whether any counter had drifted
+func (r *Report) Drifted() bool {
+	return len(r.Users) > 0 || len(r.Posts) > 0
+}
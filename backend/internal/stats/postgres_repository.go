@@ -0,0 +1,16 @@
+package stats
+
+import (
+	"database/sql"
+)
+
+// PostgresRepository implements Repository interface for PostgreSQL. The reconciliation
+// queries only use portable SQL, upserts included, so it reuses SQLiteRepository.
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository on a database opened with postgres.New
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{&SQLiteRepository{db: db}}
+}
@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/websocket"
+	"github.com/Athooh/social-network/pkg/websocket/events"
+)
+
+// Reconciler recomputes the denormalized counters from the tables they summarize.
+// The counters are kept up to date by hand as things change, and drift whenever one of
+// those steps fails or rows disappear through ON DELETE CASCADE.
+type Reconciler struct {
+	repo Repository
+	hub  *websocket.Hub
+}
+
+// NewReconciler creates a new reconciler. hub may be nil, corrected stats are then only
+// seen the next time clients load them.
+func NewReconciler(repo Repository, hub *websocket.Hub) *Reconciler {
+	return &Reconciler{
+		repo: repo,
+		hub:  hub,
+	}
+}
+
+// Check reports the counters that have drifted without changing them
+func (r *Reconciler) Check() (*Report, error) {
+	users, err := r.repo.GetUserDrift()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user stats: %w", err)
+	}
+
+	posts, err := r.repo.GetPostDrift()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check post counters: %w", err)
+	}
+
+	return &Report{CheckedAt: time.Now(), Users: users, Posts: posts}, nil
+}
+
+// Repair recomputes every counter that has drifted and sends the users whose stats
+// changed their corrected values. The report holds the values that were stored.
+func (r *Reconciler) Repair() (*Report, error) {
+	report, err := r.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, d := range report.Users {
+		counts, err := r.repo.RepairUserStats(d.UserID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to repair stats of user %s: %w", d.UserID, err))
+			continue
+		}
+		d.Actual = *counts
+		r.publish(d)
+	}
+
+	for _, d := range report.Posts {
+		if err := r.repo.RepairPostCounts(d.Table, d.PostID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to repair counters of %s %d: %w", d.Table, d.PostID, err))
+		}
+	}
+
+	report.Repaired = len(errs) == 0
+	return report, errors.Join(errs...)
+}
+
+// publish sends a user the stats that changed, with the stats types the clients know
+func (r *Reconciler) publish(d *UserDrift) {
+	if r.hub == nil {
+		return
+	}
+
+	changes := []struct {
+		statsType      string
+		stored, actual int
+	}{
+		{"Posts", d.Stored.PostsCount, d.Actual.PostsCount},
+		{"Groups", d.Stored.GroupsJoined, d.Actual.GroupsJoined},
+		{"Followers", d.Stored.FollowersCount, d.Actual.FollowersCount},
+		{"Following", d.Stored.FollowingCount, d.Actual.FollowingCount},
+	}
+
+	for _, c := range changes {
+		if c.stored == c.actual {
+			continue
+		}
+		r.hub.BroadcastToUser(d.UserID, events.Event{
+			Type: events.UserStatsUpdated,
+			Payload: events.UserStatsUpdatedPayload{
+				UserID:    d.UserID,
+				StatsType: c.statsType,
+				Count:     c.actual,
+			},
+		})
+	}
+}
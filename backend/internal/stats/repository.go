@@ -0,0 +1,178 @@
+package stats
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// postTables are the tables with post counters, group posts share their ids and their
+// likes and comments with posts
+var postTables = []string{"posts", "group_posts"}
+
+// Repository defines the interface for recomputing denormalized counters
+type Repository interface {
+	// GetUserDrift lists the users whose user_stats differ from the source tables
+	GetUserDrift() ([]*UserDrift, error)
+	// GetPostDrift lists the posts and group posts whose counters differ from the source tables
+	GetPostDrift() ([]*PostDrift, error)
+
+	// RepairUserStats recomputes a user's stats and returns the stored values
+	RepairUserStats(userID string) (*UserCounts, error)
+	// RepairPostCounts recomputes the counters of a post in table
+	RepairPostCounts(table string, postID int64) error
+}
+
+// SQLiteRepository implements Repository interface for SQLite
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite repository
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// GetUserDrift lists the users whose user_stats differ from the source tables. A user
+// without a user_stats row has drifted only if they have something to count.
+func (r *SQLiteRepository) GetUserDrift() ([]*UserDrift, error) {
+	query := `
+		SELECT id, stored_posts, stored_groups, stored_followers, stored_following,
+			actual_posts, actual_groups, actual_followers, actual_following
+		FROM (
+			SELECT u.id,
+				COALESCE(s.posts_count, 0) AS stored_posts,
+				COALESCE(s.groups_joined, 0) AS stored_groups,
+				COALESCE(s.followers_count, 0) AS stored_followers,
+				COALESCE(s.following_count, 0) AS stored_following,
+				(SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id) AS actual_posts,
+				(SELECT COUNT(*) FROM group_members gm WHERE gm.user_id = u.id AND gm.status = 'accepted') AS actual_groups,
+				(SELECT COUNT(*) FROM followers f WHERE f.following_id = u.id) AS actual_followers,
+				(SELECT COUNT(*) FROM followers f WHERE f.follower_id = u.id) AS actual_following
+			FROM users u
+			LEFT JOIN user_stats s ON s.user_id = u.id
+		) AS counts
+		WHERE stored_posts <> actual_posts
+			OR stored_groups <> actual_groups
+			OR stored_followers <> actual_followers
+			OR stored_following <> actual_following
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drift []*UserDrift
+	for rows.Next() {
+		var d UserDrift
+		err := rows.Scan(
+			&d.UserID,
+			&d.Stored.PostsCount, &d.Stored.GroupsJoined, &d.Stored.FollowersCount, &d.Stored.FollowingCount,
+			&d.Actual.PostsCount, &d.Actual.GroupsJoined, &d.Actual.FollowersCount, &d.Actual.FollowingCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		drift = append(drift, &d)
+	}
+
+	return drift, rows.Err()
+}
+
+// GetPostDrift lists the posts and group posts whose counters differ from the source tables
+func (r *SQLiteRepository) GetPostDrift() ([]*PostDrift, error) {
+	var drift []*PostDrift
+	for _, table := range postTables {
+		query := fmt.Sprintf(`
+			SELECT id, stored_likes, stored_comments, actual_likes, actual_comments
+			FROM (
+				SELECT t.id,
+					COALESCE(t.likes_count, 0) AS stored_likes,
+					COALESCE(t.comments_count, 0) AS stored_comments,
+					(SELECT COUNT(*) FROM post_likes l WHERE l.post_id = t.id) AS actual_likes,
+					(SELECT COUNT(*) FROM comments c WHERE c.post_id = t.id) AS actual_comments
+				FROM %s t
+			) AS counts
+			WHERE stored_likes <> actual_likes OR stored_comments <> actual_comments
+			ORDER BY id
+		`, table)
+
+		rows, err := r.db.Query(query)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			d := PostDrift{Table: table}
+			err := rows.Scan(&d.PostID, &d.Stored.LikesCount, &d.Stored.CommentsCount, &d.Actual.LikesCount, &d.Actual.CommentsCount)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			drift = append(drift, &d)
+		}
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return drift, nil
+}
+
+// RepairUserStats recomputes a user's stats in one statement, so increments made by
+// other requests in the meantime are not lost, creating the user_stats row if needed
+func (r *SQLiteRepository) RepairUserStats(userID string) (*UserCounts, error) {
+	query := `
+		INSERT INTO user_stats (user_id, posts_count, groups_joined, followers_count, following_count, created_at, updated_at)
+		SELECT u.id,
+			(SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id),
+			(SELECT COUNT(*) FROM group_members gm WHERE gm.user_id = u.id AND gm.status = 'accepted'),
+			(SELECT COUNT(*) FROM followers f WHERE f.following_id = u.id),
+			(SELECT COUNT(*) FROM followers f WHERE f.follower_id = u.id),
+			CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM users u
+		WHERE u.id = ?
+		ON CONFLICT (user_id) DO UPDATE SET
+			posts_count = excluded.posts_count,
+			groups_joined = excluded.groups_joined,
+			followers_count = excluded.followers_count,
+			following_count = excluded.following_count,
+			updated_at = excluded.updated_at
+		RETURNING posts_count, groups_joined, followers_count, following_count
+	`
+
+	var counts UserCounts
+	err := r.db.QueryRow(query, userID).Scan(
+		&counts.PostsCount,
+		&counts.GroupsJoined,
+		&counts.FollowersCount,
+		&counts.FollowingCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &counts, nil
+}
+
+// RepairPostCounts recomputes the counters of a post in table
+func (r *SQLiteRepository) RepairPostCounts(table string, postID int64) error {
+	if table != "posts" && table != "group_posts" {
+		return fmt.Errorf("unknown post table %q", table)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET likes_count = (SELECT COUNT(*) FROM post_likes l WHERE l.post_id = ?),
+			comments_count = (SELECT COUNT(*) FROM comments c WHERE c.post_id = ?)
+		WHERE id = ?
+	`, table)
+
+	_, err := r.db.Exec(query, postID, postID, postID)
+	return err
+}
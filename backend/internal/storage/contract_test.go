@@ -0,0 +1,1854 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Athooh/social-network/internal/event"
+	"github.com/Athooh/social-network/pkg/db/postgres"
+	"github.com/Athooh/social-network/pkg/db/sqlite"
+	"github.com/Athooh/social-network/pkg/ical"
+	"github.com/Athooh/social-network/pkg/logger"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/Athooh/social-network/pkg/user"
+)
+
+// The contract tests run the same checks against every backend. SQLite always runs,
+// PostgreSQL runs when TEST_POSTGRES_DSN points at a server the tests may create and
+// drop schemas on, e.g. make test-postgres.
+
+func TestMain(m *testing.M) {
+	logger.Init(logger.Config{
+		Level:         logger.WARN,
+		OutputType:    logger.ConsoleOutput,
+		ConsoleOutput: os.Stderr,
+	})
+	os.Exit(m.Run())
+}
+
+// backend opens a fresh, migrated database and returns its repositories
+type backend struct {
+	name string
+	open func(t *testing.T) *Repositories
+}
+
+func backends() []backend {
+	list := []backend{{name: "sqlite", open: openSQLite}}
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		list = append(list, backend{name: "postgres", open: func(t *testing.T) *Repositories {
+			return openPostgres(t, dsn)
+		}})
+	}
+	return list
+}
+
+func openSQLite(t *testing.T) *Repositories {
+	t.Helper()
+
+	db, err := sqlite.New(sqlite.Config{
+		DBPath:         filepath.Join(t.TempDir(), "contract.db"),
+		MigrationsPath: filepath.Join("..", "..", "pkg", "db", "migrations", "sqlite"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := sqlite.ApplyMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	return NewSQLiteRepositories(db.DB)
+}
+
+// openPostgres migrates a new schema, dropped again when the test ends
+func openPostgres(t *testing.T, dsn string) *Repositories {
+	t.Helper()
+
+	admin, err := postgres.New(postgres.Config{URL: dsn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("contract_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+	})
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	db, err := postgres.New(postgres.Config{
+		URL:            u.String(),
+		MigrationsPath: filepath.Join("..", "..", "pkg", "db", "migrations", "postgres"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := postgres.ApplyMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	return NewPostgresRepositories(db.DB)
+}
+
+func TestRepositoryContract(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repos *Repositories)
+	}{
+		{"users", testUsers},
+		{"sessions", testSessions},
+		{"statuses", testStatuses},
+		{"follows", testFollows},
+		{"blocks", testBlocks},
+		{"posts", testPosts},
+		{"audience lists", testAudienceLists},
+		{"comments", testComments},
+		{"tags", testTags},
+		{"groups", testGroups},
+		{"group roles", testGroupRoles},
+		{"group moderation", testGroupModeration},
+		{"group joining", testGroupJoining},
+		{"events", testEvents},
+		{"chat", testChat},
+		{"profiles", testProfiles},
+		{"notifications", testNotifications},
+		{"stats", testStats},
+		{"jobs", testJobs},
+		{"exports", testExports},
+		{"accounts", testAccounts},
+	}
+
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.run(t, b.open(t))
+				})
+			}
+		})
+	}
+}
+
+// createUser adds a public user with the given nickname
+func createUser(t *testing.T, repos *Repositories, nickname string) *user.User {
+	t.Helper()
+
+	u := &user.User{
+		Email:       nickname + "@example.com",
+		Password:    "hash",
+		FirstName:   nickname,
+		LastName:    "Tester",
+		DateOfBirth: "1990-01-01",
+		Nickname:    nickname,
+		IsPublic:    true,
+	}
+	if err := repos.Users.Create(u); err != nil {
+		t.Fatalf("failed to create user %s: %v", nickname, err)
+	}
+	return u
+}
+
+// createPost adds a public post by userID
+func createPost(t *testing.T, repos *Repositories, userID, content string) *models.Post {
+	t.Helper()
+
+	p := &models.Post{UserID: userID, Content: content, Privacy: "public"}
+	if err := repos.Posts.CreatePost(p); err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	return p
+}
+
+func testUsers(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+
+	got, err := repos.Users.GetByID(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != alice.Email || got.Nickname != "alice" || !got.IsPublic {
+		t.Errorf("GetByID = %+v, want the created user", got)
+	}
+
+	got, err = repos.Users.GetByEmail("alice@example.com")
+	if err != nil || got.ID != alice.ID {
+		t.Errorf("GetByEmail = %v, %v, want user %s", got, err, alice.ID)
+	}
+
+	if err := repos.Users.Create(&user.User{Email: alice.Email, Password: "x", FirstName: "a", LastName: "b", DateOfBirth: "2000-01-01"}); err == nil {
+		t.Error("creating a second user with the same email succeeded")
+	}
+
+	if err := repos.Users.Delete(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Users.GetByID(alice.ID); err == nil {
+		t.Error("GetByID found a deleted user")
+	}
+}
+
+func testSessions(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	id, err := repos.Sessions.CreateSession(alice.ID, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, gotExpiry, err := repos.Sessions.GetSession(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != alice.ID || !gotExpiry.Equal(expiresAt) {
+		t.Errorf("GetSession = %s, %v, want %s, %v", userID, gotExpiry, alice.ID, expiresAt)
+	}
+
+	if valid, err := repos.Sessions.HasValidSession(alice.ID); err != nil || !valid {
+		t.Errorf("HasValidSession = %v, %v, want true", valid, err)
+	}
+
+	expired, err := repos.Sessions.CreateSession(alice.ID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Sessions.CleanExpired(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := repos.Sessions.GetSession(expired); err == nil {
+		t.Error("CleanExpired kept an expired session")
+	}
+
+	sessions, err := repos.Sessions.GetUserSessions(alice.ID)
+	if err != nil || len(sessions) != 1 {
+		t.Errorf("GetUserSessions = %d sessions, %v, want 1", len(sessions), err)
+	}
+
+	if err := repos.Sessions.DeleteUserSessions(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if valid, _ := repos.Sessions.HasValidSession(alice.ID); valid {
+		t.Error("HasValidSession is true after DeleteUserSessions")
+	}
+}
+
+func testStatuses(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	if err := repos.Statuses.SetUserOnline(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Setting a status twice updates it in place
+	if err := repos.Statuses.SetUserOnline(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if online, err := repos.Statuses.GetUserStatus(alice.ID); err != nil || !online {
+		t.Errorf("GetUserStatus = %v, %v, want online", online, err)
+	}
+
+	if err := repos.Follows.CreateFollower(bob.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	followers, err := repos.Statuses.GetFollowersForStatusUpdate(alice.ID)
+	if err != nil || len(followers) != 1 || followers[0] != bob.ID {
+		t.Errorf("GetFollowersForStatusUpdate = %v, %v, want [%s]", followers, err, bob.ID)
+	}
+
+	online, err := repos.Statuses.GetAllOnlineUsers()
+	if err != nil || len(online) != 1 || online[0] != alice.ID {
+		t.Errorf("GetAllOnlineUsers = %v, %v, want [%s]", online, err, alice.ID)
+	}
+
+	if err := repos.Statuses.SetUserOffline(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if online, _ := repos.Statuses.GetUserStatus(alice.ID); online {
+		t.Error("GetUserStatus is online after SetUserOffline")
+	}
+}
+
+func testFollows(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	carol := createUser(t, repos, "carol")
+
+	if err := repos.Follows.CreateFollowRequest(alice.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	request, err := repos.Follows.GetFollowRequest(alice.ID, bob.ID)
+	if err != nil || request.Status != "pending" {
+		t.Errorf("GetFollowRequest = %+v, %v, want a pending request", request, err)
+	}
+	pending, err := repos.Follows.GetPendingFollowRequests(bob.ID)
+	if err != nil || len(pending) != 1 {
+		t.Errorf("GetPendingFollowRequests = %d, %v, want 1", len(pending), err)
+	}
+	if err := repos.Follows.UpdateFollowRequestStatus(alice.ID, bob.ID, "accepted"); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := repos.Follows.GetPendingFollowRequests(bob.ID); len(pending) != 0 {
+		t.Errorf("GetPendingFollowRequests = %d after accepting, want 0", len(pending))
+	}
+
+	for _, f := range [][2]string{{alice.ID, bob.ID}, {alice.ID, carol.ID}, {carol.ID, bob.ID}} {
+		if err := repos.Follows.CreateFollower(f[0], f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if following, err := repos.Follows.IsFollowing(alice.ID, bob.ID); err != nil || !following {
+		t.Errorf("IsFollowing = %v, %v, want true", following, err)
+	}
+	if count, err := repos.Follows.GetFollowersCount(bob.ID); err != nil || count != 2 {
+		t.Errorf("GetFollowersCount = %d, %v, want 2", count, err)
+	}
+	if count, err := repos.Follows.GetFollowingCount(alice.ID); err != nil || count != 2 {
+		t.Errorf("GetFollowingCount = %d, %v, want 2", count, err)
+	}
+	if followers, err := repos.Follows.GetFollowers(bob.ID, bob.ID); err != nil || len(followers) != 2 {
+		t.Errorf("GetFollowers = %d, %v, want 2", len(followers), err)
+	}
+	if mutual, err := repos.Follows.GetMutualFollowersCount(alice.ID, carol.ID); err != nil || mutual < 0 {
+		t.Errorf("GetMutualFollowersCount = %d, %v", mutual, err)
+	}
+	if public, err := repos.Follows.IsUserProfilePublic(bob.ID); err != nil || !public {
+		t.Errorf("IsUserProfilePublic = %v, %v, want true", public, err)
+	}
+
+	if err := repos.Follows.DeleteFollower(alice.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if following, _ := repos.Follows.IsFollowing(alice.ID, bob.ID); following {
+		t.Error("IsFollowing is true after DeleteFollower")
+	}
+}
+
+func testBlocks(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	carol := createUser(t, repos, "carol")
+
+	post := createPost(t, repos, bob.ID, "hello #gophers")
+	if err := repos.Posts.SetPostTags(post.ID, []string{"gophers"}, post.CreatedAt); err != nil {
+		t.Fatal(err)
+	}
+	createPost(t, repos, carol.ID, "from carol")
+
+	// Blocking twice keeps one block, either user blocking hides both from each other
+	for i := 0; i < 2; i++ {
+		if err := repos.Follows.CreateBlock(alice.ID, bob.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if blocks, err := repos.Follows.GetBlocks(alice.ID); err != nil || len(blocks) != 1 || blocks[0].BlockedID != bob.ID {
+		t.Errorf("GetBlocks = %v, %v, want bob", blocks, err)
+	}
+	for _, pair := range [][2]string{{alice.ID, bob.ID}, {bob.ID, alice.ID}} {
+		if blocked, err := repos.Users.IsBlocked(pair[0], pair[1]); err != nil || !blocked {
+			t.Errorf("IsBlocked(%s, %s) = %v, %v, want true", pair[0], pair[1], blocked, err)
+		}
+	}
+	if blocked, err := repos.Follows.IsBlocked(alice.ID, carol.ID); err != nil || blocked {
+		t.Errorf("IsBlocked(alice, carol) = %v, %v, want false", blocked, err)
+	}
+
+	if canView, err := repos.Posts.CanViewPost(post.ID, alice.ID); err != nil || canView {
+		t.Errorf("CanViewPost of a blocked user's post = %v, %v, want false", canView, err)
+	}
+	if posts, err := repos.Posts.GetFeedPosts(alice.ID, 10, 0); err != nil || len(posts) != 1 || posts[0].UserID != carol.ID {
+		t.Errorf("GetFeedPosts = %d, %v, want carol's post only", len(posts), err)
+	}
+	if posts, err := repos.Posts.GetPostsByTag("gophers", alice.ID, 10, 0); err != nil || len(posts) != 0 {
+		t.Errorf("GetPostsByTag = %d, %v, want none", len(posts), err)
+	}
+	if canSend, err := repos.Chat.CanSendMessage(bob.ID, alice.ID); err != nil || canSend {
+		t.Errorf("CanSendMessage to a blocker = %v, %v, want false", canSend, err)
+	}
+	if blocked, err := repos.Groups.IsBlocked(bob.ID, alice.ID); err != nil || !blocked {
+		t.Errorf("Groups.IsBlocked = %v, %v, want true", blocked, err)
+	}
+	if blocked, err := repos.Profiles.IsUserBlocked(bob.ID, alice.ID); err != nil || !blocked {
+		t.Errorf("Profiles.IsUserBlocked = %v, %v, want true", blocked, err)
+	}
+
+	// Follow lists and chat contacts leave out users blocked with the viewer
+	for _, f := range [][2]string{{carol.ID, alice.ID}, {carol.ID, bob.ID}, {bob.ID, carol.ID}, {alice.ID, bob.ID}, {bob.ID, alice.ID}} {
+		if err := repos.Follows.CreateFollower(f[0], f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if following, err := repos.Follows.GetFollowing(carol.ID, alice.ID); err != nil || len(following) != 1 || following[0].FollowingID != alice.ID {
+		t.Errorf("GetFollowing seen by alice = %+v, %v, want alice only", following, err)
+	}
+	if following, err := repos.Follows.GetFollowing(carol.ID, carol.ID); err != nil || len(following) != 2 {
+		t.Errorf("GetFollowing seen by carol = %d, %v, want 2", len(following), err)
+	}
+	if followers, err := repos.Follows.GetFollowers(carol.ID, alice.ID); err != nil || len(followers) != 0 {
+		t.Errorf("GetFollowers seen by alice = %d, %v, want none", len(followers), err)
+	}
+	if followers, err := repos.Follows.GetFollowers(carol.ID, carol.ID); err != nil || len(followers) != 1 {
+		t.Errorf("GetFollowers seen by carol = %d, %v, want bob", len(followers), err)
+	}
+	if contacts, err := repos.Chat.GetChatContacts(alice.ID); err != nil || len(contacts) != 1 || contacts[0].UserID != carol.ID {
+		t.Errorf("GetChatContacts = %+v, %v, want carol only", contacts, err)
+	}
+	suggestions, err := repos.Follows.GetUsersNotFollowed(bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range suggestions {
+		if u.ID == alice.ID {
+			t.Error("GetUsersNotFollowed suggested a blocker")
+		}
+	}
+
+	if err := repos.Follows.DeleteBlock(alice.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if canView, err := repos.Posts.CanViewPost(post.ID, alice.ID); err != nil || !canView {
+		t.Errorf("CanViewPost after DeleteBlock = %v, %v, want true", canView, err)
+	}
+
+	// Muted users only drop out of the muter's feeds
+	if err := repos.Follows.CreateMute(alice.ID, carol.ID); err != nil {
+		t.Fatal(err)
+	}
+	if mutes, err := repos.Follows.GetMutes(alice.ID); err != nil || len(mutes) != 1 || mutes[0].MutedID != carol.ID {
+		t.Errorf("GetMutes = %v, %v, want carol", mutes, err)
+	}
+	if posts, err := repos.Posts.GetFeedPosts(alice.ID, 10, 0); err != nil || len(posts) != 1 || posts[0].UserID != bob.ID {
+		t.Errorf("GetFeedPosts with carol muted = %d, %v, want bob's post only", len(posts), err)
+	}
+	if posts, _ := repos.Posts.GetFeedPosts(carol.ID, 10, 0); len(posts) != 2 {
+		t.Errorf("GetFeedPosts of the muted user = %d, want 2", len(posts))
+	}
+	if err := repos.Follows.DeleteMute(alice.ID, carol.ID); err != nil {
+		t.Fatal(err)
+	}
+	if posts, _ := repos.Posts.GetFeedPosts(alice.ID, 10, 0); len(posts) != 2 {
+		t.Errorf("GetFeedPosts after DeleteMute = %d, want 2", len(posts))
+	}
+}
+
+func testPosts(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	first := createPost(t, repos, alice.ID, "first")
+	second := createPost(t, repos, alice.ID, "second")
+	if first.ID == 0 || second.ID <= first.ID {
+		t.Fatalf("post ids = %d, %d, want increasing ids", first.ID, second.ID)
+	}
+
+	got, err := repos.Posts.GetPostByID(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Content != "first" || got.UserID != alice.ID || got.SharedPostID.Valid {
+		t.Errorf("GetPostByID = %+v, want the first post", got)
+	}
+
+	// A reshare points at the original and bumps its share count
+	reshare := &models.Post{UserID: bob.ID, Content: "look", Privacy: "public", SharedPostID: sql.NullInt64{Int64: first.ID, Valid: true}}
+	if err := repos.Posts.CreatePost(reshare); err != nil {
+		t.Fatal(err)
+	}
+	got, err = repos.Posts.GetPostByID(reshare.ID)
+	if err != nil || got.SharedPostID.Int64 != first.ID {
+		t.Errorf("reshare SharedPostID = %v, %v, want %d", got, err, first.ID)
+	}
+	if got, _ := repos.Posts.GetPostByID(first.ID); got.SharesCount != 1 {
+		t.Errorf("SharesCount = %d, want 1", got.SharesCount)
+	}
+
+	if previous, err := repos.Posts.SetReaction(first.ID, bob.ID, "love"); err != nil || previous != "" {
+		t.Errorf("SetReaction = %q, %v, want no previous reaction", previous, err)
+	}
+	if previous, err := repos.Posts.SetReaction(first.ID, bob.ID, "like"); err != nil || previous != "love" {
+		t.Errorf("SetReaction = %q, %v, want previous love", previous, err)
+	}
+	if count, err := repos.Posts.GetLikesCount(first.ID); err != nil || count != 1 {
+		t.Errorf("GetLikesCount = %d, %v, want 1", count, err)
+	}
+	counts, err := repos.Posts.GetReactionCounts(first.ID)
+	if err != nil || counts["like"] != 1 || counts["love"] != 0 {
+		t.Errorf("GetReactionCounts = %v, %v, want one like", counts, err)
+	}
+	if removed, err := repos.Posts.RemoveReaction(first.ID, bob.ID); err != nil || removed != "like" {
+		t.Errorf("RemoveReaction = %q, %v, want like", removed, err)
+	}
+	if count, _ := repos.Posts.GetLikesCount(first.ID); count != 0 {
+		t.Errorf("GetLikesCount = %d after removing the reaction, want 0", count)
+	}
+
+	feed, err := repos.Posts.GetFeedPosts(bob.ID, 10, 0)
+	if err != nil || len(feed) != 3 {
+		t.Errorf("GetFeedPosts = %d posts, %v, want 3", len(feed), err)
+	}
+
+	private := &models.Post{UserID: alice.ID, Content: "secret", Privacy: "private"}
+	if err := repos.Posts.CreatePost(private); err != nil {
+		t.Fatal(err)
+	}
+	if can, err := repos.Posts.CanViewPost(private.ID, bob.ID); err != nil || can {
+		t.Errorf("CanViewPost = %v, %v before adding a viewer, want false", can, err)
+	}
+	if err := repos.Posts.AddPostViewer(private.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if can, err := repos.Posts.CanViewPost(private.ID, bob.ID); err != nil || !can {
+		t.Errorf("CanViewPost = %v, %v after adding a viewer, want true", can, err)
+	}
+
+	edited := *second
+	edited.Content = "second, edited"
+	revision := &models.PostRevision{TargetType: models.RevisionTargetPost, TargetID: second.ID, EditorID: alice.ID, Content: second.Content, Privacy: second.Privacy}
+	if err := repos.Posts.UpdatePost(&edited, revision); err != nil {
+		t.Fatal(err)
+	}
+
+	// The history and viewers of a deleted post go with it
+	if err := repos.Posts.DeletePost(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repos.Posts.GetPostByID(second.ID); err != nil || got != nil {
+		t.Errorf("GetPostByID = %v, %v for a deleted post, want nil", got, err)
+	}
+	if revisions, err := repos.Posts.GetRevisions(models.RevisionTargetPost, second.ID); err != nil || len(revisions) != 0 {
+		t.Errorf("GetRevisions = %d revisions, %v for a deleted post, want none", len(revisions), err)
+	}
+
+	// Deleting the original tombstones the reshare, and ids of deleted posts aren't handed
+	// out again, even the latest one's
+	if err := repos.Posts.DeletePost(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Posts.DeletePost(private.ID); err != nil {
+		t.Fatal(err)
+	}
+	if viewers, err := repos.Posts.GetPostViewers(private.ID); err != nil || len(viewers) != 0 {
+		t.Errorf("GetPostViewers = %v, %v for a deleted post, want none", viewers, err)
+	}
+	next := createPost(t, repos, alice.ID, "next")
+	if next.ID <= private.ID {
+		t.Errorf("new post id = %d after deleting post %d, want a fresh id", next.ID, private.ID)
+	}
+	got, err = repos.Posts.GetPostByID(reshare.ID)
+	if err != nil || got.SharedPostID.Int64 != models.DeletedSharedPostID {
+		t.Fatalf("reshare = %+v, %v after deleting the original, want a tombstoned SharedPostID", got, err)
+	}
+	if original, err := repos.Posts.GetPostByID(got.SharedPostID.Int64); err != nil || original != nil {
+		t.Errorf("reshare of a deleted post resolves to %+v, %v, want nothing", original, err)
+	}
+}
+
+func testAudienceLists(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	carol := createUser(t, repos, "carol")
+	dave := createUser(t, repos, "dave")
+
+	list := &models.AudienceList{OwnerID: alice.ID, Name: "Close friends"}
+	if err := repos.Posts.CreateAudienceList(list); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := repos.Posts.AddAudienceListMember(list.ID, bob.ID); err != nil {
+			t.Fatalf("AddAudienceListMember #%d: %v", i+1, err)
+		}
+	}
+
+	post := &models.Post{
+		UserID:         alice.ID,
+		Content:        "#weekend plans",
+		Privacy:        "private",
+		AudienceListID: sql.NullInt64{Int64: list.ID, Valid: true},
+	}
+	if err := repos.Posts.CreatePost(post); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Posts.AddPostViewer(post.ID, carol.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Posts.SetPostTags(post.ID, []string{"weekend"}, post.CreatedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := repos.Posts.GetPostByID(post.ID); err != nil || got.AudienceListID.Int64 != list.ID {
+		t.Errorf("GetPostByID = %+v, %v, want audience list %d", got, err, list.ID)
+	}
+
+	canView := func(u *user.User) bool {
+		t.Helper()
+		can, err := repos.Posts.CanViewPost(post.ID, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return can
+	}
+	inFeed := func(u *user.User) bool {
+		t.Helper()
+		feed, err := repos.Posts.GetFeedPosts(u.ID, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagged, err := repos.Posts.GetPostsByTag("weekend", u.ID, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feed) != len(tagged) {
+			t.Errorf("GetFeedPosts = %d posts but GetPostsByTag = %d for %s", len(feed), len(tagged), u.Nickname)
+		}
+		return len(feed) == 1 && feed[0].ID == post.ID
+	}
+
+	for _, tc := range []struct {
+		user *user.User
+		want bool
+	}{{bob, true}, {carol, true}, {dave, false}} {
+		if got := canView(tc.user); got != tc.want {
+			t.Errorf("CanViewPost(%s) = %v, want %v", tc.user.Nickname, got, tc.want)
+		}
+		if got := inFeed(tc.user); got != tc.want {
+			t.Errorf("post in feed of %s = %v, want %v", tc.user.Nickname, got, tc.want)
+		}
+	}
+
+	audience, err := repos.Posts.GetPostAudience(post.ID)
+	if err != nil || len(audience) != 2 {
+		t.Errorf("GetPostAudience = %v, %v, want bob and carol", audience, err)
+	}
+	for _, id := range audience {
+		if id != bob.ID && id != carol.ID {
+			t.Errorf("GetPostAudience includes %s, want only bob and carol", id)
+		}
+	}
+
+	// Membership changes apply to posts already shared with the list
+	if err := repos.Posts.AddAudienceListMember(list.ID, dave.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Posts.RemoveAudienceListMember(list.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !canView(dave) || !inFeed(dave) {
+		t.Error("dave cannot see the post after joining the list")
+	}
+	if canView(bob) || inFeed(bob) {
+		t.Error("bob can still see the post after leaving the list")
+	}
+
+	lists, err := repos.Posts.GetAudienceLists(alice.ID)
+	if err != nil || len(lists) != 1 || len(lists[0].Members) != 1 || lists[0].Members[0].ID != dave.ID {
+		t.Errorf("GetAudienceLists = %+v, %v, want Close friends with dave", lists, err)
+	}
+	if err := repos.Posts.RenameAudienceList(list.ID, "Besties"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repos.Posts.GetAudienceList(list.ID); err != nil || got.Name != "Besties" {
+		t.Errorf("GetAudienceList = %+v, %v, want Besties", got, err)
+	}
+
+	if err := repos.Posts.DeleteAudienceList(list.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repos.Posts.GetAudienceList(list.ID); err != nil || got != nil {
+		t.Errorf("GetAudienceList = %+v, %v after deleting it, want nil", got, err)
+	}
+	if got, err := repos.Posts.GetPostByID(post.ID); err != nil || got.AudienceListID.Valid || got.Privacy != "private" {
+		t.Errorf("GetPostByID = %+v, %v after deleting its list, want a private post without one", got, err)
+	}
+	if canView(dave) || !canView(carol) {
+		t.Error("deleting the list should only keep the picked viewers")
+	}
+}
+
+func testComments(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	p := createPost(t, repos, alice.ID, "post")
+
+	comment := &models.Comment{PostID: p.ID, UserID: bob.ID, Content: "nice"}
+	if err := repos.Posts.CreateComment(comment); err != nil {
+		t.Fatal(err)
+	}
+	reply := &models.Comment{PostID: p.ID, UserID: alice.ID, Content: "thanks", ParentID: sql.NullInt64{Int64: comment.ID, Valid: true}, Depth: 1}
+	if err := repos.Posts.CreateComment(reply); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repos.Posts.GetCommentByID(reply.ID)
+	if err != nil || got.ParentID.Int64 != comment.ID {
+		t.Errorf("GetCommentByID = %+v, %v, want a reply to %d", got, err, comment.ID)
+	}
+	replies, err := repos.Posts.GetCommentReplies(comment.ID, bob.ID, 10, 0)
+	if err != nil || len(replies) != 1 {
+		t.Errorf("GetCommentReplies = %d, %v, want 1", len(replies), err)
+	}
+
+	// Comments, replies and reactions of a user the viewer blocked are hidden from the viewer only
+	carol := createUser(t, repos, "carol")
+	for _, c := range []*models.Comment{
+		{PostID: p.ID, UserID: carol.ID, Content: "first"},
+		{PostID: p.ID, UserID: carol.ID, Content: "agreed", ParentID: sql.NullInt64{Int64: comment.ID, Valid: true}, Depth: 1},
+	} {
+		if err := repos.Posts.CreateComment(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repos.Posts.SetReaction(p.ID, carol.ID, "like"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Follows.CreateBlock(carol.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, viewer := range []struct {
+		id   string
+		want int
+	}{{alice.ID, 1}, {bob.ID, 2}} {
+		if comments, err := repos.Posts.GetCommentsByPostID(p.ID, viewer.id); err != nil || len(comments) != viewer.want {
+			t.Errorf("GetCommentsByPostID(%s) = %d, %v, want %d", viewer.id, len(comments), err, viewer.want)
+		}
+		if replies, err := repos.Posts.GetCommentReplies(comment.ID, viewer.id, 10, 0); err != nil || len(replies) != viewer.want {
+			t.Errorf("GetCommentReplies(%s) = %d, %v, want %d", viewer.id, len(replies), err, viewer.want)
+		}
+		if reactors, err := repos.Posts.GetReactors(p.ID, viewer.id, "", 10, 0); err != nil || len(reactors) != viewer.want-1 {
+			t.Errorf("GetReactors(%s) = %d, %v, want %d", viewer.id, len(reactors), err, viewer.want-1)
+		}
+	}
+
+	// Liking twice keeps one like
+	for i := 0; i < 2; i++ {
+		if err := repos.Posts.LikeComment(comment.ID, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if liked, err := repos.Posts.HasLikedComment(comment.ID, alice.ID); err != nil || !liked {
+		t.Errorf("HasLikedComment = %v, %v, want true", liked, err)
+	}
+	if count, err := repos.Posts.GetCommentLikesCount(comment.ID); err != nil || count != 1 {
+		t.Errorf("GetCommentLikesCount = %d, %v, want 1", count, err)
+	}
+	if err := repos.Posts.UnlikeComment(comment.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting a comment removes its replies and keeps the post's count in step
+	if err := repos.Posts.DeleteComment(comment.ID); err != nil {
+		t.Fatal(err)
+	}
+	comments, err := repos.Posts.GetCommentsByPostID(p.ID, bob.ID)
+	if err != nil || len(comments) != 1 || comments[0].UserID != carol.ID {
+		t.Errorf("GetCommentsByPostID = %d, %v after deleting, want carol's comment only", len(comments), err)
+	}
+	if got, _ := repos.Posts.GetPostByID(p.ID); got.CommentsCount != 0 {
+		t.Errorf("CommentsCount = %d after deleting, want 0", got.CommentsCount)
+	}
+}
+
+func testTags(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	p := createPost(t, repos, alice.ID, "hello #golang")
+
+	now := time.Now()
+	if err := repos.Posts.SetPostTags(p.ID, []string{"golang", "sql"}, now); err != nil {
+		t.Fatal(err)
+	}
+	posts, err := repos.Posts.GetPostsByTag("golang", alice.ID, 10, 0)
+	if err != nil || len(posts) != 1 || posts[0].ID != p.ID {
+		t.Errorf("GetPostsByTag = %d posts, %v, want post %d", len(posts), err, p.ID)
+	}
+	usage, err := repos.Posts.GetPublicTagUsage(now.Add(-time.Hour))
+	if err != nil || len(usage) != 2 {
+		t.Errorf("GetPublicTagUsage = %d, %v, want 2", len(usage), err)
+	}
+
+	topics := []*models.TrendingTopic{{Tag: "golang", Score: 2, PostsCount: 1, UpdatedAt: now}}
+	if err := repos.Posts.ReplaceTrendingTopics(topics); err != nil {
+		t.Fatal(err)
+	}
+	trending, err := repos.Posts.GetTrendingTopics(5)
+	if err != nil || len(trending) != 1 || trending[0].Tag != "golang" {
+		t.Errorf("GetTrendingTopics = %v, %v, want golang", trending, err)
+	}
+}
+
+func testGroups(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	g := &models.Group{Name: "Gophers", Description: "Go", CreatorID: alice.ID, IsPublic: true}
+	if err := repos.Groups.CreateGroup(g); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repos.Groups.GetGroupByID(g.ID)
+	if err != nil || got.Name != "Gophers" {
+		t.Errorf("GetGroupByID = %+v, %v, want Gophers", got, err)
+	}
+
+	// The creator joins as an admin
+	if err := repos.Groups.AddMember(&models.GroupMember{GroupID: g.ID, UserID: bob.ID, Role: "member", Status: "pending", InvitedBy: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.UpdateMemberStatus(g.ID, bob.ID, "accepted"); err != nil {
+		t.Fatal(err)
+	}
+	if member, err := repos.Groups.IsGroupMember(g.ID, bob.ID); err != nil || !member {
+		t.Errorf("IsGroupMember = %v, %v, want true", member, err)
+	}
+	if role, err := repos.Groups.GetMemberRole(g.ID, alice.ID); err != nil || role != "admin" {
+		t.Errorf("GetMemberRole = %q, %v, want admin", role, err)
+	}
+	if count, err := repos.Groups.GetGroupMemberCount(g.ID); err != nil || count != 2 {
+		t.Errorf("GetGroupMemberCount = %d, %v, want 2", count, err)
+	}
+
+	// Invited users are not members yet
+	carol := createUser(t, repos, "carol")
+	if err := repos.Groups.AddMember(&models.GroupMember{GroupID: g.ID, UserID: carol.ID, Role: "member", Status: "pending", InvitedBy: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := repos.Posts.GetGroupMemberIDs(g.ID); err != nil || len(ids) != 2 {
+		t.Errorf("GetGroupMemberIDs = %v, %v, want alice and bob", ids, err)
+	}
+	ids, err := repos.Posts.GetGroupMemberIDsByNicknames(g.ID, []string{"BOB", "carol", "nobody"})
+	if err != nil || len(ids) != 1 || ids[0] != bob.ID {
+		t.Errorf("GetGroupMemberIDsByNicknames = %v, %v, want bob", ids, err)
+	}
+
+	// Group posts share their ids with posts
+	p := createPost(t, repos, alice.ID, "outside")
+	gp := &models.GroupPost{GroupID: g.ID, UserID: bob.ID, Content: "inside"}
+	if err := repos.Groups.CreateGroupPost(gp); err != nil {
+		t.Fatal(err)
+	}
+	if gp.ID <= p.ID {
+		t.Errorf("group post id = %d, want more than post id %d", gp.ID, p.ID)
+	}
+	if isGroupPost, err := repos.Posts.IsGroupPost(gp.ID); err != nil || !isGroupPost {
+		t.Errorf("IsGroupPost = %v, %v, want true", isGroupPost, err)
+	}
+	posts, err := repos.Groups.GetGroupPosts(g.ID, alice.ID, 10, 0)
+	if err != nil || len(posts) != 1 {
+		t.Errorf("GetGroupPosts = %d, %v, want 1", len(posts), err)
+	}
+
+	message := &models.GroupChatMessage{GroupID: g.ID, UserID: bob.ID, Content: "hi"}
+	if err := repos.Groups.AddChatMessage(message); err != nil {
+		t.Fatal(err)
+	}
+	if message.ID == 0 {
+		t.Error("AddChatMessage did not set the message id")
+	}
+	messages, err := repos.Groups.GetGroupChatMessages(g.ID, 10, 0)
+	if err != nil || len(messages) != 1 || messages[0].Content != "hi" {
+		t.Errorf("GetGroupChatMessages = %v, %v, want the message", messages, err)
+	}
+
+	if err := repos.Groups.RemoveMember(g.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if member, _ := repos.Groups.IsGroupMember(g.ID, bob.ID); member {
+		t.Error("IsGroupMember is true after RemoveMember")
+	}
+}
+
+func testGroupRoles(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	carol := createUser(t, repos, "carol")
+
+	g := &models.Group{Name: "Gophers", CreatorID: alice.ID, IsPublic: true}
+	if err := repos.Groups.CreateGroup(g); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.AddMember(&models.GroupMember{GroupID: g.ID, UserID: bob.ID, Role: "member", Status: "accepted"}); err != nil {
+		t.Fatal(err)
+	}
+
+	access, err := repos.Groups.GetMemberAccess(g.ID, alice.ID)
+	if err != nil || access == nil || !access.IsOwner || access.Role != "admin" {
+		t.Errorf("GetMemberAccess(owner) = %+v, %v, want the owning admin", access, err)
+	}
+	if access, err := repos.Groups.GetMemberAccess(g.ID, carol.ID); err != nil || access != nil {
+		t.Errorf("GetMemberAccess(non-member) = %+v, %v, want nil", access, err)
+	}
+
+	role := &models.GroupRole{GroupID: g.ID, Name: "editor", Permissions: "post,pin"}
+	if err := repos.Groups.CreateGroupRole(role); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.CreateGroupRole(&models.GroupRole{GroupID: g.ID, Name: "editor", Permissions: "post"}); err == nil {
+		t.Error("CreateGroupRole accepted a duplicate name")
+	}
+	if err := repos.Groups.UpdateMemberRole(g.ID, bob.ID, "editor"); err != nil {
+		t.Fatal(err)
+	}
+	access, err = repos.Groups.GetMemberAccess(g.ID, bob.ID)
+	if err != nil || access == nil || access.IsOwner || access.RolePermissions.String != "post,pin" {
+		t.Errorf("GetMemberAccess(custom role) = %+v, %v, want post,pin", access, err)
+	}
+
+	if err := repos.Groups.UpdateGroupRolePermissions(g.ID, "editor", "post"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repos.Groups.GetGroupRole(g.ID, "editor"); err != nil || got == nil || got.Permissions != "post" {
+		t.Errorf("GetGroupRole = %+v, %v, want post", got, err)
+	}
+	if roles, err := repos.Groups.GetGroupRoles(g.ID); err != nil || len(roles) != 1 {
+		t.Errorf("GetGroupRoles = %d, %v, want 1", len(roles), err)
+	}
+
+	if err := repos.Groups.ReassignMemberRole(g.ID, "editor", "member"); err != nil {
+		t.Fatal(err)
+	}
+	if role, _ := repos.Groups.GetMemberRole(g.ID, bob.ID); role != "member" {
+		t.Errorf("role after ReassignMemberRole = %q, want member", role)
+	}
+	if err := repos.Groups.DeleteGroupRole(g.ID, "editor"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repos.Groups.GetGroupRole(g.ID, "editor"); err != nil || got != nil {
+		t.Errorf("GetGroupRole after delete = %+v, %v, want nil", got, err)
+	}
+
+	if err := repos.Groups.UpdateGroupOwner(g.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if access, _ := repos.Groups.GetMemberAccess(g.ID, bob.ID); access == nil || !access.IsOwner {
+		t.Errorf("GetMemberAccess after UpdateGroupOwner = %+v, want owner", access)
+	}
+
+	// Pinned posts come first whatever their age
+	older := &models.GroupPost{GroupID: g.ID, UserID: alice.ID, Content: "older", CreatedAt: time.Now().Add(-time.Hour)}
+	newer := &models.GroupPost{GroupID: g.ID, UserID: alice.ID, Content: "newer", CreatedAt: time.Now()}
+	for _, gp := range []*models.GroupPost{older, newer} {
+		if err := repos.Groups.CreateGroupPost(gp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repos.Groups.SetGroupPostPinned(older.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	posts, err := repos.Groups.GetGroupPosts(g.ID, alice.ID, 10, 0)
+	if err != nil || len(posts) != 2 || posts[0].ID != older.ID || !posts[0].IsPinned {
+		t.Errorf("GetGroupPosts = %d posts, %v, want the pinned post first", len(posts), err)
+	}
+	if groupID, err := repos.Posts.GetPostGroupID(older.ID); err != nil || groupID != g.ID {
+		t.Errorf("GetPostGroupID = %q, %v, want %s", groupID, err, g.ID)
+	}
+	p := createPost(t, repos, alice.ID, "outside")
+	if groupID, err := repos.Posts.GetPostGroupID(p.ID); err != nil || groupID != "" {
+		t.Errorf("GetPostGroupID(post) = %q, %v, want empty", groupID, err)
+	}
+}
+
+func testGroupModeration(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	g := &models.Group{Name: "Gophers", CreatorID: alice.ID, IsPublic: true}
+	if err := repos.Groups.CreateGroup(g); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.UpdateModerationSettings(g.ID, true, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repos.Groups.GetGroupByID(g.ID); err != nil || !got.PostApproval || got.NewMemberPosts != 3 {
+		t.Errorf("GetGroupByID = %+v, %v, want the moderation settings", got, err)
+	}
+
+	// Pending posts are only listed for their author until approved
+	pending := &models.GroupPost{GroupID: g.ID, UserID: bob.ID, Content: "wait", Status: models.GroupPostPending}
+	if err := repos.Groups.CreateGroupPost(pending); err != nil {
+		t.Fatal(err)
+	}
+	if posts, _ := repos.Groups.GetGroupPosts(g.ID, alice.ID, 10, 0); len(posts) != 0 {
+		t.Errorf("GetGroupPosts(other member) = %d posts, want the pending post hidden", len(posts))
+	}
+	if posts, _ := repos.Groups.GetGroupPosts(g.ID, bob.ID, 10, 0); len(posts) != 1 || posts[0].Status != models.GroupPostPending {
+		t.Errorf("GetGroupPosts(author) = %d posts, want the pending post", len(posts))
+	}
+	if queue, err := repos.Groups.GetPendingGroupPosts(g.ID, 10, 0); err != nil || len(queue) != 1 {
+		t.Errorf("GetPendingGroupPosts = %d, %v, want 1", len(queue), err)
+	}
+	if canView, err := repos.Posts.CanViewPost(pending.ID, alice.ID); err != nil || canView {
+		t.Errorf("CanViewPost(pending) = %v, %v, want false", canView, err)
+	}
+	if err := repos.Groups.SetGroupPostStatus(pending.ID, models.GroupPostApproved); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := repos.Groups.CountApprovedGroupPosts(g.ID, bob.ID); err != nil || count != 1 {
+		t.Errorf("CountApprovedGroupPosts = %d, %v, want 1", count, err)
+	}
+
+	// Expired bans and mutes are ignored
+	expired := &models.GroupBan{GroupID: g.ID, UserID: bob.ID, BannedBy: alice.ID, ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}}
+	if err := repos.Groups.SaveBan(expired); err != nil {
+		t.Fatal(err)
+	}
+	if ban, err := repos.Groups.GetActiveBan(g.ID, bob.ID); err != nil || ban != nil {
+		t.Errorf("GetActiveBan(expired) = %+v, %v, want nil", ban, err)
+	}
+	if err := repos.Groups.SaveBan(&models.GroupBan{GroupID: g.ID, UserID: bob.ID, BannedBy: alice.ID, Reason: "spam"}); err != nil {
+		t.Fatal(err)
+	}
+	if ban, err := repos.Groups.GetActiveBan(g.ID, bob.ID); err != nil || ban == nil || ban.Reason != "spam" || ban.ExpiresAt.Valid {
+		t.Errorf("GetActiveBan = %+v, %v, want the permanent ban", ban, err)
+	}
+	if bans, err := repos.Groups.GetActiveBans(g.ID); err != nil || len(bans) != 1 {
+		t.Errorf("GetActiveBans = %d, %v, want 1", len(bans), err)
+	}
+	if err := repos.Groups.DeleteBan(g.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ban, _ := repos.Groups.GetActiveBan(g.ID, bob.ID); ban != nil {
+		t.Error("GetActiveBan is not nil after DeleteBan")
+	}
+
+	if err := repos.Groups.SaveMute(&models.GroupMute{GroupID: g.ID, UserID: bob.ID, MutedBy: alice.ID, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if mute, err := repos.Groups.GetActiveMute(g.ID, bob.ID); err != nil || mute == nil {
+		t.Errorf("GetActiveMute = %+v, %v, want the mute", mute, err)
+	}
+	if err := repos.Groups.SaveMute(&models.GroupMute{GroupID: g.ID, UserID: bob.ID, MutedBy: alice.ID, ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if mutes, err := repos.Groups.GetActiveMutes(g.ID); err != nil || len(mutes) != 0 {
+		t.Errorf("GetActiveMutes = %d, %v, want the replaced mute expired", len(mutes), err)
+	}
+
+	entry := &models.GroupAuditLog{GroupID: g.ID, ActorID: alice.ID, Action: "member_banned", TargetUserID: sql.NullString{String: bob.ID, Valid: true}}
+	if err := repos.Groups.AddAuditLogEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.ID == 0 {
+		t.Error("AddAuditLogEntry did not set the entry id")
+	}
+	entries, err := repos.Groups.GetAuditLog(g.ID, 10, 0)
+	if err != nil || len(entries) != 1 || entries[0].TargetUserID.String != bob.ID {
+		t.Errorf("GetAuditLog = %v, %v, want the entry", entries, err)
+	}
+}
+
+func testGroupJoining(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	carol := createUser(t, repos, "carol")
+
+	g := &models.Group{Name: "Gophers", CreatorID: alice.ID, IsPublic: true}
+	if err := repos.Groups.CreateGroup(g); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.UpdateAutoApproveRules(g.ID, "followed_by_admin"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repos.Groups.GetGroupByID(g.ID); err != nil || got.AutoApprove != "followed_by_admin" {
+		t.Errorf("GetGroupByID = %+v, %v, want the auto-approve rules", got, err)
+	}
+
+	if err := repos.Follows.CreateFollower(alice.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if followed, err := repos.Groups.IsFollowedByAny(bob.ID, []string{carol.ID, alice.ID}); err != nil || !followed {
+		t.Errorf("IsFollowedByAny(bob) = %v, %v, want true", followed, err)
+	}
+	if followed, err := repos.Groups.IsFollowedByAny(carol.ID, []string{alice.ID, bob.ID}); err != nil || followed {
+		t.Errorf("IsFollowedByAny(carol) = %v, %v, want false", followed, err)
+	}
+
+	// A link with one use lets one user in
+	link := &models.GroupInviteLink{GroupID: g.ID, Token: "token-1", CreatedBy: alice.ID, MaxUses: 1}
+	if err := repos.Groups.CreateInviteLink(link); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repos.Groups.GetInviteLinkByToken("token-1"); err != nil || got == nil || got.ID != link.ID {
+		t.Errorf("GetInviteLinkByToken = %+v, %v, want the link", got, err)
+	}
+	if got, err := repos.Groups.GetInviteLinkByToken("missing"); err != nil || got != nil {
+		t.Errorf("GetInviteLinkByToken(missing) = %+v, %v, want nil", got, err)
+	}
+	if err := repos.Groups.UseInviteLink(link.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.UseInviteLink(link.ID); err == nil {
+		t.Error("UseInviteLink succeeded past the use limit")
+	}
+
+	expired := &models.GroupInviteLink{GroupID: g.ID, Token: "token-2", CreatedBy: alice.ID, ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}}
+	if err := repos.Groups.CreateInviteLink(expired); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.UseInviteLink(expired.ID); err == nil {
+		t.Error("UseInviteLink succeeded on an expired link")
+	}
+	if err := repos.Groups.RevokeInviteLink(g.ID, expired.ID); err != nil {
+		t.Fatal(err)
+	}
+	if links, err := repos.Groups.GetInviteLinks(g.ID); err != nil || len(links) != 1 || links[0].Uses != 1 {
+		t.Errorf("GetInviteLinks = %v, %v, want the used link only", links, err)
+	}
+
+	first := &models.GroupJoinQuestion{GroupID: g.ID, Question: "Why?", Required: true}
+	second := &models.GroupJoinQuestion{GroupID: g.ID, Question: "Where from?", Position: 1}
+	for _, q := range []*models.GroupJoinQuestion{second, first} {
+		if err := repos.Groups.CreateJoinQuestion(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	questions, err := repos.Groups.GetJoinQuestions(g.ID)
+	if err != nil || len(questions) != 2 || questions[0].ID != first.ID || !questions[0].Required {
+		t.Errorf("GetJoinQuestions = %v, %v, want both in position order", questions, err)
+	}
+
+	answers := []*models.GroupJoinAnswer{
+		{GroupID: g.ID, QuestionID: first.ID, UserID: carol.ID, Answer: "Go"},
+		{GroupID: g.ID, QuestionID: second.ID, UserID: carol.ID, Answer: "Here"},
+	}
+	if err := repos.Groups.SaveJoinAnswers(answers); err != nil {
+		t.Fatal(err)
+	}
+	answers[0].ID, answers[0].Answer = "", "Gophers"
+	if err := repos.Groups.SaveJoinAnswers(answers[:1]); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repos.Groups.GetJoinAnswers(g.ID)
+	if err != nil || len(got) != 2 || got[0].Answer != "Gophers" || got[0].Question != "Why?" {
+		t.Errorf("GetJoinAnswers = %v, %v, want the replaced answer first", got, err)
+	}
+
+	if err := repos.Groups.DeleteJoinQuestion(g.ID, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repos.Groups.GetJoinAnswers(g.ID); len(got) != 1 {
+		t.Errorf("GetJoinAnswers = %d after DeleteJoinQuestion, want 1", len(got))
+	}
+}
+
+func testEvents(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	g := &models.Group{Name: "Gophers", CreatorID: alice.ID, IsPublic: true}
+	if err := repos.Groups.CreateGroup(g); err != nil {
+		t.Fatal(err)
+	}
+
+	eventDate := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	e := &models.GroupEvent{GroupID: g.ID, CreatorID: alice.ID, Title: "Meetup", Description: "Talks", EventDate: eventDate}
+	if err := repos.Events.CreateEvent(e); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repos.Events.GetEventByID(e.ID)
+	if err != nil || got.Title != "Meetup" || !got.EventDate.Equal(eventDate) {
+		t.Errorf("GetEventByID = %+v, %v, want Meetup on %v", got, err, eventDate)
+	}
+	if events, err := repos.Events.GetGroupEvents(g.ID, time.Now(), time.Now().AddDate(0, 1, 0)); err != nil || len(events) != 1 {
+		t.Errorf("GetGroupEvents = %d, %v, want 1", len(events), err)
+	}
+	if events, err := repos.Events.GetGroupEvents(g.ID, eventDate.Add(time.Hour), eventDate.AddDate(0, 1, 0)); err != nil || len(events) != 0 {
+		t.Errorf("GetGroupEvents after the event = %d, %v, want 0", len(events), err)
+	}
+
+	// A weekly series of four, without its second occurrence, expands within the range.
+	// Summer dates keep daylight saving time out of it, the range is in another zone.
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 6, 3, 18, 0, 0, 0, newYork)
+	series := &models.GroupEvent{
+		GroupID: g.ID, CreatorID: alice.ID, Title: "Standup", EventDate: start,
+		EndDate:    sql.NullTime{Time: start.Add(time.Hour), Valid: true},
+		TimeZone:   "America/New_York",
+		Location:   "Room 1",
+		Recurrence: "FREQ=WEEKLY;COUNT=4",
+		Exceptions: ical.FormatUTC(start.AddDate(0, 0, 7)),
+	}
+	if err := repos.Events.CreateEvent(series); err != nil {
+		t.Fatal(err)
+	}
+	events, err := repos.Events.GetGroupEvents(g.ID, start.Add(time.Minute).UTC(), start.AddDate(0, 0, 30).UTC())
+	if err != nil || len(events) != 3 {
+		t.Fatalf("GetGroupEvents of the series = %d, %v, want 3", len(events), err)
+	}
+	// The first occurrence is still going on at the start of the range
+	if events[0].ID != series.ID || events[0].RecurrenceID == "" || !events[0].EventDate.Equal(start) {
+		t.Errorf("first occurrence = %s at %v, want the series at %v", events[0].ID, events[0].EventDate, start)
+	}
+	if last := start.AddDate(0, 0, 21); !events[2].EventDate.Equal(last) || !events[2].EndDate.Time.Equal(last.Add(time.Hour)) {
+		t.Errorf("last occurrence = %v to %v, want %v", events[2].EventDate, events[2].EndDate.Time, last)
+	}
+
+	// Times are compared in UTC whatever zone they were given in
+	late := &models.GroupEvent{GroupID: g.ID, CreatorID: alice.ID, Title: "Late", EventDate: time.Date(2030, 6, 10, 20, 0, 0, 0, newYork)}
+	if err := repos.Events.CreateEvent(late); err != nil {
+		t.Fatal(err)
+	}
+	events, err = repos.Events.GetGroupEvents(g.ID, time.Date(2030, 6, 10, 23, 0, 0, 0, time.UTC), time.Date(2030, 6, 11, 1, 0, 0, 0, time.UTC))
+	if err != nil || len(events) != 1 || events[0].ID != late.ID {
+		t.Errorf("GetGroupEvents around the late event = %d, %v, want it alone", len(events), err)
+	}
+
+	// Only events of groups the user is in and going to are in their feed
+	if err := repos.Groups.AddMember(&models.GroupMember{GroupID: g.ID, UserID: bob.ID, Role: "member", Status: "accepted"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Events.AddEventResponse(&models.EventResponse{EventID: series.ID, UserID: bob.ID, Response: "going"}); err != nil {
+		t.Fatal(err)
+	}
+	if going, err := repos.Events.GetUserGoingEvents(bob.ID); err != nil || len(going) != 1 || going[0].Recurrence != "FREQ=WEEKLY;COUNT=4" {
+		t.Errorf("GetUserGoingEvents = %d, %v, want the series", len(going), err)
+	}
+
+	if token, err := repos.Events.GetFeedToken(bob.ID); err != nil || token != "" {
+		t.Errorf("GetFeedToken = %q, %v, want none", token, err)
+	}
+	for _, token := range []string{"first", "second"} {
+		if err := repos.Events.SaveFeedToken(bob.ID, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if token, err := repos.Events.GetFeedToken(bob.ID); err != nil || token != "second" {
+		t.Errorf("GetFeedToken = %q, %v, want second", token, err)
+	}
+	if userID, err := repos.Events.GetUserIDByFeedToken("first"); err != nil || userID != "" {
+		t.Errorf("GetUserIDByFeedToken(replaced) = %q, %v, want none", userID, err)
+	}
+	if userID, err := repos.Events.GetUserIDByFeedToken("second"); err != nil || userID != bob.ID {
+		t.Errorf("GetUserIDByFeedToken = %q, %v, want bob", userID, err)
+	}
+
+	if _, err := repos.Events.AddEventResponse(&models.EventResponse{EventID: e.ID, UserID: bob.ID, Response: "going"}); err != nil {
+		t.Fatal(err)
+	}
+	if counts, err := repos.Events.GetEventResponseCounts(e.ID); err != nil || counts.Going != 1 || counts.NotGoing != 0 {
+		t.Errorf("GetEventResponseCounts = %+v, %v, want 1 going", counts, err)
+	}
+	// Responding again changes the response instead of adding one
+	if _, err := repos.Events.AddEventResponse(&models.EventResponse{EventID: e.ID, UserID: bob.ID, Response: "maybe"}); err != nil {
+		t.Fatal(err)
+	}
+	if counts, err := repos.Events.GetEventResponseCounts(e.ID); err != nil || counts.Going != 0 || counts.Maybe != 1 {
+		t.Errorf("GetEventResponseCounts = %+v, %v, want 1 maybe", counts, err)
+	}
+
+	// Three seats: alice and her guest fill two, carol's party of two waits for dave's seat
+	carol := createUser(t, repos, "carol")
+	dave := createUser(t, repos, "dave")
+	small := &models.GroupEvent{GroupID: g.ID, CreatorID: alice.ID, Title: "Dinner", EventDate: eventDate, Capacity: 3, GuestLimit: 1}
+	if err := repos.Events.CreateEvent(small); err != nil {
+		t.Fatal(err)
+	}
+	respond := func(user *user.User, guests int) *models.EventResponse {
+		t.Helper()
+		response := &models.EventResponse{EventID: small.ID, UserID: user.ID, Response: "going", Guests: guests}
+		if _, err := repos.Events.AddEventResponse(response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	if r := respond(alice, 1); r.WaitlistedAt.Valid {
+		t.Error("AddEventResponse waitlisted alice with seats free")
+	}
+	if r := respond(dave, 0); r.WaitlistedAt.Valid {
+		t.Error("AddEventResponse waitlisted dave with a seat free")
+	}
+	if r := respond(carol, 1); !r.WaitlistedAt.Valid {
+		t.Error("AddEventResponse gave carol's party seats of a full event")
+	}
+	if r := respond(bob, 0); !r.WaitlistedAt.Valid {
+		t.Error("AddEventResponse gave bob a seat of a full event")
+	}
+	// Confirmed responders cannot bring guests that do not fit
+	if _, err := repos.Events.AddEventResponse(&models.EventResponse{EventID: small.ID, UserID: dave.ID, Response: "going", Guests: 1}); !errors.Is(err, event.ErrNotEnoughSeats) {
+		t.Errorf("AddEventResponse with too many guests = %v, want ErrNotEnoughSeats", err)
+	}
+	if counts, err := repos.Events.GetEventResponseCounts(small.ID); err != nil || counts.Going != 2 || counts.Guests != 1 || counts.Waitlisted != 2 {
+		t.Errorf("GetEventResponseCounts = %+v, %v, want 2 going with a guest and 2 waitlisted", counts, err)
+	}
+
+	// Alice leaving frees two seats, carol's party is first in line and takes both
+	promoted, err := repos.Events.DeleteEventResponse(small.ID, alice.ID)
+	if err != nil || len(promoted) != 1 || promoted[0].UserID != carol.ID {
+		t.Fatalf("DeleteEventResponse promoted %v, %v, want carol", promoted, err)
+	}
+	// Dave dropping to maybe frees a seat for bob
+	promoted, err = repos.Events.AddEventResponse(&models.EventResponse{EventID: small.ID, UserID: dave.ID, Response: "maybe"})
+	if err != nil || len(promoted) != 1 || promoted[0].UserID != bob.ID {
+		t.Fatalf("AddEventResponse promoted %v, %v, want bob", promoted, err)
+	}
+	// Dave going again joins the end of the waitlist until the capacity is raised
+	if r := respond(dave, 0); !r.WaitlistedAt.Valid {
+		t.Error("AddEventResponse gave dave back a seat that was given up")
+	}
+	small.Capacity = 4
+	if err := repos.Events.UpdateEvent(small); err != nil {
+		t.Fatal(err)
+	}
+	promoted, err = repos.Events.PromoteWaitlist(small.ID)
+	if err != nil || len(promoted) != 1 || promoted[0].UserID != dave.ID {
+		t.Fatalf("PromoteWaitlist = %v, %v, want dave", promoted, err)
+	}
+	if r, err := repos.Events.GetUserEventResponse(small.ID, dave.ID); err != nil || r == nil || r.WaitlistedAt.Valid {
+		t.Errorf("GetUserEventResponse = %+v, %v, want dave confirmed", r, err)
+	}
+
+	// A party queued before the capacity was lowered below its size is passed over
+	tiny := &models.GroupEvent{GroupID: g.ID, CreatorID: alice.ID, Title: "Tea", EventDate: eventDate, Capacity: 3, GuestLimit: 2}
+	if err := repos.Events.CreateEvent(tiny); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*models.EventResponse{
+		{EventID: tiny.ID, UserID: carol.ID, Response: "going", Guests: 2},
+		{EventID: tiny.ID, UserID: bob.ID, Response: "going", Guests: 2},
+		{EventID: tiny.ID, UserID: dave.ID, Response: "going"},
+	} {
+		if _, err := repos.Events.AddEventResponse(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tiny.Capacity, tiny.GuestLimit = 2, 1
+	if err := repos.Events.UpdateEvent(tiny); err != nil {
+		t.Fatal(err)
+	}
+	promoted, err = repos.Events.DeleteEventResponse(tiny.ID, carol.ID)
+	if err != nil || len(promoted) != 1 || promoted[0].UserID != dave.ID {
+		t.Fatalf("DeleteEventResponse promoted %v, %v, want dave ahead of bob's oversized party", promoted, err)
+	}
+
+	if err := repos.Events.DeleteEvent(e.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Events.GetEventByID(e.ID); err == nil {
+		t.Error("GetEventByID found a deleted event")
+	}
+}
+
+func testChat(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	if err := repos.Follows.CreateFollower(alice.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if can, err := repos.Chat.CanSendMessage(alice.ID, bob.ID); err != nil || !can {
+		t.Errorf("CanSendMessage = %v, %v, want true", can, err)
+	}
+
+	for _, content := range []string{"hello", "are you there?"} {
+		m := &models.PrivateMessage{SenderID: alice.ID, ReceiverID: bob.ID, Content: content, CreatedAt: time.Now()}
+		if err := repos.Chat.SaveMessage(m); err != nil {
+			t.Fatal(err)
+		}
+		if m.ID == 0 {
+			t.Error("SaveMessage did not set the message id")
+		}
+	}
+
+	messages, err := repos.Chat.GetMessagesBetweenUsers(bob.ID, alice.ID, 10, 0)
+	if err != nil || len(messages) != 2 {
+		t.Errorf("GetMessagesBetweenUsers = %d, %v, want 2", len(messages), err)
+	}
+	unread, err := repos.Chat.GetUnreadMessagesCount(bob.ID)
+	if err != nil || unread[alice.ID] != 2 {
+		t.Errorf("GetUnreadMessagesCount = %v, %v, want 2 from alice", unread, err)
+	}
+
+	contacts, err := repos.Chat.GetChatContacts(bob.ID)
+	if err != nil || len(contacts) != 1 || contacts[0].UnreadCount != 2 {
+		t.Errorf("GetChatContacts = %v, %v, want alice with 2 unread", contacts, err)
+	}
+
+	if err := repos.Chat.MarkMessagesAsRead(alice.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if unread, _ := repos.Chat.GetUnreadMessagesCount(bob.ID); unread[alice.ID] != 0 {
+		t.Errorf("GetUnreadMessagesCount = %v after reading, want none", unread)
+	}
+}
+
+func testProfiles(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+
+	profile, err := repos.Profiles.GetUserProfileByID(alice.ID)
+	if err != nil || profile.IsPrivate {
+		t.Fatalf("GetUserProfileByID = %+v, %v, want a public profile", profile, err)
+	}
+
+	err = repos.Profiles.UpdateUserProfile(alice.ID, map[string]interface{}{
+		"bio":       "Gopher",
+		"location":  "Nairobi",
+		"isPrivate": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err = repos.Profiles.GetUserProfileByID(alice.ID)
+	if err != nil || profile.Bio != "Gopher" || profile.Location != "Nairobi" || !profile.IsPrivate {
+		t.Errorf("GetUserProfileByID = %+v, %v, want the updated profile", profile, err)
+	}
+	if public, err := repos.Profiles.IsUserProfilePublic(alice.ID); err != nil || public {
+		t.Errorf("IsUserProfilePublic = %v, %v, want false", public, err)
+	}
+}
+
+func testNotifications(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	p := createPost(t, repos, alice.ID, "post")
+
+	for i := 0; i < 2; i++ {
+		n := &models.Notification{
+			UserID:       alice.ID,
+			SenderID:     sql.NullString{String: bob.ID, Valid: true},
+			Type:         "post_like",
+			Message:      "liked your post",
+			TargetPostID: sql.NullInt64{Int64: p.ID, Valid: true},
+		}
+		if err := repos.Notifications.CreateNotification(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := repos.Notifications.GetNotifications(alice.ID, 10, 0)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("GetNotifications = %d, %v, want 2", len(list), err)
+	}
+	if list[0].TargetPostID.Int64 != p.ID || list[0].SenderID.String != bob.ID || list[0].IsRead {
+		t.Errorf("notification = %+v, want an unread notification about post %d", list[0], p.ID)
+	}
+
+	if err := repos.Notifications.MarkNotificationAsRead(list[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Notifications.DeleteNotificationDb(list[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	list, err = repos.Notifications.GetNotifications(alice.ID, 10, 0)
+	if err != nil || len(list) != 1 || !list[0].IsRead {
+		t.Errorf("GetNotifications = %+v, %v, want one read notification", list, err)
+	}
+
+	if err := repos.Notifications.ClearAllNotificationsDB(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := repos.Notifications.GetNotifications(alice.ID, 10, 0); len(list) != 0 {
+		t.Errorf("GetNotifications = %d after clearing, want 0", len(list))
+	}
+}
+
+func testStats(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+
+	// The repositories leave counters to the services, so these rows are not counted yet
+	p := createPost(t, repos, alice.ID, "post")
+	if err := repos.Posts.CreateComment(&models.Comment{PostID: p.ID, UserID: bob.ID, Content: "nice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := repos.Stats.GetUserDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].UserID != alice.ID || users[0].Stored.PostsCount != 0 || users[0].Actual.PostsCount != 1 {
+		t.Errorf("GetUserDrift = %+v, want alice with 0 posts stored and 1 actual", users)
+	}
+	posts, err := repos.Stats.GetPostDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].Table != "posts" || posts[0].PostID != p.ID || posts[0].Actual.CommentsCount != 1 {
+		t.Errorf("GetPostDrift = %+v, want post %d with 1 comment", posts, p.ID)
+	}
+
+	counts, err := repos.Stats.RepairUserStats(alice.ID)
+	if err != nil || counts.PostsCount != 1 {
+		t.Errorf("RepairUserStats = %+v, %v, want 1 post", counts, err)
+	}
+	if err := repos.Stats.RepairPostCounts("posts", p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Stats.RepairPostCounts("users", p.ID); err == nil {
+		t.Error("RepairPostCounts accepted a table without post counters")
+	}
+
+	if users, err := repos.Stats.GetUserDrift(); err != nil || len(users) != 0 {
+		t.Errorf("GetUserDrift = %d, %v after repairing, want 0", len(users), err)
+	}
+	if posts, err := repos.Stats.GetPostDrift(); err != nil || len(posts) != 0 {
+		t.Errorf("GetPostDrift = %d, %v after repairing, want 0", len(posts), err)
+	}
+	if got, _ := repos.Posts.GetPostByID(p.ID); got.CommentsCount != 1 {
+		t.Errorf("CommentsCount = %d after repairing, want 1", got.CommentsCount)
+	}
+}
+
+func testJobs(t *testing.T, repos *Repositories) {
+	now := time.Now().Truncate(time.Second)
+
+	for i, key := range []string{"reminder:1:1h", "reminder:1:24h", "reminder:10:1h"} {
+		job := &models.ScheduledJob{ID: fmt.Sprintf("job-%d", i), Kind: "reminder", Key: key, Payload: "{}", RunAt: now.Add(-time.Duration(i) * time.Minute), CreatedAt: now}
+		if err := repos.Jobs.SaveJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Saving a key again replaces its job
+	if err := repos.Jobs.SaveJob(&models.ScheduledJob{ID: "job-moved", Kind: "reminder", Key: "reminder:1:24h", RunAt: now.Add(time.Hour), CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if job, err := repos.Jobs.GetJob("reminder:1:24h"); err != nil || job == nil || job.ID != "job-moved" || !job.RunAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("GetJob after SaveJob of the same key = %+v, %v, want job-moved", job, err)
+	}
+
+	due, err := repos.Jobs.GetDueJobs(now, 10)
+	if err != nil || len(due) != 2 || due[0].Key != "reminder:10:1h" {
+		t.Fatalf("GetDueJobs = %d, %v, want 2, oldest first", len(due), err)
+	}
+
+	if ok, err := repos.Jobs.ClaimJob(due[0].ID, now, now.Add(time.Minute)); err != nil || !ok {
+		t.Errorf("ClaimJob = %v, %v, want true", ok, err)
+	}
+	if ok, err := repos.Jobs.ClaimJob(due[0].ID, now, now.Add(time.Minute)); err != nil || ok {
+		t.Errorf("ClaimJob of a claimed job = %v, %v, want false", ok, err)
+	}
+	if due, _ := repos.Jobs.GetDueJobs(now, 10); len(due) != 1 {
+		t.Errorf("GetDueJobs with one claimed = %d, want 1", len(due))
+	}
+
+	if err := repos.Jobs.RetryJob(due[0].ID, now.Add(time.Minute), "failed"); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := repos.Jobs.GetJob("reminder:10:1h"); job == nil || job.Attempts != 1 || job.LastError != "failed" || job.LockedUntil.Valid {
+		t.Errorf("GetJob after RetryJob = %+v, want one attempt, unlocked", job)
+	}
+
+	if err := repos.Jobs.CompleteJob(due[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := repos.Jobs.GetJob("reminder:10:1h"); job != nil {
+		t.Error("GetJob found a completed job")
+	}
+
+	if err := repos.Jobs.DeleteJobsWithPrefix("reminder:1:"); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ := repos.Jobs.GetDueJobs(now.Add(2*time.Hour), 10); len(due) != 0 {
+		t.Errorf("GetDueJobs after DeleteJobsWithPrefix = %d, want 0", len(due))
+	}
+}
+
+func testExports(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if latest, err := repos.Exports.GetLatestExport(alice.ID); err != nil || latest != nil {
+		t.Errorf("GetLatestExport before any export = %+v, %v, want nil", latest, err)
+	}
+	for i, id := range []string{"export-1", "export-2"} {
+		e := &models.DataExport{ID: id, UserID: alice.ID, Status: models.ExportPending, Token: "token-" + id, CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+		if err := repos.Exports.CreateExport(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if latest, err := repos.Exports.GetLatestExport(alice.ID); err != nil || latest == nil || latest.ID != "export-2" {
+		t.Errorf("GetLatestExport = %+v, %v, want export-2", latest, err)
+	}
+
+	if err := repos.Exports.CompleteExport("export-2", "export-2.zip", 1024, now, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	e, err := repos.Exports.GetExportByToken("token-export-2")
+	if err != nil || e == nil || e.Status != models.ExportReady || e.Size != 1024 || !e.ExpiresAt.Time.Equal(now.Add(time.Hour)) {
+		t.Errorf("GetExportByToken = %+v, %v, want export-2 ready for an hour", e, err)
+	}
+	if err := repos.Exports.SetExportStatus("export-2", models.ExportExpired); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := repos.Exports.GetExport("export-2"); e == nil || e.Status != models.ExportExpired {
+		t.Errorf("GetExport after expiring = %+v, want expired", e)
+	}
+	if e, err := repos.Exports.GetExportByToken("unknown"); err != nil || e != nil {
+		t.Errorf("GetExportByToken of an unknown token = %+v, %v, want nil", e, err)
+	}
+
+	// The data of an export comes from every part of the schema
+	if err := repos.Follows.CreateFollower(bob.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	p := createPost(t, repos, alice.ID, "hello")
+	if _, err := repos.Posts.SetReaction(p.ID, alice.ID, "love"); err != nil {
+		t.Fatal(err)
+	}
+	c := &models.Comment{PostID: p.ID, UserID: alice.ID, Content: "first"}
+	if err := repos.Posts.CreateComment(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Posts.LikeComment(c.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &models.Group{Name: "Gophers", CreatorID: bob.ID, IsPublic: true}
+	if err := repos.Groups.CreateGroup(g); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.AddMember(&models.GroupMember{GroupID: g.ID, UserID: alice.ID, Role: "member", Status: "accepted"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.CreateGroupPost(&models.GroupPost{GroupID: g.ID, UserID: alice.ID, Content: "inside"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Groups.AddChatMessage(&models.GroupChatMessage{GroupID: g.ID, UserID: alice.ID, Content: "hi all", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.GroupEvent{GroupID: g.ID, CreatorID: bob.ID, Title: "Meetup", EventDate: now.Add(24 * time.Hour)}
+	if err := repos.Events.CreateEvent(event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Events.AddEventResponse(&models.EventResponse{EventID: event.ID, UserID: alice.ID, Response: "going", Guests: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.Chat.SaveMessage(&models.PrivateMessage{SenderID: bob.ID, ReceiverID: alice.ID, Content: "hey", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Notifications.CreateNotification(&models.Notification{UserID: alice.ID, SenderID: sql.NullString{String: bob.ID, Valid: true}, Type: "follow", Message: "followed you"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if profile, err := repos.Exports.GetProfile(alice.ID); err != nil || profile == nil || profile.Email != alice.Email {
+		t.Errorf("GetProfile = %+v, %v, want alice", profile, err)
+	}
+	if posts, err := repos.Exports.GetPosts(alice.ID); err != nil || len(posts) != 2 || posts[0].Content != "hello" || posts[1].GroupID != g.ID {
+		t.Errorf("GetPosts = %d, %v, want the post then the group post", len(posts), err)
+	}
+	if comments, err := repos.Exports.GetComments(alice.ID); err != nil || len(comments) != 1 || comments[0].PostID != p.ID {
+		t.Errorf("GetComments = %d, %v, want 1", len(comments), err)
+	}
+	if reactions, err := repos.Exports.GetReactions(alice.ID); err != nil || len(reactions) != 2 {
+		t.Errorf("GetReactions = %d, %v, want a post and a comment reaction", len(reactions), err)
+	}
+	if followers, err := repos.Exports.GetFollowers(alice.ID); err != nil || len(followers) != 1 || followers[0].UserID != bob.ID {
+		t.Errorf("GetFollowers = %+v, %v, want bob", followers, err)
+	}
+	if following, err := repos.Exports.GetFollowing(alice.ID); err != nil || len(following) != 0 {
+		t.Errorf("GetFollowing = %+v, %v, want none", following, err)
+	}
+	if memberships, err := repos.Exports.GetMemberships(alice.ID); err != nil || len(memberships) != 1 || memberships[0].GroupName != "Gophers" {
+		t.Errorf("GetMemberships = %+v, %v, want Gophers", memberships, err)
+	}
+	if responses, err := repos.Exports.GetEventResponses(alice.ID); err != nil || len(responses) != 1 || responses[0].EventTitle != "Meetup" || responses[0].Guests != 1 {
+		t.Errorf("GetEventResponses = %+v, %v, want going to Meetup with a guest", responses, err)
+	}
+	if messages, err := repos.Exports.GetMessages(alice.ID); err != nil || len(messages) != 1 || messages[0].SenderID != bob.ID {
+		t.Errorf("GetMessages = %+v, %v, want the message from bob", messages, err)
+	}
+	if messages, err := repos.Exports.GetGroupMessages(alice.ID); err != nil || len(messages) != 1 || messages[0].GroupName != "Gophers" {
+		t.Errorf("GetGroupMessages = %+v, %v, want one in Gophers", messages, err)
+	}
+	if notifications, err := repos.Exports.GetNotifications(alice.ID); err != nil || len(notifications) != 1 || notifications[0].SenderID != bob.ID {
+		t.Errorf("GetNotifications = %+v, %v, want the follow notification", notifications, err)
+	}
+}
+
+func testAccounts(t *testing.T, repos *Repositories) {
+	alice := createUser(t, repos, "alice")
+	bob := createUser(t, repos, "bob")
+	carol := createUser(t, repos, "carol")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// Deactivated accounts come back on login, and logging in cancels their deletion
+	post := createPost(t, repos, alice.ID, "before leaving")
+	if err := repos.Accounts.Deactivate(alice.ID, now); err != nil {
+		t.Fatal(err)
+	}
+	if deactivated, err := repos.Users.IsDeactivated(alice.ID); err != nil || !deactivated {
+		t.Errorf("IsDeactivated after Deactivate = %v, %v, want true", deactivated, err)
+	}
+	if canView, err := repos.Posts.CanViewPost(post.ID, bob.ID); err != nil || canView {
+		t.Errorf("CanViewPost of a deactivated user's post = %v, %v, want false", canView, err)
+	}
+	if canView, err := repos.Posts.CanViewPost(post.ID, alice.ID); err != nil || !canView {
+		t.Errorf("CanViewPost of their own post while deactivated = %v, %v, want true", canView, err)
+	}
+	deletion := &models.AccountDeletion{UserID: alice.ID, Content: models.DeletionRemove, RequestedAt: now, DeleteAt: now.Add(time.Hour)}
+	if err := repos.Accounts.ScheduleDeletion(deletion); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := repos.Accounts.GetDeletion(alice.ID); err != nil || d == nil || d.Content != models.DeletionRemove || !d.DeleteAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("GetDeletion = %+v, %v, want removal in an hour", d, err)
+	}
+	if reactivated, err := repos.Users.Reactivate(alice.ID); err != nil || !reactivated {
+		t.Errorf("Reactivate = %v, %v, want true", reactivated, err)
+	}
+	if canView, err := repos.Posts.CanViewPost(post.ID, bob.ID); err != nil || !canView {
+		t.Errorf("CanViewPost after Reactivate = %v, %v, want true", canView, err)
+	}
+	if reactivated, err := repos.Users.Reactivate(alice.ID); err != nil || reactivated {
+		t.Errorf("Reactivate of an active account = %v, %v, want false", reactivated, err)
+	}
+	if d, err := repos.Accounts.GetDeletion(alice.ID); err != nil || d != nil {
+		t.Errorf("GetDeletion after Reactivate = %+v, %v, want nil", d, err)
+	}
+
+	// Groups go to admins before moderators before members
+	shared := &models.Group{Name: "Shared", CreatorID: alice.ID, IsPublic: true}
+	solo := &models.Group{Name: "Solo", CreatorID: alice.ID, IsPublic: true}
+	for _, g := range []*models.Group{shared, solo} {
+		if err := repos.Groups.CreateGroup(g); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.Groups.AddMember(&models.GroupMember{GroupID: g.ID, UserID: alice.ID, Role: "admin", Status: "accepted"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range []*models.GroupMember{
+		{GroupID: shared.ID, UserID: bob.ID, Role: "member", Status: "accepted"},
+		{GroupID: shared.ID, UserID: carol.ID, Role: "moderator", Status: "accepted"},
+	} {
+		if err := repos.Groups.AddMember(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if owned, err := repos.Accounts.GetOwnedGroups(alice.ID); err != nil || len(owned) != 2 {
+		t.Errorf("GetOwnedGroups = %d, %v, want 2", len(owned), err)
+	}
+	if successor, err := repos.Accounts.GetGroupSuccessor(shared.ID, alice.ID); err != nil || successor != carol.ID {
+		t.Errorf("GetGroupSuccessor = %q, %v, want the moderator", successor, err)
+	}
+	if successor, err := repos.Accounts.GetGroupSuccessor(solo.ID, alice.ID); err != nil || successor != "" {
+		t.Errorf("GetGroupSuccessor of a group without other members = %q, %v, want none", successor, err)
+	}
+
+	// Removing alice's content keeps what others wrote, except replies to her comments
+	own := &models.Post{UserID: alice.ID, Content: "mine", Privacy: "public", ImagePath: sql.NullString{String: "posts/mine.png", Valid: true}}
+	if err := repos.Posts.CreatePost(own); err != nil {
+		t.Fatal(err)
+	}
+	p := createPost(t, repos, bob.ID, "bob's post")
+	aliceComment := &models.Comment{PostID: p.ID, UserID: alice.ID, Content: "nice"}
+	bobComment := &models.Comment{PostID: p.ID, UserID: bob.ID, Content: "thanks"}
+	for _, c := range []*models.Comment{aliceComment, bobComment} {
+		if err := repos.Posts.CreateComment(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []*models.Comment{
+		{PostID: p.ID, UserID: bob.ID, Content: "reply to alice", ParentID: sql.NullInt64{Int64: aliceComment.ID, Valid: true}, Depth: 1},
+		{PostID: p.ID, UserID: alice.ID, Content: "reply to bob", ParentID: sql.NullInt64{Int64: bobComment.ID, Valid: true}, Depth: 1},
+	} {
+		if err := repos.Posts.CreateComment(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repos.Posts.LikeComment(bobComment.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*models.PrivateMessage{
+		{SenderID: alice.ID, ReceiverID: bob.ID, Content: "from alice", CreatedAt: now},
+		{SenderID: bob.ID, ReceiverID: alice.ID, Content: "from bob", CreatedAt: now},
+	} {
+		if err := repos.Chat.SaveMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repos.Follows.CreateFollower(bob.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// What others left on alice's post goes with it, their reshares stay unavailable
+	onOwn := &models.Comment{PostID: own.ID, UserID: bob.ID, Content: "cool"}
+	if err := repos.Posts.CreateComment(onOwn); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Posts.LikeComment(onOwn.ID, carol.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Posts.SetReaction(own.ID, bob.ID, "like"); err != nil {
+		t.Fatal(err)
+	}
+	reshare := &models.Post{UserID: carol.ID, Privacy: "public", SharedPostID: sql.NullInt64{Int64: own.ID, Valid: true}}
+	if err := repos.Posts.CreatePost(reshare); err != nil {
+		t.Fatal(err)
+	}
+
+	media, err := repos.Accounts.DeletePosts(alice.ID)
+	if err != nil || len(media) != 1 || media[0] != "posts/mine.png" {
+		t.Errorf("DeletePosts = %v, %v, want the post's image", media, err)
+	}
+	if got, _ := repos.Posts.GetPostByID(own.ID); got != nil {
+		t.Errorf("GetPostByID after DeletePosts = %+v, want nil", got)
+	}
+	if c, err := repos.Posts.GetCommentByID(onOwn.ID); err != nil || c != nil {
+		t.Errorf("bob's comment on alice's post after DeletePosts = %+v, %v, want nil", c, err)
+	}
+	if counts, err := repos.Posts.GetReactionCounts(own.ID); err != nil || counts["like"] != 0 {
+		t.Errorf("reactions to alice's post after DeletePosts = %v, %v, want none", counts, err)
+	}
+	if got, err := repos.Posts.GetPostByID(reshare.ID); err != nil || got == nil || got.SharedPostID.Int64 != models.DeletedSharedPostID {
+		t.Errorf("carol's reshare after DeletePosts = %+v, %v, want kept and tombstoned", got, err)
+	}
+	if err := repos.Accounts.DeleteReactions(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Accounts.DeleteComments(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := repos.Posts.GetCommentByID(aliceComment.ID); c != nil {
+		t.Errorf("alice's comment after DeleteComments = %+v, want nil", c)
+	}
+	if c, err := repos.Posts.GetCommentByID(bobComment.ID); err != nil || c == nil || c.RepliesCount != 0 || c.LikesCount != 0 {
+		t.Errorf("bob's comment after DeleteComments = %+v, %v, want kept without replies or likes", c, err)
+	}
+	if err := repos.Accounts.DeleteMessages(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if messages, err := repos.Chat.GetMessagesBetweenUsers(alice.ID, bob.ID, 10, 0); err != nil || len(messages) != 1 || messages[0].SenderID != bob.ID {
+		t.Errorf("messages after DeleteMessages = %+v, %v, want only bob's", messages, err)
+	}
+	if err := repos.Accounts.DeleteConnections(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if followers, err := repos.Follows.GetFollowers(alice.ID, alice.ID); err != nil || len(followers) != 0 {
+		t.Errorf("followers after DeleteConnections = %d, %v, want none", len(followers), err)
+	}
+
+	if _, err := repos.Accounts.Anonymize(alice.ID, now); err != nil {
+		t.Fatal(err)
+	}
+	u, err := repos.Users.GetByID(alice.ID)
+	if err != nil || u.Email == alice.Email || u.FirstName != "Deleted" || u.Nickname != "" {
+		t.Errorf("user after Anonymize = %+v, %v, want personal data erased", u, err)
+	}
+	if deactivated, err := repos.Users.IsDeactivated(alice.ID); err != nil || !deactivated {
+		t.Errorf("IsDeactivated after Anonymize = %v, %v, want true", deactivated, err)
+	}
+	if reactivated, err := repos.Users.Reactivate(alice.ID); err != nil || reactivated {
+		t.Errorf("Reactivate of a deleted account = %v, %v, want false", reactivated, err)
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Athooh/social-network/internal/account"
+	"github.com/Athooh/social-network/internal/chat"
+	"github.com/Athooh/social-network/internal/config"
+	"github.com/Athooh/social-network/internal/event"
+	"github.com/Athooh/social-network/internal/export"
+	"github.com/Athooh/social-network/internal/follow"
+	"github.com/Athooh/social-network/internal/group"
+	notifications "github.com/Athooh/social-network/internal/notifcations"
+	"github.com/Athooh/social-network/internal/post"
+	"github.com/Athooh/social-network/internal/profile"
+	"github.com/Athooh/social-network/internal/stats"
+	userStatus "github.com/Athooh/social-network/internal/user"
+	"github.com/Athooh/social-network/pkg/db/postgres"
+	"github.com/Athooh/social-network/pkg/db/sqlite"
+	"github.com/Athooh/social-network/pkg/scheduler"
+	"github.com/Athooh/social-network/pkg/session"
+	"github.com/Athooh/social-network/pkg/user"
+)
+
+// Repositories holds the repository of every domain package, all backed by one database
+type Repositories struct {
+	Users         user.Repository
+	Statuses      user.StatusRepository
+	Sessions      session.SessionStore
+	Posts         post.Repository
+	Follows       follow.Repository
+	Groups        group.Repository
+	Events        event.Repository
+	Chat          chat.Repository
+	Profiles      profile.Repository
+	Notifications notifications.Repository
+	Stats         stats.Repository
+	Jobs          scheduler.Repository
+	Exports       export.Repository
+	Accounts      account.Repository
+}
+
+// NewSQLiteRepositories creates the SQLite repositories
+func NewSQLiteRepositories(db *sql.DB) *Repositories {
+	return &Repositories{
+		Users:         user.NewSQLiteRepository(db),
+		Statuses:      userStatus.NewSQLiteStatusRepository(db),
+		Sessions:      session.NewSQLiteRepository(db),
+		Posts:         post.NewSQLiteRepository(db),
+		Follows:       follow.NewSQLiteRepository(db),
+		Groups:        group.NewSQLiteRepository(db),
+		Events:        event.NewSQLiteRepository(db),
+		Chat:          chat.NewSQLiteRepository(db),
+		Profiles:      profile.NewSQLiteRepository(db),
+		Notifications: notifications.NewSQLiteRepository(db),
+		Stats:         stats.NewSQLiteRepository(db),
+		Jobs:          scheduler.NewSQLiteRepository(db),
+		Exports:       export.NewSQLiteRepository(db),
+		Accounts:      account.NewSQLiteRepository(db),
+	}
+}
+
+// NewPostgresRepositories creates the PostgreSQL repositories, db must be opened with postgres.New
+func NewPostgresRepositories(db *sql.DB) *Repositories {
+	return &Repositories{
+		Users:         user.NewPostgresRepository(db),
+		Statuses:      userStatus.NewPostgresStatusRepository(db),
+		Sessions:      session.NewPostgresRepository(db),
+		Posts:         post.NewPostgresRepository(db),
+		Follows:       follow.NewPostgresRepository(db),
+		Groups:        group.NewPostgresRepository(db),
+		Events:        event.NewPostgresRepository(db),
+		Chat:          chat.NewPostgresRepository(db),
+		Profiles:      profile.NewPostgresRepository(db),
+		Notifications: notifications.NewPostgresRepository(db),
+		Stats:         stats.NewPostgresRepository(db),
+		Jobs:          scheduler.NewPostgresRepository(db),
+		Exports:       export.NewPostgresRepository(db),
+		Accounts:      account.NewPostgresRepository(db),
+	}
+}
+
+// Open connects to the database DB_DRIVER selects, applies its committed migrations and
+// creates the repositories for it
+func Open(cfg config.DatabaseConfig) (*sql.DB, *Repositories, error) {
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		db, err := sqlite.New(sqlite.Config{DBPath: cfg.Path, MigrationsPath: cfg.MigrationsPath})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := sqlite.ApplyMigrations(db); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		return db.DB, NewSQLiteRepositories(db.DB), nil
+
+	case config.DriverPostgres:
+		db, err := postgres.New(postgres.Config{URL: cfg.URL, MigrationsPath: cfg.MigrationsPath})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := postgres.ApplyMigrations(db); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		return db.DB, NewPostgresRepositories(db.DB), nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown DB_DRIVER %q, use %s or %s", cfg.Driver, config.DriverSQLite, config.DriverPostgres)
+	}
+}
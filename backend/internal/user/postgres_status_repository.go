@@ -0,0 +1,17 @@
+package user
+
+import (
+	"database/sql"
+)
+
+// PostgresStatusRepository implements StatusRepository for PostgreSQL. The ON CONFLICT
+// upserts of SQLiteStatusRepository are valid PostgreSQL as they are.
+type PostgresStatusRepository struct {
+	*SQLiteStatusRepository
+}
+
+// NewPostgresStatusRepository creates a new PostgreSQL status repository, db must be opened
+// with postgres.New
+func NewPostgresStatusRepository(db *sql.DB) *PostgresStatusRepository {
+	return &PostgresStatusRepository{&SQLiteStatusRepository{db: db}}
+}
@@ -61,7 +61,7 @@ func (h *Handler) HandleConnection(w http.ResponseWriter, r *http.Request) {
 
 	// Check if this tab already has an active connection
 	if h.hub.HasClientWithID(clientID) {
-		h.log.Info("Closing existing connection for client %s", clientID)
+		h.log.WithContext(r.Context()).Info("Closing existing connection for client %s", clientID)
 		h.hub.CloseClientWithID(clientID)
 	}
 
@@ -95,7 +95,7 @@ func (h *Handler) HandleConnection(w http.ResponseWriter, r *http.Request) {
 		go h.statusService.SetUserOnline(userID)
 	}
 
-	h.log.Info("New WebSocket connection established for user: %s, tab: %s", userID, tabID)
+	h.log.WithContext(r.Context()).Info("New WebSocket connection established for user: %s, tab: %s", userID, tabID)
 
 	// Start goroutines for reading and writing
 	go client.ReadPump()
@@ -0,0 +1,483 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// manifestVersion is bumped whenever the archive layout changes
+	manifestVersion = 1
+
+	snapshotPrefix = "snapshot-"
+	snapshotLayout = "20060102-150405"
+	checksumSuffix = ".sha256"
+
+	manifestName = "manifest.json"
+	databaseName = "database.db"
+	uploadsDir   = "uploads"
+)
+
+var (
+	// ErrChecksumMismatch is returned when a snapshot or a file inside it doesn't match its checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrIntegrityCheck is returned when the database in a snapshot fails PRAGMA integrity_check
+	ErrIntegrityCheck = errors.New("database integrity check failed")
+)
+
+// Config holds the backup configuration
+type Config struct {
+	DBPath     string
+	UploadDir  string
+	BackupDir  string
+	Compress   bool // gzip the snapshot archive
+	KeepDaily  int  // number of days to keep the newest snapshot of
+	KeepWeekly int  // number of weeks to keep the newest snapshot of
+}
+
+// FileEntry is a file stored in a snapshot
+type FileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes the contents of a snapshot
+type Manifest struct {
+	Version        int         `json:"version"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	SchemaVersion  uint        `json:"schemaVersion"`
+	Database       FileEntry   `json:"database"`
+	Uploads        []FileEntry `json:"uploads"`
+	MissingUploads []string    `json:"missingUploads"` // referenced by the database but not on disk
+}
+
+// Snapshot is a snapshot archive in the backup directory
+type Snapshot struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"-"`
+	Size       int64     `json:"size"`
+	Compressed bool      `json:"compressed"`
+	CreatedAt  time.Time `json:"createdAt"`
+	SHA256     string    `json:"sha256,omitempty"`
+}
+
+// Manager takes snapshots of a live database and prunes old ones
+type Manager struct {
+	db     *sql.DB
+	config Config
+	mu     sync.Mutex
+}
+
+// NewManager creates a backup manager for db, creating the backup directory if needed
+func NewManager(db *sql.DB, config Config) (*Manager, error) {
+	if err := os.MkdirAll(config.BackupDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return &Manager{db: db, config: config}, nil
+}
+
+// Create takes a consistent snapshot of the database with VACUUM INTO, which works
+// while the WAL database is being written to, and bundles it with the upload files it
+// references into an archive in the backup directory
+func (m *Manager) Create() (*Snapshot, *Manifest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	createdAt := time.Now().UTC()
+	name := snapshotPrefix + createdAt.Format(snapshotLayout) + ".tar"
+	if m.config.Compress {
+		name += ".gz"
+	}
+	archivePath := filepath.Join(m.config.BackupDir, name)
+	if _, err := os.Stat(archivePath); err == nil {
+		return nil, nil, fmt.Errorf("snapshot %s already exists", name)
+	}
+
+	// Work next to the archive so the final rename doesn't cross file systems
+	workDir, err := os.MkdirTemp(m.config.BackupDir, ".snapshot-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	dbCopy := filepath.Join(workDir, databaseName)
+	if _, err := m.db.Exec("VACUUM INTO ?", dbCopy); err != nil {
+		return nil, nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	manifest := &Manifest{Version: manifestVersion, CreatedAt: createdAt}
+	references, err := inspectSnapshot(dbCopy, manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tmpArchive := filepath.Join(workDir, name)
+	sum, err := writeArchive(tmpArchive, dbCopy, m.config.UploadDir, references, manifest, m.config.Compress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.Rename(tmpArchive, archivePath); err != nil {
+		return nil, nil, fmt.Errorf("failed to move snapshot into place: %w", err)
+	}
+	if err := os.WriteFile(archivePath+checksumSuffix, []byte(fmt.Sprintf("%s  %s\n", sum, name)), 0o644); err != nil {
+		return nil, nil, fmt.Errorf("failed to write snapshot checksum: %w", err)
+	}
+
+	snapshot, err := readSnapshot(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, manifest, nil
+}
+
+// List returns the snapshots in the backup directory, newest first
+func (m *Manager) List() ([]Snapshot, error) {
+	return List(m.config.BackupDir)
+}
+
+// Prune deletes the snapshots the retention policy doesn't keep and returns them
+func (m *Manager) Prune() ([]Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshots, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []Snapshot
+	for _, snapshot := range expiredSnapshots(snapshots, m.config.KeepDaily, m.config.KeepWeekly) {
+		if err := os.Remove(snapshot.Path); err != nil {
+			return deleted, fmt.Errorf("failed to delete snapshot %s: %w", snapshot.Name, err)
+		}
+		os.Remove(snapshot.Path + checksumSuffix)
+		deleted = append(deleted, snapshot)
+	}
+	return deleted, nil
+}
+
+// List returns the snapshots in dir, newest first
+func List(dir string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), snapshotPrefix) || strings.HasSuffix(entry.Name(), checksumSuffix) {
+			continue
+		}
+		snapshot, err := readSnapshot(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// expiredSnapshots applies the retention policy to snapshots sorted newest first: the
+// newest snapshot of each of the last keepDaily days and of each of the last keepWeekly
+// ISO weeks is kept. Nothing expires when both are zero, and the newest snapshot never does.
+func expiredSnapshots(snapshots []Snapshot, keepDaily, keepWeekly int) []Snapshot {
+	if keepDaily <= 0 && keepWeekly <= 0 {
+		return nil
+	}
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	var expired []Snapshot
+	for i, snapshot := range snapshots {
+		keep := i == 0
+
+		day := snapshot.CreatedAt.Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep = true
+		}
+
+		year, week := snapshot.CreatedAt.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep = true
+		}
+
+		if !keep {
+			expired = append(expired, snapshot)
+		}
+	}
+	return expired
+}
+
+// readSnapshot describes the snapshot archive at path
+func readSnapshot(path string) (*Snapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(path)
+	stamp := strings.TrimPrefix(name, snapshotPrefix)
+	compressed := strings.HasSuffix(stamp, ".tar.gz")
+	stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, ".gz"), ".tar")
+
+	createdAt, err := time.Parse(snapshotLayout, stamp)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot archive: %s", name)
+	}
+
+	snapshot := &Snapshot{
+		Name:       name,
+		Path:       path,
+		Size:       info.Size(),
+		Compressed: compressed,
+		CreatedAt:  createdAt,
+	}
+	if sum, err := readChecksumFile(path); err == nil {
+		snapshot.SHA256 = sum
+	}
+	return snapshot, nil
+}
+
+// readChecksumFile reads the sha256sum style checksum stored next to an archive
+func readChecksumFile(archivePath string) (string, error) {
+	data, err := os.ReadFile(archivePath + checksumSuffix)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file for %s", filepath.Base(archivePath))
+	}
+	return fields[0], nil
+}
+
+// inspectSnapshot fills in the schema version of the database copy and returns the
+// upload files it references
+func inspectSnapshot(dbPath string, manifest *Manifest) ([]string, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer db.Close()
+
+	if err := checkIntegrity(db); err != nil {
+		return nil, err
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT version FROM schema_migrations LIMIT 1").Scan(&version); err == nil && version.Valid {
+		manifest.SchemaVersion = uint(version.Int64)
+	}
+
+	return uploadReferences(db)
+}
+
+// uploadReferences returns every upload path stored in the database, found in the
+// columns named like the ones that hold file store paths
+func uploadReferences(db *sql.DB) ([]string, error) {
+	tables, err := queryStrings(db, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var references []string
+	for _, table := range tables {
+		columns, err := queryStrings(db, fmt.Sprintf("SELECT name FROM pragma_table_info(%q)", table))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+
+		for _, column := range columns {
+			if !isUploadColumn(column) {
+				continue
+			}
+
+			values, err := queryStrings(db, fmt.Sprintf("SELECT DISTINCT %q FROM %q WHERE %q IS NOT NULL AND %q != ''", column, table, column, column))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+			}
+
+			for _, value := range values {
+				path, ok := uploadPath(value)
+				if ok && !seen[path] {
+					seen[path] = true
+					references = append(references, path)
+				}
+			}
+		}
+	}
+
+	sort.Strings(references)
+	return references, nil
+}
+
+// isUploadColumn reports whether a column holds a path relative to the upload directory
+func isUploadColumn(column string) bool {
+	return column == "avatar" || strings.HasSuffix(column, "_path") || strings.HasSuffix(column, "_image")
+}
+
+// uploadPath turns a stored upload reference into a slash separated path inside the
+// upload directory, rejecting anything that would point outside it
+func uploadPath(value string) (string, bool) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "/"), "uploads/")
+	if value == "" || strings.Contains(value, "://") {
+		return "", false
+	}
+
+	path := filepath.ToSlash(filepath.Clean(value))
+	if !filepath.IsLocal(path) {
+		return "", false
+	}
+	return path, true
+}
+
+// queryStrings runs a query returning a single text column
+func queryStrings(db *sql.DB, query string) ([]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			values = append(values, value.String)
+		}
+	}
+	return values, rows.Err()
+}
+
+// checkIntegrity runs PRAGMA integrity_check and returns its complaints as an error
+func checkIntegrity(db *sql.DB) error {
+	results, err := queryStrings(db, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("failed to check database integrity: %w", err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("%w: %s", ErrIntegrityCheck, strings.Join(results, "; "))
+	}
+	return nil
+}
+
+// writeArchive writes the database copy, the referenced upload files and the manifest
+// to a tar archive at path and returns the SHA-256 of the archive
+func writeArchive(path, dbCopy, uploadDir string, references []string, manifest *Manifest, compress bool) (string, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot archive: %w", err)
+	}
+	defer file.Close()
+
+	archiveHash := sha256.New()
+	var out io.Writer = io.MultiWriter(file, archiveHash)
+
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(out)
+		out = gz
+	}
+	tw := tar.NewWriter(out)
+
+	manifest.Database, err = addFile(tw, databaseName, dbCopy)
+	if err != nil {
+		return "", err
+	}
+
+	for _, reference := range references {
+		entry, err := addFile(tw, uploadsDir+"/"+reference, filepath.Join(uploadDir, filepath.FromSlash(reference)))
+		if errors.Is(err, os.ErrNotExist) {
+			manifest.MissingUploads = append(manifest.MissingUploads, reference)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		entry.Path = reference
+		manifest.Uploads = append(manifest.Uploads, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	header := &tar.Header{Name: manifestName, Mode: 0o644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return "", fmt.Errorf("failed to finish snapshot archive: %w", err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return "", fmt.Errorf("failed to flush snapshot archive: %w", err)
+	}
+
+	return hex.EncodeToString(archiveHash.Sum(nil)), nil
+}
+
+// addFile copies the file at source into the archive under name and returns its entry
+func addFile(tw *tar.Writer, name, source string) (FileEntry, error) {
+	src, err := os.Open(source)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return FileEntry{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return FileEntry{}, fmt.Errorf("%s is not a regular file", source)
+	}
+
+	header := &tar.Header{Name: name, Mode: 0o644, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(header); err != nil {
+		return FileEntry{}, fmt.Errorf("failed to add %s to snapshot: %w", name, err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, hash), src); err != nil {
+		return FileEntry{}, fmt.Errorf("failed to add %s to snapshot: %w", name, err)
+	}
+
+	return FileEntry{Path: name, Size: info.Size(), SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
@@ -0,0 +1,263 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Verify checks a snapshot archive without restoring it: the archive checksum when one
+// is stored next to it, the checksum of every file against the manifest and the
+// integrity of the database
+func Verify(archivePath string) (*Manifest, error) {
+	workDir, err := os.MkdirTemp("", "snapshot-verify-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	return extractVerified(archivePath, workDir)
+}
+
+// Restore replaces the database at config.DBPath with the one in a snapshot and copies
+// the snapshot's upload files into config.UploadDir. Nothing is touched unless the
+// snapshot passes Verify. The replaced database is kept next to it with a .pre-restore
+// suffix. The server must be stopped while restoring.
+func Restore(archivePath string, config Config) (*Manifest, error) {
+	dbDir := filepath.Dir(config.DBPath)
+	if err := os.MkdirAll(dbDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	// Extract next to the database so swapping it in is a rename
+	workDir, err := os.MkdirTemp(dbDir, ".restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	manifest, err := extractVerified(archivePath, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := restoreUploads(filepath.Join(workDir, uploadsDir), config.UploadDir, manifest.Uploads); err != nil {
+		return nil, err
+	}
+
+	if err := swapDatabase(filepath.Join(workDir, databaseName), config.DBPath); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// extractVerified extracts a snapshot archive into dir and verifies it
+func extractVerified(archivePath, dir string) (*Manifest, error) {
+	if err := verifyArchiveChecksum(archivePath); err != nil {
+		return nil, err
+	}
+
+	if err := extractArchive(archivePath, dir); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot has no manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if manifest.Version > manifestVersion {
+		return nil, fmt.Errorf("snapshot format version %d is newer than this build supports (%d)", manifest.Version, manifestVersion)
+	}
+
+	if err := verifyFile(filepath.Join(dir, databaseName), manifest.Database); err != nil {
+		return nil, err
+	}
+	for _, entry := range manifest.Uploads {
+		if !filepath.IsLocal(filepath.FromSlash(entry.Path)) {
+			return nil, fmt.Errorf("snapshot manifest contains an unsafe path: %s", entry.Path)
+		}
+		if err := verifyFile(filepath.Join(dir, uploadsDir, filepath.FromSlash(entry.Path)), entry); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, databaseName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot database: %w", err)
+	}
+	defer db.Close()
+	if err := checkIntegrity(db); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// verifyArchiveChecksum compares the archive with the checksum stored next to it, if any
+func verifyArchiveChecksum(archivePath string) error {
+	want, err := readChecksumFile(archivePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	got, err := fileChecksum(archivePath)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, filepath.Base(archivePath))
+	}
+	return nil
+}
+
+// verifyFile checks the size and checksum of an extracted file against its manifest entry
+func verifyFile(path string, entry FileEntry) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("snapshot is missing %s: %w", entry.Path, err)
+	}
+	if info.Size() != entry.Size {
+		return fmt.Errorf("%w: %s is %d bytes, the manifest says %d", ErrChecksumMismatch, entry.Path, info.Size(), entry.Size)
+	}
+
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if sum != entry.SHA256 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, entry.Path)
+	}
+	return nil
+}
+
+// fileChecksum returns the hex encoded SHA-256 of a file
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extractArchive extracts the regular files of a possibly gzipped tar archive into dir
+func extractArchive(archivePath, dir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	var in io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("failed to decompress snapshot: %w", err)
+		}
+		defer gz.Close()
+		in = gz
+	}
+
+	tr := tar.NewReader(in)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !filepath.IsLocal(header.Name) {
+			return fmt.Errorf("snapshot contains an unsafe path: %s", header.Name)
+		}
+
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(header.Name)), tr); err != nil {
+			return err
+		}
+	}
+}
+
+// restoreUploads copies the upload files of a snapshot into the upload directory,
+// overwriting files with the same path and leaving every other file alone
+func restoreUploads(fromDir, uploadDir string, uploads []FileEntry) error {
+	for _, entry := range uploads {
+		if !filepath.IsLocal(filepath.FromSlash(entry.Path)) {
+			return fmt.Errorf("snapshot manifest contains an unsafe path: %s", entry.Path)
+		}
+
+		src, err := os.Open(filepath.Join(fromDir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", entry.Path, err)
+		}
+
+		err = writeFile(filepath.Join(uploadDir, filepath.FromSlash(entry.Path)), src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// swapDatabase moves the restored database into place. The current database and its
+// WAL files are renamed rather than deleted so that the restore can be undone.
+func swapDatabase(restored, dbPath string) error {
+	if _, err := os.Stat(dbPath); err == nil {
+		kept := fmt.Sprintf("%s.pre-restore-%s", dbPath, time.Now().UTC().Format(snapshotLayout))
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			err := os.Rename(dbPath+suffix, kept+suffix)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to move the current database aside: %w", err)
+			}
+		}
+	}
+
+	if err := os.Rename(restored, dbPath); err != nil {
+		return fmt.Errorf("failed to move the restored database into place: %w", err)
+	}
+	return nil
+}
+
+// writeFile writes r to path, creating its directory
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return dst.Close()
+}
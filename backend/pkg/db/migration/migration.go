@@ -0,0 +1,235 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Athooh/social-network/pkg/logger"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+var (
+	// ErrDirtyDatabase is returned when a previous migration failed part way through
+	ErrDirtyDatabase = errors.New("database is in a dirty migration state")
+	// ErrUnknownVersion is returned when the database version has no migration file,
+	// usually because it was migrated by a newer build or by locally generated files
+	ErrUnknownVersion = errors.New("database version is not in the migrations directory")
+)
+
+// Migrator applies the committed migration files to a database
+type Migrator struct {
+	m        *migrate.Migrate
+	source   source.Driver
+	path     string
+	beforeUp func(Status) error
+	afterUp  func()
+}
+
+// File is a committed migration and whether it has been applied
+type File struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Status describes the migration state of a database
+type Status struct {
+	Version    uint // 0 when no migration has been applied
+	Dirty      bool
+	Known      bool // Version has a migration file, always true when Version is 0
+	Migrations []File
+}
+
+// Pending returns the migrations that have not been applied yet
+func (s Status) Pending() []File {
+	var pending []File
+	for _, migration := range s.Migrations {
+		if !migration.Applied {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// New creates a migrator for the migration files in migrationsPath, applied through the
+// given database driver. beforeUp, if not nil, gets the status of the database before
+// pending migrations are applied and can prepare it for them. afterUp, if not nil, runs
+// after migrations have been applied.
+func New(migrationsPath, databaseName string, driver database.Driver, beforeUp func(Status) error, afterUp func()) (*Migrator, error) {
+	sourceURL := fmt.Sprintf("file://%s", migrationsPath)
+	src, err := source.Open(sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations directory %s: %w", migrationsPath, err)
+	}
+
+	m, err := migrate.NewWithInstance("file", src, databaseName, driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
+	}
+
+	return &Migrator{m: m, source: src, path: migrationsPath, beforeUp: beforeUp, afterUp: afterUp}, nil
+}
+
+// Apply applies the committed migrations that are still pending. It refuses to touch a
+// database that is dirty or at a version it has no migration file for.
+func (mg *Migrator) Apply() error {
+	if err := mg.Verify(); err != nil {
+		return err
+	}
+
+	if err := mg.Up(); err != nil {
+		return err
+	}
+
+	status, err := mg.Status()
+	if err != nil {
+		return err
+	}
+	logger.Info("Database migrations applied successfully, version %d", status.Version)
+
+	return nil
+}
+
+// Status reports the current version and every committed migration
+func (mg *Migrator) Status() (Status, error) {
+	status := Status{Known: true}
+
+	version, dirty, err := mg.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	migrations, err := mg.files()
+	if err != nil {
+		return status, err
+	}
+
+	if version > 0 {
+		status.Known = false
+	}
+	for _, migration := range migrations {
+		if migration.Version == version {
+			status.Known = true
+		}
+		migration.Applied = version > 0 && migration.Version <= version
+		status.Migrations = append(status.Migrations, migration)
+	}
+
+	return status, nil
+}
+
+// Verify checks that the database is clean and at a version the migrations directory knows
+func (mg *Migrator) Verify() error {
+	status, err := mg.Status()
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty {
+		return fmt.Errorf("%w at version %d, fix the schema by hand and run `migrate force %d`", ErrDirtyDatabase, status.Version, status.Version)
+	}
+
+	if !status.Known {
+		return fmt.Errorf("%w: version %d, migrations are read from %s", ErrUnknownVersion, status.Version, mg.path)
+	}
+
+	return nil
+}
+
+// Up applies all pending migrations
+func (mg *Migrator) Up() error {
+	if mg.beforeUp != nil {
+		status, err := mg.Status()
+		if err != nil {
+			return err
+		}
+		if err := mg.beforeUp(status); err != nil {
+			return fmt.Errorf("failed to prepare database for migrations: %w", err)
+		}
+	}
+
+	err := mg.m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	if mg.afterUp != nil {
+		mg.afterUp()
+	}
+	return nil
+}
+
+// Down rolls back the last n applied migrations
+func (mg *Migrator) Down(n int) error {
+	if n < 1 {
+		return fmt.Errorf("number of migrations to roll back must be positive, got %d", n)
+	}
+
+	if err := mg.m.Steps(-n); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+	return nil
+}
+
+// Force sets the database version without running any migration and clears the dirty flag.
+// A version of -1 marks the database as having no migrations applied.
+func (mg *Migrator) Force(version int) error {
+	if version >= 0 {
+		r, _, err := mg.source.ReadUp(uint(version))
+		if err != nil {
+			return fmt.Errorf("%w: version %d", ErrUnknownVersion, version)
+		}
+		r.Close()
+	}
+
+	if err := mg.m.Force(version); err != nil {
+		return fmt.Errorf("failed to force version: %w", err)
+	}
+	return nil
+}
+
+// Close closes the migration source and the underlying database
+func (mg *Migrator) Close() error {
+	sourceErr, dbErr := mg.m.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+	return dbErr
+}
+
+// files lists the committed migrations in version order
+func (mg *Migrator) files() ([]File, error) {
+	var migrations []File
+
+	version, err := mg.source.First()
+	for err == nil {
+		r, identifier, readErr := mg.source.ReadUp(version)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+		}
+		r.Close()
+
+		migrations = append(migrations, File{
+			Version: version,
+			Name:    fmt.Sprintf("%06d_%s", version, strings.TrimSuffix(identifier, ".up")),
+		})
+
+		version, err = mg.source.Next(version)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	return migrations, nil
+}
@@ -0,0 +1,24 @@
+DROP TABLE IF EXISTS trending_topics;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS post_revisions;
+DROP TABLE IF EXISTS user_profiles;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS chat_contacts;
+DROP TABLE IF EXISTS private_messages;
+DROP TABLE IF EXISTS event_responses;
+DROP TABLE IF EXISTS group_events;
+DROP TABLE IF EXISTS group_posts;
+DROP TABLE IF EXISTS group_chat_messages;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS user_status;
+DROP TABLE IF EXISTS comment_likes;
+DROP TABLE IF EXISTS post_likes;
+DROP TABLE IF EXISTS user_stats;
+DROP TABLE IF EXISTS followers;
+DROP TABLE IF EXISTS follow_requests;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS post_viewers;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
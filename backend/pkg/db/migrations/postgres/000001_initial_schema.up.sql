@@ -0,0 +1,324 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    date_of_birth TEXT NOT NULL,
+    avatar TEXT,
+    nickname TEXT,
+    about_me TEXT,
+    is_public BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    video_path TEXT,
+    privacy TEXT NOT NULL,
+    likes_count BIGINT DEFAULT 0,
+    comments_count BIGINT DEFAULT 0,
+    is_edited BOOLEAN DEFAULT FALSE,
+    shared_post_id BIGINT,
+    shares_count BIGINT DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts(user_id);
+CREATE INDEX IF NOT EXISTS idx_posts_shared_post_id ON posts(shared_post_id);
+
+CREATE TABLE IF NOT EXISTS post_viewers (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    post_id BIGINT NOT NULL,
+    user_id TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_viewers_post_id ON post_viewers(post_id);
+CREATE INDEX IF NOT EXISTS idx_post_viewers_user_id ON post_viewers(user_id);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    post_id BIGINT NOT NULL,
+    user_id TEXT NOT NULL,
+    parent_id BIGINT,
+    depth BIGINT DEFAULT 0,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    is_edited BOOLEAN DEFAULT FALSE,
+    likes_count BIGINT DEFAULT 0,
+    replies_count BIGINT DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments(user_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    follower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    following_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (follower_id, following_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_requests_follower_id ON follow_requests(follower_id);
+CREATE INDEX IF NOT EXISTS idx_follow_requests_following_id ON follow_requests(following_id);
+CREATE INDEX IF NOT EXISTS idx_follow_requests_status ON follow_requests(status);
+
+CREATE TABLE IF NOT EXISTS followers (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    follower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    following_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (follower_id, following_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers(follower_id);
+CREATE INDEX IF NOT EXISTS idx_followers_following_id ON followers(following_id);
+
+CREATE TABLE IF NOT EXISTS user_stats (
+    user_id TEXT PRIMARY KEY,
+    posts_count BIGINT DEFAULT 0,
+    groups_joined BIGINT DEFAULT 0,
+    followers_count BIGINT DEFAULT 0,
+    following_count BIGINT DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_stats_user_id ON user_stats(user_id);
+
+CREATE TABLE IF NOT EXISTS post_likes (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    post_id BIGINT NOT NULL,
+    user_id TEXT NOT NULL,
+    reaction_type TEXT NOT NULL DEFAULT 'like',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_likes_post_id ON post_likes(post_id);
+CREATE INDEX IF NOT EXISTS idx_post_likes_user_id ON post_likes(user_id);
+CREATE INDEX IF NOT EXISTS idx_post_likes_reaction_type ON post_likes(reaction_type);
+
+CREATE TABLE IF NOT EXISTS comment_likes (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    comment_id BIGINT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_likes_comment_id ON comment_likes(comment_id);
+CREATE INDEX IF NOT EXISTS idx_comment_likes_user_id ON comment_likes(user_id);
+
+CREATE TABLE IF NOT EXISTS user_status (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    is_online BOOLEAN DEFAULT FALSE,
+    last_activity TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_status_user_id ON user_status(user_id);
+
+CREATE TABLE IF NOT EXISTS groups (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    creator_id TEXT NOT NULL,
+    banner_path TEXT,
+    profile_pic_path TEXT,
+    is_public BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_groups_creator_id ON groups(creator_id);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL,
+    status TEXT NOT NULL,
+    invited_by TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    avatar TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_group_id ON group_members(group_id);
+CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members(user_id);
+CREATE INDEX IF NOT EXISTS idx_group_members_status ON group_members(status);
+
+CREATE TABLE IF NOT EXISTS group_chat_messages (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_chat_messages_group_id ON group_chat_messages(group_id);
+CREATE INDEX IF NOT EXISTS idx_group_chat_messages_created_at ON group_chat_messages(created_at);
+
+CREATE TABLE IF NOT EXISTS group_posts (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    content TEXT,
+    image_path TEXT,
+    video_path TEXT,
+    likes_count BIGINT DEFAULT 0,
+    comments_count BIGINT DEFAULT 0,
+    is_edited BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_posts_group_id ON group_posts(group_id);
+CREATE INDEX IF NOT EXISTS idx_group_posts_user_id ON group_posts(user_id);
+
+CREATE TABLE IF NOT EXISTS group_events (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    creator_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    event_date TIMESTAMPTZ NOT NULL,
+    banner_path TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_events_group_id ON group_events(group_id);
+
+CREATE TABLE IF NOT EXISTS event_responses (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    response TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_responses_event_id ON event_responses(event_id);
+CREATE INDEX IF NOT EXISTS idx_event_responses_user_id ON event_responses(user_id);
+
+CREATE TABLE IF NOT EXISTS private_messages (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    sender_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    receiver_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMPTZ,
+    is_read BOOLEAN DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_private_messages_sender_id ON private_messages(sender_id);
+CREATE INDEX IF NOT EXISTS idx_private_messages_receiver_id ON private_messages(receiver_id);
+
+CREATE TABLE IF NOT EXISTS chat_contacts (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    avatar TEXT,
+    is_online BOOLEAN DEFAULT FALSE,
+    last_message TEXT,
+    last_sent TIMESTAMPTZ,
+    unread_count BIGINT DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sender_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    message TEXT NOT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    target_group_id TEXT REFERENCES groups(id) ON DELETE SET NULL,
+    target_event_id TEXT REFERENCES group_events(id) ON DELETE SET NULL,
+    target_post_id BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    banner_image TEXT,
+    profile_image TEXT,
+    username TEXT,
+    full_name TEXT,
+    bio TEXT,
+    work TEXT,
+    education TEXT,
+    email TEXT,
+    phone TEXT,
+    website TEXT,
+    location TEXT,
+    tech_skills TEXT,
+    soft_skills TEXT,
+    interests TEXT,
+    is_private BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_profiles_user_id ON user_profiles(user_id);
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    target_type TEXT NOT NULL,
+    target_id BIGINT NOT NULL,
+    editor_id TEXT NOT NULL,
+    content TEXT,
+    image_path TEXT,
+    video_path TEXT,
+    privacy TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_target_type ON post_revisions(target_type);
+CREATE INDEX IF NOT EXISTS idx_post_revisions_target_id ON post_revisions(target_id);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    post_id BIGINT NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_post_id ON post_tags(post_id);
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags(tag);
+CREATE INDEX IF NOT EXISTS idx_post_tags_created_at ON post_tags(created_at);
+
+CREATE TABLE IF NOT EXISTS trending_topics (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    tag TEXT NOT NULL UNIQUE,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    posts_count BIGINT DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Revert migration for group_posts table
+
+BEGIN;
+
+ALTER TABLE group_posts DROP COLUMN IF EXISTS is_pinned;
+
+COMMIT;
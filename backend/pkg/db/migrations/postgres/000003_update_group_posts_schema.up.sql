@@ -0,0 +1,7 @@
+-- Migration to update group_posts table schema
+
+BEGIN;
+
+ALTER TABLE group_posts ADD COLUMN is_pinned BOOLEAN DEFAULT FALSE;
+
+COMMIT;
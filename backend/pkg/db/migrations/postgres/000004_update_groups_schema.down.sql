@@ -0,0 +1,8 @@
+-- Revert migration for groups table
+
+BEGIN;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS post_approval;
+ALTER TABLE groups DROP COLUMN IF EXISTS new_member_approval_posts;
+
+COMMIT;
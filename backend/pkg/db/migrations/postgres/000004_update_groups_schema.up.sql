@@ -0,0 +1,8 @@
+-- Migration to update groups table schema
+
+BEGIN;
+
+ALTER TABLE groups ADD COLUMN post_approval BOOLEAN DEFAULT FALSE;
+ALTER TABLE groups ADD COLUMN new_member_approval_posts BIGINT DEFAULT 0;
+
+COMMIT;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS group_bans;
\ No newline at end of file
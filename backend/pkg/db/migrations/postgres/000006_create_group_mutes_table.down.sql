@@ -0,0 +1 @@
+DROP TABLE IF EXISTS group_mutes;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- Revert migration for group_posts table
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_group_posts_status;
+ALTER TABLE group_posts DROP COLUMN IF EXISTS status;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Migration to update group_posts table schema
+
+BEGIN;
+
+ALTER TABLE group_posts ADD COLUMN status TEXT DEFAULT 'approved' NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_group_posts_status ON group_posts(status);
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Revert migration for groups table
+
+BEGIN;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS auto_approve_rules;
+
+COMMIT;
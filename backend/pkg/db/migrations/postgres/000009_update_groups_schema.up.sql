@@ -0,0 +1,7 @@
+-- Migration to update groups table schema
+
+BEGIN;
+
+ALTER TABLE groups ADD COLUMN auto_approve_rules TEXT DEFAULT '' NOT NULL;
+
+COMMIT;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS group_invite_links;
\ No newline at end of file
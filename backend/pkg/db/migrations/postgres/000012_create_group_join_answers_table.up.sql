@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS group_join_answers (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    question_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    answer TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (question_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_join_answers_group_id ON group_join_answers(group_id);
+
+COMMIT;
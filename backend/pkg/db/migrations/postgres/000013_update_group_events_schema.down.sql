@@ -0,0 +1,12 @@
+-- Revert migration for group_events table
+
+BEGIN;
+
+ALTER TABLE group_events DROP COLUMN IF EXISTS end_date;
+ALTER TABLE group_events DROP COLUMN IF EXISTS time_zone;
+ALTER TABLE group_events DROP COLUMN IF EXISTS location;
+ALTER TABLE group_events DROP COLUMN IF EXISTS capacity;
+ALTER TABLE group_events DROP COLUMN IF EXISTS recurrence_rule;
+ALTER TABLE group_events DROP COLUMN IF EXISTS recurrence_exdates;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration to update group_events table schema
+
+BEGIN;
+
+ALTER TABLE group_events ADD COLUMN end_date TIMESTAMPTZ;
+ALTER TABLE group_events ADD COLUMN time_zone TEXT DEFAULT 'UTC' NOT NULL;
+ALTER TABLE group_events ADD COLUMN location TEXT DEFAULT '' NOT NULL;
+ALTER TABLE group_events ADD COLUMN capacity BIGINT DEFAULT 0;
+ALTER TABLE group_events ADD COLUMN recurrence_rule TEXT DEFAULT '' NOT NULL;
+ALTER TABLE group_events ADD COLUMN recurrence_exdates TEXT DEFAULT '' NOT NULL;
+
+COMMIT;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS scheduled_jobs;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- Revert migration for group_events table
+
+BEGIN;
+
+ALTER TABLE group_events DROP COLUMN IF EXISTS guest_limit;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Migration to update group_events table schema
+
+BEGIN;
+
+ALTER TABLE group_events ADD COLUMN guest_limit BIGINT DEFAULT 0 NOT NULL;
+
+COMMIT;
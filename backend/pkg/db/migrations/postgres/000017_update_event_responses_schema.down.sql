@@ -0,0 +1,8 @@
+-- Revert migration for event_responses table
+
+BEGIN;
+
+ALTER TABLE event_responses DROP COLUMN IF EXISTS guests;
+ALTER TABLE event_responses DROP COLUMN IF EXISTS waitlisted_at;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Migration to update event_responses table schema
+
+BEGIN;
+
+ALTER TABLE event_responses ADD COLUMN guests BIGINT DEFAULT 0 NOT NULL;
+ALTER TABLE event_responses ADD COLUMN waitlisted_at TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_blocks;
\ No newline at end of file
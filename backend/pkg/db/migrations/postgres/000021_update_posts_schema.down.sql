@@ -0,0 +1,9 @@
+-- Revert migration for posts table
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_posts_audience_list_id;
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_audience_list_id_fkey;
+ALTER TABLE posts DROP COLUMN IF EXISTS audience_list_id;
+
+COMMIT;
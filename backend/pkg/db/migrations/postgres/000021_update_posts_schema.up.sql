@@ -0,0 +1,10 @@
+-- Migration to update posts table schema
+
+BEGIN;
+
+ALTER TABLE posts ADD COLUMN audience_list_id BIGINT;
+UPDATE posts SET audience_list_id = NULL WHERE NOT audience_list_id IN (SELECT id FROM audience_lists);
+ALTER TABLE posts ADD CONSTRAINT posts_audience_list_id_fkey FOREIGN KEY (audience_list_id) REFERENCES audience_lists(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_audience_list_id ON posts(audience_list_id);
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Revert migration for users table
+
+BEGIN;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+
+COMMIT;
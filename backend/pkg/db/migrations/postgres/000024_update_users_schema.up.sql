@@ -0,0 +1,8 @@
+-- Migration to update users table schema
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+COMMIT;
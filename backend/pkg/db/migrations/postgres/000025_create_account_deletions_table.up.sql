@@ -0,0 +1,10 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS account_deletions (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    requested_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    delete_at TIMESTAMPTZ NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS post_keys;
\ No newline at end of file
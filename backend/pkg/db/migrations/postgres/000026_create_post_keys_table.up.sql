@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS post_keys (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Reshares of posts deleted before ids were reserved are tombstoned like DeletePost does
+UPDATE posts SET shared_post_id = 0 WHERE shared_post_id NOT IN (SELECT id FROM posts UNION SELECT id FROM group_posts);
+
+-- Ids already given to posts and group posts stay reserved, new posts continue after them
+INSERT INTO post_keys (id) SELECT id FROM posts UNION SELECT id FROM group_posts;
+SELECT setval(pg_get_serial_sequence('post_keys', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM post_keys;
+
+COMMIT;
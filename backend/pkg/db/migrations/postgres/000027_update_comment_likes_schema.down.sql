@@ -0,0 +1,7 @@
+-- Revert migration for comment_likes table
+
+BEGIN;
+
+ALTER TABLE comment_likes DROP CONSTRAINT IF EXISTS comment_likes_comment_id_user_id_key;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration to update comment_likes table schema
+
+BEGIN;
+
+DELETE FROM comment_likes a USING comment_likes b
+WHERE a.ctid > b.ctid AND a.comment_id = b.comment_id AND a.user_id = b.user_id;
+ALTER TABLE comment_likes ADD CONSTRAINT comment_likes_comment_id_user_id_key UNIQUE (comment_id, user_id);
+
+-- Duplicate likes from concurrent toggles were dropped, count what is left
+UPDATE comments SET likes_count = (SELECT COUNT(*) FROM comment_likes WHERE comment_likes.comment_id = comments.id);
+
+COMMIT;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS followers;
\ No newline at end of file
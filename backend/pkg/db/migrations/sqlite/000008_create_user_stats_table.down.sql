@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_stats;
\ No newline at end of file
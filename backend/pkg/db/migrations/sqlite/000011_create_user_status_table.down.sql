@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_status;
\ No newline at end of file
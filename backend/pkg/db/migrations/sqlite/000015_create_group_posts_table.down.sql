@@ -0,0 +1 @@
+DROP TABLE IF EXISTS group_posts;
\ No newline at end of file
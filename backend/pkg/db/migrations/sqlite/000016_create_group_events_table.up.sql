@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS group_events (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    creator_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    event_date TIMESTAMP NOT NULL,
+    banner_path TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_events_group_id ON group_events(group_id);
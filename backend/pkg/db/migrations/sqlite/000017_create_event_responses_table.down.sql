@@ -0,0 +1 @@
+DROP TABLE IF EXISTS event_responses;
\ No newline at end of file
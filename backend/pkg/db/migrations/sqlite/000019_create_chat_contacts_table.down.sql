@@ -0,0 +1 @@
+DROP TABLE IF EXISTS chat_contacts;
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS user_profiles (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    banner_image TEXT,
+    profile_image TEXT,
+    username TEXT,
+    full_name TEXT,
+    bio TEXT,
+    work TEXT,
+    education TEXT,
+    email TEXT,
+    phone TEXT,
+    website TEXT,
+    location TEXT,
+    tech_skills TEXT,
+    soft_skills TEXT,
+    interests TEXT,
+    is_private BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_profiles_user_id ON user_profiles(user_id);
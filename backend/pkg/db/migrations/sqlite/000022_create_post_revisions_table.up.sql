@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    editor_id TEXT NOT NULL,
+    content TEXT,
+    image_path TEXT,
+    video_path TEXT,
+    privacy TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_target_type ON post_revisions(target_type);
+CREATE INDEX IF NOT EXISTS idx_post_revisions_target_id ON post_revisions(target_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS trending_topics;
\ No newline at end of file
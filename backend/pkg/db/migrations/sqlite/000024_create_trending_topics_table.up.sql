@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS trending_topics (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tag TEXT NOT NULL UNIQUE,
+    score REAL NOT NULL DEFAULT 0,
+    posts_count INTEGER DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
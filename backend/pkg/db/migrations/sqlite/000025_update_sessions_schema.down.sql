@@ -0,0 +1,26 @@
+-- Revert migration for sessions table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE sessions_new (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO sessions_new (id, user_id, expires_at, created_at)
+SELECT id, user_id, expires_at, created_at FROM sessions;
+
+-- Drop new table and rename temp table
+DROP TABLE sessions;
+ALTER TABLE sessions_new RENAME TO sessions;
+
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,34 @@
+-- Migration to update follow_requests table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE follow_requests_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    follower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    following_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (follower_id, following_id)
+);
+
+-- Copy data from old table to new table
+INSERT OR IGNORE INTO follow_requests_new (id, follower_id, following_id, status, created_at, updated_at)
+SELECT id, follower_id, following_id, status, created_at, updated_at FROM follow_requests
+WHERE (follower_id IS NULL OR follower_id IN (SELECT id FROM users))
+  AND (following_id IS NULL OR following_id IN (SELECT id FROM users));
+
+-- Drop old table and rename new table
+DROP TABLE follow_requests;
+ALTER TABLE follow_requests_new RENAME TO follow_requests;
+
+CREATE INDEX IF NOT EXISTS idx_follow_requests_follower_id ON follow_requests(follower_id);
+CREATE INDEX IF NOT EXISTS idx_follow_requests_following_id ON follow_requests(following_id);
+CREATE INDEX IF NOT EXISTS idx_follow_requests_status ON follow_requests(status);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,28 @@
+-- Revert migration for followers table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE followers_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    follower_id TEXT NOT NULL,
+    following_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO followers_new (id, follower_id, following_id, created_at)
+SELECT id, follower_id, following_id, created_at FROM followers;
+
+-- Drop new table and rename temp table
+DROP TABLE followers;
+ALTER TABLE followers_new RENAME TO followers;
+
+CREATE INDEX IF NOT EXISTS idx_followers_following_id ON followers(following_id);
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers(follower_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
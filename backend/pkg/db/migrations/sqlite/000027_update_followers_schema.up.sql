@@ -0,0 +1,31 @@
+-- Migration to update followers table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE followers_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    follower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    following_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (follower_id, following_id)
+);
+
+-- Copy data from old table to new table
+INSERT OR IGNORE INTO followers_new (id, follower_id, following_id, created_at)
+SELECT id, follower_id, following_id, created_at FROM followers
+WHERE (follower_id IS NULL OR follower_id IN (SELECT id FROM users))
+  AND (following_id IS NULL OR following_id IN (SELECT id FROM users));
+
+-- Drop old table and rename new table
+DROP TABLE followers;
+ALTER TABLE followers_new RENAME TO followers;
+
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers(follower_id);
+CREATE INDEX IF NOT EXISTS idx_followers_following_id ON followers(following_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,27 @@
+-- Revert migration for user_status table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE user_status_new (
+    user_id TEXT PRIMARY KEY,
+    is_online BOOLEAN DEFAULT FALSE,
+    last_activity TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO user_status_new (user_id, is_online, last_activity, updated_at)
+SELECT user_id, is_online, last_activity, updated_at FROM user_status;
+
+-- Drop new table and rename temp table
+DROP TABLE user_status;
+ALTER TABLE user_status_new RENAME TO user_status;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_status_user_id ON user_status(user_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,28 @@
+-- Migration to update user_status table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE user_status_new (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    is_online BOOLEAN DEFAULT FALSE,
+    last_activity TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data from old table to new table
+INSERT INTO user_status_new (user_id, is_online, last_activity, updated_at)
+SELECT user_id, is_online, last_activity, updated_at FROM user_status
+WHERE (user_id IS NULL OR user_id IN (SELECT id FROM users));
+
+-- Drop old table and rename new table
+DROP TABLE user_status;
+ALTER TABLE user_status_new RENAME TO user_status;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_status_user_id ON user_status(user_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
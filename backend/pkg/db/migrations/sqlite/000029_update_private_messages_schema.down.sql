@@ -0,0 +1,31 @@
+-- Revert migration for private_messages table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE private_messages_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sender_id TEXT NOT NULL,
+    receiver_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP,
+    is_read BOOLEAN DEFAULT FALSE
+);
+
+-- Copy data back (best effort)
+INSERT INTO private_messages_new (id, sender_id, receiver_id, content, created_at, read_at, is_read)
+SELECT id, sender_id, receiver_id, content, created_at, read_at, is_read FROM private_messages;
+
+-- Drop new table and rename temp table
+DROP TABLE private_messages;
+ALTER TABLE private_messages_new RENAME TO private_messages;
+
+CREATE INDEX IF NOT EXISTS idx_private_messages_receiver_id ON private_messages(receiver_id);
+CREATE INDEX IF NOT EXISTS idx_private_messages_sender_id ON private_messages(sender_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,33 @@
+-- Migration to update private_messages table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE private_messages_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sender_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    receiver_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP,
+    is_read BOOLEAN DEFAULT FALSE
+);
+
+-- Copy data from old table to new table
+INSERT INTO private_messages_new (id, sender_id, receiver_id, content, created_at, read_at, is_read)
+SELECT id, sender_id, receiver_id, content, created_at, read_at, is_read FROM private_messages
+WHERE (sender_id IS NULL OR sender_id IN (SELECT id FROM users))
+  AND (receiver_id IS NULL OR receiver_id IN (SELECT id FROM users));
+
+-- Drop old table and rename new table
+DROP TABLE private_messages;
+ALTER TABLE private_messages_new RENAME TO private_messages;
+
+CREATE INDEX IF NOT EXISTS idx_private_messages_sender_id ON private_messages(sender_id);
+CREATE INDEX IF NOT EXISTS idx_private_messages_receiver_id ON private_messages(receiver_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,31 @@
+-- Revert migration for chat_contacts table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE chat_contacts_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    avatar TEXT,
+    is_online BOOLEAN DEFAULT FALSE,
+    last_message TEXT,
+    last_sent TIMESTAMP,
+    unread_count INTEGER DEFAULT 0
+);
+
+-- Copy data back (best effort)
+INSERT INTO chat_contacts_new (id, user_id, first_name, last_name, avatar, is_online, last_message, last_sent, unread_count)
+SELECT id, user_id, first_name, last_name, avatar, is_online, last_message, last_sent, unread_count FROM chat_contacts;
+
+-- Drop new table and rename temp table
+DROP TABLE chat_contacts;
+ALTER TABLE chat_contacts_new RENAME TO chat_contacts;
+
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
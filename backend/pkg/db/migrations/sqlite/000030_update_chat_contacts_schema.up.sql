@@ -0,0 +1,32 @@
+-- Migration to update chat_contacts table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE chat_contacts_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    avatar TEXT,
+    is_online BOOLEAN DEFAULT FALSE,
+    last_message TEXT,
+    last_sent TIMESTAMP,
+    unread_count INTEGER DEFAULT 0
+);
+
+-- Copy data from old table to new table
+INSERT INTO chat_contacts_new (id, user_id, first_name, last_name, avatar, is_online, last_message, last_sent, unread_count)
+SELECT id, user_id, first_name, last_name, avatar, is_online, last_message, last_sent, unread_count FROM chat_contacts
+WHERE (user_id IS NULL OR user_id IN (SELECT id FROM users));
+
+-- Drop old table and rename new table
+DROP TABLE chat_contacts;
+ALTER TABLE chat_contacts_new RENAME TO chat_contacts;
+
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,33 @@
+-- Revert migration for notifications table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    sender_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    message TEXT NOT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    target_group_id TEXT,
+    target_event_id TEXT,
+    target_post_id TEXT
+);
+
+-- Copy data back (best effort)
+INSERT INTO notifications_new (id, user_id, sender_id, type, message, is_read, created_at, target_group_id, target_event_id, target_post_id)
+SELECT id, user_id, sender_id, type, message, is_read, created_at, target_group_id, target_event_id, target_post_id FROM notifications;
+
+-- Drop new table and rename temp table
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,35 @@
+-- Migration to update notifications table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sender_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    message TEXT NOT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    target_group_id TEXT REFERENCES groups(id) ON DELETE SET NULL,
+    target_event_id TEXT REFERENCES group_events(id) ON DELETE SET NULL,
+    target_post_id TEXT
+);
+
+-- Copy data from old table to new table
+INSERT INTO notifications_new (id, user_id, sender_id, type, message, is_read, created_at, target_group_id, target_event_id, target_post_id)
+SELECT id, user_id, sender_id, type, message, is_read, created_at, CASE WHEN target_group_id IN (SELECT id FROM groups) THEN target_group_id END, CASE WHEN target_event_id IN (SELECT id FROM group_events) THEN target_event_id END, target_post_id FROM notifications
+WHERE (user_id IS NULL OR user_id IN (SELECT id FROM users))
+  AND (sender_id IS NULL OR sender_id IN (SELECT id FROM users));
+
+-- Drop old table and rename new table
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
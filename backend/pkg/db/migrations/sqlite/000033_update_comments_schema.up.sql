@@ -0,0 +1,37 @@
+-- Migration to update comments table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE comments_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    parent_id INTEGER,
+    depth INTEGER DEFAULT 0,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    is_edited BOOLEAN DEFAULT FALSE,
+    likes_count INTEGER DEFAULT 0,
+    replies_count INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Copy data from old table to new table
+INSERT INTO comments_new (id, post_id, user_id, parent_id, depth, content, image_path, is_edited, likes_count, replies_count, created_at, updated_at)
+SELECT id, post_id, user_id, parent_id, depth, content, image_path, is_edited, likes_count, replies_count, created_at, updated_at FROM comments;
+
+-- Drop old table and rename new table
+DROP TABLE comments;
+ALTER TABLE comments_new RENAME TO comments;
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments(user_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
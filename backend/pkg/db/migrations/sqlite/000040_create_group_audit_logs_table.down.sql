@@ -0,0 +1 @@
+DROP TABLE IF EXISTS group_audit_logs;
\ No newline at end of file
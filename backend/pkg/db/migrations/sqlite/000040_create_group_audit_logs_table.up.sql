@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS group_audit_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_user_id TEXT,
+    target_post_id INTEGER,
+    details TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_audit_logs_group_id ON group_audit_logs(group_id);
+CREATE INDEX IF NOT EXISTS idx_group_audit_logs_created_at ON group_audit_logs(created_at);
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- Revert migration for group_posts table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE group_posts_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    content TEXT,
+    image_path TEXT,
+    video_path TEXT,
+    likes_count INTEGER DEFAULT 0,
+    comments_count INTEGER DEFAULT 0,
+    is_edited BOOLEAN DEFAULT FALSE,
+    is_pinned BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO group_posts_new (id, group_id, user_id, content, image_path, video_path, likes_count, comments_count, is_edited, is_pinned, created_at, updated_at)
+SELECT id, group_id, user_id, content, image_path, video_path, likes_count, comments_count, is_edited, is_pinned, created_at, updated_at FROM group_posts;
+
+-- Drop new table and rename temp table
+DROP TABLE group_posts;
+ALTER TABLE group_posts_new RENAME TO group_posts;
+
+CREATE INDEX IF NOT EXISTS idx_group_posts_user_id ON group_posts(user_id);
+CREATE INDEX IF NOT EXISTS idx_group_posts_group_id ON group_posts(group_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,38 @@
+-- Migration to update group_posts table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE group_posts_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    content TEXT,
+    image_path TEXT,
+    video_path TEXT,
+    likes_count INTEGER DEFAULT 0,
+    comments_count INTEGER DEFAULT 0,
+    is_edited BOOLEAN DEFAULT FALSE,
+    is_pinned BOOLEAN DEFAULT FALSE,
+    status TEXT NOT NULL DEFAULT 'approved',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data from old table to new table
+INSERT INTO group_posts_new (id, group_id, user_id, content, image_path, video_path, likes_count, comments_count, is_edited, is_pinned, created_at, updated_at)
+SELECT id, group_id, user_id, content, image_path, video_path, likes_count, comments_count, is_edited, is_pinned, created_at, updated_at FROM group_posts;
+
+-- Drop old table and rename new table
+DROP TABLE group_posts;
+ALTER TABLE group_posts_new RENAME TO group_posts;
+
+CREATE INDEX IF NOT EXISTS idx_group_posts_group_id ON group_posts(group_id);
+CREATE INDEX IF NOT EXISTS idx_group_posts_user_id ON group_posts(user_id);
+CREATE INDEX IF NOT EXISTS idx_group_posts_status ON group_posts(status);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,34 @@
+-- Revert migration for groups table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE groups_new (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    creator_id TEXT NOT NULL,
+    banner_path TEXT,
+    profile_pic_path TEXT,
+    is_public BOOLEAN DEFAULT TRUE,
+    post_approval BOOLEAN DEFAULT FALSE,
+    new_member_approval_posts INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO groups_new (id, name, description, creator_id, banner_path, profile_pic_path, is_public, post_approval, new_member_approval_posts, created_at, updated_at)
+SELECT id, name, description, creator_id, banner_path, profile_pic_path, is_public, post_approval, new_member_approval_posts, created_at, updated_at FROM groups;
+
+-- Drop new table and rename temp table
+DROP TABLE groups;
+ALTER TABLE groups_new RENAME TO groups;
+
+CREATE INDEX IF NOT EXISTS idx_groups_creator_id ON groups(creator_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS group_join_questions (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    question TEXT NOT NULL,
+    required BOOLEAN DEFAULT FALSE,
+    position INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_join_questions_group_id ON group_join_questions(group_id);
+
+COMMIT;
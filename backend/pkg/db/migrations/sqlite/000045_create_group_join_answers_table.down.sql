@@ -0,0 +1 @@
+DROP TABLE IF EXISTS group_join_answers;
\ No newline at end of file
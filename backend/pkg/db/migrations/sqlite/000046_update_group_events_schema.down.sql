@@ -0,0 +1,32 @@
+-- Revert migration for group_events table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE group_events_new (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    creator_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    event_date TIMESTAMP NOT NULL,
+    banner_path TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO group_events_new (id, group_id, creator_id, title, description, event_date, banner_path, created_at, updated_at)
+SELECT id, group_id, creator_id, title, description, event_date, banner_path, created_at, updated_at FROM group_events;
+
+-- Drop new table and rename temp table
+DROP TABLE group_events;
+ALTER TABLE group_events_new RENAME TO group_events;
+
+CREATE INDEX IF NOT EXISTS idx_group_events_group_id ON group_events(group_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
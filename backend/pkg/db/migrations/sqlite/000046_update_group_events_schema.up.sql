@@ -0,0 +1,38 @@
+-- Migration to update group_events table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE group_events_new (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    creator_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    event_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    location TEXT NOT NULL DEFAULT '',
+    capacity INTEGER DEFAULT 0,
+    recurrence_rule TEXT NOT NULL DEFAULT '',
+    recurrence_exdates TEXT NOT NULL DEFAULT '',
+    banner_path TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data from old table to new table
+INSERT INTO group_events_new (id, group_id, creator_id, title, description, event_date, banner_path, created_at, updated_at)
+SELECT id, group_id, creator_id, title, description, event_date, banner_path, created_at, updated_at FROM group_events;
+
+-- Drop old table and rename new table
+DROP TABLE group_events;
+ALTER TABLE group_events_new RENAME TO group_events;
+
+CREATE INDEX IF NOT EXISTS idx_group_events_group_id ON group_events(group_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,30 @@
+-- Revert migration for event_responses table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE event_responses_new (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    response TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO event_responses_new (id, event_id, user_id, response, created_at, updated_at)
+SELECT id, event_id, user_id, response, created_at, updated_at FROM event_responses;
+
+-- Drop new table and rename temp table
+DROP TABLE event_responses;
+ALTER TABLE event_responses_new RENAME TO event_responses;
+
+CREATE INDEX IF NOT EXISTS idx_event_responses_user_id ON event_responses(user_id);
+CREATE INDEX IF NOT EXISTS idx_event_responses_event_id ON event_responses(event_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,32 @@
+-- Migration to update event_responses table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE event_responses_new (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    response TEXT NOT NULL,
+    guests INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    waitlisted_at TIMESTAMP
+);
+
+-- Copy data from old table to new table
+INSERT INTO event_responses_new (id, event_id, user_id, response, created_at, updated_at)
+SELECT id, event_id, user_id, response, created_at, updated_at FROM event_responses;
+
+-- Drop old table and rename new table
+DROP TABLE event_responses;
+ALTER TABLE event_responses_new RENAME TO event_responses;
+
+CREATE INDEX IF NOT EXISTS idx_event_responses_event_id ON event_responses(event_id);
+CREATE INDEX IF NOT EXISTS idx_event_responses_user_id ON event_responses(user_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
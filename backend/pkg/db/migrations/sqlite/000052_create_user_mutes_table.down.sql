@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_mutes;
\ No newline at end of file
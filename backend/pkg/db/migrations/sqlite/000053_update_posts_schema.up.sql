@@ -0,0 +1,39 @@
+-- Migration to update posts table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE posts_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    video_path TEXT,
+    privacy TEXT NOT NULL,
+    likes_count INTEGER DEFAULT 0,
+    comments_count INTEGER DEFAULT 0,
+    is_edited BOOLEAN DEFAULT FALSE,
+    shared_post_id INTEGER,
+    shares_count INTEGER DEFAULT 0,
+    audience_list_id INTEGER REFERENCES audience_lists(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Copy data from old table to new table
+INSERT INTO posts_new (id, user_id, content, image_path, video_path, privacy, likes_count, comments_count, is_edited, shared_post_id, shares_count, created_at, updated_at)
+SELECT id, user_id, content, image_path, video_path, privacy, likes_count, comments_count, is_edited, shared_post_id, shares_count, created_at, updated_at FROM posts;
+
+-- Drop old table and rename new table
+DROP TABLE posts;
+ALTER TABLE posts_new RENAME TO posts;
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts(user_id);
+CREATE INDEX IF NOT EXISTS idx_posts_shared_post_id ON posts(shared_post_id);
+CREATE INDEX IF NOT EXISTS idx_posts_audience_list_id ON posts(audience_list_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
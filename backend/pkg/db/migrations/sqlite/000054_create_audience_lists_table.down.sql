@@ -0,0 +1 @@
+DROP TABLE IF EXISTS audience_lists;
\ No newline at end of file
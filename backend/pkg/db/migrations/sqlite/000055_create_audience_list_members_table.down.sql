@@ -0,0 +1 @@
+DROP TABLE IF EXISTS audience_list_members;
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- Revert migration for users table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE users_new (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    date_of_birth TEXT NOT NULL,
+    avatar TEXT,
+    nickname TEXT,
+    about_me TEXT,
+    is_public BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO users_new (id, email, password, first_name, last_name, date_of_birth, avatar, nickname, about_me, is_public, created_at, updated_at)
+SELECT id, email, password, first_name, last_name, date_of_birth, avatar, nickname, about_me, is_public, created_at, updated_at FROM users;
+
+-- Drop new table and rename temp table
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
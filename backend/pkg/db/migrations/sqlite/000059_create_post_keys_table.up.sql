@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS post_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Reshares of posts deleted before ids were reserved are tombstoned like DeletePost does
+UPDATE posts SET shared_post_id = 0 WHERE CAST(shared_post_id AS INTEGER) NOT IN (SELECT id FROM posts UNION SELECT id FROM group_posts);
+
+-- Ids already given to posts and group posts stay reserved, new posts continue after them
+INSERT INTO post_keys (id) SELECT id FROM posts UNION SELECT id FROM group_posts;
+
+COMMIT;
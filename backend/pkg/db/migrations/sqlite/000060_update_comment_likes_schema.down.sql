@@ -0,0 +1,28 @@
+-- Revert migration for comment_likes table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE comment_likes_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    comment_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Copy data back (best effort)
+INSERT INTO comment_likes_new (id, comment_id, user_id, created_at)
+SELECT id, comment_id, user_id, created_at FROM comment_likes;
+
+-- Drop new table and rename temp table
+DROP TABLE comment_likes;
+ALTER TABLE comment_likes_new RENAME TO comment_likes;
+
+CREATE INDEX IF NOT EXISTS idx_comment_likes_user_id ON comment_likes(user_id);
+CREATE INDEX IF NOT EXISTS idx_comment_likes_comment_id ON comment_likes(comment_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,32 @@
+-- Migration to update comment_likes table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE comment_likes_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    comment_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (comment_id, user_id)
+);
+
+-- Copy data from old table to new table
+INSERT OR IGNORE INTO comment_likes_new (id, comment_id, user_id, created_at)
+SELECT id, comment_id, user_id, created_at FROM comment_likes;
+
+-- Drop old table and rename new table
+DROP TABLE comment_likes;
+ALTER TABLE comment_likes_new RENAME TO comment_likes;
+
+CREATE INDEX IF NOT EXISTS idx_comment_likes_comment_id ON comment_likes(comment_id);
+CREATE INDEX IF NOT EXISTS idx_comment_likes_user_id ON comment_likes(user_id);
+
+-- Duplicate likes from concurrent toggles were dropped, count what is left
+UPDATE comments SET likes_count = (SELECT COUNT(*) FROM comment_likes WHERE comment_likes.comment_id = comments.id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
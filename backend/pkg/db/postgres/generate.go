@@ -0,0 +1,510 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Athooh/social-network/pkg/db/sqlite"
+)
+
+// The schema is planned once, from the model structs and the SQLite migrations, and
+// rendered for each dialect. The functions below render PostgreSQL SQL from the same
+// table schemas the SQLite generator uses. Constraints get PostgreSQL's default names
+// (posts_pkey, users_email_key, comments_post_id_fkey) so later migrations can find them.
+
+// InitialMigration creates every table in tables, tables referenced by foreign keys first
+func InitialMigration(tables []sqlite.TableInfo) sqlite.Migration {
+	ordered := dependencyOrder(tables)
+
+	var up, down strings.Builder
+	up.WriteString("BEGIN;\n\n")
+	for _, table := range ordered {
+		up.WriteString(createTableSQL(table))
+	}
+	up.WriteString("COMMIT;\n")
+
+	for i := len(ordered) - 1; i >= 0; i-- {
+		down.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", ordered[i].Name))
+	}
+
+	return sqlite.Migration{Name: "initial_schema", Up: up.String(), Down: down.String()}
+}
+
+// RenderMigrations renders migrations planned by the SQLite generator as PostgreSQL
+// migrations. New tables are moved after the new tables they reference.
+func RenderMigrations(migrations []sqlite.Migration) []sqlite.Migration {
+	created := make(map[string]bool)
+	for _, m := range migrations {
+		if m.From == nil {
+			created[m.To.Name] = true
+		}
+	}
+
+	var rendered []sqlite.Migration
+	done := make(map[string]bool)
+	var visit func(m sqlite.Migration)
+	visit = func(m sqlite.Migration) {
+		if done[m.To.Name] {
+			return
+		}
+		done[m.To.Name] = true
+
+		for _, dep := range referencedTables(m.To) {
+			if created[dep] && dep != m.To.Name {
+				for _, other := range migrations {
+					if other.From == nil && other.To.Name == dep {
+						visit(other)
+					}
+				}
+			}
+		}
+		rendered = append(rendered, renderMigration(m))
+	}
+
+	for _, m := range migrations {
+		visit(m)
+	}
+	return rendered
+}
+
+// renderMigration renders a single planned migration
+func renderMigration(m sqlite.Migration) sqlite.Migration {
+	if m.From == nil {
+		return sqlite.Migration{
+			Name: m.Name,
+			Up:   "BEGIN;\n\n" + createTableSQL(m.To) + "COMMIT;\n",
+			Down: fmt.Sprintf("DROP TABLE IF EXISTS %s;", m.To.Name),
+			To:   m.To,
+		}
+	}
+
+	from, to := *m.From, m.To
+	return sqlite.Migration{
+		Name: m.Name,
+		Up: fmt.Sprintf("-- Migration to update %s table schema\n\n", to.Name) +
+			alterTableSQL(from, to, sqlite.UpColumnCopies(from, to)),
+		Down: fmt.Sprintf("-- Revert migration for %s table\n\n", to.Name) +
+			alterTableSQL(to, from, sqlite.DownColumnCopies(from, to)),
+		From: m.From,
+		To:   m.To,
+	}
+}
+
+// columnType maps the column types of the table schemas to PostgreSQL types
+func columnType(t string) string {
+	switch strings.ToUpper(t) {
+	case "INTEGER":
+		return "BIGINT"
+	case "REAL":
+		return "DOUBLE PRECISION"
+	case "BLOB":
+		return "BYTEA"
+	case "TIMESTAMP":
+		return "TIMESTAMPTZ"
+	case "TEXT", "BOOLEAN":
+		return strings.ToUpper(t)
+	default:
+		return t
+	}
+}
+
+// createTableSQL generates SQL for creating a table and its indexes
+func createTableSQL(table sqlite.TableInfo) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", table.Name))
+	for i, col := range table.Columns {
+		if i > 0 {
+			sb.WriteString(",\n")
+		}
+
+		sb.WriteString(fmt.Sprintf("    %s %s", col.Name, columnType(col.Type)))
+
+		if col.PrimaryKey {
+			if strings.EqualFold(col.Type, "INTEGER") {
+				sb.WriteString(" GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY")
+			} else {
+				sb.WriteString(" PRIMARY KEY")
+			}
+		}
+
+		if col.NotNull {
+			sb.WriteString(" NOT NULL")
+		}
+
+		if col.Unique {
+			sb.WriteString(" UNIQUE")
+		}
+
+		if col.Default != "" {
+			sb.WriteString(fmt.Sprintf(" DEFAULT %s", col.Default))
+		}
+
+		if col.References != "" {
+			sb.WriteString(fmt.Sprintf(" REFERENCES %s", col.References))
+		}
+	}
+
+	if len(table.CompositePrimaryKey) > 0 {
+		sb.WriteString(fmt.Sprintf(",\n    PRIMARY KEY (%s)", strings.Join(table.CompositePrimaryKey, ", ")))
+	}
+	for _, cols := range table.UniqueConstraints {
+		sb.WriteString(fmt.Sprintf(",\n    UNIQUE (%s)", strings.Join(cols, ", ")))
+	}
+	sb.WriteString("\n);\n\n")
+
+	for _, idx := range table.Indexes {
+		sb.WriteString(createIndexSQL(table.Name, idx))
+	}
+	if len(table.Indexes) > 0 {
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// createIndexSQL generates the CREATE INDEX statement of an index
+func createIndexSQL(tableName string, idx sqlite.IndexInfo) string {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s(%s);\n", unique, idx.Name, tableName, strings.Join(idx.Columns, ", "))
+}
+
+// constraint is a table constraint under the name PostgreSQL gives it by default
+type constraint struct {
+	Name       string
+	Definition string
+	Columns    []string
+	Reference  string // foreign keys only
+}
+
+// constraints lists the primary key, unique and foreign key constraints of a table
+func constraints(table sqlite.TableInfo) []constraint {
+	var list []constraint
+
+	pk := table.CompositePrimaryKey
+	for _, col := range table.Columns {
+		if col.PrimaryKey {
+			pk = []string{col.Name}
+		}
+	}
+	if len(pk) > 0 {
+		list = append(list, constraint{
+			Name:       table.Name + "_pkey",
+			Definition: fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pk, ", ")),
+			Columns:    pk,
+		})
+	}
+
+	unique := func(cols []string) {
+		list = append(list, constraint{
+			Name:       fmt.Sprintf("%s_%s_key", table.Name, strings.Join(cols, "_")),
+			Definition: fmt.Sprintf("UNIQUE (%s)", strings.Join(cols, ", ")),
+			Columns:    cols,
+		})
+	}
+	for _, col := range table.Columns {
+		if col.Unique {
+			unique([]string{col.Name})
+		}
+	}
+	for _, cols := range table.UniqueConstraints {
+		unique(cols)
+	}
+
+	for _, col := range table.Columns {
+		if col.References != "" {
+			list = append(list, constraint{
+				Name:       fmt.Sprintf("%s_%s_fkey", table.Name, col.Name),
+				Definition: fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", col.Name, col.References),
+				Columns:    []string{col.Name},
+				Reference:  col.References,
+			})
+		}
+	}
+
+	return list
+}
+
+// renamed returns c with its columns renamed through renames, false if one of them is dropped
+func (c constraint) renamed(renames map[string]string) (constraint, bool) {
+	out := constraint{Name: c.Name, Reference: c.Reference}
+	for _, col := range c.Columns {
+		name, ok := renames[col]
+		if !ok {
+			return constraint{}, false
+		}
+		out.Columns = append(out.Columns, name)
+	}
+
+	cols := strings.Join(out.Columns, ", ")
+	switch {
+	case strings.HasPrefix(c.Definition, "PRIMARY KEY"):
+		out.Definition = fmt.Sprintf("PRIMARY KEY (%s)", cols)
+	case strings.HasPrefix(c.Definition, "UNIQUE"):
+		out.Definition = fmt.Sprintf("UNIQUE (%s)", cols)
+	default:
+		out.Definition = fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", cols, c.Reference)
+	}
+	return out, true
+}
+
+// alterTableSQL generates the statements changing the table described by from into the
+// one described by to. copies maps the columns of to to the columns of from they keep
+// the data of, see sqlite.UpColumnCopies.
+func alterTableSQL(from, to sqlite.TableInfo, copies []sqlite.ColumnCopy) string {
+	var sb strings.Builder
+	table := to.Name
+	alter := func(format string, args ...interface{}) {
+		sb.WriteString(fmt.Sprintf("ALTER TABLE %s %s;\n", table, fmt.Sprintf(format, args...)))
+	}
+
+	// renames maps each kept column of from to its name in to
+	renames := make(map[string]string)
+	for _, c := range copies {
+		renames[c.Source] = c.Column
+	}
+
+	sb.WriteString("BEGIN;\n\n")
+
+	// Indexes and constraints that change are dropped before the columns they cover
+	wantIndexes := make(map[string]sqlite.IndexInfo)
+	for _, idx := range to.Indexes {
+		wantIndexes[idx.Name] = idx
+	}
+	keptIndexes := make(map[string]bool)
+	for _, idx := range from.Indexes {
+		want, ok := wantIndexes[idx.Name]
+		if ok && want.Unique == idx.Unique && renamedColumns(idx.Columns, renames) == strings.Join(want.Columns, ",") {
+			keptIndexes[idx.Name] = true
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("DROP INDEX IF EXISTS %s;\n", idx.Name))
+	}
+
+	wantConstraints := constraints(to)
+	var keptConstraints []constraint // kept under their old name
+	for _, old := range constraints(from) {
+		if c, ok := old.renamed(renames); ok && hasDefinition(wantConstraints, c.Definition) {
+			keptConstraints = append(keptConstraints, old)
+			continue
+		}
+		alter("DROP CONSTRAINT IF EXISTS %s", old.Name)
+	}
+
+	// Type changes and conversions run before renames since their expressions use the
+	// old column names
+	for _, c := range copies {
+		oldCol, _ := findColumn(from, c.Source)
+		newCol, _ := findColumn(to, c.Column)
+		oldType, newType := columnType(oldCol.Type), columnType(newCol.Type)
+		if oldType == newType && c.Expr == c.Source {
+			continue
+		}
+
+		if oldCol.Default != "" {
+			alter("ALTER COLUMN %s DROP DEFAULT", c.Source)
+		}
+		using := c.Expr
+		if using == c.Source {
+			using = fmt.Sprintf("%s::%s", c.Source, newType)
+		}
+		alter("ALTER COLUMN %s TYPE %s USING %s", c.Source, newType, using)
+	}
+
+	for _, c := range copies {
+		if c.Column != c.Source {
+			alter("RENAME COLUMN %s TO %s", c.Source, c.Column)
+		}
+	}
+
+	for _, col := range from.Columns {
+		if _, kept := renames[col.Name]; !kept {
+			alter("DROP COLUMN IF EXISTS %s", col.Name)
+		}
+	}
+
+	for _, col := range to.Columns {
+		c, kept := findCopy(copies, col.Name)
+		if !kept {
+			def := fmt.Sprintf("%s %s", col.Name, columnType(col.Type))
+			if col.Default != "" {
+				def += " DEFAULT " + col.Default
+			}
+			if col.NotNull {
+				def += " NOT NULL"
+			}
+			alter("ADD COLUMN %s", def)
+			continue
+		}
+
+		oldCol, _ := findColumn(from, c.Source)
+		typeChanged := columnType(oldCol.Type) != columnType(col.Type) || c.Expr != c.Source
+		if col.Default != oldCol.Default || (typeChanged && oldCol.Default != "") {
+			if col.Default != "" {
+				alter("ALTER COLUMN %s SET DEFAULT %s", col.Name, col.Default)
+			} else if !typeChanged {
+				alter("ALTER COLUMN %s DROP DEFAULT", col.Name)
+			}
+		}
+		if col.NotNull != oldCol.NotNull {
+			if col.NotNull {
+				alter("ALTER COLUMN %s SET NOT NULL", col.Name)
+			} else {
+				alter("ALTER COLUMN %s DROP NOT NULL", col.Name)
+			}
+		}
+	}
+
+	// Constraints that survive a column rename follow it to their default name
+	for _, old := range keptConstraints {
+		c, _ := old.renamed(renames)
+		for _, want := range wantConstraints {
+			if want.Definition == c.Definition && want.Name != old.Name {
+				alter("RENAME CONSTRAINT %s TO %s", old.Name, want.Name)
+			}
+		}
+	}
+
+	for _, c := range wantConstraints {
+		if hasKept(keptConstraints, renames, c.Definition) {
+			continue
+		}
+		writeConstraintCleanup(&sb, table, c)
+		alter("ADD CONSTRAINT %s %s", c.Name, c.Definition)
+	}
+
+	for _, idx := range to.Indexes {
+		if !keptIndexes[idx.Name] {
+			sb.WriteString(createIndexSQL(table, idx))
+		}
+	}
+
+	sb.WriteString("\nCOMMIT;\n")
+	return sb.String()
+}
+
+// writeConstraintCleanup writes the statements removing the rows that would stop a new
+// constraint from being added, the same rows the SQLite table rebuild leaves out
+func writeConstraintCleanup(sb *strings.Builder, table string, c constraint) {
+	switch {
+	case strings.HasPrefix(c.Definition, "UNIQUE"):
+		// Duplicates of a newly added unique constraint keep their first row
+		var conditions []string
+		for _, col := range c.Columns {
+			conditions = append(conditions, fmt.Sprintf("a.%s = b.%s", col, col))
+		}
+		sb.WriteString(fmt.Sprintf("DELETE FROM %s a USING %s b\nWHERE a.ctid > b.ctid AND %s;\n",
+			table, table, strings.Join(conditions, " AND ")))
+
+	case c.Reference != "":
+		// Rows that break a newly added foreign key get what ON DELETE would have done to them
+		ref, ok := sqlite.ParseReference(c.Reference)
+		if !ok {
+			return
+		}
+		col := c.Columns[0]
+		exists := fmt.Sprintf("%s IN (SELECT %s FROM %s)", col, ref.Column, ref.Table)
+		switch ref.OnDelete {
+		case "CASCADE":
+			sb.WriteString(fmt.Sprintf("DELETE FROM %s WHERE %s IS NOT NULL AND NOT %s;\n", table, col, exists))
+		case "SET NULL":
+			sb.WriteString(fmt.Sprintf("UPDATE %s SET %s = NULL WHERE NOT %s;\n", table, col, exists))
+		}
+	}
+}
+
+// dependencyOrder orders tables so that every table comes after the tables it references
+func dependencyOrder(tables []sqlite.TableInfo) []sqlite.TableInfo {
+	byName := make(map[string]sqlite.TableInfo)
+	for _, table := range tables {
+		byName[table.Name] = table
+	}
+
+	var ordered []sqlite.TableInfo
+	done := make(map[string]bool)
+	var visit func(table sqlite.TableInfo)
+	visit = func(table sqlite.TableInfo) {
+		if done[table.Name] {
+			return
+		}
+		done[table.Name] = true
+
+		for _, dep := range referencedTables(table) {
+			if parent, ok := byName[dep]; ok {
+				visit(parent)
+			}
+		}
+		ordered = append(ordered, table)
+	}
+
+	for _, table := range tables {
+		visit(table)
+	}
+	return ordered
+}
+
+// referencedTables lists the tables a table's foreign keys point at
+func referencedTables(table sqlite.TableInfo) []string {
+	var names []string
+	for _, col := range table.Columns {
+		if ref, ok := sqlite.ParseReference(col.References); ok {
+			names = append(names, ref.Table)
+		}
+	}
+	return names
+}
+
+// renamedColumns joins columns after renaming them, dropped columns keep their name
+func renamedColumns(columns []string, renames map[string]string) string {
+	out := make([]string, len(columns))
+	for i, col := range columns {
+		out[i] = col
+		if name, ok := renames[col]; ok {
+			out[i] = name
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// hasDefinition checks if list holds a constraint with the given definition
+func hasDefinition(list []constraint, definition string) bool {
+	for _, c := range list {
+		if c.Definition == definition {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKept checks if one of the kept constraints ends up with the given definition
+func hasKept(kept []constraint, renames map[string]string, definition string) bool {
+	for _, old := range kept {
+		if c, ok := old.renamed(renames); ok && c.Definition == definition {
+			return true
+		}
+	}
+	return false
+}
+
+// findColumn looks up a column of a table by name
+func findColumn(table sqlite.TableInfo, name string) (sqlite.ColumnInfo, bool) {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return sqlite.ColumnInfo{}, false
+}
+
+// findCopy looks up the copy filling a column
+func findCopy(copies []sqlite.ColumnCopy, column string) (sqlite.ColumnCopy, bool) {
+	for _, c := range copies {
+		if c.Column == column {
+			return c, true
+		}
+	}
+	return sqlite.ColumnCopy{}, false
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/Athooh/social-network/pkg/db/sqlite"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM users WHERE id = ? AND email = ?", "SELECT * FROM users WHERE id = $1 AND email = $2"},
+		{"UPDATE posts SET content = '?' WHERE id = ?", "UPDATE posts SET content = '?' WHERE id = $1"},
+		{"SELECT 'it''s ?', \"odd?col\" FROM t WHERE a = ?", "SELECT 'it''s ?', \"odd?col\" FROM t WHERE a = $1"},
+		{"SELECT a -- why?\nFROM t WHERE b = ?", "SELECT a -- why?\nFROM t WHERE b = $1"},
+		{"SELECT /* ? */ a FROM t WHERE b = ?", "SELECT /* ? */ a FROM t WHERE b = $1"},
+		{"SELECT $$a ? b$$, $tag$?$tag$, ?", "SELECT $$a ? b$$, $tag$?$tag$, $1"},
+		{"SELECT 'unterminated ?", "SELECT 'unterminated ?"},
+	}
+
+	for _, tt := range tests {
+		if got := Rebind(tt.query); got != tt.want {
+			t.Errorf("Rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestInitialMigrationCreatesReferencedTablesFirst(t *testing.T) {
+	tables, err := sqlite.ModelTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := make(map[string]bool)
+	for _, table := range dependencyOrder(tables) {
+		for _, ref := range referencedTables(table) {
+			if ref != table.Name && !created[ref] {
+				t.Errorf("%s is created before %s, which it references", table.Name, ref)
+			}
+		}
+		created[table.Name] = true
+	}
+	if len(created) != len(tables) {
+		t.Errorf("dependency order has %d tables, want %d", len(created), len(tables))
+	}
+}
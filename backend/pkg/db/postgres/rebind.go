@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strconv"
+	"strings"
+
+	"github.com/Athooh/social-network/pkg/metrics"
+	"github.com/lib/pq"
+)
+
+// DriverName is the database/sql driver New opens connections with. It is lib/pq with
+// the ? placeholders the repositories use rewritten to PostgreSQL's $1, $2, ...
+const DriverName = "postgres-rebind"
+
+func init() {
+	sql.Register(DriverName, metrics.WrapDriver(rebindDriver{}))
+}
+
+// Rebind rewrites the ? placeholders of query as $1, $2, ... leaving string literals,
+// quoted identifiers, comments and dollar quoted strings alone
+func Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(query) + 16)
+
+	n := 0
+	for i := 0; i < len(query); {
+		end := i + 1
+		switch c := query[i]; {
+		case c == '?':
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			i++
+			continue
+		case c == '\'' || c == '"':
+			end = skipPast(query, i+1, string(c))
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end = skipPast(query, i+2, "\n")
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end = skipPast(query, i+2, "*/")
+		case c == '$':
+			if tag, ok := dollarQuoteTag(query[i:]); ok {
+				end = skipPast(query, i+len(tag), tag)
+			}
+		}
+		sb.WriteString(query[i:end])
+		i = end
+	}
+
+	return sb.String()
+}
+
+// skipPast returns the index just past the first closing delimiter at or after start,
+// or the end of query when it is never closed
+func skipPast(query string, start int, closing string) int {
+	idx := strings.Index(query[start:], closing)
+	if idx < 0 {
+		return len(query)
+	}
+	return start + idx + len(closing)
+}
+
+// dollarQuoteTag returns the opening tag of a dollar quoted string such as $$ or $body$.
+// $1 is a positional parameter, not a tag.
+func dollarQuoteTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// rebindDriver opens lib/pq connections that rebind every query they run
+type rebindDriver struct{}
+
+func (rebindDriver) Open(name string) (driver.Conn, error) {
+	c, err := pq.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &rebindConn{c}, nil
+}
+
+func (rebindDriver) OpenConnector(name string) (driver.Connector, error) {
+	c, err := pq.NewConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &rebindConnector{c}, nil
+}
+
+// rebindConnector wraps the connections of a lib/pq connector
+type rebindConnector struct {
+	driver.Connector
+}
+
+func (c *rebindConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rebindConn{conn}, nil
+}
+
+func (c *rebindConnector) Driver() driver.Driver {
+	return rebindDriver{}
+}
+
+// rebindConn rebinds queries before handing them to a lib/pq connection and forwards
+// the optional driver interfaces that connection implements
+type rebindConn struct {
+	driver.Conn
+}
+
+func (c *rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(Rebind(query))
+}
+
+func (c *rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, Rebind(query))
+	}
+	return c.Prepare(query)
+}
+
+func (c *rebindConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, Rebind(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, Rebind(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rebindConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *rebindConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *rebindConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *rebindConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Athooh/social-network/pkg/db/migration"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+// baselineVersion is the version databases stopped at when their migrations were still
+// generated from the models at startup. Those 20 migrations were numbered differently from
+// the committed ones and created the tables as they were back then.
+const baselineVersion = 20
+
+// baselineUpgrade turns the schema of a baseline database into the one committed
+// migrations 1 to 20 create, so the following committed migrations apply on top of it
+var baselineUpgrade = []string{
+	`ALTER TABLE posts ADD COLUMN is_edited BOOLEAN DEFAULT FALSE`,
+	`ALTER TABLE posts ADD COLUMN shared_post_id TEXT`,
+	`ALTER TABLE posts ADD COLUMN shares_count INTEGER DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_shared_post_id ON posts(shared_post_id)`,
+
+	`ALTER TABLE comments ADD COLUMN parent_id TEXT`,
+	`ALTER TABLE comments ADD COLUMN depth INTEGER DEFAULT 0`,
+	`ALTER TABLE comments ADD COLUMN is_edited BOOLEAN DEFAULT FALSE`,
+	`ALTER TABLE comments ADD COLUMN likes_count INTEGER DEFAULT 0`,
+	`ALTER TABLE comments ADD COLUMN replies_count INTEGER DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id)`,
+
+	`CREATE TABLE IF NOT EXISTS comment_likes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    comment_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`,
+	`CREATE INDEX IF NOT EXISTS idx_comment_likes_comment_id ON comment_likes(comment_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_comment_likes_user_id ON comment_likes(user_id)`,
+
+	// Likes from before reactions existed become "like" reactions
+	`ALTER TABLE post_likes ADD COLUMN reaction_type TEXT NOT NULL DEFAULT 'like'`,
+	`UPDATE post_likes SET reaction_type = 'like' WHERE reaction_type IS NULL OR reaction_type = ''`,
+	`CREATE INDEX IF NOT EXISTS idx_post_likes_reaction_type ON post_likes(reaction_type)`,
+
+	`ALTER TABLE group_posts ADD COLUMN is_edited BOOLEAN DEFAULT FALSE`,
+
+	`ALTER TABLE notifications ADD COLUMN target_post_id TEXT`,
+}
+
+// upgradeBaseline upgrades a baseline database before pending migrations run. Databases
+// created from the committed migrations already have posts.is_edited and are left alone.
+func upgradeBaseline(db *sql.DB, status migration.Status) error {
+	if status.Version != baselineVersion || status.Dirty {
+		return nil
+	}
+
+	edited, err := hasColumn(db, "posts", "is_edited")
+	if err != nil || edited {
+		return err
+	}
+
+	logger.Info("Database was created before migrations were committed, upgrading its schema")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range baselineUpgrade {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to upgrade baseline schema: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// hasColumn reports whether table has the named column
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			typ       string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+func TestApplyMigrationsUpgradesBaseline(t *testing.T) {
+	logger.Init(logger.Config{OutputType: logger.ConsoleOutput, ConsoleOutput: io.Discard})
+
+	db, err := New(Config{
+		DBPath:         filepath.Join(t.TempDir(), "baseline.db"),
+		MigrationsPath: filepath.Join("..", "migrations", "sqlite"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	schema, err := os.ReadFile(filepath.Join("testdata", "baseline.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	execMigration(t, db, string(schema))
+	execMigration(t, db, `
+		INSERT INTO users (id, email, password, first_name, last_name, date_of_birth) VALUES
+			('alice', 'alice@example.com', 'x', 'Alice', 'A', '1990-01-01'),
+			('bob', 'bob@example.com', 'x', 'Bob', 'B', '1990-01-01');
+		INSERT INTO posts (id, user_id, content, privacy, likes_count, comments_count, updated_at) VALUES
+			(1, 'alice', 'hello', 'public', 1, 1, CURRENT_TIMESTAMP);
+		INSERT INTO comments (post_id, user_id, content, updated_at) VALUES
+			(1, 'bob', 'hi', CURRENT_TIMESTAMP);
+		INSERT INTO post_likes (post_id, user_id) VALUES (1, 'bob');
+	`)
+
+	if err := ApplyMigrations(db); err != nil {
+		t.Fatalf("failed to migrate a baseline database: %v", err)
+	}
+
+	migrator, err := NewMigrator(db.DB, db.config.MigrationsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Dirty || len(status.Pending()) > 0 {
+		t.Fatalf("got version %d dirty %v with %d pending migrations, want every migration applied", status.Version, status.Dirty, len(status.Pending()))
+	}
+
+	var content string
+	var edited bool
+	var shares int
+	err = db.QueryRow("SELECT content, is_edited, shares_count FROM posts WHERE id = 1").Scan(&content, &edited, &shares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "hello" || edited || shares != 0 {
+		t.Errorf("got post %q edited %v shares %d, want hello, not edited, no shares", content, edited, shares)
+	}
+
+	var comments, likes int
+	if err := db.QueryRow("SELECT COUNT(*) FROM comments WHERE post_id = 1 AND depth = 0").Scan(&comments); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM post_likes WHERE post_id = 1 AND reaction_type = 'like'").Scan(&likes); err != nil {
+		t.Fatal(err)
+	}
+	if comments != 1 || likes != 1 {
+		t.Errorf("got %d comments and %d like reactions on the post, want 1 and 1", comments, likes)
+	}
+
+	planned, err := db.PlanMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range planned {
+		t.Errorf("upgraded schema differs from the models: %s", migration.Name)
+	}
+}
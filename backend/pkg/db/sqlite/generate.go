@@ -0,0 +1,386 @@
+package sqlite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Migration is a generated migration that has not been written to disk yet
+type Migration struct {
+	Name string // e.g. create_users_table
+	Up   string
+	Down string
+
+	// The table schema before and after the migration, From is nil for a new table.
+	// Other dialects render their own SQL from them.
+	From *TableInfo
+	To   TableInfo
+}
+
+// PlanMigrations compares the registered model structs with the database schema and
+// returns the migrations needed to bring the database in line with the structs
+func (db *DB) PlanMigrations() ([]Migration, error) {
+	tables, err := ModelTables()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, newTableInfo := range tables {
+		// Check if table exists in database
+		var count int
+		err = db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type='table' AND name=?", newTableInfo.Name).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if table %s exists: %w", newTableInfo.Name, err)
+		}
+
+		if count == 0 {
+			migrations = append(migrations, createTableMigration(newTableInfo))
+			continue
+		}
+
+		// Get current table schema from database
+		currentTableInfo, err := db.getTableInfoFromDB(newTableInfo.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get table info for %s from database: %w", newTableInfo.Name, err)
+		}
+
+		if !schemasEqual(currentTableInfo, newTableInfo) {
+			migrations = append(migrations, schemaUpdateMigration(currentTableInfo, newTableInfo))
+		}
+	}
+
+	return migrations, nil
+}
+
+// DiffMigrations applies the migrations in migrationsPath to a scratch database and plans
+// the migrations still needed on top of them. Nothing is written to migrationsPath.
+func DiffMigrations(migrationsPath string) ([]Migration, error) {
+	dir, err := os.MkdirTemp("", "social-network-migrate-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	scratch, err := New(Config{
+		DBPath:         filepath.Join(dir, "scratch.db"),
+		MigrationsPath: migrationsPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scratch database: %w", err)
+	}
+	defer scratch.Close()
+
+	hasMigrations, err := hasMigrationFiles(migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasMigrations {
+		migrator, err := NewMigrator(scratch.DB, migrationsPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := migrator.Up(); err != nil {
+			return nil, fmt.Errorf("failed to apply committed migrations to scratch database: %w", err)
+		}
+	}
+
+	return scratch.PlanMigrations()
+}
+
+// WriteMigrations writes migrations to migrationsPath using the next free sequence
+// numbers and returns the names of the files written
+func WriteMigrations(migrationsPath string, migrations []Migration) ([]string, error) {
+	if err := os.MkdirAll(migrationsPath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+
+	seq, err := getNextMigrationSequence(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next migration sequence: %w", err)
+	}
+
+	var written []string
+	for _, migration := range migrations {
+		upFileName := fmt.Sprintf("%06d_%s.up.sql", seq, migration.Name)
+		downFileName := fmt.Sprintf("%06d_%s.down.sql", seq, migration.Name)
+
+		upFilePath := filepath.Join(migrationsPath, upFileName)
+		if err := os.WriteFile(upFilePath, []byte(migration.Up), 0o644); err != nil {
+			return written, fmt.Errorf("failed to write up migration file: %w", err)
+		}
+		written = append(written, upFileName)
+
+		downFilePath := filepath.Join(migrationsPath, downFileName)
+		if err := os.WriteFile(downFilePath, []byte(migration.Down), 0o644); err != nil {
+			return written, fmt.Errorf("failed to write down migration file: %w", err)
+		}
+		written = append(written, downFileName)
+
+		seq++
+	}
+
+	return written, nil
+}
+
+// hasMigrationFiles checks if migrationsPath contains any up migrations
+func hasMigrationFiles(migrationsPath string) (bool, error) {
+	files, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".up.sql") {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// getNextMigrationSequence determines the next sequence number for migrations
+func getNextMigrationSequence(migrationsPath string) (int, error) {
+	files, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 1, nil
+		}
+		return 0, err
+	}
+
+	maxSeq := 0
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		name := file.Name()
+		if len(name) < 6 {
+			continue
+		}
+
+		var seq int
+		_, err := fmt.Sscanf(name, "%d_", &seq)
+		if err == nil && seq > maxSeq {
+			maxSeq = seq
+		}
+	}
+
+	return maxSeq + 1, nil
+}
+
+// createTableMigration generates the migration creating a new table
+func createTableMigration(table TableInfo) Migration {
+	return Migration{
+		Name: fmt.Sprintf("create_%s_table", table.Name),
+		Up:   "BEGIN;\n\n" + generateCreateTableSQL(table) + "\nCOMMIT;\n",
+		Down: fmt.Sprintf("DROP TABLE IF EXISTS %s;", table.Name),
+		To:   table,
+	}
+}
+
+// schemaUpdateMigration generates the migration rebuilding a table whose schema changed.
+// SQLite can't alter most column properties so the table is recreated and the data copied
+// across. Columns keep their data when they change type or are renamed with a
+// renamed_from tag; columns only in the old schema are dropped.
+func schemaUpdateMigration(currentSchema, newSchema TableInfo) Migration {
+	tableName := newSchema.Name
+
+	var upSQL strings.Builder
+	upSQL.WriteString(fmt.Sprintf("-- Migration to update %s table schema\n\n", tableName))
+	writeRebuildTable(&upSQL, currentSchema, newSchema, UpColumnCopies(currentSchema, newSchema),
+		"Create new table with updated schema",
+		"Copy data from old table to new table",
+		"Drop old table and rename new table")
+
+	var downSQL strings.Builder
+	downSQL.WriteString(fmt.Sprintf("-- Revert migration for %s table\n\n", tableName))
+	writeRebuildTable(&downSQL, newSchema, currentSchema, DownColumnCopies(currentSchema, newSchema),
+		"Create table with original schema",
+		"Copy data back (best effort)",
+		"Drop new table and rename temp table")
+
+	return Migration{
+		Name: fmt.Sprintf("update_%s_schema", tableName),
+		Up:   upSQL.String(),
+		Down: downSQL.String(),
+		From: &currentSchema,
+		To:   newSchema,
+	}
+}
+
+// ColumnCopy is a column of a rebuilt table and the expression filling it from the old table
+type ColumnCopy struct {
+	Column string // column of the rebuilt table
+	Source string // column of the old table the data comes from
+	Expr   string // expression over the old row, usually just Source
+}
+
+// UpColumnCopies maps the columns of newSchema to their data in currentSchema. Columns
+// with no counterpart are left out so they get their default value.
+func UpColumnCopies(currentSchema, newSchema TableInfo) []ColumnCopy {
+	var copies []ColumnCopy
+	for _, col := range newSchema.Columns {
+		source, ok := findColumn(currentSchema, col.Name)
+		renamed := false
+		if !ok && col.RenamedFrom != "" {
+			source, ok = findColumn(currentSchema, col.RenamedFrom)
+			renamed = ok
+		}
+		if !ok {
+			continue
+		}
+
+		// Conversions only apply while the column actually changes, otherwise a later
+		// rebuild of the table would convert the data a second time
+		expr := source.Name
+		if col.Convert != "" && (renamed || !strings.EqualFold(source.Type, col.Type)) {
+			expr = col.Convert
+		}
+		copies = append(copies, ColumnCopy{Column: col.Name, Source: source.Name, Expr: expr})
+	}
+	return copies
+}
+
+// DownColumnCopies maps the columns of currentSchema back from newSchema, reversing renames
+func DownColumnCopies(currentSchema, newSchema TableInfo) []ColumnCopy {
+	var copies []ColumnCopy
+	for _, col := range currentSchema.Columns {
+		var source ColumnInfo
+		ok, renamed := false, false
+
+		for _, newCol := range newSchema.Columns {
+			if newCol.RenamedFrom == col.Name {
+				if _, existed := findColumn(currentSchema, newCol.Name); !existed {
+					source, ok, renamed = newCol, true, true
+					break
+				}
+			}
+		}
+		if !ok {
+			source, ok = findColumn(newSchema, col.Name)
+		}
+		if !ok {
+			continue
+		}
+
+		expr := source.Name
+		if source.Revert != "" && (renamed || !strings.EqualFold(source.Type, col.Type)) {
+			expr = source.Revert
+		}
+		copies = append(copies, ColumnCopy{Column: col.Name, Source: source.Name, Expr: expr})
+	}
+	return copies
+}
+
+// findColumn looks up a column of a table by name
+func findColumn(table TableInfo, name string) (ColumnInfo, bool) {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return ColumnInfo{}, false
+}
+
+// writeRebuildTable writes the statements replacing the table described by from with one
+// built from to, filled using copies
+func writeRebuildTable(sb *strings.Builder, from, to TableInfo, copies []ColumnCopy, createComment, copyComment, swapComment string) {
+	tableName := to.Name
+	tempTableName := tableName + "_new"
+
+	// Dropping the old table would run the ON DELETE actions of tables referencing it,
+	// so foreign keys are switched off, which only works outside a transaction
+	sb.WriteString("PRAGMA foreign_keys=off;\n\nBEGIN;\n\n")
+
+	sb.WriteString(fmt.Sprintf("-- %s\n", createComment))
+	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", tempTableName))
+	writeColumnDefinitions(sb, to)
+	sb.WriteString("\n);\n\n")
+
+	// Rows that break a newly added foreign key get what ON DELETE would have done to them
+	var conditions []string
+	columns := make([]string, 0, len(copies))
+	exprs := make([]string, 0, len(copies))
+	for _, c := range copies {
+		expr := c.Expr
+		col, _ := findColumn(to, c.Column)
+		if ref, ok := ParseReference(col.References); ok && !hasReference(from, c, col.References) {
+			exists := fmt.Sprintf("%s IN (SELECT %s FROM %s)", expr, ref.Column, ref.Table)
+			switch ref.OnDelete {
+			case "CASCADE":
+				conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s)", expr, exists))
+			case "SET NULL":
+				expr = fmt.Sprintf("CASE WHEN %s THEN %s END", exists, expr)
+			}
+		}
+		columns = append(columns, c.Column)
+		exprs = append(exprs, expr)
+	}
+
+	// Duplicates of a newly added unique constraint keep their first row
+	insert := "INSERT"
+	if addsUniqueConstraint(from, to) {
+		insert = "INSERT OR IGNORE"
+	}
+
+	sb.WriteString(fmt.Sprintf("-- %s\n", copyComment))
+	sb.WriteString(fmt.Sprintf("%s INTO %s (%s)\nSELECT %s FROM %s", insert, tempTableName,
+		strings.Join(columns, ", "), strings.Join(exprs, ", "), tableName))
+	if len(conditions) > 0 {
+		sb.WriteString("\nWHERE " + strings.Join(conditions, "\n  AND "))
+	}
+	sb.WriteString(";\n\n")
+
+	sb.WriteString(fmt.Sprintf("-- %s\n", swapComment))
+	sb.WriteString(fmt.Sprintf("DROP TABLE %s;\n", tableName))
+	sb.WriteString(fmt.Sprintf("ALTER TABLE %s RENAME TO %s;\n\n", tempTableName, tableName))
+
+	writeIndexes(sb, tableName, to.Indexes)
+
+	sb.WriteString("\nCOMMIT;\n\nPRAGMA foreign_keys=on;\n")
+}
+
+// hasReference checks if the column a copy reads from already had the given foreign key
+func hasReference(from TableInfo, c ColumnCopy, references string) bool {
+	source, ok := findColumn(from, c.Source)
+	return ok && normalizeReference(source.References) == normalizeReference(references)
+}
+
+// addsUniqueConstraint checks if to has a UNIQUE column or table constraint that from lacks
+func addsUniqueConstraint(from, to TableInfo) bool {
+	for _, col := range to.Columns {
+		if !col.Unique {
+			continue
+		}
+		if source, ok := findColumn(from, col.Name); !ok || !source.Unique {
+			return true
+		}
+	}
+
+	for _, cols := range to.UniqueConstraints {
+		if !containsColumnSet(from.UniqueConstraints, cols) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsColumnSet checks if sets holds a column list equal to cols
+func containsColumnSet(sets [][]string, cols []string) bool {
+	key := strings.Join(cols, ",")
+	for _, set := range sets {
+		if strings.Join(set, ",") == key {
+			return true
+		}
+	}
+	return false
+}
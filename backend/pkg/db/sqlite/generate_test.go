@@ -0,0 +1,295 @@
+package sqlite
+
+import (
+	"database/sql"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// widgetV1 and widgetV2 are two versions of the same table used to test schema updates
+type widgetV1 struct {
+	ID       int64  `db:"id,pk"`
+	OwnerID  string `db:"owner_id,notnull" index:"name=idx_widgets_owner_id"`
+	Title    string `db:"title,notnull"`
+	Price    string `db:"price"`
+	Quantity string `db:"quantity"`
+	Legacy   string `db:"legacy"`
+}
+
+type widgetV2 struct {
+	ID         int64          `db:"id,pk"`
+	OwnerID    string         `db:"owner_id,notnull" index:"name=idx_widgets_owner_id" references:"owners(id) ON DELETE CASCADE"`
+	Name       string         `db:"name,notnull" renamed_from:"title"`
+	PriceCents int64          `db:"price_cents" renamed_from:"price" convert:"CAST(ROUND(price * 100) AS INTEGER)" revert:"printf('%.2f', price_cents / 100.0)"`
+	Quantity   int64          `db:"quantity"`
+	CategoryID sql.NullString `db:"category_id" references:"categories(id) ON DELETE SET NULL"`
+
+	_ struct{} `db:"unique:owner_id,name"`
+}
+
+// membership has a composite primary key
+type membership struct {
+	WidgetID int64  `db:"widget_id,notnull"`
+	UserID   string `db:"user_id,notnull"`
+	Role     string `db:"role,default='member'"`
+
+	_ struct{} `db:"pk(widget_id,user_id)"`
+}
+
+// checkGolden compares got with the golden file testdata/name, rewriting it with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run go test -update to create it: %v", err)
+	}
+	if string(want) != got {
+		t.Errorf("generated SQL does not match %s\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+// migrationSQL lays out a migration the way golden files store it
+func migrationSQL(m Migration) string {
+	return "-- up\n" + m.Up + "\n-- down\n" + m.Down + "\n"
+}
+
+// tableAs extracts the table info of model under a different table name
+func tableAs(t *testing.T, model interface{}, name string) TableInfo {
+	t.Helper()
+
+	table, err := extractTableInfoFromStruct(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.Name = name
+	return table
+}
+
+// openTestDB opens an empty database in a temporary directory
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	db, err := New(Config{DBPath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// execMigration runs migration SQL the way the migrator does, on one connection
+// without wrapping it in a transaction
+func execMigration(t *testing.T, db *DB, query string) {
+	t.Helper()
+
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("migration failed: %v\n%s", err, query)
+	}
+}
+
+// foreignKeyViolations counts the rows violating a foreign key
+func foreignKeyViolations(t *testing.T, db *DB) int {
+	t.Helper()
+
+	rows, err := db.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	return count
+}
+
+func TestCreateTableMigrationsGolden(t *testing.T) {
+	for _, model := range DiscoverModelStructs() {
+		table, err := extractTableInfoFromStruct(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run(table.Name, func(t *testing.T) {
+			checkGolden(t, filepath.Join("models", table.Name+".sql"), migrationSQL(createTableMigration(table)))
+		})
+	}
+}
+
+func TestSchemaUpdateMigrationGolden(t *testing.T) {
+	current := tableAs(t, widgetV1{}, "widgets")
+	updated := tableAs(t, widgetV2{}, "widgets")
+
+	checkGolden(t, filepath.Join("scenarios", "widgets_update.sql"), migrationSQL(schemaUpdateMigration(current, updated)))
+	checkGolden(t, filepath.Join("scenarios", "memberships_create.sql"), migrationSQL(createTableMigration(tableAs(t, membership{}, "memberships"))))
+}
+
+func TestIntrospectionMatchesStructs(t *testing.T) {
+	db := openTestDB(t)
+
+	tables := []TableInfo{tableAs(t, widgetV2{}, "widgets"), tableAs(t, membership{}, "memberships")}
+	for _, model := range DiscoverModelStructs() {
+		table, err := extractTableInfoFromStruct(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, table)
+	}
+
+	for _, table := range tables {
+		if _, err := db.Exec(generateCreateTableSQL(table)); err != nil {
+			t.Fatalf("failed to create %s: %v", table.Name, err)
+		}
+
+		fromDB, err := db.getTableInfoFromDB(table.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !schemasEqual(fromDB, table) {
+			t.Errorf("schema of %s read from the database differs from its struct\nstruct: %+v\ndatabase: %+v", table.Name, table, fromDB)
+		}
+	}
+}
+
+func TestSchemaUpdateMigrationPreservesData(t *testing.T) {
+	db := openTestDB(t)
+
+	current := tableAs(t, widgetV1{}, "widgets")
+	updated := tableAs(t, widgetV2{}, "widgets")
+
+	execMigration(t, db, `
+		CREATE TABLE owners (id TEXT PRIMARY KEY);
+		CREATE TABLE categories (id TEXT PRIMARY KEY);
+		INSERT INTO owners (id) VALUES ('alice'), ('bob');
+	`+generateCreateTableSQL(current)+`
+		INSERT INTO widgets (id, owner_id, title, price, quantity, legacy) VALUES
+			(1, 'alice', 'Lamp', '12.50', '3', 'x'),
+			(2, 'bob', 'Desk', '99.99', '1', 'y'),
+			(3, 'carol', 'Chair', '5', '2', 'z'),
+			(4, 'alice', 'Lamp', '13.00', '7', 'w');
+	`)
+
+	migration := schemaUpdateMigration(current, updated)
+	execMigration(t, db, migration.Up)
+
+	if n := foreignKeyViolations(t, db); n > 0 {
+		t.Errorf("%d rows violate foreign keys after the up migration", n)
+	}
+
+	fromDB, err := db.getTableInfoFromDB("widgets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schemasEqual(fromDB, updated) {
+		t.Fatalf("schema after the up migration differs from widgetV2: %+v", fromDB)
+	}
+
+	// carol doesn't exist so her widget goes as ON DELETE CASCADE would have removed it,
+	// and the second Lamp of alice breaks the new unique constraint
+	type widget struct {
+		id         int64
+		name       string
+		priceCents int64
+		quantity   interface{}
+	}
+	want := []widget{{1, "Lamp", 1250, int64(3)}, {2, "Desk", 9999, int64(1)}}
+
+	rows, err := db.Query("SELECT id, name, price_cents, quantity FROM widgets ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []widget
+	for rows.Next() {
+		var w widget
+		if err := rows.Scan(&w.id, &w.name, &w.priceCents, &w.quantity); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, w)
+	}
+	rows.Close()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d widgets after the up migration, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("widget %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	execMigration(t, db, migration.Down)
+
+	fromDB, err = db.getTableInfoFromDB("widgets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schemasEqual(fromDB, current) {
+		t.Fatalf("schema after the down migration differs from widgetV1: %+v", fromDB)
+	}
+
+	var title, price string
+	if err := db.QueryRow("SELECT title, price FROM widgets WHERE id = 1").Scan(&title, &price); err != nil {
+		t.Fatal(err)
+	}
+	if title != "Lamp" || price != "12.50" {
+		t.Errorf("after the down migration got title %q and price %q, want Lamp and 12.50", title, price)
+	}
+}
+
+func TestSchemaUpdateKeepsParentRows(t *testing.T) {
+	db := openTestDB(t)
+
+	// Rebuilding a table other tables reference must not cascade into them
+	execMigration(t, db, `
+		CREATE TABLE owners (id TEXT PRIMARY KEY, name TEXT);
+		CREATE TABLE pets (id INTEGER PRIMARY KEY, owner_id TEXT REFERENCES owners(id) ON DELETE CASCADE);
+		INSERT INTO owners (id, name) VALUES ('alice', 'Alice');
+		INSERT INTO pets (id, owner_id) VALUES (1, 'alice');
+	`)
+
+	current, err := db.getTableInfoFromDB("owners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := current
+	updated.Columns = append([]ColumnInfo{}, current.Columns...)
+	updated.Columns = append(updated.Columns, ColumnInfo{Name: "nickname", Type: "TEXT"})
+
+	execMigration(t, db, schemaUpdateMigration(current, updated).Up)
+
+	var pets int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pets").Scan(&pets); err != nil {
+		t.Fatal(err)
+	}
+	if pets != 1 {
+		t.Errorf("got %d pets after rebuilding owners, want 1", pets)
+	}
+}
+
+func TestCommittedMigrationsMatchModels(t *testing.T) {
+	migrations, err := DiffMigrations(filepath.Join("..", "migrations", "sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, migration := range migrations {
+		t.Errorf("model change has no committed migration, run go run ./cmd/migrate generate: %s", migration.Name)
+	}
+}
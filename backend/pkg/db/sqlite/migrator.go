@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Athooh/social-network/pkg/db/migration"
+	"github.com/Athooh/social-network/pkg/logger"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+)
+
+// NewMigrator creates a migrator for the SQLite migration files in migrationsPath
+func NewMigrator(db *sql.DB, migrationsPath string) (*migration.Migrator, error) {
+	// Migration files manage their own transactions so that table rebuilds can switch
+	// foreign keys off, which SQLite ignores inside a transaction
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{NoTxWrap: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration driver: %w", err)
+	}
+
+	return migration.New(migrationsPath, "sqlite3", driver, func(status migration.Status) error {
+		return upgradeBaseline(db, status)
+	}, func() { checkForeignKeys(db) })
+}
+
+// ApplyMigrations applies the committed migrations that are still pending. It refuses to
+// touch a database that is dirty or at a version it has no migration file for.
+func ApplyMigrations(db *DB) error {
+	migrator, err := NewMigrator(db.DB, db.config.MigrationsPath)
+	if err != nil {
+		return err
+	}
+
+	if err := migrator.Apply(); err != nil {
+		return err
+	}
+
+	// Warn about model changes that nobody has generated a migration for yet
+	planned, err := db.PlanMigrations()
+	if err != nil {
+		logger.Warn("Failed to compare models with the database schema: %v", err)
+	} else if len(planned) > 0 {
+		logger.Warn("%d model change(s) have no migration, run `go run ./cmd/migrate diff` to review them", len(planned))
+	}
+
+	return nil
+}
+
+// checkForeignKeys warns about rows left violating a foreign key, which table rebuilds
+// can't catch since they run with foreign keys switched off
+func checkForeignKeys(db *sql.DB) {
+	rows, err := db.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		logger.Warn("Failed to check foreign keys after migrating: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	violations := make(map[string]int)
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			logger.Warn("Failed to check foreign keys after migrating: %v", err)
+			return
+		}
+		violations[fmt.Sprintf("%s -> %s", table, parent)]++
+	}
+
+	for reference, count := range violations {
+		logger.Warn("%d row(s) in %s violate a foreign key after migrating", count, reference)
+	}
+}
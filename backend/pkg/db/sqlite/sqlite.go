@@ -2,21 +2,28 @@ package sqlite
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/Athooh/social-network/pkg/logger"
+	"github.com/Athooh/social-network/pkg/metrics"
 	models "github.com/Athooh/social-network/pkg/models/dbTables"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/mattn/go-sqlite3"
 )
 
+// DriverName is the database/sql driver New opens connections with, go-sqlite3 with
+// its statements timed for the metrics endpoint
+const DriverName = "sqlite3-metrics"
+
+func init() {
+	sql.Register(DriverName, metrics.WrapDriver(&sqlite3.SQLiteDriver{}))
+}
+
 // DB represents the database connection
 type DB struct {
 	*sql.DB
@@ -42,7 +49,7 @@ func New(config Config) (*DB, error) {
 	)
 
 	// Open the database connection
-	db, err := sql.Open("sqlite3", dsn)
+	db, err := sql.Open(DriverName, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -60,44 +67,6 @@ func New(config Config) (*DB, error) {
 	return &DB{db, config}, nil
 }
 
-// runMigrations runs the database migrations
-func runMigrations(db *sql.DB, migrationsPath string) error {
-	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
-	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %w", err)
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", migrationsPath),
-		"sqlite3",
-		driver,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
-	}
-
-	// Check if database is dirty
-	version, dirty, err := m.Version()
-	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
-		return fmt.Errorf("failed to get migration version: %w", err)
-	}
-
-	// If database is dirty, force the version
-	if dirty {
-		logger.Warn("Database is in dirty state at version %d, forcing version", version)
-		if err := m.Force(int(version)); err != nil {
-			return fmt.Errorf("failed to force version: %w", err)
-		}
-	}
-
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	logger.Info("Database migrations applied successfully")
-	return nil
-}
-
 // Close closes the database connection
 func (db *DB) Close() error {
 	return db.DB.Close()
@@ -109,6 +78,7 @@ type TableInfo struct {
 	Columns             []ColumnInfo
 	Indexes             []IndexInfo
 	CompositePrimaryKey []string
+	UniqueConstraints   [][]string // composite UNIQUE table constraints
 }
 
 // ColumnInfo holds information about a table column
@@ -119,7 +89,12 @@ type ColumnInfo struct {
 	NotNull    bool
 	Unique     bool
 	Default    string
-	References string
+	References string // e.g. users(id) ON DELETE CASCADE
+
+	// Migration hints, only set on columns read from structs
+	RenamedFrom string // previous column name, its data is kept when the column is renamed
+	Convert     string // SQL expression over the old row used when the column is renamed or changes type
+	Revert      string // SQL expression over the new row used by the down migration
 }
 
 // IndexInfo holds information about a table index
@@ -137,184 +112,58 @@ func DiscoverModelStructs() []interface{} {
 		models.User{},
 		models.Session{},
 		models.Post{},
+		models.PostKey{},
 		models.PostViewer{},
+		models.AudienceList{},
+		models.AudienceListMember{},
 		models.Comment{},
 		models.FollowRequest{},
 		models.Follower{},
+		models.UserBlock{},
+		models.UserMute{},
 		models.UserStat{},
 		models.PostLike{},
+		models.CommentLike{},
 		models.UserStatus{},
 		models.Group{},
 		models.GroupMember{},
+		models.GroupRole{},
+		models.GroupBan{},
+		models.GroupMute{},
+		models.GroupAuditLog{},
+		models.GroupInviteLink{},
+		models.GroupJoinQuestion{},
+		models.GroupJoinAnswer{},
 		models.GroupChatMessage{},
 		models.GroupPost{},
 		models.GroupEvent{},
 		models.EventResponse{},
+		models.CalendarFeedToken{},
 		models.PrivateMessage{},
 		models.ChatContact{},
 		models.Notification{},
 		models.UserProfile{},
+		models.PostRevision{},
+		models.PostTag{},
+		models.TrendingTopic{},
+		models.ScheduledJob{},
+		models.DataExport{},
+		models.AccountDeletion{},
 		// Add new models here
 	}
 }
 
-// CreateMigrationFromStruct generates migration files from a struct
-func (db *DB) CreateMigrationFromStruct(modelStruct interface{}, migrationName string) error {
-	// Check if migration already exists for this table
-	tableName := camelToSnake(reflect.TypeOf(modelStruct).Name())
-	if !strings.HasSuffix(tableName, "s") {
-		tableName += "s"
-	}
-	existingMigration, err := checkMigrationExists(db.config.MigrationsPath, tableName)
-	if err != nil {
-		return fmt.Errorf("failed to check existing migrations: %w", err)
-	}
-
-	if existingMigration {
-		logger.Info("Migration for table %s already exists, skipping", tableName)
-		return nil
-	}
-
-	// Get table info from struct
-	tableInfo, err := extractTableInfoFromStruct(modelStruct)
-	if err != nil {
-		return fmt.Errorf("failed to extract table info: %w", err)
-	}
-
-	// Create migrations directory if it doesn't exist
-	if err := os.MkdirAll(db.config.MigrationsPath, 0o755); err != nil {
-		return fmt.Errorf("failed to create migrations directory: %w", err)
-	}
-
-	// Generate migration sequence number
-	seq, err := getNextMigrationSequence(db.config.MigrationsPath)
-	if err != nil {
-		return fmt.Errorf("failed to get next migration sequence: %w", err)
-	}
-
-	// Generate migration file names
-	upFileName := fmt.Sprintf("%06d_%s.up.sql", seq, migrationName)
-	downFileName := fmt.Sprintf("%06d_%s.down.sql", seq, migrationName)
-
-	// Generate SQL for up migration
-	upSQL := generateCreateTableSQL(tableInfo)
-
-	// Generate SQL for down migration
-	downSQL := fmt.Sprintf("DROP TABLE IF EXISTS %s;", tableInfo.Name)
-
-	// Write migration files
-	upFilePath := filepath.Join(db.config.MigrationsPath, upFileName)
-	if err := os.WriteFile(upFilePath, []byte(upSQL), 0o644); err != nil {
-		return fmt.Errorf("failed to write up migration file: %w", err)
-	}
-
-	downFilePath := filepath.Join(db.config.MigrationsPath, downFileName)
-	if err := os.WriteFile(downFilePath, []byte(downSQL), 0o644); err != nil {
-		return fmt.Errorf("failed to write down migration file: %w", err)
-	}
-
-	logger.Info("Created migration files: %s, %s", upFileName, downFileName)
-	return nil
-}
-
-// checkMigrationExists checks if a migration for the given table already exists
-func checkMigrationExists(migrationsPath string, tableName string) (bool, error) {
-	files, err := os.ReadDir(migrationsPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-		return false, err
-	}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		// Check if filename contains create_tablename_table
-		if strings.Contains(file.Name(), fmt.Sprintf("create_%s_table", tableName)) {
-			return true, nil
-		}
-	}
-
-	return false, nil
-}
-
-func CreateMigrations(db *DB) error {
-	// Discover all model structs
-	logger.Info("Discovering model structs...")
-	modelStructs := DiscoverModelStructs()
-
-	// Generate migrations for all models
-	logger.Info("Generating database migrations...")
-	for _, model := range modelStructs {
-		// Get the struct name
-		t := reflect.TypeOf(model)
-		if t.Kind() == reflect.Ptr {
-			t = t.Elem()
-		}
-		structName := t.Name()
-
-		// Generate migration name
-		tableName := camelToSnake(structName)
-		// Only add 's' if the name doesn't already end with 's'
-		if !strings.HasSuffix(tableName, "s") {
-			tableName += "s"
-		}
-		migrationName := fmt.Sprintf("create_%s_table", tableName)
-
-		// Create migration
-		if err := db.CreateMigrationFromStruct(model, migrationName); err != nil {
-			logger.Fatal("Failed to create migration for %s: %v", structName, err)
-			return err
-		}
-	}
-
-	// Check for schema updates
-	if err := db.UpdateMigrations(); err != nil {
-		logger.Fatal("Failed to update migrations: %v", err)
-		return err
-	}
-
-	// Run migrations
-	if err := runMigrations(db.DB, db.config.MigrationsPath); err != nil {
-		db.DB.Close()
-		return fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	return nil
-}
-
-// getNextMigrationSequence determines the next sequence number for migrations
-func getNextMigrationSequence(migrationsPath string) (int, error) {
-	files, err := os.ReadDir(migrationsPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return 1, nil
-		}
-		return 0, err
-	}
-
-	maxSeq := 0
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		name := file.Name()
-		if len(name) < 6 {
-			continue
-		}
-
-		var seq int
-		_, err := fmt.Sscanf(name, "%d_", &seq)
-		if err == nil && seq > maxSeq {
-			maxSeq = seq
+// ModelTables returns the table schema of every registered model struct
+func ModelTables() ([]TableInfo, error) {
+	var tables []TableInfo
+	for _, model := range DiscoverModelStructs() {
+		table, err := extractTableInfoFromStruct(model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract table info from %T: %w", model, err)
 		}
+		tables = append(tables, table)
 	}
-
-	return maxSeq + 1, nil
+	return tables, nil
 }
 
 // extractTableInfoFromStruct extracts table information from a struct
@@ -342,19 +191,33 @@ func extractTableInfoFromStruct(modelStruct interface{}) (TableInfo, error) {
 	// Check for composite primary keys
 	var compositePKColumns []string
 
-	// First pass to find composite primary key
+	// First pass to find table constraints
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 
-		// Check for composite primary key tag
 		dbTag := field.Tag.Get("db")
-		if dbTag != "" {
-			parts := strings.Split(dbTag, ",")
-			for _, part := range parts {
-				if strings.HasPrefix(part, "pk(") && strings.HasSuffix(part, ")") {
-					// Extract column names from pk(col1,col2)
-					pkCols := strings.TrimSuffix(strings.TrimPrefix(part, "pk("), ")")
-					compositePKColumns = strings.Split(pkCols, ",")
+		if dbTag == "" {
+			continue
+		}
+
+		// Composite unique constraint, declared as _ struct{} `db:"unique:col1,col2"`
+		if field.Name == "_" && strings.HasPrefix(dbTag, "unique:") {
+			var uniqueCols []string
+			for _, col := range strings.Split(strings.TrimPrefix(dbTag, "unique:"), ",") {
+				uniqueCols = append(uniqueCols, strings.TrimSpace(col))
+			}
+			tableInfo.UniqueConstraints = append(tableInfo.UniqueConstraints, uniqueCols)
+			continue
+		}
+
+		// Check for composite primary key tag
+		for _, part := range splitTagOptions(dbTag) {
+			if strings.HasPrefix(part, "pk(") && strings.HasSuffix(part, ")") {
+				// Extract column names from pk(col1,col2)
+				compositePKColumns = nil
+				pkCols := strings.TrimSuffix(strings.TrimPrefix(part, "pk("), ")")
+				for _, col := range strings.Split(pkCols, ",") {
+					compositePKColumns = append(compositePKColumns, strings.TrimSpace(col))
 				}
 			}
 		}
@@ -465,7 +328,7 @@ func extractColumnInfoFromField(field reflect.StructField) ColumnInfo {
 	}
 
 	if dbTag != "" {
-		parts := strings.Split(dbTag, ",")
+		parts := splitTagOptions(dbTag)
 		column.Name = parts[0]
 
 		// Process options
@@ -489,18 +352,116 @@ func extractColumnInfoFromField(field reflect.StructField) ColumnInfo {
 		column.Name = camelToSnake(field.Name)
 	}
 
+	// Foreign keys may also be declared in their own tag
+	if references := field.Tag.Get("references"); references != "" {
+		column.References = references
+	}
+	if column.References != "" {
+		column.References = normalizeReference(column.References)
+	}
+
+	column.RenamedFrom = field.Tag.Get("renamed_from")
+	column.Convert = field.Tag.Get("convert")
+	column.Revert = field.Tag.Get("revert")
+
 	// Map Go types to SQLite types
 	column.Type = mapGoTypeToSQLite(field.Type)
 
 	return column
 }
 
+// splitTagOptions splits a db tag on commas that are not inside parentheses,
+// so pk(col1,col2) stays a single option
+func splitTagOptions(tag string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range tag {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, tag[start:])
+}
+
+// referencePattern matches a foreign key reference such as users(id) ON DELETE CASCADE
+var referencePattern = regexp.MustCompile(`(?i)^\s*(\w+)\s*\(\s*(\w+)\s*\)((?:\s+ON\s+(?:DELETE|UPDATE)\s+(?:SET\s+NULL|SET\s+DEFAULT|CASCADE|RESTRICT|NO\s+ACTION))*)\s*$`)
+
+// referenceActionPattern matches a single ON DELETE or ON UPDATE action
+var referenceActionPattern = regexp.MustCompile(`(?i)ON\s+(DELETE|UPDATE)\s+(SET\s+NULL|SET\s+DEFAULT|CASCADE|RESTRICT|NO\s+ACTION)`)
+
+// Reference is a parsed foreign key reference
+type Reference struct {
+	Table    string
+	Column   string
+	OnDelete string
+	OnUpdate string
+}
+
+// ParseReference parses a reference such as users(id) ON DELETE CASCADE
+func ParseReference(ref string) (Reference, bool) {
+	match := referencePattern.FindStringSubmatch(ref)
+	if match == nil {
+		return Reference{}, false
+	}
+
+	parsed := Reference{Table: match[1], Column: match[2], OnDelete: "NO ACTION", OnUpdate: "NO ACTION"}
+	for _, action := range referenceActionPattern.FindAllStringSubmatch(match[3], -1) {
+		value := strings.Join(strings.Fields(strings.ToUpper(action[2])), " ")
+		if strings.EqualFold(action[1], "DELETE") {
+			parsed.OnDelete = value
+		} else {
+			parsed.OnUpdate = value
+		}
+	}
+
+	return parsed, true
+}
+
+// String formats the reference the way it is written in generated SQL
+func (r Reference) String() string {
+	s := fmt.Sprintf("%s(%s)", r.Table, r.Column)
+	if r.OnDelete != "NO ACTION" {
+		s += " ON DELETE " + r.OnDelete
+	}
+	if r.OnUpdate != "NO ACTION" {
+		s += " ON UPDATE " + r.OnUpdate
+	}
+	return s
+}
+
+// normalizeReference rewrites a reference in a canonical form so that references
+// read from structs and from the database can be compared
+func normalizeReference(ref string) string {
+	parsed, ok := ParseReference(ref)
+	if !ok {
+		return strings.TrimSpace(ref)
+	}
+	return parsed.String()
+}
+
 // generateCreateTableSQL generates SQL for creating a table
 func generateCreateTableSQL(table TableInfo) string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", table.Name))
+	writeColumnDefinitions(&sb, table)
+	sb.WriteString("\n);\n\n")
+
+	writeIndexes(&sb, table.Name, table.Indexes)
+
+	return sb.String()
+}
 
+// writeColumnDefinitions writes the column and composite primary key definitions of a table
+func writeColumnDefinitions(sb *strings.Builder, table TableInfo) {
 	// Add columns
 	for i, col := range table.Columns {
 		if i > 0 {
@@ -510,7 +471,7 @@ func generateCreateTableSQL(table TableInfo) string {
 		sb.WriteString(fmt.Sprintf("    %s %s", col.Name, col.Type))
 
 		if col.PrimaryKey {
-			if strings.ToUpper(col.Type) == "INTEGER" {
+			if strings.Contains(strings.ToUpper(col.Type), "INTEGER") {
 				sb.WriteString(" PRIMARY KEY AUTOINCREMENT")
 			} else {
 				sb.WriteString(" PRIMARY KEY")
@@ -537,19 +498,21 @@ func generateCreateTableSQL(table TableInfo) string {
 	// Add composite primary key if needed
 	if len(table.CompositePrimaryKey) > 0 {
 		sb.WriteString(",\n    PRIMARY KEY (")
-		for i, col := range table.CompositePrimaryKey {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString(col)
-		}
+		sb.WriteString(strings.Join(table.CompositePrimaryKey, ", "))
 		sb.WriteString(")")
 	}
 
-	sb.WriteString("\n);\n\n")
+	// Add composite unique constraints
+	for _, cols := range table.UniqueConstraints {
+		sb.WriteString(",\n    UNIQUE (")
+		sb.WriteString(strings.Join(cols, ", "))
+		sb.WriteString(")")
+	}
+}
 
-	// Add indexes
-	for _, idx := range table.Indexes {
+// writeIndexes writes the CREATE INDEX statements for a table
+func writeIndexes(sb *strings.Builder, tableName string, indexes []IndexInfo) {
+	for _, idx := range indexes {
 		uniqueStr := ""
 		if idx.Unique {
 			uniqueStr = "UNIQUE "
@@ -557,10 +520,8 @@ func generateCreateTableSQL(table TableInfo) string {
 
 		columns := strings.Join(idx.Columns, ", ")
 		sb.WriteString(fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s(%s);\n",
-			uniqueStr, idx.Name, table.Name, columns))
+			uniqueStr, idx.Name, tableName, columns))
 	}
-
-	return sb.String()
 }
 
 // mapGoTypeToSQLite maps Go types to SQLite types
@@ -576,10 +537,18 @@ func mapGoTypeToSQLite(t reflect.Type) string {
 	case reflect.String:
 		return "TEXT"
 	case reflect.Struct:
-		if t == reflect.TypeOf(time.Time{}) {
+		switch t {
+		case reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{}):
 			return "TIMESTAMP"
-		}
-		return "TEXT" // JSON serialization for other structs
+		case reflect.TypeOf(sql.NullInt64{}), reflect.TypeOf(sql.NullInt32{}),
+			reflect.TypeOf(sql.NullInt16{}), reflect.TypeOf(sql.NullByte{}):
+			return "INTEGER"
+		case reflect.TypeOf(sql.NullFloat64{}):
+			return "REAL"
+		case reflect.TypeOf(sql.NullBool{}):
+			return "BOOLEAN"
+		}
+		return "TEXT" // JSON serialization for other structs, sql.NullString included
 	case reflect.Slice:
 		if t.Elem().Kind() == reflect.Uint8 {
 			return "BLOB" // []byte
@@ -632,68 +601,6 @@ func (db *DB) AutoMigrate(modelStructs ...interface{}) error {
 	return nil
 }
 
-// UpdateMigrations checks for schema changes and creates migration files if needed
-func (db *DB) UpdateMigrations() error {
-	logger.Info("Checking for schema changes...")
-
-	// Discover all model structs
-	modelStructs := DiscoverModelStructs()
-
-	for _, model := range modelStructs {
-		if err := db.checkAndUpdateSchema(model); err != nil {
-			return fmt.Errorf("failed to update schema for %T: %w", model, err)
-		}
-	}
-
-	return nil
-}
-
-// checkAndUpdateSchema compares model struct with database table and creates migration if needed
-func (db *DB) checkAndUpdateSchema(modelStruct interface{}) error {
-	t := reflect.TypeOf(modelStruct)
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-
-	tableName := camelToSnake(t.Name())
-	// Only add 's' if the name doesn't already end with 's'
-	if !strings.HasSuffix(tableName, "s") {
-		tableName += "s"
-	}
-
-	// Extract current table info from struct
-	newTableInfo, err := extractTableInfoFromStruct(modelStruct)
-	if err != nil {
-		return fmt.Errorf("failed to extract table info from struct: %w", err)
-	}
-
-	// Check if table exists in database
-	var count int
-	err = db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type='table' AND name=?", tableName).Scan(&count)
-	if err != nil {
-		return fmt.Errorf("failed to check if table exists: %w", err)
-	}
-
-	// If table doesn't exist yet, no need to create update migration
-	if count == 0 {
-		return nil
-	}
-
-	// Get current table schema from database
-	currentTableInfo, err := db.getTableInfoFromDB(tableName)
-	if err != nil {
-		return fmt.Errorf("failed to get table info from database: %w", err)
-	}
-
-	// Compare schemas
-	if !schemasEqual(currentTableInfo, newTableInfo) {
-		// Generate migration for schema update
-		return db.createSchemaMigration(tableName, currentTableInfo, newTableInfo)
-	}
-
-	return nil
-}
-
 // getTableInfoFromDB extracts table information from the database
 func (db *DB) getTableInfoFromDB(tableName string) (TableInfo, error) {
 	tableInfo := TableInfo{
@@ -708,6 +615,7 @@ func (db *DB) getTableInfoFromDB(tableName string) (TableInfo, error) {
 	}
 	defer rows.Close()
 
+	pkColumns := make(map[int]string)
 	for rows.Next() {
 		var cid int
 		var name, typeName string
@@ -729,64 +637,142 @@ func (db *DB) getTableInfoFromDB(tableName string) (TableInfo, error) {
 			column.Default = fmt.Sprintf("%v", dfltValue)
 		}
 
+		if pk > 0 {
+			pkColumns[pk] = name
+		}
+
 		tableInfo.Columns = append(tableInfo.Columns, column)
 	}
+	if err := rows.Err(); err != nil {
+		return tableInfo, err
+	}
+
+	// pk is the column's position in the primary key, more than one means a composite key
+	if len(pkColumns) > 1 {
+		for i := 1; i <= len(pkColumns); i++ {
+			tableInfo.CompositePrimaryKey = append(tableInfo.CompositePrimaryKey, pkColumns[i])
+		}
+		for i := range tableInfo.Columns {
+			tableInfo.Columns[i].PrimaryKey = false
+		}
+	}
+
+	// Get foreign keys, only single column references can be declared in struct tags
+	query = fmt.Sprintf("PRAGMA foreign_key_list(%s)", tableName)
+	fkRows, err := db.Query(query)
+	if err != nil {
+		return tableInfo, err
+	}
+	defer fkRows.Close()
+
+	for fkRows.Next() {
+		var id, seq int
+		var table, from, onUpdate, onDelete, match string
+		var to sql.NullString
+
+		if err := fkRows.Scan(&id, &seq, &table, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return tableInfo, err
+		}
+
+		ref := Reference{Table: table, Column: to.String, OnDelete: onDelete, OnUpdate: onUpdate}
+		for i := range tableInfo.Columns {
+			if tableInfo.Columns[i].Name == from {
+				tableInfo.Columns[i].References = ref.String()
+			}
+		}
+	}
+	if err := fkRows.Err(); err != nil {
+		return tableInfo, err
+	}
 
 	// Get index information
 	query = fmt.Sprintf("PRAGMA index_list(%s)", tableName)
-	rows, err = db.Query(query)
+	indexRows, err := db.Query(query)
 	if err != nil {
 		return tableInfo, err
 	}
-	defer rows.Close()
+	defer indexRows.Close()
 
-	for rows.Next() {
+	type indexListEntry struct {
+		name   string
+		unique bool
+		origin string
+	}
+	var indexList []indexListEntry
+	for indexRows.Next() {
 		var seq int
 		var name string
 		var unique int
 		var origin string
 		var partial int
 
-		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+		if err := indexRows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
 			return tableInfo, err
 		}
+		indexList = append(indexList, indexListEntry{name: name, unique: unique == 1, origin: origin})
+	}
+	if err := indexRows.Err(); err != nil {
+		return tableInfo, err
+	}
 
-		// Skip sqlite_autoindex entries
-		if strings.HasPrefix(name, "sqlite_autoindex") {
+	for _, entry := range indexList {
+		// The primary key's index is described by the columns already
+		if entry.origin == "pk" {
 			continue
 		}
 
-		// Get columns for this index
-		var columns []string
-		indexInfoQuery := fmt.Sprintf("PRAGMA index_info(%s)", name)
-		indexRows, err := db.Query(indexInfoQuery)
+		columns, err := db.getIndexColumns(entry.name)
 		if err != nil {
 			return tableInfo, err
 		}
 
-		for indexRows.Next() {
-			var seqno, cid int
-			var colName string
-
-			if err := indexRows.Scan(&seqno, &cid, &colName); err != nil {
-				indexRows.Close()
-				return tableInfo, err
+		// UNIQUE column and table constraints are backed by automatic indexes
+		if entry.origin == "u" {
+			if len(columns) == 1 {
+				for i := range tableInfo.Columns {
+					if tableInfo.Columns[i].Name == columns[0] {
+						tableInfo.Columns[i].Unique = true
+					}
+				}
+			} else {
+				tableInfo.UniqueConstraints = append(tableInfo.UniqueConstraints, columns)
 			}
-
-			columns = append(columns, colName)
+			continue
 		}
-		indexRows.Close()
 
 		tableInfo.Indexes = append(tableInfo.Indexes, IndexInfo{
-			Name:    name,
+			Name:    entry.name,
 			Columns: columns,
-			Unique:  unique == 1,
+			Unique:  entry.unique,
 		})
 	}
 
 	return tableInfo, nil
 }
 
+// getIndexColumns returns the columns of an index in order
+func (db *DB) getIndexColumns(indexName string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA index_info(%s)", indexName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var seqno, cid int
+		var colName string
+
+		if err := rows.Scan(&seqno, &cid, &colName); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, colName)
+	}
+
+	return columns, rows.Err()
+}
+
 // schemasEqual compares two table schemas to check if they're equivalent
 func schemasEqual(current, new TableInfo) bool {
 	// Compare column count
@@ -808,252 +794,63 @@ func schemasEqual(current, new TableInfo) bool {
 		}
 
 		// Compare column properties
-		if currentCol.Type != newCol.Type ||
+		if !strings.EqualFold(currentCol.Type, newCol.Type) ||
 			currentCol.NotNull != newCol.NotNull ||
-			currentCol.PrimaryKey != newCol.PrimaryKey {
+			currentCol.PrimaryKey != newCol.PrimaryKey ||
+			currentCol.Unique != newCol.Unique ||
+			currentCol.Default != newCol.Default ||
+			normalizeReference(currentCol.References) != normalizeReference(newCol.References) {
 			return false
 		}
 	}
 
-	// Compare indexes (simplified)
-	if len(current.Indexes) != len(new.Indexes) {
+	// Compare table constraints
+	if strings.Join(current.CompositePrimaryKey, ",") != strings.Join(new.CompositePrimaryKey, ",") {
 		return false
 	}
-
-	return true
-}
-
-// createSchemaMigration creates migration files for schema updates
-func (db *DB) createSchemaMigration(tableName string, currentSchema, newSchema TableInfo) error {
-	// Generate migration sequence number
-	seq, err := getNextMigrationSequence(db.config.MigrationsPath)
-	if err != nil {
-		return fmt.Errorf("failed to get next migration sequence: %w", err)
-	}
-
-	migrationName := fmt.Sprintf("update_%s_schema", tableName)
-
-	// Generate migration file names
-	upFileName := fmt.Sprintf("%06d_%s.up.sql", seq, migrationName)
-	downFileName := fmt.Sprintf("%06d_%s.down.sql", seq, migrationName)
-
-	// Generate SQL for up migration (create temp table, copy data, drop old, rename new)
-	var upSQL strings.Builder
-	upSQL.WriteString(fmt.Sprintf("-- Migration to update %s table schema\n\n", tableName))
-	upSQL.WriteString("PRAGMA foreign_keys=off;\n\n")
-
-	// Create new table with _new suffix
-	tempTableName := tableName + "_new"
-	upSQL.WriteString(fmt.Sprintf("-- Create new table with updated schema\n"))
-	upSQL.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", tempTableName))
-
-	// Add columns
-	for i, col := range newSchema.Columns {
-		if i > 0 {
-			upSQL.WriteString(",\n")
-		}
-
-		upSQL.WriteString(fmt.Sprintf("    %s %s", col.Name, col.Type))
-
-		if col.PrimaryKey {
-			if strings.Contains(col.Type, "INTEGER") {
-				upSQL.WriteString(" PRIMARY KEY AUTOINCREMENT")
-			} else {
-				upSQL.WriteString(" PRIMARY KEY")
-			}
-		}
-
-		if col.NotNull {
-			upSQL.WriteString(" NOT NULL")
-		}
-
-		if col.Unique {
-			upSQL.WriteString(" UNIQUE")
-		}
-
-		if col.Default != "" {
-			upSQL.WriteString(fmt.Sprintf(" DEFAULT %s", col.Default))
-		}
-
-		if col.References != "" {
-			upSQL.WriteString(fmt.Sprintf(" REFERENCES %s", col.References))
-		}
-	}
-
-	// Add composite primary key if needed
-	if len(newSchema.CompositePrimaryKey) > 0 {
-		upSQL.WriteString(",\n    PRIMARY KEY (")
-		for i, col := range newSchema.CompositePrimaryKey {
-			if i > 0 {
-				upSQL.WriteString(", ")
-			}
-			upSQL.WriteString(col)
-		}
-		upSQL.WriteString(")")
-	}
-
-	upSQL.WriteString("\n);\n\n")
-
-	// Copy data from old table to new table
-	upSQL.WriteString("-- Copy data from old table to new table\n")
-	upSQL.WriteString(fmt.Sprintf("INSERT INTO %s (", tempTableName))
-
-	// Find common columns between old and new schemas
-	var commonColumns []string
-	for _, newCol := range newSchema.Columns {
-		for _, currentCol := range currentSchema.Columns {
-			if newCol.Name == currentCol.Name {
-				commonColumns = append(commonColumns, newCol.Name)
-				break
-			}
-		}
-	}
-
-	// Add column names
-	for i, col := range commonColumns {
-		if i > 0 {
-			upSQL.WriteString(", ")
-		}
-		upSQL.WriteString(col)
-	}
-
-	upSQL.WriteString(")\nSELECT ")
-
-	// Add column names again for SELECT
-	for i, col := range commonColumns {
-		if i > 0 {
-			upSQL.WriteString(", ")
-		}
-		upSQL.WriteString(col)
+	if !sameColumnSets(current.UniqueConstraints, new.UniqueConstraints) {
+		return false
 	}
 
-	upSQL.WriteString(fmt.Sprintf(" FROM %s;\n\n", tableName))
-
-	// Drop old table and rename new table
-	upSQL.WriteString(fmt.Sprintf("-- Drop old table and rename new table\n"))
-	upSQL.WriteString(fmt.Sprintf("DROP TABLE %s;\n", tableName))
-	upSQL.WriteString(fmt.Sprintf("ALTER TABLE %s RENAME TO %s;\n\n", tempTableName, tableName))
-
-	// Add indexes
-	for _, idx := range newSchema.Indexes {
-		uniqueStr := ""
-		if idx.Unique {
-			uniqueStr = "UNIQUE "
-		}
-
-		columns := strings.Join(idx.Columns, ", ")
-		upSQL.WriteString(fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s(%s);\n",
-			uniqueStr, idx.Name, tableName, columns))
+	// Compare indexes by name
+	if len(current.Indexes) != len(new.Indexes) {
+		return false
 	}
 
-	upSQL.WriteString("\nPRAGMA foreign_keys=on;\n")
-
-	// Generate SQL for down migration (restore original schema)
-	var downSQL strings.Builder
-	downSQL.WriteString(fmt.Sprintf("-- Revert migration for %s table\n\n", tableName))
-	downSQL.WriteString("PRAGMA foreign_keys=off;\n\n")
-
-	// Create temp table with original schema
-	downSQL.WriteString(fmt.Sprintf("-- Create table with original schema\n"))
-	downSQL.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", tempTableName))
-
-	// Add columns
-	for i, col := range currentSchema.Columns {
-		if i > 0 {
-			downSQL.WriteString(",\n")
-		}
-
-		downSQL.WriteString(fmt.Sprintf("    %s %s", col.Name, col.Type))
-
-		if col.PrimaryKey {
-			if strings.Contains(col.Type, "INTEGER") {
-				downSQL.WriteString(" PRIMARY KEY AUTOINCREMENT")
-			} else {
-				downSQL.WriteString(" PRIMARY KEY")
-			}
-		}
-
-		if col.NotNull {
-			downSQL.WriteString(" NOT NULL")
-		}
-
-		if col.Unique {
-			downSQL.WriteString(" UNIQUE")
-		}
-
-		if col.Default != "" {
-			downSQL.WriteString(fmt.Sprintf(" DEFAULT %s", col.Default))
-		}
+	currentIndexes := make(map[string]IndexInfo)
+	for _, idx := range current.Indexes {
+		currentIndexes[idx.Name] = idx
 	}
 
-	// Add composite primary key if needed
-	if len(currentSchema.CompositePrimaryKey) > 0 {
-		downSQL.WriteString(",\n    PRIMARY KEY (")
-		for i, col := range currentSchema.CompositePrimaryKey {
-			if i > 0 {
-				downSQL.WriteString(", ")
-			}
-			downSQL.WriteString(col)
+	for _, newIdx := range new.Indexes {
+		currentIdx, exists := currentIndexes[newIdx.Name]
+		if !exists ||
+			currentIdx.Unique != newIdx.Unique ||
+			strings.Join(currentIdx.Columns, ",") != strings.Join(newIdx.Columns, ",") {
+			return false
 		}
-		downSQL.WriteString(")")
 	}
 
-	downSQL.WriteString("\n);\n\n")
-
-	// Copy data back (best effort)
-	downSQL.WriteString("-- Copy data back (best effort)\n")
-	downSQL.WriteString(fmt.Sprintf("INSERT INTO %s (", tempTableName))
+	return true
+}
 
-	// Add column names
-	for i, col := range commonColumns {
-		if i > 0 {
-			downSQL.WriteString(", ")
-		}
-		downSQL.WriteString(col)
+// sameColumnSets checks if two lists of column lists hold the same lists in any order
+func sameColumnSets(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
 	}
 
-	downSQL.WriteString(")\nSELECT ")
-
-	// Add column names again for SELECT
-	for i, col := range commonColumns {
-		if i > 0 {
-			downSQL.WriteString(", ")
-		}
-		downSQL.WriteString(col)
+	counts := make(map[string]int)
+	for _, cols := range a {
+		counts[strings.Join(cols, ",")]++
 	}
-
-	downSQL.WriteString(fmt.Sprintf(" FROM %s;\n\n", tableName))
-
-	// Drop new table and rename temp table
-	downSQL.WriteString(fmt.Sprintf("-- Drop new table and rename temp table\n"))
-	downSQL.WriteString(fmt.Sprintf("DROP TABLE %s;\n", tableName))
-	downSQL.WriteString(fmt.Sprintf("ALTER TABLE %s RENAME TO %s;\n\n", tempTableName, tableName))
-
-	// Add original indexes
-	for _, idx := range currentSchema.Indexes {
-		uniqueStr := ""
-		if idx.Unique {
-			uniqueStr = "UNIQUE "
+	for _, cols := range b {
+		key := strings.Join(cols, ",")
+		if counts[key] == 0 {
+			return false
 		}
-
-		columns := strings.Join(idx.Columns, ", ")
-		downSQL.WriteString(fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s(%s);\n",
-			uniqueStr, idx.Name, tableName, columns))
-	}
-
-	downSQL.WriteString("\nPRAGMA foreign_keys=on;\n")
-
-	// Write migration files
-	upFilePath := filepath.Join(db.config.MigrationsPath, upFileName)
-	if err := os.WriteFile(upFilePath, []byte(upSQL.String()), 0o644); err != nil {
-		return fmt.Errorf("failed to write up migration file: %w", err)
-	}
-
-	downFilePath := filepath.Join(db.config.MigrationsPath, downFileName)
-	if err := os.WriteFile(downFilePath, []byte(downSQL.String()), 0o644); err != nil {
-		return fmt.Errorf("failed to write down migration file: %w", err)
+		counts[key]--
 	}
 
-	logger.Info("Created schema update migration files: %s, %s", upFileName, downFileName)
-	return nil
+	return true
 }
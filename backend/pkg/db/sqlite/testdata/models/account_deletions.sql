@@ -0,0 +1,15 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS account_deletions (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    requested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delete_at TIMESTAMP NOT NULL
+);
+
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS account_deletions;
@@ -0,0 +1,17 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audience_list_members (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    list_id INTEGER NOT NULL REFERENCES audience_lists(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (list_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audience_list_members_user_id ON audience_list_members(user_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS audience_list_members;
@@ -0,0 +1,17 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audience_lists (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (owner_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audience_lists_owner_id ON audience_lists(owner_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS audience_lists;
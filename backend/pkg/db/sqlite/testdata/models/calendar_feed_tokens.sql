@@ -0,0 +1,14 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+    user_id TEXT PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS calendar_feed_tokens;
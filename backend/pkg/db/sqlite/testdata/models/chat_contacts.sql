@@ -0,0 +1,20 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS chat_contacts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    avatar TEXT,
+    is_online BOOLEAN DEFAULT FALSE,
+    last_message TEXT,
+    last_sent TIMESTAMP,
+    unread_count INTEGER DEFAULT 0
+);
+
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS chat_contacts;
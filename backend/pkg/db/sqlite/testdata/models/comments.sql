@@ -0,0 +1,26 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    parent_id INTEGER,
+    depth INTEGER DEFAULT 0,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    is_edited BOOLEAN DEFAULT FALSE,
+    likes_count INTEGER DEFAULT 0,
+    replies_count INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments(user_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS comments;
@@ -0,0 +1,21 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS event_responses (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    response TEXT NOT NULL,
+    guests INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    waitlisted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_responses_event_id ON event_responses(event_id);
+CREATE INDEX IF NOT EXISTS idx_event_responses_user_id ON event_responses(user_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS event_responses;
@@ -0,0 +1,21 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    follower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    following_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (follower_id, following_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_requests_follower_id ON follow_requests(follower_id);
+CREATE INDEX IF NOT EXISTS idx_follow_requests_following_id ON follow_requests(following_id);
+CREATE INDEX IF NOT EXISTS idx_follow_requests_status ON follow_requests(status);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS follow_requests;
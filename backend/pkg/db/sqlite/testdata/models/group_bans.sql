@@ -0,0 +1,20 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS group_bans (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    banned_by TEXT NOT NULL,
+    reason TEXT,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_bans_group_id ON group_bans(group_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS group_bans;
@@ -0,0 +1,28 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS group_events (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    creator_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    event_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    location TEXT NOT NULL DEFAULT '',
+    capacity INTEGER DEFAULT 0,
+    guest_limit INTEGER NOT NULL DEFAULT 0,
+    recurrence_rule TEXT NOT NULL DEFAULT '',
+    recurrence_exdates TEXT NOT NULL DEFAULT '',
+    banner_path TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_events_group_id ON group_events(group_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS group_events;
@@ -0,0 +1,21 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS group_invite_links (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    created_by TEXT NOT NULL,
+    max_uses INTEGER DEFAULT 0,
+    uses INTEGER DEFAULT 0,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_invite_links_group_id ON group_invite_links(group_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS group_invite_links;
@@ -0,0 +1,19 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS group_roles (
+    id TEXT PRIMARY KEY,
+    group_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    permissions TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (group_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_roles_group_id ON group_roles(group_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS group_roles;
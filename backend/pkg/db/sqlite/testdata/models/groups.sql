@@ -0,0 +1,24 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS groups (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    creator_id TEXT NOT NULL,
+    banner_path TEXT,
+    profile_pic_path TEXT,
+    is_public BOOLEAN DEFAULT TRUE,
+    post_approval BOOLEAN DEFAULT FALSE,
+    new_member_approval_posts INTEGER DEFAULT 0,
+    auto_approve_rules TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_groups_creator_id ON groups(creator_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS groups;
@@ -0,0 +1,22 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sender_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    message TEXT NOT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    target_group_id TEXT REFERENCES groups(id) ON DELETE SET NULL,
+    target_event_id TEXT REFERENCES group_events(id) ON DELETE SET NULL,
+    target_post_id INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,13 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS post_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS post_keys;
@@ -0,0 +1,28 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS posts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    video_path TEXT,
+    privacy TEXT NOT NULL,
+    likes_count INTEGER DEFAULT 0,
+    comments_count INTEGER DEFAULT 0,
+    is_edited BOOLEAN DEFAULT FALSE,
+    shared_post_id INTEGER,
+    shares_count INTEGER DEFAULT 0,
+    audience_list_id INTEGER REFERENCES audience_lists(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts(user_id);
+CREATE INDEX IF NOT EXISTS idx_posts_shared_post_id ON posts(shared_post_id);
+CREATE INDEX IF NOT EXISTS idx_posts_audience_list_id ON posts(audience_list_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS posts;
@@ -0,0 +1,17 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_mutes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    muter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    muted_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (muter_id, muted_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_mutes_muter_id ON user_mutes(muter_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS user_mutes;
@@ -0,0 +1,19 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_stats (
+    user_id TEXT PRIMARY KEY,
+    posts_count INTEGER DEFAULT 0,
+    groups_joined INTEGER DEFAULT 0,
+    followers_count INTEGER DEFAULT 0,
+    following_count INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_stats_user_id ON user_stats(user_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS user_stats;
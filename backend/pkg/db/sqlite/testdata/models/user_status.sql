@@ -0,0 +1,16 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_status (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    is_online BOOLEAN DEFAULT FALSE,
+    last_activity TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_status_user_id ON user_status(user_id);
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS user_status;
@@ -0,0 +1,15 @@
+-- up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS memberships (
+    widget_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    role TEXT DEFAULT 'member',
+    PRIMARY KEY (widget_id, user_id)
+);
+
+
+COMMIT;
+
+-- down
+DROP TABLE IF EXISTS memberships;
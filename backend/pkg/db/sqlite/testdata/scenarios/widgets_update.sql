@@ -0,0 +1,64 @@
+-- up
+-- Migration to update widgets table schema
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create new table with updated schema
+CREATE TABLE widgets_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id TEXT NOT NULL REFERENCES owners(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    price_cents INTEGER,
+    quantity INTEGER,
+    category_id TEXT REFERENCES categories(id) ON DELETE SET NULL,
+    UNIQUE (owner_id, name)
+);
+
+-- Copy data from old table to new table
+INSERT OR IGNORE INTO widgets_new (id, owner_id, name, price_cents, quantity)
+SELECT id, owner_id, title, CAST(ROUND(price * 100) AS INTEGER), quantity FROM widgets
+WHERE (owner_id IS NULL OR owner_id IN (SELECT id FROM owners));
+
+-- Drop old table and rename new table
+DROP TABLE widgets;
+ALTER TABLE widgets_new RENAME TO widgets;
+
+CREATE INDEX IF NOT EXISTS idx_widgets_owner_id ON widgets(owner_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
+
+-- down
+-- Revert migration for widgets table
+
+PRAGMA foreign_keys=off;
+
+BEGIN;
+
+-- Create table with original schema
+CREATE TABLE widgets_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    price TEXT,
+    quantity TEXT,
+    legacy TEXT
+);
+
+-- Copy data back (best effort)
+INSERT INTO widgets_new (id, owner_id, title, price, quantity)
+SELECT id, owner_id, name, printf('%.2f', price_cents / 100.0), quantity FROM widgets;
+
+-- Drop new table and rename temp table
+DROP TABLE widgets;
+ALTER TABLE widgets_new RENAME TO widgets;
+
+CREATE INDEX IF NOT EXISTS idx_widgets_owner_id ON widgets(owner_id);
+
+COMMIT;
+
+PRAGMA foreign_keys=on;
+
@@ -0,0 +1,205 @@
+// Package uow lets a service run the methods of several repositories in one database
+// transaction, and defer side effects such as websocket broadcasts until it commits.
+//
+// Repositories run their queries on a *DB. Outside a unit of work it uses the connection
+// pool and Begin starts a real transaction. A repository bound to a unit of work with
+// WithTx runs everything in the unit's transaction, and its Begin calls become savepoints,
+// so repository methods that manage their own transactions still work unchanged.
+package uow
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrTxDone is returned when a transaction or savepoint is used after it was committed
+// or rolled back
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// UnitOfWork runs functions in a database transaction
+type UnitOfWork struct {
+	pool *sql.DB
+}
+
+// New creates a unit of work runner for the database
+func New(pool *sql.DB) *UnitOfWork {
+	return &UnitOfWork{pool: pool}
+}
+
+// Do runs fn in a transaction. The transaction is committed if fn returns nil and rolled
+// back otherwise, and the after-commit hooks fn registered run only once it is committed.
+func (u *UnitOfWork) Do(fn func(tx *Tx) error) (err error) {
+	tx, err := NewDB(u.pool).Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DB is the database handle repositories run their queries on
+type DB struct {
+	pool *sql.DB
+	tx   *Tx
+}
+
+// NewDB creates a handle that runs queries on the connection pool
+func NewDB(pool *sql.DB) *DB {
+	return &DB{pool: pool}
+}
+
+// WithTx returns a handle that runs its queries in tx
+func (db *DB) WithTx(tx *Tx) *DB {
+	return &DB{pool: db.pool, tx: tx}
+}
+
+// Exec executes a query without returning any rows
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if db.tx != nil {
+		return db.tx.Exec(query, args...)
+	}
+	return db.pool.Exec(query, args...)
+}
+
+// Query executes a query that returns rows
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if db.tx != nil {
+		return db.tx.Query(query, args...)
+	}
+	return db.pool.Query(query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	if db.tx != nil {
+		return db.tx.QueryRow(query, args...)
+	}
+	return db.pool.QueryRow(query, args...)
+}
+
+// Begin starts a transaction, or a savepoint in the transaction the handle is bound to
+func (db *DB) Begin() (*Tx, error) {
+	if db.tx == nil {
+		tx, err := db.pool.Begin()
+		if err != nil {
+			return nil, err
+		}
+		return &Tx{tx: tx}, nil
+	}
+
+	root := db.tx.root()
+	if root.done {
+		return nil, ErrTxDone
+	}
+
+	root.savepoints++
+	savepoint := fmt.Sprintf("sp_%d", root.savepoints)
+	if _, err := root.tx.Exec("SAVEPOINT " + savepoint); err != nil {
+		return nil, err
+	}
+
+	return &Tx{tx: root.tx, parent: root, savepoint: savepoint, hooksMark: len(root.hooks)}, nil
+}
+
+// Tx is a transaction, or a savepoint inside one
+type Tx struct {
+	tx   *sql.Tx
+	done bool
+
+	// Set on savepoints only
+	parent    *Tx
+	savepoint string
+	hooksMark int // hooks registered before the savepoint
+
+	// Set on transactions only
+	hooks      []func()
+	savepoints int
+}
+
+// root returns the transaction the savepoint belongs to
+func (tx *Tx) root() *Tx {
+	if tx.parent != nil {
+		return tx.parent
+	}
+	return tx
+}
+
+// Exec executes a query without returning any rows
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.tx.Exec(query, args...)
+}
+
+// Query executes a query that returns rows
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.tx.Query(query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.tx.QueryRow(query, args...)
+}
+
+// AfterCommit registers fn to run once the transaction commits. It never runs if the
+// transaction, or the savepoint fn was registered in, is rolled back. Hooks run after
+// the transaction is gone, so they must use repositories that aren't bound to it.
+func (tx *Tx) AfterCommit(fn func()) {
+	root := tx.root()
+	root.hooks = append(root.hooks, fn)
+}
+
+// Commit commits the transaction and runs its after-commit hooks, or releases the
+// savepoint
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	if tx.parent != nil {
+		_, err := tx.tx.Exec("RELEASE SAVEPOINT " + tx.savepoint)
+		return err
+	}
+
+	if err := tx.tx.Commit(); err != nil {
+		return err
+	}
+	for _, hook := range tx.hooks {
+		hook()
+	}
+	return nil
+}
+
+// Rollback rolls the transaction back, or undoes everything since the savepoint and
+// drops the hooks registered in it. Rolling back after Commit does nothing and returns
+// ErrTxDone, so it is safe to defer.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	if tx.parent == nil {
+		tx.hooks = nil
+		return tx.tx.Rollback()
+	}
+
+	tx.parent.hooks = tx.parent.hooks[:tx.hooksMark]
+	if _, err := tx.tx.Exec("ROLLBACK TO SAVEPOINT " + tx.savepoint); err != nil {
+		return err
+	}
+	_, err := tx.tx.Exec("RELEASE SAVEPOINT " + tx.savepoint)
+	return err
+}
@@ -0,0 +1,193 @@
+package uow
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE items (name TEXT NOT NULL UNIQUE)"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// itemRepository is a repository in the style of the domain packages, it manages its
+// own transaction when inserting several items
+type itemRepository struct {
+	db *DB
+}
+
+func (r *itemRepository) withTx(tx *Tx) *itemRepository {
+	return &itemRepository{db: r.db.WithTx(tx)}
+}
+
+func (r *itemRepository) addItems(names ...string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, name := range names {
+		if _, err := tx.Exec("INSERT INTO items (name) VALUES (?)", name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *itemRepository) count(t *testing.T) int {
+	t.Helper()
+
+	var n int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDoCommitsAndRunsHooks(t *testing.T) {
+	db := openTestDB(t)
+	repo := &itemRepository{db: NewDB(db)}
+
+	var ran []string
+	err := New(db).Do(func(tx *Tx) error {
+		r := repo.withTx(tx)
+		if err := r.addItems("a", "b"); err != nil {
+			return err
+		}
+		tx.AfterCommit(func() { ran = append(ran, "first") })
+		if err := r.addItems("c"); err != nil {
+			return err
+		}
+		tx.AfterCommit(func() { ran = append(ran, "second") })
+
+		if len(ran) != 0 {
+			t.Error("hooks ran before the commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := repo.count(t); n != 3 {
+		t.Errorf("%d items after commit, want 3", n)
+	}
+	if len(ran) != 2 || ran[0] != "first" || ran[1] != "second" {
+		t.Errorf("hooks ran %v, want [first second]", ran)
+	}
+}
+
+func TestDoRollsBackOnError(t *testing.T) {
+	db := openTestDB(t)
+	repo := &itemRepository{db: NewDB(db)}
+
+	failed := errors.New("failed")
+	hookRan := false
+	err := New(db).Do(func(tx *Tx) error {
+		if err := repo.withTx(tx).addItems("a", "b"); err != nil {
+			return err
+		}
+		tx.AfterCommit(func() { hookRan = true })
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Do returned %v, want %v", err, failed)
+	}
+
+	if n := repo.count(t); n != 0 {
+		t.Errorf("%d items after rollback, want 0", n)
+	}
+	if hookRan {
+		t.Error("after-commit hook ran for a rolled back transaction")
+	}
+
+	// The connection is free again for writes outside a unit of work
+	if err := repo.addItems("c"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSavepointRollbackKeepsTheRestOfTheUnit(t *testing.T) {
+	db := openTestDB(t)
+	repo := &itemRepository{db: NewDB(db)}
+
+	var ran []string
+	err := New(db).Do(func(tx *Tx) error {
+		r := repo.withTx(tx)
+		if err := r.addItems("a"); err != nil {
+			return err
+		}
+		tx.AfterCommit(func() { ran = append(ran, "kept") })
+
+		// The duplicate fails inside the repository's savepoint, which undoes "b" and
+		// the hook registered in it
+		sp, err := r.db.Begin()
+		if err != nil {
+			return err
+		}
+		sp.AfterCommit(func() { ran = append(ran, "dropped") })
+		if err := r.withTx(sp).addItems("b", "a"); err == nil {
+			t.Error("inserting a duplicate succeeded")
+		}
+		sp.Rollback()
+
+		return r.addItems("c")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query("SELECT name FROM items ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "c" {
+		t.Errorf("items = %v, want [a c]", names)
+	}
+	if len(ran) != 1 || ran[0] != "kept" {
+		t.Errorf("hooks ran %v, want [kept]", ran)
+	}
+}
+
+func TestTxIsDoneAfterCommit(t *testing.T) {
+	db := openTestDB(t)
+
+	tx, err := NewDB(db).Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Rollback after Commit = %v, want ErrTxDone", err)
+	}
+	if err := tx.Commit(); !errors.Is(err, ErrTxDone) {
+		t.Errorf("second Commit = %v, want ErrTxDone", err)
+	}
+}
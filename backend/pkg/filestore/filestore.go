@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Athooh/social-network/pkg/metrics"
 	"github.com/google/uuid"
 )
 
@@ -64,9 +65,15 @@ func (fs *FileStore) SaveFile(file *multipart.FileHeader, subdir string) (string
 	defer dst.Close()
 
 	// Copy file contents
-	if _, err = io.Copy(dst, src); err != nil {
+	written, err := io.Copy(dst, src)
+	if err != nil {
 		return "", fmt.Errorf("failed to copy file: %w", err)
 	}
+	dir := subdir
+	if dir == "" {
+		dir = "root"
+	}
+	metrics.UploadBytes.WithLabelValues(dir).Add(float64(written))
 
 	// Return relative path (including subdirectory)
 	if subdir != "" {
@@ -97,3 +104,13 @@ func (fs *FileStore) DeleteFile(filename string) error {
 	}
 	return nil
 }
+
+// Open opens a file of the upload directory for reading. Names that would lead out of
+// the upload directory are rejected.
+func (fs *FileStore) Open(filename string) (*os.File, error) {
+	name := filepath.Clean(filename)
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrInvalid}
+	}
+	return os.Open(filepath.Join(fs.uploadDir, name))
+}
@@ -14,12 +14,14 @@ func SendJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-// SendError is a helper function to send error responses
+// SendError is a helper function to send error responses, the logged message carries
+// the fields of the request w answers
 func SendError(w http.ResponseWriter, status int, message string, isWarning bool) {
+	log := logger.FromResponse(w)
 	if isWarning {
-		logger.Warn("%s (status: %d)", message, status)
+		log.Warn("%s (status: %d)", message, status)
 	} else {
-		logger.Error("%s (status: %d)", message, status)
+		log.Error("%s (status: %d)", message, status)
 	}
 	SendJSON(w, status, map[string]string{"error": message})
 }
@@ -0,0 +1,289 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// localLayout is the form of date-times in a named time zone
+const localLayout = "20060102T150405"
+
+// maxLineOctets is where RFC 5545 folds content lines
+const maxLineOctets = 75
+
+// futureTransitionYears is how far past the present time zone definitions reach for
+// series that repeat indefinitely
+const futureTransitionYears = 10
+
+// Event is a VEVENT
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time   // Zero for events without an end
+	TimeZone    string      // IANA name the times are written in, UTC when empty
+	Rule        string      // RRULE value, empty for one-off events
+	Except      []time.Time // Starts of cancelled occurrences
+	Created     time.Time
+	Modified    time.Time
+}
+
+// Calendar is a VCALENDAR of events
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Encode writes the calendar, with a VTIMEZONE for every time zone its events use
+func (c *Calendar) Encode(w io.Writer) error {
+	lw := &lineWriter{w: bufio.NewWriter(w)}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + c.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+
+	zones, err := c.zones()
+	if err != nil {
+		return err
+	}
+	for _, zone := range zones {
+		writeTimezone(lw, zone.loc, zone.from, zone.to)
+	}
+
+	for _, event := range c.Events {
+		if err := writeEvent(lw, event); err != nil {
+			return err
+		}
+	}
+
+	lw.line("END:VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return lw.w.Flush()
+}
+
+// zoneSpan is a time zone and the years its events cover
+type zoneSpan struct {
+	loc      *time.Location
+	from, to int
+}
+
+func (c *Calendar) zones() ([]*zoneSpan, error) {
+	spans := make(map[string]*zoneSpan)
+	for _, event := range c.Events {
+		if isUTC(event.TimeZone) {
+			continue
+		}
+		loc, err := time.LoadLocation(event.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %w", event.TimeZone, err)
+		}
+
+		from, to := event.Start.Year(), event.Start.Year()
+		if !event.End.IsZero() {
+			to = event.End.Year()
+		}
+		if event.Rule != "" {
+			// The series may go on, cover the years it is likely to be looked at
+			to = max(to, time.Now().Year()) + futureTransitionYears
+		}
+
+		span, ok := spans[event.TimeZone]
+		if !ok {
+			spans[event.TimeZone] = &zoneSpan{loc: loc, from: from, to: to}
+			continue
+		}
+		span.from, span.to = min(span.from, from), max(span.to, to)
+	}
+
+	names := make([]string, 0, len(spans))
+	for name := range spans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zones := make([]*zoneSpan, len(names))
+	for i, name := range names {
+		zones[i] = spans[name]
+	}
+	return zones, nil
+}
+
+func isUTC(zone string) bool {
+	return zone == "" || zone == "UTC"
+}
+
+// writeTimezone writes a VTIMEZONE listing the offset in force at the start of year
+// from and every transition up to the end of year to
+func writeTimezone(lw *lineWriter, loc *time.Location, from, to int) {
+	start := time.Date(from, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(to+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	lw.line("BEGIN:VTIMEZONE")
+	lw.line("TZID:" + loc.String())
+	writeObservance(lw, start.In(loc), offsetAt(start.Add(-time.Second), loc))
+
+	for day := start; day.Before(end); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if offsetAt(day, loc) == offsetAt(next, loc) {
+			continue
+		}
+
+		// Narrow the change down to the second
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if offsetAt(mid, loc) == offsetAt(lo, loc) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		writeObservance(lw, hi.In(loc), offsetAt(lo, loc))
+	}
+
+	lw.line("END:VTIMEZONE")
+}
+
+// writeObservance writes the STANDARD or DAYLIGHT component starting at t, fromOffset
+// being the offset in force just before
+func writeObservance(lw *lineWriter, t time.Time, fromOffset int) {
+	kind := "STANDARD"
+	if t.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	name, offset := t.Zone()
+
+	lw.line("BEGIN:" + kind)
+	lw.line("DTSTART:" + t.UTC().Add(time.Duration(fromOffset)*time.Second).Format(localLayout))
+	lw.line("TZOFFSETFROM:" + formatOffset(fromOffset))
+	lw.line("TZOFFSETTO:" + formatOffset(offset))
+	lw.line("TZNAME:" + escapeText(name))
+	lw.line("END:" + kind)
+}
+
+func offsetAt(t time.Time, loc *time.Location) int {
+	_, offset := t.In(loc).Zone()
+	return offset
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
+
+func writeEvent(lw *lineWriter, event Event) error {
+	loc := time.UTC
+	if !isUTC(event.TimeZone) {
+		var err error
+		if loc, err = time.LoadLocation(event.TimeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q: %w", event.TimeZone, err)
+		}
+	}
+
+	stamp := event.Modified
+	if stamp.IsZero() {
+		stamp = event.Created
+	}
+
+	lw.line("BEGIN:VEVENT")
+	lw.line("UID:" + event.UID)
+	lw.line("DTSTAMP:" + FormatUTC(stamp))
+	lw.line(dateTimeProperty("DTSTART", loc, event.Start))
+	if !event.End.IsZero() {
+		lw.line(dateTimeProperty("DTEND", loc, event.End))
+	}
+	if event.Rule != "" {
+		lw.line("RRULE:" + event.Rule)
+	}
+	for _, t := range event.Except {
+		lw.line(dateTimeProperty("EXDATE", loc, t))
+	}
+	lw.line("SUMMARY:" + escapeText(event.Summary))
+	if event.Description != "" {
+		lw.line("DESCRIPTION:" + escapeText(event.Description))
+	}
+	if event.Location != "" {
+		lw.line("LOCATION:" + escapeText(event.Location))
+	}
+	if !event.Created.IsZero() {
+		lw.line("CREATED:" + FormatUTC(event.Created))
+	}
+	if !event.Modified.IsZero() {
+		lw.line("LAST-MODIFIED:" + FormatUTC(event.Modified))
+	}
+	lw.line("END:VEVENT")
+	return nil
+}
+
+// dateTimeProperty writes t in UTC, or as local time with a TZID in any other location
+func dateTimeProperty(name string, loc *time.Location, t time.Time) string {
+	if loc == time.UTC {
+		return name + ":" + FormatUTC(t)
+	}
+	return name + ";TZID=" + loc.String() + ":" + t.In(loc).Format(localLayout)
+}
+
+// escapeText escapes a TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// lineWriter writes content lines ending in CRLF, folding them at 75 octets without
+// splitting characters
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		lw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = maxLineOctets - 1
+	}
+	lw.write(s + "\r\n")
+}
+
+func (lw *lineWriter) write(s string) {
+	if lw.err == nil {
+		_, lw.err = lw.w.WriteString(s)
+	}
+}
@@ -0,0 +1,86 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cal := &Calendar{
+		ProdID: "-//Test//EN",
+		Name:   "Meetups",
+		Events: []Event{
+			{
+				UID:         "weekly@test",
+				Summary:     "Go meetup; bring snacks, please",
+				Description: "Line one\nLine two",
+				Start:       time.Date(2025, 3, 20, 19, 0, 0, 0, paris),
+				End:         time.Date(2025, 3, 20, 21, 0, 0, 0, paris),
+				TimeZone:    "Europe/Paris",
+				Rule:        "FREQ=WEEKLY;COUNT=4",
+				Except:      []time.Time{time.Date(2025, 3, 27, 19, 0, 0, 0, paris)},
+				Created:     created,
+			},
+			{
+				UID:         "once@test",
+				Summary:     "Launch",
+				Description: strings.Repeat("é", 60),
+				Start:       time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC),
+				Created:     created,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := cal.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Meetups\r\n",
+		"TZID:Europe/Paris\r\n",
+		// Summer time starts on the last Sunday of March at 02:00 local time
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n",
+		"DTSTART;TZID=Europe/Paris:20250320T190000\r\n",
+		"DTEND;TZID=Europe/Paris:20250320T210000\r\n",
+		"RRULE:FREQ=WEEKLY;COUNT=4\r\n",
+		"EXDATE;TZID=Europe/Paris:20250327T190000\r\n",
+		`SUMMARY:Go meetup\; bring snacks\, please` + "\r\n",
+		`DESCRIPTION:Line one\nLine two` + "\r\n",
+		"DTSTART:20250501T120000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, out)
+		}
+	}
+
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line of %d octets is not folded: %q", len(line), line)
+		}
+	}
+
+	// Unfolding gives back the long description
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, "DESCRIPTION:"+strings.Repeat("é", 60)+"\r\n") {
+		t.Error("folded description does not unfold to the original")
+	}
+}
+
+func TestEncodeUnknownTimeZone(t *testing.T) {
+	cal := &Calendar{Events: []Event{{UID: "x", Start: time.Now(), TimeZone: "Mars/Olympus"}}}
+	if err := cal.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("Encode succeeded with an unknown time zone")
+	}
+}
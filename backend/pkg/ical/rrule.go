@@ -0,0 +1,453 @@
+// Package ical implements the parts of iCalendar (RFC 5545) the server needs: parsing
+// and expanding recurrence rules and writing calendars.
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is how often a recurrence rule repeats
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// utcLayout is the form of UTC date-times in iCalendar
+const utcLayout = "20060102T150405Z"
+
+// maxEmptyPeriods stops expanding rules that can never match, such as the 30th of February
+const maxEmptyPeriods = 1000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is a BYDAY entry: a weekday, limited to its Nth occurrence in the month or
+// year when N is not 0. Negative N counts from the end.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return weekdayNames[w.Day]
+	}
+	return strconv.Itoa(w.N) + weekdayNames[w.Day]
+}
+
+// Rule is a recurrence rule. The FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH
+// and WKST parts are supported, rules using other parts are rejected.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// ParseRule parses the value of an RRULE property, with or without the "RRULE:" prefix
+func ParseRule(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name, value = strings.ToUpper(strings.TrimSpace(name)), strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("recurrence rule part %s appears twice", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch f := Frequency(value); f {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = f
+			default:
+				err = fmt.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = parseInt(value, 1, 1000)
+		case "COUNT":
+			r.Count, err = parseInt(value, 1, 10000)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			for _, item := range strings.Split(value, ",") {
+				var day WeekdayNum
+				if day, err = parseWeekdayNum(item); err != nil {
+					break
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(value, ",") {
+				var day int
+				if day, err = parseInt(item, -31, 31); err != nil || day == 0 {
+					err = fmt.Errorf("invalid month day %q", item)
+					break
+				}
+				r.ByMonthDay = append(r.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(value, ",") {
+				var month int
+				if month, err = parseInt(item, 1, 12); err != nil {
+					break
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			day, ok := weekdays[value]
+			if !ok {
+				err = fmt.Errorf("invalid week start %q", value)
+			}
+			r.WeekStart = day
+		default:
+			err = fmt.Errorf("unsupported recurrence rule part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("recurrence rule needs a FREQ")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, errors.New("recurrence rule cannot have both COUNT and UNTIL")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return nil, errors.New("BYMONTHDAY cannot be used with a weekly rule")
+	}
+	if r.Freq == Daily || r.Freq == Weekly {
+		for _, day := range r.ByDay {
+			if day.N != 0 {
+				return nil, fmt.Errorf("numbered BYDAY %s needs a monthly or yearly rule", day)
+			}
+		}
+	}
+
+	sort.Slice(r.ByMonth, func(i, j int) bool { return r.ByMonth[i] < r.ByMonth[j] })
+	return r, nil
+}
+
+func parseInt(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("invalid number %q, want %d to %d", s, min, max)
+	}
+	return n, nil
+}
+
+// parseUntil reads a UTC date-time, or a date which then includes the whole day
+func parseUntil(s string) (time.Time, error) {
+	for _, layout := range []string{utcLayout, "20060102T150405"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", s)
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+	}
+	day, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+	}
+	n := 0
+	if prefix := s[:len(s)-2]; prefix != "" {
+		var err error
+		if n, err = parseInt(strings.TrimPrefix(prefix, "+"), -53, 53); err != nil || n == 0 {
+			return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+		}
+	}
+	return WeekdayNum{N: n, Day: day}, nil
+}
+
+// String formats the rule as the value of an RRULE property
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(utcLayout))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the starts of the occurrences of a series beginning at start that fall
+// in [from, to), leaving out those in except. Occurrences keep the wall clock time of
+// start in its location, so they stay at the same local time across daylight saving
+// changes. start is always the first occurrence, as RFC 5545 requires, and COUNT
+// includes occurrences left out by except. At most limit starts are returned, any
+// number when limit is 0.
+func (r *Rule) Between(start, from, to time.Time, except []time.Time, limit int) []time.Time {
+	excluded := make(map[int64]bool, len(except))
+	for _, t := range except {
+		excluded[t.Unix()] = true
+	}
+
+	var out []time.Time
+	generated := 0
+	emit := func(t time.Time) bool {
+		if r.Count > 0 && generated >= r.Count {
+			return false
+		}
+		if (!r.Until.IsZero() && t.After(r.Until)) || !t.Before(to) {
+			return false
+		}
+		generated++
+		if !t.Before(from) && !excluded[t.Unix()] {
+			out = append(out, t)
+			if limit > 0 && len(out) >= limit {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !emit(start) {
+		return out
+	}
+
+	hour, minute, second := start.Clock()
+	for period, empty := 0, 0; empty < maxEmptyPeriods; period++ {
+		days := r.periodDays(start, period)
+		if len(days) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+
+		for _, day := range days {
+			t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, start.Nanosecond(), start.Location())
+			if !t.After(start) {
+				continue
+			}
+			if !emit(t) {
+				return out
+			}
+		}
+	}
+
+	return out
+}
+
+// periodDays returns the days, as UTC midnights in order, the rule selects in the period
+// that is n intervals after the one holding start
+func (r *Rule) periodDays(start time.Time, n int) []time.Time {
+	y, m, d := start.Date()
+	base := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	step := n * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := base.AddDate(0, 0, step)
+		if r.inMonths(day) && r.onMonthDays(day) && r.onWeekdays(day) {
+			days = append(days, day)
+		}
+
+	case Weekly:
+		offset := (int(base.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := base.AddDate(0, 0, 7*step-offset)
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != base.Weekday() {
+				continue
+			}
+			if r.onWeekdays(day) && r.inMonths(day) {
+				days = append(days, day)
+			}
+		}
+
+	case Monthly:
+		first := time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if r.inMonths(first) {
+			days = r.pick(monthDays(first), d)
+		}
+
+	case Yearly:
+		year := y + step
+		switch {
+		case len(r.ByMonth) > 0:
+			for _, month := range r.ByMonth {
+				days = append(days, r.pick(monthDays(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)), d)...)
+			}
+		case len(r.ByMonthDay) > 0:
+			for month := time.January; month <= time.December; month++ {
+				days = append(days, r.pick(monthDays(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)), d)...)
+			}
+		case len(r.ByDay) > 0:
+			days = r.pick(yearDays(year), d)
+		default:
+			days = r.pick(monthDays(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)), d)
+		}
+	}
+
+	return days
+}
+
+// pick selects the days of scope, a month or a year, that match BYMONTHDAY and BYDAY.
+// Numbered weekdays count within scope. Without either part the day of the month
+// startDay is picked, if scope has it.
+func (r *Rule) pick(scope []time.Time, startDay int) []time.Time {
+	var days []time.Time
+	for i, day := range scope {
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			if day.Day() == startDay {
+				days = append(days, day)
+			}
+			continue
+		}
+		if r.onMonthDays(day) && r.onNthWeekdays(scope, i) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func (r *Rule) inMonths(day time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if day.Month() == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) onMonthDays(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if (md > 0 && day.Day() == md) || (md < 0 && day.Day() == last+md+1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) onWeekdays(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// onNthWeekdays reports whether scope[i] matches BYDAY, counting numbered weekdays from
+// the start or end of scope
+func (r *Rule) onNthWeekdays(scope []time.Time, i int) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	day := scope[i]
+	for _, wd := range r.ByDay {
+		if wd.Day != day.Weekday() {
+			continue
+		}
+		switch {
+		case wd.N == 0:
+			return true
+		case wd.N > 0 && i/7+1 == wd.N:
+			return true
+		case wd.N < 0 && (len(scope)-1-i)/7+1 == -wd.N:
+			return true
+		}
+	}
+	return false
+}
+
+func monthDays(first time.Time) []time.Time {
+	var days []time.Time
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+func yearDays(year int) []time.Time {
+	var days []time.Time
+	for day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC); day.Year() == year; day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// FormatUTC formats t as an iCalendar UTC date-time
+func FormatUTC(t time.Time) string {
+	return t.UTC().Format(utcLayout)
+}
+
+// ParseUTC parses an iCalendar UTC date-time
+func ParseUTC(s string) (time.Time, error) {
+	return time.Parse(utcLayout, s)
+}
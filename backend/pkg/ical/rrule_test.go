@@ -0,0 +1,159 @@
+package ical
+
+import (
+	"testing"
+	"time"
+)
+
+// dates formats times as their local dates and clocks for comparison
+func dates(times []time.Time) []string {
+	out := make([]string, len(times))
+	for i, t := range times {
+		out[i] = t.Format("2006-01-02 15:04 MST")
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func mustRule(t *testing.T, s string) *Rule {
+	t.Helper()
+
+	r, err := ParseRule(s)
+	if err != nil {
+		t.Fatalf("ParseRule(%q): %v", s, err)
+	}
+	return r
+}
+
+func TestBetween(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	far := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		rule   string
+		start  time.Time
+		except []time.Time
+		want   []string
+	}{
+		{
+			name:  "daily count",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: time.Date(2025, 1, 30, 9, 0, 0, 0, time.UTC),
+			want:  []string{"2025-01-30 09:00 UTC", "2025-01-31 09:00 UTC", "2025-02-01 09:00 UTC"},
+		},
+		{
+			name:  "weekly keeps local time across daylight saving",
+			rule:  "FREQ=WEEKLY;BYDAY=SA;COUNT=3",
+			start: time.Date(2025, 3, 1, 18, 30, 0, 0, ny),
+			want:  []string{"2025-03-01 18:30 EST", "2025-03-08 18:30 EST", "2025-03-15 18:30 EDT"},
+		},
+		{
+			name:  "every other week on two days",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=5",
+			start: time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC),
+			want: []string{
+				"2025-06-02 12:00 UTC", "2025-06-04 12:00 UTC", "2025-06-16 12:00 UTC",
+				"2025-06-18 12:00 UTC", "2025-06-30 12:00 UTC",
+			},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: time.Date(2025, 1, 31, 8, 0, 0, 0, time.UTC),
+			want:  []string{"2025-01-31 08:00 UTC", "2025-03-31 08:00 UTC", "2025-05-31 08:00 UTC"},
+		},
+		{
+			name:  "last friday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start: time.Date(2025, 1, 31, 17, 0, 0, 0, time.UTC),
+			want:  []string{"2025-01-31 17:00 UTC", "2025-02-28 17:00 UTC", "2025-03-28 17:00 UTC"},
+		},
+		{
+			name:  "yearly on the fourth thursday of november",
+			rule:  "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH;COUNT=2",
+			start: time.Date(2025, 11, 27, 15, 0, 0, 0, time.UTC),
+			want:  []string{"2025-11-27 15:00 UTC", "2026-11-26 15:00 UTC"},
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20250103T090000Z",
+			start: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+			want:  []string{"2025-01-01 09:00 UTC", "2025-01-02 09:00 UTC", "2025-01-03 09:00 UTC"},
+		},
+		{
+			name:   "exceptions still count",
+			rule:   "FREQ=DAILY;COUNT=3",
+			start:  time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+			except: []time.Time{time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)},
+			want:   []string{"2025-01-01 09:00 UTC", "2025-01-03 09:00 UTC"},
+		},
+		{
+			name:  "impossible rule ends",
+			rule:  "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30",
+			start: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+			want:  []string{"2025-01-01 09:00 UTC"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dates(mustRule(t, tt.rule).Between(tt.start, tt.start, far, tt.except, 0))
+			if !equal(got, tt.want) {
+				t.Errorf("Between = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetweenWindowAndLimit(t *testing.T) {
+	r := mustRule(t, "FREQ=DAILY")
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	got := dates(r.Between(start, start.AddDate(0, 0, 10), start.AddDate(0, 0, 13), nil, 0))
+	want := []string{"2025-01-11 09:00 UTC", "2025-01-12 09:00 UTC", "2025-01-13 09:00 UTC"}
+	if !equal(got, want) {
+		t.Errorf("Between(window) = %v, want %v", got, want)
+	}
+
+	if got := r.Between(start, start, start.AddDate(1, 0, 0), nil, 5); len(got) != 5 {
+		t.Errorf("Between(limit 5) returned %d occurrences", len(got))
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	r := mustRule(t, "RRULE:freq=monthly;interval=2;byday=1MO,-1FR;until=20251231")
+	if got, want := r.String(), "FREQ=MONTHLY;INTERVAL=2;UNTIL=20251231T235959Z;BYDAY=1MO,-1FR"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101T000000Z",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=DAILY;FREQ=WEEKLY",
+	} {
+		if _, err := ParseRule(bad); err == nil {
+			t.Errorf("ParseRule(%q) succeeded, want an error", bad)
+		}
+	}
+}
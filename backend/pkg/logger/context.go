@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the header that carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the longest request ID accepted from a client
+const maxRequestIDLength = 64
+
+// scopeKey is the context key of the request scope
+type scopeKey struct{}
+
+// scope holds the fields of one request. It is shared by every context derived from the
+// request, so fields added by inner middleware, like the user ID, also reach the access
+// line written by outer middleware.
+type scope struct {
+	mu   sync.Mutex
+	args []any
+}
+
+func (s *scope) add(args []any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.args = append(s.args, args...)
+}
+
+func (s *scope) fields() []any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]any(nil), s.args...)
+}
+
+// NewContext returns a context that carries a request scope with the fields in args
+func NewContext(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{args: args})
+}
+
+// AddFields adds the key-value pairs in args to the request ctx belongs to, so that they
+// are written with every later message of that request. It does nothing if ctx was not
+// created by NewContext.
+func AddFields(ctx context.Context, args ...any) {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		s.add(args)
+	}
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "" outside a request
+func RequestID(ctx context.Context) string {
+	args := scopeFields(ctx)
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "request_id" {
+			id, _ := args[i+1].(string)
+			return id
+		}
+	}
+	return ""
+}
+
+// FromContext returns a logger that writes the fields of the request ctx belongs to
+func FromContext(ctx context.Context) *Logger {
+	return (&Logger{}).WithContext(ctx)
+}
+
+// FromResponse returns a logger that writes the fields of the request w answers. It
+// finds them through the writer RequestIDMiddleware wraps every response in, for code
+// that is handed the response writer but not the request.
+func FromResponse(w http.ResponseWriter) *Logger {
+	for w != nil {
+		if sw, ok := w.(*scopedWriter); ok {
+			return &Logger{ctx: sw.ctx}
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return &Logger{}
+}
+
+// RequestIDMiddleware gives every request an ID and a scope for its log fields. A valid
+// X-Request-ID from the client is kept so requests can be followed across services,
+// otherwise a new one is generated. The ID is returned in the X-Request-ID header.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := NewContext(r.Context(), "request_id", id)
+		next.ServeHTTP(&scopedWriter{ResponseWriter: w, ctx: ctx}, r.WithContext(ctx))
+	})
+}
+
+// validRequestID reports whether a client supplied ID is short and only uses characters
+// that are safe in log lines and headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// scopedWriter ties a response writer to the context of its request
+type scopedWriter struct {
+	http.ResponseWriter
+	ctx context.Context
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController
+func (w *scopedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush sends buffered data to the client if the underlying writer supports it
+func (w *scopedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the websocket upgrader take over the connection
+func (w *scopedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// scopeFields returns the fields of the request ctx belongs to
+func scopeFields(ctx context.Context) []any {
+	if ctx == nil {
+		return nil
+	}
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		return s.fields()
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// callerKey holds the file and line that logged a message
+	callerKey = "caller"
+	// methodKey marks access lines, so that text handlers can color the HTTP method
+	methodKey = "http_method"
+)
+
+// slogLevel returns the log/slog level of l, FATAL maps to four above slog.LevelError
+func (l Level) slogLevel() slog.Level {
+	switch l {
+	case DEBUG:
+		return slog.LevelDebug
+	case INFO:
+		return slog.LevelInfo
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	default:
+		return slog.LevelError + 4
+	}
+}
+
+// levelFromSlog returns the level a log/slog level falls in
+func levelFromSlog(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return DEBUG
+	case l < slog.LevelWarn:
+		return INFO
+	case l < slog.LevelError:
+		return WARN
+	case l < slog.LevelError+4:
+		return ERROR
+	default:
+		return FATAL
+	}
+}
+
+// newJSONHandler writes one JSON object per line with the level names used in text
+func newJSONHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.LevelKey {
+				if l, ok := a.Value.Any().(slog.Level); ok {
+					return slog.String(slog.LevelKey, levelFromSlog(l).String())
+				}
+			}
+			return a
+		},
+	})
+}
+
+// textHandler writes lines in the "[time] [LEVEL] [caller] message key=value" format
+type textHandler struct {
+	mu         *sync.Mutex
+	w          io.Writer
+	timeFormat string
+	color      bool
+	fields     string
+	prefix     string
+}
+
+func newTextHandler(w io.Writer, timeFormat string, color bool) *textHandler {
+	return &textHandler{
+		mu:         &sync.Mutex{},
+		w:          w,
+		timeFormat: timeFormat,
+		color:      color,
+	}
+}
+
+// Enabled reports true, levels are filtered before records reach the handler
+func (h *textHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle writes r as a single line
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var caller, method string
+	var fields strings.Builder
+	fields.WriteString(h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case callerKey:
+			caller = a.Value.String()
+		case methodKey:
+			method = a.Value.String()
+		default:
+			writeField(&fields, h.prefix, a)
+		}
+		return true
+	})
+
+	lvl := levelFromSlog(r.Level)
+	levelStr := lvl.String()
+	msg := r.Message
+	if h.color {
+		levelColor := getLevelColor(lvl)
+		levelStr = fmt.Sprintf("%s%s%s", levelColor, levelStr, ColorReset)
+		if method != "" {
+			// Access lines color the method rather than the message
+			msg = strings.Replace(msg, "["+method+"]", fmt.Sprintf("%s[%s%s%s]", ColorReset, getMethodColor(method), method, ColorReset), 1)
+		} else {
+			msg = fmt.Sprintf("%s%s%s", levelColor, msg, ColorReset)
+		}
+	}
+
+	line := fmt.Sprintf("[%s] [%s]", r.Time.Format(h.timeFormat), levelStr)
+	if caller != "" {
+		line += " [" + caller + "]"
+	}
+	line += " " + msg + fields.String() + "\n"
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line)
+	return err
+}
+
+// WithAttrs returns a handler that writes attrs on every line
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields strings.Builder
+	fields.WriteString(h.fields)
+	for _, a := range attrs {
+		writeField(&fields, h.prefix, a)
+	}
+
+	h2 := *h
+	h2.fields = fields.String()
+	return &h2
+}
+
+// WithGroup returns a handler that prefixes the keys of later fields with name
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// writeField writes a as " key=value", quoting values that would be ambiguous
+func writeField(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			writeField(b, prefix, ga)
+		}
+		return
+	}
+
+	var value string
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		value = a.Value.Time().Format(time.RFC3339)
+	default:
+		value = a.Value.String()
+	}
+	if value == "" || strings.ContainsAny(value, " =\"\n\t") {
+		value = strconv.Quote(value)
+	}
+
+	b.WriteString(" ")
+	b.WriteString(prefix + a.Key)
+	b.WriteString("=")
+	b.WriteString(value)
+}
+
+// multiHandler writes every record to each of its handlers
+type multiHandler []slog.Handler
+
+// Enabled reports whether any of the handlers is enabled for level
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle writes r to every handler and returns the first error
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var first error
+	for _, h := range m {
+		if err := h.Handle(ctx, r.Clone()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// WithAttrs adds attrs to every handler
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+// WithGroup opens a group on every handler
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// slogHandler lets log/slog loggers write through the configured outputs. It applies the
+// configured level, adds the caller when ShowCaller is set and adds the fields of the
+// request the context passed to the *Context methods belongs to.
+type slogHandler struct {
+	next slog.Handler
+}
+
+// Enabled reports whether level is at or above the configured level
+func (h slogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return h.next != nil && levelFromSlog(l) >= level
+}
+
+// Handle adds the caller and the request fields to r
+func (h slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if config.ShowCaller && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		r.AddAttrs(slog.String(callerKey, fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)))
+	}
+	r.Add(scopeFields(ctx)...)
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs adds attrs to the wrapped handler
+func (h slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next == nil {
+		return h
+	}
+	return slogHandler{h.next.WithAttrs(attrs)}
+}
+
+// WithGroup opens a group on the wrapped handler
+func (h slogHandler) WithGroup(name string) slog.Handler {
+	if h.next == nil {
+		return h
+	}
+	return slogHandler{h.next.WithGroup(name)}
+}
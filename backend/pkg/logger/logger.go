@@ -1,15 +1,18 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -49,14 +52,11 @@ func (l Level) String() string {
 
 // Global loggers
 var (
-	debugLogger *log.Logger
-	infoLogger  *log.Logger
-	warnLogger  *log.Logger
-	errorLogger *log.Logger
-	fatalLogger *log.Logger
-	level       Level
-	once        sync.Once
-	config      Config
+	handler slog.Handler
+	logFile *RotatingFile
+	level   Level
+	once    sync.Once
+	config  Config
 )
 
 // Color represents a terminal color code
@@ -86,9 +86,28 @@ const (
 	BothOutput
 )
 
+// Format represents how log lines are written
+type Format int
+
+const (
+	// TextFormat writes human readable lines with fields as key=value
+	TextFormat Format = iota
+	// JSONFormat writes one JSON object per line
+	JSONFormat
+)
+
+// ParseFormat returns the format named by s, text unless s is "json"
+func ParseFormat(s string) Format {
+	if strings.EqualFold(s, "json") {
+		return JSONFormat
+	}
+	return TextFormat
+}
+
 // Config holds the logger configuration
 type Config struct {
 	Level         Level
+	Format        Format
 	Output        io.Writer  // Will be used for backward compatibility
 	OutputType    OutputType // Determines where logs are written
 	FileOutput    io.Writer  // File to write logs to
@@ -97,6 +116,7 @@ type Config struct {
 	ShowCaller    bool
 	EnableColor   bool // Whether to enable colors in console output
 	FilePath      string
+	Rotation      RotationConfig // When the log file is rotated, used with FileOutput and BothOutput
 }
 
 // Init initializes the global loggers
@@ -119,31 +139,37 @@ func Init(cfg Config) {
 			config.ConsoleOutput = os.Stdout
 		}
 
-		// Configure the output based on OutputType
-		var output io.Writer
-		switch config.OutputType {
-		case ConsoleOutput:
-			output = config.ConsoleOutput
-		case FileOutput:
-			output = config.FileOutput
-		case BothOutput:
-			output = io.MultiWriter(config.ConsoleOutput, config.FileOutput)
-		}
-
-		config.Output = output
-
-		flags := 0
-		prefix := ""
-
-		debugLogger = log.New(output, prefix, flags)
-		infoLogger = log.New(output, prefix, flags)
-		warnLogger = log.New(output, prefix, flags)
-		errorLogger = log.New(output, prefix, flags)
-		fatalLogger = log.New(output, prefix, flags)
 		level = config.Level
+		configureOutput()
 	})
 }
 
+// configureOutput points the handler at the outputs selected by config.OutputType. In
+// text format the console and the file get their own handler, so that only the console
+// is colored.
+func configureOutput() {
+	switch config.OutputType {
+	case ConsoleOutput:
+		config.Output = config.ConsoleOutput
+	case FileOutput:
+		config.Output = config.FileOutput
+	case BothOutput:
+		config.Output = io.MultiWriter(config.ConsoleOutput, config.FileOutput)
+	}
+
+	switch {
+	case config.Format == JSONFormat:
+		handler = newJSONHandler(config.Output)
+	case config.OutputType == BothOutput:
+		handler = multiHandler{
+			newTextHandler(config.ConsoleOutput, config.TimeFormat, config.EnableColor),
+			newTextHandler(config.FileOutput, config.TimeFormat, false),
+		}
+	default:
+		handler = newTextHandler(config.Output, config.TimeFormat, config.EnableColor && config.OutputType == ConsoleOutput)
+	}
+}
+
 // getLevelColor returns the appropriate color for a log level
 func getLevelColor(level Level) Color {
 	switch level {
@@ -162,28 +188,6 @@ func getLevelColor(level Level) Color {
 	}
 }
 
-// formatMessage formats a log message with timestamp, level, and caller information
-func formatMessage(level Level, message string, config Config, forConsole bool) string {
-	timestamp := time.Now().Format(config.TimeFormat)
-
-	var caller string
-	if config.ShowCaller {
-		// Get the caller information
-		caller = getCaller()
-	}
-
-	levelStr := level.String()
-
-	// Apply colors only for console output and if enabled
-	if forConsole && config.EnableColor {
-		levelColor := getLevelColor(level)
-		levelStr = fmt.Sprintf("%s%s%s", levelColor, levelStr, ColorReset)
-		message = fmt.Sprintf("%s%s%s", levelColor, message, ColorReset)
-	}
-
-	return fmt.Sprintf("[%s] [%s]%s %s", timestamp, levelStr, caller, message)
-}
-
 // getCaller returns the file and line number of the actual caller
 func getCaller() string {
 	// We need to skip the logger package and any wrapper functions
@@ -194,7 +198,7 @@ func getCaller() string {
 		}
 
 		// Skip any calls from the logger package itself
-		if !strings.Contains(file, "logger/logger.go") {
+		if !strings.Contains(file, "pkg/logger/") || strings.HasSuffix(file, "_test.go") {
 			// Get the function name
 			funcName := runtime.FuncForPC(pc).Name()
 
@@ -205,75 +209,60 @@ func getCaller() string {
 
 			// We found the real caller
 			file = filepath.Base(file)
-			return fmt.Sprintf(" [%s:%d]", file, line)
+			return fmt.Sprintf("%s:%d", file, line)
 		}
 	}
 
 	return ""
 }
 
-// writeLog writes a log message to the appropriate outputs
-func writeLog(logger *log.Logger, level Level, msg string) {
-	// For BothOutput, we need to handle console and file separately
-	if config.OutputType == BothOutput {
-		// Format message for console (with colors if enabled)
-		consoleMsg := formatMessage(level, msg, config, true)
-
-		// Format message for file (without colors)
-		fileMsg := formatMessage(level, msg, config, false)
+// writeLog writes a log message with its fields to the configured outputs
+func writeLog(ctx context.Context, lvl Level, msg string, args []any) {
+	if handler == nil {
+		return
+	}
 
-		// Write to console
-		fmt.Fprintln(config.ConsoleOutput, consoleMsg)
+	record := slog.NewRecord(time.Now(), lvl.slogLevel(), msg, 0)
+	if config.ShowCaller {
+		if caller := getCaller(); caller != "" {
+			record.AddAttrs(slog.String(callerKey, caller))
+		}
+	}
+	record.Add(args...)
+	handler.Handle(ctx, record)
+}
 
-		// Write to file
-		fmt.Fprintln(config.FileOutput, fileMsg)
-	} else {
-		// For single output types, use the normal approach
-		forConsole := config.OutputType == ConsoleOutput
-		formattedMsg := formatMessage(level, msg, config, forConsole)
-		logger.Println(formattedMsg)
+// logf formats a message and writes it if lvl is enabled
+func logf(ctx context.Context, lvl Level, args []any, format string, v []interface{}) {
+	if level <= lvl {
+		writeLog(ctx, lvl, fmt.Sprintf(format, v...), args)
 	}
 }
 
 // Debug logs a debug message
 func Debug(format string, v ...interface{}) {
-	if level <= DEBUG {
-		msg := fmt.Sprintf(format, v...)
-		writeLog(debugLogger, DEBUG, msg)
-	}
+	logf(context.Background(), DEBUG, nil, format, v)
 }
 
 // Info logs an info message
 func Info(format string, v ...interface{}) {
-	if level <= INFO {
-		msg := fmt.Sprintf(format, v...)
-		writeLog(infoLogger, INFO, msg)
-	}
+	logf(context.Background(), INFO, nil, format, v)
 }
 
 // Warn logs a warning message
 func Warn(format string, v ...interface{}) {
-	if level <= WARN {
-		msg := fmt.Sprintf(format, v...)
-		writeLog(warnLogger, WARN, msg)
-	}
+	logf(context.Background(), WARN, nil, format, v)
 }
 
 // Error logs an error message
 func Error(format string, v ...interface{}) {
-	if level <= ERROR {
-		msg := fmt.Sprintf(format, v...)
-		writeLog(errorLogger, ERROR, msg)
-	}
+	logf(context.Background(), ERROR, nil, format, v)
 }
 
 // Fatal logs a fatal message and exits the application
 func Fatal(format string, v ...interface{}) {
-	if level <= FATAL {
-		msg := fmt.Sprintf(format, v...)
-		writeLog(fatalLogger, FATAL, msg)
-		os.Exit(1)
-	}
+	logf(context.Background(), FATAL, nil, format, v)
+	os.Exit(1)
 }
 
 // SetOutput sets the output destination for the logger
@@ -288,39 +277,20 @@ func SetOutput(outputType OutputType, consoleOutput, fileOutput io.Writer) {
 		config.FileOutput = fileOutput
 	}
 
-	var output io.Writer
-	switch outputType {
-	case ConsoleOutput:
-		output = config.ConsoleOutput
-	case FileOutput:
-		output = config.FileOutput
-	case BothOutput:
-		output = io.MultiWriter(config.ConsoleOutput, config.FileOutput)
-	}
-
-	config.Output = output
-
-	// Update all loggers with the new output
-	debugLogger.SetOutput(output)
-	infoLogger.SetOutput(output)
-	warnLogger.SetOutput(output)
-	errorLogger.SetOutput(output)
-	fatalLogger.SetOutput(output)
+	configureOutput()
 }
 
 // SetColorEnabled enables or disables colored output
 func SetColorEnabled(enabled bool) {
 	config.EnableColor = enabled
+	configureOutput()
 }
 
-// Logger represents a logger instance for compatibility with existing code
+// Logger represents a logger instance for compatibility with existing code. It writes
+// through the global loggers and adds its fields to every message.
 type Logger struct {
-	debugLogger *log.Logger
-	infoLogger  *log.Logger
-	warnLogger  *log.Logger
-	errorLogger *log.Logger
-	fatalLogger *log.Logger
-	level       Level
+	ctx  context.Context
+	args []any
 }
 
 // New creates a new logger instance (for backward compatibility)
@@ -328,39 +298,76 @@ func New(config Config) *Logger {
 	// Initialize global loggers if not already done
 	Init(config)
 
+	return &Logger{}
+}
+
+// With returns a logger that adds the key-value pairs in args to every message, in the
+// same form as slog.Logger.With
+func (l *Logger) With(args ...any) *Logger {
 	return &Logger{
-		debugLogger: debugLogger,
-		infoLogger:  infoLogger,
-		warnLogger:  warnLogger,
-		errorLogger: errorLogger,
-		fatalLogger: fatalLogger,
-		level:       level,
+		ctx:  l.ctx,
+		args: append(l.args[:len(l.args):len(l.args)], args...),
+	}
+}
+
+// WithContext returns a logger that adds the fields of the request ctx belongs to, such
+// as the request ID and the user ID, to every message
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	return &Logger{ctx: ctx, args: l.args}
+}
+
+// Slog returns a slog.Logger that writes through this logger, for libraries that log
+// with log/slog. It keeps the outputs configured when it is called.
+func (l *Logger) Slog() *slog.Logger {
+	if handler == nil {
+		return slog.New(slogHandler{})
+	}
+	return slog.New(slogHandler{handler}).With(l.fields()...)
+}
+
+// fields returns the logger's fields followed by the fields of its request
+func (l *Logger) fields() []any {
+	if l.ctx == nil {
+		return l.args
+	}
+	return append(scopeFields(l.ctx), l.args...)
+}
+
+func (l *Logger) log(lvl Level, format string, v []interface{}) {
+	if level > lvl {
+		return
 	}
+	ctx := l.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	writeLog(ctx, lvl, fmt.Sprintf(format, v...), l.fields())
 }
 
 // Debug logs a debug message (method for backward compatibility)
 func (l *Logger) Debug(format string, v ...interface{}) {
-	Debug(format, v...)
+	l.log(DEBUG, format, v)
 }
 
 // Info logs an info message (method for backward compatibility)
 func (l *Logger) Info(format string, v ...interface{}) {
-	Info(format, v...)
+	l.log(INFO, format, v)
 }
 
 // Warn logs a warning message (method for backward compatibility)
 func (l *Logger) Warn(format string, v ...interface{}) {
-	Warn(format, v...)
+	l.log(WARN, format, v)
 }
 
 // Error logs an error message (method for backward compatibility)
 func (l *Logger) Error(format string, v ...interface{}) {
-	Error(format, v...)
+	l.log(ERROR, format, v)
 }
 
 // Fatal logs a fatal message and exits the application (method for backward compatibility)
 func (l *Logger) Fatal(format string, v ...interface{}) {
-	Fatal(format, v...)
+	l.log(FATAL, format, v)
+	os.Exit(1)
 }
 
 // getMethodColor returns a color based on the HTTP method
@@ -383,8 +390,22 @@ func getMethodColor(method string) Color {
 	}
 }
 
-// HTTPMiddleware creates a middleware for logging HTTP requests
+// HTTPMiddleware creates a middleware for logging HTTP requests. Access lines are
+// written whatever the level, with the fields of the request.
 func (l *Logger) HTTPMiddleware(next http.Handler) http.Handler {
+	return l.httpMiddleware(next, nil)
+}
+
+// HTTPMiddlewareRedacting logs HTTP requests like HTTPMiddleware, with the values of the
+// given query parameters left out. It is meant for routes authenticated by a token in
+// their URL, which would otherwise end up in every access log.
+func (l *Logger) HTTPMiddlewareRedacting(params ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return l.httpMiddleware(next, params)
+	}
+}
+
+func (l *Logger) httpMiddleware(next http.Handler, redacted []string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -398,101 +419,49 @@ func (l *Logger) HTTPMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(rw, r)
 
 		// Log the request
-		duration := time.Since(start)
-
-		// Format method with color only for console output
-		method := r.Method
-
-		// Create log message without caller information (we'll add it manually)
-		logMsg := fmt.Sprintf(
-			"%s %s %d (%.2fms)",
-			method,
-			r.RequestURI,
-			rw.statusCode,
-			float64(duration.Microseconds())/1000,
-		)
-
-		// For BothOutput, we need to handle the method formatting separately
-		if config.OutputType == BothOutput {
-			// Format for console (with colors)
-			consoleMethod := method
-			if config.EnableColor {
-				methodColor := getMethodColor(method)
-				consoleMethod = fmt.Sprintf("%s[%s%s%s]", ColorReset, methodColor, method, ColorReset)
-			} else {
-				consoleMethod = fmt.Sprintf("[%s]", method)
-			}
-
-			// Format for file (without colors)
-			fileMethod := fmt.Sprintf("[%s]", method)
-
-			// Log messages separately
-			consoleMsg := fmt.Sprintf(
-				"%s %s %d (%.2fms)",
-				consoleMethod,
-				r.RequestURI,
-				rw.statusCode,
-				float64(duration.Microseconds())/1000,
+		duration := float64(time.Since(start).Microseconds()) / 1000
+		uri := redactQuery(r, redacted)
+
+		if config.Format == JSONFormat {
+			args := append(l.WithContext(r.Context()).fields(),
+				"method", r.Method,
+				"uri", uri,
+				"status", rw.statusCode,
+				"duration_ms", duration,
 			)
+			writeLog(r.Context(), INFO, "request", args)
+			return
+		}
 
-			fileMsg := fmt.Sprintf(
-				"%s %s %d (%.2fms)",
-				fileMethod,
-				r.RequestURI,
-				rw.statusCode,
-				float64(duration.Microseconds())/1000,
-			)
+		msg := fmt.Sprintf("[%s] %s %d (%.2fms)", r.Method, uri, rw.statusCode, duration)
+		record := slog.NewRecord(time.Now(), INFO.slogLevel(), msg, 0)
+		record.Add(l.WithContext(r.Context()).fields()...)
+		record.AddAttrs(slog.String(methodKey, r.Method))
+		if handler != nil {
+			handler.Handle(r.Context(), record)
+		}
+	})
+}
 
-			// Get timestamp
-			timestamp := time.Now().Format(config.TimeFormat)
-			
-			// Format messages manually without caller information
-			consoleFormattedMsg := fmt.Sprintf("[%s] [%s] %s", timestamp, INFO.String(), consoleMsg)
-			fileFormattedMsg := fmt.Sprintf("[%s] [%s] %s", timestamp, INFO.String(), fileMsg)
-			
-			// Apply colors to console message if enabled
-			if config.EnableColor {
-				levelColor := getLevelColor(INFO)
-				consoleFormattedMsg = fmt.Sprintf("[%s] [%s%s%s] %s", 
-					timestamp, 
-					levelColor, INFO.String(), ColorReset,
-					consoleMsg)
-			}
+// redactQuery returns the URI of r with the values of the given query parameters
+// replaced by REDACTED
+func redactQuery(r *http.Request, params []string) string {
+	if len(params) == 0 || r.URL.RawQuery == "" {
+		return r.RequestURI
+	}
 
-			fmt.Fprintln(config.ConsoleOutput, consoleFormattedMsg)
-			fmt.Fprintln(config.FileOutput, fileFormattedMsg)
-		} else {
-			// Create a custom log entry without using the standard Info function
-			timestamp := time.Now().Format(config.TimeFormat)
-			levelStr := INFO.String()
-			
-			// Apply colors if enabled and for console output
-			if config.EnableColor && config.OutputType == ConsoleOutput {
-				levelColor := getLevelColor(INFO)
-				levelStr = fmt.Sprintf("%s%s%s", levelColor, levelStr, ColorReset)
-				
-				// Also color the method
-				methodColor := getMethodColor(method)
-				method = fmt.Sprintf("[%s%s%s]", methodColor, method, ColorReset)
-			} else {
-				method = fmt.Sprintf("[%s]", method)
-			}
-			
-			// Recreate the message with the formatted method
-			logMsg = fmt.Sprintf(
-				"%s %s %d (%.2fms)",
-				method,
-				r.RequestURI,
-				rw.statusCode,
-				float64(duration.Microseconds())/1000,
-			)
-			
-			// Format the final message without caller information
-			formattedMsg := fmt.Sprintf("[%s] [%s] %s", timestamp, levelStr, logMsg)
-			
-			fmt.Fprintln(config.Output, formattedMsg)
+	query := r.URL.Query()
+	found := false
+	for _, param := range params {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			found = true
 		}
-	})
+	}
+	if !found {
+		return r.RequestURI
+	}
+	return r.URL.Path + "?" + query.Encode()
 }
 
 // responseWriter is a wrapper for http.ResponseWriter that captures the status code
@@ -507,29 +476,63 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// setupFileWriter creates and returns a file writer for logging
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController and FromResponse
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// setupFileWriter creates and returns a file writer for logging, rotated as set in
+// config.Rotation
 func setupFileWriter(config Config) io.Writer {
 	if config.FilePath == "" {
 		// Use project root directory for logs
-		projectRoot := getProjectRoot()
-		logDir := filepath.Join(projectRoot, "logs")
-		if err := os.MkdirAll(logDir, 0o755); err != nil {
-			fmt.Printf("Failed to create log directory: %v\n", err)
-			os.Exit(1)
-		}
-		config.FilePath = filepath.Join(logDir, fmt.Sprintf("%s.log", time.Now().Format("2006-01-02")))
+		config.FilePath = filepath.Join(getProjectRoot(), "logs", "app.log")
 	}
 
-	// Create or open the log file with append mode
-	file, err := os.OpenFile(config.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	file, err := OpenRotatingFile(config.FilePath, config.Rotation)
 	if err != nil {
 		fmt.Printf("Failed to open log file: %v\n", err)
 		return os.Stdout
 	}
 
+	logFile = file
 	return file
 }
 
+// Reopen reopens the log file, see RotatingFile.Reopen. It does nothing when logs are
+// not written to a file.
+func Reopen() error {
+	if logFile == nil {
+		return nil
+	}
+	return logFile.Reopen()
+}
+
+// ReopenOnSIGHUP reopens the log file every time the process receives SIGHUP, so that
+// logrotate and similar tools can move it away and signal the server
+func ReopenOnSIGHUP() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	go func() {
+		for range sigCh {
+			if err := Reopen(); err != nil {
+				Error("Failed to reopen log file: %v", err)
+				continue
+			}
+			Info("Reopened log file after SIGHUP")
+		}
+	}()
+}
+
+// Close flushes and closes the log file, waiting for rotated files to be compressed
+func Close() error {
+	if logFile == nil {
+		return nil
+	}
+	return logFile.Close()
+}
+
 // getProjectRoot attempts to find the project root directory
 func getProjectRoot() string {
 	// Try to use working directory as project root
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// capture points the global handler at a buffer for the duration of a test
+func capture(t *testing.T, format Format) *bytes.Buffer {
+	t.Helper()
+
+	prevHandler, prevLevel, prevConfig := handler, level, config
+	t.Cleanup(func() { handler, level, config = prevHandler, prevLevel, prevConfig })
+
+	var buf bytes.Buffer
+	config = Config{Format: format, OutputType: ConsoleOutput, ConsoleOutput: &buf, TimeFormat: "15:04:05"}
+	level = DEBUG
+	configureOutput()
+	return &buf
+}
+
+func TestJSONFormatWritesRequestFields(t *testing.T) {
+	buf := capture(t, JSONFormat)
+
+	ctx := NewContext(t.Context(), "request_id", "req-1")
+	AddFields(ctx, "user_id", "user-1")
+	(&Logger{}).With("post_id", 42).WithContext(ctx).Error("Failed to get post: %v", "boom")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("output %q is not a JSON line: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":      "ERROR",
+		"msg":        "Failed to get post: boom",
+		"request_id": "req-1",
+		"user_id":    "user-1",
+		"post_id":    float64(42),
+	}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("%s = %v, want %v", key, line[key], value)
+		}
+	}
+}
+
+func TestTextFormatAppendsFields(t *testing.T) {
+	buf := capture(t, TextFormat)
+
+	(&Logger{}).With("user_id", "user-1", "note", "two words").Warn("Slow query")
+
+	got := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(got, `[WARN] Slow query user_id=user-1 note="two words"`) {
+		t.Errorf("line = %q, want fields after the message", got)
+	}
+}
+
+func TestLevelFiltersMessages(t *testing.T) {
+	buf := capture(t, TextFormat)
+	level = WARN
+
+	Info("hidden")
+	(&Logger{}).Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("messages below the level were written: %q", buf.String())
+	}
+}
+
+func TestSlogWritesThroughLogger(t *testing.T) {
+	buf := capture(t, JSONFormat)
+
+	ctx := NewContext(t.Context(), "request_id", "req-2")
+	(&Logger{}).Slog().InfoContext(ctx, "from slog", "attempt", 2)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("output %q is not a JSON line: %v", buf.String(), err)
+	}
+	if line["level"] != "INFO" || line["request_id"] != "req-2" || line["attempt"] != float64(2) {
+		t.Errorf("line = %v, want INFO with request_id and attempt", line)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	buf := capture(t, JSONFormat)
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"kept", "abc-123", true},
+		{"generated", "", false},
+		{"invalid replaced", "bad id\n", false},
+		{"too long replaced", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+
+			var seen string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestID(r.Context())
+				AddFields(r.Context(), "user_id", "user-1")
+				FromResponse(w).Warn("handled")
+			})
+			h := RequestIDMiddleware((&Logger{}).HTTPMiddleware(next))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			header := rec.Header().Get(RequestIDHeader)
+			if header == "" || header != seen {
+				t.Fatalf("header %q and context %q should hold the same ID", header, seen)
+			}
+			if tt.keep != (header == tt.incoming) {
+				t.Errorf("request ID = %q for incoming %q, keep = %v", header, tt.incoming, tt.keep)
+			}
+
+			// The handler's message and the access line both carry the request fields
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+			}
+			for _, l := range lines {
+				var line map[string]any
+				if err := json.Unmarshal([]byte(l), &line); err != nil {
+					t.Fatal(err)
+				}
+				if line["request_id"] != header || line["user_id"] != "user-1" {
+					t.Errorf("line %v does not carry request_id %s and user_id", line, header)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareRedactsQueryParams(t *testing.T) {
+	buf := capture(t, JSONFormat)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := (&Logger{}).HTTPMiddlewareRedacting("token")(next)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/exports/download?token=secret&part=2", nil))
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("output %q is not a JSON line: %v", buf.String(), err)
+	}
+	if line["uri"] != "/api/exports/download?part=2&token=REDACTED" {
+		t.Errorf("uri = %v, want the token redacted", line["uri"])
+	}
+}
@@ -0,0 +1,337 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated files, it sorts in time order and is safe in file names
+const backupTimeFormat = "20060102-150405.000"
+
+// RotationConfig controls when a log file is rotated and how many rotated files are kept
+type RotationConfig struct {
+	MaxSize    int64         // Rotate before a write would grow the file past this many bytes, 0 disables
+	Interval   time.Duration // Rotate when a write falls in a later interval than the file's last write, 0 disables
+	MaxBackups int           // Rotated files to keep, the oldest are deleted first, 0 keeps all
+	Compress   bool          // Gzip rotated files
+}
+
+// RotatingFile is an io.Writer that appends to a log file and moves it aside when it
+// grows too large or a new interval starts. Rotated files are named after the time they
+// were rotated, e.g. app-20250101-030000.000.log, and are compressed and pruned in the
+// background.
+type RotatingFile struct {
+	path string
+	cfg  RotationConfig
+	now  func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time
+
+	millCh   chan struct{}
+	millDone chan struct{}
+	millErr  error
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory if needed
+func OpenRotatingFile(path string, cfg RotationConfig) (*RotatingFile, error) {
+	return openRotatingFile(path, cfg, time.Now)
+}
+
+// openRotatingFile opens a rotating file that reads the time from now
+func openRotatingFile(path string, cfg RotationConfig, now func() time.Time) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:     path,
+		cfg:      cfg,
+		now:      now,
+		millCh:   make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	go f.mill()
+	// Compress and prune files left over from a previous run
+	f.startMill()
+	return f, nil
+}
+
+// open opens the log file and records its size and the interval of its last write
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.period = f.periodOf(f.now())
+	if f.size > 0 {
+		f.period = f.periodOf(info.ModTime())
+	}
+	return nil
+}
+
+// periodOf returns the start of the rotation interval t falls in
+func (f *RotatingFile) periodOf(t time.Time) time.Time {
+	if f.cfg.Interval <= 0 {
+		return time.Time{}
+	}
+	return t.Truncate(f.cfg.Interval)
+}
+
+// Write appends p to the log file, rotating it first if p would not fit or a new
+// interval has started
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.size > 0 {
+		tooBig := f.cfg.MaxSize > 0 && f.size+int64(len(p)) > f.cfg.MaxSize
+		newPeriod := f.cfg.Interval > 0 && !f.periodOf(f.now()).Equal(f.period)
+		if tooBig || newPeriod {
+			if err := f.rotate(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate moves the current file aside and starts a new one
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// rotate renames the current file after the current time and opens a new one, callers
+// hold f.mu
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	// Two rotations within a millisecond would otherwise overwrite the first one
+	rotatedAt := f.now()
+	for exists(f.backupName(rotatedAt)) || exists(f.backupName(rotatedAt)+".gz") {
+		rotatedAt = rotatedAt.Add(time.Millisecond)
+	}
+
+	if err := os.Rename(f.path, f.backupName(rotatedAt)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.startMill()
+	return nil
+}
+
+// Reopen closes and reopens the log file, for tools like logrotate that move the file
+// away and then signal the process
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+	return f.open()
+}
+
+// Close closes the log file and waits for background compression and pruning to finish.
+// It returns the last error of the background work, if any.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.file == nil {
+		f.mu.Unlock()
+		return os.ErrClosed
+	}
+	err := f.file.Close()
+	f.file = nil
+	f.mu.Unlock()
+
+	close(f.millCh)
+	<-f.millDone
+	if err != nil {
+		return err
+	}
+	return f.millErr
+}
+
+// backupName returns the name a file rotated at t is moved to
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := f.nameParts()
+	return filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+}
+
+// nameParts splits the log path into its directory, the prefix of rotated files and
+// the extension, app.log gives app- and .log
+func (f *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(f.path)
+	base := filepath.Base(f.path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// startMill asks the background goroutine to compress and prune rotated files
+func (f *RotatingFile) startMill() {
+	select {
+	case f.millCh <- struct{}{}:
+	default:
+		// A run is already pending and will see this file too
+	}
+}
+
+// mill compresses and prunes rotated files each time it is asked to, until Close
+func (f *RotatingFile) mill() {
+	defer close(f.millDone)
+	for range f.millCh {
+		if err := f.millOnce(); err != nil {
+			f.millErr = err
+			fmt.Fprintf(os.Stderr, "logger: %v\n", err)
+		}
+	}
+}
+
+// millOnce compresses rotated files if enabled and deletes the oldest beyond MaxBackups
+func (f *RotatingFile) millOnce() error {
+	backups, err := f.backups()
+	if err != nil {
+		return fmt.Errorf("failed to list rotated log files: %w", err)
+	}
+
+	var errs []error
+	if f.cfg.Compress {
+		for i, name := range backups {
+			if strings.HasSuffix(name, ".gz") {
+				continue
+			}
+			if err := compressFile(name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			backups[i] = name + ".gz"
+		}
+	}
+
+	if f.cfg.MaxBackups > 0 && len(backups) > f.cfg.MaxBackups {
+		for _, name := range backups[:len(backups)-f.cfg.MaxBackups] {
+			if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("failed to remove rotated log file: %w", err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// backups lists the rotated files of this log, oldest first
+func (f *RotatingFile) backups() ([]string, error) {
+	dir, prefix, ext := f.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		stamp = strings.TrimPrefix(stamp, prefix)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(dir, name))
+	}
+
+	// The timestamps sort in time order, a compressed and an uncompressed copy of the
+	// same file cannot both exist for long
+	sort.Strings(names)
+	return names, nil
+}
+
+// exists reports whether a file named name exists
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// compressFile gzips name to name.gz and removes name once the copy is complete
+func compressFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open rotated log file: %w", err)
+	}
+	defer src.Close()
+
+	tmp := name + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed log file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		return fmt.Errorf("failed to compress rotated log file: %w", err)
+	}
+	if err = zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress rotated log file: %w", err)
+	}
+	if err = dst.Close(); err != nil {
+		return fmt.Errorf("failed to write compressed log file: %w", err)
+	}
+	if err = os.Rename(tmp, name+".gz"); err != nil {
+		return fmt.Errorf("failed to write compressed log file: %w", err)
+	}
+
+	src.Close()
+	return os.Remove(name)
+}
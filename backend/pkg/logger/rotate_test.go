@@ -0,0 +1,234 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// openTestFile opens app.log in a temporary directory with a fake clock
+func openTestFile(t *testing.T, cfg RotationConfig) (*RotatingFile, *fakeClock, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	clock := newFakeClock()
+	f, err := openRotatingFile(filepath.Join(dir, "app.log"), cfg, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, clock, dir
+}
+
+func write(t *testing.T, f *RotatingFile, s string) {
+	t.Helper()
+	if _, err := f.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rotated lists the rotated files in dir, oldest first
+func rotated(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name() != "app.log" {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readLog returns the contents of a log file, decompressing .gz files
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if filepath.Ext(path) == ".gz" {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotateOnSize(t *testing.T) {
+	f, _, dir := openTestFile(t, RotationConfig{MaxSize: 10})
+
+	write(t, f, "first\n")
+	write(t, f, "abc\n") // 10 bytes in total, still fits
+	write(t, f, "next\n")
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := rotated(t, dir)
+	if len(backups) != 1 || backups[0] != "app-20250101-103000.000.log" {
+		t.Fatalf("rotated files = %v, want one named after the rotation time", backups)
+	}
+	if got := readLog(t, filepath.Join(dir, backups[0])); got != "first\nabc\n" {
+		t.Errorf("rotated file = %q", got)
+	}
+	if got := readLog(t, filepath.Join(dir, "app.log")); got != "next\n" {
+		t.Errorf("current file = %q", got)
+	}
+}
+
+func TestRotateOnInterval(t *testing.T) {
+	f, clock, dir := openTestFile(t, RotationConfig{Interval: time.Hour})
+
+	write(t, f, "10:30\n")
+	clock.Advance(29 * time.Minute)
+	write(t, f, "10:59\n")
+	if backups := rotated(t, dir); len(backups) != 0 {
+		t.Fatalf("rotated within the hour: %v", backups)
+	}
+
+	clock.Advance(time.Minute)
+	write(t, f, "11:00\n")
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := rotated(t, dir)
+	if len(backups) != 1 || backups[0] != "app-20250101-110000.000.log" {
+		t.Fatalf("rotated files = %v, want one rotated at 11:00", backups)
+	}
+	if got := readLog(t, filepath.Join(dir, backups[0])); got != "10:30\n10:59\n" {
+		t.Errorf("rotated file = %q", got)
+	}
+	if got := readLog(t, filepath.Join(dir, "app.log")); got != "11:00\n" {
+		t.Errorf("current file = %q", got)
+	}
+}
+
+func TestRetentionAndCompression(t *testing.T) {
+	f, clock, dir := openTestFile(t, RotationConfig{MaxBackups: 2, Compress: true})
+
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		write(t, f, line)
+		if err := f.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Second)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := rotated(t, dir)
+	want := []string{"app-20250101-103002.000.log.gz", "app-20250101-103003.000.log.gz"}
+	if len(backups) != len(want) || backups[0] != want[0] || backups[1] != want[1] {
+		t.Fatalf("rotated files = %v, want %v", backups, want)
+	}
+	if got := readLog(t, filepath.Join(dir, backups[0])); got != "three\n" {
+		t.Errorf("oldest kept file = %q, want three", got)
+	}
+	if got := readLog(t, filepath.Join(dir, backups[1])); got != "four\n" {
+		t.Errorf("newest kept file = %q, want four", got)
+	}
+}
+
+func TestRotationsWithinAMillisecondKeepBothFiles(t *testing.T) {
+	f, _, dir := openTestFile(t, RotationConfig{})
+
+	write(t, f, "one\n")
+	if err := f.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	write(t, f, "two\n")
+	if err := f.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := rotated(t, dir)
+	if len(backups) != 2 {
+		t.Fatalf("rotated files = %v, want 2", backups)
+	}
+	if got := readLog(t, filepath.Join(dir, backups[0])) + readLog(t, filepath.Join(dir, backups[1])); got != "one\ntwo\n" {
+		t.Errorf("rotated files hold %q", got)
+	}
+}
+
+func TestReopenAfterExternalRotation(t *testing.T) {
+	f, _, dir := openTestFile(t, RotationConfig{})
+	path := filepath.Join(dir, "app.log")
+
+	write(t, f, "before\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	write(t, f, "still old\n")
+
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	write(t, f, "after\n")
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readLog(t, path+".1"); got != "before\nstill old\n" {
+		t.Errorf("moved file = %q", got)
+	}
+	if got := readLog(t, path); got != "after\n" {
+		t.Errorf("reopened file = %q", got)
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	f, _, _ := openTestFile(t, RotationConfig{})
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("late\n")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "social_network"
+
+// Registry holds the server's metrics. It is kept apart from the default prometheus
+// registry so that /metrics only shows what the server registers.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	// HTTPRequests counts requests by route pattern, method and status code
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPRequestDuration observes how long requests take by route pattern and method
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time to serve HTTP requests by route pattern and method, websocket connections excluded.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// WebsocketMessages counts messages queued for websocket clients by event type, and
+	// whether they were sent or dropped because the client's queue was full
+	WebsocketMessages = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "messages_total",
+		Help:      "Messages for websocket clients by event type and result, sent or dropped.",
+	}, []string{"event_type", "result"})
+
+	// DBQueryDuration observes how long database statements take by operation, exec or query
+	DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Time to run database statements by operation, exec or query.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"operation"})
+
+	// DBQueryErrors counts database statements that failed by operation
+	DBQueryErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Database statements that returned an error by operation, exec or query.",
+	}, []string{"operation"})
+
+	// UploadBytes counts the bytes of uploaded files by upload directory
+	UploadBytes = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "uploads",
+		Name:      "bytes_total",
+		Help:      "Bytes of uploaded files saved by upload directory.",
+	}, []string{"dir"})
+
+	// NotificationsCreated counts stored notifications by notification type
+	NotificationsCreated = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "notifications",
+		Name:      "created_total",
+		Help:      "Notifications stored by type.",
+	}, []string{"type"})
+
+	// RateLimitRejections counts requests refused by the rate limiter
+	RateLimitRejections = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests refused by the rate limiter.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+var (
+	connectionsMu    sync.Mutex
+	connectionsGauge prometheus.Collector
+)
+
+// ObserveWebsocketConnections reports count as the number of open websocket connections,
+// replacing the function of a hub created earlier
+func ObserveWebsocketConnections(count func() int) {
+	connectionsMu.Lock()
+	defer connectionsMu.Unlock()
+
+	if connectionsGauge != nil {
+		Registry.Unregister(connectionsGauge)
+	}
+	connectionsGauge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "connections",
+		Help:      "Open websocket connections.",
+	}, func() float64 { return float64(count()) })
+	Registry.MustRegister(connectionsGauge)
+}
+
+// ObserveDB reports the connection pool statistics of db, labelled with name
+func ObserveDB(db *sql.DB, name string) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(db, name))
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
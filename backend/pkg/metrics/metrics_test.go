@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"bufio"
+	"database/sql"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func init() {
+	sql.Register("sqlite3-metrics-test", WrapDriver(&sqlite3.SQLiteDriver{}))
+}
+
+// sampleCount returns the number of observations of a histogram
+func sampleCount(t *testing.T, o prometheus.Observer) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := o.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// gauge returns the value of the gauge called name in the registry
+func gauge(t *testing.T, name string) float64 {
+	t.Helper()
+
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == name && len(f.GetMetric()) == 1 {
+			return f.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("gauge %s not found", name)
+	return 0
+}
+
+func TestInstrumentRoute(t *testing.T) {
+	h := InstrumentRoute("/test/route", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("missing") != "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, target := range []string{"/test/route", "/test/route?missing=1", "/test/route"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("/test/route", "GET", "200")); got != 2 {
+		t.Errorf("200 responses = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("/test/route", "GET", "404")); got != 1 {
+		t.Errorf("404 responses = %v, want 1", got)
+	}
+	if got := sampleCount(t, HTTPRequestDuration.WithLabelValues("/test/route", "GET")); got != 3 {
+		t.Errorf("observed durations = %d, want 3", got)
+	}
+}
+
+func TestInstrumentRouteHijacked(t *testing.T) {
+	h := InstrumentRoute("/test/ws", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		rw.Flush()
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /test/ws HTTP/1.1\r\nHost: test\r\n\r\n"))
+	status, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.Contains(status, "101") {
+		t.Fatalf("status line = %q, %v", status, err)
+	}
+	srv.Close()
+
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("/test/ws", "GET", "101")); got != 1 {
+		t.Errorf("101 responses = %v, want 1", got)
+	}
+	if got := sampleCount(t, HTTPRequestDuration.WithLabelValues("/test/ws", "GET")); got != 0 {
+		t.Errorf("observed durations = %d, want hijacked connections left out", got)
+	}
+}
+
+func TestWrapDriver(t *testing.T) {
+	db, err := sql.Open("sqlite3-metrics-test", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	execs := sampleCount(t, DBQueryDuration.WithLabelValues("exec"))
+	queries := sampleCount(t, DBQueryDuration.WithLabelValues("query"))
+	queryErrors := testutil.ToFloat64(DBQueryErrors.WithLabelValues("query"))
+
+	if _, err := db.Exec("CREATE TABLE items (name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO items (name) VALUES (?)", "a"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("count = %d, %v", n, err)
+	}
+	if _, err := db.Query("SELECT * FROM missing"); err == nil {
+		t.Fatal("query of a missing table succeeded")
+	}
+
+	if got := sampleCount(t, DBQueryDuration.WithLabelValues("exec")) - execs; got != 2 {
+		t.Errorf("timed execs = %d, want 2", got)
+	}
+	if got := sampleCount(t, DBQueryDuration.WithLabelValues("query")) - queries; got != 2 {
+		t.Errorf("timed queries = %d, want 2", got)
+	}
+	if got := testutil.ToFloat64(DBQueryErrors.WithLabelValues("query")) - queryErrors; got != 1 {
+		t.Errorf("query errors = %v, want 1", got)
+	}
+}
+
+func TestObserveWebsocketConnections(t *testing.T) {
+	ObserveWebsocketConnections(func() int { return 3 })
+	if got := gauge(t, "social_network_websocket_connections"); got != 3 {
+		t.Errorf("connections = %v, want 3", got)
+	}
+
+	// A new hub replaces the old one instead of failing to register
+	ObserveWebsocketConnections(func() int { return 5 })
+	if got := gauge(t, "social_network_websocket_connections"); got != 5 {
+		t.Errorf("connections = %v, want 5", got)
+	}
+}
+
+func TestObserveDB(t *testing.T) {
+	db, err := sql.Open("sqlite3-metrics-test", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := ObserveDB(db, "observe_test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ObserveDB(db, "observe_test"); err != nil {
+		t.Errorf("registering the same database twice: %v", err)
+	}
+	if got := gauge(t, "go_sql_max_open_connections"); got != 0 {
+		t.Errorf("max open connections = %v, want 0 for unlimited", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	RateLimitRejections.Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+	for _, name := range []string{"social_network_http_rate_limited_total", "go_goroutines", "process_start_time_seconds"} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("metrics do not include %s", name)
+		}
+	}
+}
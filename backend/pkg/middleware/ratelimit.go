@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Athooh/social-network/pkg/logger"
+	"github.com/Athooh/social-network/pkg/metrics"
 )
 
 // RateLimiter implements a simple rate limiting middleware
@@ -61,6 +62,7 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 		if len(rl.requests[clientIP]) >= rl.limit {
 			rl.mu.Unlock()
 			logger.Warn("Rate limit exceeded for IP: %s", clientIP)
+			metrics.RateLimitRejections.Inc()
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusTooManyRequests)
 			w.Write([]byte(`{"error":"Rate limit exceeded. Please try again later."}`))
@@ -25,3 +25,24 @@ type Follower struct {
 	// Add unique constraint for follower_id and following_id
 	_ struct{} `db:"unique:follower_id,following_id"`
 }
+
+// UserBlock hides two users from each other, whichever of them blocked the other
+type UserBlock struct {
+	ID        int64     `db:"id,pk,autoincrement"`
+	BlockerID string    `db:"blocker_id,notnull" index:"idx_user_blocks_blocker_id" references:"users(id) ON DELETE CASCADE"`
+	BlockedID string    `db:"blocked_id,notnull" index:"idx_user_blocks_blocked_id" references:"users(id) ON DELETE CASCADE"`
+	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+
+	_ struct{} `db:"unique:blocker_id,blocked_id"`
+}
+
+// UserMute hides a user's posts from the feed of the user who muted them, without them
+// knowing
+type UserMute struct {
+	ID        int64     `db:"id,pk,autoincrement"`
+	MuterID   string    `db:"muter_id,notnull" index:"idx_user_mutes_muter_id" references:"users(id) ON DELETE CASCADE"`
+	MutedID   string    `db:"muted_id,notnull" references:"users(id) ON DELETE CASCADE"`
+	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+
+	_ struct{} `db:"unique:muter_id,muted_id"`
+}
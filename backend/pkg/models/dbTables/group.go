@@ -14,6 +14,9 @@ type Group struct {
 	BannerPath     sql.NullString `db:"banner_path"`
 	ProfilePicPath sql.NullString `db:"profile_pic_path"`
 	IsPublic       bool           `db:"is_public,default=TRUE"`
+	PostApproval   bool           `db:"post_approval,default=FALSE"`           // Every post waits for a moderator
+	NewMemberPosts int            `db:"new_member_approval_posts,default=0"` // Posts of each member that wait for a moderator, 0 for none
+	AutoApprove    string         `db:"auto_approve_rules,notnull,default=''"` // Comma separated rules that accept join requests on their own
 	CreatedAt      time.Time      `db:"created_at,default=CURRENT_TIMESTAMP"`
 	UpdatedAt      time.Time      `db:"updated_at,default=CURRENT_TIMESTAMP"`
 
@@ -23,6 +26,7 @@ type Group struct {
 	IsMember      bool           `db:"-"`
 	MemberStatus  string         `db:"-"`
 	Members 	[]*GroupMember   `db:"-"`
+	Permissions   []string       `db:"-"` // What the viewing user may do in the group
 }
 
 // GroupMember represents a member of a group
@@ -30,7 +34,7 @@ type GroupMember struct {
 	ID        string    `db:"id,pk"`
 	GroupID   string    `db:"group_id,notnull" index:"idx_group_members_group_id"`
 	UserID    string    `db:"user_id,notnull" index:"idx_group_members_user_id"`
-	Role      string    `db:"role,notnull"` // admin, moderator, member
+	Role      string    `db:"role,notnull"` // admin, moderator, member or a GroupRole name
 	Status    string    `db:"status,notnull" index:"idx_group_members_status"` // pending, accepted, rejected
 	InvitedBy string    `db:"invited_by"`
 	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
@@ -40,6 +44,60 @@ type GroupMember struct {
 	// Non-DB fields
 	User      *UserBasic `db:"-"`
 	Inviter   *UserBasic `db:"-"`
+	Answers   []*GroupJoinAnswer `db:"-"` // Answers to the join questions, only for pending join requests
+}
+
+// GroupInviteLink lets whoever holds its token join a group without waiting for approval
+type GroupInviteLink struct {
+	ID        string       `db:"id,pk"`
+	GroupID   string       `db:"group_id,notnull" index:"idx_group_invite_links_group_id"`
+	Token     string       `db:"token,notnull,unique"`
+	CreatedBy string       `db:"created_by,notnull"`
+	MaxUses   int          `db:"max_uses,default=0"` // 0 for unlimited
+	Uses      int          `db:"uses,default=0"`
+	ExpiresAt sql.NullTime `db:"expires_at"`
+	RevokedAt sql.NullTime `db:"revoked_at"`
+	CreatedAt time.Time    `db:"created_at,default=CURRENT_TIMESTAMP"`
+
+	// Non-DB fields
+	Creator *UserBasic `db:"-"`
+}
+
+// GroupJoinQuestion is asked of everyone who requests to join a group
+type GroupJoinQuestion struct {
+	ID        string    `db:"id,pk"`
+	GroupID   string    `db:"group_id,notnull" index:"idx_group_join_questions_group_id"`
+	Question  string    `db:"question,notnull"`
+	Required  bool      `db:"required,default=FALSE"`
+	Position  int       `db:"position,default=0"`
+	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+}
+
+// GroupJoinAnswer is a user's answer to a join question
+type GroupJoinAnswer struct {
+	ID         string    `db:"id,pk"`
+	GroupID    string    `db:"group_id,notnull" index:"idx_group_join_answers_group_id"`
+	QuestionID string    `db:"question_id,notnull"`
+	UserID     string    `db:"user_id,notnull"`
+	Answer     string    `db:"answer"`
+	CreatedAt  time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+
+	_ struct{} `db:"unique:question_id,user_id"`
+
+	// Non-DB fields
+	Question string `db:"-"`
+}
+
+// GroupRole is a role a group defines on top of the built-in admin, moderator and member
+type GroupRole struct {
+	ID          string    `db:"id,pk"`
+	GroupID     string    `db:"group_id,notnull" index:"idx_group_roles_group_id"`
+	Name        string    `db:"name,notnull"`
+	Permissions string    `db:"permissions,notnull"` // Comma separated permission names
+	CreatedAt   time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time `db:"updated_at,default=CURRENT_TIMESTAMP"`
+
+	_ struct{} `db:"unique:group_id,name"`
 }
 
 // GroupPost represents a post in a group
@@ -52,6 +110,9 @@ type GroupPost struct {
 	VideoPath     sql.NullString `db:"video_path"`
 	LikesCount    int64          `db:"likes_count,default=0"`
 	CommentsCount int64          `db:"comments_count,default=0"`
+	IsEdited      bool           `db:"is_edited,default=FALSE"`
+	IsPinned      bool           `db:"is_pinned,default=FALSE"`
+	Status        string         `db:"status,notnull,default='approved'" index:"idx_group_posts_status"` // approved, pending, rejected
 	CreatedAt     time.Time      `db:"created_at,default=CURRENT_TIMESTAMP"`
 	UpdatedAt     time.Time      `db:"updated_at,default=CURRENT_TIMESTAMP"`
 
@@ -59,6 +120,63 @@ type GroupPost struct {
 	User  *PostUserData `db:"-"`
 	Group *GroupBasic   `db:"-"`
 	Isliked bool          `db:"-"`
+
+	ReactionCounts map[string]int `db:"-"`
+	UserReaction   string         `db:"-"`
+}
+
+// Group post statuses, pending posts are only shown to their author and moderators
+const (
+	GroupPostApproved = "approved"
+	GroupPostPending  = "pending"
+	GroupPostRejected = "rejected"
+)
+
+// GroupBan keeps a user out of a group until it expires, a null expiry is permanent
+type GroupBan struct {
+	ID        string       `db:"id,pk"`
+	GroupID   string       `db:"group_id,notnull" index:"idx_group_bans_group_id"`
+	UserID    string       `db:"user_id,notnull"`
+	BannedBy  string       `db:"banned_by,notnull"`
+	Reason    string       `db:"reason"`
+	ExpiresAt sql.NullTime `db:"expires_at"`
+	CreatedAt time.Time    `db:"created_at,default=CURRENT_TIMESTAMP"`
+
+	_ struct{} `db:"unique:group_id,user_id"`
+
+	// Non-DB fields
+	User *UserBasic `db:"-"`
+}
+
+// GroupMute stops a member from posting and chatting in a group until it expires
+type GroupMute struct {
+	ID        string    `db:"id,pk"`
+	GroupID   string    `db:"group_id,notnull" index:"idx_group_mutes_group_id"`
+	UserID    string    `db:"user_id,notnull"`
+	MutedBy   string    `db:"muted_by,notnull"`
+	Reason    string    `db:"reason"`
+	ExpiresAt time.Time `db:"expires_at,notnull"`
+	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+
+	_ struct{} `db:"unique:group_id,user_id"`
+
+	// Non-DB fields
+	User *UserBasic `db:"-"`
+}
+
+// GroupAuditLog records a moderation action taken in a group
+type GroupAuditLog struct {
+	ID           int64          `db:"id,pk,autoincrement"`
+	GroupID      string         `db:"group_id,notnull" index:"idx_group_audit_logs_group_id"`
+	ActorID      string         `db:"actor_id,notnull"`
+	Action       string         `db:"action,notnull"` // One of the Audit* constants of the group package
+	TargetUserID sql.NullString `db:"target_user_id"`
+	TargetPostID sql.NullInt64  `db:"target_post_id"`
+	Details      string         `db:"details"`
+	CreatedAt    time.Time      `db:"created_at,default=CURRENT_TIMESTAMP" index:"idx_group_audit_logs_created_at"`
+
+	// Non-DB fields
+	Actor *UserBasic `db:"-"`
 }
 
 // GroupEvent represents an event in a group
@@ -68,18 +186,39 @@ type GroupEvent struct {
 	CreatorID   string         `db:"creator_id,notnull"`
 	Title       string         `db:"title,notnull"`
 	Description string         `db:"description"`
-	EventDate   time.Time      `db:"event_date,notnull"`
+	EventDate   time.Time      `db:"event_date,notnull"` // Start, of the first occurrence for recurring events
+	EndDate     sql.NullTime   `db:"end_date"`
+	TimeZone    string         `db:"time_zone,notnull,default='UTC'"` // IANA name, recurrences keep their wall clock time in it
+	Location    string         `db:"location,notnull,default=''"`
+	Capacity    int            `db:"capacity,default=0"` // Seats, guests included, 0 for unlimited
+	GuestLimit  int            `db:"guest_limit,notnull,default=0"` // Guests each going responder may bring
+	Recurrence  string         `db:"recurrence_rule,notnull,default=''"` // RFC 5545 RRULE value, empty for one-off events
+	Exceptions  string         `db:"recurrence_exdates,notnull,default=''"` // Comma separated UTC starts of cancelled occurrences
 	BannerPath  sql.NullString `db:"banner_path"`
 	CreatedAt   time.Time      `db:"created_at,default=CURRENT_TIMESTAMP"`
 	UpdatedAt   time.Time      `db:"updated_at,default=CURRENT_TIMESTAMP"`
 
 
 	// Non-DB fields
-	Creator       *UserBasic  `db:"-"`
-	Group         *GroupBasic `db:"-"`
-	GoingCount    int         `db:"-"`
-	NotGoingCount int         `db:"-"`
-	UserResponse  string      `db:"-"` // The current user's response
+	Creator        *UserBasic          `db:"-"`
+	Group          *GroupBasic         `db:"-"`
+	GoingCount     int                 `db:"-"`
+	NotGoingCount  int                 `db:"-"`
+	ResponseCounts EventResponseCounts `db:"-"`
+	UserResponse   string              `db:"-"` // The current user's response
+	UserGuests     int                 `db:"-"`
+	UserWaitlisted bool                `db:"-"`
+	RecurrenceID   string              `db:"-"` // RFC 3339 start of this occurrence when a recurring event is expanded
+}
+
+// EventResponseCounts are the numbers of responses to an event by type
+type EventResponseCounts struct {
+	Going      int // Confirmed going responders, without their guests
+	Guests     int // Guests of confirmed going responders
+	Waitlisted int // Going responders waiting for a seat
+	NotGoing   int
+	Interested int
+	Maybe      int
 }
 
 // EventResponse represents a user's response to an event
@@ -88,14 +227,24 @@ type EventResponse struct {
 	EventID   string    `db:"event_id,notnull" index:"idx_event_responses_event_id"`
 	UserID    string    `db:"user_id,notnull" index:"idx_event_responses_user_id"`
 	Response  string    `db:"response,notnull"` // going, not_going, interested, maybe
+	Guests    int       `db:"guests,notnull,default=0"` // People a going responder brings along
 	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
 	UpdatedAt time.Time `db:"updated_at,default=CURRENT_TIMESTAMP"`
 
+	WaitlistedAt sql.NullTime `db:"waitlisted_at"` // Set while a going response waits for a seat
+
 
 	// Non-DB fields
 	User *UserBasic `db:"-"`
 }
 
+// CalendarFeedToken gives read access to a user's calendar feed without a session
+type CalendarFeedToken struct {
+	UserID    string    `db:"user_id,pk"`
+	Token     string    `db:"token,notnull,unique"`
+	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+}
+
 // GroupChatMessage represents a message in a group chat
 type GroupChatMessage struct {
 	ID        int64     `db:"id,pk,autoincrement"`
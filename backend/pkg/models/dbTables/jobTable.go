@@ -0,0 +1,20 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ScheduledJob is a job the scheduler runs once RunAt has passed. Jobs are replaced by
+// key, so scheduling a key again moves the job instead of adding one.
+type ScheduledJob struct {
+	ID          string       `db:"id,pk"`
+	Kind        string       `db:"kind,notnull"`
+	Key         string       `db:"job_key,notnull,unique"`
+	Payload     string       `db:"payload,notnull,default=''"`
+	RunAt       time.Time    `db:"run_at,notnull" index:"idx_scheduled_jobs_run_at"`
+	Attempts    int          `db:"attempts,notnull,default=0"`
+	LockedUntil sql.NullTime `db:"locked_until"` // Set while a scheduler runs the job
+	LastError   string       `db:"last_error,notnull,default=''"`
+	CreatedAt   time.Time    `db:"created_at,default=CURRENT_TIMESTAMP"`
+}
@@ -15,4 +15,5 @@ type Notification struct {
 	CreatedAt     time.Time      `db:"created_at,default=CURRENT_TIMESTAMP"`                                                      // Creation time
 	TargetGroupID sql.NullString `db:"target_group_id" references:"groups(id) ON DELETE SET NULL"`                                // Nullable group FK
 	TargetEventID sql.NullString `db:"target_event_id" references:"group_events(id) ON DELETE SET NULL"`                          // Nullable event FK
+	TargetPostID  sql.NullInt64  `db:"target_post_id"`                                                                            // Nullable post or group post ID
 }
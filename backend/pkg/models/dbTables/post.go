@@ -12,6 +12,40 @@ const (
 	PrivacyPrivate       = "private"
 )
 
+// Reaction type constants
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionHaha  = "haha"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+	ReactionAngry = "angry"
+)
+
+// ReactionTypes lists the supported reactions in display order
+var ReactionTypes = []string{ReactionLike, ReactionLove, ReactionHaha, ReactionWow, ReactionSad, ReactionAngry}
+
+// IsValidReaction checks if a reaction type is supported
+func IsValidReaction(reactionType string) bool {
+	for _, t := range ReactionTypes {
+		if t == reactionType {
+			return true
+		}
+	}
+	return false
+}
+
+// Revision target types
+const (
+	RevisionTargetPost      = "post"
+	RevisionTargetGroupPost = "group_post"
+	RevisionTargetComment   = "comment"
+)
+
+// DeletedSharedPostID replaces the shared_post_id of reshares once their original is
+// deleted. Post ids start at 1, so it never refers to a post.
+const DeletedSharedPostID int64 = 0
+
 // Post represents a user post in the database
 type Post struct {
 	ID            int64          `db:"id,pk"`
@@ -20,11 +54,30 @@ type Post struct {
 	ImagePath     sql.NullString `db:"image_path"`
 	VideoPath     sql.NullString `db:"video_path"`
 	Privacy       string         `db:"privacy,notnull"`
-	LikesCount    int64          `db:"likes_count,default=0"`
+	LikesCount    int64          `db:"likes_count,default=0"` // total reactions of any type
 	CommentsCount int64          `db:"comments_count,default=0"`
-	CreatedAt     time.Time      `db:"created_at,default=CURRENT_TIMESTAMP"`
-	UpdatedAt     time.Time      `db:"updated_at,notnull"`
-	UserData      *PostUserData  `db:"-"`
+	IsEdited      bool           `db:"is_edited,default=FALSE"`
+	SharedPostID  sql.NullInt64  `db:"shared_post_id" index:"idx_post_shared_post_id"` // set on reshares and quote posts
+	SharesCount   int64          `db:"shares_count,default=0"`
+	// Private posts are also visible to the members of this list, whenever they joined it
+	AudienceListID sql.NullInt64 `db:"audience_list_id" index:"idx_post_audience_list_id" references:"audience_lists(id) ON DELETE SET NULL"`
+	CreatedAt      time.Time     `db:"created_at,default=CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time     `db:"updated_at,notnull"`
+	UserData       *PostUserData `db:"-"`
+
+	ReactionCounts map[string]int `db:"-"`
+	UserReaction   string         `db:"-"` // the viewer's reaction, empty if none
+
+	SharedPost            *Post `db:"-"`
+	SharedPostUnavailable bool  `db:"-"` // the original was deleted
+}
+
+// PostKey hands out the ids of posts and group posts, which share one id space since
+// likes and comments refer to either by id. Rows are kept so an id is never given to
+// another post once its post is deleted.
+type PostKey struct {
+	ID        int64     `db:"id,pk,autoincrement"`
+	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
 }
 
 // PostViewer represents which users can view a private post
@@ -34,24 +87,105 @@ type PostViewer struct {
 	UserID string `db:"user_id,notnull" index:"idx_post_viewer_user_id"`
 }
 
+// AudienceList is a named, reusable set of users such as "Close friends" that its owner
+// can share private posts with
+type AudienceList struct {
+	ID        int64     `db:"id,pk,autoincrement" json:"id"`
+	OwnerID   string    `db:"owner_id,notnull" index:"idx_audience_lists_owner_id" references:"users(id) ON DELETE CASCADE" json:"ownerId"`
+	Name      string    `db:"name,notnull" json:"name"`
+	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP" json:"createdAt"`
+
+	Members []*PostUserData `db:"-" json:"members"`
+
+	_ struct{} `db:"unique:owner_id,name"`
+}
+
+// AudienceListMember is a user in an audience list
+type AudienceListMember struct {
+	ID      int64     `db:"id,pk,autoincrement"`
+	ListID  int64     `db:"list_id,notnull" references:"audience_lists(id) ON DELETE CASCADE"`
+	UserID  string    `db:"user_id,notnull" index:"idx_audience_list_members_user_id" references:"users(id) ON DELETE CASCADE"`
+	AddedAt time.Time `db:"added_at,default=CURRENT_TIMESTAMP"`
+
+	_ struct{} `db:"unique:list_id,user_id"`
+}
+
+// MaxCommentDepth is the deepest level a reply can be nested at, top-level comments are depth 0
+const MaxCommentDepth = 3
+
 // Comment represents a comment on a post
 type Comment struct {
-	ID        int64          `db:"id,pk,autoincrement"`
-	PostID    int64          `db:"post_id,notnull" index:"idx_comment_post_id"`
-	UserID    string         `db:"user_id,notnull" index:"idx_comment_user_id"`
-	Content   string         `db:"content,notnull"`
-	ImagePath sql.NullString `db:"image_path"`
-	CreatedAt time.Time      `db:"created_at,default=CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time      `db:"updated_at,notnull"`
-	UserData  *PostUserData  `db:"-"`
+	ID           int64          `db:"id,pk,autoincrement"`
+	PostID       int64          `db:"post_id,notnull" index:"idx_comment_post_id"`
+	UserID       string         `db:"user_id,notnull" index:"idx_comment_user_id"`
+	ParentID     sql.NullInt64  `db:"parent_id" index:"idx_comment_parent_id"`
+	Depth        int            `db:"depth,default=0"`
+	Content      string         `db:"content,notnull"`
+	ImagePath    sql.NullString `db:"image_path"`
+	IsEdited     bool           `db:"is_edited,default=FALSE"`
+	LikesCount   int64          `db:"likes_count,default=0"`
+	RepliesCount int64          `db:"replies_count,default=0"`
+	CreatedAt    time.Time      `db:"created_at,default=CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time      `db:"updated_at,notnull"`
+	UserData     *PostUserData  `db:"-"`
+	IsLiked      bool           `db:"-"`
 }
 
-// PostLike represents a like on a post
-type PostLike struct {
+// CommentLike represents a like on a comment
+type CommentLike struct {
 	ID        int64     `db:"id,pk,autoincrement"`
-	PostID    int64     `db:"post_id,notnull" index:"idx_post_likes_post_id"`
-	UserID    string    `db:"user_id,notnull" index:"idx_post_likes_user_id"`
+	CommentID int64     `db:"comment_id,notnull" index:"idx_comment_likes_comment_id"`
+	UserID    string    `db:"user_id,notnull" index:"idx_comment_likes_user_id"`
 	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+
+	_ struct{} `db:"unique:comment_id,user_id"`
+}
+
+// PostRevision stores a prior version of an edited post, group post or comment
+type PostRevision struct {
+	ID         int64          `db:"id,pk,autoincrement"`
+	TargetType string         `db:"target_type,notnull" index:"idx_post_revisions_target_type"` // post, group_post, comment
+	TargetID   int64          `db:"target_id,notnull" index:"idx_post_revisions_target_id"`
+	EditorID   string         `db:"editor_id,notnull"`
+	Content    string         `db:"content"`
+	ImagePath  sql.NullString `db:"image_path"`
+	VideoPath  sql.NullString `db:"video_path"`
+	Privacy    string         `db:"privacy"`
+	CreatedAt  time.Time      `db:"created_at,default=CURRENT_TIMESTAMP"`
+	EditorData *PostUserData  `db:"-"`
+}
+
+// PostLike represents a reaction on a post or group post, existing likes default to the "like" reaction
+type PostLike struct {
+	ID           int64     `db:"id,pk,autoincrement"`
+	PostID       int64     `db:"post_id,notnull" index:"idx_post_likes_post_id"`
+	UserID       string    `db:"user_id,notnull" index:"idx_post_likes_user_id"`
+	ReactionType string    `db:"reaction_type,notnull,default='like'" index:"idx_post_likes_reaction_type"`
+	CreatedAt    time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
+}
+
+// PostTag indexes a hashtag used in a post. CreatedAt is the post's creation time so edits don't refresh trends.
+type PostTag struct {
+	ID        int64     `db:"id,pk,autoincrement"`
+	PostID    int64     `db:"post_id,notnull" index:"idx_post_tags_post_id"`
+	Tag       string    `db:"tag,notnull" index:"idx_post_tags_tag"`
+	CreatedAt time.Time `db:"created_at,default=CURRENT_TIMESTAMP" index:"idx_post_tags_created_at"`
+}
+
+// TrendingTopic is a hashtag ranked by its time-decayed usage in public posts
+type TrendingTopic struct {
+	ID         int64     `db:"id,pk,autoincrement"`
+	Tag        string    `db:"tag,notnull,unique"`
+	Score      float64   `db:"score,notnull,default=0"`
+	PostsCount int       `db:"posts_count,default=0"`
+	UpdatedAt  time.Time `db:"updated_at,default=CURRENT_TIMESTAMP"`
+}
+
+// PostReactor is a user who reacted to a post along with their reaction
+type PostReactor struct {
+	User         *PostUserData `json:"user"`
+	ReactionType string        `json:"reactionType"`
+	CreatedAt    time.Time     `json:"createdAt"`
 }
 
 type PostUserData struct {
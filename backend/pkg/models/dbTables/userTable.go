@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 // User represents a user in the system
 type User struct {
@@ -16,6 +19,9 @@ type User struct {
 	IsPublic    bool      `db:"is_public,default=TRUE"`
 	CreatedAt   time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
 	UpdatedAt   time.Time `db:"updated_at,default=CURRENT_TIMESTAMP"`
+
+	DeactivatedAt sql.NullTime `db:"deactivated_at"` // Hidden and logged out until the user logs in again
+	DeletedAt     sql.NullTime `db:"deleted_at"`     // Personal data erased, the row stays so that history keeps its author
 }
 
 // UserStats represents additional statistics and metrics for a user
@@ -59,3 +65,40 @@ type UserProfile struct {
 	CreatedAt    time.Time `db:"created_at,default=CURRENT_TIMESTAMP"`
 	UpdatedAt    time.Time `db:"updated_at,default=CURRENT_TIMESTAMP"`
 }
+
+// Data export statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// DataExport is a ZIP archive of everything a user shared or received, built in the
+// background and downloadable with its token until it expires
+type DataExport struct {
+	ID          string       `db:"id,pk"`
+	UserID      string       `db:"user_id,notnull" index:"idx_data_exports_user_id" references:"users(id) ON DELETE CASCADE"`
+	Status      string       `db:"status,notnull"`
+	Token       string       `db:"token,notnull,unique"`
+	FileName    string       `db:"file_name,notnull,default=''"` // In the export directory, once ready
+	Size        int64        `db:"size,notnull,default=0"`
+	CreatedAt   time.Time    `db:"created_at,default=CURRENT_TIMESTAMP"`
+	CompletedAt sql.NullTime `db:"completed_at"`
+	ExpiresAt   sql.NullTime `db:"expires_at"`
+}
+
+// What happens to the comments and messages of a deleted account
+const (
+	DeletionAnonymize = "anonymize" // Kept, shown as written by a deleted user
+	DeletionRemove    = "remove"
+)
+
+// AccountDeletion is a deletion the user asked for, carried out once DeleteAt passes
+// unless they log in again before
+type AccountDeletion struct {
+	UserID      string    `db:"user_id,pk" references:"users(id) ON DELETE CASCADE"`
+	Content     string    `db:"content,notnull"` // DeletionAnonymize or DeletionRemove
+	RequestedAt time.Time `db:"requested_at,default=CURRENT_TIMESTAMP"`
+	DeleteAt    time.Time `db:"delete_at,notnull"`
+}
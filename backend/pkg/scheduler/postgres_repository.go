@@ -0,0 +1,15 @@
+package scheduler
+
+import (
+	"database/sql"
+)
+
+// PostgresRepository implements Repository for PostgreSQL with the queries of SQLiteRepository
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository on a database opened with postgres.New
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{&SQLiteRepository{db: db}}
+}
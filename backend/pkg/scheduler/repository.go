@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+)
+
+// Repository stores scheduled jobs
+type Repository interface {
+	// SaveJob adds a job, or replaces the job with the same key
+	SaveJob(job *models.ScheduledJob) error
+	DeleteJob(key string) error
+	DeleteJobsWithPrefix(prefix string) error
+	GetJob(key string) (*models.ScheduledJob, error)
+
+	// GetDueJobs gets up to limit jobs due at now that no scheduler holds, oldest first
+	GetDueJobs(now time.Time, limit int) ([]*models.ScheduledJob, error)
+	// ClaimJob locks a due job until the given time, false if another scheduler got it first
+	ClaimJob(id string, now, until time.Time) (bool, error)
+	// CompleteJob deletes a job that ran, unless it was scheduled again while running
+	CompleteJob(id string) error
+	// RetryJob unlocks a job that failed to run at runAt
+	RetryJob(id string, runAt time.Time, lastError string) error
+}
+
+// SQLiteRepository implements Repository for SQLite. Times are stored in UTC, SQLite
+// compares them as text.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository creates a new SQLite repository
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+// SaveJob adds a job, or replaces the job with the same key. The replacement gets the new
+// ID so a run of the old job that is still going on does not delete it.
+func (r *SQLiteRepository) SaveJob(job *models.ScheduledJob) error {
+	_, err := r.db.Exec(`
+		INSERT INTO scheduled_jobs (id, kind, job_key, payload, run_at, attempts, locked_until, last_error, created_at)
+		VALUES (?, ?, ?, ?, ?, 0, NULL, '', ?)
+		ON CONFLICT (job_key) DO UPDATE SET
+			id = excluded.id, kind = excluded.kind, payload = excluded.payload, run_at = excluded.run_at,
+			attempts = 0, locked_until = NULL, last_error = '', created_at = excluded.created_at
+	`, job.ID, job.Kind, job.Key, job.Payload, job.RunAt.UTC(), job.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// DeleteJob deletes the job with a key, if any
+func (r *SQLiteRepository) DeleteJob(key string) error {
+	if _, err := r.db.Exec("DELETE FROM scheduled_jobs WHERE job_key = ?", key); err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	return nil
+}
+
+// DeleteJobsWithPrefix deletes the jobs whose keys start with prefix
+func (r *SQLiteRepository) DeleteJobsWithPrefix(prefix string) error {
+	// Compared with substr so that % and _ in the prefix need no escaping
+	_, err := r.db.Exec("DELETE FROM scheduled_jobs WHERE substr(job_key, 1, ?) = ?", len(prefix), prefix)
+	if err != nil {
+		return fmt.Errorf("failed to delete jobs: %w", err)
+	}
+	return nil
+}
+
+const jobColumns = "id, kind, job_key, payload, run_at, attempts, locked_until, last_error, created_at"
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row rowScanner) (*models.ScheduledJob, error) {
+	var job models.ScheduledJob
+	err := row.Scan(&job.ID, &job.Kind, &job.Key, &job.Payload, &job.RunAt, &job.Attempts, &job.LockedUntil, &job.LastError, &job.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJob gets the job with a key, nil if there is none
+func (r *SQLiteRepository) GetJob(key string) (*models.ScheduledJob, error) {
+	job, err := scanJob(r.db.QueryRow("SELECT "+jobColumns+" FROM scheduled_jobs WHERE job_key = ?", key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// GetDueJobs gets up to limit jobs due at now that no scheduler holds, oldest first
+func (r *SQLiteRepository) GetDueJobs(now time.Time, limit int) ([]*models.ScheduledJob, error) {
+	rows, err := r.db.Query(`
+		SELECT `+jobColumns+`
+		FROM scheduled_jobs
+		WHERE run_at <= ? AND (locked_until IS NULL OR locked_until <= ?)
+		ORDER BY run_at ASC
+		LIMIT ?
+	`, now.UTC(), now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.ScheduledJob
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimJob locks a due job until the given time, false if another scheduler got it first
+func (r *SQLiteRepository) ClaimJob(id string, now, until time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE scheduled_jobs
+		SET locked_until = ?, attempts = attempts + 1
+		WHERE id = ? AND (locked_until IS NULL OR locked_until <= ?)
+	`, until.UTC(), id, now.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return n == 1, nil
+}
+
+// CompleteJob deletes a job that ran, unless it was scheduled again while running
+func (r *SQLiteRepository) CompleteJob(id string) error {
+	if _, err := r.db.Exec("DELETE FROM scheduled_jobs WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return nil
+}
+
+// RetryJob unlocks a job that failed to run at runAt
+func (r *SQLiteRepository) RetryJob(id string, runAt time.Time, lastError string) error {
+	_, err := r.db.Exec(`
+		UPDATE scheduled_jobs SET run_at = ?, locked_until = NULL, last_error = ? WHERE id = ?
+	`, runAt.UTC(), lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Package scheduler runs delayed jobs kept in the database, so that jobs scheduled before a
+// restart still run after it. Jobs are claimed before they run, several instances of the
+// application can share one table.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/logger"
+	models "github.com/Athooh/social-network/pkg/models/dbTables"
+	"github.com/google/uuid"
+)
+
+// Job is a scheduled job handed to its Handler
+type Job struct {
+	Kind    string
+	Key     string
+	RunAt   time.Time
+	Attempt int // 1 on the first run
+	payload string
+}
+
+// Decode unmarshals the payload the job was scheduled with into v
+func (j *Job) Decode(v interface{}) error {
+	if err := json.Unmarshal([]byte(j.payload), v); err != nil {
+		return fmt.Errorf("failed to decode %s job payload: %w", j.Kind, err)
+	}
+	return nil
+}
+
+// Handler runs the jobs of a kind. An error runs the job again later, up to
+// Config.MaxAttempts times.
+type Handler func(ctx context.Context, job *Job) error
+
+// Config holds the scheduler configuration, zero values take the defaults
+type Config struct {
+	PollInterval time.Duration // How often due jobs are looked for, 15 seconds by default
+	BatchSize    int           // Jobs run per poll at most, 50 by default
+	LockTimeout  time.Duration // A claimed job that did not finish in time is run again, 5 minutes by default
+	MaxAttempts  int           // Runs before a failing job is dropped, 5 by default
+	RetryDelay   time.Duration // Delay before the first retry, doubled on every attempt, 1 minute by default
+}
+
+func (c *Config) setDefaults() {
+	if c.PollInterval <= 0 {
+		c.PollInterval = 15 * time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 50
+	}
+	if c.LockTimeout <= 0 {
+		c.LockTimeout = 5 * time.Minute
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 5
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = time.Minute
+	}
+}
+
+// Scheduler runs persisted jobs once they are due
+type Scheduler struct {
+	repo Repository
+	log  *logger.Logger
+	cfg  Config
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a new scheduler, Run starts running jobs
+func New(repo Repository, log *logger.Logger, cfg Config) *Scheduler {
+	cfg.setDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		repo:     repo,
+		log:      log,
+		cfg:      cfg,
+		handlers: make(map[string]Handler),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Handle sets the handler of a kind of job. Handlers should be set before Run, jobs of a
+// kind without one fail.
+func (s *Scheduler) Handle(kind string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = handler
+}
+
+// Schedule schedules a job to run at runAt with payload encoded as JSON. A job already
+// scheduled with the same key is replaced, keys let callers move or cancel their jobs.
+func (s *Scheduler) Schedule(kind, key string, runAt time.Time, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s job payload: %w", kind, err)
+	}
+
+	return s.repo.SaveJob(&models.ScheduledJob{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Key:       key,
+		Payload:   string(data),
+		RunAt:     runAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Cancel cancels the job with a key, if any
+func (s *Scheduler) Cancel(key string) error {
+	return s.repo.DeleteJob(key)
+}
+
+// CancelPrefix cancels the jobs whose keys start with prefix
+func (s *Scheduler) CancelPrefix(prefix string) error {
+	return s.repo.DeleteJobsWithPrefix(prefix)
+}
+
+// Run runs due jobs, those that came due while the application was down first, and then
+// polls for jobs until Stop is called
+func (s *Scheduler) Run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunDue(time.Now()); err != nil {
+			s.log.Error("Failed to run scheduled jobs: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop stops Run, cancelling the context of the job it is running and waiting for it
+func (s *Scheduler) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// RunDue runs the jobs due at now, a batch at a time until none are left
+func (s *Scheduler) RunDue(now time.Time) error {
+	for s.ctx.Err() == nil {
+		jobs, err := s.repo.GetDueJobs(now, s.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		ran := 0
+		for _, job := range jobs {
+			if s.ctx.Err() != nil {
+				return nil
+			}
+
+			claimed, err := s.repo.ClaimJob(job.ID, now, time.Now().Add(s.cfg.LockTimeout))
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+			ran++
+			s.run(job)
+		}
+
+		if ran == 0 || len(jobs) < s.cfg.BatchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// run runs a claimed job and then deletes it, or schedules it again if it failed
+func (s *Scheduler) run(record *models.ScheduledJob) {
+	job := &Job{
+		Kind:    record.Kind,
+		Key:     record.Key,
+		RunAt:   record.RunAt,
+		Attempt: record.Attempts + 1,
+		payload: record.Payload,
+	}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[job.Kind]
+	s.mu.RUnlock()
+
+	var err error
+	if ok {
+		err = s.call(handler, job)
+	} else {
+		err = fmt.Errorf("no handler for %s jobs", job.Kind)
+	}
+
+	if err == nil {
+		if err := s.repo.CompleteJob(record.ID); err != nil {
+			s.log.Error("Failed to complete %s job %s: %v", job.Kind, job.Key, err)
+		}
+		return
+	}
+
+	if job.Attempt >= s.cfg.MaxAttempts {
+		s.log.Error("Dropping %s job %s after %d attempts: %v", job.Kind, job.Key, job.Attempt, err)
+		if err := s.repo.CompleteJob(record.ID); err != nil {
+			s.log.Error("Failed to drop %s job %s: %v", job.Kind, job.Key, err)
+		}
+		return
+	}
+
+	retryAt := time.Now().Add(s.cfg.RetryDelay << (job.Attempt - 1))
+	s.log.Warn("Scheduled %s job %s failed, retrying at %s: %v", job.Kind, job.Key, retryAt.Format(time.RFC3339), err)
+	if err := s.repo.RetryJob(record.ID, retryAt, err.Error()); err != nil {
+		s.log.Error("Failed to reschedule %s job %s: %v", job.Kind, job.Key, err)
+	}
+}
+
+// call runs a handler, turning a panic into an error so one bad job cannot stop the others
+func (s *Scheduler) call(handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(s.ctx, job)
+}
@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Athooh/social-network/pkg/db/sqlite"
+	"github.com/Athooh/social-network/pkg/logger"
+)
+
+func openTestRepository(t *testing.T) *SQLiteRepository {
+	t.Helper()
+
+	db, err := sqlite.New(sqlite.Config{
+		DBPath:         filepath.Join(t.TempDir(), "scheduler.db"),
+		MigrationsPath: filepath.Join("..", "db", "migrations", "sqlite"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := sqlite.ApplyMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	return NewSQLiteRepository(db.DB)
+}
+
+func newTestScheduler(t *testing.T, repo Repository) *Scheduler {
+	t.Helper()
+
+	log := logger.New(logger.Config{Level: logger.FATAL, OutputType: logger.ConsoleOutput, ConsoleOutput: io.Discard})
+	return New(repo, log, Config{RetryDelay: time.Minute, MaxAttempts: 2})
+}
+
+type reminder struct {
+	EventID string `json:"eventId"`
+}
+
+func TestRunDue(t *testing.T) {
+	repo := openTestRepository(t)
+	now := time.Now()
+
+	// Jobs scheduled by an earlier scheduler run with the handlers of the next one
+	earlier := newTestScheduler(t, repo)
+	if err := earlier.Schedule("reminder", "reminder:a", now.Add(-time.Minute), reminder{EventID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := earlier.Schedule("reminder", "reminder:b", now.Add(time.Hour), reminder{EventID: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestScheduler(t, repo)
+	var ran []string
+	s.Handle("reminder", func(ctx context.Context, job *Job) error {
+		var r reminder
+		if err := job.Decode(&r); err != nil {
+			return err
+		}
+		ran = append(ran, r.EventID)
+		return nil
+	})
+
+	if err := s.RunDue(now); err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 1 || ran[0] != "a" {
+		t.Fatalf("ran %v at now, want [a]", ran)
+	}
+	if job, _ := repo.GetJob("reminder:a"); job != nil {
+		t.Error("job still stored after it ran")
+	}
+
+	// Scheduling a key again moves its job
+	if err := s.Schedule("reminder", "reminder:b", now.Add(2*time.Hour), reminder{EventID: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunDue(now.Add(90 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 1 {
+		t.Fatalf("ran %v before the moved job was due", ran)
+	}
+	if err := s.RunDue(now.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 2 || ran[1] != "b" {
+		t.Fatalf("ran %v, want [a b]", ran)
+	}
+}
+
+func TestRunDueRetriesFailedJobs(t *testing.T) {
+	repo := openTestRepository(t)
+	s := newTestScheduler(t, repo)
+	now := time.Now()
+
+	attempts := 0
+	s.Handle("flaky", func(ctx context.Context, job *Job) error {
+		attempts++
+		if job.Attempt != attempts {
+			t.Errorf("Attempt = %d on run %d", job.Attempt, attempts)
+		}
+		return errors.New("unavailable")
+	})
+
+	if err := s.Schedule("flaky", "flaky:1", now, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunDue(now); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := repo.GetJob("flaky:1")
+	if err != nil || job == nil {
+		t.Fatalf("GetJob after a failure = %v, %v, want the job", job, err)
+	}
+	if job.LastError != "unavailable" || !job.RunAt.After(now) || job.LockedUntil.Valid {
+		t.Errorf("failed job = %+v, want it unlocked and due later with its error", job)
+	}
+
+	// The second failure is the last attempt
+	if err := s.RunDue(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("ran %d times, want 2", attempts)
+	}
+	if job, _ := repo.GetJob("flaky:1"); job != nil {
+		t.Error("job still stored after its last attempt")
+	}
+}
+
+func TestClaimedJobsRunOnce(t *testing.T) {
+	repo := openTestRepository(t)
+	s := newTestScheduler(t, repo)
+	now := time.Now()
+
+	if err := s.Schedule("noop", "noop:1", now, nil); err != nil {
+		t.Fatal(err)
+	}
+	job, err := repo.GetJob("noop:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance holds the job
+	if ok, err := repo.ClaimJob(job.ID, now, now.Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("ClaimJob = %v, %v, want true", ok, err)
+	}
+	if ok, err := repo.ClaimJob(job.ID, now, now.Add(time.Minute)); err != nil || ok {
+		t.Errorf("ClaimJob of a held job = %v, %v, want false", ok, err)
+	}
+
+	ran := 0
+	s.Handle("noop", func(ctx context.Context, job *Job) error {
+		ran++
+		return nil
+	})
+	if err := s.RunDue(now); err != nil {
+		t.Fatal(err)
+	}
+	if ran != 0 {
+		t.Error("ran a job another scheduler holds")
+	}
+
+	// Until its lock runs out
+	if err := s.RunDue(now.Add(2 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if ran != 1 {
+		t.Errorf("ran %d times after the lock ran out, want 1", ran)
+	}
+}
+
+func TestCancelPrefix(t *testing.T) {
+	repo := openTestRepository(t)
+	s := newTestScheduler(t, repo)
+	runAt := time.Now().Add(time.Hour)
+
+	for _, key := range []string{"event:1:1h", "event:1:24h", "event:10:1h"} {
+		if err := s.Schedule("reminder", key, runAt, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CancelPrefix("event:1:"); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"event:1:1h": false, "event:1:24h": false, "event:10:1h": true} {
+		if job, _ := repo.GetJob(key); (job != nil) != want {
+			t.Errorf("job %s stored = %v, want %v", key, job != nil, want)
+		}
+	}
+}
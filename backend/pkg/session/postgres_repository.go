@@ -0,0 +1,15 @@
+package session
+
+import (
+	"database/sql"
+)
+
+// PostgresRepository implements SessionStore for PostgreSQL with the queries of SQLiteRepository
+type PostgresRepository struct {
+	*SQLiteRepository
+}
+
+// NewPostgresRepository creates a new PostgreSQL session store on a database opened with postgres.New
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{&SQLiteRepository{db: db}}
+}
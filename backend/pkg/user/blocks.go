@@ -0,0 +1,39 @@
+package user
+
+import "database/sql"
+
+// Blocks and mutes are kept by the follow package. The queries here let every other
+// package leave blocked and muted users out of what it shows.
+
+// BlockedUsersSQL selects the IDs of the users who blocked, or were blocked by, the user
+// bound to both of its placeholders, for use in NOT IN conditions
+const BlockedUsersSQL = `
+	SELECT blocked_id FROM user_blocks WHERE blocker_id = ?
+	UNION
+	SELECT blocker_id FROM user_blocks WHERE blocked_id = ?
+`
+
+// MutedUsersSQL selects the IDs of the users muted by the user bound to its placeholder
+const MutedUsersSQL = `SELECT muted_id FROM user_mutes WHERE muter_id = ?`
+
+// queryRower is a *sql.DB, *sql.Tx or a unit of work handle
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// IsBlocked reports whether either user blocked the other
+func IsBlocked(db queryRower, userID, otherID string) (bool, error) {
+	var blocked bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)
+		)
+	`, userID, otherID, otherID, userID).Scan(&blocked)
+	return blocked, err
+}
+
+// IsBlocked reports whether either user blocked the other
+func (r *SQLiteRepository) IsBlocked(userID, otherID string) (bool, error) {
+	return IsBlocked(r.db, userID, otherID)
+}
@@ -0,0 +1,50 @@
+package user
+
+import "time"
+
+// Accounts are deactivated and deleted by the account package. The queries here let every
+// other package leave deactivated users out, deleted accounts included.
+
+// DeactivatedUsersSQL selects the IDs of deactivated users, for use in NOT IN conditions
+const DeactivatedUsersSQL = `SELECT id FROM users WHERE deactivated_at IS NOT NULL`
+
+// IsDeactivated reports whether a user deactivated their account, or had it deleted
+func IsDeactivated(db queryRower, userID string) (bool, error) {
+	var deactivated bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = ? AND deactivated_at IS NOT NULL)
+	`, userID).Scan(&deactivated)
+	return deactivated, err
+}
+
+// IsDeactivated reports whether a user deactivated their account, or had it deleted
+func (r *SQLiteRepository) IsDeactivated(userID string) (bool, error) {
+	return IsDeactivated(r.db, userID)
+}
+
+// Reactivate brings back a deactivated account and cancels its scheduled deletion,
+// reporting whether it was deactivated. Deleted accounts stay deleted.
+func (r *SQLiteRepository) Reactivate(userID string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET deactivated_at = NULL, updated_at = ?
+		WHERE id = ? AND deactivated_at IS NOT NULL AND deleted_at IS NULL
+	`, time.Now().UTC(), userID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM account_deletions WHERE user_id = ?", userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
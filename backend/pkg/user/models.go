@@ -8,6 +8,14 @@ type Repository interface {
 	GetByID(id string) (*User, error)
 	GetByEmail(email string) (*User, error)
 	Delete(id string) error
+
+	// IsBlocked reports whether either user blocked the other
+	IsBlocked(userID, otherID string) (bool, error)
+
+	// IsDeactivated reports whether a user deactivated their account, or had it deleted
+	IsDeactivated(userID string) (bool, error)
+	// Reactivate brings back a deactivated account and cancels its scheduled deletion
+	Reactivate(userID string) (bool, error)
 }
 
 // StatusRepository defines the interface for user status operations
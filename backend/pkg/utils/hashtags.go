@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hashtagPattern matches #tag where the # is not part of a word, e.g. a URL fragment
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&/])#([\p{L}\p{N}_]{1,100})`)
+
+// validHashtag matches a bare tag without the leading #
+var validHashtag = regexp.MustCompile(`^[\p{L}\p{N}_]{1,100}$`)
+
+// ParseHashtags returns the unique hashtags in content, lower-cased and in order of appearance
+func ParseHashtags(content string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(match[1])
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// NormalizeHashtag lower-cases a tag and strips a leading #, returning "" if it isn't a valid tag
+func NormalizeHashtag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	if !validHashtag.MatchString(tag) {
+		return ""
+	}
+	return strings.ToLower(tag)
+}
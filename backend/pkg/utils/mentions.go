@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @nickname where the @ is not part of a word, e.g. an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_.]{1,50})`)
+
+// ParseMentions returns the unique nicknames mentioned in content, lower-cased and in order of appearance
+func ParseMentions(content string) []string {
+	var nicknames []string
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		nickname := strings.ToLower(strings.TrimRight(match[1], "."))
+		if nickname == "" || seen[nickname] {
+			continue
+		}
+		seen[nickname] = true
+		nicknames = append(nicknames, nickname)
+	}
+
+	return nicknames
+}
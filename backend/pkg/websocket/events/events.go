@@ -5,8 +5,11 @@ type EventType string
 
 const (
 	PostCreated           EventType = "post_created"
-	PostLiked             EventType = "post_liked"
+	PostUpdated           EventType = "post_updated"
+	PostReactionChanged   EventType = "post_reaction_changed"
 	PostCommented         EventType = "post_commented"
+	CommentUpdated        EventType = "comment_updated"
+	CommentLiked          EventType = "comment_liked"
 	UserStatsUpdated      EventType = "user_stats_updated"
 	FollowUpdate          EventType = "follow_update"
 	FollowRequest         EventType = "follow_request"
@@ -16,6 +19,7 @@ const (
 	GroupEventCreated     EventType = "group_event_created"
 	GroupEventUpdated     EventType = "group_event_updated"
 	GroupEventDeleted     EventType = "group_event_deleted"
+	GroupEventReminder    EventType = "group_event_reminder"
 	EventResponseUpdated  EventType = "event_response_updated"
 
 	// Chat events
@@ -28,6 +32,9 @@ const (
 
 	// header notifications
 	HeaderNotificationUpdate EventType = "notification_Update"
+
+	// account events
+	DataExportReady EventType = "data_export_ready"
 )
 
 // Event represents a WebSocket event
@@ -43,8 +50,35 @@ type PostCreatedPayload struct {
 	UserName string      `json:"userName"`
 }
 
-// PostLikedPayload represents the data sent when a post is liked/unliked
-type PostLikedPayload struct {
+// PostUpdatedPayload represents the payload for a post_updated event
+type PostUpdatedPayload struct {
+	Post     interface{} `json:"post"`
+	PostID   int64       `json:"postId"`
+	IsEdited bool        `json:"isEdited"`
+}
+
+// CommentUpdatedPayload represents the payload for a comment_updated event
+type CommentUpdatedPayload struct {
+	Comment  interface{} `json:"comment"`
+	PostID   int64       `json:"postId"`
+	IsEdited bool        `json:"isEdited"`
+}
+
+// PostReactionChangedPayload represents the data sent when a user adds, changes or removes a reaction.
+// ReactionType is empty when the reaction was removed.
+type PostReactionChangedPayload struct {
+	PostID           int64          `json:"postId"`
+	UserID           string         `json:"userId"`
+	UserName         string         `json:"userName"`
+	ReactionType     string         `json:"reactionType"`
+	PreviousReaction string         `json:"previousReaction"`
+	ReactionCounts   map[string]int `json:"reactionCounts"`
+	LikesCount       int            `json:"likesCount"`
+}
+
+// CommentLikedPayload represents the data sent when a comment is liked/unliked
+type CommentLikedPayload struct {
+	CommentID  int64  `json:"commentId"`
 	PostID     int64  `json:"postId"`
 	UserID     string `json:"userId"`
 	UserName   string `json:"userName"`
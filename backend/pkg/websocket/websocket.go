@@ -1,11 +1,16 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/Athooh/social-network/pkg/logger"
+	"github.com/Athooh/social-network/pkg/metrics"
+	"github.com/Athooh/social-network/pkg/websocket/events"
 	"github.com/gorilla/websocket"
 )
 
@@ -51,6 +56,14 @@ type Hub struct {
 
 	heartbeatCheckInterval time.Duration
 	heartbeatTimeout       time.Duration
+
+	// Liveness probes answered by Run
+	probe chan chan struct{}
+
+	// Set by Shutdown, guarded by Mu. Clients registering while draining are closed
+	// right away and every client is sent closeMessage as its close frame.
+	draining     bool
+	closeMessage []byte
 }
 
 // Message represents a WebSocket message
@@ -61,7 +74,7 @@ type Message struct {
 
 // NewHub creates a new Hub instance
 func NewHub(log *logger.Logger) *Hub {
-	return &Hub{
+	h := &Hub{
 		Broadcast:              make(chan []byte),
 		Register:               make(chan *Client),
 		Unregister:             make(chan *Client),
@@ -71,7 +84,34 @@ func NewHub(log *logger.Logger) *Hub {
 		log:                    log,
 		heartbeatCheckInterval: 60 * time.Second,
 		heartbeatTimeout:       120 * time.Second,
+		probe:                  make(chan chan struct{}),
+	}
+	metrics.ObserveWebsocketConnections(h.connectionCount)
+	return h
+}
+
+// connectionCount returns the number of registered clients
+func (h *Hub) connectionCount() int {
+	h.Mu.RLock()
+	defer h.Mu.RUnlock()
+	return len(h.Clients)
+}
+
+// eventType names a message by its type for the websocket metrics
+func eventType(message interface{}) string {
+	switch m := message.(type) {
+	case events.Event:
+		return string(m.Type)
+	case *events.Event:
+		return string(m.Type)
+	case Message:
+		return m.Type
+	case map[string]interface{}:
+		if t, ok := m["type"].(string); ok {
+			return t
+		}
 	}
+	return "unknown"
 }
 
 // Run starts the Hub
@@ -88,6 +128,13 @@ func (h *Hub) Run() {
 		case client := <-h.Register:
 			h.Mu.Lock()
 
+			// The server is shutting down, send the close frame straight away
+			if h.draining {
+				close(client.Send)
+				h.Mu.Unlock()
+				continue
+			}
+
 			// Close ALL existing connections for this user
 			// This ensures only one connection per user
 			if clients, exists := h.UserClients[client.UserID]; exists {
@@ -155,10 +202,77 @@ func (h *Hub) Run() {
 		case <-pingTicker.C:
 			// Send ping to all clients
 			h.sendPingToAllClients()
+
+		case reply := <-h.probe:
+			close(reply)
 		}
 	}
 }
 
+// Alive reports whether Run is still handling the hub's channels, it fails when Run has
+// stopped or is blocked until ctx is done
+func (h *Hub) Alive(ctx context.Context) error {
+	reply := make(chan struct{})
+	select {
+	case h.probe <- reply:
+		<-reply
+		return nil
+	case <-ctx.Done():
+		return errors.New("hub is not responding")
+	}
+}
+
+// Shutdown disconnects every client once the messages queued for it are written. Clients
+// get a close frame with code 1012 (service restart) whose reason tells them how long to
+// wait before reconnecting, e.g. {"reconnect_after_ms":5000}. Shutdown returns when all
+// clients are gone or ctx is done. Run must still be running.
+func (h *Hub) Shutdown(ctx context.Context, reconnectAfter time.Duration) error {
+	reason, err := json.Marshal(map[string]int64{"reconnect_after_ms": reconnectAfter.Milliseconds()})
+	if err != nil {
+		return err
+	}
+
+	h.Mu.Lock()
+	h.draining = true
+	h.closeMessage = websocket.FormatCloseMessage(websocket.CloseServiceRestart, string(reason))
+
+	clients := make([]*Client, 0, len(h.Clients))
+	for client := range h.Clients {
+		// Stop broadcasts before closing the queue, WritePump writes what is left in it
+		// followed by the close frame
+		client.Mu.Lock()
+		client.IsActive = false
+		close(client.Send)
+		client.Mu.Unlock()
+
+		delete(h.Clients, client)
+		clients = append(clients, client)
+	}
+	h.UserClients = make(map[string][]*Client)
+	h.Mu.Unlock()
+
+	h.log.Info("Disconnecting %d websocket clients", len(clients))
+
+	for _, client := range clients {
+		select {
+		case <-client.Done:
+		case <-ctx.Done():
+			return fmt.Errorf("websocket clients did not disconnect: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// closeFrame returns the payload of the close frame sent when a client's queue is closed
+func (h *Hub) closeFrame() []byte {
+	h.Mu.RLock()
+	defer h.Mu.RUnlock()
+	if h.closeMessage == nil {
+		return []byte{}
+	}
+	return h.closeMessage
+}
+
 // BroadcastToAll sends a message to all connected clients
 // func (h *Hub) BroadcastToAll(message interface{}) {
 // 	msgType, payload := prepareMessage("broadcast", message)
@@ -183,13 +297,16 @@ func (h *Hub) BroadcastToUser(userID string, message interface{}) {
 		return
 	}
 
+	msgType := eventType(message)
 	for _, client := range clients {
 		client.Mu.Lock()
 		if client.IsActive {
 			select {
 			case client.Send <- payload:
+				metrics.WebsocketMessages.WithLabelValues(msgType, "sent").Inc()
 				h.log.Info("Sent message to user %s client %s", userID, client.ID)
 			default:
+				metrics.WebsocketMessages.WithLabelValues(msgType, "dropped").Inc()
 				h.log.Info("Failed to send message to user %s client %s", userID, client.ID)
 			}
 		}
@@ -375,7 +492,7 @@ func (c *Client) WritePump() {
 			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
 				// The hub closed the channel
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.Conn.WriteMessage(websocket.CloseMessage, c.Hub.closeFrame())
 				return
 			}
 
@@ -417,20 +534,22 @@ func (h *Hub) GetActiveConnectionCount(userID string) int {
 	return count
 }
 
+// CloseUserConnections disconnects every websocket of a user. The clients are
+// unregistered once the lock is released, Run takes it to handle them.
 func (h *Hub) CloseUserConnections(userID string) {
 	h.Mu.Lock()
-	defer h.Mu.Unlock()
-
-	if clients, exists := h.UserClients[userID]; exists {
-		for _, client := range clients {
-			if client.IsActive {
-				client.IsActive = false
-				client.Conn.Close()
-				h.Unregister <- client
-			}
+	var closing []*Client
+	for _, client := range h.UserClients[userID] {
+		if client.IsActive {
+			client.IsActive = false
+			closing = append(closing, client)
 		}
-		// Clear the user's client list
-		delete(h.UserClients, userID)
+	}
+	h.Mu.Unlock()
+
+	for _, client := range closing {
+		client.Conn.Close()
+		h.Unregister <- client
 	}
 }
 
@@ -337,11 +337,15 @@ export default function Post({ post, onPostUpdated }) {
     // Check if the post ID exists
     if (!post.id) return;
 
-    // Subscribe to post like updates from WebSocket
-    const unsubscribe = subscribe(EVENT_TYPES.POST_LIKED, (payload) => {
+    // Subscribe to post reaction updates from WebSocket
+    const unsubscribe = subscribe(EVENT_TYPES.POST_REACTION_CHANGED, (payload) => {
       // Make sure this update is for our post
       if (Number(payload.postId) === Number(post.id)) {
-        setIsLiked(payload.isLiked);
+        // Only our own reaction changes whether we liked the post, an empty
+        // reactionType means it was removed
+        if (payload.userId === currentUser?.id) {
+          setIsLiked(Boolean(payload.reactionType));
+        }
         setLikesCount(payload.likesCount);
       }
     });
@@ -352,7 +356,7 @@ export default function Post({ post, onPostUpdated }) {
         unsubscribe();
       }
     };
-  }, [post.id, subscribe]);
+  }, [post.id, subscribe, currentUser?.id]);
 
   const handleUserClick = (userId) => {
     // Navigate to profile page regardless of whether it's the current user or not
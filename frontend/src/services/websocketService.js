@@ -8,7 +8,7 @@ import { showToast } from "@/components/ui/ToastContainer";
 // Event types that match backend definitions
 export const EVENT_TYPES = {
   POST_CREATED: "post_created",
-  POST_LIKED: "post_liked",
+  POST_REACTION_CHANGED: "post_reaction_changed",
   USER_STATS_UPDATED: "user_stats_updated",
   USER_STATUS_UPDATE: "user_status_update",
   // Chat events
@@ -101,6 +101,24 @@ if (isAuthenticated && token && !loading && !globalSocket) {
         return;
       }
 
+      // Server restart (1012), reconnect after the delay it suggests without using up attempts
+      if (event.code === 1012) {
+        let delay = BASE_RECONNECT_DELAY;
+        try {
+          delay = JSON.parse(event.reason).reconnect_after_ms ?? delay;
+        } catch {
+          // No hint, use the base delay
+        }
+        // Spread reconnects so clients do not all arrive at once
+        delay += Math.random() * delay;
+        console.log(`Server restarting, reconnecting in ${Math.round(delay / 1000)} seconds`);
+        reconnectAttempts = 0;
+        reconnectTimeout = setTimeout(() => {
+          connectWebSocket();
+        }, delay);
+        return;
+      }
+
       // Reconnect unless normal closure (1000) or exceeded attempts
       if (event.code !== 1000) {
         reconnectAttempts++;